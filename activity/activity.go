@@ -0,0 +1,226 @@
+// Package activity reads the queries running on databases, and the locks
+// blocking them, from their catalogs, and kills them.
+package activity
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xo/dburl"
+)
+
+// Query is a query running on a database.
+type Query struct {
+	// ID is the id of the query's session (the pid of postgres databases,
+	// the sid,serial# of oracle databases), or of the query on clickhouse
+	// and cockroachdb databases, passed to Kill.
+	ID    string
+	User  string
+	State string
+	// Duration is how long the query has been running.
+	Duration time.Duration
+	// BlockedBy are the ids of the sessions holding the locks the query
+	// waits for.
+	BlockedBy []string
+	Query     string
+}
+
+// driver is the activity catalog query and kill statement of a driver.
+type driver struct {
+	// query returns the id, user, state, duration in seconds, the comma
+	// separated ids of the blocking sessions, and the text of the running
+	// queries, but the current session's.
+	query string
+	// blockers returns the ids of the blocked and blocking sessions, when
+	// not read by query. Its errors are ignored, as it may not be available
+	// (such as the sys schema of mysql databases).
+	blockers string
+	// kill is the statement killing the session or query with the id,
+	// matching id.
+	kill string
+	id   *regexp.Regexp
+}
+
+var (
+	numID    = regexp.MustCompile(`^\d+$`)
+	oracleID = regexp.MustCompile(`^\d+,\d+$`)
+	queryID  = regexp.MustCompile(`^[0-9A-Za-z_-]+$`)
+)
+
+// drivers are the activity queries and kill statements, by driver.
+var drivers = map[string]driver{
+	"postgres": {
+		query: `SELECT pid::text, COALESCE(usename, ''), COALESCE(state, ''), COALESCE(EXTRACT(EPOCH FROM now() - query_start), 0)::float8, ` +
+			`array_to_string(pg_blocking_pids(pid), ','), COALESCE(query, '') ` +
+			`FROM pg_stat_activity WHERE pid <> pg_backend_pid() AND backend_type = 'client backend' AND state <> 'idle'`,
+		kill: `SELECT pg_terminate_backend(%s)`,
+		id:   numID,
+	},
+	"cockroachdb": {
+		query: `SELECT query_id, user_name, phase, EXTRACT(EPOCH FROM now() - start)::float8, '', query ` +
+			`FROM crdb_internal.cluster_queries WHERE session_id <> (SELECT session_id FROM [SHOW session_id])`,
+		kill: `CANCEL QUERY '%s'`,
+		id:   queryID,
+	},
+	"mysql": {
+		query: `SELECT CAST(id AS CHAR), COALESCE(user, ''), COALESCE(state, ''), time, '', COALESCE(info, '') ` +
+			`FROM information_schema.processlist WHERE command NOT IN ('Sleep', 'Daemon') AND id <> CONNECTION_ID()`,
+		blockers: `SELECT CAST(waiting_pid AS CHAR), CAST(blocking_pid AS CHAR) FROM sys.innodb_lock_waits`,
+		kill:     `KILL %s`,
+		id:       numID,
+	},
+	"sqlserver": {
+		query: `SELECT CAST(r.session_id AS varchar(10)), COALESCE(s.login_name, ''), r.status, CAST(DATEDIFF(ms, r.start_time, GETDATE()) AS float) / 1000, ` +
+			`CASE WHEN r.blocking_session_id = 0 THEN '' ELSE CAST(r.blocking_session_id AS varchar(10)) END, COALESCE(t.text, '') ` +
+			`FROM sys.dm_exec_requests r JOIN sys.dm_exec_sessions s ON s.session_id = r.session_id OUTER APPLY sys.dm_exec_sql_text(r.sql_handle) t ` +
+			`WHERE r.session_id <> @@SPID AND s.is_user_process = 1`,
+		kill: `KILL %s`,
+		id:   numID,
+	},
+	"oracle": {
+		query: `SELECT s.sid || ',' || s.serial#, NVL(s.username, ' '), s.status, s.last_call_et, NVL(TO_CHAR(s.blocking_session), ' '), NVL(q.sql_text, ' ') ` +
+			`FROM v$session s LEFT JOIN v$sql q ON q.sql_id = s.sql_id AND q.child_number = s.sql_child_number ` +
+			`WHERE s.type = 'USER' AND s.status = 'ACTIVE' AND s.sid <> SYS_CONTEXT('USERENV', 'SID')`,
+		kill: `ALTER SYSTEM KILL SESSION '%s' IMMEDIATE`,
+		id:   oracleID,
+	},
+	"clickhouse": {
+		query: `SELECT query_id, user, '', elapsed, '', query FROM system.processes WHERE query_id <> queryID()`,
+		kill:  `KILL QUERY WHERE query_id = '%s'`,
+		id:    queryID,
+	},
+}
+
+// Supported returns true when the activity of the database can be read.
+func Supported(u *dburl.URL) bool {
+	_, ok := drivers[u.Unaliased]
+	return ok
+}
+
+// Read returns the queries running on the database, but the current
+// session's, sorted by decreasing duration.
+func Read(ctx context.Context, u *dburl.URL, db *sql.DB) ([]Query, error) {
+	d, ok := drivers[u.Unaliased]
+	if !ok {
+		return nil, fmt.Errorf("the activity of %s databases is not supported", u.Unaliased)
+	}
+	rows, err := db.QueryContext(ctx, d.query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var queries []Query
+	for rows.Next() {
+		var q Query
+		var secs float64
+		var blockedBy string
+		if err := rows.Scan(&q.ID, &q.User, &q.State, &secs, &blockedBy, &q.Query); err != nil {
+			return nil, err
+		}
+		q.User, q.State, q.Query = strings.TrimSpace(q.User), strings.TrimSpace(q.State), strings.TrimSpace(q.Query)
+		q.Duration = time.Duration(secs * float64(time.Second))
+		q.BlockedBy = splitIDs(blockedBy)
+		queries = append(queries, q)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if d.blockers != "" {
+		blockers := readBlockers(ctx, db, d.blockers)
+		for i := range queries {
+			queries[i].BlockedBy = append(queries[i].BlockedBy, blockers[queries[i].ID]...)
+		}
+	}
+	sort.SliceStable(queries, func(i, j int) bool {
+		return queries[i].Duration > queries[j].Duration
+	})
+	return queries, nil
+}
+
+// readBlockers returns the ids of the blocking sessions, by blocked session,
+// or nil when the query fails.
+func readBlockers(ctx context.Context, db *sql.DB, query string) map[string][]string {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+	blockers := make(map[string][]string)
+	for rows.Next() {
+		var blocked, blocking string
+		if rows.Scan(&blocked, &blocking) != nil {
+			return nil
+		}
+		blockers[blocked] = append(blockers[blocked], blocking)
+	}
+	return blockers
+}
+
+// splitIDs splits comma separated session ids.
+func splitIDs(s string) []string {
+	var ids []string
+	for _, id := range strings.Split(s, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// Kill kills the session (or the query, on clickhouse and cockroachdb
+// databases) with the id, as permitted by the privileges of the user.
+func Kill(ctx context.Context, u *dburl.URL, db *sql.DB, id string) error {
+	d, ok := drivers[u.Unaliased]
+	if !ok {
+		return fmt.Errorf("killing queries on %s databases is not supported", u.Unaliased)
+	}
+	if !d.id.MatchString(id) {
+		return fmt.Errorf("invalid id %q", id)
+	}
+	stmt := fmt.Sprintf(d.kill, id)
+	if u.Unaliased == "postgres" {
+		var ok bool
+		if err := db.QueryRowContext(ctx, stmt).Scan(&ok); err != nil {
+			return err
+		}
+		if !ok {
+			return fmt.Errorf("no session with pid %s", id)
+		}
+		return nil
+	}
+	_, err := db.ExecContext(ctx, stmt)
+	return err
+}
+
+// Write writes the queries as a table, with the ids of the sessions they
+// block and are blocked by, and their text collapsed to a line of at most
+// width characters (unlimited when 0).
+func Write(w io.Writer, queries []Query, width int) error {
+	if len(queries) == 0 {
+		_, err := fmt.Fprintln(w, "no running queries")
+		return err
+	}
+	blocking := make(map[string][]string)
+	for _, q := range queries {
+		for _, id := range q.BlockedBy {
+			blocking[id] = append(blocking[id], q.ID)
+		}
+	}
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "id\tuser\tstate\tduration\tblocked by\tblocking\tquery")
+	for _, q := range queries {
+		s := strings.Join(strings.Fields(q.Query), " ")
+		if r := []rune(s); width > 0 && len(r) > width {
+			s = string(r[:width-1]) + "…"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", q.ID, q.User, q.State, q.Duration.Round(time.Millisecond), strings.Join(q.BlockedBy, ","), strings.Join(blocking[q.ID], ","), s)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,66 @@
+package activity
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xo/dburl"
+)
+
+func TestWrite(t *testing.T) {
+	queries := []Query{
+		{ID: "12", User: "app", State: "active", Duration: 90 * time.Second, Query: "UPDATE orders\n  SET status = 'paid'"},
+		{ID: "34", User: "batch", State: "active", Duration: 1500 * time.Millisecond, BlockedBy: []string{"12"}, Query: "DELETE FROM orders WHERE created_at < now() - interval '1 year'"},
+	}
+	buf := new(bytes.Buffer)
+	if err := Write(buf, queries, 20); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 lines, got: %q", lines)
+	}
+	for i, exp := range [][]string{
+		{"id", "user", "state", "duration", "blocked", "by", "blocking", "query"},
+		{"12", "app", "active", "1m30s", "34", "UPDATE", "orders", "SET", "s…"},
+		{"34", "batch", "active", "1.5s", "12", "DELETE", "FROM", "orders", "…"},
+	} {
+		if fields := strings.Fields(lines[i]); !reflect.DeepEqual(fields, exp) {
+			t.Errorf("line %d expected %q, got: %q", i, exp, fields)
+		}
+	}
+	buf.Reset()
+	if err := Write(buf, nil, 0); err != nil || buf.String() != "no running queries\n" {
+		t.Errorf("expected no running queries, got: %q %v", buf.String(), err)
+	}
+}
+
+func TestKill(t *testing.T) {
+	tests := []struct {
+		driver, id string
+	}{
+		{"postgres", "1; DROP TABLE users"},
+		{"mysql", "abc"},
+		{"oracle", "12"},
+		{"clickhouse", "x' OR 1=1"},
+		{"csvq", "1"},
+	}
+	for i, test := range tests {
+		if err := Kill(context.Background(), &dburl.URL{Unaliased: test.driver}, nil, test.id); err == nil {
+			t.Errorf("test %d expected error, got nil", i)
+		}
+	}
+}
+
+func TestSplitIDs(t *testing.T) {
+	if ids, exp := splitIDs(" 1, 2,,3 "), []string{"1", "2", "3"}; !reflect.DeepEqual(ids, exp) {
+		t.Errorf("expected %v, got: %v", exp, ids)
+	}
+	if ids := splitIDs(""); ids != nil {
+		t.Errorf("expected no ids, got: %v", ids)
+	}
+}
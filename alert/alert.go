@@ -0,0 +1,187 @@
+// Package alert checks the thresholds of watched queries, such as
+// `value > 1000`, on the value of the first cell of their results.
+package alert
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Actions.
+const (
+	// ActionBell rings the terminal bell.
+	ActionBell = "bell"
+	// ActionExit stops watching the query, failing.
+	ActionExit = "exit"
+	// ActionWebhook posts a notification to the notifier.
+	ActionWebhook = "webhook"
+)
+
+// Alert is a threshold of a watched query and the action taken when the
+// value of the first cell of its results crosses it.
+type Alert struct {
+	// Op is the comparison operator: =, !=, <, <=, >, >=, or null and not
+	// null for the IS [NOT] NULL conditions.
+	Op string
+	// Operand is the value compared to, a number unless quoted.
+	Operand string
+	// Quoted is set when the operand was quoted, comparing strings.
+	Quoted bool
+	// Action is the action taken when the threshold is crossed.
+	Action string
+	// active is set while the value is past the threshold.
+	active bool
+}
+
+var (
+	// compareRE matches the comparison conditions.
+	compareRE = regexp.MustCompile(`(?i)^(?:value\s*)?(>=|<=|!=|<>|==|=|>|<)\s*('(?:[^']|'')*'|"[^"]*"|[^\s'"]+)(?:\s+(\w+))?$`)
+	// nullRE matches the IS [NOT] NULL conditions.
+	nullRE = regexp.MustCompile(`(?i)^(?:value\s+)?is\s+(not\s+)?null(?:\s+(\w+))?$`)
+)
+
+// Parse parses a threshold, `[value] OP OPERAND` or `[value] IS [NOT] NULL`,
+// optionally followed by its action, bell by default.
+func Parse(s string) (*Alert, error) {
+	s = strings.TrimSpace(s)
+	a := new(Alert)
+	var action string
+	if m := nullRE.FindStringSubmatch(s); m != nil {
+		a.Op, action = "null", m[2]
+		if m[1] != "" {
+			a.Op = "not null"
+		}
+	} else if m := compareRE.FindStringSubmatch(s); m != nil {
+		a.Op, a.Operand, action = m[1], m[2], m[3]
+		switch a.Op {
+		case "==":
+			a.Op = "="
+		case "<>":
+			a.Op = "!="
+		}
+		switch a.Operand[0] {
+		case '\'':
+			a.Operand, a.Quoted = strings.ReplaceAll(a.Operand[1:len(a.Operand)-1], "''", "'"), true
+		case '"':
+			a.Operand, a.Quoted = a.Operand[1:len(a.Operand)-1], true
+		default:
+			if _, err := strconv.ParseFloat(a.Operand, 64); err != nil {
+				return nil, fmt.Errorf("invalid threshold %q: %s is not a number, quote strings", s, a.Operand)
+			}
+		}
+	} else {
+		return nil, fmt.Errorf("invalid threshold %q: expected value OP OPERAND or value IS [NOT] NULL", s)
+	}
+	switch action = strings.ToLower(action); action {
+	case "":
+		a.Action = ActionBell
+	case ActionBell, ActionExit, ActionWebhook:
+		a.Action = action
+	default:
+		return nil, fmt.Errorf("invalid threshold action %q: expected bell, exit or webhook", action)
+	}
+	return a, nil
+}
+
+// String returns the condition of the threshold.
+func (a *Alert) String() string {
+	switch {
+	case a.Op == "null":
+		return "value IS NULL"
+	case a.Op == "not null":
+		return "value IS NOT NULL"
+	case a.Quoted:
+		return "value " + a.Op + " '" + strings.ReplaceAll(a.Operand, "'", "''") + "'"
+	}
+	return "value " + a.Op + " " + a.Operand
+}
+
+// Match returns true when the value is past the threshold. Numbers are
+// compared to the values read as numbers, and quoted strings to the text of
+// the values. NULL values only match IS NULL.
+func (a *Alert) Match(v interface{}) bool {
+	switch {
+	case a.Op == "null":
+		return v == nil
+	case a.Op == "not null":
+		return v != nil
+	case v == nil:
+		return false
+	case a.Quoted:
+		return compare(a.Op, strings.Compare(Format(v), a.Operand))
+	}
+	x, ok := number(v)
+	if !ok {
+		return false
+	}
+	y, _ := strconv.ParseFloat(a.Operand, 64)
+	switch {
+	case x < y:
+		return compare(a.Op, -1)
+	case x > y:
+		return compare(a.Op, 1)
+	}
+	return compare(a.Op, 0)
+}
+
+// Crossed returns true when the value crosses the threshold: it is past it,
+// and the previous checked value was not.
+func (a *Alert) Crossed(v interface{}) bool {
+	match := a.Match(v)
+	crossed := match && !a.active
+	a.active = match
+	return crossed
+}
+
+// compare returns true when the result of a comparison, -1, 0 or 1,
+// satisfies op.
+func compare(op string, c int) bool {
+	switch op {
+	case "=":
+		return c == 0
+	case "!=":
+		return c != 0
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// number returns the value as a number.
+func number(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case int64:
+		return float64(x), true
+	case float64:
+		return x, true
+	case bool:
+		if x {
+			return 1, true
+		}
+		return 0, true
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(Format(v)), 64)
+	return f, err == nil
+}
+
+// Format returns the text of the value.
+func Format(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,91 @@
+package alert
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s      string
+		exp    string
+		action string
+	}{
+		{"value > 1000", "value > 1000", ActionBell},
+		{"> 1000 exit", "value > 1000", ActionExit},
+		{"value<=-2.5 webhook", "value <= -2.5", ActionWebhook},
+		{"value == 0", "value = 0", ActionBell},
+		{"value <> 'it''s down' EXIT", "value != 'it''s down'", ActionExit},
+		{`value = "up"`, "value = 'up'", ActionBell},
+		{"value is null", "value IS NULL", ActionBell},
+		{"IS NOT NULL exit", "value IS NOT NULL", ActionExit},
+	}
+	for i, test := range tests {
+		a, err := Parse(test.s)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if s := a.String(); s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+		if a.Action != test.action {
+			t.Errorf("test %d expected action %q, got: %q", i, test.action, a.Action)
+		}
+	}
+	for i, s := range []string{"", "value", "value > ", "value > abc", "value > 1 beep", "value ~ 1"} {
+		if _, err := Parse(s); err == nil {
+			t.Errorf("test %d expected an error for %q", i, s)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		s   string
+		v   interface{}
+		exp bool
+	}{
+		{"value > 1000", int64(1001), true},
+		{"value > 1000", int64(1000), false},
+		{"value >= 1000", float64(1000), true},
+		{"value > 1000", []byte("1500.5"), true},
+		{"value > 1000", "n/a", false},
+		{"value > 1000", nil, false},
+		{"value != 0", int64(0), false},
+		{"value = 1", true, true},
+		{"value = 'down'", "down", true},
+		{"value = 'down'", []byte("up"), false},
+		{"value < 'b'", "a", true},
+		{"value = '2024-01-02T03:04:05Z'", time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), true},
+		{"value is null", nil, true},
+		{"value is not null", nil, false},
+		{"value is not null", int64(0), true},
+	}
+	for i, test := range tests {
+		a, err := Parse(test.s)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if b := a.Match(test.v); b != test.exp {
+			t.Errorf("test %d expected %t for %v, got: %t", i, test.exp, test.v, b)
+		}
+	}
+}
+
+func TestCrossed(t *testing.T) {
+	a, err := Parse("value > 10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []bool
+	for _, v := range []int64{5, 11, 12, 9, 20} {
+		got = append(got, a.Crossed(v))
+	}
+	exp := []bool{false, true, false, false, true}
+	for i := range exp {
+		if got[i] != exp[i] {
+			t.Errorf("expected %v, got: %v", exp, got)
+			break
+		}
+	}
+}
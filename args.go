@@ -5,8 +5,12 @@ import (
 	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/logging"
+	"github.com/xo/usql/pkg/config"
 	"github.com/xo/usql/text"
 )
 
@@ -26,14 +30,37 @@ type Args struct {
 	NoPassword        bool
 	NoRC              bool
 	SingleTransaction bool
+	DryRun            bool
 	Variables         []string
 	PVariables        []string
 
 	// Support for config file
 	ConfigFilePath string
+	EnvFile        string
 	DB             string
 	Role           string
 	List           bool
+	NoCache        bool
+	NoProgress     bool
+	MetricsListen  string
+	Unmask         bool
+	Yes            bool
+	Offline        bool
+	AppName        string
+	Record         string
+	Query          string
+	Params         []string
+	Notify         string
+	JSON           bool
+	Verbose        bool
+	Debug          bool
+
+	// configs is the config file, loaded on first use
+	configs *config.Store
+	// session is the named session resumed by usql repl --session
+	session *env.Session
+	// timeout is the maximum duration of the run of usql runfile
+	timeout time.Duration
 }
 
 func (args *Args) Next() (string, bool, error) {
@@ -66,6 +93,17 @@ func (c commandOrFile) IsCumulative() bool {
 	return true
 }
 
+// logLevel returns the logging level of the --verbose and --debug flags.
+func (args *Args) logLevel() logging.Level {
+	switch {
+	case args.Debug:
+		return logging.LevelDebug
+	case args.Verbose:
+		return logging.LevelVerbose
+	}
+	return logging.LevelDefault
+}
+
 // for populating args.PVariables with user-specified options
 type pset struct {
 	args *Args
@@ -104,19 +142,37 @@ func NewArgs() *Args {
 	kingpin.Flag("out", "output file").Short('o').StringVar(&args.Out)
 	kingpin.Flag("password", "force password prompt (should happen automatically)").Short('W').BoolVar(&args.ForcePassword)
 	kingpin.Flag("single-transaction", "execute as a single transaction (if non-interactive)").Short('1').BoolVar(&args.SingleTransaction)
+	kingpin.Flag("dry-run", "execute in a transaction rolled back at the end, reporting the rows the statements would affect (if non-interactive)").BoolVar(&args.DryRun)
 	kingpin.Flag("set", "set variable NAME to VALUE").Short('v').PlaceHolder(", --variable=NAME=VALUE").StringsVar(&args.Variables)
 
 	// Custom wrapper args for config file
 	kingpin.Flag("config", "Databases config yaml file path").PlaceHolder("/path/to/config.yaml").StringVar(&args.ConfigFilePath)
+	kingpin.Flag("env-file", "set the environment variables of the .env file, for the ${VAR} references of the config file").PlaceHolder(".env").StringVar(&args.EnvFile)
 	kingpin.Flag("db", "Database name to login. Should be present in config file").PlaceHolder("test").StringVar(&args.DB)
 	kingpin.Flag("role", "user role to use for logging into given DB").PlaceHolder("reader").StringVar(&args.Role)
 	kingpin.Flag("list", "List available databases from config").BoolVar(&args.List)
+	kingpin.Flag("no-cache", "Do not cache query results, even when cache_ttl is set in config").BoolVar(&args.NoCache)
+	kingpin.Flag("no-progress", "do not report the progress of imports, exports and copies to stderr").BoolVar(&args.NoProgress)
+	kingpin.Flag("unmask", "Show the values of the columns masked by mask_columns in config, when allowed for the role").BoolVar(&args.Unmask)
+	kingpin.Flag("yes", "execute the statements of the confirm policy in config without asking to confirm them").Short('y').BoolVar(&args.Yes)
+	kingpin.Flag("offline", "describe and complete the objects of the --db database from its last metadata snapshot (see usql offline sync), without connecting, refusing the statements").BoolVar(&args.Offline)
+	kingpin.Flag("application-name", "application name of the connections, shown in the monitoring of the server (default usql/VERSION ALIAS, or application_name in config)").PlaceHolder("NAME").StringVar(&args.AppName)
+	kingpin.Flag("record", "record the executed statements, their results and timing to a session file (see usql replay)").PlaceHolder("session.json").StringVar(&args.Record)
+	kingpin.Flag("query", "execute query template NAME from config and exit").PlaceHolder("NAME").StringVar(&args.Query)
+	kingpin.Flag("param", "set query template parameter NAME to VALUE, or bind VALUE to the next parameter ($1, ? or :1) of the --command statements").PlaceHolder("NAME=VALUE").StringsVar(&args.Params)
+	kingpin.Flag("notify", "notify notifier NAME from config when a long-running statement finishes").PlaceHolder("NAME").StringVar(&args.Notify)
+	kingpin.Flag("verbose", "log the config file and the resolved database, role and DSN to stderr").BoolVar(&args.Verbose)
+	kingpin.Flag("debug", "log the config file discovery and the statements sent to the driver too, implies --verbose").BoolVar(&args.Debug)
+	kingpin.Flag("metrics-listen", "address to serve Prometheus metrics on, at /metrics").PlaceHolder(":9100").StringVar(&args.MetricsListen)
 
 	// pset
 	kingpin.Flag("pset", `set printing option VAR to ARG (see \pset command)`).Short('P').PlaceHolder("VAR[=ARG]").StringsVar(&args.PVariables)
 	// pset flags
 	kingpin.Flag("field-separator", `field separator for unaligned and CSV output (default "|" and ",")`).Short('F').SetValue(pset{args, []string{"fieldsep=%q", "csv_fieldsep=%q"}})
 	kingpin.Flag("record-separator", `record separator for unaligned and CSV output (default \n)`).Short('R').SetValue(pset{args, []string{"recordsep=%q"}})
+	kingpin.Flag("format", "set output format (see \\pset format)").PlaceHolder("FORMAT").SetValue(pset{args, []string{"format=%q"}})
+	kingpin.Flag("table", "table of the INSERT statements of the inserts output format (see \\pset insert_table)").PlaceHolder("NAME").SetValue(pset{args, []string{"insert_table=%q"}})
+	kingpin.Flag("max-col-width", "truncate the values of the aligned and vertical formats wider than N (see \\pset max_col_width)").PlaceHolder("N").SetValue(pset{args, []string{"max_col_width=%q"}})
 	kingpin.Flag("table-attr", "set HTML table tag attributes (e.g., width, border)").Short('T').SetValue(pset{args, []string{"tableattr=%q"}})
 	type psetconfig struct {
 		long  string
@@ -134,7 +190,6 @@ func NewArgs() *Args {
 		pc("expanded", 'x', "turn on expanded table output", "expanded=on"),
 		pc("field-separator-zero", 'z', "set field separator for unaligned and CSV output to zero byte", "fieldsep_zero=on"),
 		pc("record-separator-zero", '0', "set record separator for unaligned and CSV output to zero byte", "recordsep_zero=on"),
-		pc("json", 'J', "JSON output mode", "format=json"),
 		pc("csv", 'C', "CSV output mode", "format=csv"),
 		pc("vertical", 'G', "vertical output mode", "format=vertical"),
 	} {
@@ -146,10 +201,20 @@ func NewArgs() *Args {
 			return nil
 		}).Bool()
 	}
+	kingpin.Flag("json", "JSON output mode, also of --list, the errors and the results of statements not returning rows").Short('J').PreAction(func(*kingpin.ParseContext) error {
+		args.JSON = true
+		args.PVariables = append(args.PVariables, "format=json")
+		return nil
+	}).Bool()
 	kingpin.Flag("quiet", "run quietly (no messages, only query output)").Short('q').PreAction(func(*kingpin.ParseContext) error {
 		args.Variables = append(args.Variables, "QUIET=on")
 		return nil
 	}).Bool()
+	kingpin.Flag("no-color", "disable colored output (syntax highlighting, explain plans, color themes)").PreAction(func(*kingpin.ParseContext) error {
+		os.Setenv("NO_COLOR", "1")
+		args.Variables = append(args.Variables, "SYNTAX_HL=false")
+		return nil
+	}).Bool()
 	// add --set as a hidden alias for --variable
 	kingpin.Flag("variable", "set variable NAME to VALUE").Hidden().StringsVar(&args.Variables)
 	// add --version flag
@@ -162,5 +227,6 @@ func NewArgs() *Args {
 	kingpin.HelpFlag.Short('h').Hidden()
 	// parse
 	kingpin.Parse()
+	args.configs = newConfigStore(args.ConfigFilePath)
 	return args
 }
@@ -0,0 +1,97 @@
+// Package audit writes a structured log of executed statements.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Syslog is the path of the audit log writing to the system logger.
+const Syslog = "syslog"
+
+// Entry is an audit log entry.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	OSUser string    `json:"os_user"`
+	Alias  string    `json:"alias,omitempty"`
+	Role   string    `json:"role,omitempty"`
+	// URL is the redacted URL of the database.
+	URL           string `json:"url"`
+	StatementHash string `json:"statement_hash"`
+	// Statement is the full text of the statement, when logged.
+	Statement string  `json:"statement,omitempty"`
+	Duration  float64 `json:"duration_ms"`
+	// Rows is the number of rows returned or affected, when known.
+	Rows  *int64 `json:"rows,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// Logger writes entries as JSON lines. A Logger is safe for concurrent use.
+type Logger struct {
+	mu sync.Mutex
+	w  io.WriteCloser
+	// full is set when the full text of statements is logged
+	full bool
+}
+
+// Open opens the audit log at path, appending to the file, or writing to the
+// system logger when path is Syslog. When full is set, the full text of the
+// statements is logged in addition to their hash.
+func Open(path string, full bool) (*Logger, error) {
+	var w io.WriteCloser
+	var err error
+	if path == Syslog {
+		w, err = openSyslog()
+	} else {
+		w, err = os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return New(w, full), nil
+}
+
+// New creates a logger writing to w.
+func New(w io.WriteCloser, full bool) *Logger {
+	return &Logger{w: w, full: full}
+}
+
+// Log writes the entry for the statement, setting its hash and, when
+// logging full statements, its text.
+func (l *Logger) Log(e Entry, sqlstr string) error {
+	sqlstr = strings.TrimSpace(sqlstr)
+	e.StatementHash = Hash(sqlstr)
+	if l.full {
+		e.Statement = sqlstr
+	}
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	_, err = l.w.Write(append(buf, '\n'))
+	return err
+}
+
+// Close closes the log.
+func (l *Logger) Close() error {
+	return l.w.Close()
+}
+
+// Hash returns the hash of a statement.
+func Hash(sqlstr string) string {
+	h := sha256.Sum256([]byte(sqlstr))
+	return hex.EncodeToString(h[:])
+}
+
+// Duration returns d in milliseconds, as logged.
+func Duration(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
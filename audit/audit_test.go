@@ -0,0 +1,77 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type nopCloser struct {
+	bytes.Buffer
+}
+
+func (*nopCloser) Close() error {
+	return nil
+}
+
+func TestLog(t *testing.T) {
+	tests := []struct {
+		full bool
+		exp  string
+	}{
+		{false, ""},
+		{true, "select 1"},
+	}
+	for i, test := range tests {
+		w := new(nopCloser)
+		l := New(w, test.full)
+		rows := int64(1)
+		e := Entry{Time: time.Now(), OSUser: "alice", Alias: "db", URL: "pg://u@h/db", Duration: Duration(1500 * time.Microsecond), Rows: &rows}
+		if err := l.Log(e, "  select 1\n"); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if !strings.HasSuffix(w.String(), "}\n") {
+			t.Errorf("test %d expected a JSON line, got: %q", i, w.String())
+		}
+		var res Entry
+		if err := json.Unmarshal(w.Bytes(), &res); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if res.Statement != test.exp {
+			t.Errorf("test %d expected statement %q, got: %q", i, test.exp, res.Statement)
+		}
+		if res.StatementHash != Hash("select 1") {
+			t.Errorf("test %d expected hash of trimmed statement, got: %s", i, res.StatementHash)
+		}
+		if res.Duration != 1.5 || res.Rows == nil || *res.Rows != 1 {
+			t.Errorf("test %d expected duration 1.5 and 1 row, got: %v %v", i, res.Duration, res.Rows)
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	for i := 0; i < 2; i++ {
+		l, err := Open(path, false)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if err := l.Log(Entry{Error: "boom"}, "drop table t"); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if err := l.Close(); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := strings.Count(string(buf), "\n"); n != 2 {
+		t.Errorf("expected 2 appended lines, got: %d", n)
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows && !plan9
+
+package audit
+
+import (
+	"io"
+	"log/syslog"
+)
+
+// openSyslog opens the system logger.
+func openSyslog() (io.WriteCloser, error) {
+	return syslog.New(syslog.LOG_INFO|syslog.LOG_AUTH, "usql")
+}
@@ -0,0 +1,13 @@
+//go:build windows || plan9
+
+package audit
+
+import (
+	"errors"
+	"io"
+)
+
+// openSyslog returns an error, as there is no system logger.
+func openSyslog() (io.WriteCloser, error) {
+	return nil, errors.New("syslog audit log is not supported on this platform")
+}
@@ -0,0 +1,228 @@
+// Package bench measures the latency, throughput and error rate of
+// workloads of statements executed concurrently against a database.
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+// Options are the options of a benchmark.
+type Options struct {
+	// Iterations is the number of statements executed, when not 0.
+	Iterations int
+	// Duration is how long statements are executed, when not 0.
+	Duration time.Duration
+	// Concurrency is the number of concurrent connections executing the
+	// statements.
+	Concurrency int
+}
+
+// Stats are the statistics of the executions of statements.
+type Stats struct {
+	// Name is the name of the statement.
+	Name string
+	// Latencies are the sorted latencies of the executions, including the
+	// failed ones.
+	Latencies []time.Duration
+	// Errors is the number of failed executions.
+	Errors int
+	// Err is the first error.
+	Err error
+}
+
+// Result is the result of a benchmark.
+type Result struct {
+	// Stats are the statistics of all the executions.
+	Stats
+	// Concurrency is the number of concurrent connections.
+	Concurrency int
+	// Duration is the wall time of the benchmark.
+	Duration time.Duration
+	// Statements are the statistics of the statements of the workload.
+	Statements []*Stats
+}
+
+// Run executes the statements of the workload, picked in proportion to their
+// weight, on opts.Concurrency concurrent connections, until opts.Iterations
+// statements were executed, opts.Duration elapsed or the context is done.
+// Queries are executed until all their rows are read.
+func Run(ctx context.Context, u *dburl.URL, db *sql.DB, w *Workload, opts Options) (*Result, error) {
+	switch {
+	case opts.Iterations < 0 || opts.Duration < 0 || opts.Iterations == 0 && opts.Duration == 0:
+		return nil, fmt.Errorf("invalid iterations %d and duration %s", opts.Iterations, opts.Duration)
+	case opts.Concurrency <= 0:
+		return nil, fmt.Errorf("invalid concurrency %d", opts.Concurrency)
+	}
+	if err := w.prepare(u); err != nil {
+		return nil, err
+	}
+	// keep the connections open between executions
+	db.SetMaxIdleConns(opts.Concurrency)
+	res := &Result{Concurrency: opts.Concurrency}
+	for _, s := range w.Statements {
+		res.Statements = append(res.Statements, &Stats{Name: s.name()})
+	}
+	p := newPicker(w)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	count := 0
+	start := time.Now()
+	end := start.Add(opts.Duration)
+	for i := 0; i < opts.Concurrency; i++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			for {
+				mu.Lock()
+				done := opts.Iterations != 0 && count >= opts.Iterations
+				count++
+				mu.Unlock()
+				if done || opts.Duration != 0 && !time.Now().Before(end) || ctx.Err() != nil {
+					return
+				}
+				i := p.pick(r)
+				s := w.Statements[i]
+				t := time.Now()
+				err := execute(ctx, db, s.sqlstr, s.isQuery, s.args(r))
+				d := time.Since(t)
+				// executions interrupted by the context are not counted
+				if ctx.Err() != nil {
+					return
+				}
+				mu.Lock()
+				res.Statements[i].add(d, drivers.WrapErr(u.Driver, err))
+				mu.Unlock()
+			}
+		}(rand.New(rand.NewSource(start.UnixNano() + int64(i))))
+	}
+	wg.Wait()
+	res.Duration = time.Since(start)
+	for _, st := range res.Statements {
+		res.Latencies = append(res.Latencies, st.Latencies...)
+		res.Errors += st.Errors
+		if res.Err == nil {
+			res.Err = st.Err
+		}
+		st.sort()
+	}
+	res.sort()
+	return res, nil
+}
+
+// execute executes the statement, reading all the rows of queries.
+func execute(ctx context.Context, db *sql.DB, sqlstr string, isQuery bool, args []interface{}) error {
+	if !isQuery {
+		_, err := db.ExecContext(ctx, sqlstr, args...)
+		return err
+	}
+	rows, err := db.QueryContext(ctx, sqlstr, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	for rows.Next() {
+	}
+	return rows.Err()
+}
+
+// add adds an execution.
+func (st *Stats) add(d time.Duration, err error) {
+	st.Latencies = append(st.Latencies, d)
+	if err != nil {
+		if st.Errors++; st.Err == nil {
+			st.Err = err
+		}
+	}
+}
+
+// sort sorts the latencies.
+func (st *Stats) sort() {
+	sort.Slice(st.Latencies, func(i, j int) bool {
+		return st.Latencies[i] < st.Latencies[j]
+	})
+}
+
+// Iterations returns the number of executions.
+func (st *Stats) Iterations() int {
+	return len(st.Latencies)
+}
+
+// Percentile returns the p'th (0-100) percentile latency, using the nearest
+// rank.
+func (st *Stats) Percentile(p float64) time.Duration {
+	if len(st.Latencies) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(st.Latencies)))) - 1
+	if i < 0 {
+		i = 0
+	}
+	return st.Latencies[i]
+}
+
+// Throughput returns the number of executions per second.
+func (r *Result) Throughput() float64 {
+	if r.Duration <= 0 {
+		return 0
+	}
+	return float64(len(r.Latencies)) / r.Duration.Seconds()
+}
+
+// ErrorRate returns the percentage of failed executions.
+func (st *Stats) ErrorRate() float64 {
+	if len(st.Latencies) == 0 {
+		return 0
+	}
+	return 100 * float64(st.Errors) / float64(len(st.Latencies))
+}
+
+// Report writes the report of the result to w, with the statistics of each
+// statement of workloads of several statements.
+func (r *Result) Report(w io.Writer) {
+	fmt.Fprintf(w, "iterations:  %d\n", r.Iterations())
+	fmt.Fprintf(w, "concurrency: %d\n", r.Concurrency)
+	fmt.Fprintf(w, "duration:    %s\n", round(r.Duration))
+	fmt.Fprintf(w, "throughput:  %.2f/s\n", r.Throughput())
+	r.Stats.report(w, "")
+	if len(r.Statements) < 2 {
+		return
+	}
+	for _, st := range r.Statements {
+		fmt.Fprintf(w, "\n%s:\n", st.Name)
+		fmt.Fprintf(w, "  iterations:  %d (%.2f%%)\n", st.Iterations(), 100*float64(st.Iterations())/float64(r.Iterations()))
+		st.report(w, "  ")
+	}
+}
+
+// report writes the errors and latencies of the statistics to w.
+func (st *Stats) report(w io.Writer, indent string) {
+	fmt.Fprintf(w, "%serrors:      %d (%.2f%%)\n", indent, st.Errors, st.ErrorRate())
+	fmt.Fprintf(w, "%slatency:     min %s, p50 %s, p95 %s, p99 %s, max %s\n", indent,
+		round(st.Percentile(0)), round(st.Percentile(50)), round(st.Percentile(95)),
+		round(st.Percentile(99)), round(st.Percentile(100)))
+	if st.Err != nil {
+		fmt.Fprintf(w, "%sfirst error: %v\n", indent, st.Err)
+	}
+}
+
+// round rounds d for display.
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	}
+	return d
+}
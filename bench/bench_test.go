@@ -0,0 +1,156 @@
+package bench
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+)
+
+func TestRun(t *testing.T) {
+	u, db := open(t)
+	tests := []struct {
+		sqlstr string
+		errors int
+	}{
+		{"select id, name from t", 0},
+		{"update t set name = 'c' where id = 1", 0},
+		{"select * from missing", 20},
+	}
+	for i, test := range tests {
+		res, err := Run(context.Background(), u, db, NewWorkload(test.sqlstr), Options{Iterations: 20, Concurrency: 4})
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if n := res.Iterations(); n != 20 {
+			t.Errorf("test %d expected 20 iterations, got: %d", i, n)
+		}
+		if res.Errors != test.errors {
+			t.Errorf("test %d expected %d errors, got: %d (%v)", i, test.errors, res.Errors, res.Err)
+		}
+		if res.Percentile(50) > res.Percentile(99) || res.Throughput() <= 0 {
+			t.Errorf("test %d expected ordered latencies and a throughput, got: %v, %f", i, res.Latencies, res.Throughput())
+		}
+	}
+	if _, err := Run(context.Background(), u, db, NewWorkload("select 1"), Options{Iterations: 1}); err == nil || err.Error() != "invalid concurrency 0" {
+		t.Errorf("expected invalid concurrency error, got: %v", err)
+	}
+}
+
+func TestWorkload(t *testing.T) {
+	u, db := open(t)
+	path := filepath.Join(t.TempDir(), "workload.yaml")
+	src := `statements:
+  - name: lookup
+    weight: 3
+    sql: select * from t where id = {{.id}} or id = {{.id}} + 1
+    params:
+      id: {type: int, min: 1, max: 2}
+  - name: insert
+    sql: insert into t (id, name) values ({{.id}}, {{.name}})
+    params:
+      id: {type: sequence, min: 100}
+      name: {type: choice, values: [x, y]}
+`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	w, err := LoadWorkload(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	res, err := Run(context.Background(), u, db, w, Options{Duration: 100 * time.Millisecond, Concurrency: 2})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if res.Errors != 0 {
+		t.Fatalf("expected no errors, got: %d (%v)", res.Errors, res.Err)
+	}
+	lookups, inserts := res.Statements[0].Iterations(), res.Statements[1].Iterations()
+	if lookups+inserts != res.Iterations() || lookups < inserts {
+		t.Errorf("expected about 3 lookups per insert, got: %d lookups, %d inserts", lookups, inserts)
+	}
+	var n int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM t WHERE id >= 100 AND name IN ('x', 'y')`).Scan(&n); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n != inserts {
+		t.Errorf("expected %d inserted rows, got: %d", inserts, n)
+	}
+}
+
+func TestWorkloadError(t *testing.T) {
+	u, _ := open(t)
+	tests := []struct {
+		s   *Statement
+		exp string
+	}{
+		{&Statement{SQL: "select {{.id}}"}, `statement select {{.id}}: no generator for parameter "id"`},
+		{&Statement{Name: "a", SQL: "select {{.id}}", Params: map[string]*Generator{"id": {Type: "uuid"}}}, `statement a: parameter "id": invalid generator type "uuid"`},
+		{&Statement{Name: "a", SQL: "select {{.id}}", Params: map[string]*Generator{"id": {Type: "int", Min: 2, Max: 1}}}, `statement a: parameter "id": max 1 is less than min 2`},
+		{&Statement{Name: "a", SQL: "select 1", Weight: -1}, `statement a: invalid weight -1`},
+	}
+	for i, test := range tests {
+		w := &Workload{Statements: []*Statement{test.s}}
+		if err := w.prepare(u); err == nil || err.Error() != test.exp {
+			t.Errorf("test %d expected error %q, got: %v", i, test.exp, err)
+		}
+	}
+}
+
+func TestResult(t *testing.T) {
+	res := &Result{Concurrency: 2, Duration: 2 * time.Second, Stats: Stats{Errors: 1}}
+	for i := 1; i <= 10; i++ {
+		res.Latencies = append(res.Latencies, time.Duration(i)*time.Millisecond)
+	}
+	tests := []struct {
+		p   float64
+		exp time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 5 * time.Millisecond},
+		{95, 10 * time.Millisecond},
+		{100, 10 * time.Millisecond},
+	}
+	for i, test := range tests {
+		if d := res.Percentile(test.p); d != test.exp {
+			t.Errorf("test %d expected p%v %s, got: %s", i, test.p, test.exp, d)
+		}
+	}
+	var sb strings.Builder
+	res.Report(&sb)
+	exp := `iterations:  10
+concurrency: 2
+duration:    2s
+throughput:  5.00/s
+errors:      1 (10.00%)
+latency:     min 1ms, p50 5ms, p95 10ms, p99 10ms, max 10ms
+`
+	if s := sb.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+}
+
+// open opens a test database.
+func open(t *testing.T) (*dburl.URL, *sql.DB) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	u, err := dburl.Parse("sqlite3:" + path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER, name TEXT); INSERT INTO t VALUES (1, 'a'), (2, 'b')`); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	return u, db
+}
@@ -0,0 +1,243 @@
+package bench
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/stmt"
+	"gopkg.in/yaml.v2"
+)
+
+// Workload is a mix of statements, executed in proportion to their weight.
+type Workload struct {
+	// Path is the absolute path of the workload file, when loaded from one.
+	Path string `yaml:"-"`
+	// Statements are the statements of the workload.
+	Statements []*Statement `yaml:"statements"`
+}
+
+// Statement is a statement of a workload, with {{.NAME}} parameters
+// generated for every execution.
+type Statement struct {
+	// Name is the name of the statement in reports, the statement itself
+	// when empty.
+	Name string `yaml:"name"`
+	// Weight is the weight of the statement in the workload, 1 when 0.
+	Weight int `yaml:"weight"`
+	// SQL is the statement, with {{.NAME}} parameters.
+	SQL string `yaml:"sql"`
+	// Params are the generators of the parameters, by name.
+	Params map[string]*Generator `yaml:"params"`
+
+	// sqlstr is the processed statement, with placeholders
+	sqlstr string
+	// names are the names of the parameters of the placeholders
+	names   []string
+	isQuery bool
+}
+
+// Generator generates the values of a parameter.
+type Generator struct {
+	// Type is the type of the generator:
+	//
+	//	int       a random integer between min and max
+	//	float     a random float between min and max
+	//	string    a random alphanumeric string of length characters (default 8)
+	//	choice    a random value of values
+	//	sequence  an increasing integer, starting at min
+	//	timestamp the current time
+	Type   string   `yaml:"type"`
+	Min    float64  `yaml:"min"`
+	Max    float64  `yaml:"max"`
+	Length int      `yaml:"length"`
+	Values []string `yaml:"values"`
+
+	seq int64
+}
+
+// NewWorkload creates a workload of a single statement.
+func NewWorkload(sqlstr string) *Workload {
+	return &Workload{Statements: []*Statement{{SQL: sqlstr}}}
+}
+
+// LoadWorkload loads the workload file at path.
+func LoadWorkload(path string) (*Workload, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	w := &Workload{Path: path}
+	if err := yaml.Unmarshal(buf, w); err != nil {
+		return nil, fmt.Errorf("invalid workload file %s: %w", path, err)
+	}
+	if len(w.Statements) == 0 {
+		return nil, fmt.Errorf("no statements in workload file %s", path)
+	}
+	return w, nil
+}
+
+// prepare validates the statements of the workload, and processes them for
+// the database.
+func (w *Workload) prepare(u *dburl.URL) error {
+	for i, s := range w.Statements {
+		if s == nil || s.SQL == "" {
+			return fmt.Errorf("statement %d: sql is required", i+1)
+		}
+		if err := s.prepare(u); err != nil {
+			return fmt.Errorf("statement %s: %w", s.name(), err)
+		}
+	}
+	return nil
+}
+
+// prepare validates the statement and its generators, replacing its
+// parameters with placeholders.
+func (s *Statement) prepare(u *dburl.URL) error {
+	switch {
+	case s.Weight < 0:
+		return fmt.Errorf("invalid weight %d", s.Weight)
+	case s.Weight == 0:
+		s.Weight = 1
+	}
+	// render the parameters as their names, to get the names of the
+	// placeholders
+	q := &config.QueryConfig{SQL: s.SQL}
+	names, err := q.ParamNames()
+	if err != nil {
+		return err
+	}
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		g := s.Params[name]
+		if g == nil {
+			return fmt.Errorf("no generator for parameter %q", name)
+		}
+		if err := g.validate(); err != nil {
+			return fmt.Errorf("parameter %q: %w", name, err)
+		}
+		values[name] = name
+	}
+	sqlstr, args, err := q.Render(values, func(n int) string {
+		return drivers.Placeholder(u, n)
+	})
+	if err != nil {
+		return err
+	}
+	s.names = make([]string, len(args))
+	for i, arg := range args {
+		s.names[i] = arg.(string)
+	}
+	_, s.sqlstr, s.isQuery, err = drivers.Process(u, stmt.FindPrefix(sqlstr, true, true, true), sqlstr)
+	return err
+}
+
+// name returns the name of the statement.
+func (s *Statement) name() string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return s.SQL
+}
+
+// args generates the values of the parameters of an execution.
+func (s *Statement) args(r *rand.Rand) []interface{} {
+	if len(s.names) == 0 {
+		return nil
+	}
+	values := make(map[string]interface{}, len(s.Params))
+	args := make([]interface{}, len(s.names))
+	for i, name := range s.names {
+		v, ok := values[name]
+		if !ok {
+			v = s.Params[name].Generate(r)
+			values[name] = v
+		}
+		args[i] = v
+	}
+	return args
+}
+
+// validate validates the generator.
+func (g *Generator) validate() error {
+	switch g.Type {
+	case "int", "float":
+		if g.Max < g.Min {
+			return fmt.Errorf("max %v is less than min %v", g.Max, g.Min)
+		}
+	case "choice":
+		if len(g.Values) == 0 {
+			return fmt.Errorf("values are required")
+		}
+	case "string", "sequence", "timestamp":
+	default:
+		return fmt.Errorf("invalid generator type %q", g.Type)
+	}
+	return nil
+}
+
+// Generate generates a value. It's safe for concurrent use, when each
+// goroutine uses its own r.
+func (g *Generator) Generate(r *rand.Rand) interface{} {
+	switch g.Type {
+	case "int":
+		return int64(g.Min) + r.Int63n(int64(g.Max)-int64(g.Min)+1)
+	case "float":
+		return g.Min + r.Float64()*(g.Max-g.Min)
+	case "string":
+		n := g.Length
+		if n <= 0 {
+			n = 8
+		}
+		const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+		b := make([]byte, n)
+		for i := range b {
+			b[i] = chars[r.Intn(len(chars))]
+		}
+		return string(b)
+	case "choice":
+		return g.Values[r.Intn(len(g.Values))]
+	case "sequence":
+		return int64(g.Min) + atomic.AddInt64(&g.seq, 1) - 1
+	case "timestamp":
+		return time.Now()
+	}
+	panic("invalid generator type " + strconv.Quote(g.Type))
+}
+
+// picker picks the statements of a workload in proportion to their weight.
+type picker struct {
+	w     *Workload
+	total int
+}
+
+// newPicker creates a picker of the statements of the workload.
+func newPicker(w *Workload) *picker {
+	p := &picker{w: w}
+	for _, s := range w.Statements {
+		p.total += s.Weight
+	}
+	return p
+}
+
+// pick returns the index of a random statement.
+func (p *picker) pick(r *rand.Rand) int {
+	n := r.Intn(p.total)
+	for i, s := range p.w.Statements {
+		if n -= s.Weight; n < 0 {
+			return i
+		}
+	}
+	return len(p.w.Statements) - 1
+}
@@ -0,0 +1,184 @@
+// Package blob summarizes the binary values of result set columns, such as
+// bytea or BLOB columns, or writes them to files, instead of printing them.
+package blob
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xo/tblfmt"
+)
+
+// Binary returns true when the database type name of a column is a binary
+// type.
+func Binary(typ string) bool {
+	switch typ = strings.ToUpper(typ); typ {
+	case "BYTEA", "BINARY", "VARBINARY", "IMAGE", "RAW", "LONG RAW", "BYTES", "BLOB":
+		return true
+	}
+	return strings.HasSuffix(typ, "BLOB")
+}
+
+// Summary returns the summary of a binary value: its size and the prefix of
+// its SHA-256 checksum.
+func Summary(b []byte) string {
+	return fmt.Sprintf("<%s, sha256:%x>", Size(len(b)), Sum(b)[:8])
+}
+
+// Sum returns the SHA-256 checksum of a binary value.
+func Sum(b []byte) []byte {
+	sum := sha256.Sum256(b)
+	return sum[:]
+}
+
+// Size returns a size in bytes, in a human-readable form.
+func Size(n int) string {
+	switch {
+	case n < 1<<10:
+		return fmt.Sprintf("%d bytes", n)
+	case n < 1<<20:
+		return fmt.Sprintf("%.1f kB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+}
+
+// ResultSet wraps a result set, replacing the values of its binary columns
+// by their summaries, or by the paths of the files they are written to.
+type ResultSet struct {
+	tblfmt.ResultSet
+	// dir is the directory of the files of the values, when written to
+	// files.
+	dir   string
+	files bool
+	cols  []string
+	// binary are the binary columns of the current result set, nil when
+	// unknown, then detected by their values.
+	binary []bool
+	row    int
+}
+
+// New wraps the result set, replacing the values of its binary columns by
+// their summaries, or writing them to files in dir when files is true.
+func New(resultSet tblfmt.ResultSet, dir string, files bool) *ResultSet {
+	return &ResultSet{ResultSet: resultSet, dir: dir, files: files}
+}
+
+// Columns returns the column names.
+func (rs *ResultSet) Columns() ([]string, error) {
+	cols, err := rs.ResultSet.Columns()
+	if err != nil {
+		return nil, err
+	}
+	rs.cols, rs.binary = cols, nil
+	if types, err := rs.ColumnTypes(); err == nil && len(types) == len(cols) {
+		rs.binary = make([]bool, len(cols))
+		for i, typ := range types {
+			rs.binary[i] = Binary(typ.DatabaseTypeName())
+		}
+	}
+	return cols, nil
+}
+
+// ColumnTypes returns the column types of the wrapped result set.
+func (rs *ResultSet) ColumnTypes() ([]*sql.ColumnType, error) {
+	z, ok := rs.ResultSet.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+	if !ok {
+		return nil, tblfmt.ErrResultSetHasNoColumnTypes
+	}
+	return z.ColumnTypes()
+}
+
+// Next advances to the next row.
+func (rs *ResultSet) Next() bool {
+	if !rs.ResultSet.Next() {
+		return false
+	}
+	rs.row++
+	return true
+}
+
+// Scan scans the values of the current row to dest, replacing the values of
+// the binary columns.
+func (rs *ResultSet) Scan(dest ...interface{}) error {
+	if err := rs.ResultSet.Scan(dest...); err != nil {
+		return err
+	}
+	if rs.cols == nil {
+		if _, err := rs.Columns(); err != nil {
+			return err
+		}
+	}
+	for i, d := range dest {
+		switch p := d.(type) {
+		case *interface{}:
+			if b, ok := (*p).([]byte); ok && rs.isBinary(i, b) {
+				s, err := rs.replace(i, b)
+				if err != nil {
+					return err
+				}
+				*p = s
+			}
+		case *[]byte:
+			if *p != nil && rs.isBinary(i, *p) {
+				s, err := rs.replace(i, *p)
+				if err != nil {
+					return err
+				}
+				*p = []byte(s)
+			}
+		}
+	}
+	return nil
+}
+
+// isBinary returns true when column i is binary, or when its type is unknown
+// and its value b is not text.
+func (rs *ResultSet) isBinary(i int, b []byte) bool {
+	if rs.binary != nil {
+		return i < len(rs.binary) && rs.binary[i]
+	}
+	return !utf8.Valid(b)
+}
+
+// replace returns the replacement of the value b of column i: its summary, or
+// the path of the file it's written to, followed by its summary.
+func (rs *ResultSet) replace(i int, b []byte) (string, error) {
+	if !rs.files {
+		return Summary(b), nil
+	}
+	name := fmt.Sprintf("%s-%d.bin", fileName(rs.cols, i), rs.row)
+	path := filepath.Join(rs.dir, name)
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return "", err
+	}
+	return path + " " + Summary(b), nil
+}
+
+// NextResultSet prepares the next result set.
+func (rs *ResultSet) NextResultSet() bool {
+	rs.cols, rs.binary, rs.row = nil, nil, 0
+	return rs.ResultSet.NextResultSet()
+}
+
+// unsafeRE matches the characters of column names not kept in file names.
+var unsafeRE = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// fileName returns the file name of the values of column i.
+func fileName(cols []string, i int) string {
+	var name string
+	if i < len(cols) {
+		name = strings.Trim(unsafeRE.ReplaceAllString(cols[i], "_"), "._")
+	}
+	if name == "" {
+		name = fmt.Sprintf("column%d", i+1)
+	}
+	return name
+}
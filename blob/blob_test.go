@@ -0,0 +1,108 @@
+package blob
+
+import (
+	"bytes"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestBinary(t *testing.T) {
+	tests := []struct {
+		typ string
+		exp bool
+	}{
+		{"BYTEA", true},
+		{"bytea", true},
+		{"BLOB", true},
+		{"LONGBLOB", true},
+		{"VARBINARY", true},
+		{"IMAGE", true},
+		{"TEXT", false},
+		{"VARCHAR", false},
+		{"", false},
+	}
+	for i, test := range tests {
+		if ok := Binary(test.typ); ok != test.exp {
+			t.Errorf("test %d expected %q to be binary %t, got: %t", i, test.typ, test.exp, ok)
+		}
+	}
+}
+
+func TestSummary(t *testing.T) {
+	tests := []struct {
+		n   int
+		exp string
+	}{
+		{0, "<0 bytes, sha256:e3b0c44298fc1c14>"},
+		{3, "<3 bytes, sha256:"},
+		{2048, "<2.0 kB, sha256:"},
+		{3 << 20, "<3.0 MB, sha256:"},
+	}
+	for i, test := range tests {
+		if s := Summary(make([]byte, test.n)); !strings.HasPrefix(s, test.exp) {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+	}
+}
+
+func TestResultSet(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE files (name TEXT, content BLOB)`); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	content := []byte{0x89, 'P', 'N', 'G', 0}
+	if _, err := db.Exec(`INSERT INTO files VALUES ('a.png', ?)`, content); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, files := range []bool{false, true} {
+		dir := t.TempDir()
+		rows, err := db.Query(`SELECT name, content FROM files`)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		rs := New(rows, dir, files)
+		cols, err := rs.Columns()
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if !rs.Next() {
+			t.Fatalf("expected a row, got: %v", rs.Err())
+		}
+		row := make([]interface{}, len(cols))
+		for i := range row {
+			row[i] = new(interface{})
+		}
+		if err := rs.Scan(row...); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		rows.Close()
+		name, value := *row[0].(*interface{}), *row[1].(*interface{})
+		if s, ok := name.(string); !ok || s != "a.png" {
+			t.Errorf("expected name to be kept, got: %v", name)
+		}
+		exp := Summary(content)
+		if files {
+			path := filepath.Join(dir, "content-1.bin")
+			exp = path + " " + exp
+			buf, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if !bytes.Equal(buf, content) {
+				t.Errorf("expected file to contain %v, got: %v", content, buf)
+			}
+		}
+		if value != exp {
+			t.Errorf("expected %q, got: %v", exp, value)
+		}
+	}
+}
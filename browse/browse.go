@@ -0,0 +1,524 @@
+// Package browse browses the rows of a table in an interactive grid, read a
+// page at a time with keyset pagination on the primary key of the table.
+package browse
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/display"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/export"
+)
+
+// DefaultMaxWidth is the default maximum width of the columns.
+const DefaultMaxWidth = 30
+
+// Options are the browser options.
+type Options struct {
+	// Null is the string NULL values are shown as.
+	Null string
+	// MaxWidth is the maximum width of the columns, DefaultMaxWidth when 0.
+	MaxWidth int
+}
+
+// Browser is the state of the browser of the rows of a table.
+type Browser struct {
+	db       *sql.DB
+	q        query
+	null     string
+	maxWidth int
+	// width and height are the size of the screen.
+	width, height int
+	// rows are the rows of the current page, and kinds the kinds of their
+	// columns. page is the number of the page, from 0, and more is set when
+	// it is followed by another one.
+	rows  [][]interface{}
+	kinds []export.ColumnKind
+	page  int
+	more  bool
+	// starts are the literals of the key of the last row before each page,
+	// nil for the first one, when the pages are read in keyset mode.
+	starts [][]string
+	// row and col are the position of the current cell in the page, and left
+	// the first shown column.
+	row, col, left int
+	// selected are the selected rows, by the literals of their key, and order
+	// their keys in the order of their selection.
+	selected map[string][]interface{}
+	order    []string
+	// editing is set while the filter is edited, with input.
+	editing bool
+	input   string
+	// status is the message of the status line.
+	status string
+	// clip is the text to copy to the clipboard, written by Run.
+	clip string
+}
+
+// New creates a browser of the table name, optionally schema qualified,
+// reading its first page. The table must have a primary key.
+func New(ctx context.Context, u *dburl.URL, db *sql.DB, name string, opts Options) (*Browser, error) {
+	tables, err := dump.ReadTables(ctx, u, db, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	t := tables[0]
+	if len(t.PrimaryKey) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key", name)
+	}
+	dialect := drivers.Caps(u).Dialect
+	d := dump.DialectFor(dialect)
+	b := &Browser{
+		db: db,
+		q: query{
+			d:       d,
+			dialect: dialect,
+			table:   d.Qualify(t.Schema, t.Name),
+			key:     t.PrimaryKey,
+			sort:    -1,
+		},
+		null:     opts.Null,
+		maxWidth: opts.MaxWidth,
+		width:    80,
+		height:   24,
+		starts:   [][]string{nil},
+		selected: make(map[string][]interface{}),
+	}
+	if b.maxWidth <= 0 {
+		b.maxWidth = DefaultMaxWidth
+	}
+	for _, c := range t.Columns {
+		b.q.columns = append(b.q.columns, c.Name)
+	}
+	if err := b.load(ctx); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// SetSize sets the size of the screen, reading the current page again when
+// the number of its rows changes.
+func (b *Browser) SetSize(ctx context.Context, width, height int) error {
+	n := b.pageSize()
+	b.width, b.height = width, height
+	if b.pageSize() == n {
+		return nil
+	}
+	return b.load(ctx)
+}
+
+// pageSize returns the number of rows of a page, the height of the screen
+// less the header, its rule and the status line.
+func (b *Browser) pageSize() int {
+	if b.height < 4 {
+		return 1
+	}
+	return b.height - 3
+}
+
+// fetch reads the page of the rows after the literals of the key, in keyset
+// mode, or at the offset of the page.
+func (b *Browser) fetch(ctx context.Context, page int, after []string) ([][]interface{}, bool, error) {
+	n := b.pageSize()
+	rows, err := b.db.QueryContext(ctx, b.q.build(n+1, after, page*n))
+	if err != nil {
+		return nil, false, err
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, false, err
+	}
+	b.kinds = make([]export.ColumnKind, len(cts))
+	for i, ct := range cts {
+		b.kinds[i] = export.Kind(ct)
+	}
+	var res [][]interface{}
+	for rows.Next() {
+		values := make([]interface{}, len(cts))
+		for i := range values {
+			values[i] = new(interface{})
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, false, err
+		}
+		row := make([]interface{}, len(values))
+		for i, v := range values {
+			row[i] = *(v.(*interface{}))
+		}
+		res = append(res, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, false, err
+	}
+	if len(res) > n {
+		return res[:n], true, nil
+	}
+	return res, false, nil
+}
+
+// load reads the current page again.
+func (b *Browser) load(ctx context.Context) error {
+	rows, more, err := b.fetch(ctx, b.page, b.starts[b.page])
+	if err != nil {
+		return err
+	}
+	b.rows, b.more = rows, more
+	b.clamp()
+	return nil
+}
+
+// reset reads the first page, after the query changed, restoring the query
+// when it fails.
+func (b *Browser) reset(ctx context.Context, prev query) {
+	page, starts := b.page, b.starts
+	b.page, b.starts = 0, [][]string{nil}
+	if err := b.load(ctx); err != nil {
+		b.q, b.page, b.starts = prev, page, starts
+		b.status = err.Error()
+		return
+	}
+	b.row = 0
+}
+
+// next reads the next page, returning false when there is none.
+func (b *Browser) next(ctx context.Context) bool {
+	if !b.more || len(b.rows) == 0 {
+		return false
+	}
+	after := b.keyLiterals(b.rows[len(b.rows)-1])
+	rows, more, err := b.fetch(ctx, b.page+1, after)
+	switch {
+	case err != nil:
+		b.status = err.Error()
+		return false
+	case len(rows) == 0:
+		b.more = false
+		return false
+	}
+	b.page, b.rows, b.more = b.page+1, rows, more
+	b.starts = append(b.starts[:b.page], after)
+	return true
+}
+
+// prev reads the previous page, returning false when there is none.
+func (b *Browser) prev(ctx context.Context) bool {
+	if b.page == 0 {
+		return false
+	}
+	rows, more, err := b.fetch(ctx, b.page-1, b.starts[b.page-1])
+	if err != nil {
+		b.status = err.Error()
+		return false
+	}
+	b.page, b.rows, b.more = b.page-1, rows, more
+	b.starts = b.starts[:b.page+1]
+	return true
+}
+
+// clamp keeps the current cell in the page.
+func (b *Browser) clamp() {
+	if b.row >= len(b.rows) {
+		b.row = len(b.rows) - 1
+	}
+	if b.row < 0 {
+		b.row = 0
+	}
+}
+
+// keyLiterals returns the literals of the key of the row.
+func (b *Browser) keyLiterals(row []interface{}) []string {
+	var v []string
+	for _, k := range b.q.key {
+		for i, c := range b.q.columns {
+			if c == k {
+				v = append(v, b.q.d.Literal(b.kinds[i], row[i]))
+			}
+		}
+	}
+	return v
+}
+
+// Key handles a key, returning true when the browser quits.
+func (b *Browser) Key(ctx context.Context, k Key) bool {
+	if b.editing {
+		b.editKey(ctx, k)
+		return false
+	}
+	b.status = ""
+	switch {
+	case k.Code == KeyEsc, k.Code == KeyCtrlC, k.is('q'):
+		return true
+	case k.Code == KeyUp, k.is('k'):
+		if b.row > 0 {
+			b.row--
+		} else if b.prev(ctx) {
+			b.row = len(b.rows) - 1
+		}
+	case k.Code == KeyDown, k.is('j'):
+		if b.row < len(b.rows)-1 {
+			b.row++
+		} else if b.next(ctx) {
+			b.row = 0
+		}
+	case k.Code == KeyLeft, k.is('h'):
+		if b.col > 0 {
+			b.col--
+		}
+	case k.Code == KeyRight, k.is('l'):
+		if b.col < len(b.q.columns)-1 {
+			b.col++
+		}
+	case k.Code == KeyHome, k.is('0'):
+		b.col = 0
+	case k.Code == KeyEnd, k.is('$'):
+		b.col = len(b.q.columns) - 1
+	case k.Code == KeyPgUp:
+		if !b.prev(ctx) {
+			b.row = 0
+		}
+	case k.Code == KeyPgDn:
+		if !b.next(ctx) {
+			b.row = len(b.rows) - 1
+		}
+		b.clamp()
+	case k.is('g'):
+		prev := b.q
+		b.reset(ctx, prev)
+	case k.is('s'):
+		prev := b.q
+		switch {
+		case b.q.sort != b.col:
+			b.q.sort, b.q.desc = b.col, false
+		case !b.q.desc:
+			b.q.desc = true
+		default:
+			b.q.sort, b.q.desc = -1, false
+		}
+		b.reset(ctx, prev)
+	case k.is('/'):
+		b.editing, b.input = true, b.q.filter
+	case k.is(' '):
+		if len(b.rows) != 0 {
+			b.toggle(b.rows[b.row])
+		}
+	case k.is('y'):
+		if len(b.rows) != 0 {
+			b.clip = b.text(b.col, b.rows[b.row][b.col], false)
+			b.status = "copied cell"
+		}
+	case k.is('Y'):
+		b.copyRows()
+	}
+	return false
+}
+
+// editKey handles a key while the filter is edited.
+func (b *Browser) editKey(ctx context.Context, k Key) {
+	switch k.Code {
+	case KeyEsc, KeyCtrlC:
+		b.editing = false
+	case KeyEnter:
+		b.editing = false
+		prev := b.q
+		b.q.filter = strings.TrimSpace(b.input)
+		b.reset(ctx, prev)
+	case KeyBackspace:
+		if r := []rune(b.input); len(r) != 0 {
+			b.input = string(r[:len(r)-1])
+		}
+	case KeyRune:
+		b.input += string(k.Rune)
+	}
+}
+
+// toggle toggles the selection of the row.
+func (b *Browser) toggle(row []interface{}) {
+	key := strings.Join(b.keyLiterals(row), ",")
+	if _, ok := b.selected[key]; !ok {
+		b.selected[key], b.order = row, append(b.order, key)
+		return
+	}
+	delete(b.selected, key)
+	for i, s := range b.order {
+		if s == key {
+			b.order = append(b.order[:i], b.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// copyRows copies the selected rows, or the current row when none is
+// selected, as tab separated values with a header.
+func (b *Browser) copyRows() {
+	var rows [][]interface{}
+	for _, key := range b.order {
+		rows = append(rows, b.selected[key])
+	}
+	if len(rows) == 0 && len(b.rows) != 0 {
+		rows = append(rows, b.rows[b.row])
+	}
+	if len(rows) == 0 {
+		return
+	}
+	var sb strings.Builder
+	sb.WriteString(strings.Join(b.q.columns, "\t") + "\n")
+	for _, row := range rows {
+		for i, v := range row {
+			if i != 0 {
+				sb.WriteByte('\t')
+			}
+			sb.WriteString(b.text(i, v, true))
+		}
+		sb.WriteByte('\n')
+	}
+	b.clip = sb.String()
+	b.status = fmt.Sprintf("copied %d rows", len(rows))
+}
+
+// text returns the text of the value of the column i, with its control
+// characters escaped when escape is set.
+func (b *Browser) text(i int, v interface{}, escape bool) string {
+	var s string
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		if b.kinds[i] == export.KindBinary {
+			s = `\x` + hex.EncodeToString(x)
+		} else {
+			s = string(x)
+		}
+	case time.Time:
+		s = x.Format(time.RFC3339Nano)
+	default:
+		s = fmt.Sprint(v)
+	}
+	if escape {
+		s = escaper.Replace(s)
+	}
+	return s
+}
+
+// escaper escapes the control characters breaking the lines of the grid.
+var escaper = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`)
+
+// cell returns the value of the column i as shown in the grid.
+func (b *Browser) cell(i int, v interface{}) string {
+	if v == nil {
+		return b.null
+	}
+	return b.text(i, v, true)
+}
+
+// widths returns the widths of the columns of the page.
+func (b *Browser) widths() []int {
+	widths := make([]int, len(b.q.columns))
+	for i, c := range b.q.columns {
+		widths[i] = display.Width(c)
+		for _, row := range b.rows {
+			if w := display.Width(b.cell(i, row[i])); w > widths[i] {
+				widths[i] = w
+			}
+		}
+		if widths[i] > b.maxWidth {
+			widths[i] = b.maxWidth
+		}
+	}
+	return widths
+}
+
+// visible returns the number of columns shown from the first shown column,
+// scrolling the columns so that the current column is shown.
+func (b *Browser) visible(widths []int) int {
+	if b.col < b.left {
+		b.left = b.col
+	}
+	// the row marker, and a separator before every column
+	fits := func(from, to int) bool {
+		w := 2
+		for i := from; i <= to; i++ {
+			w += widths[i] + 3
+		}
+		return w <= b.width
+	}
+	for b.left < b.col && !fits(b.left, b.col) {
+		b.left++
+	}
+	n := 1
+	for b.left+n < len(widths) && fits(b.left, b.left+n) {
+		n++
+	}
+	return n
+}
+
+// Render returns the lines of the screen.
+func (b *Browser) Render() []string {
+	widths := b.widths()
+	n := b.visible(widths)
+	pad := func(s string, w int) string {
+		s = display.Value(s, w, display.Marker)
+		return s + strings.Repeat(" ", w-display.Width(s))
+	}
+	header, rule := "  ", "  "
+	for i := b.left; i < b.left+n; i++ {
+		header += " " + pad(b.q.columns[i], widths[i]) + "  "
+		rule += strings.Repeat("─", widths[i]+3)
+	}
+	lines := []string{"\x1b[1m" + strings.TrimRight(header, " ") + "\x1b[0m", rule}
+	for r, row := range b.rows {
+		line := "  "
+		if _, ok := b.selected[strings.Join(b.keyLiterals(row), ",")]; ok {
+			line = "* "
+		}
+		for i := b.left; i < b.left+n; i++ {
+			s := pad(b.cell(i, row[i]), widths[i])
+			if r == b.row && i == b.col {
+				s = "\x1b[7m" + s + "\x1b[0m"
+			}
+			line += " " + s + "  "
+		}
+		lines = append(lines, strings.TrimRight(line, " "))
+	}
+	for len(lines) < b.pageSize()+2 {
+		lines = append(lines, "")
+	}
+	return append(lines, display.Value(b.statusLine(), b.width, display.Marker))
+}
+
+// statusLine returns the status line: the filter while edited, or the shown
+// rows, sort, filter and selection, followed by the message.
+func (b *Browser) statusLine() string {
+	if b.editing {
+		return "filter (SQL condition, empty for none): " + b.input
+	}
+	first := b.page*b.pageSize() + 1
+	s := fmt.Sprintf("%s  rows %d-%d", b.q.table, first, first+len(b.rows)-1)
+	if len(b.rows) == 0 {
+		s = b.q.table + "  no rows"
+	}
+	if b.q.sort != -1 {
+		dir := "asc"
+		if b.q.desc {
+			dir = "desc"
+		}
+		s += fmt.Sprintf("  sort %s %s", b.q.columns[b.q.sort], dir)
+	}
+	if b.q.filter != "" {
+		s += "  filter " + b.q.filter
+	}
+	if len(b.order) != 0 {
+		s += fmt.Sprintf("  %d selected", len(b.order))
+	}
+	if b.status != "" {
+		return s + "  " + b.status
+	}
+	return s + "  (q quit, / filter, s sort, space select, y copy cell, Y copy rows)"
+}
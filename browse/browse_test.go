@@ -0,0 +1,192 @@
+package browse
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xo/dburl"
+	_ "github.com/xo/usql/drivers/sqlite3"
+	"github.com/xo/usql/dump"
+)
+
+func TestBrowse(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "test.db")
+	u, err := dburl.Parse("sqlite3:" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY, name TEXT, qty INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 25; i++ {
+		if _, err := db.Exec(`INSERT INTO items VALUES (?, ?, ?)`, i, fmt.Sprintf("item %02d", i), i%7); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := db.Exec(`CREATE TABLE nokey (id INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := New(ctx, u, db, "nokey", Options{}); err == nil {
+		t.Errorf("expected an error browsing a table without a primary key")
+	}
+	b, err := New(ctx, u, db, "items", Options{Null: "NULL"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// 10 rows a page
+	if err := b.SetSize(ctx, 80, 13); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(b); !reflect.DeepEqual(got, seq(1, 10)) {
+		t.Fatalf("expected rows 1-10, got: %v", got)
+	}
+	keys(ctx, b, Key{Code: KeyPgDn}, Key{Code: KeyPgDn})
+	if got := ids(b); !reflect.DeepEqual(got, seq(21, 25)) {
+		t.Errorf("expected rows 21-25, got: %v", got)
+	}
+	keys(ctx, b, Key{Code: KeyPgDn})
+	if got := ids(b); !reflect.DeepEqual(got, seq(21, 25)) || b.row != 4 {
+		t.Errorf("expected the last row of rows 21-25, got: %v (row %d)", got, b.row)
+	}
+	keys(ctx, b, Key{Code: KeyPgUp})
+	if got := ids(b); !reflect.DeepEqual(got, seq(11, 20)) {
+		t.Errorf("expected rows 11-20, got: %v", got)
+	}
+	// moving up from the first row reads the previous page
+	keys(ctx, b, Key{Code: KeyRune, Rune: 'g'}, Key{Code: KeyDown})
+	for i := 0; i < 10; i++ {
+		keys(ctx, b, Key{Code: KeyDown})
+	}
+	if got := ids(b); !reflect.DeepEqual(got, seq(11, 20)) || b.row != 1 {
+		t.Errorf("expected the second row of rows 11-20, got: %v (row %d)", got, b.row)
+	}
+	keys(ctx, b, Key{Code: KeyUp}, Key{Code: KeyUp})
+	if got := ids(b); !reflect.DeepEqual(got, seq(1, 10)) || b.row != 9 {
+		t.Errorf("expected the last row of rows 1-10, got: %v (row %d)", got, b.row)
+	}
+	// sort by id descending
+	keys(ctx, b, Key{Code: KeyRune, Rune: 's'}, Key{Code: KeyRune, Rune: 's'}, Key{Code: KeyPgDn})
+	if got := ids(b); !reflect.DeepEqual(got, seq(15, 6)) {
+		t.Errorf("expected rows 15-6, got: %v", got)
+	}
+	// sort by qty, paged with an offset
+	keys(ctx, b, Key{Code: KeyRune, Rune: 's'}, Key{Code: KeyRight}, Key{Code: KeyRight}, Key{Code: KeyRune, Rune: 's'}, Key{Code: KeyPgDn})
+	if got := ids(b); !reflect.DeepEqual(got, []int64{23, 3, 10, 17, 24, 4, 11, 18, 25, 5}) {
+		t.Errorf("expected the second page sorted by qty, got: %v", got)
+	}
+	// filter
+	keys(ctx, b, Key{Code: KeyRune, Rune: 's'}, Key{Code: KeyRune, Rune: 's'}, Key{Code: KeyRune, Rune: '/'})
+	for _, r := range "qty = 0" {
+		keys(ctx, b, Key{Code: KeyRune, Rune: r})
+	}
+	keys(ctx, b, Key{Code: KeyEnter})
+	if got := ids(b); !reflect.DeepEqual(got, []int64{7, 14, 21}) {
+		t.Errorf("expected rows 7, 14 and 21, got: %v", got)
+	}
+	keys(ctx, b, Key{Code: KeyRune, Rune: '/'}, Key{Code: KeyRune, Rune: '!'}, Key{Code: KeyEnter})
+	if got := ids(b); !reflect.DeepEqual(got, []int64{7, 14, 21}) || b.status == "" || b.q.filter != "qty = 0" {
+		t.Errorf("expected the filter to be kept with an error, got: %v %q %q", got, b.q.filter, b.status)
+	}
+	// select and copy
+	keys(ctx, b, Key{Code: KeyRune, Rune: ' '}, Key{Code: KeyDown}, Key{Code: KeyDown}, Key{Code: KeyRune, Rune: ' '}, Key{Code: KeyRune, Rune: 'Y'})
+	if exp := "id\tname\tqty\n7\titem 07\t0\n21\titem 21\t0\n"; b.clip != exp {
+		t.Errorf("expected %q, got: %q", exp, b.clip)
+	}
+	keys(ctx, b, Key{Code: KeyLeft}, Key{Code: KeyRune, Rune: 'y'})
+	if exp := "item 21"; b.clip != exp {
+		t.Errorf("expected %q, got: %q", exp, b.clip)
+	}
+	lines := b.Render()
+	if len(lines) != 13 || !strings.HasPrefix(lines[4], "*") || !strings.Contains(lines[12], "2 selected") {
+		t.Errorf("unexpected screen:\n%s", strings.Join(lines, "\n"))
+	}
+	if !b.Key(ctx, Key{Code: KeyRune, Rune: 'q'}) {
+		t.Errorf("expected q to quit")
+	}
+}
+
+func TestBuild(t *testing.T) {
+	q := query{
+		d:       dump.DialectFor("postgres"),
+		dialect: "postgres",
+		table:   `"t"`,
+		columns: []string{"a", "b", "c"},
+		key:     []string{"a", "b"},
+		sort:    -1,
+	}
+	tests := []struct {
+		sort   int
+		desc   bool
+		filter string
+		exp    string
+	}{
+		{-1, false, "", `SELECT "a", "b", "c" FROM "t" WHERE (("a" > 1) OR ("a" = 1 AND "b" > 'x')) ORDER BY "a", "b" LIMIT 10`},
+		{-1, false, "c > 0", `SELECT "a", "b", "c" FROM "t" WHERE (c > 0) AND (("a" > 1) OR ("a" = 1 AND "b" > 'x')) ORDER BY "a", "b" LIMIT 10`},
+		{2, true, "", `SELECT "a", "b", "c" FROM "t" ORDER BY "c" DESC, "a" DESC, "b" DESC LIMIT 10 OFFSET 20`},
+	}
+	for i, test := range tests {
+		q.sort, q.desc, q.filter = test.sort, test.desc, test.filter
+		if s := q.build(10, []string{"1", "'x'"}, 20); s != test.exp {
+			t.Errorf("test %d expected:\n%s\ngot:\n%s", i, test.exp, s)
+		}
+	}
+}
+
+func TestDecodeKeys(t *testing.T) {
+	exp := []Key{
+		{Code: KeyRune, Rune: 'j'},
+		{Code: KeyUp},
+		{Code: KeyPgDn},
+		{Code: KeyRune, Rune: 'é'},
+		{Code: KeyHome},
+		{Code: KeyEnter},
+		{Code: KeyBackspace},
+	}
+	if got := decodeKeys([]byte("j\x1b[A\x1b[6~é\x1bOH\r\x7f")); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got: %v", exp, got)
+	}
+	if got := decodeKeys([]byte("\x1b")); !reflect.DeepEqual(got, []Key{{Code: KeyEsc}}) {
+		t.Errorf("expected escape, got: %v", got)
+	}
+}
+
+// keys handles the keys.
+func keys(ctx context.Context, b *Browser, keys ...Key) {
+	for _, k := range keys {
+		b.Key(ctx, k)
+	}
+}
+
+// ids returns the ids of the rows of the page.
+func ids(b *Browser) []int64 {
+	var v []int64
+	for _, row := range b.rows {
+		v = append(v, row[0].(int64))
+	}
+	return v
+}
+
+// seq returns the numbers from i to j, counting down when j is less than i.
+func seq(i, j int64) []int64 {
+	step := int64(1)
+	if j < i {
+		step = -1
+	}
+	v := []int64{i}
+	for i != j {
+		i += step
+		v = append(v, i)
+	}
+	return v
+}
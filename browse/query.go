@@ -0,0 +1,96 @@
+package browse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xo/usql/dump"
+)
+
+// pageQueries are the queries of a page of rows, by dialect, formatted with
+// the ordered query, the number of rows and the offset of the page. The
+// queries of the other dialects use LIMIT and OFFSET.
+var pageQueries = map[string]string{
+	"trino":     "%[1]s OFFSET %[3]d ROWS FETCH NEXT %[2]d ROWS ONLY",
+	"oracle":    "%[1]s OFFSET %[3]d ROWS FETCH NEXT %[2]d ROWS ONLY",
+	"sqlserver": "%[1]s OFFSET %[3]d ROWS FETCH NEXT %[2]d ROWS ONLY",
+}
+
+// query is the query of a page of rows of a table.
+type query struct {
+	d       dump.Dialect
+	dialect string
+	// table is the qualified name of the table, and columns and key the
+	// names of its columns and of the columns of its primary key.
+	table        string
+	columns, key []string
+	// filter is the condition the rows are filtered with, when not empty.
+	filter string
+	// sort is the index of the column the rows are sorted by, or -1 when
+	// sorted by the key, and desc is set when sorted in descending order.
+	sort int
+	desc bool
+}
+
+// keyset returns true when the rows are sorted by the key, so that the pages
+// are read after the key of the last row of the previous page instead of at
+// an offset.
+func (q query) keyset() bool {
+	return q.sort == -1 || len(q.key) == 1 && q.columns[q.sort] == q.key[0]
+}
+
+// build returns the query of the page of n rows, after the literals of the
+// key of the last row of the previous page in keyset mode, or at offset.
+func (q query) build(n int, after []string, offset int) string {
+	var conds []string
+	if q.filter != "" {
+		conds = append(conds, "("+q.filter+")")
+	}
+	op, dir := ">", ""
+	if q.desc {
+		op, dir = "<", " DESC"
+	}
+	if q.keyset() && after != nil {
+		conds = append(conds, keyAfter(q.d, q.key, after, op))
+	}
+	sqlstr := "SELECT " + q.d.QuoteIdents(q.columns) + " FROM " + q.table
+	if len(conds) != 0 {
+		sqlstr += " WHERE " + strings.Join(conds, " AND ")
+	}
+	var order []string
+	if !q.keyset() {
+		order = append(order, q.d.QuoteIdent(q.columns[q.sort])+dir)
+	}
+	for _, k := range q.key {
+		order = append(order, q.d.QuoteIdent(k)+dir)
+	}
+	sqlstr += " ORDER BY " + strings.Join(order, ", ")
+	if q.keyset() {
+		offset = 0
+	}
+	if s, ok := pageQueries[q.dialect]; ok {
+		return fmt.Sprintf(s, sqlstr, n, offset)
+	}
+	if offset == 0 {
+		return fmt.Sprintf("%s LIMIT %d", sqlstr, n)
+	}
+	return fmt.Sprintf("%s LIMIT %d OFFSET %d", sqlstr, n, offset)
+}
+
+// keyAfter returns the condition of the rows whose key is after the literals
+// of last, compared with op.
+func keyAfter(d dump.Dialect, key, last []string, op string) string {
+	ors := make([]string, len(key))
+	for i := range key {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, d.QuoteIdent(key[j])+" = "+last[j])
+		}
+		ands = append(ands, d.QuoteIdent(key[i])+" "+op+" "+last[i])
+		ors[i] = strings.Join(ands, " AND ")
+	}
+	if len(ors) == 1 {
+		return ors[0]
+	}
+	return "((" + strings.Join(ors, ") OR (") + "))"
+}
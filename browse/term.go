@@ -0,0 +1,151 @@
+package browse
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/term"
+)
+
+// KeyCode is the code of a key.
+type KeyCode int
+
+// Key codes.
+const (
+	KeyRune KeyCode = iota
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyPgUp
+	KeyPgDn
+	KeyHome
+	KeyEnd
+	KeyEnter
+	KeyBackspace
+	KeyEsc
+	KeyCtrlC
+	KeyUnknown
+)
+
+// Key is a key read from the terminal.
+type Key struct {
+	Code KeyCode
+	// Rune is the rune of a KeyRune key.
+	Rune rune
+}
+
+// is returns true when the key is the rune r.
+func (k Key) is(r rune) bool {
+	return k.Code == KeyRune && k.Rune == r
+}
+
+// escapes are the keys of the escape sequences of the terminals.
+var escapes = map[string]KeyCode{
+	"\x1b[A":  KeyUp,
+	"\x1b[B":  KeyDown,
+	"\x1b[C":  KeyRight,
+	"\x1b[D":  KeyLeft,
+	"\x1bOA":  KeyUp,
+	"\x1bOB":  KeyDown,
+	"\x1bOC":  KeyRight,
+	"\x1bOD":  KeyLeft,
+	"\x1b[5~": KeyPgUp,
+	"\x1b[6~": KeyPgDn,
+	"\x1b[H":  KeyHome,
+	"\x1b[F":  KeyEnd,
+	"\x1bOH":  KeyHome,
+	"\x1bOF":  KeyEnd,
+	"\x1b[1~": KeyHome,
+	"\x1b[4~": KeyEnd,
+}
+
+// decodeKeys decodes the keys of a read of the terminal. A lone escape is the
+// escape key.
+func decodeKeys(buf []byte) []Key {
+	var keys []Key
+	for len(buf) != 0 {
+		switch c := buf[0]; {
+		case c == 0x1b && len(buf) == 1:
+			keys, buf = append(keys, Key{Code: KeyEsc}), buf[1:]
+		case c == 0x1b:
+			// sequences end with a letter or ~
+			n := 2
+			for n < len(buf) && (buf[n] < 'A' || buf[n] > 'Z') && buf[n] != '~' && buf[1] != 'O' {
+				n++
+			}
+			if n < len(buf) {
+				n++
+			}
+			code, ok := escapes[string(buf[:n])]
+			if !ok {
+				code = KeyUnknown
+			}
+			keys, buf = append(keys, Key{Code: code}), buf[n:]
+		case c == '\r' || c == '\n':
+			keys, buf = append(keys, Key{Code: KeyEnter}), buf[1:]
+		case c == 0x7f || c == 0x08:
+			keys, buf = append(keys, Key{Code: KeyBackspace}), buf[1:]
+		case c == 0x03:
+			keys, buf = append(keys, Key{Code: KeyCtrlC}), buf[1:]
+		case c < 0x20:
+			keys, buf = append(keys, Key{Code: KeyUnknown}), buf[1:]
+		default:
+			r, n := utf8.DecodeRune(buf)
+			keys, buf = append(keys, Key{Code: KeyRune, Rune: r}), buf[n:]
+		}
+	}
+	return keys
+}
+
+// Run runs the browser in the terminal of in and out, on its alternate
+// screen, until it quits. The copied text is sent to the clipboard of the
+// terminal with the OSC 52 sequence.
+func Run(ctx context.Context, in, out *os.File, b *Browser) error {
+	fd := int(in.Fd())
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer term.Restore(fd, state)
+	w := bufio.NewWriter(out)
+	// alternate screen, hidden cursor
+	w.WriteString("\x1b[?1049h\x1b[?25l")
+	defer func() {
+		w.WriteString("\x1b[?25h\x1b[?1049l")
+		w.Flush()
+	}()
+	buf := make([]byte, 256)
+	for {
+		if width, height, err := term.GetSize(int(out.Fd())); err == nil {
+			if err := b.SetSize(ctx, width, height); err != nil {
+				return err
+			}
+		}
+		if b.clip != "" {
+			w.WriteString("\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(b.clip)) + "\a")
+			b.clip = ""
+		}
+		w.WriteString("\x1b[H\x1b[2J" + strings.Join(b.Render(), "\r\n"))
+		if err := w.Flush(); err != nil {
+			return err
+		}
+		n, err := in.Read(buf)
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		for _, k := range decodeKeys(buf[:n]) {
+			if b.Key(ctx, k) {
+				return nil
+			}
+		}
+	}
+}
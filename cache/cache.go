@@ -0,0 +1,356 @@
+// Package cache caches query results on disk, keyed by the normalized text of
+// the query.
+package cache
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Cache is a query result cache stored in a directory.
+type Cache struct {
+	Dir string
+}
+
+// New creates a cache in the usql directory of the user's cache directory.
+func New() (*Cache, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: filepath.Join(dir, "usql", "results")}, nil
+}
+
+// Key returns the cache key of the query on the database, ignoring the
+// differences in whitespace and trailing semicolons of the query.
+func Key(urlstr, sqlstr string) string {
+	h := sha256.New()
+	h.Write([]byte(urlstr))
+	h.Write([]byte{0})
+	h.Write([]byte(Normalize(sqlstr)))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Normalize collapses the whitespace outside of quoted strings and
+// identifiers to a single space, and removes trailing semicolons.
+func Normalize(sqlstr string) string {
+	var sb strings.Builder
+	var quote rune
+	space := false
+	for _, r := range strings.TrimSpace(sqlstr) {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case unicode.IsSpace(r):
+			space = true
+			continue
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		}
+		if space {
+			sb.WriteByte(' ')
+			space = false
+		}
+		sb.WriteRune(r)
+	}
+	return strings.TrimRightFunc(sb.String(), func(r rune) bool {
+		return r == ';' || unicode.IsSpace(r)
+	})
+}
+
+// Get returns the cached result of the key, or nil when there is no result
+// cached less than ttl ago. Expired results are removed.
+func (c *Cache) Get(key string, ttl time.Duration) (*Result, error) {
+	buf, err := os.ReadFile(c.path(key))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	res := new(Result)
+	if err := json.Unmarshal(buf, res); err != nil {
+		os.Remove(c.path(key))
+		return nil, nil
+	}
+	if time.Since(res.Created) > ttl {
+		os.Remove(c.path(key))
+		return nil, nil
+	}
+	return res, nil
+}
+
+// Put caches the result of the key.
+func (c *Cache) Put(key string, res *Result) error {
+	buf, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.Dir, 0o700); err != nil {
+		return err
+	}
+	// write to a temporary file, so concurrent reads never see partial
+	// results
+	f, err := os.CreateTemp(c.Dir, key+".*")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(key))
+}
+
+// Clear removes all cached results.
+func (c *Cache) Clear() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// path returns the path of the cached result of the key.
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".json")
+}
+
+// Result is a cached query result.
+type Result struct {
+	Created time.Time `json:"created"`
+	Columns []string  `json:"columns"`
+	Rows    [][]Value `json:"rows"`
+}
+
+// ResultSet returns a result set reading the rows of the result.
+func (res *Result) ResultSet() *ResultSet {
+	return &ResultSet{res: res, pos: -1}
+}
+
+// ResultSet reads the rows of a cached result, satisfying tblfmt.ResultSet.
+type ResultSet struct {
+	res *Result
+	pos int
+}
+
+// Next advances to the next row.
+func (rs *ResultSet) Next() bool {
+	if rs.pos < len(rs.res.Rows) {
+		rs.pos++
+	}
+	return rs.pos < len(rs.res.Rows)
+}
+
+// Scan scans the values of the current row to dest, which must be
+// *interface{} values.
+func (rs *ResultSet) Scan(dest ...interface{}) error {
+	if rs.pos < 0 || rs.pos >= len(rs.res.Rows) {
+		return sql.ErrNoRows
+	}
+	row := rs.res.Rows[rs.pos]
+	if len(dest) != len(row) {
+		return fmt.Errorf("expected %d destination arguments in Scan, not %d", len(row), len(dest))
+	}
+	for i, v := range row {
+		p, ok := dest[i].(*interface{})
+		if !ok {
+			return fmt.Errorf("unsupported Scan destination %T", dest[i])
+		}
+		*p = v.V
+	}
+	return nil
+}
+
+// Columns returns the column names.
+func (rs *ResultSet) Columns() ([]string, error) {
+	return append([]string(nil), rs.res.Columns...), nil
+}
+
+// Close satisfies the tblfmt.ResultSet interface.
+func (rs *ResultSet) Close() error {
+	return nil
+}
+
+// Err satisfies the tblfmt.ResultSet interface.
+func (rs *ResultSet) Err() error {
+	return nil
+}
+
+// NextResultSet satisfies the tblfmt.ResultSet interface. Results are cached
+// only for the first result set.
+func (rs *ResultSet) NextResultSet() bool {
+	return false
+}
+
+// MaxRows is the maximum number of rows of recorded results. Larger results
+// are not cached, bounding the memory used to record them.
+var MaxRows = 10000
+
+// Recorder wraps rows, recording the scanned rows.
+type Recorder struct {
+	*sql.Rows
+	res *Result
+	// done is set when all the rows of the first result set were read.
+	done bool
+	// skip is set when the rows are not recorded: when they have multiple
+	// result sets, values scanned to specific types, or more than MaxRows
+	// rows.
+	skip bool
+}
+
+// NewRecorder creates a recorder for the rows.
+func NewRecorder(rows *sql.Rows) *Recorder {
+	return &Recorder{Rows: rows, res: &Result{Created: time.Now()}}
+}
+
+// Next advances to the next row.
+func (r *Recorder) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.done = r.done || (r.Rows.Err() == nil && !r.skip)
+	return false
+}
+
+// Columns returns the column names.
+func (r *Recorder) Columns() ([]string, error) {
+	cols, err := r.Rows.Columns()
+	if err == nil && r.res.Columns == nil {
+		r.res.Columns = append([]string{}, cols...)
+	}
+	return cols, err
+}
+
+// Scan scans the values of the current row to dest, recording the values.
+func (r *Recorder) Scan(dest ...interface{}) error {
+	if err := r.Rows.Scan(dest...); err != nil {
+		return err
+	}
+	if r.skip {
+		return nil
+	}
+	if len(r.res.Rows) >= MaxRows {
+		r.res.Rows, r.skip = nil, true
+		return nil
+	}
+	row := make([]Value, len(dest))
+	for i, d := range dest {
+		p, ok := d.(*interface{})
+		if !ok {
+			// only generic values are recorded
+			r.res.Rows, r.skip = nil, true
+			return nil
+		}
+		row[i] = Value{V: *p}
+	}
+	r.res.Rows = append(r.res.Rows, row)
+	return nil
+}
+
+// NextResultSet prepares the next result set, which is not recorded.
+func (r *Recorder) NextResultSet() bool {
+	if !r.Rows.NextResultSet() {
+		return false
+	}
+	r.skip = true
+	return true
+}
+
+// Result returns the recorded result, or nil when the rows were not entirely
+// read or not recorded.
+func (r *Recorder) Result() *Result {
+	if !r.done || r.skip || r.res.Columns == nil {
+		return nil
+	}
+	return r.res
+}
+
+// Value is a cached value, encoded with its type.
+type Value struct {
+	V interface{}
+}
+
+// Value types.
+const (
+	typeNull   = "n"
+	typeString = "s"
+	typeInt    = "i"
+	typeFloat  = "f"
+	typeBool   = "b"
+	typeTime   = "t"
+	typeBytes  = "x"
+)
+
+// encodedValue is the JSON encoding of a value.
+type encodedValue struct {
+	Type  string `json:"t"`
+	Value string `json:"v,omitempty"`
+}
+
+// MarshalJSON satisfies the json.Marshaler interface. Values of other types
+// than the database/sql driver values are stored as strings.
+func (v Value) MarshalJSON() ([]byte, error) {
+	var e encodedValue
+	switch x := v.V.(type) {
+	case nil:
+		e.Type = typeNull
+	case string:
+		e.Type, e.Value = typeString, x
+	case int64:
+		e.Type, e.Value = typeInt, strconv.FormatInt(x, 10)
+	case float64:
+		e.Type, e.Value = typeFloat, strconv.FormatFloat(x, 'g', -1, 64)
+	case bool:
+		e.Type, e.Value = typeBool, strconv.FormatBool(x)
+	case time.Time:
+		e.Type, e.Value = typeTime, x.Format(time.RFC3339Nano)
+	case []byte:
+		e.Type, e.Value = typeBytes, base64.StdEncoding.EncodeToString(x)
+	default:
+		e.Type, e.Value = typeString, fmt.Sprint(x)
+	}
+	return json.Marshal(e)
+}
+
+// UnmarshalJSON satisfies the json.Unmarshaler interface.
+func (v *Value) UnmarshalJSON(buf []byte) error {
+	var e encodedValue
+	if err := json.Unmarshal(buf, &e); err != nil {
+		return err
+	}
+	var err error
+	switch e.Type {
+	case typeNull:
+		v.V = nil
+	case typeString:
+		v.V = e.Value
+	case typeInt:
+		v.V, err = strconv.ParseInt(e.Value, 10, 64)
+	case typeFloat:
+		v.V, err = strconv.ParseFloat(e.Value, 64)
+	case typeBool:
+		v.V, err = strconv.ParseBool(e.Value)
+	case typeTime:
+		v.V, err = time.Parse(time.RFC3339Nano, e.Value)
+	case typeBytes:
+		v.V, err = base64.StdEncoding.DecodeString(e.Value)
+	default:
+		err = fmt.Errorf("invalid value type %q", e.Type)
+	}
+	return err
+}
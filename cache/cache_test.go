@@ -0,0 +1,146 @@
+package cache
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		s, exp string
+	}{
+		{"select 1", "select 1"},
+		{"  select\n\t1 ;; \n", "select 1"},
+		{"select 'a  b',\n  \"c  d\"", "select 'a  b', \"c  d\""},
+		{"select `a\n b`  from t;", "select `a\n b` from t"},
+	}
+	for i, test := range tests {
+		if s := Normalize(test.s); s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+	}
+	if Key("pg://a", "select  1;") != Key("pg://a", "select 1") {
+		t.Errorf("expected equal keys for equivalent queries")
+	}
+	if Key("pg://a", "select 1") == Key("pg://b", "select 1") {
+		t.Errorf("expected different keys for different databases")
+	}
+}
+
+func TestCache(t *testing.T) {
+	c := &Cache{Dir: t.TempDir()}
+	created := time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)
+	res := &Result{
+		Created: time.Now(),
+		Columns: []string{"a", "b"},
+		Rows: [][]Value{
+			{{nil}, {"x"}},
+			{{int64(42)}, {1.5}},
+			{{true}, {created}},
+			{{[]byte("raw")}, {"y"}},
+		},
+	}
+	key := Key("pg://", "select a, b from t")
+	if err := c.Put(key, res); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	got, err := c.Get(key, time.Hour)
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case got == nil:
+		t.Fatalf("expected cached result")
+	}
+	rs := got.ResultSet()
+	cols, _ := rs.Columns()
+	if len(cols) != 2 || cols[0] != "a" || cols[1] != "b" {
+		t.Errorf("expected columns [a b], got: %v", cols)
+	}
+	var rows [][]interface{}
+	for rs.Next() {
+		var a, b interface{}
+		if err := rs.Scan(&a, &b); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		rows = append(rows, []interface{}{a, b})
+	}
+	if len(rows) != 4 {
+		t.Fatalf("expected 4 rows, got: %d", len(rows))
+	}
+	if rows[0][0] != nil || rows[0][1] != "x" {
+		t.Errorf("row 0: unexpected values %v", rows[0])
+	}
+	if rows[1][0] != int64(42) || rows[1][1] != 1.5 {
+		t.Errorf("row 1: unexpected values %v", rows[1])
+	}
+	if rows[2][0] != true || !rows[2][1].(time.Time).Equal(created) {
+		t.Errorf("row 2: unexpected values %v", rows[2])
+	}
+	if !bytes.Equal(rows[3][0].([]byte), []byte("raw")) {
+		t.Errorf("row 3: unexpected values %v", rows[3])
+	}
+	// expired results are removed
+	if got, err := c.Get(key, time.Nanosecond); err != nil || got != nil {
+		t.Errorf("expected no expired result, got: %v, %v", got, err)
+	}
+	if got, err := c.Get(key, time.Hour); err != nil || got != nil {
+		t.Errorf("expected expired result to be removed, got: %v, %v", got, err)
+	}
+	// clear
+	if err := c.Put(key, res); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := c.Clear(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if got, _ := c.Get(key, time.Hour); got != nil {
+		t.Errorf("expected no result after clear")
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	defer func(n int) { MaxRows = n }(MaxRows)
+	MaxRows = 3
+	for _, test := range []struct {
+		n   int
+		exp bool
+	}{
+		{3, true},
+		{4, false},
+	} {
+		rows, err := db.Query(`WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c WHERE x < ?) SELECT x FROM c`, test.n)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		rec := NewRecorder(rows)
+		if _, err := rec.Columns(); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		var n int
+		for rec.Next() {
+			var x interface{}
+			if err := rec.Scan(&x); err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			n++
+		}
+		rows.Close()
+		if n != test.n {
+			t.Errorf("expected %d rows read, got: %d", test.n, n)
+		}
+		if res := rec.Result(); (res != nil) != test.exp {
+			t.Errorf("%d rows: expected recorded %t, got: %v", test.n, test.exp, res)
+		} else if res != nil && len(res.Rows) != test.n {
+			t.Errorf("expected %d recorded rows, got: %d", test.n, len(res.Rows))
+		}
+	}
+}
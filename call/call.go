@@ -0,0 +1,240 @@
+// Package call builds the statements calling the stored procedures of a
+// database, binding their arguments and their OUT parameters as supported by
+// its driver.
+package call
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Call is the call of a stored procedure.
+type Call struct {
+	// Name is the name of the procedure, optionally schema qualified.
+	Name string
+	// Args are the arguments of the call.
+	Args []Arg
+}
+
+// Arg is an argument of a call.
+type Arg struct {
+	// Value is the value of an IN argument: a string, int64, float64, bool or
+	// nil.
+	Value interface{}
+	// Out is set for OUT parameters.
+	Out bool
+	// Name is the name of an OUT parameter, outN by default.
+	Name string
+}
+
+// nameRE matches the name of a procedure.
+var nameRE = regexp.MustCompile(`^[\pL_][\pL\pN_$#]*(\.[\pL_][\pL\pN_$#]*)*$`)
+
+// Parse parses a call of a procedure, NAME(ARG, ...), whose arguments are
+// quoted strings, numbers, NULL, TRUE, FALSE, :NAME variables or OUT [NAME]
+// parameters.
+func Parse(s string, vars map[string]string) (*Call, error) {
+	s = strings.TrimRight(strings.TrimSpace(s), ";")
+	name, rest, hasArgs := strings.Cut(s, "(")
+	c := &Call{Name: strings.TrimSpace(name)}
+	if !nameRE.MatchString(c.Name) {
+		return nil, fmt.Errorf("invalid procedure name %q", c.Name)
+	}
+	if !hasArgs {
+		return c, nil
+	}
+	rest = strings.TrimSpace(rest)
+	if !strings.HasSuffix(rest, ")") {
+		return nil, fmt.Errorf("missing ) after the arguments of %s", c.Name)
+	}
+	p := &parser{s: []rune(strings.TrimSuffix(rest, ")")), vars: vars}
+	for p.skipSpace(); p.i < len(p.s); {
+		arg, err := p.arg(len(c.Args) + 1)
+		if err != nil {
+			return nil, fmt.Errorf("argument %d of %s: %w", len(c.Args)+1, c.Name, err)
+		}
+		c.Args = append(c.Args, arg)
+		p.skipSpace()
+		switch {
+		case p.i == len(p.s):
+		case p.s[p.i] == ',':
+			p.i++
+			p.skipSpace()
+			if p.i == len(p.s) {
+				return nil, fmt.Errorf("missing argument %d of %s", len(c.Args)+1, c.Name)
+			}
+		default:
+			return nil, fmt.Errorf("argument %d of %s: unexpected %q", len(c.Args), c.Name, string(p.s[p.i:]))
+		}
+	}
+	return c, nil
+}
+
+// parser parses the arguments of a call.
+type parser struct {
+	s    []rune
+	i    int
+	vars map[string]string
+}
+
+// skipSpace skips the white space.
+func (p *parser) skipSpace() {
+	for p.i < len(p.s) && unicode.IsSpace(p.s[p.i]) {
+		p.i++
+	}
+}
+
+// word returns the next unquoted word.
+func (p *parser) word() string {
+	start := p.i
+	for p.i < len(p.s) && p.s[p.i] != ',' && !unicode.IsSpace(p.s[p.i]) {
+		p.i++
+	}
+	return string(p.s[start:p.i])
+}
+
+// arg parses the n'th argument.
+func (p *parser) arg(n int) (Arg, error) {
+	if p.s[p.i] == '\'' {
+		s, err := p.quoted()
+		return Arg{Value: s}, err
+	}
+	w := p.word()
+	switch strings.ToUpper(w) {
+	case "OUT":
+		arg := Arg{Out: true, Name: "out" + strconv.Itoa(n)}
+		p.skipSpace()
+		if p.i < len(p.s) && p.s[p.i] != ',' {
+			if arg.Name = p.word(); !nameRE.MatchString(arg.Name) || strings.Contains(arg.Name, ".") {
+				return Arg{}, fmt.Errorf("invalid OUT parameter name %q", arg.Name)
+			}
+		}
+		return arg, nil
+	case "NULL":
+		return Arg{}, nil
+	case "TRUE", "FALSE":
+		return Arg{Value: strings.EqualFold(w, "TRUE")}, nil
+	}
+	if strings.HasPrefix(w, ":") {
+		name := strings.Trim(w[1:], `'"`)
+		v, ok := p.vars[name]
+		if !ok {
+			return Arg{}, fmt.Errorf("variable %q is not set", name)
+		}
+		return Arg{Value: v}, nil
+	}
+	if i, err := strconv.ParseInt(w, 10, 64); err == nil {
+		return Arg{Value: i}, nil
+	}
+	if f, err := strconv.ParseFloat(w, 64); err == nil {
+		return Arg{Value: f}, nil
+	}
+	return Arg{}, fmt.Errorf("invalid value %q: quote strings", w)
+}
+
+// quoted parses a single quoted string, with two single quotes escaping a
+// quote.
+func (p *parser) quoted() (string, error) {
+	var sb strings.Builder
+	for p.i++; p.i < len(p.s); p.i++ {
+		if p.s[p.i] == '\'' {
+			if p.i+1 < len(p.s) && p.s[p.i+1] == '\'' {
+				sb.WriteRune('\'')
+				p.i++
+				continue
+			}
+			p.i++
+			return sb.String(), nil
+		}
+		sb.WriteRune(p.s[p.i])
+	}
+	return "", fmt.Errorf("unterminated quoted string")
+}
+
+// Statement is the statement calling a procedure.
+type Statement struct {
+	// SQL is the statement.
+	SQL string
+	// Args are the arguments bound to the statement.
+	Args []interface{}
+	// Query is set when the statement is queried for the result sets of the
+	// procedure.
+	Query bool
+	// Outs are the names of the OUT parameters.
+	Outs []string
+	// OutQuery is the query selecting the OUT parameters, to execute on the
+	// connection of the statement after it, when not bound by the driver.
+	OutQuery string
+	// dests are the values of the OUT parameters bound by the driver.
+	dests []*string
+}
+
+// OutValues returns the values of the OUT parameters bound by the driver,
+// once the statement is executed and its result sets are read.
+func (st *Statement) OutValues() []interface{} {
+	v := make([]interface{}, len(st.dests))
+	for i, d := range st.dests {
+		v[i] = *d
+	}
+	return v
+}
+
+// Statement returns the statement calling the procedure on a database of the
+// SQL dialect, with its n'th argument as placeholder(n):
+//
+//   - postgres: CALL NAME($1, NULL), OUT parameters being returned as a row
+//   - mysql: CALL NAME(?, @usql_NAME), OUT parameters being selected after it
+//   - sqlserver: EXEC NAME @p1, @p2 OUTPUT, bound as sql.Out
+//   - oracle: BEGIN NAME(:1, :2); END;, bound as sql.Out
+//   - others: CALL NAME(?, ?), without OUT parameters
+//
+// The OUT parameters bound by the driver are read as strings.
+func (c *Call) Statement(dialect string, placeholder func(int) string) (*Statement, error) {
+	st := &Statement{Query: dialect != "oracle"}
+	var params, outs []string
+	for _, arg := range c.Args {
+		if !arg.Out {
+			st.Args = append(st.Args, arg.Value)
+			params = append(params, placeholder(len(st.Args)))
+			continue
+		}
+		st.Outs = append(st.Outs, arg.Name)
+		switch dialect {
+		case "postgres":
+			params = append(params, "NULL")
+		case "mysql":
+			params = append(params, "@usql_"+arg.Name)
+			outs = append(outs, "@usql_"+arg.Name+" AS "+arg.Name)
+		case "sqlserver", "oracle":
+			d := new(string)
+			st.dests = append(st.dests, d)
+			st.Args = append(st.Args, sql.Out{Dest: d})
+			params = append(params, placeholder(len(st.Args)))
+			if dialect == "sqlserver" {
+				params[len(params)-1] += " OUTPUT"
+			}
+		default:
+			return nil, fmt.Errorf("OUT parameters not supported by %s", dialect)
+		}
+	}
+	switch dialect {
+	case "sqlserver":
+		// name the arguments, as OUTPUT parameters are bound by name
+		for i, arg := range st.Args {
+			st.Args[i] = sql.Named(strings.TrimPrefix(placeholder(i+1), "@"), arg)
+		}
+		st.SQL = strings.TrimSpace("EXEC " + c.Name + " " + strings.Join(params, ", "))
+	case "oracle":
+		st.SQL = "BEGIN " + c.Name + "(" + strings.Join(params, ", ") + "); END;"
+	default:
+		st.SQL = "CALL " + c.Name + "(" + strings.Join(params, ", ") + ")"
+	}
+	if len(outs) != 0 {
+		st.OutQuery = "SELECT " + strings.Join(outs, ", ")
+	}
+	return st, nil
+}
@@ -0,0 +1,124 @@
+package call
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	vars := map[string]string{"id": "42"}
+	tests := []struct {
+		s   string
+		exp *Call
+	}{
+		{"refresh", &Call{Name: "refresh"}},
+		{"refresh();", &Call{Name: "refresh"}},
+		{"sales.close_month ( 2024 , 'it''s', 1.5, NULL, true )", &Call{Name: "sales.close_month", Args: []Arg{
+			{Value: int64(2024)}, {Value: "it's"}, {Value: 1.5}, {}, {Value: true},
+		}}},
+		{"get_order(:id, OUT, out total)", &Call{Name: "get_order", Args: []Arg{
+			{Value: "42"}, {Out: true, Name: "out2"}, {Out: true, Name: "total"},
+		}}},
+		{"say('a, b)')", &Call{Name: "say", Args: []Arg{{Value: "a, b)"}}}},
+	}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c, err := Parse(test.s, vars)
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if !reflect.DeepEqual(c, test.exp) {
+				t.Errorf("expected %+v, got: %+v", test.exp, c)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for i, s := range []string{
+		"",
+		"drop table t; --",
+		"p(1",
+		"p('a)",
+		"p(1,)",
+		"p(abc)",
+		"p(:missing)",
+		"p(1 2)",
+		"p(OUT a.b)",
+	} {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if _, err := Parse(s, nil); err == nil {
+				t.Errorf("expected error parsing %q", s)
+			}
+		})
+	}
+}
+
+func TestStatement(t *testing.T) {
+	c, err := Parse("get_order(42, OUT total)", nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		dialect  string
+		sql      string
+		query    bool
+		outQuery string
+		args     int
+	}{
+		{"postgres", "CALL get_order($1, NULL)", true, "", 1},
+		{"mysql", "CALL get_order(?, @usql_total)", true, "SELECT @usql_total AS total", 1},
+		{"sqlserver", "EXEC get_order @p1, @p2 OUTPUT", true, "", 2},
+		{"oracle", "BEGIN get_order(:1, :2); END;", false, "", 2},
+	}
+	placeholders := map[string]func(int) string{
+		"postgres":  func(n int) string { return "$" + strconv.Itoa(n) },
+		"mysql":     func(int) string { return "?" },
+		"sqlserver": func(n int) string { return "@p" + strconv.Itoa(n) },
+		"oracle":    func(n int) string { return ":" + strconv.Itoa(n) },
+	}
+	for _, test := range tests {
+		t.Run(test.dialect, func(t *testing.T) {
+			st, err := c.Statement(test.dialect, placeholders[test.dialect])
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			if st.SQL != test.sql {
+				t.Errorf("expected %q, got: %q", test.sql, st.SQL)
+			}
+			if st.Query != test.query {
+				t.Errorf("expected query %t, got: %t", test.query, st.Query)
+			}
+			if st.OutQuery != test.outQuery {
+				t.Errorf("expected %q, got: %q", test.outQuery, st.OutQuery)
+			}
+			if len(st.Args) != test.args {
+				t.Fatalf("expected %d args, got: %d", test.args, len(st.Args))
+			}
+			if !reflect.DeepEqual(st.Outs, []string{"total"}) {
+				t.Errorf("expected OUT parameter total, got: %v", st.Outs)
+			}
+		})
+	}
+	st, err := c.Statement("sqlserver", placeholders["sqlserver"])
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	out, ok := st.Args[1].(sql.NamedArg).Value.(sql.Out)
+	if !ok || st.Args[1].(sql.NamedArg).Name != "p2" {
+		t.Fatalf("expected sql.Out named p2, got: %#v", st.Args[1])
+	}
+	*out.Dest.(*string) = "12.50"
+	if v := st.OutValues(); !reflect.DeepEqual(v, []interface{}{"12.50"}) {
+		t.Errorf("expected OUT value 12.50, got: %v", v)
+	}
+	if _, err := c.Statement("sqlite3", func(int) string { return "?" }); err == nil {
+		t.Errorf("expected OUT parameters to not be supported by sqlite3")
+	}
+	c, _ = Parse("refresh", nil)
+	if st, _ := c.Statement("sqlserver", placeholders["sqlserver"]); st.SQL != "EXEC refresh" {
+		t.Errorf("expected EXEC refresh, got: %q", st.SQL)
+	}
+}
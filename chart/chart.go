@@ -0,0 +1,253 @@
+// Package chart renders numeric series as terminal bar and line charts.
+package chart
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Kind is a chart kind.
+type Kind string
+
+// Chart kinds.
+const (
+	// Bar is a horizontal bar chart, with a bar per value.
+	Bar Kind = "bar"
+	// Line is a line chart, drawn with braille characters.
+	Line Kind = "line"
+)
+
+// ParseKind parses a chart kind.
+func ParseKind(s string) (Kind, error) {
+	switch k := Kind(strings.ToLower(s)); k {
+	case Bar, Line:
+		return k, nil
+	}
+	return "", fmt.Errorf("unknown chart kind %q", s)
+}
+
+// Series is a series of labeled values.
+type Series struct {
+	Labels []string
+	Values []float64
+}
+
+// Add adds a value, parsed from s.
+func (s *Series) Add(label, value string) error {
+	v, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil {
+		return fmt.Errorf("value %q is not a number", value)
+	}
+	s.Labels, s.Values = append(s.Labels, label), append(s.Values, v)
+	return nil
+}
+
+// Write writes the chart of the series to w, fitting width columns.
+func Write(w io.Writer, kind Kind, s Series, width int) error {
+	if len(s.Values) == 0 {
+		return nil
+	}
+	if kind == Line {
+		return writeLine(w, s, width, lineHeight)
+	}
+	return writeBar(w, s, width)
+}
+
+// bar eighths, from an eighth to a full block.
+var eighths = []rune("▏▎▍▌▋▊▉█")
+
+// maxLabel is the maximum width of the labels of the bars.
+const maxLabel = 24
+
+// writeBar writes a horizontal bar chart.
+func writeBar(w io.Writer, s Series, width int) error {
+	lo, hi := bounds(s.Values)
+	lo = math.Min(lo, 0)
+	// label and value widths
+	lw, vw := 0, 0
+	values := make([]string, len(s.Values))
+	for i, v := range s.Values {
+		lw = maxInt(lw, minInt(utf8.RuneCountInString(s.Labels[i]), maxLabel))
+		values[i] = formatValue(v)
+		vw = maxInt(vw, len(values[i]))
+	}
+	bw := maxInt(width-lw-vw-4, 10)
+	for i, v := range s.Values {
+		n := 0
+		if hi > lo {
+			n = int(math.Round((v - lo) / (hi - lo) * float64(bw*8)))
+		}
+		bar := strings.Repeat(string(eighths[7]), n/8)
+		if n%8 != 0 {
+			bar += string(eighths[n%8-1])
+		}
+		label := truncate(s.Labels[i], maxLabel)
+		pad := strings.Repeat(" ", lw-utf8.RuneCountInString(label))
+		if _, err := fmt.Fprintf(w, "%s%s │%s %s\n", label, pad, bar, values[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lineHeight is the height of line charts, in rows.
+const lineHeight = 12
+
+// writeLine writes a line chart, with its axis labels.
+func writeLine(w io.Writer, s Series, width, height int) error {
+	lo, hi := bounds(s.Values)
+	top, bottom := formatValue(hi), formatValue(lo)
+	aw := maxInt(len(top), len(bottom))
+	cols := maxInt(width-aw-2, 10)
+	c := newCanvas(cols, height)
+	// plot the values, joining them with lines
+	px, py := -1, -1
+	for i, v := range s.Values {
+		x := 0
+		if len(s.Values) > 1 {
+			x = int(math.Round(float64(i) / float64(len(s.Values)-1) * float64(c.w-1)))
+		}
+		y := c.h - 1
+		if hi > lo {
+			y = int(math.Round((hi - v) / (hi - lo) * float64(c.h-1)))
+		}
+		if px == -1 {
+			c.set(x, y)
+		} else {
+			c.line(px, py, x, y)
+		}
+		px, py = x, y
+	}
+	for i, row := range c.rows() {
+		axis := ""
+		switch i {
+		case 0:
+			axis = top
+		case height - 1:
+			axis = bottom
+		}
+		if _, err := fmt.Fprintf(w, "%*s ┤%s\n", aw, axis, row); err != nil {
+			return err
+		}
+	}
+	// x axis, with the first and last labels
+	first, last := s.Labels[0], s.Labels[len(s.Labels)-1]
+	gap := cols - utf8.RuneCountInString(first) - utf8.RuneCountInString(last)
+	if len(s.Labels) == 1 || gap < 1 {
+		last, gap = "", 0
+	}
+	_, err := fmt.Fprintf(w, "%*s └%s\n%*s  %s%s%s\n", aw, "", strings.Repeat("─", cols), aw, "", first, strings.Repeat(" ", gap), last)
+	return err
+}
+
+// canvas is a braille canvas, whose characters are 2 by 4 dots.
+type canvas struct {
+	w, h  int
+	cells [][]rune
+}
+
+// newCanvas creates a canvas of cols by rows characters.
+func newCanvas(cols, rows int) *canvas {
+	cells := make([][]rune, rows)
+	for i := range cells {
+		cells[i] = []rune(strings.Repeat("⠀", cols))
+	}
+	return &canvas{w: cols * 2, h: rows * 4, cells: cells}
+}
+
+// dots are the braille dots of the positions of a character.
+var dots = [4][2]rune{{0x01, 0x08}, {0x02, 0x10}, {0x04, 0x20}, {0x40, 0x80}}
+
+// set sets the dot at x, y.
+func (c *canvas) set(x, y int) {
+	if x < 0 || x >= c.w || y < 0 || y >= c.h {
+		return
+	}
+	c.cells[y/4][x/2] |= dots[y%4][x%2]
+}
+
+// line draws a line from x0, y0 to x1, y1.
+func (c *canvas) line(x0, y0, x1, y1 int) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	for e := dx + dy; ; {
+		c.set(x0, y0)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * e
+		if e2 >= dy {
+			e, x0 = e+dy, x0+sx
+		}
+		if e2 <= dx {
+			e, y0 = e+dx, y0+sy
+		}
+	}
+}
+
+// rows returns the rows of the canvas.
+func (c *canvas) rows() []string {
+	rows := make([]string, len(c.cells))
+	for i, row := range c.cells {
+		rows[i] = string(row)
+	}
+	return rows
+}
+
+// bounds returns the minimum and maximum of values.
+func bounds(values []float64) (float64, float64) {
+	lo, hi := values[0], values[0]
+	for _, v := range values[1:] {
+		lo, hi = math.Min(lo, v), math.Max(hi, v)
+	}
+	return lo, hi
+}
+
+// formatValue formats a value.
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// truncate truncates s to n runes.
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return string(r[:n-1]) + "…"
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
+
+func sign(i int) int {
+	switch {
+	case i < 0:
+		return -1
+	case i > 0:
+		return 1
+	}
+	return 0
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
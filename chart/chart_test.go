@@ -0,0 +1,53 @@
+package chart
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteBar(t *testing.T) {
+	var s Series
+	for _, v := range [][2]string{{"a", "1"}, {"bb", "2.5"}, {"c", "5"}} {
+		if err := s.Add(v[0], v[1]); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	var b strings.Builder
+	if err := Write(&b, Bar, s, 24); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := "a  │███ 1\n" +
+		"bb │███████▌ 2.5\n" +
+		"c  │███████████████ 5\n"
+	if s := b.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+}
+
+func TestWriteLine(t *testing.T) {
+	s := Series{
+		Labels: []string{"mon", "tue", "wed"},
+		Values: []float64{0, 10, 5},
+	}
+	var b strings.Builder
+	if err := writeLine(&b, s, 14, 2); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := "10 ┤⠀⠀⢀⡠⠒⠉⠒⠤⣀⠀\n" +
+		" 0 ┤⡠⠔⠁⠀⠀⠀⠀⠀⠀⠉\n" +
+		"   └──────────\n" +
+		"    mon    wed\n"
+	if s := b.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	var s Series
+	if err := s.Add("a", "x"); err == nil {
+		t.Errorf("expected an error for a non numeric value")
+	}
+	if _, err := ParseKind("pie"); err == nil {
+		t.Errorf("expected an error for an unknown kind")
+	}
+}
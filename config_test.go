@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xo/usql/notify"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/redact"
+)
+
+const testConfig = `
+confirm:
+  drop: always
+  truncate: prod-only
+confirm_tags:
+  dev:
+    drop: never
+notify:
+  slack:
+    url: https://hooks.slack.com/services/T0/B0/X
+    min_duration: 1m
+  ops:
+    url: https://ops.example.com/hook
+databases:
+  prod_db:
+    name: app
+    db_type: postgres
+    tags: [prod]
+    credentials:
+      - role: admin
+        password: s3cr3t-admin
+        confirm:
+          delete_without_where: always
+      - role: ci
+        deny_statements: [DROP]
+        confirm:
+          drop: never
+          truncate: never
+  dev_db:
+    name: app
+    db_type: postgres
+    tags: [dev]
+`
+
+// loadTestConfig writes the config file buf, and loads it.
+func loadTestConfig(t *testing.T, buf string) (*config.Config, error) {
+	path := filepath.Join(t.TempDir(), "dbconfig.yaml")
+	if err := os.WriteFile(path, []byte(buf), 0o600); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	return newConfigStore(path).Config()
+}
+
+func TestInitConfig(t *testing.T) {
+	cfg, err := loadTestConfig(t, testConfig)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// the secrets of the loaded config files are redacted
+	if s := redact.String("password s3cr3t-admin"); s != "password "+redact.Mask {
+		t.Errorf("expected the password to be redacted, got: %q", s)
+	}
+	if cfg.ResolveSecret == nil || cfg.AddSecret == nil {
+		t.Errorf("expected the secret funcs to be set")
+	}
+	for _, buf := range []string{
+		"confirm:\n  dropp: always\n",
+		"confirm_tags:\n  prod:\n    drop: sometimes\n",
+		"databases:\n  x:\n    credentials:\n      - role: r\n        confirm:\n          drop: prod\n",
+	} {
+		if _, err := loadTestConfig(t, buf); err == nil || !strings.Contains(err.Error(), "invalid confirm") {
+			t.Errorf("expected invalid confirm error for %q, got: %v", buf, err)
+		}
+	}
+}
+
+func TestRolePolicy(t *testing.T) {
+	cfg, err := loadTestConfig(t, testConfig)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	p, err := rolePolicy(cfg.Databases["prod_db"], "ci")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := p.Check("drop table users"); err == nil {
+		t.Errorf("expected policy violation, got nil")
+	}
+	if p, err := rolePolicy(cfg.Databases["dev_db"], ""); p != nil || err != nil {
+		t.Errorf("expected no policy, got: %v %v", p, err)
+	}
+	tests := []struct {
+		alias, role, sqlstr, kind string
+	}{
+		{"prod_db", "", "drop table t", "drop"},
+		{"prod_db", "", "truncate t", "truncate"},
+		{"prod_db", "admin", "delete from t", "delete_without_where"},
+		{"prod_db", "ci", "drop table t", ""},
+		{"prod_db", "ci", "delete from t", ""},
+		{"dev_db", "", "drop table t", ""},
+		{"dev_db", "", "truncate t", ""},
+	}
+	for i, test := range tests {
+		c, err := roleConfirm(cfg, test.alias, test.role)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		u := c.Check(test.sqlstr, "postgres")
+		switch {
+		case test.kind == "" && u != nil:
+			t.Errorf("test %d expected no confirmation, got: %v", i, u)
+		case test.kind != "" && (u == nil || u.Kind != test.kind):
+			t.Errorf("test %d expected a %s confirmation, got: %v", i, test.kind, u)
+		}
+	}
+}
+
+func TestNewNotifier(t *testing.T) {
+	cfg, err := loadTestConfig(t, testConfig)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		name, typ string
+		min       time.Duration
+	}{
+		{"slack", notify.TypeSlack, time.Minute},
+		{"ops", notify.TypeWebhook, 0},
+	}
+	for i, test := range tests {
+		n, err := newNotifier(cfg, test.name)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if n.Type != test.typ || n.MinDuration != test.min {
+			t.Errorf("test %d expected %s notifier with minimum duration %s, got: %s, %s", i, test.typ, test.min, n.Type, n.MinDuration)
+		}
+	}
+	if _, err := newNotifier(cfg, "email"); err == nil {
+		t.Errorf("expected error for unknown notifier, got nil")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"os"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/handler"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/offline"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/pkg/conn"
+	"github.com/xo/usql/text"
+	"github.com/xo/usql/workers"
+)
+
+// openAlias opens a connection to the database alias from the config file,
+// using the credentials of args.Role, or to the URL of an ad-hoc connection.
+// Patterns of aliases are resolved to the matching alias (see selectAlias).
+// The connection is retried and the on_connect statements are executed as
+// for interactive sessions.
+func openAlias(ctx context.Context, args *Args, alias string) (*dburl.URL, *sql.DB, error) {
+	if config.IsPattern(alias) {
+		var err error
+		if alias, err = selectAlias(args, alias); err != nil {
+			return nil, nil, err
+		}
+	}
+	cfg, err := aliasConfig(args, alias)
+	if err != nil {
+		return nil, nil, err
+	}
+	u, db, err := newOpener(cfg).Open(ctx, alias, args.Role)
+	if err != nil {
+		return nil, nil, jsonout.WithCode(jsonout.CodeConnection, err)
+	}
+	if cfg.Databases[alias] == nil {
+		_ = config.SaveLastDSN(alias)
+	}
+	return u, db, nil
+}
+
+// openOffline opens the database alias of args in offline mode, from its last
+// metadata snapshot, without connecting to it.
+func openOffline(h *handler.Handler, args *Args) error {
+	if args.DB == "" {
+		return text.ErrOfflineRequiresDB
+	}
+	s, err := loadSnapshot(args.DB)
+	if err != nil {
+		return err
+	}
+	u, err := dburl.Parse(args.DSN)
+	if err != nil {
+		return err
+	}
+	h.OpenOffline(u, s)
+	return nil
+}
+
+// loadSnapshot loads the last metadata snapshot of the database alias.
+func loadSnapshot(alias string) (*offline.Snapshot, error) {
+	st, err := offline.New()
+	if err != nil {
+		return nil, err
+	}
+	return st.Load(alias)
+}
+
+// aliasConfig returns the config file of args, or an empty config when there
+// is none and alias is the URL of an ad-hoc connection (see config.IsDSN).
+func aliasConfig(args *Args, alias string) (*config.Config, error) {
+	cfg, err := loadConfig(args)
+	if err != nil && config.IsDSN(alias) {
+		return &config.Config{}, nil
+	}
+	return cfg, err
+}
+
+// newOpener creates an opener of the database aliases of the config,
+// writing to the standard outputs.
+func newOpener(cfg *config.Config) *conn.Opener {
+	return &conn.Opener{Config: cfg, Stdout: os.Stdout, Stderr: os.Stderr}
+}
+
+// newPool creates a pool of n workers for the bulk operations on the database
+// aliases from the config file, limited to their max_connections.
+func newPool(ctx context.Context, args *Args, n int) (*workers.Pool, error) {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return nil, err
+	}
+	return workers.New(ctx, n, cfg.MaxConnections), nil
+}
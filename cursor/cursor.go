@@ -0,0 +1,126 @@
+// Package cursor reads the results of queries with server-side cursors, in
+// batches, so that neither the client nor the server hold all their rows.
+package cursor
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Name is the name of the declared cursors.
+const Name = "usql_cursor"
+
+// DefaultSize is the default number of rows of the fetched batches.
+const DefaultSize = 1000
+
+// Rows are the rows of a query read from a cursor, in batches.
+type Rows struct {
+	ctx context.Context
+	tx  *sql.Tx
+	// commit is set when tx was begun for the cursor, and is committed when
+	// the rows are closed.
+	commit bool
+	size   int
+	// rows are the rows of the current batch, and n their number.
+	rows *sql.Rows
+	n    int
+	err  error
+}
+
+// Declare declares a cursor of the query sqlstr in the transaction tx,
+// returning its rows fetched in batches of size rows (DefaultSize when 0). tx is committed when the
+// rows are closed when commit is true. Cursors are supported by PostgreSQL and
+// the databases of its dialect.
+func Declare(ctx context.Context, tx *sql.Tx, commit bool, sqlstr string, size int) (*Rows, error) {
+	if size <= 0 {
+		size = DefaultSize
+	}
+	if _, err := tx.ExecContext(ctx, "DECLARE "+Name+" NO SCROLL CURSOR FOR "+sqlstr); err != nil {
+		if commit {
+			tx.Rollback()
+		}
+		return nil, err
+	}
+	r := &Rows{ctx: ctx, tx: tx, commit: commit, size: size}
+	if err := r.fetch(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// fetch fetches the next batch of rows.
+func (r *Rows) fetch() error {
+	rows, err := r.tx.QueryContext(r.ctx, fmt.Sprintf("FETCH FORWARD %d FROM %s", r.size, Name))
+	if err != nil {
+		return err
+	}
+	r.rows, r.n = rows, 0
+	return nil
+}
+
+// Next advances to the next row, fetching the next batch when the rows of
+// the current one were read.
+func (r *Rows) Next() bool {
+	for r.err == nil && r.rows != nil {
+		if r.rows.Next() {
+			r.n++
+			return true
+		}
+		if r.err = r.rows.Err(); r.err != nil {
+			return false
+		}
+		// a partial batch is the last one
+		last := r.n < r.size
+		r.rows.Close()
+		if last {
+			return false
+		}
+		r.err = r.fetch()
+	}
+	return false
+}
+
+// Scan scans the values of the current row.
+func (r *Rows) Scan(dest ...interface{}) error {
+	return r.rows.Scan(dest...)
+}
+
+// Columns returns the column names.
+func (r *Rows) Columns() ([]string, error) {
+	return r.rows.Columns()
+}
+
+// ColumnTypes returns the column types.
+func (r *Rows) ColumnTypes() ([]*sql.ColumnType, error) {
+	return r.rows.ColumnTypes()
+}
+
+// Err returns the error of the rows, if any.
+func (r *Rows) Err() error {
+	return r.err
+}
+
+// NextResultSet returns false, as a cursor has a single result set.
+func (r *Rows) NextResultSet() bool {
+	return false
+}
+
+// Close closes the cursor, and commits the transaction begun for it.
+func (r *Rows) Close() error {
+	if r.tx == nil {
+		return nil
+	}
+	if r.rows != nil {
+		r.rows.Close()
+	}
+	_, err := r.tx.ExecContext(r.ctx, "CLOSE "+Name)
+	if r.commit {
+		if e := r.tx.Commit(); err == nil {
+			err = e
+		}
+	}
+	r.tx = nil
+	return err
+}
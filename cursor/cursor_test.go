@@ -0,0 +1,133 @@
+package cursor
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRows(t *testing.T) {
+	tests := []struct {
+		rows, size int
+		fetches    int
+	}{
+		{0, 2, 1},
+		{1, 2, 1},
+		{4, 2, 3},
+		{5, 2, 3},
+		{5, 0, 1},
+	}
+	for i, test := range tests {
+		d := &fakeDriver{rows: test.rows}
+		sql.Register(fmt.Sprintf("cursor%d", i), d)
+		db, err := sql.Open(fmt.Sprintf("cursor%d", i), "")
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		ctx := context.Background()
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		r, err := Declare(ctx, tx, true, "SELECT id FROM t", test.size)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		var ids []int64
+		for r.Next() {
+			var id int64
+			if err := r.Scan(&id); err != nil {
+				t.Fatalf("test %d expected no error, got: %v", i, err)
+			}
+			ids = append(ids, id)
+		}
+		if err := r.Err(); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if err := r.Close(); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if len(ids) != test.rows || (test.rows != 0 && ids[test.rows-1] != int64(test.rows)) {
+			t.Errorf("test %d expected %d rows, got: %v", i, test.rows, ids)
+		}
+		exp := []string{"DECLARE usql_cursor NO SCROLL CURSOR FOR SELECT id FROM t"}
+		for j := 0; j < test.fetches; j++ {
+			size := test.size
+			if size == 0 {
+				size = DefaultSize
+			}
+			exp = append(exp, fmt.Sprintf("FETCH FORWARD %d FROM usql_cursor", size))
+		}
+		exp = append(exp, "CLOSE usql_cursor", "COMMIT")
+		if !reflect.DeepEqual(d.stmts, exp) {
+			t.Errorf("test %d expected statements %q, got: %q", i, exp, d.stmts)
+		}
+	}
+}
+
+// fakeDriver is a driver of a cursor of rows numbered from 1.
+type fakeDriver struct {
+	rows, fetched int
+	stmts         []string
+}
+
+func (d *fakeDriver) Open(string) (driver.Conn, error) { return d, nil }
+
+func (d *fakeDriver) Prepare(string) (driver.Stmt, error) {
+	return nil, fmt.Errorf("not supported")
+}
+
+func (d *fakeDriver) Close() error { return nil }
+
+func (d *fakeDriver) Begin() (driver.Tx, error) { return d, nil }
+
+func (d *fakeDriver) Commit() error {
+	d.stmts = append(d.stmts, "COMMIT")
+	return nil
+}
+
+func (d *fakeDriver) Rollback() error {
+	d.stmts = append(d.stmts, "ROLLBACK")
+	return nil
+}
+
+func (d *fakeDriver) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	d.stmts = append(d.stmts, query)
+	return driver.RowsAffected(0), nil
+}
+
+func (d *fakeDriver) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	d.stmts = append(d.stmts, query)
+	var n int
+	if _, err := fmt.Sscanf(strings.TrimPrefix(query, "FETCH FORWARD "), "%d", &n); err != nil {
+		return nil, err
+	}
+	from := d.fetched
+	if d.fetched += n; d.fetched > d.rows {
+		d.fetched = d.rows
+	}
+	return &fakeRows{next: from + 1, last: d.fetched}, nil
+}
+
+// fakeRows are the rows of a fetched batch.
+type fakeRows struct {
+	next, last int
+}
+
+func (r *fakeRows) Columns() []string { return []string{"id"} }
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.next > r.last {
+		return io.EOF
+	}
+	dest[0] = int64(r.next)
+	r.next++
+	return nil
+}
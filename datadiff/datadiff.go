@@ -0,0 +1,358 @@
+// Package datadiff compares the rows of a table in two databases using
+// chunked checksums.
+package datadiff
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash"
+	"hash/fnv"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/export"
+)
+
+// DefaultChunkSize is the default number of keys per chunk.
+const DefaultChunkSize = 1000
+
+// Source is a database to compare.
+type Source struct {
+	URL *dburl.URL
+	DB  *sql.DB
+}
+
+// Options are the comparison options.
+type Options struct {
+	// Table is the table to compare, optionally schema qualified.
+	Table string
+	// Key are the key columns the rows are matched and ordered by.
+	Key []string
+	// ChunkSize is the number of keys per chunk.
+	ChunkSize int
+}
+
+// Result is the result of a comparison.
+type Result struct {
+	// RowsA and RowsB are the row counts of each database.
+	RowsA, RowsB int64
+	// Chunks is the number of compared chunks.
+	Chunks int
+	// Ranges are the key ranges of the divergent chunks, with adjacent
+	// chunks merged.
+	Ranges []Range
+}
+
+// Range is a range of keys whose rows differ.
+type Range struct {
+	// From and To are the first and last keys of the range, in either
+	// database.
+	From, To []string
+	// DivergentChunks is the number of divergent chunks in the range.
+	DivergentChunks int
+	// RowsA and RowsB are the row counts in the range of each database.
+	RowsA, RowsB int64
+	// lastChunk is the number of the last chunk of the range.
+	lastChunk int
+}
+
+// Compare compares the rows of the table in databases a and b. The rows of
+// both databases are read ordered by the key, and split into chunks of
+// ChunkSize keys. The checksums of the rows of each chunk are compared.
+func Compare(ctx context.Context, a, b Source, opts Options) (*Result, error) {
+	if len(opts.Key) == 0 {
+		return nil, fmt.Errorf("no key for table %s", opts.Table)
+	}
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	ca, err := newCursor(ctx, a, opts.Table, nil, opts.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer ca.rows.Close()
+	cb, err := newCursor(ctx, b, opts.Table, ca.cols, opts.Key)
+	if err != nil {
+		return nil, err
+	}
+	defer cb.rows.Close()
+	res := new(Result)
+	var c chunk
+	for {
+		if err := ca.advance(); err != nil {
+			return nil, err
+		}
+		if err := cb.advance(); err != nil {
+			return nil, err
+		}
+		if ca.key == nil && cb.key == nil {
+			break
+		}
+		var key []string
+		switch cmp := compareKeys(ca.key, cb.key, ca.numeric); {
+		case cmp < 0:
+			key = ca.key
+			c.add(ca, nil)
+		case cmp > 0:
+			key = cb.key
+			c.add(nil, cb)
+		default:
+			key = ca.key
+			c.add(ca, cb)
+		}
+		if c.keys == 1 {
+			c.from = key
+		}
+		c.to = key
+		if c.keys == opts.ChunkSize {
+			res.addChunk(&c)
+		}
+	}
+	if c.keys != 0 {
+		res.addChunk(&c)
+	}
+	res.RowsA, res.RowsB = ca.n, cb.n
+	return res, nil
+}
+
+// addChunk adds the chunk to the result, and resets the chunk.
+func (res *Result) addChunk(c *chunk) {
+	res.Chunks++
+	if c.rowsA != c.rowsB || c.hashA.Sum64() != c.hashB.Sum64() {
+		if n := len(res.Ranges); n != 0 && res.Ranges[n-1].lastChunk == res.Chunks-1 {
+			r := &res.Ranges[n-1]
+			r.To, r.lastChunk = c.to, res.Chunks
+			r.DivergentChunks++
+			r.RowsA, r.RowsB = r.RowsA+c.rowsA, r.RowsB+c.rowsB
+		} else {
+			res.Ranges = append(res.Ranges, Range{
+				From:            c.from,
+				To:              c.to,
+				DivergentChunks: 1,
+				RowsA:           c.rowsA,
+				RowsB:           c.rowsB,
+				lastChunk:       res.Chunks,
+			})
+		}
+	}
+	*c = chunk{}
+}
+
+// chunk is a chunk of keys being compared.
+type chunk struct {
+	keys         int
+	from, to     []string
+	rowsA, rowsB int64
+	hashA, hashB hash.Hash64
+}
+
+// add adds the current rows of the cursors to the chunk. A nil cursor has no
+// row for the key.
+func (c *chunk) add(a, b *cursor) {
+	if c.hashA == nil {
+		c.hashA, c.hashB = fnv.New64a(), fnv.New64a()
+	}
+	c.keys++
+	if a != nil {
+		c.rowsA++
+		a.write(c.hashA)
+		a.consume()
+	}
+	if b != nil {
+		c.rowsB++
+		b.write(c.hashB)
+		b.consume()
+	}
+}
+
+// cursor reads the rows of a table ordered by its key.
+type cursor struct {
+	rows   *sql.Rows
+	cols   []string
+	keyPos []int
+	// numeric is set for the numeric key columns.
+	numeric []bool
+	values  []interface{}
+	// key is the key of the current row, nil at the end of the rows.
+	key []string
+	// norm are the normalized values of the current row.
+	norm []string
+	// pending is set when the current row has not been consumed.
+	pending bool
+	// done is set at the end of the rows.
+	done bool
+	n    int64
+}
+
+// newCursor queries the columns of the table, all columns when cols is nil,
+// ordered by the key.
+func newCursor(ctx context.Context, src Source, table string, cols, key []string) (*cursor, error) {
+	d := dump.DialectFor(drivers.Caps(src.URL).Dialect)
+	schema, name := "", table
+	if i := strings.LastIndex(table, "."); i != -1 {
+		schema, name = table[:i], table[i+1:]
+	}
+	list := "*"
+	if cols != nil {
+		list = d.QuoteIdents(cols)
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", list, d.Qualify(schema, name), d.QuoteIdents(key))
+	rows, err := src.DB.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		rows.Close()
+		return nil, err
+	}
+	c := &cursor{rows: rows}
+	for _, ct := range cts {
+		c.cols = append(c.cols, ct.Name())
+	}
+	for _, k := range key {
+		pos := -1
+		for i, col := range c.cols {
+			if strings.EqualFold(col, k) {
+				pos = i
+			}
+		}
+		if pos == -1 {
+			rows.Close()
+			return nil, fmt.Errorf("table %s has no column %s", table, k)
+		}
+		kind := export.Kind(cts[pos])
+		c.keyPos = append(c.keyPos, pos)
+		c.numeric = append(c.numeric, kind == export.KindInt || kind == export.KindFloat)
+	}
+	c.values = make([]interface{}, len(c.cols))
+	for i := range c.values {
+		c.values[i] = new(interface{})
+	}
+	return c, nil
+}
+
+// advance reads the next row when the current row was consumed.
+func (c *cursor) advance() error {
+	if c.pending || c.done {
+		return nil
+	}
+	if !c.rows.Next() {
+		c.key, c.done = nil, true
+		return c.rows.Err()
+	}
+	if err := c.rows.Scan(c.values...); err != nil {
+		return err
+	}
+	c.norm = make([]string, len(c.values))
+	for i, v := range c.values {
+		c.norm[i] = normalize(*(v.(*interface{})))
+	}
+	key := make([]string, len(c.keyPos))
+	for i, pos := range c.keyPos {
+		key[i] = c.norm[pos]
+	}
+	if c.key != nil && compareKeys(c.key, key, c.numeric) > 0 {
+		return fmt.Errorf("rows are not ordered by key (%s after %s): use numeric keys or a binary collation", FormatKey(key), FormatKey(c.key))
+	}
+	c.key, c.pending = key, true
+	c.n++
+	return nil
+}
+
+// consume marks the current row as consumed.
+func (c *cursor) consume() {
+	c.pending = false
+}
+
+// write writes the current row to h.
+func (c *cursor) write(h hash.Hash64) {
+	for _, s := range c.norm {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+	h.Write([]byte{'\n'})
+}
+
+// null is the normalized value of NULL, which cannot clash with strings as
+// values are hashed with a terminating NUL.
+const null = "\x00"
+
+// normalize returns v as a string, so that equal values read from different
+// databases compare equal.
+func normalize(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return null
+	case []byte:
+		return string(x)
+	case string:
+		return x
+	case bool:
+		if x {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case time.Time:
+		return x.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// compareKeys compares keys a and b, with nil, the end of the rows, after all
+// keys. The values of numeric key columns are compared as numbers, other
+// values byte-wise.
+func compareKeys(a, b []string, numeric []bool) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	for i := range a {
+		if cmp := compareValues(a[i], b[i], numeric[i]); cmp != 0 {
+			return cmp
+		}
+	}
+	return 0
+}
+
+// compareValues compares the normalized values a and b.
+func compareValues(a, b string, numeric bool) int {
+	if !numeric {
+		return strings.Compare(a, b)
+	}
+	if x, ok := new(big.Float).SetString(a); ok {
+		if y, ok := new(big.Float).SetString(b); ok {
+			return x.Cmp(y)
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// FormatKey formats a key for display.
+func FormatKey(key []string) string {
+	strs := make([]string, len(key))
+	for i, s := range key {
+		if s == null {
+			s = "NULL"
+		}
+		strs[i] = s
+	}
+	if len(strs) == 1 {
+		return strs[0]
+	}
+	return "(" + strings.Join(strs, ", ") + ")"
+}
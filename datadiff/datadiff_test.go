@@ -0,0 +1,46 @@
+package datadiff
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompareKeys(t *testing.T) {
+	tests := []struct {
+		a, b    []string
+		numeric []bool
+		exp     int
+	}{
+		{[]string{"9"}, []string{"10"}, []bool{true}, -1},
+		{[]string{"9"}, []string{"10"}, []bool{false}, 1},
+		{[]string{"1.50"}, []string{"1.5"}, []bool{true}, 0},
+		{[]string{"a", "2"}, []string{"a", "1"}, []bool{false, true}, 1},
+		{[]string{"a"}, nil, []bool{false}, -1},
+		{nil, []string{"a"}, []bool{false}, 1},
+		{nil, nil, []bool{false}, 0},
+	}
+	for i, test := range tests {
+		if cmp := compareKeys(test.a, test.b, test.numeric); cmp != test.exp {
+			t.Errorf("test %d expected %d, got: %d", i, test.exp, cmp)
+		}
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		v   interface{}
+		exp string
+	}{
+		{nil, null},
+		{[]byte("abc"), "abc"},
+		{true, "1"},
+		{int64(42), "42"},
+		{1.5, "1.5"},
+		{time.Date(2024, 1, 2, 4, 4, 5, 0, time.FixedZone("", 3600)), "2024-01-02T03:04:05Z"},
+	}
+	for i, test := range tests {
+		if s := normalize(test.v); s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Package deps builds the foreign key dependency graph of tables, ordering
+// the tables so that the tables referenced by foreign keys come first.
+package deps
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xo/usql/dump"
+)
+
+// Graph is the foreign key dependency graph of tables. Tables are matched by
+// their case-insensitive name.
+type Graph struct {
+	// names are the names of the tables, in their order.
+	names []string
+	// refs are the tables referenced by the foreign keys of the tables, but
+	// themselves and the tables not in the graph, by lower cased name.
+	refs map[string][]string
+}
+
+// New returns the dependency graph of the tables, keeping their order.
+func New(tables []*dump.Table) *Graph {
+	g := &Graph{refs: make(map[string][]string)}
+	in := make(map[string]bool, len(tables))
+	for _, t := range tables {
+		if key := strings.ToLower(t.Name); !in[key] {
+			g.names, in[key] = append(g.names, t.Name), true
+		}
+	}
+	for _, t := range tables {
+		key := strings.ToLower(t.Name)
+		for _, c := range t.Constraints {
+			ref := strings.ToLower(c.ForeignTable)
+			if c.Type != "FOREIGN KEY" || ref == key || !in[ref] || contains(g.refs[key], ref) {
+				continue
+			}
+			g.refs[key] = append(g.refs[key], ref)
+		}
+	}
+	return g
+}
+
+// Order returns the names of the tables ordered so that the tables
+// referenced by foreign keys come first, keeping the order of the graph
+// otherwise. Tables are deleted or truncated safely in the reverse order. An
+// error is returned when foreign keys are circular.
+func (g *Graph) Order() ([]string, error) {
+	pending := make(map[string]bool, len(g.names))
+	for _, name := range g.names {
+		pending[strings.ToLower(name)] = true
+	}
+	var sorted []string
+	for len(sorted) < len(g.names) {
+		var next []string
+		for _, name := range g.names {
+			key := strings.ToLower(name)
+			if !pending[key] {
+				continue
+			}
+			ready := true
+			for _, ref := range g.refs[key] {
+				ready = ready && !pending[ref]
+			}
+			if ready {
+				next = append(next, name)
+			}
+		}
+		if len(next) == 0 {
+			var names []string
+			for name := range pending {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return nil, fmt.Errorf("circular foreign keys between tables %s", strings.Join(names, ", "))
+		}
+		for _, name := range next {
+			delete(pending, strings.ToLower(name))
+		}
+		sorted = append(sorted, next...)
+	}
+	return sorted, nil
+}
+
+// References returns the names of the tables referenced by the foreign keys
+// of the table, but itself.
+func (g *Graph) References(table string) []string {
+	var names []string
+	for _, ref := range g.refs[strings.ToLower(table)] {
+		names = append(names, g.name(ref))
+	}
+	return names
+}
+
+// Related returns the graph of the table, of the tables it references and of
+// the tables referencing it, transitively.
+func (g *Graph) Related(table string) (*Graph, error) {
+	key := strings.ToLower(table)
+	if g.name(key) == "" {
+		return nil, fmt.Errorf("table %s does not exist", table)
+	}
+	// tables referencing the tables, by lower cased name
+	referencing := make(map[string][]string)
+	for _, name := range g.names {
+		for _, ref := range g.refs[strings.ToLower(name)] {
+			referencing[ref] = append(referencing[ref], strings.ToLower(name))
+		}
+	}
+	keep := map[string]bool{key: true}
+	for _, edges := range []map[string][]string{g.refs, referencing} {
+		queue := []string{key}
+		for len(queue) != 0 {
+			k := queue[0]
+			queue = queue[1:]
+			for _, next := range edges[k] {
+				if !keep[next] {
+					keep[next] = true
+					queue = append(queue, next)
+				}
+			}
+		}
+	}
+	sub := &Graph{refs: make(map[string][]string)}
+	for _, name := range g.names {
+		if k := strings.ToLower(name); keep[k] {
+			sub.names, sub.refs[k] = append(sub.names, name), g.refs[k]
+		}
+	}
+	return sub, nil
+}
+
+// WriteDOT writes the graph in the Graphviz DOT language, with an edge from
+// each table to the tables it references.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var sb strings.Builder
+	sb.WriteString("digraph deps {\n\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, name := range g.names {
+		fmt.Fprintf(&sb, "\t%q;\n", name)
+	}
+	for _, name := range g.names {
+		for _, ref := range g.refs[strings.ToLower(name)] {
+			fmt.Fprintf(&sb, "\t%q -> %q;\n", name, g.name(ref))
+		}
+	}
+	sb.WriteString("}\n")
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// name returns the name of the table of the graph with the lower cased name,
+// or an empty string.
+func (g *Graph) name(key string) string {
+	for _, name := range g.names {
+		if strings.ToLower(name) == key {
+			return name
+		}
+	}
+	return ""
+}
+
+// contains returns true when strs contains s.
+func contains(strs []string, s string) bool {
+	for _, v := range strs {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,87 @@
+package deps
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xo/usql/dump"
+)
+
+func testTables() []*dump.Table {
+	fk := func(table string) dump.Constraint {
+		return dump.Constraint{Type: "FOREIGN KEY", ForeignTable: table}
+	}
+	return []*dump.Table{
+		{Name: "audit"},
+		{Name: "order_items", Constraints: []dump.Constraint{fk("orders"), fk("products"), fk("Orders")}},
+		{Name: "orders", Constraints: []dump.Constraint{fk("customers"), fk("orders")}},
+		{Name: "products"},
+		{Name: "Customers"},
+	}
+}
+
+func TestOrder(t *testing.T) {
+	tables := testTables()
+	order, err := New(tables).Order()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := []string{"audit", "products", "Customers", "orders", "order_items"}; !reflect.DeepEqual(order, exp) {
+		t.Errorf("expected %v, got: %v", exp, order)
+	}
+	tables[4].Constraints = []dump.Constraint{{Type: "FOREIGN KEY", ForeignTable: "order_items"}}
+	if _, err := New(tables).Order(); err == nil || !strings.Contains(err.Error(), "customers, order_items, orders") {
+		t.Errorf("expected circular foreign keys error, got: %v", err)
+	}
+}
+
+func TestRelated(t *testing.T) {
+	g := New(testTables())
+	tests := []struct {
+		table string
+		exp   []string
+	}{
+		{"orders", []string{"Customers", "orders", "order_items"}},
+		{"customers", []string{"Customers", "orders", "order_items"}},
+		{"order_items", []string{"products", "Customers", "orders", "order_items"}},
+		{"audit", []string{"audit"}},
+	}
+	for i, test := range tests {
+		sub, err := g.Related(test.table)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		order, err := sub.Order()
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if !reflect.DeepEqual(order, test.exp) {
+			t.Errorf("test %d expected %v, got: %v", i, test.exp, order)
+		}
+	}
+	if _, err := g.Related("missing"); err == nil {
+		t.Errorf("expected error for missing table, got nil")
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	var sb strings.Builder
+	if err := New(testTables()[1:3]).WriteDOT(&sb); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := "digraph deps {\n\trankdir=LR;\n\tnode [shape=box];\n\t\"order_items\";\n\t\"orders\";\n\t\"order_items\" -> \"orders\";\n}\n"
+	if s := sb.String(); s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+}
+
+func TestReferences(t *testing.T) {
+	g := New(testTables())
+	if refs, exp := g.References("ORDER_ITEMS"), []string{"orders", "products"}; !reflect.DeepEqual(refs, exp) {
+		t.Errorf("expected %v, got: %v", exp, refs)
+	}
+	if refs := g.References("orders"); !reflect.DeepEqual(refs, []string{"Customers"}) {
+		t.Errorf("expected [Customers], got: %v", refs)
+	}
+}
@@ -0,0 +1,223 @@
+// Package display prepares the values of result sets for their display in
+// text tables, so that their cells stay aligned in a terminal.
+package display
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/rivo/uniseg"
+	"github.com/xo/tblfmt"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Marker and ASCIIMarker are the markers of the truncated values.
+const (
+	Marker      = "…"
+	ASCIIMarker = "..."
+)
+
+// tabWidth is the width of the tab stops of the values.
+const tabWidth = 8
+
+// Width returns the display width of the line of a value, as measured by the
+// table encoders: graphic runes count their width, and the other runes the
+// width of their escape.
+func Width(s string) int {
+	var width int
+	for _, r := range s {
+		width += runeWidth(r)
+	}
+	return width
+}
+
+// runeWidth returns the display width of the rune, as measured by the table
+// encoders.
+func runeWidth(r rune) int {
+	if strconv.IsGraphic(r) {
+		return runewidth.RuneWidth(r)
+	}
+	return len(strconv.QuoteRune(r)) - 2
+}
+
+// Value prepares the value for its display: its combining marks are composed,
+// its tabs are expanded, the grapheme clusters whose runes are measured with
+// another width than the cluster are reduced to their base rune, and its
+// lines wider than max are truncated with the marker. Printable ASCII values
+// fitting in max are kept as is.
+func Value(s string, max int, marker string) string {
+	if simple(s, max) {
+		return s
+	}
+	s = norm.NFC.String(s)
+	lines := strings.Split(s, "\n")
+	for i, line := range lines {
+		lines[i] = displayLine(line, max, marker)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// simple returns true when the value is printable ASCII without tabs, with
+// lines fitting in max.
+func simple(s string, max int) bool {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\n':
+			if max > 0 && i-start > max {
+				return false
+			}
+			start = i + 1
+		case c < ' ' || c >= utf8.RuneSelf:
+			return false
+		}
+	}
+	return max <= 0 || len(s)-start <= max
+}
+
+// displayLine prepares the line of a value for its display.
+func displayLine(line string, max int, marker string) string {
+	// split the line in the units measured by the encoders
+	var units []string
+	var lineWidth int
+	add := func(u string) {
+		units = append(units, u)
+		lineWidth += Width(u)
+	}
+	state := -1
+	for rest := line; rest != ""; {
+		var cluster string
+		var w int
+		cluster, rest, w, state = uniseg.FirstGraphemeClusterInString(rest, state)
+		switch {
+		case cluster == "\t":
+			add(strings.Repeat(" ", tabWidth-lineWidth%tabWidth))
+		case utf8.RuneCountInString(cluster) == 1:
+			add(cluster)
+		case !graphic(cluster):
+			// the runes of clusters with escaped runes are written apart
+			for _, r := range cluster {
+				add(string(r))
+			}
+		case Width(cluster) != w:
+			// the encoders measure the runes of the cluster separately: the
+			// clusters displayed with another width are reduced to their base
+			r, _ := utf8.DecodeRuneInString(cluster)
+			add(string(r))
+		default:
+			add(cluster)
+		}
+	}
+	if max <= 0 || lineWidth <= max {
+		return strings.Join(units, "")
+	}
+	// the marker is dropped when there is no room for it
+	markerWidth := Width(marker)
+	if max <= markerWidth {
+		marker, markerWidth = "", 0
+	}
+	var sb strings.Builder
+	var width int
+	for _, u := range units {
+		w := Width(u)
+		if width+w > max-markerWidth {
+			break
+		}
+		sb.WriteString(u)
+		width += w
+	}
+	return sb.String() + marker
+}
+
+// graphic returns true when all the runes of the cluster are graphic.
+func graphic(cluster string) bool {
+	for _, r := range cluster {
+		if !strconv.IsGraphic(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// ResultSet wraps a result set, preparing its column names and values for
+// their display.
+type ResultSet struct {
+	tblfmt.ResultSet
+	max    int
+	marker string
+}
+
+// New wraps the result set, preparing its column names and values for their
+// display, with their lines wider than max truncated with the marker. Lines
+// are not truncated when max is 0.
+func New(resultSet tblfmt.ResultSet, max int, marker string) *ResultSet {
+	return &ResultSet{ResultSet: resultSet, max: max, marker: marker}
+}
+
+// Columns returns the column names.
+func (rs *ResultSet) Columns() ([]string, error) {
+	cols, err := rs.ResultSet.Columns()
+	if err != nil {
+		return nil, err
+	}
+	for i, col := range cols {
+		cols[i] = Value(col, rs.max, rs.marker)
+	}
+	return cols, nil
+}
+
+// ColumnTypes returns the column types of the wrapped result set.
+func (rs *ResultSet) ColumnTypes() ([]*sql.ColumnType, error) {
+	z, ok := rs.ResultSet.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+	if !ok {
+		return nil, tblfmt.ErrResultSetHasNoColumnTypes
+	}
+	return z.ColumnTypes()
+}
+
+// Scan scans the values of the current row to dest, preparing the text
+// values for their display.
+func (rs *ResultSet) Scan(dest ...interface{}) error {
+	if err := rs.ResultSet.Scan(dest...); err != nil {
+		return err
+	}
+	for _, d := range dest {
+		switch p := d.(type) {
+		case *interface{}:
+			switch v := (*p).(type) {
+			case string:
+				*p = Value(v, rs.max, rs.marker)
+			case []byte:
+				*p = rs.bytes(v)
+			}
+		case *string:
+			*p = Value(*p, rs.max, rs.marker)
+		case *[]byte:
+			*p = rs.bytes(*p)
+		case *sql.RawBytes:
+			*p = rs.bytes(*p)
+		case *sql.NullString:
+			if p.Valid {
+				p.String = Value(p.String, rs.max, rs.marker)
+			}
+		}
+	}
+	return nil
+}
+
+// bytes prepares the bytes of a text value for their display, binary values
+// being kept as is.
+func (rs *ResultSet) bytes(b []byte) []byte {
+	if b == nil || !utf8.Valid(b) {
+		return b
+	}
+	if s := Value(string(b), rs.max, rs.marker); s != string(b) {
+		return []byte(s)
+	}
+	return b
+}
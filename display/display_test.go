@@ -0,0 +1,109 @@
+package display
+
+import (
+	"database/sql"
+	"reflect"
+	"strconv"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		s      string
+		max    int
+		marker string
+		exp    string
+	}{
+		{"abc", 0, Marker, "abc"},
+		{"abcdef", 4, Marker, "abc…"},
+		{"abcd", 4, Marker, "abcd"},
+		{"abcdef", 5, ASCIIMarker, "ab..."},
+		{"abcdef", 3, ASCIIMarker, "abc"},
+		{"line one\nline two", 6, Marker, "line …\nline …"},
+		{"short\nlong line", 6, Marker, "short\nlong …"},
+		{"日本語テキスト", 7, Marker, "日本語…"},
+		{"日本語テキスト", 8, Marker, "日本語…"},
+		{"日本語", 6, Marker, "日本語"},
+		// combining marks are composed
+		{"cafe\u0301", 0, Marker, "café"},
+		{"cafe\u0301s", 4, Marker, "caf…"},
+		// tabs are expanded to the tab stops
+		{"a\tb", 0, Marker, "a       b"},
+		{"abcdefgh\tb", 0, Marker, "abcdefgh        b"},
+		// emoji modifiers measured apart are reduced to their base
+		{"👍🏽 ok", 0, Marker, "👍 ok"},
+		// escaped runes are truncated apart
+		{"👩\u200d💻 dev", 9, Marker, "👩\u200d…"},
+		{"x\u200by", 0, Marker, "x\u200by"},
+	}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if s := Value(test.s, test.max, test.marker); s != test.exp {
+				t.Errorf("expected %q, got: %q", test.exp, s)
+			}
+		})
+	}
+}
+
+func TestWidth(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp int
+	}{
+		{"abc", 3},
+		{"日本", 4},
+		{"ｶﾀｶﾅ", 4},
+		{"x\u200by", 8},
+	}
+	for i, test := range tests {
+		if n := Width(test.s); n != test.exp {
+			t.Errorf("test %d expected width of %q to be %d, got: %d", i, test.s, test.exp, n)
+		}
+	}
+}
+
+func TestResultSet(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT 1 AS id, 'a long value' AS long_name, CAST('bytes value' AS BLOB) AS b, NULL AS n`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer rows.Close()
+	rs := New(rows, 6, Marker)
+	cols, err := rs.Columns()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := []string{"id", "long_…", "b", "n"}; !reflect.DeepEqual(cols, exp) {
+		t.Errorf("expected columns %q, got: %q", exp, cols)
+	}
+	if !rs.Next() {
+		t.Fatalf("expected a row, got: %v", rs.Err())
+	}
+	vals := make([]interface{}, 4)
+	dest := make([]interface{}, 4)
+	for i := range vals {
+		dest[i] = &vals[i]
+	}
+	if err := rs.Scan(dest...); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if vals[0] != int64(1) {
+		t.Errorf("expected 1, got: %#v", vals[0])
+	}
+	if vals[1] != "a lon…" {
+		t.Errorf("expected truncated value, got: %#v", vals[1])
+	}
+	if b, ok := vals[2].([]byte); !ok || string(b) != "bytes…" {
+		t.Errorf("expected truncated bytes, got: %#v", vals[2])
+	}
+	if vals[3] != nil {
+		t.Errorf("expected nil, got: %#v", vals[3])
+	}
+}
@@ -4,10 +4,92 @@
 package bigquery
 
 import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"cloud.google.com/go/bigquery"
+	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
+	"google.golang.org/api/option"
 	_ "gorm.io/driver/bigquery/driver" // DRIVER
 )
 
 func init() {
-	drivers.Register("bigquery", drivers.Driver{})
+	drivers.Register("bigquery", drivers.Driver{
+		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(name string, dsn string) (driver.Connector, error) {
+				// the driver only uses the application default credentials
+				if file := u.Query().Get("credentials_file"); file != "" {
+					if err := os.Setenv("GOOGLE_APPLICATION_CREDENTIALS", file); err != nil {
+						return nil, err
+					}
+				}
+				return drivers.Connector(name, dsn)
+			}, nil
+		},
+		Estimate: estimate,
+	})
+}
+
+// tebibytePrice is the on-demand price of a TiB processed by queries, in US
+// dollars.
+const tebibytePrice = 6.25
+
+// clients are the BigQuery clients of the dry runs, by project and
+// credentials file.
+var clients sync.Map
+
+// estimate returns the bytes the query will process and their on-demand
+// price, using a dry run of the query.
+func estimate(ctx context.Context, u *dburl.URL, query string) (string, error) {
+	project, file := u.Hostname(), u.Query().Get("credentials_file")
+	key := project + "\x00" + file
+	v, ok := clients.Load(key)
+	if !ok {
+		var opts []option.ClientOption
+		if file != "" {
+			opts = append(opts, option.WithCredentialsFile(file))
+		}
+		client, err := bigquery.NewClient(context.Background(), project, opts...)
+		if err != nil {
+			return "", err
+		}
+		v, _ = clients.LoadOrStore(key, client)
+	}
+	q := v.(*bigquery.Client).Query(query)
+	q.DryRun, q.DefaultProjectID = true, project
+	// the path is /DATASET or /LOCATION/DATASET
+	if fields := strings.Split(strings.TrimPrefix(u.Path, "/"), "/"); len(fields) == 2 {
+		q.Location, q.DefaultDatasetID = fields[0], fields[1]
+	} else {
+		q.DefaultDatasetID = fields[0]
+	}
+	job, err := q.Run(ctx)
+	if err != nil {
+		return "", err
+	}
+	status := job.LastStatus()
+	if status == nil || status.Statistics == nil {
+		return "", nil
+	}
+	n := status.Statistics.TotalBytesProcessed
+	return fmt.Sprintf("estimate: the query will process %s, about $%.4f on demand", formatBytes(n), float64(n)/(1<<40)*tebibytePrice), nil
+}
+
+// formatBytes formats n bytes with a binary unit.
+func formatBytes(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	f, i := float64(n)/1024, 0
+	for ; f >= 1024 && i < len(units)-1; i++ {
+		f /= 1024
+	}
+	return fmt.Sprintf("%.1f %ciB", f, units[i])
 }
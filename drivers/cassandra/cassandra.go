@@ -6,13 +6,19 @@ package cassandra
 import (
 	"context"
 	"database/sql"
-	"encoding/json"
+	"database/sql/driver"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"log"
+	"net"
+	"net/url"
 	"os"
+	"reflect"
 	"regexp"
+	"sort"
 	"strings"
+	"time"
 
 	cql "github.com/MichaelS11/go-cql-driver" // DRIVER: cql
 	"github.com/gocql/gocql"
@@ -70,17 +76,27 @@ func init() {
 		AllowMultilineComments: true,
 		AllowCComments:         true,
 		LexerName:              "cql",
+		NoTransactions:         true,
 		ForceParams: func(u *dburl.URL) {
 			if q := u.Query(); q.Get("timeout") == "" {
 				q.Set("timeout", "300s")
 				u.RawQuery = q.Encode()
 			}
 		},
-		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (*sql.DB, error), error) {
+		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (driver.Connector, error), error) {
 			// override cql and gocql loggers
 			l = &logger{debug: debug}
 			gocql.Logger, cql.CqlDriver.Logger = l, log.New(l, "", 0)
-			return sql.Open, nil
+			return func(_ string, dsn string) (driver.Connector, error) {
+				config, err := clusterConfig(dsn)
+				if err != nil {
+					return nil, err
+				}
+				return &cql.CqlConnector{
+					Logger:        cql.CqlDriver.Logger,
+					ClusterConfig: config,
+				}, nil
+			}, nil
 		},
 		Version: func(ctx context.Context, db drivers.DB) (string, error) {
 			var release, protocol, cql string
@@ -112,15 +128,136 @@ func init() {
 		RowsAffected: func(sql.Result) (int64, error) {
 			return 0, nil
 		},
+		ConvertBytes: func(buf []byte, _ string) (string, error) {
+			return "0x" + hex.EncodeToString(buf), nil
+		},
+		ConvertMap: func(v map[string]interface{}) (string, error) {
+			return convert(v), nil
+		},
+		ConvertSlice: func(v []interface{}) (string, error) {
+			return convert(v), nil
+		},
 		ConvertDefault: func(v interface{}) (string, error) {
-			buf, err := json.Marshal(v)
-			if err != nil {
-				return "", err
-			}
-			return string(buf), nil
+			return convert(v), nil
 		},
 		BatchQueryPrefixes: map[string]string{
 			"BEGIN BATCH": "APPLY BATCH",
 		},
 	})
 }
+
+// clusterConfig returns the cluster config of the dsn, HOST:PORT,HOST?OPTIONS,
+// with the port of the last host used by the hosts without port, and the
+// nodes of the datacenter option queried first.
+func clusterConfig(dsn string) (*gocql.ClusterConfig, error) {
+	s, opts, _ := strings.Cut(dsn, "?")
+	q, err := url.ParseQuery(opts)
+	if err != nil {
+		return nil, err
+	}
+	hosts := strings.Split(s, ",")
+	if _, port, err := net.SplitHostPort(hosts[len(hosts)-1]); err == nil {
+		for i, host := range hosts {
+			if _, _, err := net.SplitHostPort(host); err != nil {
+				hosts[i] = net.JoinHostPort(host, port)
+			}
+		}
+	}
+	datacenter := q.Get("datacenter")
+	q.Del("datacenter")
+	s = strings.Join(hosts, ",")
+	if len(q) != 0 {
+		s += "?" + q.Encode()
+	}
+	config, err := cql.ConfigStringToClusterConfig(s)
+	if err != nil {
+		return nil, err
+	}
+	if datacenter != "" {
+		config.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.DCAwareRoundRobinPolicy(datacenter))
+	}
+	return config, nil
+}
+
+// convert converts the value v to its CQL literal: lists and sets as [...],
+// maps and user defined types as {...}, and blobs as 0x....
+func convert(v interface{}) string {
+	var sb strings.Builder
+	writeValue(&sb, reflect.ValueOf(v), false)
+	return sb.String()
+}
+
+// writeValue writes the value v, quoted when inside a collection.
+func writeValue(sb *strings.Builder, v reflect.Value, quote bool) {
+	if !v.IsValid() {
+		sb.WriteString("null")
+		return
+	}
+	switch x := v.Interface().(type) {
+	case []byte:
+		sb.WriteString("0x" + hex.EncodeToString(x))
+		return
+	case time.Time:
+		writeString(sb, x.UTC().Format("2006-01-02 15:04:05.000000-0700"), quote)
+		return
+	case fmt.Stringer:
+		// uuids, inets, decimals and varints are not quoted
+		if v.Kind() != reflect.Ptr || !v.IsNil() {
+			sb.WriteString(x.String())
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			sb.WriteString("null")
+			return
+		}
+		writeValue(sb, v.Elem(), quote)
+	case reflect.String:
+		writeString(sb, v.String(), quote)
+	case reflect.Slice, reflect.Array:
+		sb.WriteByte('[')
+		for i := 0; i < v.Len(); i++ {
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			writeValue(sb, v.Index(i), true)
+		}
+		sb.WriteByte(']')
+	case reflect.Map:
+		// the fields of user defined types are not quoted
+		udt := v.Type().Key().Kind() == reflect.String && v.Type().Elem().Kind() == reflect.Interface
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]string, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			var k, val strings.Builder
+			writeValue(&k, it.Key(), !udt)
+			writeValue(&val, it.Value(), true)
+			keys = append(keys, k.String())
+			values[k.String()] = val.String()
+		}
+		sort.Strings(keys)
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i != 0 {
+				sb.WriteString(", ")
+			}
+			sb.WriteString(k + ": " + values[k])
+		}
+		sb.WriteByte('}')
+	default:
+		fmt.Fprint(sb, v.Interface())
+	}
+}
+
+// writeString writes s, quoted and escaped when quote is set.
+func writeString(sb *strings.Builder, s string, quote bool) {
+	if !quote {
+		sb.WriteString(s)
+		return
+	}
+	sb.WriteByte('\'')
+	sb.WriteString(strings.ReplaceAll(s, "'", "''"))
+	sb.WriteByte('\'')
+}
@@ -5,18 +5,154 @@ package clickhouse
 
 import (
 	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	_ "github.com/ClickHouse/clickhouse-go/v2" // DRIVER
+	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
 )
 
 func init() {
 	drivers.Register("clickhouse", drivers.Driver{
 		AllowMultilineComments: true,
+		NoSavepoints:           true,
+		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(name string, dsn string) (driver.Connector, error) {
+				dsn, err := protocolDSN(dsn)
+				if err != nil {
+					return nil, err
+				}
+				return drivers.Connector(name, dsn)
+			}, nil
+		},
 		RowsAffected: func(sql.Result) (int64, error) {
 			return 0, nil
 		},
+		ConvertSlice: func(v []interface{}) (string, error) {
+			return convert(v), nil
+		},
+		ConvertDefault: func(v interface{}) (string, error) {
+			return convert(v), nil
+		},
 		Copy:              drivers.CopyWithInsert(func(int) string { return "?" }),
 		NewMetadataReader: NewMetadataReader,
 	})
 }
+
+// protocolDSN returns the DSN of the HTTP protocol, http://, or https://
+// with secure=true, when the protocol=http parameter of dsn is set, as the
+// protocol is selected by the scheme of the DSN.
+func protocolDSN(dsn string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	switch q.Get("protocol") {
+	case "", "native":
+	case "http":
+		u.Scheme = "http"
+		if q.Get("secure") == "true" {
+			u.Scheme = "https"
+		}
+	default:
+		return "", fmt.Errorf("invalid protocol %q: expected native or http", q.Get("protocol"))
+	}
+	if !q.Has("protocol") {
+		return dsn, nil
+	}
+	q.Del("protocol")
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// convert renders v as ClickHouse does: arrays as [1,2], maps as {'a':1},
+// tuples as (1,'a'), with the strings and dates quoted inside, and the
+// DateTime64 values with their fractional seconds.
+func convert(v interface{}) string {
+	var sb strings.Builder
+	writeValue(&sb, reflect.ValueOf(v), false)
+	return sb.String()
+}
+
+// writeValue writes the value v, quoted when inside an array, map or tuple.
+func writeValue(sb *strings.Builder, v reflect.Value, quote bool) {
+	if !v.IsValid() {
+		sb.WriteString("NULL")
+		return
+	}
+	switch x := v.Interface().(type) {
+	case time.Time:
+		writeString(sb, x.Format("2006-01-02 15:04:05.999999999"), quote)
+		return
+	case fmt.Stringer:
+		if v.Kind() != reflect.Ptr || !v.IsNil() {
+			writeString(sb, x.String(), quote)
+			return
+		}
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			sb.WriteString("NULL")
+			return
+		}
+		writeValue(sb, v.Elem(), quote)
+	case reflect.String:
+		writeString(sb, v.String(), quote)
+	case reflect.Slice, reflect.Array:
+		// tuples are the only untyped slices
+		start, end := "[", "]"
+		if v.Type().Elem().Kind() == reflect.Interface {
+			start, end = "(", ")"
+		}
+		sb.WriteString(start)
+		for i := 0; i < v.Len(); i++ {
+			if i != 0 {
+				sb.WriteByte(',')
+			}
+			writeValue(sb, v.Index(i), true)
+		}
+		sb.WriteString(end)
+	case reflect.Map:
+		// sort the keys, as ClickHouse does not keep them ordered
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]string, v.Len())
+		for it := v.MapRange(); it.Next(); {
+			var k, val strings.Builder
+			writeValue(&k, it.Key(), true)
+			writeValue(&val, it.Value(), true)
+			keys = append(keys, k.String())
+			values[k.String()] = val.String()
+		}
+		sort.Strings(keys)
+		sb.WriteByte('{')
+		for i, k := range keys {
+			if i != 0 {
+				sb.WriteByte(',')
+			}
+			sb.WriteString(k + ":" + values[k])
+		}
+		sb.WriteByte('}')
+	default:
+		fmt.Fprint(sb, v.Interface())
+	}
+}
+
+// writeString writes s, quoted and escaped when quote is set.
+func writeString(sb *strings.Builder, s string, quote bool) {
+	if !quote {
+		sb.WriteString(s)
+		return
+	}
+	sb.WriteByte('\'')
+	sb.WriteString(strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s))
+	sb.WriteByte('\'')
+}
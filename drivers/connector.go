@@ -0,0 +1,113 @@
+package drivers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Connector returns the connector of the registered driver for the DSN, as
+// used by sql.Open.
+func Connector(name, dsn string) (driver.Connector, error) {
+	// sql.Open only looks up the driver, without connecting
+	db, err := sql.Open(name, dsn)
+	if err != nil {
+		return nil, err
+	}
+	d := db.Driver()
+	if err := db.Close(); err != nil {
+		return nil, err
+	}
+	if dc, ok := d.(driver.DriverContext); ok {
+		return dc.OpenConnector(dsn)
+	}
+	return dsnConnector{d: d, dsn: dsn}, nil
+}
+
+// dsnConnector is the connector of a driver not implementing
+// driver.DriverContext.
+type dsnConnector struct {
+	d   driver.Driver
+	dsn string
+}
+
+// Connect satisfies the driver.Connector interface.
+func (c dsnConnector) Connect(context.Context) (driver.Conn, error) {
+	return c.d.Open(c.dsn)
+}
+
+// Driver satisfies the driver.Connector interface.
+func (c dsnConnector) Driver() driver.Driver {
+	return c.d
+}
+
+// initConnector is a connector executing init statements on each connection
+// it opens, so that the session settings (such as on_connect statements) of
+// a pool apply to all its connections, including those opened after idle
+// connections were closed.
+type initConnector struct {
+	driver.Connector
+	init []string
+}
+
+// Connect satisfies the driver.Connector interface.
+func (c initConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range c.init {
+		if strings.TrimSpace(s) == "" {
+			continue
+		}
+		if err := execConn(ctx, conn, s); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("on_connect %q: %w", s, err)
+		}
+	}
+	return conn, nil
+}
+
+// Close closes the wrapped connector, when closable, as sql.DB.Close does.
+func (c initConnector) Close() error {
+	if cl, ok := c.Connector.(io.Closer); ok {
+		return cl.Close()
+	}
+	return nil
+}
+
+// execConn executes a statement without arguments on a driver connection.
+func execConn(ctx context.Context, conn driver.Conn, sqlstr string) error {
+	switch c := conn.(type) {
+	case driver.ExecerContext:
+		_, err := c.ExecContext(ctx, sqlstr, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	case driver.Execer:
+		_, err := c.Exec(sqlstr, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+	var stmt driver.Stmt
+	var err error
+	if c, ok := conn.(driver.ConnPrepareContext); ok {
+		stmt, err = c.PrepareContext(ctx, sqlstr)
+	} else {
+		stmt, err = conn.Prepare(sqlstr)
+	}
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	if s, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = s.ExecContext(ctx, nil)
+	} else {
+		_, err = stmt.Exec(nil)
+	}
+	return err
+}
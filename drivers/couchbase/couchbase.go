@@ -13,6 +13,7 @@ import (
 func init() {
 	drivers.Register("n1ql", drivers.Driver{
 		AllowMultilineComments: true,
+		NoTransactions:         true,
 		Err: func(err error) (string, string) {
 			return "", strings.TrimPrefix(err.Error(), "N1QL: ")
 		},
@@ -5,6 +5,7 @@ package drivers
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -52,6 +53,16 @@ type Driver struct {
 	// AllowHashComments will be passed to query buffers to enable hash (#)
 	// style comments.
 	AllowHashComments bool
+	// AllowBacktick will be passed to query buffers to enable backtick (`)
+	// quoted identifiers.
+	AllowBacktick bool
+	// AllowBlocks will be passed to query buffers to enable anonymous blocks
+	// (BEGIN ... END;), declarations of routines before their BEGIN, and
+	// lines of a single / terminating blocks (ie, PL/SQL).
+	AllowBlocks bool
+	// AllowDelimiter will be passed to query buffers to enable changing the
+	// statement delimiter with DELIMITER.
+	AllowDelimiter bool
 	// RequirePreviousPassword will be used by RequirePreviousPassword.
 	RequirePreviousPassword bool
 	// LexerName is the name of the syntax lexer to use.
@@ -63,8 +74,13 @@ type Driver struct {
 	UseColumnTypes bool
 	// ForceParams will be used to force parameters if defined.
 	ForceParams func(*dburl.URL)
-	// Open will be used by Open if defined.
-	Open func(*dburl.URL, func() io.Writer, func() io.Writer) (func(string, string) (*sql.DB, error), error)
+	// ApplicationName will be used by SetApplicationName if defined, returning
+	// the DSN query parameter and value of the application name of the
+	// connections.
+	ApplicationName func(*dburl.URL, string) (string, string)
+	// Open will be used by Open if defined, returning the func opening the
+	// connector of the driver for the driver name and DSN (see Connector).
+	Open func(*dburl.URL, func() io.Writer, func() io.Writer) (func(string, string) (driver.Connector, error), error)
 	// Version will be used by Version if defined.
 	Version func(context.Context, DB) (string, error)
 	// User will be used by User if defined.
@@ -103,7 +119,58 @@ type Driver struct {
 	// NewCompleter returns a db auto-completer.
 	NewCompleter func(db DB, opts ...completer.Option) readline.AutoCompleter
 	// Copy rows into the database table
-	Copy func(ctx context.Context, db *sql.DB, rows *sql.Rows, table string) (int64, error)
+	Copy func(ctx context.Context, db *sql.DB, rows CopyRows, table string) (int64, error)
+	// Placeholder returns the query parameter placeholder for the n'th
+	// (starting at 1) parameter. Defaults to "?" when not defined.
+	Placeholder func(int) string
+	// Import will be used by Import if defined, to bulk load records into a
+	// table using the database's native bulk load path.
+	Import func(ctx context.Context, db *sql.DB, table string, columns []string, next func() ([]interface{}, error)) (int64, error)
+	// CopyFrom will be used by CopyFrom if defined, to stream the data read
+	// from r to a COPY ... FROM STDIN statement with the database's copy
+	// protocol.
+	CopyFrom func(ctx context.Context, u *dburl.URL, db *sql.DB, sqlstr string, r io.Reader) (int64, error)
+	// CopyTo will be used by CopyTo if defined, to stream the data of a COPY
+	// ... TO STDOUT statement to w with the database's copy protocol.
+	CopyTo func(ctx context.Context, u *dburl.URL, db *sql.DB, sqlstr string, w io.Writer) (int64, error)
+	// SavepointQuery will be used by SavepointQuery if defined.
+	SavepointQuery func(SavepointType, string) (string, error)
+	// AbortTxOnError indicates that the database aborts the current
+	// transaction when a statement fails (ie, PostgreSQL), requiring a
+	// rollback before any other statement can be executed.
+	AbortTxOnError bool
+	// NoTransactions indicates that the database does not support
+	// transactions.
+	NoTransactions bool
+	// NoSavepoints indicates that the database does not support savepoints
+	// in transactions.
+	NoSavepoints bool
+	// Cursors indicates that the database supports server-side cursors
+	// declared with DECLARE and read with FETCH in transactions.
+	Cursors bool
+	// ReadOnlyTransactions indicates that the driver begins read-only
+	// transactions (sql.TxOptions.ReadOnly), refusing the statements
+	// writing.
+	ReadOnlyTransactions bool
+	// Dialect is the SQL dialect of the database, as the name of the driver
+	// whose SQL the database accepts (ie, postgres for pgx), used to generate
+	// statements. Defaults to the driver name.
+	Dialect string
+	// Estimate returns a message estimating the cost of the query, shown
+	// before executing interactive queries, or an empty string.
+	Estimate func(ctx context.Context, u *dburl.URL, query string) (string, error)
+	// Warnings will be used by Warnings if defined, returning the warnings
+	// of the last statement executed on the connection (ie, SHOW WARNINGS).
+	Warnings func(context.Context, DB) ([]string, error)
+}
+
+// NoticeWriter is the interface of the standard error writers of the
+// connections (see Open) collecting the notices of the database (ie, RAISE
+// NOTICE), written to them by the drivers.
+type NoticeWriter interface {
+	io.Writer
+	// Notice writes a notice of the database.
+	Notice(string)
 }
 
 // drivers are registered drivers.
@@ -159,24 +226,46 @@ func ForceParams(u *dburl.URL) {
 	}
 }
 
-// Open opens a sql.DB connection for a driver.
-func Open(u *dburl.URL, stdout, stderr func() io.Writer) (*sql.DB, error) {
+// SetApplicationName sets the application name of the connections of the
+// DSN, when supported by its driver and not set by the DSN, returning true
+// when set.
+func SetApplicationName(u *dburl.URL, name string) bool {
+	d, ok := drivers[u.Driver]
+	if !ok || d.ApplicationName == nil || name == "" {
+		return false
+	}
+	param, value := d.ApplicationName(u, name)
+	v := u.Query()
+	if v.Get(param) != "" {
+		return false
+	}
+	v.Set(param, value)
+	u.RawQuery = v.Encode()
+	return true
+}
+
+// Open opens a sql.DB connection for a driver, executing the init statements
+// on each connection of its pool when opened.
+func Open(u *dburl.URL, stdout, stderr func() io.Writer, init ...string) (*sql.DB, error) {
 	d, ok := drivers[u.Driver]
 	if !ok {
 		return nil, WrapErr(u.Driver, text.ErrDriverNotAvailable)
 	}
-	f := sql.Open
+	f := Connector
 	if d.Open != nil {
 		var err error
 		if f, err = d.Open(u, stdout, stderr); err != nil {
 			return nil, WrapErr(u.Driver, err)
 		}
 	}
-	db, err := f(u.Driver, u.DSN)
+	c, err := f(u.Driver, u.DSN)
 	if err != nil {
 		return nil, WrapErr(u.Driver, err)
 	}
-	return db, nil
+	if len(init) != 0 {
+		c = initConnector{Connector: c, init: init}
+	}
+	return sql.OpenDB(c), nil
 }
 
 // stmtOpts returns statement options for a driver.
@@ -188,6 +277,9 @@ func stmtOpts(u *dburl.URL) []stmt.Option {
 				stmt.WithAllowMultilineComments(d.AllowMultilineComments),
 				stmt.WithAllowCComments(d.AllowCComments),
 				stmt.WithAllowHashComments(d.AllowHashComments),
+				stmt.WithAllowBacktick(d.AllowBacktick),
+				stmt.WithAllowBlocks(d.AllowBlocks),
+				stmt.WithAllowDelimiter(d.AllowDelimiter),
 			}
 		}
 	}
@@ -196,6 +288,8 @@ func stmtOpts(u *dburl.URL) []stmt.Option {
 		stmt.WithAllowMultilineComments(true),
 		stmt.WithAllowCComments(true),
 		stmt.WithAllowHashComments(true),
+		stmt.WithAllowBacktick(true),
+		stmt.WithAllowDelimiter(true),
 	}
 }
 
@@ -204,6 +298,47 @@ func NewStmt(u *dburl.URL, f func() ([]rune, error), opts ...stmt.Option) *stmt.
 	return stmt.New(f, append(opts, stmtOpts(u)...)...)
 }
 
+// Statements splits sqlstr into its statements, processed for the driver.
+// Variables are not interpolated, and meta commands are not allowed.
+func Statements(u *dburl.URL, sqlstr string) ([]string, error) {
+	lines := strings.Split(sqlstr, "\n")
+	st := NewStmt(u, func() ([]rune, error) {
+		if len(lines) == 0 {
+			return nil, io.EOF
+		}
+		line := lines[0]
+		lines = lines[1:]
+		return []rune(line), nil
+	})
+	unquote := func(string, bool) (bool, string, error) {
+		return false, "", nil
+	}
+	var stmts []string
+	for {
+		cmd, _, err := st.Next(unquote)
+		switch {
+		case err != nil && err != io.EOF:
+			return nil, err
+		case cmd != "":
+			return nil, fmt.Errorf("meta command %s is not allowed", cmd)
+		case err == nil && !st.Ready():
+			continue
+		}
+		// skip empty and comment only statements, which have no prefix
+		if s := strings.TrimSpace(st.String()); st.Prefix != "" {
+			_, s, _, perr := Process(u, st.Prefix, s)
+			if perr != nil {
+				return nil, perr
+			}
+			stmts = append(stmts, s)
+		}
+		st.Reset(nil)
+		if err == io.EOF {
+			return stmts, nil
+		}
+	}
+}
+
 // ConfigStmt sets the stmt.Stmt options for a driver.
 func ConfigStmt(u *dburl.URL, s *stmt.Stmt) {
 	if u == nil {
@@ -414,6 +549,124 @@ func Ping(ctx context.Context, u *dburl.URL, db *sql.DB) error {
 	return WrapErr(u.Driver, db.PingContext(ctx))
 }
 
+// SavepointType is a savepoint operation.
+type SavepointType int
+
+// Savepoint operations.
+const (
+	// SavepointCreate creates a savepoint.
+	SavepointCreate SavepointType = iota
+	// SavepointRollback rolls back to a savepoint.
+	SavepointRollback
+	// SavepointRelease releases a savepoint.
+	SavepointRelease
+)
+
+// SavepointQuery returns the query to create, roll back to, or release the
+// named savepoint for a driver.
+func SavepointQuery(u *dburl.URL, typ SavepointType, name string) (string, error) {
+	if d, ok := drivers[u.Driver]; ok && d.SavepointQuery != nil {
+		return d.SavepointQuery(typ, name)
+	}
+	switch typ {
+	case SavepointCreate:
+		return "SAVEPOINT " + name, nil
+	case SavepointRollback:
+		return "ROLLBACK TO SAVEPOINT " + name, nil
+	case SavepointRelease:
+		return "RELEASE SAVEPOINT " + name, nil
+	}
+	return "", text.ErrNotSupported
+}
+
+// Capabilities are the capabilities of a driver.
+type Capabilities struct {
+	// Dialect is the SQL dialect of the database.
+	Dialect string
+	// Transactions is set when the database supports transactions.
+	Transactions bool
+	// Savepoints is set when the database supports savepoints.
+	Savepoints bool
+	// Cursors is set when the database supports server-side cursors.
+	Cursors bool
+	// ReadOnly is set when the driver begins read-only transactions.
+	ReadOnly bool
+	// Copy is set when rows can be copied into the database.
+	Copy bool
+	// Import is set when the driver bulk loads records with the database's
+	// native bulk load path, instead of batched inserts.
+	Import bool
+	// CopyStream is set when the driver streams the data of COPY statements
+	// from and to files with the database's copy protocol.
+	CopyStream bool
+	// Describe is set when the driver reads the metadata of the database.
+	Describe bool
+	// Placeholder is the placeholder of the first query parameter.
+	Placeholder string
+}
+
+// Caps returns the capabilities of a driver.
+func Caps(u *dburl.URL) Capabilities {
+	d, ok := drivers[u.Driver]
+	if !ok {
+		return Capabilities{Dialect: u.Driver, Placeholder: "?"}
+	}
+	c := Capabilities{
+		Dialect:      d.Dialect,
+		Transactions: !d.NoTransactions,
+		Savepoints:   !d.NoTransactions && !d.NoSavepoints,
+		Cursors:      !d.NoTransactions && d.Cursors,
+		ReadOnly:     !d.NoTransactions && d.ReadOnlyTransactions,
+		Copy:         d.Copy != nil,
+		Import:       d.Import != nil,
+		CopyStream:   d.CopyFrom != nil && d.CopyTo != nil,
+		Describe:     d.NewMetadataReader != nil || d.NewMetadataWriter != nil,
+		Placeholder:  Placeholder(u, 1),
+	}
+	if c.Dialect == "" {
+		c.Dialect = u.Driver
+	}
+	return c
+}
+
+// quoteIdent quotes an identifier for a driver, as dump.Dialect.QuoteIdent
+// does for the driver's dialect.
+func quoteIdent(u *dburl.URL, s string) string {
+	if Caps(u).Dialect == "mysql" {
+		return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// AbortTxOnError returns whether or not a failed statement aborts the current
+// transaction for a driver.
+func AbortTxOnError(u *dburl.URL) bool {
+	if d, ok := drivers[u.Driver]; ok {
+		return d.AbortTxOnError
+	}
+	return false
+}
+
+// Estimate returns a message estimating the cost of the query for a driver,
+// or an empty string when the driver does not estimate costs.
+func Estimate(ctx context.Context, u *dburl.URL, query string) (string, error) {
+	if d, ok := drivers[u.Driver]; ok && d.Estimate != nil {
+		msg, err := d.Estimate(ctx, u, query)
+		return msg, WrapErr(u.Driver, err)
+	}
+	return "", nil
+}
+
+// Warnings returns the warnings of the last statement executed on the
+// connection for a driver, or nil when the driver does not report warnings.
+func Warnings(ctx context.Context, u *dburl.URL, db DB) ([]string, error) {
+	if d, ok := drivers[u.Driver]; ok && d.Warnings != nil {
+		warnings, err := d.Warnings(ctx, db)
+		return warnings, WrapErr(u.Driver, err)
+	}
+	return nil, nil
+}
+
 // Lexer returns the syntax lexer for a driver.
 func Lexer(u *dburl.URL) chroma.Lexer {
 	var l chroma.Lexer
@@ -497,8 +750,17 @@ func NewCompleter(ctx context.Context, u *dburl.URL, db DB, readerOpts []metadat
 	return completer.NewDefaultCompleter(opts...)
 }
 
+// CopyRows are the rows copied to a table, such as *sql.Rows.
+type CopyRows interface {
+	Columns() ([]string, error)
+	ColumnTypes() ([]*sql.ColumnType, error)
+	Next() bool
+	Scan(...interface{}) error
+	Err() error
+}
+
 // Copy copies the result set to the destination sql.DB.
-func Copy(ctx context.Context, u *dburl.URL, stdout, stderr func() io.Writer, rows *sql.Rows, table string) (int64, error) {
+func Copy(ctx context.Context, u *dburl.URL, stdout, stderr func() io.Writer, rows CopyRows, table string) (int64, error) {
 	d, ok := drivers[u.Driver]
 	if !ok {
 		return 0, WrapErr(u.Driver, text.ErrDriverNotAvailable)
@@ -514,12 +776,122 @@ func Copy(ctx context.Context, u *dburl.URL, stdout, stderr func() io.Writer, ro
 	return d.Copy(ctx, db, rows, table)
 }
 
+// CopyFrom streams the data read from r to the COPY ... FROM STDIN statement
+// sqlstr, returning the number of copied rows.
+func CopyFrom(ctx context.Context, u *dburl.URL, db *sql.DB, sqlstr string, r io.Reader) (int64, error) {
+	d, ok := drivers[u.Driver]
+	if !ok || d.CopyFrom == nil {
+		return 0, fmt.Errorf(text.NotSupportedByDriver, `\copy`, u.Driver)
+	}
+	n, err := d.CopyFrom(ctx, u, db, sqlstr, r)
+	return n, WrapErr(u.Driver, err)
+}
+
+// CopyTo streams the data of the COPY ... TO STDOUT statement sqlstr to w,
+// returning the number of copied rows.
+func CopyTo(ctx context.Context, u *dburl.URL, db *sql.DB, sqlstr string, w io.Writer) (int64, error) {
+	d, ok := drivers[u.Driver]
+	if !ok || d.CopyTo == nil {
+		return 0, fmt.Errorf(text.NotSupportedByDriver, `\copy`, u.Driver)
+	}
+	n, err := d.CopyTo(ctx, u, db, sqlstr, w)
+	return n, WrapErr(u.Driver, err)
+}
+
+// Placeholder returns the n'th (starting at 1) query parameter placeholder
+// for a driver.
+func Placeholder(u *dburl.URL, n int) string {
+	if d, ok := drivers[u.Driver]; ok && d.Placeholder != nil {
+		return d.Placeholder(n)
+	}
+	return "?"
+}
+
+// Import loads the records returned by next into the columns of table, using
+// the driver's native bulk load path when available, and batched inserts
+// otherwise. next must return io.EOF after the last record.
+func Import(ctx context.Context, u *dburl.URL, db *sql.DB, table string, columns []string, batch int, next func() ([]interface{}, error)) (int64, error) {
+	if d, ok := drivers[u.Driver]; ok && d.Import != nil {
+		n, err := d.Import(ctx, db, table, columns, next)
+		return n, WrapErr(u.Driver, err)
+	}
+	return ImportWithInsert(ctx, u, db, table, columns, batch, next)
+}
+
+// ImportWithInsert loads the records returned by next into the columns of
+// table using multi-row inserts of batch records, in a single transaction.
+// The column names are quoted.
+func ImportWithInsert(ctx context.Context, u *dburl.URL, db *sql.DB, table string, columns []string, batch int, next func() ([]interface{}, error)) (int64, error) {
+	if batch < 1 {
+		batch = 1
+	}
+	clen := len(columns)
+	quoted := make([]string, clen)
+	for i, c := range columns {
+		quoted[i] = quoteIdent(u, c)
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, WrapErr(u.Driver, err)
+	}
+	defer tx.Rollback()
+	var n int64
+	values := make([]interface{}, 0, batch*clen)
+	flush := func() error {
+		if len(values) == 0 {
+			return nil
+		}
+		rows := make([]string, len(values)/clen)
+		for i := range rows {
+			placeholders := make([]string, clen)
+			for j := range placeholders {
+				placeholders[j] = Placeholder(u, i*clen+j+1)
+			}
+			rows[i] = "(" + strings.Join(placeholders, ", ") + ")"
+		}
+		query := "INSERT INTO " + table + " (" + strings.Join(quoted, ", ") + ") VALUES " + strings.Join(rows, ", ")
+		res, err := tx.ExecContext(ctx, query, values...)
+		if err != nil {
+			return err
+		}
+		count, err := RowsAffected(u, res)
+		if err != nil {
+			return err
+		}
+		n, values = n+count, values[:0]
+		return nil
+	}
+	for {
+		row, err := next()
+		switch {
+		case err == io.EOF:
+			if err := flush(); err != nil {
+				return n, WrapErr(u.Driver, err)
+			}
+			if err := tx.Commit(); err != nil {
+				return n, WrapErr(u.Driver, err)
+			}
+			return n, nil
+		case err != nil:
+			return n, err
+		case len(row) != clen:
+			return n, fmt.Errorf("expected %d values, got: %d", clen, len(row))
+		}
+		values = append(values, row...)
+		if len(values) >= batch*clen {
+			if err := flush(); err != nil {
+				return n, WrapErr(u.Driver, err)
+			}
+		}
+	}
+}
+
 // CopyWithInsert builds a copy handler based on insert.
-func CopyWithInsert(placeholder func(int) string) func(ctx context.Context, db *sql.DB, rows *sql.Rows, table string) (int64, error) {
+func CopyWithInsert(placeholder func(int) string) func(ctx context.Context, db *sql.DB, rows CopyRows, table string) (int64, error) {
 	if placeholder == nil {
 		placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
 	}
-	return func(ctx context.Context, db *sql.DB, rows *sql.Rows, table string) (int64, error) {
+	return func(ctx context.Context, db *sql.DB, rows CopyRows, table string) (int64, error) {
 		columns, err := rows.Columns()
 		if err != nil {
 			return 0, fmt.Errorf("failed to fetch source rows columns: %w", err)
@@ -21,8 +21,19 @@ import (
 	dc "github.com/ory/dockertest/v3/docker"
 	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
+	_ "github.com/xo/usql/drivers/cassandra"
+	_ "github.com/xo/usql/drivers/clickhouse"
+	_ "github.com/xo/usql/drivers/cosmos"
+	_ "github.com/xo/usql/drivers/couchbase"
 	"github.com/xo/usql/drivers/metadata"
+	_ "github.com/xo/usql/drivers/moderncsqlite"
+	_ "github.com/xo/usql/drivers/mongodb"
+	_ "github.com/xo/usql/drivers/mymysql"
+	_ "github.com/xo/usql/drivers/pgx"
+	_ "github.com/xo/usql/drivers/snowflake"
+	_ "github.com/xo/usql/drivers/trino"
 	_ "github.com/xo/usql/internal"
+	"github.com/xo/usql/text"
 )
 
 type Database struct {
@@ -483,6 +494,77 @@ func TestCopy(t *testing.T) {
 	}
 }
 
+func TestCaps(t *testing.T) {
+	tests := []struct {
+		driver       string
+		dialect      string
+		transactions bool
+		savepoints   bool
+		placeholder  string
+	}{
+		{"postgres", "postgres", true, true, "$1"},
+		{"pgx", "postgres", true, true, "$1"},
+		{"mysql", "mysql", true, true, "?"},
+		{"mymysql", "mysql", true, true, "?"},
+		{"sqlite3", "sqlite3", true, true, "?"},
+		{"moderncsqlite", "sqlite3", true, true, "?"},
+		{"sqlserver", "sqlserver", true, true, "@p1"},
+		{"oracle", "oracle", true, true, ":1"},
+		{"clickhouse", "clickhouse", true, false, "?"},
+		{"snowflake", "snowflake", true, false, "?"},
+		{"cql", "cql", false, false, "?"},
+		{"cosmos", "cosmos", false, false, "?"},
+		{"n1ql", "n1ql", false, false, "?"},
+		{"mongodb", "mongodb", false, false, "?"},
+		{"trino", "trino", false, false, "?"},
+		{"unknown", "unknown", false, false, "?"},
+	}
+	for i, test := range tests {
+		c := drivers.Caps(&dburl.URL{Driver: test.driver})
+		if c.Dialect != test.dialect {
+			t.Errorf("test %d (%s) expected dialect %q, got: %q", i, test.driver, test.dialect, c.Dialect)
+		}
+		if c.Transactions != test.transactions || c.Savepoints != test.savepoints {
+			t.Errorf("test %d (%s) expected transactions %t and savepoints %t, got: %t %t", i, test.driver, test.transactions, test.savepoints, c.Transactions, c.Savepoints)
+		}
+		if c.Placeholder != test.placeholder {
+			t.Errorf("test %d (%s) expected placeholder %q, got: %q", i, test.driver, test.placeholder, c.Placeholder)
+		}
+		// the capabilities of transactions are not set without transactions
+		if !c.Transactions && (c.Cursors || c.ReadOnly) {
+			t.Errorf("test %d (%s) expected no cursors or read-only transactions, got: %t %t", i, test.driver, c.Cursors, c.ReadOnly)
+		}
+	}
+}
+
+func TestSavepointQuery(t *testing.T) {
+	tests := []struct {
+		driver string
+		typ    drivers.SavepointType
+		exp    string
+		err    error
+	}{
+		{"postgres", drivers.SavepointCreate, "SAVEPOINT a", nil},
+		{"postgres", drivers.SavepointRollback, "ROLLBACK TO SAVEPOINT a", nil},
+		{"postgres", drivers.SavepointRelease, "RELEASE SAVEPOINT a", nil},
+		{"mysql", drivers.SavepointCreate, "SAVEPOINT a", nil},
+		{"sqlite3", drivers.SavepointRollback, "ROLLBACK TO SAVEPOINT a", nil},
+		{"sqlserver", drivers.SavepointCreate, "SAVE TRANSACTION a", nil},
+		{"sqlserver", drivers.SavepointRollback, "ROLLBACK TRANSACTION a", nil},
+		{"sqlserver", drivers.SavepointRelease, "", text.ErrNotSupported},
+		{"unknown", drivers.SavepointRelease, "RELEASE SAVEPOINT a", nil},
+	}
+	for i, test := range tests {
+		sqlstr, err := drivers.SavepointQuery(&dburl.URL{Driver: test.driver}, test.typ, "a")
+		if err != test.err {
+			t.Fatalf("test %d (%s) expected error %v, got: %v", i, test.driver, test.err, err)
+		}
+		if sqlstr != test.exp {
+			t.Errorf("test %d (%s) expected %q, got: %q", i, test.driver, test.exp, sqlstr)
+		}
+	}
+}
+
 // filesEqual compares the files at paths a and b and returns an error if
 // the content is not equal. Ignore is a regex. All matches will be removed
 // from the file contents before comparison.
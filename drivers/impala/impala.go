@@ -10,5 +10,7 @@ import (
 )
 
 func init() {
-	drivers.Register("impala", drivers.Driver{})
+	drivers.Register("impala", drivers.Driver{
+		NoTransactions: true,
+	})
 }
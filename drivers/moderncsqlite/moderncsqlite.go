@@ -6,7 +6,7 @@ package moderncsqlite
 
 import (
 	"context"
-	"database/sql"
+	"database/sql/driver"
 	"io"
 	"strconv"
 
@@ -19,9 +19,10 @@ import (
 func init() {
 	drivers.Register("moderncsqlite", drivers.Driver{
 		AllowMultilineComments: true,
-		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (*sql.DB, error), error) {
-			return func(_ string, params string) (*sql.DB, error) {
-				return sql.Open("sqlite", params)
+		Dialect:                "sqlite3",
+		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(_ string, params string) (driver.Connector, error) {
+				return drivers.Connector("sqlite", params)
 			}, nil
 		},
 		Version: func(ctx context.Context, db drivers.DB) (string, error) {
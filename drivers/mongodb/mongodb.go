@@ -0,0 +1,80 @@
+// Package mongodb defines and registers usql's MongoDB driver. Queries are
+// read-only: find and aggregate commands expressed as JSON, or simple SELECT
+// statements.
+//
+// See: https://github.com/mongodb/mongo-go-driver
+package mongodb
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	_ "github.com/xo/usql/drivers/mongodb/mongosql" // DRIVER: mongodb
+)
+
+func init() {
+	dburl.Register(dburl.Scheme{
+		Driver: "mongodb",
+		Generator: func(u *dburl.URL) (string, error) {
+			z := u.URL
+			switch u.Transport {
+			case "tcp":
+				z.Scheme = "mongodb"
+			case "srv":
+				z.Scheme = "mongodb+srv"
+			default:
+				return "", dburl.ErrInvalidTransportProtocol
+			}
+			return z.String(), nil
+		},
+		Transport: dburl.TransportTCP | dburl.TransportAny,
+		Aliases:   []string{"mongo"},
+	})
+	endRE := regexp.MustCompile(`;?\s*$`)
+	drivers.Register("mongodb", drivers.Driver{
+		NoTransactions: true,
+		Process: func(prefix string, sqlstr string) (string, string, bool, error) {
+			sqlstr = endRE.ReplaceAllString(sqlstr, "")
+			// commands are JSON documents
+			if strings.HasPrefix(strings.TrimSpace(sqlstr), "{") {
+				return "COMMAND", sqlstr, true, nil
+			}
+			typ, q := drivers.QueryExecType(prefix, sqlstr)
+			return typ, sqlstr, q, nil
+		},
+		Version: func(ctx context.Context, db drivers.DB) (string, error) {
+			rows, err := db.QueryContext(ctx, `{"buildInfo": 1}`)
+			if err != nil {
+				return "", err
+			}
+			defer rows.Close()
+			cols, err := rows.Columns()
+			if err != nil {
+				return "", err
+			}
+			vals := make([]interface{}, len(cols))
+			for i := range vals {
+				vals[i] = new(interface{})
+			}
+			if !rows.Next() {
+				return "", rows.Err()
+			}
+			if err := rows.Scan(vals...); err != nil {
+				return "", err
+			}
+			for i, col := range cols {
+				if col == "version" {
+					return fmt.Sprintf("MongoDB %v", *vals[i].(*interface{})), nil
+				}
+			}
+			return "MongoDB", nil
+		},
+		Err: func(err error) (string, string) {
+			return "", strings.TrimPrefix(err.Error(), "mongodb: ")
+		},
+	})
+}
@@ -0,0 +1,273 @@
+// Package mongosql is a read-only database/sql driver for MongoDB, querying
+// collections with find and aggregate commands expressed as JSON, or with
+// simple SELECT statements.
+package mongosql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/connstring"
+)
+
+func init() {
+	sql.Register("mongodb", &Driver{})
+}
+
+// ErrReadOnly is the error of statements other than queries.
+var ErrReadOnly = errors.New("mongodb: only queries are supported")
+
+// Driver is the MongoDB driver.
+type Driver struct{}
+
+// Open satisfies the driver.Driver interface.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	cs, err := connstring.ParseAndValidate(dsn)
+	if err != nil {
+		return nil, err
+	}
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(dsn))
+	if err != nil {
+		return nil, err
+	}
+	name := cs.Database
+	if name == "" {
+		name = "test"
+	}
+	return &conn{client: client, db: client.Database(name)}, nil
+}
+
+// conn is a connection to a MongoDB database.
+type conn struct {
+	client *mongo.Client
+	db     *mongo.Database
+}
+
+// Prepare satisfies the driver.Conn interface.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+// Close satisfies the driver.Conn interface.
+func (c *conn) Close() error {
+	return c.client.Disconnect(context.Background())
+}
+
+// Begin satisfies the driver.Conn interface.
+func (c *conn) Begin() (driver.Tx, error) {
+	return nil, ErrReadOnly
+}
+
+// Ping satisfies the driver.Pinger interface.
+func (c *conn) Ping(ctx context.Context) error {
+	return c.client.Ping(ctx, readpref.Primary())
+}
+
+// ExecContext satisfies the driver.ExecerContext interface.
+func (c *conn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	return nil, ErrReadOnly
+}
+
+// QueryContext satisfies the driver.QueryerContext interface.
+func (c *conn) QueryContext(ctx context.Context, s string, args []driver.NamedValue) (driver.Rows, error) {
+	if len(args) != 0 {
+		return nil, errors.New("mongodb: query parameters are not supported")
+	}
+	q, err := parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb: %w", err)
+	}
+	docs, err := c.run(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	// the $count stage returns no document when none match
+	if q.count && len(docs) == 0 {
+		docs = []bson.D{{{Key: "count", Value: int32(0)}}}
+	}
+	return newRows(q.columns, docs), nil
+}
+
+// run runs the query, returning the documents of its result.
+func (c *conn) run(ctx context.Context, q *query) ([]bson.D, error) {
+	var cur *mongo.Cursor
+	var err error
+	switch {
+	case q.command == "find":
+		opts := options.Find()
+		if q.projection != nil {
+			opts.SetProjection(q.projection)
+		}
+		if q.sort != nil {
+			opts.SetSort(q.sort)
+		}
+		if q.skip != 0 {
+			opts.SetSkip(q.skip)
+		}
+		if q.limit != 0 {
+			opts.SetLimit(q.limit)
+		}
+		filter := q.filter
+		if filter == nil {
+			filter = bson.D{}
+		}
+		cur, err = c.db.Collection(q.collection).Find(ctx, filter, opts)
+	case q.command == "aggregate":
+		pipeline := q.pipeline
+		if pipeline == nil {
+			pipeline = bson.A{}
+		}
+		cur, err = c.db.Collection(q.collection).Aggregate(ctx, pipeline)
+	case commands[strings.ToLower(q.command)]:
+		cur, err = c.db.RunCommandCursor(ctx, q.doc)
+	default:
+		var doc bson.D
+		if err := c.db.RunCommand(ctx, q.doc).Decode(&doc); err != nil {
+			return nil, err
+		}
+		return []bson.D{doc}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var docs []bson.D
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+// stmt is a statement, run when queried.
+type stmt struct {
+	c     *conn
+	query string
+}
+
+// Close satisfies the driver.Stmt interface.
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput satisfies the driver.Stmt interface.
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+// Exec satisfies the driver.Stmt interface.
+func (s *stmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, ErrReadOnly
+}
+
+// Query satisfies the driver.Stmt interface.
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return s.c.QueryContext(context.Background(), s.query, named)
+}
+
+// rows are the rows of the documents of a query.
+type rows struct {
+	columns []string
+	docs    []bson.D
+}
+
+// newRows returns the rows of the documents, whose columns are the columns,
+// when not empty, or else the fields of the documents, in their order of
+// appearance.
+func newRows(columns []string, docs []bson.D) *rows {
+	if len(columns) == 0 {
+		seen := make(map[string]bool)
+		for _, doc := range docs {
+			for _, e := range doc {
+				if !seen[e.Key] {
+					seen[e.Key] = true
+					columns = append(columns, e.Key)
+				}
+			}
+		}
+	}
+	return &rows{columns: columns, docs: docs}
+}
+
+// Columns satisfies the driver.Rows interface.
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+// Close satisfies the driver.Rows interface.
+func (r *rows) Close() error {
+	r.docs = nil
+	return nil
+}
+
+// Next satisfies the driver.Rows interface.
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.docs) == 0 {
+		return io.EOF
+	}
+	doc := r.docs[0]
+	r.docs = r.docs[1:]
+	for i, column := range r.columns {
+		dest[i] = value(lookup(doc, column))
+	}
+	return nil
+}
+
+// lookup returns the value of the field of the document, following the dots
+// of the paths of embedded documents.
+func lookup(doc bson.D, field string) interface{} {
+	for _, e := range doc {
+		if e.Key == field {
+			return e.Value
+		}
+	}
+	if i := strings.IndexByte(field, '.'); i != -1 {
+		if d, ok := lookup(doc, field[:i]).(bson.D); ok {
+			return lookup(d, field[i+1:])
+		}
+	}
+	return nil
+}
+
+// value converts a BSON value to a driver value: ObjectIDs to their hex
+// string, dates to times, binary data to bytes, and embedded documents and
+// arrays to their relaxed extended JSON.
+func value(v interface{}) driver.Value {
+	switch x := v.(type) {
+	case nil, primitive.Null, primitive.Undefined:
+		return nil
+	case string, bool, int64, float64, time.Time:
+		return x
+	case int32:
+		return int64(x)
+	case primitive.ObjectID:
+		return x.Hex()
+	case primitive.DateTime:
+		return x.Time().UTC()
+	case primitive.Decimal128:
+		return x.String()
+	case primitive.Binary:
+		return x.Data
+	case primitive.D, primitive.A:
+		buf, err := bson.MarshalExtJSON(bson.D{{Key: "v", Value: x}}, false, false)
+		if err != nil {
+			return fmt.Sprint(x)
+		}
+		// strip the {"v":...} wrapper
+		return strings.TrimSuffix(strings.TrimPrefix(string(buf), `{"v":`), "}")
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,490 @@
+package mongosql
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// query is a read-only query of a collection or a database.
+type query struct {
+	// command is the command of the query: find, aggregate, or a read-only
+	// database command.
+	command string
+	// collection is the collection of find and aggregate.
+	collection string
+	filter     bson.D
+	projection bson.D
+	sort       bson.D
+	skip       int64
+	limit      int64
+	pipeline   bson.A
+	// doc is the document of database commands.
+	doc bson.D
+	// columns are the columns of the rows, when known.
+	columns []string
+	// count is set for the COUNT(*) of SELECT statements.
+	count bool
+}
+
+// commands are the read-only database commands, and whether they return a
+// cursor.
+var commands = map[string]bool{
+	"buildinfo":        false,
+	"collstats":        false,
+	"connectionstatus": false,
+	"count":            false,
+	"dbstats":          false,
+	"distinct":         false,
+	"hello":            false,
+	"hostinfo":         false,
+	"listcollections":  true,
+	"listindexes":      true,
+	"ping":             false,
+	"serverstatus":     false,
+}
+
+// parse parses a query, expressed either as the JSON document of a command,
+// or as a SELECT statement.
+func parse(s string) (*query, error) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "{") {
+		return parseCommand(s)
+	}
+	return parseSelect(s)
+}
+
+// parseCommand parses the extended JSON document of a find, aggregate or
+// read-only database command.
+func parseCommand(s string) (*query, error) {
+	var doc bson.D
+	if err := bson.UnmarshalExtJSON([]byte(s), false, &doc); err != nil {
+		return nil, fmt.Errorf("invalid command: %w", err)
+	}
+	if len(doc) == 0 {
+		return nil, errors.New("empty command")
+	}
+	q := &query{command: doc[0].Key}
+	switch q.command {
+	case "find", "aggregate":
+		var ok bool
+		if q.collection, ok = doc[0].Value.(string); !ok {
+			return nil, fmt.Errorf("the collection of %s must be a string", q.command)
+		}
+	default:
+		if _, ok := commands[strings.ToLower(q.command)]; !ok {
+			return nil, fmt.Errorf("command %s is not supported: only find, aggregate and read-only commands are", q.command)
+		}
+		q.doc = doc
+		return q, nil
+	}
+	for _, e := range doc[1:] {
+		var err error
+		switch e.Key {
+		case "filter":
+			q.filter, err = document(e)
+		case "projection":
+			q.projection, err = document(e)
+		case "sort":
+			q.sort, err = document(e)
+		case "skip":
+			q.skip, err = integer(e)
+		case "limit":
+			q.limit, err = integer(e)
+		case "pipeline":
+			var ok bool
+			if q.pipeline, ok = e.Value.(bson.A); !ok {
+				err = errors.New("pipeline must be an array")
+			}
+		default:
+			err = fmt.Errorf("unsupported %s option %s", q.command, e.Key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if q.command == "find" && q.pipeline != nil || q.command == "aggregate" && (q.filter != nil || q.projection != nil || q.sort != nil || q.skip != 0 || q.limit != 0) {
+		return nil, fmt.Errorf("unsupported %s options", q.command)
+	}
+	// queries are read-only
+	for _, stage := range q.pipeline {
+		if d, ok := stage.(bson.D); ok && len(d) != 0 && (d[0].Key == "$out" || d[0].Key == "$merge") {
+			return nil, fmt.Errorf("aggregate stage %s is not supported: queries are read-only", d[0].Key)
+		}
+	}
+	return q, nil
+}
+
+// document returns the document value of the element.
+func document(e bson.E) (bson.D, error) {
+	d, ok := e.Value.(bson.D)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a document", e.Key)
+	}
+	return d, nil
+}
+
+// integer returns the integer value of the element.
+func integer(e bson.E) (int64, error) {
+	switch v := e.Value.(type) {
+	case int32:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case float64:
+		if v == float64(int64(v)) {
+			return int64(v), nil
+		}
+	}
+	return 0, fmt.Errorf("%s must be an integer", e.Key)
+}
+
+// parseSelect parses a simple SELECT statement:
+//
+//	SELECT * | COUNT(*) | FIELD [, FIELD]... FROM COLLECTION
+//	  [WHERE FIELD OP VALUE [AND FIELD OP VALUE]...]
+//	  [ORDER BY FIELD [ASC|DESC] [, FIELD [ASC|DESC]]...]
+//	  [LIMIT N [OFFSET N]]
+//
+// where OP is one of =, <>, !=, <, <=, >, >=, [NOT] IN (VALUE, ...), [NOT]
+// LIKE or IS [NOT] NULL, and VALUE is a string, a number, true, false or null.
+func parseSelect(s string) (*query, error) {
+	p := &parser{toks: tokenize(s)}
+	if !p.keyword("SELECT") {
+		return nil, errors.New("expected a SELECT statement or the JSON document of a command")
+	}
+	q := &query{command: "find"}
+	switch {
+	case p.symbol("*"):
+	case p.keyword("COUNT"):
+		if !p.symbol("(") || !p.symbol("*") || !p.symbol(")") {
+			return nil, errors.New("expected COUNT(*)")
+		}
+		q.count, q.columns = true, []string{"count"}
+	default:
+		for {
+			field, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			q.columns = append(q.columns, field)
+			q.projection = append(q.projection, bson.E{Key: field, Value: 1})
+			if !p.symbol(",") {
+				break
+			}
+		}
+		// _id is returned unless excluded
+		if !contains(q.columns, "_id") {
+			q.projection = append(q.projection, bson.E{Key: "_id", Value: 0})
+		}
+	}
+	if !p.keyword("FROM") {
+		return nil, errors.New("expected FROM")
+	}
+	var err error
+	if q.collection, err = p.ident(); err != nil {
+		return nil, err
+	}
+	if p.keyword("WHERE") {
+		for {
+			e, err := p.condition()
+			if err != nil {
+				return nil, err
+			}
+			q.filter = append(q.filter, e)
+			if !p.keyword("AND") {
+				break
+			}
+		}
+	}
+	if p.keyword("ORDER") {
+		if !p.keyword("BY") {
+			return nil, errors.New("expected ORDER BY")
+		}
+		for {
+			field, err := p.ident()
+			if err != nil {
+				return nil, err
+			}
+			dir := 1
+			if p.keyword("DESC") {
+				dir = -1
+			} else {
+				p.keyword("ASC")
+			}
+			q.sort = append(q.sort, bson.E{Key: field, Value: dir})
+			if !p.symbol(",") {
+				break
+			}
+		}
+	}
+	if p.keyword("LIMIT") {
+		if q.limit, err = p.integer(); err != nil {
+			return nil, err
+		}
+		if p.keyword("OFFSET") {
+			if q.skip, err = p.integer(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if t := p.next(); t != "" {
+		return nil, fmt.Errorf("unexpected %q", t)
+	}
+	if q.count {
+		// counts are the $count stage of an aggregate
+		q.command = "aggregate"
+		if q.filter != nil {
+			q.pipeline = append(q.pipeline, bson.D{{Key: "$match", Value: q.filter}})
+		}
+		q.pipeline = append(q.pipeline, bson.D{{Key: "$count", Value: "count"}})
+		q.filter, q.sort, q.skip, q.limit = nil, nil, 0, 0
+	}
+	return q, nil
+}
+
+// parser is a parser of the tokens of a SELECT statement.
+type parser struct {
+	toks []string
+}
+
+// next returns the next token.
+func (p *parser) next() string {
+	if len(p.toks) == 0 {
+		return ""
+	}
+	t := p.toks[0]
+	p.toks = p.toks[1:]
+	return t
+}
+
+// peek returns the next token, without consuming it.
+func (p *parser) peek() string {
+	if len(p.toks) == 0 {
+		return ""
+	}
+	return p.toks[0]
+}
+
+// keyword consumes the next token when it's the keyword kw.
+func (p *parser) keyword(kw string) bool {
+	if strings.EqualFold(p.peek(), kw) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// symbol consumes the next token when it's the symbol sym.
+func (p *parser) symbol(sym string) bool {
+	if p.peek() == sym {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// identRE matches the unquoted identifiers.
+var identRE = regexp.MustCompile(`^[\pL_$][\pL\pN_$]*(\.[\pL_$][\pL\pN_$]*)*$`)
+
+// ident returns the next token as a field or collection name.
+func (p *parser) ident() (string, error) {
+	t := p.next()
+	switch {
+	case len(t) > 1 && (t[0] == '"' || t[0] == '`'):
+		return strings.ReplaceAll(t[1:len(t)-1], t[:1]+t[:1], t[:1]), nil
+	case identRE.MatchString(t):
+		return t, nil
+	}
+	return "", fmt.Errorf("expected a field name, got %q", t)
+}
+
+// integer returns the next token as an integer.
+func (p *parser) integer() (int64, error) {
+	t := p.next()
+	i, err := strconv.ParseInt(t, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("expected an integer, got %q", t)
+	}
+	return i, nil
+}
+
+// value returns the next token as a value.
+func (p *parser) value() (interface{}, error) {
+	t := p.next()
+	switch {
+	case strings.HasPrefix(t, "'"):
+		return strings.ReplaceAll(t[1:len(t)-1], "''", "'"), nil
+	case strings.EqualFold(t, "TRUE"):
+		return true, nil
+	case strings.EqualFold(t, "FALSE"):
+		return false, nil
+	case strings.EqualFold(t, "NULL"):
+		return nil, nil
+	}
+	if i, err := strconv.ParseInt(t, 10, 64); err == nil {
+		return i, nil
+	}
+	if f, err := strconv.ParseFloat(t, 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("expected a value, got %q", t)
+}
+
+// operators are the MongoDB operators of the comparison operators.
+var operators = map[string]string{
+	"=":  "$eq",
+	"<>": "$ne",
+	"!=": "$ne",
+	"<":  "$lt",
+	"<=": "$lte",
+	">":  "$gt",
+	">=": "$gte",
+}
+
+// condition returns the filter of the next condition.
+func (p *parser) condition() (bson.E, error) {
+	field, err := p.ident()
+	if err != nil {
+		return bson.E{}, err
+	}
+	not := p.keyword("NOT")
+	switch {
+	case !not && p.keyword("IS"):
+		op := "$eq"
+		if p.keyword("NOT") {
+			op = "$ne"
+		}
+		if !p.keyword("NULL") {
+			return bson.E{}, errors.New("expected IS [NOT] NULL")
+		}
+		return bson.E{Key: field, Value: bson.D{{Key: op, Value: nil}}}, nil
+	case p.keyword("IN"):
+		if !p.symbol("(") {
+			return bson.E{}, errors.New("expected IN (VALUE, ...)")
+		}
+		var values bson.A
+		for {
+			v, err := p.value()
+			if err != nil {
+				return bson.E{}, err
+			}
+			values = append(values, v)
+			if !p.symbol(",") {
+				break
+			}
+		}
+		if !p.symbol(")") {
+			return bson.E{}, errors.New("expected IN (VALUE, ...)")
+		}
+		op := "$in"
+		if not {
+			op = "$nin"
+		}
+		return bson.E{Key: field, Value: bson.D{{Key: op, Value: values}}}, nil
+	case p.keyword("LIKE"):
+		v, err := p.value()
+		pattern, ok := v.(string)
+		if err != nil || !ok {
+			return bson.E{}, errors.New("expected LIKE 'PATTERN'")
+		}
+		re := bson.D{{Key: "$regex", Value: likeRegexp(pattern)}}
+		if not {
+			re = bson.D{{Key: "$not", Value: re}}
+		}
+		return bson.E{Key: field, Value: re}, nil
+	case not:
+		return bson.E{}, errors.New("expected NOT IN or NOT LIKE")
+	}
+	t := p.next()
+	op, ok := operators[t]
+	if !ok {
+		return bson.E{}, fmt.Errorf("expected a comparison operator, got %q", t)
+	}
+	v, err := p.value()
+	if err != nil {
+		return bson.E{}, err
+	}
+	return bson.E{Key: field, Value: bson.D{{Key: op, Value: v}}}, nil
+}
+
+// likeRegexp returns the regular expression of a LIKE pattern.
+func likeRegexp(pattern string) string {
+	var sb strings.Builder
+	sb.WriteByte('^')
+	for _, r := range pattern {
+		switch r {
+		case '%':
+			sb.WriteString(".*")
+		case '_':
+			sb.WriteByte('.')
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	sb.WriteByte('$')
+	return sb.String()
+}
+
+// tokenize splits a SELECT statement into its tokens: quoted strings and
+// identifiers, words, numbers and symbols.
+func tokenize(s string) []string {
+	var toks []string
+	r := []rune(s)
+	for i := 0; i < len(r); {
+		switch c := r[i]; {
+		case unicode.IsSpace(c):
+			i++
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for ; j < len(r); j++ {
+				if r[j] == c {
+					// doubled quotes are escaped quotes
+					if j+1 < len(r) && r[j+1] == c {
+						j++
+						continue
+					}
+					break
+				}
+			}
+			if j == len(r) {
+				j--
+			}
+			toks = append(toks, string(r[i:j+1]))
+			i = j + 1
+		case c == '<' || c == '>' || c == '!':
+			j := i + 1
+			if j < len(r) && (r[j] == '=' || c == '<' && r[j] == '>') {
+				j++
+			}
+			toks = append(toks, string(r[i:j]))
+			i = j
+		case unicode.IsLetter(c) || unicode.IsDigit(c) || c == '_' || c == '$' || c == '-' || c == '.':
+			j := i + 1
+			for j < len(r) && (unicode.IsLetter(r[j]) || unicode.IsDigit(r[j]) || r[j] == '_' || r[j] == '$' || r[j] == '.') {
+				j++
+			}
+			toks = append(toks, string(r[i:j]))
+			i = j
+		default:
+			toks = append(toks, string(c))
+			i++
+		}
+	}
+	return toks
+}
+
+// contains returns whether v contains s.
+func contains(v []string, s string) bool {
+	for _, x := range v {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
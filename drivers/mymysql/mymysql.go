@@ -18,8 +18,11 @@ func init() {
 	drivers.Register("mymysql", drivers.Driver{
 		AllowMultilineComments: true,
 		AllowHashComments:      true,
+		AllowBacktick:          true,
+		AllowDelimiter:         true,
 		LexerName:              "mysql",
 		UseColumnTypes:         true,
+		Dialect:                "mysql",
 		Err: func(err error) (string, string) {
 			if e, ok := err.(*mysql.Error); ok {
 				return strconv.Itoa(int(e.Code)), string(e.Msg)
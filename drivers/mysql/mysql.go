@@ -9,8 +9,14 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
 	"io"
 	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/go-sql-driver/mysql" // DRIVER
 	"github.com/xo/usql/drivers"
@@ -22,6 +28,9 @@ func init() {
 	drivers.Register("mysql", drivers.Driver{
 		AllowMultilineComments: true,
 		AllowHashComments:      true,
+		AllowBacktick:          true,
+		AllowDelimiter:         true,
+		ReadOnlyTransactions:   true,
 		LexerName:              "mysql",
 		UseColumnTypes:         true,
 		ForceParams: drivers.ForceQueryParameters([]string{
@@ -46,6 +55,106 @@ func init() {
 			return metadata.NewDefaultWriter(mymeta.NewReader(db, opts...))(db, w)
 		},
 		Copy:         drivers.CopyWithInsert(func(int) string { return "?" }),
+		Placeholder:  func(int) string { return "?" },
+		Import:       importWithLoadData,
 		NewCompleter: mymeta.NewCompleter,
+		Warnings:     showWarnings,
 	}, "memsql", "vitess", "tidb")
 }
+
+// showWarnings returns the warnings of the last statement, with SHOW
+// WARNINGS, as LEVEL CODE: MESSAGE.
+func showWarnings(ctx context.Context, db drivers.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SHOW WARNINGS`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var warnings []string
+	for rows.Next() {
+		var level, message string
+		var code int
+		if err := rows.Scan(&level, &code, &message); err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, fmt.Sprintf("%s %d: %s", level, code, message))
+	}
+	return warnings, rows.Err()
+}
+
+// readerCount is used to generate unique reader handler names.
+var readerCount uint64
+
+// importWithLoadData loads records into table using LOAD DATA LOCAL INFILE,
+// streaming the records as tab separated values through a registered reader
+// handler.
+func importWithLoadData(ctx context.Context, db *sql.DB, table string, columns []string, next func() ([]interface{}, error)) (int64, error) {
+	name := fmt.Sprintf("usql_import_%d", atomic.AddUint64(&readerCount, 1))
+	pr, pw := io.Pipe()
+	mysql.RegisterReaderHandler(name, func() io.Reader { return pr })
+	defer mysql.DeregisterReaderHandler(name)
+	go func() {
+		for {
+			values, err := next()
+			if err == io.EOF {
+				pw.Close()
+				return
+			}
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			if _, err := io.WriteString(pw, encodeRecord(values)); err != nil {
+				return
+			}
+		}
+	}()
+	query := "LOAD DATA LOCAL INFILE 'Reader::" + name + "' INTO TABLE " + table +
+		` FIELDS TERMINATED BY '\t' ESCAPED BY '\\' LINES TERMINATED BY '\n'` +
+		" (" + quoteIdents(columns) + ")"
+	res, err := db.ExecContext(ctx, query)
+	// unblock the writer when the server stopped reading early
+	pr.Close()
+	if e, ok := err.(*mysql.MySQLError); ok && (e.Number == 1148 || e.Number == 3948) {
+		return 0, fmt.Errorf("%w (enable local_infile on the server, or import without bulk loading)", err)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// quoteIdents quotes and joins the identifiers.
+func quoteIdents(strs []string) string {
+	quoted := make([]string, len(strs))
+	for i, s := range strs {
+		quoted[i] = "`" + strings.ReplaceAll(s, "`", "``") + "`"
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// tsvEscaper escapes values for LOAD DATA.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+
+// encodeRecord encodes values as a LOAD DATA line.
+func encodeRecord(values []interface{}) string {
+	fields := make([]string, len(values))
+	for i, v := range values {
+		switch x := v.(type) {
+		case nil:
+			fields[i] = `\N`
+		case bool:
+			fields[i] = "0"
+			if x {
+				fields[i] = "1"
+			}
+		case time.Time:
+			fields[i] = x.Format("2006-01-02 15:04:05.999999")
+		case []byte:
+			fields[i] = tsvEscaper.Replace(string(x))
+		default:
+			fields[i] = tsvEscaper.Replace(fmt.Sprint(x))
+		}
+	}
+	return strings.Join(fields, "\t") + "\n"
+}
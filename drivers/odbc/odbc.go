@@ -6,15 +6,24 @@
 package odbc
 
 import (
+	"database/sql/driver"
+	"io"
+	"sort"
 	"strings"
 
 	"github.com/alexbrainman/odbc" // DRIVER
+	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
 )
 
 func init() {
 	drivers.Register("odbc", drivers.Driver{
 		LexerName: "tsql",
+		Open: func(u *dburl.URL, _, _ func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(name, _ string) (driver.Connector, error) {
+				return drivers.Connector(name, connString(u))
+			}, nil
+		},
 		IsPasswordErr: func(err error) bool {
 			if e, ok := err.(*odbc.Error); ok {
 				msg := strings.ToLower(e.Error())
@@ -27,3 +36,62 @@ func init() {
 		},
 	})
 }
+
+// connString returns the ODBC connection string of the URL, whose query
+// parameters are the raw attributes of the connection string. Unlike the
+// connection string generated by dburl, the values are escaped, and the
+// driver, server and port are only set when the URL has a transport or a
+// host, so that data sources (DSN=...) and file drivers (Access, ...) can be
+// connected to.
+func connString(u *dburl.URL) string {
+	q := u.Query()
+	if t := u.Transport; q.Get("Driver") == "" && t != "" && t != "tcp" && t != "unix" {
+		q.Set("Driver", strings.ReplaceAll(t, "+", " "))
+	}
+	if driver := q.Get("Driver"); driver != "" && !strings.HasPrefix(driver, "{") {
+		q.Set("Driver", "{"+strings.ReplaceAll(driver, "}", "}}")+"}")
+	}
+	if host := u.Hostname(); host != "" {
+		q.Set("Server", host)
+		port, proto := u.Port(), strings.ToLower(q.Get("Driver"))
+		switch {
+		case port != "":
+			q.Set("Port", port)
+		case strings.Contains(proto, "mysql"):
+			q.Set("Port", "3306")
+		case strings.Contains(proto, "postgres"):
+			q.Set("Port", "5432")
+		case strings.Contains(proto, "db2") || strings.Contains(proto, "ibm"):
+			q.Set("ServiceName", "50000")
+		default:
+			q.Set("Port", "1433")
+		}
+	}
+	if dbname := strings.TrimPrefix(u.Path, "/"); dbname != "" {
+		q.Set("Database", dbname)
+	}
+	if u.User != nil {
+		q.Set("UID", u.User.Username())
+		if pass, ok := u.User.Password(); ok {
+			q.Set("PWD", pass)
+		}
+	}
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var attrs []string
+	for _, k := range keys {
+		v := q.Get(k)
+		if v == "" {
+			continue
+		}
+		// values with special characters are enclosed in braces
+		if !strings.HasPrefix(v, "{") && (strings.ContainsAny(v, ";{}=") || strings.TrimSpace(v) != v) {
+			v = "{" + strings.ReplaceAll(v, "}", "}}") + "}"
+		}
+		attrs = append(attrs, k+"="+v)
+	}
+	return strings.Join(attrs, ";")
+}
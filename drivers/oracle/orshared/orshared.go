@@ -22,7 +22,9 @@ func Register(name string, err func(error) (string, string), isPasswordErr func(
 	endAnchorRE := regexp.MustCompile(`(?i)\send\s*;\s*$`)
 	drivers.Register(name, drivers.Driver{
 		AllowMultilineComments: true,
+		AllowBlocks:            true,
 		LowerColumnNames:       true,
+		Dialect:                "oracle",
 		ForceParams: func(u *dburl.URL) {
 			// if the service name is not specified, use the environment
 			// variable if present
@@ -67,8 +69,11 @@ func Register(name string, err func(error) (string, string), isPasswordErr func(
 		NewMetadataWriter: func(db drivers.DB, w io.Writer, opts ...metadata.ReaderOption) metadata.Writer {
 			return metadata.NewDefaultWriter(orameta.NewReader()(db, opts...))(db, w)
 		},
-		Copy: drivers.CopyWithInsert(func(n int) string {
-			return fmt.Sprintf(":%d", n)
-		}),
+		Copy:        drivers.CopyWithInsert(placeholder),
+		Placeholder: placeholder,
 	})
 }
+
+func placeholder(n int) string {
+	return fmt.Sprintf(":%d", n)
+}
@@ -6,6 +6,7 @@ package pgx
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
 	"io"
@@ -13,7 +14,9 @@ import (
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v5"
+	pgxconn "github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/stdlib" // DRIVER
+	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
 	"github.com/xo/usql/drivers/metadata"
 	pgmeta "github.com/xo/usql/drivers/metadata/postgres"
@@ -23,7 +26,38 @@ func init() {
 	drivers.Register("pgx", drivers.Driver{
 		AllowDollar:            true,
 		AllowMultilineComments: true,
+		AbortTxOnError:         true,
+		Cursors:                true,
+		ReadOnlyTransactions:   true,
+		Dialect:                "postgres",
 		LexerName:              "postgres",
+		ApplicationName: func(_ *dburl.URL, name string) (string, string) {
+			return "application_name", name
+		},
+		Open: func(_ *dburl.URL, _, stderr func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(_, dsn string) (driver.Connector, error) {
+				config, err := pgx.ParseConfig(dsn)
+				if err != nil {
+					return nil, err
+				}
+				config.OnNotice = func(_ *pgxconn.PgConn, notice *pgxconn.Notice) {
+					out := stderr()
+					// collected for the metadata of the statement
+					if nw, ok := out.(drivers.NoticeWriter); ok {
+						nw.Notice(notice.Severity + ": " + notice.Message)
+						if notice.Hint != "" {
+							nw.Notice("HINT: " + notice.Hint)
+						}
+						return
+					}
+					fmt.Fprintln(out, notice.Severity+": ", notice.Message)
+					if notice.Hint != "" {
+						fmt.Fprintln(out, "HINT: ", notice.Hint)
+					}
+				}
+				return stdlib.GetConnector(*config), nil
+			}, nil
+		},
 		Version: func(ctx context.Context, db drivers.DB) (string, error) {
 			var ver string
 			err := db.QueryRowContext(ctx, `SHOW server_version`).Scan(&ver)
@@ -54,7 +88,7 @@ func init() {
 		NewMetadataWriter: func(db drivers.DB, w io.Writer, opts ...metadata.ReaderOption) metadata.Writer {
 			return metadata.NewDefaultWriter(pgmeta.NewReader()(db, opts...))(db, w)
 		},
-		Copy: func(ctx context.Context, db *sql.DB, rows *sql.Rows, table string) (int64, error) {
+		Copy: func(ctx context.Context, db *sql.DB, rows drivers.CopyRows, table string) (int64, error) {
 			conn, err := db.Conn(context.Background())
 			if err != nil {
 				return 0, fmt.Errorf("failed to get a connection from pool: %w", err)
@@ -98,11 +132,58 @@ func init() {
 			})
 			return n, err
 		},
+		Placeholder: func(n int) string {
+			return fmt.Sprintf("$%d", n)
+		},
+		Import: func(ctx context.Context, db *sql.DB, table string, columns []string, next func() ([]interface{}, error)) (int64, error) {
+			conn, err := db.Conn(ctx)
+			if err != nil {
+				return 0, fmt.Errorf("failed to get a connection from pool: %w", err)
+			}
+			defer conn.Close()
+			var n int64
+			err = conn.Raw(func(driverConn interface{}) error {
+				conn := driverConn.(*stdlib.Conn).Conn()
+				n, err = conn.CopyFrom(ctx, pgx.Identifier(strings.Split(table, ".")), columns, &importRows{next: next})
+				return err
+			})
+			return n, err
+		},
+		CopyFrom: func(ctx context.Context, _ *dburl.URL, db *sql.DB, sqlstr string, r io.Reader) (int64, error) {
+			var n int64
+			err := rawConn(ctx, db, func(conn *pgx.Conn) error {
+				tag, err := conn.PgConn().CopyFrom(ctx, r, sqlstr)
+				n = tag.RowsAffected()
+				return err
+			})
+			return n, err
+		},
+		CopyTo: func(ctx context.Context, _ *dburl.URL, db *sql.DB, sqlstr string, w io.Writer) (int64, error) {
+			var n int64
+			err := rawConn(ctx, db, func(conn *pgx.Conn) error {
+				tag, err := conn.PgConn().CopyTo(ctx, w, sqlstr)
+				n = tag.RowsAffected()
+				return err
+			})
+			return n, err
+		},
+	})
+}
+
+// rawConn calls f with the pgx connection of a connection from the pool.
+func rawConn(ctx context.Context, db *sql.DB, f func(*pgx.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a connection from pool: %w", err)
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn interface{}) error {
+		return f(driverConn.(*stdlib.Conn).Conn())
 	})
 }
 
 type copyRows struct {
-	rows   *sql.Rows
+	rows   drivers.CopyRows
 	values []interface{}
 }
 
@@ -118,3 +199,25 @@ func (r *copyRows) Values() ([]interface{}, error) {
 func (r *copyRows) Err() error {
 	return r.rows.Err()
 }
+
+type importRows struct {
+	next   func() ([]interface{}, error)
+	values []interface{}
+	err    error
+}
+
+func (r *importRows) Next() bool {
+	r.values, r.err = r.next()
+	return r.err == nil
+}
+
+func (r *importRows) Values() ([]interface{}, error) {
+	return r.values, nil
+}
+
+func (r *importRows) Err() error {
+	if r.err == io.EOF {
+		return nil
+	}
+	return r.err
+}
@@ -10,10 +10,12 @@ package postgres
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"io"
 	"strings"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/lib/pq" // DRIVER
 	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
@@ -27,20 +29,38 @@ func init() {
 		Name:                   "pq",
 		AllowDollar:            true,
 		AllowMultilineComments: true,
+		AbortTxOnError:         true,
+		Cursors:                true,
+		ReadOnlyTransactions:   true,
 		LexerName:              "postgres",
+		ApplicationName: func(u *dburl.URL, name string) (string, string) {
+			// the key=value DSN of postgres URLs doesn't quote values
+			if u.Scheme == "postgres" && strings.ContainsAny(name, ` '\`) {
+				name = `'` + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(name) + `'`
+			}
+			return "application_name", name
+		},
 		ForceParams: func(u *dburl.URL) {
 			if u.Scheme == "cockroachdb" {
 				drivers.ForceQueryParameters([]string{"sslmode", "disable"})(u)
 			}
 		},
-		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (*sql.DB, error), error) {
-			return func(typ, dsn string) (*sql.DB, error) {
+		Open: func(u *dburl.URL, stdout, stderr func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(typ, dsn string) (driver.Connector, error) {
 				conn, err := pq.NewConnector(dsn)
 				if err != nil {
 					return nil, err
 				}
 				noticeConn := pq.ConnectorWithNoticeHandler(conn, func(notice *pq.Error) {
 					out := stderr()
+					// collected for the metadata of the statement
+					if nw, ok := out.(drivers.NoticeWriter); ok {
+						nw.Notice(notice.Severity + ": " + notice.Message)
+						if notice.Hint != "" {
+							nw.Notice("HINT: " + notice.Hint)
+						}
+						return
+					}
 					fmt.Fprintln(out, notice.Severity+": ", notice.Message)
 					if notice.Hint != "" {
 						fmt.Fprintln(out, "HINT: ", notice.Hint)
@@ -53,7 +73,7 @@ func init() {
 					}
 					fmt.Fprintln(stdout(), fmt.Sprintf(text.NotificationReceived, notification.Channel, payload, notification.BePid))
 				})
-				return sql.OpenDB(notificationConn), nil
+				return notificationConn, nil
 			}, nil
 		},
 		Version: func(ctx context.Context, db drivers.DB) (string, error) {
@@ -86,7 +106,7 @@ func init() {
 		NewMetadataWriter: func(db drivers.DB, w io.Writer, opts ...metadata.ReaderOption) metadata.Writer {
 			return metadata.NewDefaultWriter(pgmeta.NewReader()(db, opts...))(db, w)
 		},
-		Copy: func(ctx context.Context, db *sql.DB, rows *sql.Rows, table string) (int64, error) {
+		Copy: func(ctx context.Context, db *sql.DB, rows drivers.CopyRows, table string) (int64, error) {
 			columns, err := rows.Columns()
 			if err != nil {
 				return 0, fmt.Errorf("failed to fetch source rows columns: %w", err)
@@ -159,5 +179,68 @@ func init() {
 
 			return n, rows.Err()
 		},
+		Placeholder: func(n int) string {
+			return fmt.Sprintf("$%d", n)
+		},
+		Import: func(ctx context.Context, db *sql.DB, table string, columns []string, next func() ([]interface{}, error)) (int64, error) {
+			query := pq.CopyIn(table, columns...)
+			if i := strings.IndexRune(table, '.'); i != -1 {
+				query = pq.CopyInSchema(table[:i], table[i+1:], columns...)
+			}
+			tx, err := db.BeginTx(ctx, nil)
+			if err != nil {
+				return 0, fmt.Errorf("failed to begin transaction: %w", err)
+			}
+			defer tx.Rollback()
+			stmt, err := tx.PrepareContext(ctx, query)
+			if err != nil {
+				return 0, fmt.Errorf("failed to prepare copy query: %w", err)
+			}
+			defer stmt.Close()
+			for {
+				values, err := next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return 0, err
+				}
+				if _, err := stmt.ExecContext(ctx, values...); err != nil {
+					return 0, fmt.Errorf("failed to exec copy: %w", err)
+				}
+			}
+			res, err := stmt.ExecContext(ctx)
+			if err != nil {
+				return 0, fmt.Errorf("failed to final exec copy: %w", err)
+			}
+			n, err := res.RowsAffected()
+			if err != nil {
+				return 0, fmt.Errorf("failed to check rows affected: %w", err)
+			}
+			if err := tx.Commit(); err != nil {
+				return 0, fmt.Errorf("failed to commit transaction: %w", err)
+			}
+			return n, nil
+		},
+		// lib/pq doesn't stream COPY statements, that are streamed on a
+		// connection of pgconn
+		CopyFrom: func(ctx context.Context, u *dburl.URL, _ *sql.DB, sqlstr string, r io.Reader) (int64, error) {
+			conn, err := pgconn.Connect(ctx, u.DSN)
+			if err != nil {
+				return 0, err
+			}
+			defer conn.Close(ctx)
+			tag, err := conn.CopyFrom(ctx, r, sqlstr)
+			return tag.RowsAffected(), err
+		},
+		CopyTo: func(ctx context.Context, u *dburl.URL, _ *sql.DB, sqlstr string, w io.Writer) (int64, error) {
+			conn, err := pgconn.Connect(ctx, u.DSN)
+			if err != nil {
+				return 0, err
+			}
+			defer conn.Close(ctx)
+			tag, err := conn.CopyTo(ctx, w, sqlstr)
+			return tag.RowsAffected(), err
+		},
 	}, "cockroachdb", "redshift")
 }
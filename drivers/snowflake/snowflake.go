@@ -33,6 +33,7 @@ func init() {
 		infos.WithColumnPrivileges(false),
 	)
 	drivers.Register("snowflake", drivers.Driver{
+		NoSavepoints: true,
 		Err: func(err error) (string, string) {
 			if e, ok := err.(*gosnowflake.SnowflakeError); ok {
 				return strconv.Itoa(e.Number), e.Message
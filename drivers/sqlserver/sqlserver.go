@@ -6,7 +6,7 @@ package sqlserver
 
 import (
 	"context"
-	"database/sql"
+	"database/sql/driver"
 	"fmt"
 	"io"
 	"strconv"
@@ -14,9 +14,12 @@ import (
 
 	sqlserver "github.com/microsoft/go-mssqldb" // DRIVER
 	"github.com/microsoft/go-mssqldb/azuread"
+	_ "github.com/microsoft/go-mssqldb/namedpipe"    // named pipes (np:), on windows
+	_ "github.com/microsoft/go-mssqldb/sharedmemory" // shared memory (lpc:), on windows
 	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
 	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/text"
 )
 
 func init() {
@@ -24,13 +27,26 @@ func init() {
 		AllowMultilineComments:  true,
 		RequirePreviousPassword: true,
 		LexerName:               "tsql",
-		Open: func(u *dburl.URL, _, _ func() io.Writer) (func(string, string) (*sql.DB, error), error) {
-			return func(_ string, params string) (*sql.DB, error) {
-				driver := "sqlserver"
+		ApplicationName: func(_ *dburl.URL, name string) (string, string) {
+			return "app name", name
+		},
+		SavepointQuery: func(typ drivers.SavepointType, name string) (string, error) {
+			switch typ {
+			case drivers.SavepointCreate:
+				return "SAVE TRANSACTION " + name, nil
+			case drivers.SavepointRollback:
+				return "ROLLBACK TRANSACTION " + name, nil
+			}
+			// sqlserver savepoints are released on commit/rollback
+			return "", text.ErrNotSupported
+		},
+		Open: func(u *dburl.URL, _, _ func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(_ string, params string) (driver.Connector, error) {
+				name := "sqlserver"
 				if u.Query().Has("fedauth") {
-					driver = azuread.DriverName
+					name = azuread.DriverName
 				}
-				return sql.Open(driver, params)
+				return drivers.Connector(name, params)
 			}, nil
 		},
 		Version: func(ctx context.Context, db drivers.DB) (string, error) {
@@ -65,7 +81,8 @@ func init() {
 		NewMetadataWriter: func(db drivers.DB, w io.Writer, opts ...metadata.ReaderOption) metadata.Writer {
 			return metadata.NewDefaultWriter(NewReader(db, opts...))(db, w)
 		},
-		Copy: drivers.CopyWithInsert(placeholder),
+		Copy:        drivers.CopyWithInsert(placeholder),
+		Placeholder: placeholder,
 	})
 }
 
@@ -5,10 +5,19 @@ package trino
 
 import (
 	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"database/sql/driver"
+	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"os"
 	"regexp"
 
-	_ "github.com/trinodb/trino-go-client/trino" // DRIVER
+	"github.com/trinodb/trino-go-client/trino" // DRIVER
+	"github.com/xo/dburl"
 	"github.com/xo/usql/drivers"
 	"github.com/xo/usql/drivers/metadata"
 	infos "github.com/xo/usql/drivers/metadata/informationschema"
@@ -39,6 +48,16 @@ func init() {
 	}
 	drivers.Register("trino", drivers.Driver{
 		AllowMultilineComments: true,
+		NoTransactions:         true,
+		Open: func(_ *dburl.URL, _, _ func() io.Writer) (func(string, string) (driver.Connector, error), error) {
+			return func(name, dsn string) (driver.Connector, error) {
+				dsn, err := tokenDSN(dsn)
+				if err != nil {
+					return nil, err
+				}
+				return drivers.Connector(name, dsn)
+			}, nil
+		},
 		Process: func(prefix string, sqlstr string) (string, string, bool, error) {
 			sqlstr = endRE.ReplaceAllString(sqlstr, "")
 			typ, q := drivers.QueryExecType(prefix, sqlstr)
@@ -62,3 +81,56 @@ func init() {
 		Copy: drivers.CopyWithInsert(func(int) string { return "?" }),
 	})
 }
+
+// tokenDSN returns the dsn with its accessToken parameter replaced by a custom
+// client sending the token as the bearer token of JWT authentication, which
+// the driver doesn't support.
+func tokenDSN(dsn string) (string, error) {
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	q := u.Query()
+	token := q.Get("accessToken")
+	if token == "" {
+		return dsn, nil
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// the driver ignores the certificate with a custom client
+	if path := q.Get(trino.SSLCertPathConfig); path != "" {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(buf) {
+			return "", fmt.Errorf("%s: no PEM certificate", path)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	key := fmt.Sprintf("usql-%x", sha256.Sum256([]byte(dsn)))
+	if err := trino.RegisterCustomClient(key, &http.Client{
+		Transport: &bearerTransport{token: token, transport: transport},
+	}); err != nil {
+		return "", err
+	}
+	q.Del("accessToken")
+	q.Del(trino.SSLCertPathConfig)
+	q.Set("custom_client", key)
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
+
+// bearerTransport is a http transport adding the authorization header of a
+// bearer token to the requests.
+type bearerTransport struct {
+	token     string
+	transport http.RoundTripper
+}
+
+// RoundTrip satisfies the http.RoundTripper interface.
+func (t *bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+t.token)
+	return t.transport.RoundTrip(req)
+}
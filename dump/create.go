@@ -0,0 +1,184 @@
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+// errNoDefinition is returned by the create funcs when the database has no
+// definition of the object, reconstructed from the metadata of the table.
+var errNoDefinition = errors.New("no definition")
+
+// createFuncs are the funcs reading the CREATE statement of a table or view
+// from the database, by driver.
+var createFuncs = map[string]func(context.Context, *sql.DB, string, string) (string, error){
+	"postgres":   postgresCreate,
+	"mysql":      showCreate,
+	"clickhouse": showCreate,
+	"sqlite3":    sqliteCreate,
+	"duckdb":     duckdbCreate,
+	"sqlserver":  sqlserverCreate,
+	"oracle":     oracleCreate,
+}
+
+// ShowCreate returns the CREATE statement of the table or view, optionally
+// schema qualified: as written by the database (SHOW CREATE TABLE of mysql
+// and clickhouse databases, the sql of the sqlite_master table of sqlite3
+// databases, DBMS_METADATA.GET_DDL of oracle databases, and the definition
+// of the views of postgres and sqlserver databases), or reconstructed from
+// the metadata of the table, with its indexes.
+func ShowCreate(ctx context.Context, u *dburl.URL, db *sql.DB, name string) (string, error) {
+	schema, table := "", name
+	if i := strings.LastIndex(name, "."); i != -1 {
+		schema, table = name[:i], name[i+1:]
+	}
+	if f, ok := createFuncs[u.Unaliased]; ok {
+		switch stmt, err := f(ctx, db, schema, table); {
+		case err == nil:
+			return strings.TrimRight(stmt, "; \n") + ";", nil
+		case !errors.Is(err, errNoDefinition):
+			return "", err
+		}
+	}
+	tables, err := ReadTables(ctx, u, db, []string{name})
+	if err != nil {
+		return "", err
+	}
+	d := DialectFor(drivers.Caps(u).Dialect)
+	stmts := []string{d.CreateTable(tables[0])}
+	for _, index := range tables[0].Indexes {
+		stmts = append(stmts, d.CreateIndex(tables[0], index))
+	}
+	return strings.Join(stmts, "\n"), nil
+}
+
+// postgresCreate returns the CREATE statement of a postgres view or
+// materialized view, tables being reconstructed.
+func postgresCreate(ctx context.Context, db *sql.DB, schema, name string) (string, error) {
+	ident := name
+	if schema != "" {
+		ident = schema + "." + name
+	}
+	var kind, def string
+	err := db.QueryRowContext(ctx, `SELECT c.relkind::text, CASE WHEN c.relkind IN ('v', 'm') THEN pg_get_viewdef(c.oid, true) ELSE '' END `+
+		`FROM pg_class c WHERE c.oid = to_regclass($1)`, ident).Scan(&kind, &def)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return "", fmt.Errorf("relation %s does not exist", ident)
+	case err != nil:
+		return "", err
+	case kind == "v":
+		return "CREATE VIEW " + ident + " AS\n" + def, nil
+	case kind == "m":
+		return "CREATE MATERIALIZED VIEW " + ident + " AS\n" + def, nil
+	}
+	return "", errNoDefinition
+}
+
+// showCreate returns the CREATE statement of a mysql or clickhouse table or
+// view, with SHOW CREATE TABLE.
+func showCreate(ctx context.Context, db *sql.DB, schema, name string) (string, error) {
+	d := DialectFor("mysql")
+	rows, err := db.QueryContext(ctx, "SHOW CREATE TABLE "+d.Qualify(schema, name))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+		return "", fmt.Errorf("table %s does not exist", name)
+	}
+	vals := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return "", err
+	}
+	// mysql returns the name and the statement, clickhouse the statement
+	return vals[len(cols)-1].String, nil
+}
+
+// sqliteCreate returns the CREATE statements of a sqlite3 table or view, and
+// of its indexes.
+func sqliteCreate(ctx context.Context, db *sql.DB, schema, name string) (string, error) {
+	master := "sqlite_master"
+	if schema != "" {
+		master = Dialect{}.QuoteIdent(schema) + ".sqlite_master"
+	}
+	rows, err := db.QueryContext(ctx, `SELECT sql FROM `+master+` WHERE tbl_name = ? AND sql IS NOT NULL `+
+		`ORDER BY CASE WHEN type IN ('table', 'view') THEN 0 ELSE 1 END, name`, name)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var stmts []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			return "", err
+		}
+		stmts = append(stmts, strings.TrimRight(stmt, "; \n")+";")
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	if len(stmts) == 0 {
+		return "", fmt.Errorf("table %s does not exist", name)
+	}
+	return strings.Join(stmts, "\n"), nil
+}
+
+// duckdbCreate returns the CREATE statement of a duckdb table or view.
+func duckdbCreate(ctx context.Context, db *sql.DB, schema, name string) (string, error) {
+	var stmt string
+	err := db.QueryRowContext(ctx, `SELECT sql FROM (`+
+		`SELECT schema_name, table_name AS name, sql FROM duckdb_tables() UNION ALL `+
+		`SELECT schema_name, view_name, sql FROM duckdb_views() WHERE NOT internal`+
+		`) WHERE name = ? AND (? = '' OR schema_name = ?) LIMIT 1`, name, schema, schema).Scan(&stmt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("table %s does not exist", name)
+	}
+	return stmt, err
+}
+
+// sqlserverCreate returns the CREATE statement of a sqlserver view, tables
+// being reconstructed.
+func sqlserverCreate(ctx context.Context, db *sql.DB, schema, name string) (string, error) {
+	var def sql.NullString
+	if err := db.QueryRowContext(ctx, `SELECT OBJECT_DEFINITION(OBJECT_ID(@p1))`, Dialect{}.Qualify(schema, name)).Scan(&def); err != nil {
+		return "", err
+	}
+	if !def.Valid {
+		return "", errNoDefinition
+	}
+	return strings.TrimSpace(def.String), nil
+}
+
+// oracleCreate returns the CREATE statement of an oracle table or view of the
+// current user, with DBMS_METADATA.GET_DDL.
+func oracleCreate(ctx context.Context, db *sql.DB, schema, name string) (string, error) {
+	if schema != "" {
+		return "", errNoDefinition
+	}
+	var stmt string
+	err := db.QueryRowContext(ctx, `SELECT DBMS_METADATA.GET_DDL(object_type, object_name) FROM user_objects `+
+		`WHERE object_name = UPPER(:1) AND object_type IN ('TABLE', 'VIEW')`, name).Scan(&stmt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("table %s does not exist", name)
+	}
+	return strings.TrimSpace(stmt), err
+}
@@ -0,0 +1,50 @@
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+)
+
+func TestShowCreate(t *testing.T) {
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, s := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT)`,
+		`CREATE UNIQUE INDEX users_email ON users (email)`,
+		`CREATE VIEW emails AS SELECT email FROM users;`,
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		name, exp string
+	}{
+		{"users", "CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT);\nCREATE UNIQUE INDEX users_email ON users (email);"},
+		{"main.emails", "CREATE VIEW emails AS SELECT email FROM users;"},
+	}
+	for i, test := range tests {
+		stmt, err := ShowCreate(context.Background(), u, db, test.name)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if stmt != test.exp {
+			t.Errorf("test %d expected:\n%s\ngot:\n%s", i, test.exp, stmt)
+		}
+	}
+	if _, err := ShowCreate(context.Background(), u, db, "missing"); err == nil {
+		t.Errorf("expected error for missing table, got nil")
+	}
+}
@@ -0,0 +1,166 @@
+package dump
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/export"
+)
+
+// Dialect are the identifier and literal rules of a database.
+type Dialect struct {
+	// escapeBackslash doubles backslashes in strings.
+	escapeBackslash bool
+	// byteaHex writes binary values as '\x..' instead of X'..'.
+	byteaHex bool
+	// timeZone includes the time zone offset in time values.
+	timeZone bool
+	// backtick quotes identifiers with backticks.
+	backtick bool
+	// sqlite is set for sqlite databases.
+	sqlite bool
+	// maxBatch is the maximum number of rows of multi-row INSERT
+	// statements, unlimited when 0.
+	maxBatch int
+}
+
+// DialectFor returns the dialect of the SQL dialect of a driver (see
+// drivers.Capabilities).
+func DialectFor(dialect string) Dialect {
+	switch dialect {
+	case "postgres":
+		return Dialect{byteaHex: true, timeZone: true}
+	case "mysql":
+		return Dialect{escapeBackslash: true, backtick: true}
+	case "sqlite3":
+		return Dialect{sqlite: true}
+	case "sqlserver":
+		return Dialect{maxBatch: 1000}
+	case "oracle":
+		return Dialect{maxBatch: 1}
+	}
+	return Dialect{}
+}
+
+// CreateTable returns the CREATE TABLE statement of t.
+func (d Dialect) CreateTable(t *Table) string {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE " + d.Qualify(t.Schema, t.Name) + " (")
+	for i, c := range t.Columns {
+		if i != 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString("\n  " + d.ColumnDef(c))
+	}
+	if len(t.PrimaryKey) != 0 {
+		sb.WriteString(",\n  PRIMARY KEY (" + d.QuoteIdents(t.PrimaryKey) + ")")
+	}
+	sb.WriteString("\n);")
+	return sb.String()
+}
+
+// ColumnDef returns the definition of the column, as used in CREATE TABLE.
+func (d Dialect) ColumnDef(c metadata.Column) string {
+	def := d.QuoteIdent(c.Name)
+	if c.DataType != "" {
+		def += " " + c.DataType
+	}
+	if c.IsNullable == metadata.NO {
+		def += " NOT NULL"
+	}
+	// sequences are not dumped, so their defaults cannot be restored
+	if c.Default != "" && !strings.HasPrefix(c.Default, "nextval(") {
+		def += " DEFAULT " + c.Default
+	}
+	return def
+}
+
+// CreateIndex returns the CREATE INDEX statement of the index of t.
+func (d Dialect) CreateIndex(t *Table, index Index) string {
+	name := index.Name
+	if strings.HasPrefix(name, "sqlite_") {
+		// names of sqlite's implicit indexes are reserved
+		name = t.Name + "_" + strings.Join(index.Columns, "_") + "_idx"
+	}
+	unique := ""
+	if index.Unique {
+		unique = "UNIQUE "
+	}
+	return fmt.Sprintf("CREATE %sINDEX %s ON %s (%s);", unique, d.QuoteIdent(name), d.Qualify(t.Schema, t.Name), d.QuoteIdents(index.Columns))
+}
+
+// Literal returns v, a value of a column of the kind, as a SQL literal.
+func (d Dialect) Literal(kind export.ColumnKind, v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		if x {
+			return "TRUE"
+		}
+		return "FALSE"
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return d.Quote(strconv.FormatFloat(x, 'g', -1, 64))
+		}
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case time.Time:
+		layout := "2006-01-02 15:04:05.999999999"
+		if d.timeZone {
+			layout += "-07:00"
+		}
+		return d.Quote(x.Format(layout))
+	case []byte:
+		if kind == export.KindBinary {
+			if d.byteaHex {
+				return `'\x` + hex.EncodeToString(x) + `'`
+			}
+			return "X'" + hex.EncodeToString(x) + "'"
+		}
+		return d.Quote(string(x))
+	case string:
+		return d.Quote(x)
+	}
+	return d.Quote(fmt.Sprint(v))
+}
+
+// Quote returns s as a quoted string literal.
+func (d Dialect) Quote(s string) string {
+	if d.escapeBackslash {
+		s = strings.ReplaceAll(s, `\`, `\\`)
+	}
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// QuoteIdent quotes the identifier, preserving its case and avoiding clashes
+// with keywords.
+func (d Dialect) QuoteIdent(s string) string {
+	if d.backtick {
+		return "`" + strings.ReplaceAll(s, "`", "``") + "`"
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// QuoteIdents quotes and joins the identifiers.
+func (d Dialect) QuoteIdents(strs []string) string {
+	quoted := make([]string, len(strs))
+	for i, s := range strs {
+		quoted[i] = d.QuoteIdent(s)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// Qualify returns the quoted, schema qualified name of a table.
+func (d Dialect) Qualify(schema, name string) string {
+	if schema == "" {
+		return d.QuoteIdent(name)
+	}
+	return d.QuoteIdent(schema) + "." + d.QuoteIdent(name)
+}
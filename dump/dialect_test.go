@@ -0,0 +1,55 @@
+package dump
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/xo/usql/export"
+)
+
+func TestLiteral(t *testing.T) {
+	ts := time.Date(2024, 1, 2, 3, 4, 5, 600000000, time.FixedZone("", 3600))
+	tests := []struct {
+		driver string
+		kind   export.ColumnKind
+		v      interface{}
+		exp    string
+	}{
+		{"sqlite3", export.KindString, nil, "NULL"},
+		{"sqlite3", export.KindInt, int64(-42), "-42"},
+		{"sqlite3", export.KindFloat, 1.5, "1.5"},
+		{"sqlite3", export.KindFloat, math.Inf(1), "'+Inf'"},
+		{"sqlite3", export.KindBool, true, "TRUE"},
+		{"sqlite3", export.KindString, "o'brien", "'o''brien'"},
+		{"sqlite3", export.KindString, []byte(`a\b`), `'a\b'`},
+		{"mysql", export.KindString, []byte(`a\b`), `'a\\b'`},
+		{"sqlite3", export.KindBinary, []byte{1, 0xab}, "X'01ab'"},
+		{"postgres", export.KindBinary, []byte{1, 0xab}, `'\x01ab'`},
+		{"sqlite3", export.KindTime, ts, "'2024-01-02 03:04:05.6'"},
+		{"postgres", export.KindTime, ts, "'2024-01-02 03:04:05.6+01:00'"},
+	}
+	for i, test := range tests {
+		if s := DialectFor(test.driver).Literal(test.kind, test.v); s != test.exp {
+			t.Errorf("test %d expected %s, got: %s", i, test.exp, s)
+		}
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	tests := []struct {
+		driver string
+		s      string
+		exp    string
+	}{
+		{"postgres", "Order", `"Order"`},
+		{"postgres", `a"b`, `"a""b"`},
+		{"mysql", "order", "`order`"},
+		{"mysql", "a`b", "`a``b`"},
+	}
+	for i, test := range tests {
+		if s := DialectFor(test.driver).QuoteIdent(test.s); s != test.exp {
+			t.Errorf("test %d expected %s, got: %s", i, test.exp, s)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+// Package dump writes the schema and data of a database as a SQL script.
+package dump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/export"
+	"github.com/xo/usql/progress"
+	"github.com/xo/usql/text"
+)
+
+// Options are the dump options.
+type Options struct {
+	// SchemaOnly dumps only the table definitions.
+	SchemaOnly bool
+	// DataOnly dumps only the table rows.
+	DataOnly bool
+	// Tables are the tables to dump, optionally schema qualified. All tables
+	// are dumped when empty.
+	Tables []string
+	// Progress is the progress the dumped rows are added to, when not nil.
+	Progress *progress.Progress
+}
+
+// SQL writes the tables of the database as a portable SQL script of CREATE
+// TABLE, INSERT and CREATE INDEX statements, using the driver's metadata
+// reader. Views, sequences, foreign keys and other objects are not dumped.
+func SQL(ctx context.Context, w io.Writer, u *dburl.URL, db *sql.DB, opts Options) error {
+	tables, err := ReadTables(ctx, u, db, opts.Tables)
+	if err != nil {
+		return err
+	}
+	d := DialectFor(drivers.Caps(u).Dialect)
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "-- %s dump of %s\n", text.CommandName, u.Redacted())
+	if !opts.DataOnly {
+		for _, t := range tables {
+			fmt.Fprintf(bw, "\n%s\n", d.CreateTable(t))
+		}
+	}
+	if !opts.SchemaOnly {
+		for _, t := range tables {
+			if err := insertRows(ctx, bw, d, db, t, opts.Progress); err != nil {
+				return err
+			}
+		}
+	}
+	if !opts.DataOnly {
+		for _, t := range tables {
+			for _, index := range t.Indexes {
+				fmt.Fprintf(bw, "\n%s\n", d.CreateIndex(t, index))
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+// insertRows writes an INSERT statement for each row of t, adding the rows to
+// the progress.
+func insertRows(ctx context.Context, w *bufio.Writer, d Dialect, db *sql.DB, t *Table, p *progress.Progress) error {
+	name := d.Qualify(t.Schema, t.Name)
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	names, kinds := make([]string, len(cts)), make([]export.ColumnKind, len(cts))
+	for i, ct := range cts {
+		names[i], kinds[i] = ct.Name(), export.Kind(ct)
+	}
+	prefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES (", name, d.QuoteIdents(names))
+	values := make([]interface{}, len(cts))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	first := true
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return err
+		}
+		if first {
+			w.WriteString("\n")
+			first = false
+		}
+		w.WriteString(prefix)
+		for i, v := range values {
+			if i != 0 {
+				w.WriteString(", ")
+			}
+			w.WriteString(d.Literal(kinds[i], *(v.(*interface{}))))
+		}
+		w.WriteString(");\n")
+		p.Add(1)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,72 @@
+package dump
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/xo/usql/export"
+)
+
+// Inserts writes the rows as INSERT statements into the table, optionally
+// schema qualified, of up to batch rows each (as supported by the dialect),
+// returning the number of written rows.
+func (d Dialect) Inserts(w io.Writer, rows export.Rows, table string, batch int) (int64, error) {
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	names, kinds := make([]string, len(cts)), make([]export.ColumnKind, len(cts))
+	for i, ct := range cts {
+		names[i], kinds[i] = ct.Name(), export.Kind(ct)
+	}
+	if d.maxBatch != 0 && batch > d.maxBatch {
+		batch = d.maxBatch
+	}
+	if batch < 1 {
+		batch = 1
+	}
+	schema, name := "", table
+	if i := strings.LastIndex(table, "."); i != -1 {
+		schema, name = table[:i], table[i+1:]
+	}
+	prefix := "INSERT INTO " + d.Qualify(schema, name) + " (" + d.QuoteIdents(names) + ") VALUES"
+	values := make([]interface{}, len(cts))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	bw := bufio.NewWriter(w)
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return n, err
+		}
+		switch {
+		case n%int64(batch) == 0:
+			bw.WriteString(prefix)
+			if batch != 1 {
+				bw.WriteString("\n ")
+			}
+		default:
+			bw.WriteString(",\n ")
+		}
+		bw.WriteString(" (")
+		for i, v := range values {
+			if i != 0 {
+				bw.WriteString(", ")
+			}
+			bw.WriteString(d.Literal(kinds[i], *(v.(*interface{}))))
+		}
+		bw.WriteString(")")
+		if n++; n%int64(batch) == 0 {
+			bw.WriteString(";\n")
+		}
+	}
+	if n%int64(batch) != 0 {
+		bw.WriteString(";\n")
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
@@ -0,0 +1,58 @@
+package dump
+
+import (
+	"bytes"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestInserts(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, s := range []string{
+		`CREATE TABLE plans (id INTEGER, name TEXT, price REAL)`,
+		`INSERT INTO plans VALUES (1, 'free', 0), (2, 'o''pro', 9.5), (3, NULL, NULL)`,
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		dialect, table string
+		batch          int
+		exp            string
+	}{
+		{"sqlite3", "plans", 2, `INSERT INTO "plans" ("id", "name", "price") VALUES
+  (1, 'free', 0),
+  (2, 'o''pro', 9.5);
+INSERT INTO "plans" ("id", "name", "price") VALUES
+  (3, NULL, NULL);
+`},
+		{"mysql", "app.plans", 100, "INSERT INTO `app`.`plans` (`id`, `name`, `price`) VALUES\n  (1, 'free', 0),\n  (2, 'o''pro', 9.5),\n  (3, NULL, NULL);\n"},
+		{"oracle", "plans", 100, `INSERT INTO "plans" ("id", "name", "price") VALUES (1, 'free', 0);
+INSERT INTO "plans" ("id", "name", "price") VALUES (2, 'o''pro', 9.5);
+INSERT INTO "plans" ("id", "name", "price") VALUES (3, NULL, NULL);
+`},
+	}
+	for i, test := range tests {
+		rows, err := db.Query(`SELECT * FROM plans ORDER BY id`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		buf := new(bytes.Buffer)
+		n, err := DialectFor(test.dialect).Inserts(buf, rows, test.table, test.batch)
+		rows.Close()
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if n != 3 || buf.String() != test.exp {
+			t.Errorf("test %d expected 3 rows:\n%s\ngot %d:\n%s", i, test.exp, n, buf.String())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package dump
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+// Native returns the command running the database's native dump tool (ie,
+// pg_dump, mysqldump) for the database, with the credentials of the URL
+// passed through the environment. Returns nil when the database has no native
+// dump tool or it is not in the PATH.
+func Native(ctx context.Context, u *dburl.URL, opts Options) *exec.Cmd {
+	var name string
+	var args, env []string
+	password, _ := u.User.Password()
+	switch drivers.Caps(u).Dialect {
+	case "postgres":
+		name, args = "pg_dump", []string{"--no-password"}
+		if h := u.Hostname(); h != "" {
+			args = append(args, "--host", h)
+		}
+		if p := u.Port(); p != "" {
+			args = append(args, "--port", p)
+		}
+		if user := u.User.Username(); user != "" {
+			args = append(args, "--username", user)
+		}
+		switch {
+		case opts.SchemaOnly:
+			args = append(args, "--schema-only")
+		case opts.DataOnly:
+			args = append(args, "--data-only")
+		}
+		for _, t := range opts.Tables {
+			args = append(args, "--table", t)
+		}
+		args = append(args, strings.TrimPrefix(u.Path, "/"))
+		if password != "" {
+			env = append(env, "PGPASSWORD="+password)
+		}
+		if sslmode := u.Query().Get("sslmode"); sslmode != "" {
+			env = append(env, "PGSSLMODE="+sslmode)
+		}
+	case "mysql":
+		name = "mysqldump"
+		if h := u.Hostname(); h != "" {
+			args = append(args, "--host", h)
+		}
+		if p := u.Port(); p != "" {
+			args = append(args, "--port", p)
+		}
+		if user := u.User.Username(); user != "" {
+			args = append(args, "--user", user)
+		}
+		switch {
+		case opts.SchemaOnly:
+			args = append(args, "--no-data")
+		case opts.DataOnly:
+			args = append(args, "--no-create-info")
+		}
+		args = append(args, "--single-transaction", strings.TrimPrefix(u.Path, "/"))
+		args = append(args, opts.Tables...)
+		if password != "" {
+			env = append(env, "MYSQL_PWD="+password)
+		}
+	default:
+		return nil
+	}
+	path, err := exec.LookPath(name)
+	if err != nil {
+		return nil
+	}
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), env...)
+	return cmd
+}
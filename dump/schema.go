@@ -0,0 +1,324 @@
+package dump
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/text"
+)
+
+// Table is the definition of a table, read from the database's metadata.
+type Table struct {
+	Schema string
+	Name   string
+	// Columns are the columns, ordered by their position.
+	Columns    []metadata.Column
+	PrimaryKey []string
+	// Indexes are the indexes, except the primary key.
+	Indexes []Index
+	// Constraints are the foreign key and check constraints.
+	Constraints []Constraint
+}
+
+// Index is a table index.
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// Constraint is a foreign key or check constraint.
+type Constraint struct {
+	Name           string
+	Type           string
+	Columns        []string
+	ForeignSchema  string
+	ForeignTable   string
+	ForeignColumns []string
+	CheckClause    string
+}
+
+// tableTypes are the metadata table types of regular tables.
+var tableTypes = []string{"TABLE", "BASE TABLE"}
+
+// ReadTables reads the definitions of the named tables, optionally schema
+// qualified, or of all tables when names is empty.
+func ReadTables(ctx context.Context, u *dburl.URL, db *sql.DB, names []string) ([]*Table, error) {
+	r, err := drivers.NewMetadataReader(ctx, u, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	tr, ok := r.(metadata.TableReader)
+	if !ok {
+		return nil, fmt.Errorf(text.NotSupportedByDriver, "reading tables", u.Driver)
+	}
+	cr, ok := r.(metadata.ColumnReader)
+	if !ok {
+		return nil, fmt.Errorf(text.NotSupportedByDriver, "reading columns", u.Driver)
+	}
+	mt, err := listTables(tr, names)
+	if err != nil {
+		return nil, err
+	}
+	sr := &schemaReader{db: db, r: r, sqlite: DialectFor(drivers.Caps(u).Dialect).sqlite}
+	tables := make([]*Table, len(mt))
+	for i, t := range mt {
+		if tables[i], err = sr.table(cr, t); err != nil {
+			return nil, err
+		}
+	}
+	return tables, nil
+}
+
+// listTables returns the named tables, or all tables when names is empty.
+func listTables(tr metadata.TableReader, names []string) ([]metadata.Table, error) {
+	if len(names) == 0 {
+		return readTables(tr, metadata.Filter{Types: tableTypes})
+	}
+	var tables []metadata.Table
+	for _, name := range names {
+		var schema string
+		if i := strings.LastIndex(name, "."); i != -1 {
+			schema, name = name[:i], name[i+1:]
+		}
+		res, err := readTables(tr, metadata.Filter{Schema: schema, Name: name, Types: tableTypes, WithSystem: true})
+		switch {
+		case err != nil:
+			return nil, err
+		case len(res) == 0:
+			return nil, fmt.Errorf("table %s does not exist", name)
+		}
+		tables = append(tables, res...)
+	}
+	return tables, nil
+}
+
+// readTables reads the tables matching f.
+func readTables(tr metadata.TableReader, f metadata.Filter) ([]metadata.Table, error) {
+	res, err := tr.Tables(f)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var tables []metadata.Table
+	for res.Next() {
+		tables = append(tables, *res.Get())
+	}
+	return tables, nil
+}
+
+// schemaReader reads table definitions.
+type schemaReader struct {
+	db *sql.DB
+	r  metadata.Reader
+	// sqlite reads rowid primary keys and foreign keys from pragmas.
+	sqlite bool
+}
+
+// table reads the definition of t.
+func (sr *schemaReader) table(cr metadata.ColumnReader, t metadata.Table) (*Table, error) {
+	table := &Table{Schema: t.Schema, Name: t.Name}
+	var err error
+	if table.Columns, err = sr.columns(cr, t); err != nil {
+		return nil, err
+	}
+	if err := sr.indexes(t, table); err != nil {
+		return nil, err
+	}
+	if table.PrimaryKey == nil && sr.sqlite {
+		if table.PrimaryKey, err = sr.rowidPrimaryKey(t); err != nil {
+			return nil, err
+		}
+	}
+	if table.Constraints, err = sr.constraints(t); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// columns returns the columns of t, ordered by their position.
+func (sr *schemaReader) columns(cr metadata.ColumnReader, t metadata.Table) ([]metadata.Column, error) {
+	res, err := cr.Columns(metadata.Filter{Catalog: t.Catalog, Schema: t.Schema, Parent: t.Name, WithSystem: true})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var cols []metadata.Column
+	for res.Next() {
+		if c := res.Get(); c.Table == t.Name {
+			cols = append(cols, *c)
+		}
+	}
+	sort.SliceStable(cols, func(i, j int) bool {
+		return cols[i].OrdinalPosition < cols[j].OrdinalPosition
+	})
+	return cols, nil
+}
+
+// indexes reads the primary key and indexes of t.
+func (sr *schemaReader) indexes(t metadata.Table, table *Table) error {
+	ir, ok := sr.r.(metadata.IndexReader)
+	if !ok {
+		return nil
+	}
+	res, err := ir.Indexes(metadata.Filter{Catalog: t.Catalog, Schema: t.Schema, Parent: t.Name, WithSystem: true})
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	for res.Next() {
+		index := res.Get()
+		if index.Table != t.Name {
+			continue
+		}
+		cols, err := sr.indexColumns(t, index)
+		switch {
+		case err != nil:
+			return err
+		case len(cols) == 0:
+		case index.IsPrimary == metadata.YES:
+			table.PrimaryKey = cols
+		default:
+			table.Indexes = append(table.Indexes, Index{
+				Name:    index.Name,
+				Unique:  index.IsUnique == metadata.YES,
+				Columns: cols,
+			})
+		}
+	}
+	return nil
+}
+
+// indexColumns returns the column names of the index.
+func (sr *schemaReader) indexColumns(t metadata.Table, index *metadata.Index) ([]string, error) {
+	icr, ok := sr.r.(metadata.IndexColumnReader)
+	if !ok {
+		if index.Columns == "" {
+			return nil, nil
+		}
+		return strings.Split(index.Columns, ", "), nil
+	}
+	res, err := icr.IndexColumns(metadata.Filter{Catalog: t.Catalog, Schema: t.Schema, Parent: t.Name, Name: index.Name, WithSystem: true})
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	var cols []metadata.IndexColumn
+	for res.Next() {
+		if c := res.Get(); c.Table == t.Name && c.IndexName == index.Name {
+			cols = append(cols, *c)
+		}
+	}
+	sort.SliceStable(cols, func(i, j int) bool {
+		return cols[i].OrdinalPosition < cols[j].OrdinalPosition
+	})
+	names := make([]string, len(cols))
+	for i, c := range cols {
+		names[i] = c.Name
+	}
+	return names, nil
+}
+
+// rowidPrimaryKey returns the primary key columns of a sqlite table, as rowid
+// primary keys (ie, INTEGER PRIMARY KEY) have no index.
+func (sr *schemaReader) rowidPrimaryKey(t metadata.Table) ([]string, error) {
+	rows, err := sr.db.Query(`SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk`, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, rows.Err()
+}
+
+// constraints returns the foreign key and check constraints of t.
+func (sr *schemaReader) constraints(t metadata.Table) ([]Constraint, error) {
+	cr, ok := sr.r.(metadata.ConstraintReader)
+	switch {
+	case !ok && sr.sqlite:
+		return sr.sqliteForeignKeys(t)
+	case !ok:
+		return nil, nil
+	}
+	res, err := cr.Constraints(metadata.Filter{Catalog: t.Catalog, Schema: t.Schema, Parent: t.Name, WithSystem: true})
+	switch {
+	case err == text.ErrNotSupported:
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	defer res.Close()
+	var constraints []Constraint
+	for res.Next() {
+		c := res.Get()
+		switch {
+		case c.Table != t.Name:
+			continue
+		case c.Type == "CHECK" && strings.HasSuffix(c.CheckClause, "IS NOT NULL"):
+			// not null columns are reported as check constraints by postgres
+			continue
+		case c.Type != "FOREIGN KEY" && c.Type != "CHECK":
+			continue
+		}
+		constraint := Constraint{
+			Name:          c.Name,
+			Type:          c.Type,
+			ForeignSchema: c.ForeignSchema,
+			ForeignTable:  c.ForeignTable,
+			CheckClause:   c.CheckClause,
+		}
+		if ccr, ok := sr.r.(metadata.ConstraintColumnReader); ok && c.Type == "FOREIGN KEY" {
+			cols, err := ccr.ConstraintColumns(metadata.Filter{Catalog: t.Catalog, Schema: t.Schema, Parent: t.Name, Name: c.Name, WithSystem: true})
+			if err != nil {
+				return nil, err
+			}
+			for cols.Next() {
+				col := cols.Get()
+				constraint.Columns = append(constraint.Columns, col.Name)
+				constraint.ForeignColumns = append(constraint.ForeignColumns, col.ForeignName)
+			}
+			cols.Close()
+		}
+		constraints = append(constraints, constraint)
+	}
+	return constraints, nil
+}
+
+// sqliteForeignKeys returns the foreign keys of a sqlite table, which have no
+// names.
+func (sr *schemaReader) sqliteForeignKeys(t metadata.Table) ([]Constraint, error) {
+	rows, err := sr.db.Query(`SELECT id, "table", "from", COALESCE("to", '') FROM pragma_foreign_key_list(?) ORDER BY id, seq`, t.Name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var constraints []Constraint
+	last := -1
+	for rows.Next() {
+		var id int
+		var table, from, to string
+		if err := rows.Scan(&id, &table, &from, &to); err != nil {
+			return nil, err
+		}
+		if id != last {
+			constraints = append(constraints, Constraint{Type: "FOREIGN KEY", ForeignTable: table})
+			last = id
+		}
+		c := &constraints[len(constraints)-1]
+		c.Columns, c.ForeignColumns = append(c.Columns, from), append(c.ForeignColumns, to)
+	}
+	return constraints, rows.Err()
+}
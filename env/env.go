@@ -4,6 +4,7 @@ package env
 
 import (
 	"bytes"
+	"errors"
 	"io"
 	"io/ioutil"
 	"os"
@@ -196,10 +197,25 @@ func Shell(s string) error {
 	// drop to shell
 	cmd := exec.Command(shell, params...)
 	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
-	_ = cmd.Run()
+	SetShellResult(cmd.Run())
 	return nil
 }
 
+// SetShellResult sets the SHELL_ERROR and SHELL_EXIT_CODE variables to the
+// result of a shell command.
+func SetShellResult(err error) {
+	code := 0
+	var e *exec.ExitError
+	switch {
+	case errors.As(err, &e):
+		code = e.ExitCode()
+	case err != nil:
+		code = 127
+	}
+	vars.Set("SHELL_ERROR", strconv.FormatBool(code != 0))
+	vars.Set("SHELL_EXIT_CODE", strconv.Itoa(code))
+}
+
 // Pipe starts a command and returns its input for writing.
 func Pipe(c string) (io.WriteCloser, *exec.Cmd, error) {
 	shell, param := Getshell()
@@ -230,6 +246,7 @@ func Exec(s string) (string, error) {
 		return "", text.ErrNoShellAvailable
 	}
 	buf, err := exec.Command(shell, param, s).CombinedOutput()
+	SetShellResult(err)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,77 @@
+package env
+
+import (
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/xo/dburl/passfile"
+	"github.com/xo/usql/text"
+	"gopkg.in/yaml.v2"
+)
+
+// Session is a named session, saved with \session save and resumed with usql
+// repl --session: the connection, the variables and the query buffer of an
+// interactive session, so that an investigation can be resumed later or
+// handed to a teammate.
+type Session struct {
+	Saved time.Time `yaml:"saved"`
+	// Alias and Role are the database alias of the config file of the
+	// connection, and its role.
+	Alias string `yaml:"alias,omitempty"`
+	Role  string `yaml:"role,omitempty"`
+	// URL is the URL of the database when not an alias, without its
+	// password.
+	URL   string `yaml:"url,omitempty"`
+	State `yaml:",inline"`
+	// Buffer is the query buffer, or the last executed statement when
+	// empty.
+	Buffer string `yaml:"buffer,omitempty"`
+}
+
+// SessionFile returns the path to the file of the named session, or name
+// when a path to a .yaml file.
+//
+// Defaults to ~/.<command name>/sessions/<name>.yaml, the directory
+// overridden by environment variable <COMMAND NAME>_SESSION_DIR (ie,
+// ~/.usql/sessions and USQL_SESSION_DIR).
+func SessionFile(u *user.User, name string) string {
+	if strings.HasSuffix(name, ".yaml") && strings.ContainsAny(name, `/\`) {
+		return name
+	}
+	n := text.CommandUpper() + "_SESSION_DIR"
+	dir := "~/." + text.CommandLower() + "/sessions"
+	if s, ok := Getenv(n); ok {
+		dir = s
+	}
+	return filepath.Join(passfile.Expand(u.HomeDir, dir), name+".yaml")
+}
+
+// LoadSession loads the session file at path.
+func LoadSession(path string) (*Session, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Session)
+	if err := yaml.Unmarshal(buf, s); err != nil {
+		return nil, fmt.Errorf("invalid session file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Save saves the session to the session file at path, creating its
+// directory.
+func (s *Session) Save(path string) error {
+	buf, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0o600)
+}
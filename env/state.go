@@ -0,0 +1,139 @@
+package env
+
+import (
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/xo/dburl/passfile"
+	"github.com/xo/usql/text"
+	"gopkg.in/yaml.v2"
+)
+
+// State is the session state of a database alias, made of the \pset and \set
+// variables and the \timing toggle set in its interactive sessions, restored
+// when opening the alias again.
+type State struct {
+	Pset   map[string]string `yaml:"pset,omitempty"`
+	Set    map[string]string `yaml:"set,omitempty"`
+	Timing *bool             `yaml:"timing,omitempty"`
+}
+
+// StateFile returns the path to the state file of the database alias.
+//
+// Defaults to ~/.<command name>/state/<alias>.yaml, the directory overridden
+// by environment variable <COMMAND NAME>_STATE_DIR (ie, ~/.usql/state and
+// USQL_STATE_DIR).
+func StateFile(u *user.User, alias string) string {
+	n := text.CommandUpper() + "_STATE_DIR"
+	dir := "~/." + text.CommandLower() + "/state"
+	if s, ok := Getenv(n); ok {
+		dir = s
+	}
+	name := strings.NewReplacer("/", "_", `\`, "_").Replace(alias) + ".yaml"
+	return filepath.Join(passfile.Expand(u.HomeDir, dir), name)
+}
+
+// SuggestFile returns the path to the file of the history of the statements
+// executed on the database alias, suggesting the completions of statements.
+//
+// Defaults to ~/.<command name>/history/<alias>.jsonl, the directory
+// overridden by environment variable <COMMAND NAME>_SUGGEST_DIR (ie,
+// ~/.usql/history and USQL_SUGGEST_DIR).
+func SuggestFile(u *user.User, alias string) string {
+	n := text.CommandUpper() + "_SUGGEST_DIR"
+	dir := "~/." + text.CommandLower() + "/history"
+	if s, ok := Getenv(n); ok {
+		dir = s
+	}
+	name := strings.NewReplacer("/", "_", `\`, "_").Replace(alias) + ".jsonl"
+	return filepath.Join(passfile.Expand(u.HomeDir, dir), name)
+}
+
+// LoadState loads the state file at path, returning an empty state when it
+// does not exist.
+func LoadState(path string) (*State, error) {
+	s := new(State)
+	buf, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, err
+	}
+	if err := yaml.Unmarshal(buf, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Save saves the state to the state file at path, creating its directory.
+func (s *State) Save(path string) error {
+	buf, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf, 0o600)
+}
+
+// Apply applies the state, setting its \pset and \set variables, in the
+// order of their names, and calling timing with its \timing toggle when set.
+// The variables are all set, the first error being returned.
+func (s *State) Apply(timing func(bool)) error {
+	var res error
+	for _, name := range sortedKeys(s.Pset) {
+		if _, err := Pset(name, s.Pset[name]); err != nil && res == nil {
+			res = err
+		}
+	}
+	for _, name := range sortedKeys(s.Set) {
+		if err := Set(name, s.Set[name]); err != nil && res == nil {
+			res = err
+		}
+	}
+	if s.Timing != nil {
+		timing(*s.Timing)
+	}
+	return res
+}
+
+// sortedKeys returns the sorted keys of m.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// SetPset sets the \pset variable of the state.
+func (s *State) SetPset(name, value string) {
+	if s.Pset == nil {
+		s.Pset = make(map[string]string)
+	}
+	s.Pset[name] = value
+}
+
+// SetVar sets the \set variable of the state.
+func (s *State) SetVar(name, value string) {
+	if s.Set == nil {
+		s.Set = make(map[string]string)
+	}
+	s.Set[name] = value
+}
+
+// UnsetVar unsets the \set variable of the state.
+func (s *State) UnsetVar(name string) {
+	delete(s.Set, name)
+}
+
+// SetTiming sets the \timing toggle of the state.
+func (s *State) SetTiming(timing bool) {
+	s.Timing = &timing
+}
@@ -3,6 +3,7 @@ package env
 import (
 	"fmt"
 	"io"
+	"os"
 	"os/exec"
 	"regexp"
 	"sort"
@@ -11,8 +12,10 @@ import (
 	"time"
 	"unicode"
 
+	"github.com/alecthomas/chroma/v2/styles"
 	syslocale "github.com/jeandeaual/go-locale"
 	"github.com/xo/terminfo"
+	"github.com/xo/usql/rline"
 	"github.com/xo/usql/text"
 )
 
@@ -26,10 +29,34 @@ func (v varName) String() string {
 }
 
 var varNames = []varName{
+	{
+		"EDITING_MODE",
+		"the editing mode of the interactive input [emacs, vi]",
+	},
 	{
 		"ECHO_HIDDEN",
 		"if set, display internal queries executed by backslash commands; if set to \"noexec\", just show them without execution",
 	},
+	{
+		"FETCH_COUNT",
+		"the number of rows fetched to compute the column widths of tables, streamed in batches of that many rows (0 = all rows)",
+	},
+	{
+		"CURSOR_THRESHOLD",
+		"the estimated rows of the queries written to files from which they are read with a server-side cursor, in batches of FETCH_COUNT rows (0 = never)",
+	},
+	{
+		"FORMAT_KEYWORD_CASE",
+		"the case of the keywords of the queries formatted with \\format [upper, lower, preserve]",
+	},
+	{
+		"FORMAT_INDENT",
+		"the spaces of an indentation level of the queries formatted with \\format (0 = tab)",
+	},
+	{
+		"KEY_BINDINGS",
+		"comma separated KEY=ACTION bindings of the interactive input (ie, C-o=accept-line,M-b=none)",
+	},
 	{
 		"ON_ERROR_STOP",
 		"stop batch execution after error",
@@ -42,13 +69,37 @@ var varNames = []varName{
 		"QUIET",
 		"run quietly (same as -q option)",
 	},
+	{
+		"RESULT_META",
+		"print the metadata of the results after each statement: rows, warnings, notices and server version [on, off]",
+	},
 	{
 		"ROW_COUNT",
 		"number of rows returned or affected by last query, or 0",
 	},
+	{
+		"SHELL_ERROR",
+		"true if the last shell command (\\!, backticks, or \\g |command) failed, false if it succeeded",
+	},
+	{
+		"SHELL_EXIT_CODE",
+		"exit status of the last shell command",
+	},
+	{
+		"SUGGEST",
+		"suggest the completions of statements from the history of the database alias, accepted with the right arrow [true, false]",
+	},
 }
 
 var pvarNames = []varName{
+	{
+		"binary",
+		"display of binary (bytea, BLOB) column values [raw, summary, files]",
+	},
+	{
+		"binary_dir",
+		"directory the binary column values are written to, when binary is files",
+	},
 	{
 		"border",
 		"border style (number)",
@@ -81,10 +132,22 @@ var pvarNames = []varName{
 		"format",
 		"set output format [unaligned, aligned, wrapped, vertical, html, asciidoc, csv, json, ...]",
 	},
+	{
+		"insert_batch",
+		"rows per INSERT statement of the inserts format (default 100)",
+	},
+	{
+		"insert_table",
+		"table of the INSERT statements of the inserts format (default the table of the query)",
+	},
 	{
 		"linestyle",
 		"set the border line drawing style [ascii, old-ascii, unicode]",
 	},
+	{
+		"max_col_width",
+		"maximum width of the values of the aligned and vertical formats, truncated with a marker (0 for no limit)",
+	},
 	{
 		"null",
 		"set the string to be printed in place of a null value",
@@ -215,7 +278,7 @@ func init() {
 	// get color level
 	colorLevel, _ := terminfo.ColorLevelFromEnv()
 	enableSyntaxHL := "true"
-	if colorLevel < terminfo.ColorLevelBasic {
+	if colorLevel < terminfo.ColorLevelBasic || os.Getenv("NO_COLOR") != "" {
 		enableSyntaxHL = "false"
 	}
 	// pager
@@ -240,8 +303,16 @@ func init() {
 		"PAGER":                 pagerCmd,
 		"EDITOR":                editorCmd,
 		"ON_ERROR_STOP":         "off",
+		"FETCH_COUNT":           "1000",
+		"CURSOR_THRESHOLD":      "100000",
+		"FORMAT_KEYWORD_CASE":   "upper",
+		"FORMAT_INDENT":         "2",
+		"EDITING_MODE":          "emacs",
+		"KEY_BINDINGS":          "",
+		"SUGGEST":               "true",
+		"RESULT_META":           "off",
 		// prompts
-		"PROMPT1": "%S%N%m%/%R%# ",
+		"PROMPT1": "%S%N%m%/%r%R%x%# ",
 		// syntax highlighting variables
 		"SYNTAX_HL":             enableSyntaxHL,
 		"SYNTAX_HL_FORMAT":      colorLevel.ChromaFormatterName(),
@@ -254,6 +325,8 @@ func init() {
 		locale = s
 	}
 	pvars = Vars{
+		"binary":                   "raw",
+		"binary_dir":               "",
 		"border":                   "1",
 		"columns":                  "0",
 		"csv_fieldsep":             ",",
@@ -262,8 +335,11 @@ func init() {
 		"fieldsep_zero":            "off",
 		"footer":                   "on",
 		"format":                   "aligned",
+		"insert_batch":             "100",
+		"insert_table":             "",
 		"linestyle":                "ascii",
 		"locale":                   locale,
+		"max_col_width":            "0",
 		"null":                     "",
 		"numericlocale":            "off",
 		"pager_min_lines":          "0",
@@ -300,7 +376,7 @@ func Set(name, value string) error {
 	if err := ValidIdentifier(name); err != nil {
 		return err
 	}
-	if name == "ON_ERROR_STOP" || name == "QUIET" {
+	if name == "ON_ERROR_STOP" || name == "QUIET" || name == "RESULT_META" {
 		if value == "" {
 			value = "on"
 		} else {
@@ -310,6 +386,31 @@ func Set(name, value string) error {
 			}
 		}
 	}
+	if name == "FORMAT_KEYWORD_CASE" {
+		if value = strings.ToLower(value); value != "upper" && value != "lower" && value != "preserve" {
+			return fmt.Errorf(text.FormatFieldInvalid, value, name)
+		}
+	}
+	if name == "EDITING_MODE" {
+		if value = strings.ToLower(value); value != "emacs" && value != "vi" {
+			return fmt.Errorf(text.FormatFieldInvalid, value, name)
+		}
+	}
+	if name == "KEY_BINDINGS" {
+		if _, err := rline.ParseBindings(value); err != nil {
+			return err
+		}
+	}
+	if name == "SYNTAX_HL_STYLE" {
+		if _, ok := styles.Registry[strings.ToLower(value)]; !ok {
+			return fmt.Errorf(text.FormatFieldInvalidValue, value, name, "chroma style name")
+		}
+	}
+	if name == "FETCH_COUNT" || name == "CURSOR_THRESHOLD" || name == "FORMAT_INDENT" {
+		if n, err := strconv.Atoi(value); err != nil || n < 0 {
+			return fmt.Errorf(text.FormatFieldInvalidValue, value, name, "non-negative integer")
+		}
+	}
 	vars.Set(name, value)
 	return nil
 }
@@ -355,7 +456,7 @@ func Pwrite(w io.Writer) error {
 		switch k {
 		case "csv_fieldsep", "fieldsep", "recordsep", "null":
 			val = strconv.QuoteToASCII(val)
-		case "tableattr", "title":
+		case "tableattr", "title", "binary_dir", "insert_table":
 			if val != "" {
 				val = strconv.QuoteToASCII(val)
 			}
@@ -366,9 +467,10 @@ func Pwrite(w io.Writer) error {
 }
 
 var (
-	formatRE    = regexp.MustCompile(`^(unaligned|aligned|wrapped|html|asciidoc|latex|latex-longtable|troff-ms|csv|json|vertical)$`)
+	formatRE    = regexp.MustCompile(`^(unaligned|aligned|wrapped|html|asciidoc|latex|latex-longtable|troff-ms|csv|json|vertical|parquet|xlsx|inserts)$`)
 	linestlyeRE = regexp.MustCompile(`^(ascii|old-ascii|unicode)$`)
 	borderRE    = regexp.MustCompile(`^(single|double)$`)
+	binaryRE    = regexp.MustCompile(`^(raw|summary|files)$`)
 )
 
 func ParseBool(value, name string) (string, error) {
@@ -416,7 +518,7 @@ func Ptoggle(name, extra string) (string, error) {
 		return "", fmt.Errorf(text.UnknownFormatFieldName, name)
 	}
 	switch name {
-	case "border", "columns", "pager_min_lines":
+	case "border", "columns", "pager_min_lines", "insert_batch", "max_col_width":
 	case "pager":
 		switch pvars[name] {
 		case "on", "always":
@@ -454,8 +556,15 @@ func Ptoggle(name, extra string) (string, error) {
 			pvars[name] = "aligned"
 		}
 	case "linestyle":
-	case "csv_fieldsep", "fieldsep", "null", "recordsep", "time", "locale":
-	case "tableattr", "title":
+	case "binary":
+		switch pvars[name] {
+		case "summary", "files":
+			pvars[name] = "raw"
+		default:
+			pvars[name] = "summary"
+		}
+	case "csv_fieldsep", "fieldsep", "null", "recordsep", "time", "locale", "binary_dir":
+	case "tableattr", "title", "insert_table":
 		pvars[name] = ""
 	case "unicode_border_linestyle", "unicode_column_linestyle", "unicode_header_linestyle":
 	default:
@@ -471,9 +580,15 @@ func Pset(name, value string) (string, error) {
 		return "", fmt.Errorf(text.UnknownFormatFieldName, name)
 	}
 	switch name {
-	case "border", "columns", "pager_min_lines":
+	case "border", "columns", "pager_min_lines", "max_col_width":
 		i, _ := strconv.Atoi(value)
 		pvars[name] = fmt.Sprintf("%d", i)
+	case "insert_batch":
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 1 {
+			return "", text.ErrInvalidFormatInsertBatch
+		}
+		pvars[name] = fmt.Sprintf("%d", i)
 	case "pager":
 		s, err := ParseKeywordBool(value, name, "always")
 		if err != nil {
@@ -502,7 +617,12 @@ func Pset(name, value string) (string, error) {
 			return "", text.ErrInvalidFormatLineStyle
 		}
 		pvars[name] = value
-	case "csv_fieldsep", "fieldsep", "null", "recordsep", "tableattr", "time", "title", "locale":
+	case "binary":
+		if !binaryRE.MatchString(value) {
+			return "", text.ErrInvalidFormatBinary
+		}
+		pvars[name] = value
+	case "csv_fieldsep", "fieldsep", "null", "recordsep", "tableattr", "time", "title", "locale", "binary_dir", "insert_table":
 		pvars[name] = value
 	case "unicode_border_linestyle", "unicode_column_linestyle", "unicode_header_linestyle":
 		if !borderRE.MatchString(value) {
@@ -0,0 +1,248 @@
+// Package erd writes entity-relationship diagrams of the tables of a
+// database, as Mermaid or PlantUML definitions.
+package erd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/dump"
+)
+
+// Diagram formats.
+const (
+	// FormatMermaid is the erDiagram of Mermaid.
+	FormatMermaid = "mermaid"
+	// FormatPlantUML is the information engineering diagram of PlantUML.
+	FormatPlantUML = "plantuml"
+)
+
+// Formats are the diagram formats.
+var Formats = []string{FormatMermaid, FormatPlantUML}
+
+// Write writes the entity-relationship diagram of the tables in the format,
+// with an entity per table, and a relationship per foreign key between the
+// tables. Foreign keys referencing other tables are left out.
+func Write(w io.Writer, format string, tables []*dump.Table) error {
+	d := newDiagram(tables)
+	var sb strings.Builder
+	switch format {
+	case FormatMermaid:
+		d.mermaid(&sb)
+	case FormatPlantUML:
+		d.plantuml(&sb)
+	default:
+		return fmt.Errorf("invalid diagram format %q", format)
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
+
+// diagram is an entity-relationship diagram.
+type diagram struct {
+	tables []*dump.Table
+	// names are the entity names of the tables, by lower cased schema
+	// qualified table name.
+	names map[string]string
+	// qualify is set when the tables are in several schemas.
+	qualify bool
+}
+
+// newDiagram creates the diagram of the tables.
+func newDiagram(tables []*dump.Table) *diagram {
+	d := &diagram{tables: tables, names: make(map[string]string, len(tables))}
+	for _, t := range tables {
+		d.qualify = d.qualify || t.Schema != tables[0].Schema
+	}
+	for _, t := range tables {
+		name := t.Name
+		if d.qualify && t.Schema != "" {
+			name = t.Schema + "_" + t.Name
+		}
+		d.names[key(t.Schema, t.Name)] = ident(name)
+	}
+	return d
+}
+
+// relationship is a foreign key between two tables of the diagram.
+type relationship struct {
+	parent, child string
+	// optional is set when the foreign key columns are nullable.
+	optional bool
+	label    string
+}
+
+// relationships returns the relationships of the tables.
+func (d *diagram) relationships() []relationship {
+	var rels []relationship
+	for _, t := range d.tables {
+		for _, c := range t.Constraints {
+			if c.Type != "FOREIGN KEY" {
+				continue
+			}
+			schema := c.ForeignSchema
+			if schema == "" {
+				schema = t.Schema
+			}
+			parent, ok := d.names[key(schema, c.ForeignTable)]
+			if !ok {
+				continue
+			}
+			label := c.Name
+			if label == "" {
+				label = strings.Join(c.Columns, ", ")
+			}
+			rels = append(rels, relationship{
+				parent:   parent,
+				child:    d.names[key(t.Schema, t.Name)],
+				optional: nullable(t, c.Columns),
+				label:    label,
+			})
+		}
+	}
+	return rels
+}
+
+// mermaid writes the Mermaid erDiagram of the tables.
+func (d *diagram) mermaid(sb *strings.Builder) {
+	sb.WriteString("erDiagram\n")
+	for _, t := range d.tables {
+		fmt.Fprintf(sb, "    %s {\n", d.names[key(t.Schema, t.Name)])
+		for _, c := range t.Columns {
+			fmt.Fprintf(sb, "        %s %s", typ(c.DataType), ident(c.Name))
+			if keys := columnKeys(t, c.Name); len(keys) != 0 {
+				sb.WriteString(" " + strings.Join(keys, ", "))
+			}
+			sb.WriteString("\n")
+		}
+		sb.WriteString("    }\n")
+	}
+	for _, r := range d.relationships() {
+		card := "||"
+		if r.optional {
+			card = "|o"
+		}
+		fmt.Fprintf(sb, "    %s %s--o{ %s : %q\n", r.parent, card, r.child, r.label)
+	}
+}
+
+// plantuml writes the PlantUML information engineering diagram of the
+// tables, with the mandatory columns marked with a *.
+func (d *diagram) plantuml(sb *strings.Builder) {
+	sb.WriteString("@startuml\nhide circle\nskinparam linetype ortho\n")
+	for _, t := range d.tables {
+		name := t.Name
+		if d.qualify && t.Schema != "" {
+			name = t.Schema + "." + t.Name
+		}
+		fmt.Fprintf(sb, "\nentity %q as %s {\n", name, d.names[key(t.Schema, t.Name)])
+		// primary key columns come first, separated from the other columns
+		var pk, other []string
+		for _, c := range t.Columns {
+			line := "  "
+			if c.IsNullable == metadata.NO {
+				line = "  * "
+			}
+			line += c.Name + " : " + c.DataType
+			keys := columnKeys(t, c.Name)
+			for _, k := range keys {
+				line += " <<" + k + ">>"
+			}
+			if len(keys) != 0 && keys[0] == "PK" {
+				pk = append(pk, line)
+			} else {
+				other = append(other, line)
+			}
+		}
+		for _, line := range pk {
+			sb.WriteString(line + "\n")
+		}
+		if len(pk) != 0 {
+			sb.WriteString("  --\n")
+		}
+		for _, line := range other {
+			sb.WriteString(line + "\n")
+		}
+		sb.WriteString("}\n")
+	}
+	if rels := d.relationships(); len(rels) != 0 {
+		sb.WriteString("\n")
+		for _, r := range rels {
+			card := "||"
+			if r.optional {
+				card = "|o"
+			}
+			fmt.Fprintf(sb, "%s %s..o{ %s : %s\n", r.parent, card, r.child, r.label)
+		}
+	}
+	sb.WriteString("@enduml\n")
+}
+
+// columnKeys returns the keys of the column of the table: PK when in its
+// primary key, and FK when in a foreign key.
+func columnKeys(t *dump.Table, name string) []string {
+	var keys []string
+	if contains(t.PrimaryKey, name) {
+		keys = append(keys, "PK")
+	}
+	for _, c := range t.Constraints {
+		if c.Type == "FOREIGN KEY" && contains(c.Columns, name) {
+			return append(keys, "FK")
+		}
+	}
+	return keys
+}
+
+// nullable returns true when any of the columns of the table is nullable.
+func nullable(t *dump.Table, names []string) bool {
+	for _, c := range t.Columns {
+		if contains(names, c.Name) && c.IsNullable != metadata.NO {
+			return true
+		}
+	}
+	return false
+}
+
+// key returns the key of a table in the entity names.
+func key(schema, name string) string {
+	return strings.ToLower(schema + "." + name)
+}
+
+// ident returns s with the characters other than letters, digits, _ and -
+// replaced by _, as the names of Mermaid entities and attributes.
+func ident(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case 'a' <= r && r <= 'z', 'A' <= r && r <= 'Z', '0' <= r && r <= '9', r == '_', r == '-':
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// typ returns the data type as a Mermaid attribute type, which can't contain
+// spaces or commas.
+func typ(s string) string {
+	if s == "" {
+		return "unknown"
+	}
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '(', ')', '[', ']':
+			return r
+		}
+		return []rune(ident(string(r)))[0]
+	}, s)
+}
+
+// contains returns true when strs contains s.
+func contains(strs []string, s string) bool {
+	for _, v := range strs {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
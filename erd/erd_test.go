@@ -0,0 +1,96 @@
+package erd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/dump"
+)
+
+func testTables() []*dump.Table {
+	return []*dump.Table{
+		{
+			Schema: "public",
+			Name:   "customers",
+			Columns: []metadata.Column{
+				{Name: "id", DataType: "integer", IsNullable: metadata.NO},
+				{Name: "name", DataType: "character varying(100)", IsNullable: metadata.YES},
+			},
+			PrimaryKey: []string{"id"},
+		},
+		{
+			Schema: "public",
+			Name:   "orders",
+			Columns: []metadata.Column{
+				{Name: "id", DataType: "integer", IsNullable: metadata.NO},
+				{Name: "customer_id", DataType: "integer", IsNullable: metadata.NO},
+				{Name: "total", DataType: "numeric(10,2)", IsNullable: metadata.YES},
+			},
+			PrimaryKey: []string{"id"},
+			Constraints: []dump.Constraint{
+				{Name: "orders_customer_fk", Type: "FOREIGN KEY", Columns: []string{"customer_id"}, ForeignTable: "customers", ForeignColumns: []string{"id"}},
+				{Type: "FOREIGN KEY", Columns: []string{"id"}, ForeignTable: "invoices"},
+			},
+		},
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	var sb strings.Builder
+	if err := Write(&sb, FormatMermaid, testTables()); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := `erDiagram
+    customers {
+        integer id PK
+        character_varying(100) name
+    }
+    orders {
+        integer id PK, FK
+        integer customer_id FK
+        numeric(10_2) total
+    }
+    customers ||--o{ orders : "orders_customer_fk"
+`
+	if s := sb.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+}
+
+func TestPlantUML(t *testing.T) {
+	tables := testTables()
+	tables[1].Schema = "sales"
+	tables[1].Constraints[0].ForeignSchema = "public"
+	tables[1].Columns[1].IsNullable = metadata.YES
+	var sb strings.Builder
+	if err := Write(&sb, FormatPlantUML, tables); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := `@startuml
+hide circle
+skinparam linetype ortho
+
+entity "public.customers" as public_customers {
+  * id : integer <<PK>>
+  --
+  name : character varying(100)
+}
+
+entity "sales.orders" as sales_orders {
+  * id : integer <<PK>> <<FK>>
+  --
+  customer_id : integer <<FK>>
+  total : numeric(10,2)
+}
+
+public_customers |o..o{ sales_orders : orders_customer_fk
+@enduml
+`
+	if s := sb.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+	if err := Write(&sb, "dot", tables); err == nil {
+		t.Errorf("expected error for invalid format, got nil")
+	}
+}
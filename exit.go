@@ -0,0 +1,51 @@
+package main
+
+import (
+	"github.com/xo/usql/jsonout"
+)
+
+// Exit codes, distinct by type of failure so that scripts can branch on them.
+const (
+	// exitError is the exit code of the errors without a more specific code.
+	exitError = 1
+	// exitPanic is the exit code of panics.
+	exitPanic = 2
+	// exitConfig is the exit code of the errors of the config file.
+	exitConfig = 3
+	// exitConnection is the exit code of the errors connecting to a database.
+	exitConnection = 4
+	// exitSQL is the exit code of the errors executing statements.
+	exitSQL = 5
+	// exitPartial is the exit code of the operations on several databases,
+	// files or tables of which some failed.
+	exitPartial = 6
+	// exitAlert is the exit code of the watched queries whose value crossed
+	// the threshold of an exit alert.
+	exitAlert = 7
+	// exitOffline is the exit code of the statements refused in offline mode.
+	exitOffline = 8
+	// exitDifferent is the exit code of the comparisons of databases finding
+	// differences.
+	exitDifferent = 9
+)
+
+// exitCode returns the exit code of an error, by its code (see jsonout.Code).
+func exitCode(err error) int {
+	switch jsonout.Code(err) {
+	case jsonout.CodeConfig:
+		return exitConfig
+	case jsonout.CodeConnection, jsonout.CodeDriverNotAvailable, jsonout.CodeNotConnected:
+		return exitConnection
+	case jsonout.CodeDatabase, jsonout.CodePolicyViolation:
+		return exitSQL
+	case jsonout.CodePartialFailure:
+		return exitPartial
+	case jsonout.CodeAlert:
+		return exitAlert
+	case jsonout.CodeOffline:
+		return exitOffline
+	case jsonout.CodeDifferent:
+		return exitDifferent
+	}
+	return exitError
+}
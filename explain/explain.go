@@ -0,0 +1,230 @@
+// Package explain runs and renders the query plans of queries.
+package explain
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/xo/usql/text"
+)
+
+// MisestimateFactor is the factor by which the actual rows of an operation
+// must differ from the estimated rows for the estimate to be highlighted.
+const MisestimateFactor = 10
+
+// Plan is a query plan.
+type Plan struct {
+	// Nodes are the top level operations of the plan.
+	Nodes []*Node
+	// Properties are the plan's summary properties (ie, Execution Time).
+	Properties []string
+}
+
+// Node is an operation of a query plan.
+type Node struct {
+	// Op is the operation (ie, Seq Scan on users).
+	Op string
+	// Details are the operation's conditions and other properties.
+	Details []string
+	// Estimated is set when the operation has a cost estimate.
+	Estimated bool
+	// StartupCost is negative when only the total cost is estimated.
+	StartupCost, TotalCost float64
+	Rows                   float64
+	// Actual is set when the operation was executed (ie, EXPLAIN ANALYZE).
+	Actual bool
+	// StartupTime and TotalTime are in milliseconds.
+	StartupTime, TotalTime float64
+	// ActualRows are the rows per loop.
+	ActualRows float64
+	Loops      float64
+	// SeqScan is set when the operation is a full table scan.
+	SeqScan  bool
+	Children []*Node
+}
+
+// Misestimate returns the factor by which the actual rows of the operation
+// differ from the estimated rows, or 0 when the estimate is within
+// MisestimateFactor.
+func (n *Node) Misestimate() float64 {
+	if !n.Estimated || !n.Actual || n.Loops == 0 {
+		return 0
+	}
+	est, act := n.Rows, n.ActualRows
+	if est < 1 {
+		est = 1
+	}
+	if act < 1 {
+		act = 1
+	}
+	f := act / est
+	if f < 1 {
+		f = 1 / f
+	}
+	if f < MisestimateFactor {
+		return 0
+	}
+	return f
+}
+
+// Statement returns the statement explaining sqlstr for the SQL dialect. The
+// query is executed when analyze is true.
+func Statement(dialect, sqlstr string, analyze bool) (string, error) {
+	switch dialect {
+	case "postgres":
+		if analyze {
+			return "EXPLAIN (ANALYZE, FORMAT JSON) " + sqlstr, nil
+		}
+		return "EXPLAIN (FORMAT JSON) " + sqlstr, nil
+	case "mysql":
+		if analyze {
+			return "EXPLAIN ANALYZE " + sqlstr, nil
+		}
+		return "EXPLAIN FORMAT=TREE " + sqlstr, nil
+	case "sqlite3":
+		if analyze {
+			return "", fmt.Errorf(text.NotSupportedByDriver, "explain analyze", dialect)
+		}
+		return "EXPLAIN QUERY PLAN " + sqlstr, nil
+	}
+	return "", fmt.Errorf(text.NotSupportedByDriver, "explain", dialect)
+}
+
+// Read reads the plan from the result of the statement of the SQL dialect.
+func Read(dialect string, rows *sql.Rows) (*Plan, error) {
+	switch dialect {
+	case "postgres":
+		var buf []byte
+		if err := scanOne(rows, &buf); err != nil {
+			return nil, err
+		}
+		return parsePostgres(buf)
+	case "mysql":
+		var s string
+		if err := scanOne(rows, &s); err != nil {
+			return nil, err
+		}
+		return parseTree(s), nil
+	}
+	var steps []sqliteStep
+	for rows.Next() {
+		var step sqliteStep
+		var notused int64
+		if err := rows.Scan(&step.id, &step.parent, &notused, &step.detail); err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return buildSQLite(steps), nil
+}
+
+// scanOne scans the single value of the result.
+func scanOne(rows *sql.Rows, v interface{}) error {
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		return sql.ErrNoRows
+	}
+	if err := rows.Scan(v); err != nil {
+		return err
+	}
+	return rows.Err()
+}
+
+// Colors of the highlighted operations.
+const (
+	seqScanColor     = "\x1b[33m"
+	misestimateColor = "\x1b[31m"
+	resetColor       = "\x1b[0m"
+)
+
+// Write writes the plan to w as an indented tree, highlighting sequential
+// scans and misestimated row counts using terminal colors when color is
+// true.
+func (p *Plan) Write(w io.Writer, color bool) error {
+	for _, n := range p.Nodes {
+		if err := writeNode(w, n, "", "", color); err != nil {
+			return err
+		}
+	}
+	for _, s := range p.Properties {
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeNode writes the node and its children, with head prefixing the node's
+// line and indent prefixing the following lines.
+func writeNode(w io.Writer, n *Node, head, indent string, color bool) error {
+	line, c := n.Op, ""
+	if s := annotation(n); s != "" {
+		line += "  " + s
+	}
+	if n.SeqScan {
+		line, c = line+"  [seq scan]", seqScanColor
+	}
+	if f := n.Misestimate(); f != 0 {
+		line, c = line+fmt.Sprintf("  [rows misestimated %sx]", formatFloat(f, 0)), misestimateColor
+	}
+	if color && c != "" {
+		line = c + line + resetColor
+	}
+	if _, err := fmt.Fprintln(w, head+line); err != nil {
+		return err
+	}
+	details := indent + "   "
+	if len(n.Children) != 0 {
+		details = indent + "│  "
+	}
+	for _, s := range n.Details {
+		if _, err := fmt.Fprintln(w, details+s); err != nil {
+			return err
+		}
+	}
+	for i, child := range n.Children {
+		h, ind := indent+"├─ ", indent+"│  "
+		if i == len(n.Children)-1 {
+			h, ind = indent+"└─ ", indent+"   "
+		}
+		if err := writeNode(w, child, h, ind, color); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// annotation returns the cost, row and time annotation of the node.
+func annotation(n *Node) string {
+	var s []string
+	if n.Estimated {
+		cost := formatFloat(n.TotalCost, 2)
+		if n.StartupCost >= 0 {
+			cost = formatFloat(n.StartupCost, 2) + ".." + cost
+		}
+		s = append(s, "(cost="+cost+" rows="+formatFloat(n.Rows, 0)+")")
+	}
+	if n.Actual {
+		if n.Loops == 0 {
+			s = append(s, "(never executed)")
+		} else {
+			s = append(s, fmt.Sprintf("(actual time=%s..%s rows=%s loops=%s)",
+				formatFloat(n.StartupTime, 3), formatFloat(n.TotalTime, 3),
+				formatFloat(n.ActualRows, 0), formatFloat(n.Loops, 0)))
+		}
+	}
+	return strings.Join(s, " ")
+}
+
+// formatFloat formats f with prec decimals.
+func formatFloat(f float64, prec int) string {
+	return strconv.FormatFloat(f, 'f', prec, 64)
+}
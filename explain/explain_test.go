@@ -0,0 +1,103 @@
+package explain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParsePostgres(t *testing.T) {
+	buf := []byte(`[{
+  "Plan": {
+    "Node Type": "Hash Join", "Join Type": "Left",
+    "Startup Cost": 1.09, "Total Cost": 2.23, "Plan Rows": 5,
+    "Actual Startup Time": 0.05, "Actual Total Time": 0.081, "Actual Rows": 500, "Actual Loops": 1,
+    "Hash Cond": "(o.customer_id = c.id)",
+    "Plans": [
+      {"Node Type": "Seq Scan", "Relation Name": "orders", "Alias": "o",
+       "Startup Cost": 0, "Total Cost": 1.05, "Plan Rows": 500,
+       "Actual Startup Time": 0.01, "Actual Total Time": 0.02, "Actual Rows": 500, "Actual Loops": 1,
+       "Filter": "(total > 10)", "Rows Removed by Filter": 2},
+      {"Node Type": "Hash",
+       "Startup Cost": 1.04, "Total Cost": 1.04, "Plan Rows": 4,
+       "Actual Startup Time": 0.02, "Actual Total Time": 0.02, "Actual Rows": 4, "Actual Loops": 1,
+       "Plans": [
+        {"Node Type": "Index Scan", "Index Name": "customers_pkey", "Relation Name": "customers", "Alias": "customers",
+         "Startup Cost": 0.15, "Total Cost": 1.04, "Plan Rows": 4,
+         "Actual Loops": 0}
+       ]}
+    ]
+  },
+  "Planning Time": 0.1,
+  "Execution Time": 0.25
+}]`)
+	p, err := parsePostgres(buf)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var sb strings.Builder
+	if err := p.Write(&sb, false); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := `Hash Left Join  (cost=1.09..2.23 rows=5) (actual time=0.050..0.081 rows=500 loops=1)  [rows misestimated 100x]
+│  Hash Cond: (o.customer_id = c.id)
+├─ Seq Scan on orders o  (cost=0.00..1.05 rows=500) (actual time=0.010..0.020 rows=500 loops=1)  [seq scan]
+│     Filter: (total > 10)
+│     Rows Removed by Filter: 2
+└─ Hash  (cost=1.04..1.04 rows=4) (actual time=0.020..0.020 rows=4 loops=1)
+   └─ Index Scan using customers_pkey on customers  (cost=0.15..1.04 rows=4) (never executed)
+Planning Time: 0.100 ms
+Execution Time: 0.250 ms
+`
+	if s := sb.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+}
+
+func TestParseTree(t *testing.T) {
+	s := `-> Nested loop inner join  (cost=0.70 rows=1) (actual time=0.050..0.060 rows=30 loops=1)
+    -> Table scan on t  (cost=0.35 rows=1) (actual time=0.020..0.030 rows=3 loops=1)
+    -> Single-row index lookup on u using PRIMARY (id=t.uid)  (cost=0.35 rows=1) (actual time=0.010..0.010 rows=1 loops=3)
+-> Select #2 (subquery in condition; run only once)
+`
+	p := parseTree(s)
+	if len(p.Nodes) != 2 {
+		t.Fatalf("expected 2 nodes, got: %d", len(p.Nodes))
+	}
+	n := p.Nodes[0]
+	switch {
+	case n.Op != "Nested loop inner join":
+		t.Errorf("expected op %q, got: %q", "Nested loop inner join", n.Op)
+	case n.StartupCost != -1 || n.TotalCost != 0.7 || n.Rows != 1:
+		t.Errorf("expected cost 0.7 and 1 row, got: %v %v %v", n.StartupCost, n.TotalCost, n.Rows)
+	case n.Misestimate() != 30:
+		t.Errorf("expected misestimate 30, got: %v", n.Misestimate())
+	case len(n.Children) != 2:
+		t.Fatalf("expected 2 children, got: %d", len(n.Children))
+	case !n.Children[0].SeqScan || n.Children[1].SeqScan:
+		t.Errorf("expected only the table scan to be a seq scan")
+	case n.Children[1].Op != "Single-row index lookup on u using PRIMARY (id=t.uid)":
+		t.Errorf("expected index lookup, got: %q", n.Children[1].Op)
+	case n.Children[1].Loops != 3:
+		t.Errorf("expected 3 loops, got: %v", n.Children[1].Loops)
+	}
+}
+
+func TestBuildSQLite(t *testing.T) {
+	p := buildSQLite([]sqliteStep{
+		{2, 0, "SEARCH a USING INTEGER PRIMARY KEY (rowid=?)"},
+		{5, 0, "LIST SUBQUERY 1"},
+		{7, 5, "SCAN b"},
+		{9, 5, "SCAN c USING COVERING INDEX c_idx"},
+	})
+	var sb strings.Builder
+	if err := p.Write(&sb, true); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := "SEARCH a USING INTEGER PRIMARY KEY (rowid=?)\n" +
+		"LIST SUBQUERY 1\n" +
+		"├─ \x1b[33mSCAN b  [seq scan]\x1b[0m\n" +
+		"└─ SCAN c USING COVERING INDEX c_idx\n"
+	if s := sb.String(); s != exp {
+		t.Errorf("expected:\n%q\ngot:\n%q", exp, s)
+	}
+}
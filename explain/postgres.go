@@ -0,0 +1,150 @@
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// pgDetails are the postgres plan properties displayed as details, in order.
+var pgDetails = []string{
+	"Hash Cond",
+	"Merge Cond",
+	"Join Filter",
+	"Index Cond",
+	"Recheck Cond",
+	"Filter",
+	"Rows Removed by Join Filter",
+	"Rows Removed by Filter",
+	"Rows Removed by Index Recheck",
+	"Sort Key",
+	"Group Key",
+	"Sort Method",
+	"Heap Fetches",
+	"Workers Planned",
+	"Workers Launched",
+}
+
+// parsePostgres parses a postgres JSON format plan.
+func parsePostgres(buf []byte) (*Plan, error) {
+	var res []map[string]interface{}
+	if err := json.Unmarshal(buf, &res); err != nil {
+		return nil, fmt.Errorf("invalid plan: %w", err)
+	}
+	p := new(Plan)
+	for _, m := range res {
+		if plan, ok := m["Plan"].(map[string]interface{}); ok {
+			p.Nodes = append(p.Nodes, pgNode(plan))
+		}
+		for _, key := range []string{"Planning Time", "Execution Time"} {
+			if f, ok := m[key].(float64); ok {
+				p.Properties = append(p.Properties, fmt.Sprintf("%s: %s ms", key, formatFloat(f, 3)))
+			}
+		}
+	}
+	return p, nil
+}
+
+// pgNode converts a postgres plan node.
+func pgNode(m map[string]interface{}) *Node {
+	str := func(key string) string {
+		s, _ := m[key].(string)
+		return s
+	}
+	typ := str("Node Type")
+	n := &Node{
+		Op:      pgOp(m),
+		SeqScan: typ == "Seq Scan",
+	}
+	if cost, ok := m["Total Cost"].(float64); ok {
+		n.Estimated, n.TotalCost = true, cost
+		n.StartupCost, _ = m["Startup Cost"].(float64)
+		n.Rows, _ = m["Plan Rows"].(float64)
+	}
+	if loops, ok := m["Actual Loops"].(float64); ok {
+		n.Actual, n.Loops = true, loops
+		n.StartupTime, _ = m["Actual Startup Time"].(float64)
+		n.TotalTime, _ = m["Actual Total Time"].(float64)
+		n.ActualRows, _ = m["Actual Rows"].(float64)
+	}
+	for _, key := range pgDetails {
+		switch v := m[key].(type) {
+		case nil:
+		case []interface{}:
+			strs := make([]string, len(v))
+			for i, x := range v {
+				strs[i] = fmt.Sprint(x)
+			}
+			n.Details = append(n.Details, key+": "+strings.Join(strs, ", "))
+		case float64:
+			if v != 0 {
+				n.Details = append(n.Details, key+": "+formatFloat(v, 0))
+			}
+		default:
+			n.Details = append(n.Details, fmt.Sprintf("%s: %v", key, v))
+		}
+	}
+	plans, _ := m["Plans"].([]interface{})
+	for _, v := range plans {
+		if child, ok := v.(map[string]interface{}); ok {
+			n.Children = append(n.Children, pgNode(child))
+		}
+	}
+	return n
+}
+
+// pgOp returns the operation of a postgres plan node, as displayed by the
+// text format.
+func pgOp(m map[string]interface{}) string {
+	str := func(key string) string {
+		s, _ := m[key].(string)
+		return s
+	}
+	op := str("Node Type")
+	switch str("Strategy") {
+	case "Hashed":
+		op = "Hash" + op
+	case "Sorted":
+		op = "Group" + op
+	case "Mixed":
+		op = "Mixed" + op
+	}
+	if jt := str("Join Type"); jt != "" && jt != "Inner" {
+		if strings.HasSuffix(op, " Join") {
+			op = strings.TrimSuffix(op, " Join") + " " + jt + " Join"
+		} else {
+			op += " " + jt + " Join"
+		}
+	}
+	if parallel, _ := m["Parallel Aware"].(bool); parallel {
+		op = "Parallel " + op
+	}
+	if str("Scan Direction") == "Backward" {
+		op += " Backward"
+	}
+	if s := str("Index Name"); s != "" {
+		op += " using " + s
+	}
+	var rel string
+	switch {
+	case str("Relation Name") != "":
+		rel = str("Relation Name")
+		if s := str("Schema"); s != "" {
+			rel = s + "." + rel
+		}
+	case str("CTE Name") != "":
+		rel = str("CTE Name")
+	case str("Function Name") != "":
+		rel = str("Function Name")
+	}
+	if rel != "" {
+		op += " on " + rel
+		if alias := str("Alias"); alias != "" && alias != str("Relation Name") && alias != rel {
+			op += " " + alias
+		}
+	}
+	if s := str("Subplan Name"); s != "" {
+		op = s + ": " + op
+	}
+	return op
+}
@@ -0,0 +1,110 @@
+package explain
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Annotations of the mysql tree format plans.
+var (
+	costRE   = regexp.MustCompile(`\s*\(cost=([0-9.e+]+)(?:\.\.([0-9.e+]+))? rows=([0-9.e+]+)\)`)
+	actualRE = regexp.MustCompile(`\s*\(actual time=([0-9.e+]+)\.\.([0-9.e+]+) rows=([0-9.e+]+) loops=([0-9]+)\)`)
+	neverRE  = regexp.MustCompile(`\s*\(never executed\)`)
+)
+
+// parseTree parses a mysql tree format plan, where each operation is on a
+// line starting with "->", indented below its parent operation.
+func parseTree(s string) *Plan {
+	type level struct {
+		indent int
+		n      *Node
+	}
+	p := new(Plan)
+	var stack []level
+	for _, line := range strings.Split(s, "\n") {
+		trimmed := strings.TrimLeft(line, " ")
+		if strings.TrimSpace(trimmed) == "" {
+			continue
+		}
+		indent := len(line) - len(trimmed)
+		if !strings.HasPrefix(trimmed, "->") {
+			// continuation of the previous operation
+			if len(stack) != 0 {
+				n := stack[len(stack)-1].n
+				n.Details = append(n.Details, strings.TrimSpace(trimmed))
+			}
+			continue
+		}
+		n := treeNode(strings.TrimSpace(strings.TrimPrefix(trimmed, "->")))
+		for len(stack) != 0 && stack[len(stack)-1].indent >= indent {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			p.Nodes = append(p.Nodes, n)
+		} else {
+			parent := stack[len(stack)-1].n
+			parent.Children = append(parent.Children, n)
+		}
+		stack = append(stack, level{indent, n})
+	}
+	return p
+}
+
+// treeNode parses the line of a mysql tree format operation.
+func treeNode(s string) *Node {
+	n := &Node{StartupCost: -1}
+	if m := costRE.FindStringSubmatch(s); m != nil {
+		n.Estimated = true
+		if m[2] != "" {
+			n.StartupCost, n.TotalCost = parseFloat(m[1]), parseFloat(m[2])
+		} else {
+			n.TotalCost = parseFloat(m[1])
+		}
+		n.Rows = parseFloat(m[3])
+		s = strings.Replace(s, m[0], "", 1)
+	}
+	if m := actualRE.FindStringSubmatch(s); m != nil {
+		n.Actual = true
+		n.StartupTime, n.TotalTime = parseFloat(m[1]), parseFloat(m[2])
+		n.ActualRows, n.Loops = parseFloat(m[3]), parseFloat(m[4])
+		s = strings.Replace(s, m[0], "", 1)
+	} else if m := neverRE.FindString(s); m != "" {
+		n.Actual = true
+		s = strings.Replace(s, m, "", 1)
+	}
+	n.Op = strings.TrimSpace(s)
+	n.SeqScan = strings.HasPrefix(n.Op, "Table scan on ")
+	return n
+}
+
+// parseFloat parses a float, returning 0 when invalid.
+func parseFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+// sqliteStep is a step of a sqlite query plan.
+type sqliteStep struct {
+	id, parent int64
+	detail     string
+}
+
+// buildSQLite builds the plan of the steps of a sqlite query plan, where each
+// step references its parent step.
+func buildSQLite(steps []sqliteStep) *Plan {
+	p := new(Plan)
+	nodes := make(map[int64]*Node, len(steps))
+	for _, step := range steps {
+		n := &Node{Op: step.detail}
+		// SCAN t USING [COVERING] INDEX i reads the index only
+		n.SeqScan = strings.HasPrefix(step.detail, "SCAN ") && !strings.Contains(step.detail, " INDEX ")
+		nodes[step.id] = n
+		if parent, ok := nodes[step.parent]; ok {
+			parent.Children = append(parent.Children, n)
+		} else {
+			p.Nodes = append(p.Nodes, n)
+		}
+	}
+	return p
+}
@@ -0,0 +1,83 @@
+package export
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSV writes rows to w as a CSV file, with a header of the column names.
+// Returns the number of written rows.
+func CSV(w io.Writer, rows Rows) (int64, error) {
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	kinds := columnKinds(rows, cts)
+	bw := bufio.NewWriter(w)
+	for i, ct := range cts {
+		if i != 0 {
+			bw.WriteByte(',')
+		}
+		bw.WriteString(CSVField(KindString, ct.Name()))
+	}
+	bw.WriteByte('\n')
+	values := make([]interface{}, len(cts))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return n, err
+		}
+		for i, v := range values {
+			if i != 0 {
+				bw.WriteByte(',')
+			}
+			bw.WriteString(CSVField(kinds[i], *(v.(*interface{}))))
+		}
+		bw.WriteByte('\n')
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	return n, bw.Flush()
+}
+
+// CSVField formats v, a value of a column of the kind, as a CSV field. NULL
+// values are written as empty unquoted fields, and strings are quoted, so
+// that they are told apart when imported. Binary values are written as hex,
+// prefixed with \x.
+func CSVField(kind ColumnKind, v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case bool:
+		return strconv.FormatBool(x)
+	case int64:
+		return strconv.FormatInt(x, 10)
+	case float64:
+		return strconv.FormatFloat(x, 'g', -1, 64)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	case []byte:
+		if kind == KindBinary {
+			return `\x` + hex.EncodeToString(x)
+		}
+		return csvQuote(string(x))
+	case string:
+		return csvQuote(x)
+	}
+	return csvQuote(fmt.Sprint(v))
+}
+
+// csvQuote quotes a CSV field.
+func csvQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
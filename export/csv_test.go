@@ -0,0 +1,28 @@
+package export
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCSVField(t *testing.T) {
+	tests := []struct {
+		kind ColumnKind
+		v    interface{}
+		exp  string
+	}{
+		{KindString, nil, ``},
+		{KindString, "", `""`},
+		{KindString, []byte(`a"b`), `"a""b"`},
+		{KindBinary, []byte{0, 0xff}, `\x00ff`},
+		{KindInt, int64(-3), `-3`},
+		{KindFloat, 1.5, `1.5`},
+		{KindBool, true, `true`},
+		{KindTime, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), `2024-01-02T03:04:05Z`},
+	}
+	for i, test := range tests {
+		if s := CSVField(test.kind, test.v); s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+	}
+}
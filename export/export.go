@@ -0,0 +1,83 @@
+// Package export writes query results to binary file formats.
+package export
+
+import (
+	"database/sql"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Rows are the rows written to a file, such as *sql.Rows.
+type Rows interface {
+	ColumnTypes() ([]*sql.ColumnType, error)
+	Next() bool
+	Scan(...interface{}) error
+	Err() error
+}
+
+// masker is the interface of rows whose values of some columns are masked.
+// Masked columns are written as strings.
+type masker interface {
+	Masked(int) bool
+}
+
+// columnKinds returns the kinds of values of the columns of rows.
+func columnKinds(rows Rows, cts []*sql.ColumnType) []ColumnKind {
+	m, _ := rows.(masker)
+	kinds := make([]ColumnKind, len(cts))
+	for i, ct := range cts {
+		if kinds[i] = Kind(ct); m != nil && m.Masked(i) {
+			kinds[i] = KindString
+		}
+	}
+	return kinds
+}
+
+// ColumnKind is the kind of values of a result column.
+type ColumnKind int
+
+// Column kinds.
+const (
+	KindString ColumnKind = iota
+	KindInt
+	KindFloat
+	KindBool
+	KindTime
+	KindBinary
+)
+
+// Kind returns the kind of values of a result column, based on its database
+// type name, or its scan type when the type name is not known.
+func Kind(ct *sql.ColumnType) ColumnKind {
+	typ := strings.ToUpper(ct.DatabaseTypeName())
+	switch {
+	case typ == "":
+	case strings.Contains(typ, "INTERVAL"), strings.Contains(typ, "POINT"):
+		return KindString
+	case strings.Contains(typ, "INT"):
+		return KindInt
+	case strings.Contains(typ, "FLOAT"), strings.Contains(typ, "DOUBLE"), typ == "REAL":
+		return KindFloat
+	case strings.HasPrefix(typ, "BOOL"), typ == "BIT":
+		return KindBool
+	case typ == "DATE", strings.HasPrefix(typ, "TIMESTAMP"), strings.HasPrefix(typ, "DATETIME"):
+		return KindTime
+	case typ == "BYTEA", strings.Contains(typ, "BLOB"), strings.Contains(typ, "BINARY"):
+		return KindBinary
+	default:
+		return KindString
+	}
+	switch t := ct.ScanType(); {
+	case t == nil:
+	case t == reflect.TypeOf(time.Time{}):
+		return KindTime
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return KindInt
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return KindFloat
+	case t.Kind() == reflect.Bool:
+		return KindBool
+	}
+	return KindString
+}
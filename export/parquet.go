@@ -0,0 +1,471 @@
+package export
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+)
+
+// parquetRowGroupSize is the number of rows per Parquet row group.
+const parquetRowGroupSize = 64 * 1024
+
+// Parquet physical types, converted types, and other enum values from the
+// Parquet format specification.
+const (
+	parquetBoolean   = 0
+	parquetInt64     = 2
+	parquetDouble    = 5
+	parquetByteArray = 6
+
+	parquetConvertedUTF8            = 0
+	parquetConvertedTimestampMicros = 10
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetEncodingPlain = 0
+	parquetEncodingRLE   = 3
+
+	parquetCodecSnappy = 1
+
+	parquetPageData = 0
+)
+
+// Parquet writes rows to w as a Parquet file, with a schema inferred from
+// the column types of rows. Returns the number of written rows.
+func Parquet(w io.Writer, rows Rows) (int64, error) {
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	pw := &parquetWriter{w: w}
+	for i, kind := range columnKinds(rows, cts) {
+		pw.cols = append(pw.cols, &parquetColumn{name: cts[i].Name(), kind: kind})
+	}
+	if err := pw.write([]byte("PAR1")); err != nil {
+		return 0, err
+	}
+	values := make([]interface{}, len(cts))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return pw.rows, err
+		}
+		for i, v := range values {
+			if err := pw.cols[i].append(*(v.(*interface{}))); err != nil {
+				return pw.rows, fmt.Errorf("column %s: %w", pw.cols[i].name, err)
+			}
+		}
+		if pw.rows++; pw.rows%parquetRowGroupSize == 0 {
+			if err := pw.flush(); err != nil {
+				return pw.rows, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return pw.rows, err
+	}
+	return pw.rows, pw.close()
+}
+
+// parquetWriter writes a Parquet file.
+type parquetWriter struct {
+	w         io.Writer
+	off       int64
+	rows      int64
+	cols      []*parquetColumn
+	rowGroups []parquetRowGroup
+}
+
+// parquetRowGroup is the metadata of a written row group.
+type parquetRowGroup struct {
+	chunks []parquetChunk
+	size   int64
+	rows   int64
+}
+
+// parquetChunk is the metadata of a written column chunk.
+type parquetChunk struct {
+	offset           int64
+	values           int64
+	uncompressedSize int64
+	compressedSize   int64
+}
+
+// write writes buf to the underlying writer.
+func (pw *parquetWriter) write(buf []byte) error {
+	n, err := pw.w.Write(buf)
+	pw.off += int64(n)
+	return err
+}
+
+// flush writes the buffered column values as a row group.
+func (pw *parquetWriter) flush() error {
+	var rg parquetRowGroup
+	for _, c := range pw.cols {
+		rg.rows = int64(c.count)
+		page := c.page()
+		compressed := snappy.Encode(nil, page)
+		var hdr thriftWriter
+		hdr.i32(1, parquetPageData)
+		hdr.i32(2, int32(len(page)))
+		hdr.i32(3, int32(len(compressed)))
+		hdr.structBegin(5)
+		hdr.i32(1, int32(c.count))
+		hdr.i32(2, parquetEncodingPlain)
+		hdr.i32(3, parquetEncodingRLE)
+		hdr.i32(4, parquetEncodingRLE)
+		hdr.structEnd()
+		hdr.stop()
+		chunk := parquetChunk{
+			offset:           pw.off,
+			values:           int64(c.count),
+			uncompressedSize: int64(hdr.buf.Len() + len(page)),
+			compressedSize:   int64(hdr.buf.Len() + len(compressed)),
+		}
+		if err := pw.write(hdr.buf.Bytes()); err != nil {
+			return err
+		}
+		if err := pw.write(compressed); err != nil {
+			return err
+		}
+		rg.chunks, rg.size = append(rg.chunks, chunk), rg.size+chunk.uncompressedSize
+		c.reset()
+	}
+	if rg.rows != 0 {
+		pw.rowGroups = append(pw.rowGroups, rg)
+	}
+	return nil
+}
+
+// close flushes the remaining rows and writes the file footer.
+func (pw *parquetWriter) close() error {
+	if len(pw.cols) != 0 && pw.cols[0].count != 0 {
+		if err := pw.flush(); err != nil {
+			return err
+		}
+	}
+	var meta thriftWriter
+	meta.i32(1, 1)
+	// schema
+	meta.listBegin(2, thriftStruct, len(pw.cols)+1)
+	meta.i32(3, parquetRequired)
+	meta.binary(4, []byte("schema"))
+	meta.i32(5, int32(len(pw.cols)))
+	meta.stop()
+	for _, c := range pw.cols {
+		typ, converted := c.types()
+		meta.i32(1, typ)
+		meta.i32(3, parquetOptional)
+		meta.binary(4, []byte(c.name))
+		if converted != -1 {
+			meta.i32(6, converted)
+		}
+		meta.stop()
+	}
+	meta.listEnd()
+	meta.i64(3, pw.rows)
+	// row groups
+	meta.listBegin(4, thriftStruct, len(pw.rowGroups))
+	for _, rg := range pw.rowGroups {
+		meta.listBegin(1, thriftStruct, len(rg.chunks))
+		for i, chunk := range rg.chunks {
+			typ, _ := pw.cols[i].types()
+			meta.i64(2, chunk.offset)
+			meta.structBegin(3)
+			meta.i32(1, typ)
+			meta.listBegin(2, thriftI32, 2)
+			meta.listI32(parquetEncodingPlain)
+			meta.listI32(parquetEncodingRLE)
+			meta.listEnd()
+			meta.listBegin(3, thriftBinary, 1)
+			meta.listBinary([]byte(pw.cols[i].name))
+			meta.listEnd()
+			meta.i32(4, parquetCodecSnappy)
+			meta.i64(5, chunk.values)
+			meta.i64(6, chunk.uncompressedSize)
+			meta.i64(7, chunk.compressedSize)
+			meta.i64(9, chunk.offset)
+			meta.structEnd()
+			meta.stop()
+		}
+		meta.listEnd()
+		meta.i64(2, rg.size)
+		meta.i64(3, rg.rows)
+		meta.stop()
+	}
+	meta.listEnd()
+	meta.binary(6, []byte("usql"))
+	meta.stop()
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(meta.buf.Len()))
+	for _, buf := range [][]byte{meta.buf.Bytes(), size[:], []byte("PAR1")} {
+		if err := pw.write(buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parquetColumn buffers the values of a column for the current row group.
+type parquetColumn struct {
+	name    string
+	kind    ColumnKind
+	count   int
+	defined []bool
+	bools   []bool
+	buf     bytes.Buffer
+}
+
+// types returns the physical and converted (or -1) types of the column.
+func (c *parquetColumn) types() (int32, int32) {
+	switch c.kind {
+	case KindInt:
+		return parquetInt64, -1
+	case KindFloat:
+		return parquetDouble, -1
+	case KindBool:
+		return parquetBoolean, -1
+	case KindTime:
+		return parquetInt64, parquetConvertedTimestampMicros
+	case KindBinary:
+		return parquetByteArray, -1
+	}
+	return parquetByteArray, parquetConvertedUTF8
+}
+
+// append appends a scanned value to the column.
+func (c *parquetColumn) append(v interface{}) error {
+	c.count++
+	c.defined = append(c.defined, v != nil)
+	if v == nil {
+		return nil
+	}
+	var b [8]byte
+	switch c.kind {
+	case KindInt:
+		i, err := toInt(v)
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(i))
+		c.buf.Write(b[:])
+	case KindFloat:
+		f, err := toFloat(v)
+		if err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(f))
+		c.buf.Write(b[:])
+	case KindBool:
+		x, err := toBool(v)
+		if err != nil {
+			return err
+		}
+		c.bools = append(c.bools, x)
+	case KindTime:
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("cannot convert %T to timestamp", v)
+		}
+		binary.LittleEndian.PutUint64(b[:], uint64(t.UnixMicro()))
+		c.buf.Write(b[:])
+	default:
+		s := toBytes(v)
+		binary.LittleEndian.PutUint32(b[:4], uint32(len(s)))
+		c.buf.Write(b[:4])
+		c.buf.Write(s)
+	}
+	return nil
+}
+
+// page returns the column's data page: the definition levels followed by the
+// plain encoded values.
+func (c *parquetColumn) page() []byte {
+	levels := bitPack(c.defined)
+	var page bytes.Buffer
+	var b [4]byte
+	// rle/bit-packed hybrid, as a single bit-packed run
+	hdr := binary.AppendUvarint(nil, uint64(len(levels))<<1|1)
+	binary.LittleEndian.PutUint32(b[:], uint32(len(hdr)+len(levels)))
+	page.Write(b[:])
+	page.Write(hdr)
+	page.Write(levels)
+	if c.kind == KindBool {
+		page.Write(bitPack(c.bools))
+	} else {
+		page.Write(c.buf.Bytes())
+	}
+	return page.Bytes()
+}
+
+// reset clears the buffered values.
+func (c *parquetColumn) reset() {
+	c.count, c.defined, c.bools = 0, c.defined[:0], c.bools[:0]
+	c.buf.Reset()
+}
+
+// bitPack packs v, least significant bit first, padded to a multiple of 8
+// values.
+func bitPack(v []bool) []byte {
+	buf := make([]byte, (len(v)+7)/8)
+	for i, b := range v {
+		if b {
+			buf[i/8] |= 1 << (i % 8)
+		}
+	}
+	return buf
+}
+
+// Thrift compact protocol types.
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes Thrift structs using the compact protocol.
+type thriftWriter struct {
+	buf  bytes.Buffer
+	last []int16
+	id   int16
+}
+
+func (w *thriftWriter) field(id int16, typ byte) {
+	if d := id - w.id; d > 0 && d <= 15 {
+		w.buf.WriteByte(byte(d)<<4 | typ)
+	} else {
+		w.buf.WriteByte(typ)
+		w.varint(int64(id))
+	}
+	w.id = id
+}
+
+func (w *thriftWriter) varint(i int64) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(i<<1^i>>63)))
+}
+
+func (w *thriftWriter) i32(id int16, i int32) {
+	w.field(id, thriftI32)
+	w.varint(int64(i))
+}
+
+func (w *thriftWriter) i64(id int16, i int64) {
+	w.field(id, thriftI64)
+	w.varint(i)
+}
+
+func (w *thriftWriter) binary(id int16, b []byte) {
+	w.field(id, thriftBinary)
+	w.listBinary(b)
+}
+
+func (w *thriftWriter) structBegin(id int16) {
+	w.field(id, thriftStruct)
+	w.last, w.id = append(w.last, w.id), 0
+}
+
+func (w *thriftWriter) structEnd() {
+	w.buf.WriteByte(0)
+	w.id, w.last = w.last[len(w.last)-1], w.last[:len(w.last)-1]
+}
+
+// stop ends a struct that is a list element, or the top level struct.
+func (w *thriftWriter) stop() {
+	w.buf.WriteByte(0)
+	w.id = 0
+}
+
+func (w *thriftWriter) listBegin(id int16, typ byte, n int) {
+	w.field(id, thriftList)
+	if n < 15 {
+		w.buf.WriteByte(byte(n)<<4 | typ)
+	} else {
+		w.buf.WriteByte(0xf0 | typ)
+		w.buf.Write(binary.AppendUvarint(nil, uint64(n)))
+	}
+	w.last, w.id = append(w.last, w.id), 0
+}
+
+func (w *thriftWriter) listEnd() {
+	w.id, w.last = w.last[len(w.last)-1], w.last[:len(w.last)-1]
+}
+
+func (w *thriftWriter) listI32(i int32) {
+	w.varint(int64(i))
+}
+
+func (w *thriftWriter) listBinary(b []byte) {
+	w.buf.Write(binary.AppendUvarint(nil, uint64(len(b))))
+	w.buf.Write(b)
+}
+
+// toInt converts a scanned value to an int64.
+func toInt(v interface{}) (int64, error) {
+	switch x := v.(type) {
+	case int64:
+		return x, nil
+	case int32:
+		return int64(x), nil
+	case int:
+		return int64(x), nil
+	case uint64:
+		return int64(x), nil
+	case float64:
+		return int64(x), nil
+	case bool:
+		if x {
+			return 1, nil
+		}
+		return 0, nil
+	}
+	return strconv.ParseInt(strings.TrimSpace(string(toBytes(v))), 10, 64)
+}
+
+// toFloat converts a scanned value to a float64.
+func toFloat(v interface{}) (float64, error) {
+	switch x := v.(type) {
+	case float64:
+		return x, nil
+	case float32:
+		return float64(x), nil
+	case int64:
+		return float64(x), nil
+	}
+	return strconv.ParseFloat(strings.TrimSpace(string(toBytes(v))), 64)
+}
+
+// toBool converts a scanned value to a bool.
+func toBool(v interface{}) (bool, error) {
+	switch x := v.(type) {
+	case bool:
+		return x, nil
+	case int64:
+		return x != 0, nil
+	}
+	return strconv.ParseBool(strings.TrimSpace(string(toBytes(v))))
+}
+
+// toBytes converts a scanned value to its textual representation.
+func toBytes(v interface{}) []byte {
+	switch x := v.(type) {
+	case string:
+		return []byte(x)
+	case []byte:
+		return x
+	case time.Time:
+		return []byte(x.Format(time.RFC3339Nano))
+	}
+	return []byte(fmt.Sprint(v))
+}
@@ -0,0 +1,136 @@
+package export
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/apache/arrow/go/v10/arrow/memory"
+	"github.com/apache/arrow/go/v10/parquet"
+	"github.com/apache/arrow/go/v10/parquet/pqarrow"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestParquet(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	for _, s := range []string{
+		`CREATE TABLE t (i integer, f real, b boolean, s text, d timestamp, x blob)`,
+		`INSERT INTO t VALUES
+			(1, 1.5, true, 'a', '2024-01-02 03:04:05', x'00ff'),
+			(NULL, NULL, NULL, NULL, NULL, NULL),
+			(-3, -2.25, false, 'héllo', '2024-01-02 03:04:05.123456', x'')`,
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	tests := []struct {
+		sqlstr string
+		rows   int64
+		exp    []string
+	}{
+		{`SELECT * FROM t`, 3, []string{
+			`i: type=int64, nullable [1 (null) -3]`,
+			`f: type=float64, nullable [1.5 (null) -2.25]`,
+			`b: type=bool, nullable [true (null) false]`,
+			`s: type=utf8, nullable ["a" (null) "héllo"]`,
+			`d: type=timestamp[us], nullable [1704164645000000 (null) 1704164645123456]`,
+			`x: type=binary, nullable ["\x00\xff" (null) ""]`,
+		}},
+		{`SELECT * FROM t WHERE i > 1`, 0, []string{
+			`i: type=int64, nullable []`,
+			`f: type=float64, nullable []`,
+			`b: type=bool, nullable []`,
+			`s: type=utf8, nullable []`,
+			`d: type=timestamp[us], nullable []`,
+			`x: type=binary, nullable []`,
+		}},
+		// more than a row group
+		{`WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n WHERE i < 70000) SELECT CAST(i AS integer) AS i FROM n`, 70000, nil},
+	}
+	for i, test := range tests {
+		rows, err := db.Query(test.sqlstr)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		var buf bytes.Buffer
+		n, err := Parquet(&buf, rows)
+		rows.Close()
+		switch {
+		case err != nil:
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		case n != test.rows:
+			t.Errorf("test %d expected %d rows written, got: %d", i, test.rows, n)
+		}
+		// read back with an independent implementation
+		tbl, err := pqarrow.ReadTable(context.Background(), bytes.NewReader(buf.Bytes()), parquet.NewReaderProperties(nil), pqarrow.ArrowReadProperties{}, memory.DefaultAllocator)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if tbl.NumRows() != test.rows {
+			t.Errorf("test %d expected %d rows read, got: %d", i, test.rows, tbl.NumRows())
+		}
+		for j, exp := range test.exp {
+			col := tbl.Column(j)
+			var values []string
+			for _, chunk := range col.Data().Chunks() {
+				values = append(values, chunk.String())
+			}
+			if s := fmt.Sprintf("%s: type=%s, nullable %s", col.Name(), col.DataType(), strings.Join(values, " ")); s != exp {
+				t.Errorf("test %d expected column %d %s, got: %s", i, j, exp, s)
+			}
+		}
+		tbl.Release()
+	}
+}
+
+func TestBitPack(t *testing.T) {
+	tests := []struct {
+		v   []bool
+		exp []byte
+	}{
+		{nil, []byte{}},
+		{[]bool{true}, []byte{0x01}},
+		{[]bool{true, false, true}, []byte{0x05}},
+		{[]bool{false, false, false, false, false, false, false, false, true}, []byte{0x00, 0x01}},
+	}
+	for i, test := range tests {
+		if b := bitPack(test.v); !bytes.Equal(b, test.exp) {
+			t.Errorf("test %d expected %x, got: %x", i, test.exp, b)
+		}
+	}
+}
+
+func TestThriftWriter(t *testing.T) {
+	var w thriftWriter
+	w.i32(1, 1)
+	w.i64(3, -2)
+	w.structBegin(20)
+	w.binary(1, []byte("ab"))
+	w.structEnd()
+	w.listBegin(21, thriftI32, 2)
+	w.listI32(0)
+	w.listI32(3)
+	w.listEnd()
+	w.stop()
+	exp := []byte{
+		0x15, 0x02, // field 1 i32 1
+		0x26, 0x03, // field 3 i64 -2
+		0x0c, 0x28, // field 20 struct (long form)
+		0x18, 0x02, 'a', 'b', // field 1 binary "ab"
+		0x00,       // struct end
+		0x19, 0x25, // field 21 list<i32> (delta 1), 2 elements
+		0x00, 0x06,
+		0x00, // stop
+	}
+	if b := w.buf.Bytes(); !bytes.Equal(b, exp) {
+		t.Errorf("expected %x, got: %x", exp, b)
+	}
+}
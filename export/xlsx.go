@@ -0,0 +1,285 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// xlsxMaxRows is the maximum number of rows of a sheet.
+const xlsxMaxRows = 1048576
+
+// Cell styles defined in xlsxStyles.
+const (
+	xlsxStyleDefault  = 0
+	xlsxStyleDateTime = 1
+	xlsxStyleDate     = 2
+	xlsxStyleHeader   = 3
+)
+
+// xlsxEpoch is the epoch of Excel's date serial numbers.
+var xlsxEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// Workbook writes result sets to an Excel (xlsx) workbook, one sheet per
+// result set. Close must be called to finish writing the workbook.
+type Workbook struct {
+	z      *zip.Writer
+	sheets []string
+}
+
+// NewWorkbook creates a new workbook writing to w.
+func NewWorkbook(w io.Writer) *Workbook {
+	return &Workbook{z: zip.NewWriter(w)}
+}
+
+// AddSheet writes rows as a new sheet, with the column names as the first
+// row. The sheet is named name, or "SheetN" when name is empty. Returns the
+// number of written rows.
+func (wb *Workbook) AddSheet(name string, rows Rows) (int64, error) {
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	wb.sheets = append(wb.sheets, wb.sheetName(name))
+	f, err := wb.create(fmt.Sprintf("xl/worksheets/sheet%d.xml", len(wb.sheets)))
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	w.WriteString(xml.Header)
+	w.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	w.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+	w.WriteString(`<sheetData>`)
+	kinds := columnKinds(rows, cts)
+	w.WriteString(`<row r="1">`)
+	for i, ct := range cts {
+		writeInlineStr(w, cellRef(i, 1), ct.Name(), xlsxStyleHeader)
+	}
+	w.WriteString(`</row>`)
+	values := make([]interface{}, len(cts))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	var n int64
+	for rows.Next() {
+		if n++; n >= xlsxMaxRows {
+			return n, fmt.Errorf("sheet %s exceeds %d rows", wb.sheets[len(wb.sheets)-1], xlsxMaxRows)
+		}
+		if err := rows.Scan(values...); err != nil {
+			return n, err
+		}
+		r := int(n) + 1
+		fmt.Fprintf(w, `<row r="%d">`, r)
+		for i, v := range values {
+			writeCell(w, cellRef(i, r), kinds[i], *(v.(*interface{})))
+		}
+		w.WriteString(`</row>`)
+	}
+	if err := rows.Err(); err != nil {
+		return n, err
+	}
+	w.WriteString(`</sheetData></worksheet>`)
+	return n, w.Flush()
+}
+
+// AddSheets writes each result set of rows, such as the results of a stored
+// procedure, as a new sheet named name, name (2), etc, or SheetN when name is
+// empty. Result sets
+// without columns, such as the status of procedures, are skipped. Returns the
+// number of written rows.
+func (wb *Workbook) AddSheets(name string, rows Rows) (int64, error) {
+	n, err := wb.AddSheet(name, rows)
+	if err != nil {
+		return n, err
+	}
+	rs, ok := rows.(interface{ NextResultSet() bool })
+	for ok && rs.NextResultSet() {
+		cts, err := rows.ColumnTypes()
+		switch {
+		case err != nil:
+			return n, err
+		case len(cts) == 0:
+			continue
+		}
+		i, err := wb.AddSheet(name, rows)
+		if n += i; err != nil {
+			return n, err
+		}
+	}
+	return n, nil
+}
+
+// create creates a compressed file in the workbook.
+func (wb *Workbook) create(name string) (io.Writer, error) {
+	return wb.z.CreateHeader(&zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: time.Now(),
+	})
+}
+
+// sheetName returns a unique, valid sheet name.
+func (wb *Workbook) sheetName(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if strings.ContainsRune(`[]:*?/\`, r) {
+			return '_'
+		}
+		return r
+	}, strings.TrimSpace(name))
+	if r := []rune(name); len(r) > 31 {
+		name = string(r[:31])
+	}
+	if name == "" {
+		name = fmt.Sprintf("Sheet%d", len(wb.sheets)+1)
+	}
+	unique := name
+	for i := 2; ; i++ {
+		var exists bool
+		for _, s := range wb.sheets {
+			exists = exists || strings.EqualFold(s, unique)
+		}
+		if !exists {
+			return unique
+		}
+		suffix := fmt.Sprintf(" (%d)", i)
+		if r := []rune(name); len(r)+len(suffix) > 31 {
+			name = string(r[:31-len(suffix)])
+		}
+		unique = name + suffix
+	}
+}
+
+// Close writes the workbook parts and closes the workbook. It does not close
+// the underlying writer.
+func (wb *Workbook) Close() error {
+	if len(wb.sheets) == 0 {
+		// excel refuses workbooks without sheets
+		f, err := wb.create("xl/worksheets/sheet1.xml")
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData/></worksheet>`); err != nil {
+			return err
+		}
+		wb.sheets = append(wb.sheets, "Sheet1")
+	}
+	var types, sheets, rels strings.Builder
+	for i, name := range wb.sheets {
+		fmt.Fprintf(&types, `<Override PartName="/xl/worksheets/sheet%d.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`, i+1)
+		fmt.Fprintf(&sheets, `<sheet name="%s" sheetId="%d" r:id="rId%d"/>`, escape(name), i+1, i+1)
+		fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet%d.xml"/>`, i+1, i+1)
+	}
+	n := len(wb.sheets)
+	fmt.Fprintf(&rels, `<Relationship Id="rId%d" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>`, n+1)
+	for _, part := range []struct {
+		name, content string
+	}{
+		{"[Content_Types].xml", `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+			`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+			`<Default Extension="xml" ContentType="application/xml"/>` +
+			`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+			`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+			types.String() + `</Types>`},
+		{"_rels/.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+			`</Relationships>`},
+		{"xl/workbook.xml", `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets>` + sheets.String() + `</sheets></workbook>`},
+		{"xl/_rels/workbook.xml.rels", `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+			rels.String() + `</Relationships>`},
+		{"xl/styles.xml", xlsxStyles},
+	} {
+		f, err := wb.create(part.name)
+		if err != nil {
+			return err
+		}
+		if _, err := io.WriteString(f, xml.Header+part.content); err != nil {
+			return err
+		}
+	}
+	return wb.z.Close()
+}
+
+// xlsxStyles are the workbook styles, with the cell formats referenced by the
+// xlsxStyle constants.
+const xlsxStyles = `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="22" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="14" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
+
+// writeCell writes a cell for a scanned value.
+func writeCell(w *bufio.Writer, ref string, kind ColumnKind, v interface{}) {
+	if v == nil {
+		return
+	}
+	switch kind {
+	case KindInt, KindFloat:
+		if f, err := toFloat(v); err == nil && !math.IsNaN(f) && !math.IsInf(f, 0) {
+			fmt.Fprintf(w, `<c r="%s"><v>%s</v></c>`, ref, strconv.FormatFloat(f, 'g', -1, 64))
+			return
+		}
+	case KindBool:
+		if b, err := toBool(v); err == nil {
+			x := 0
+			if b {
+				x = 1
+			}
+			fmt.Fprintf(w, `<c r="%s" t="b"><v>%d</v></c>`, ref, x)
+			return
+		}
+	case KindTime:
+		if t, ok := v.(time.Time); ok {
+			// excel has no time zones, so use the wall clock time
+			wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+			style := xlsxStyleDateTime
+			if wall.Equal(wall.Truncate(24 * time.Hour)) {
+				style = xlsxStyleDate
+			}
+			serial := float64(wall.Sub(xlsxEpoch)) / float64(24*time.Hour)
+			fmt.Fprintf(w, `<c r="%s" s="%d"><v>%s</v></c>`, ref, style, strconv.FormatFloat(serial, 'f', -1, 64))
+			return
+		}
+	}
+	writeInlineStr(w, ref, string(toBytes(v)), xlsxStyleDefault)
+}
+
+// writeInlineStr writes an inline string cell.
+func writeInlineStr(w *bufio.Writer, ref, s string, style int) {
+	fmt.Fprintf(w, `<c r="%s" t="inlineStr"`, ref)
+	if style != xlsxStyleDefault {
+		fmt.Fprintf(w, ` s="%d"`, style)
+	}
+	fmt.Fprintf(w, `><is><t xml:space="preserve">%s</t></is></c>`, escape(s))
+}
+
+// escape escapes s for XML.
+func escape(s string) string {
+	var sb strings.Builder
+	_ = xml.EscapeText(&sb, []byte(s))
+	return sb.String()
+}
+
+// cellRef returns the reference of the cell in the 0-based column i and row
+// r, ie, A1.
+func cellRef(i, r int) string {
+	var col []byte
+	for i++; i > 0; i = (i - 1) / 26 {
+		col = append([]byte{byte('A' + (i-1)%26)}, col...)
+	}
+	return string(col) + strconv.Itoa(r)
+}
@@ -0,0 +1,43 @@
+package export
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCellRef(t *testing.T) {
+	tests := []struct {
+		i, r int
+		exp  string
+	}{
+		{0, 1, "A1"},
+		{25, 2, "Z2"},
+		{26, 3, "AA3"},
+		{701, 4, "ZZ4"},
+		{702, 5, "AAA5"},
+	}
+	for _, test := range tests {
+		if s := cellRef(test.i, test.r); s != test.exp {
+			t.Errorf("cellRef(%d, %d) expected %q, got: %q", test.i, test.r, test.exp, s)
+		}
+	}
+}
+
+func TestSheetName(t *testing.T) {
+	wb := &Workbook{}
+	for i, test := range []struct {
+		name, exp string
+	}{
+		{"", "Sheet1"},
+		{"a/b:c", "a_b_c"},
+		{"A_B_C", "A_B_C (2)"},
+		{strings.Repeat("x", 40), strings.Repeat("x", 31)},
+		{strings.Repeat("x", 40), strings.Repeat("x", 27) + " (2)"},
+	} {
+		s := wb.sheetName(test.name)
+		if s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+		wb.sheets = append(wb.sheets, s)
+	}
+}
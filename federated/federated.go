@@ -0,0 +1,139 @@
+// Package federated registers query results from multiple databases as tables
+// of an embedded SQLite database, so that they can be joined with plain SQL.
+package federated
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/export"
+)
+
+// ErrNoEngine is the no embedded engine error.
+var ErrNoEngine = errors.New("federated mode requires the sqlite3 or moderncsqlite driver")
+
+// Create creates the embedded database in a new temporary directory,
+// returning the directory and the URL of the database. The directory should be
+// removed when the database is closed.
+func Create() (string, string, error) {
+	if !drivers.Registered("sqlite3") && !drivers.Registered("moderncsqlite") {
+		return "", "", ErrNoEngine
+	}
+	dir, err := os.MkdirTemp("", "usql-federated-")
+	if err != nil {
+		return "", "", err
+	}
+	// moderncsqlite is registered as sqlite3 when sqlite3 is not available
+	return dir, "sqlite3:" + filepath.Join(dir, "federated.db"), nil
+}
+
+// Register creates the table in the embedded database db, replacing any
+// existing table, and inserts the rows into it, returning the number of
+// inserted rows.
+func Register(ctx context.Context, db *sql.DB, table string, rows *sql.Rows) (int64, error) {
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+	d := dump.DialectFor("sqlite3")
+	cols, kinds := columns(cts), make([]export.ColumnKind, len(cts))
+	defs, pholders := make([]string, len(cts)), make([]string, len(cts))
+	for i, ct := range cts {
+		kinds[i] = export.Kind(ct)
+		defs[i], pholders[i] = d.QuoteIdent(cols[i]), "?"
+		// values of untyped columns (ie, expressions) keep their type
+		if ct.DatabaseTypeName() != "" {
+			defs[i] += " " + columnType(kinds[i])
+		}
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+d.QuoteIdent(table)); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE TABLE %s (%s)", d.QuoteIdent(table), strings.Join(defs, ", "))); err != nil {
+		return 0, err
+	}
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf("INSERT INTO %s VALUES (%s)", d.QuoteIdent(table), strings.Join(pholders, ", ")))
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+	values := make([]interface{}, len(cts))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return 0, err
+		}
+		args := make([]interface{}, len(values))
+		for i, v := range values {
+			args[i] = convert(*(v.(*interface{})), kinds[i])
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return 0, err
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	return n, tx.Commit()
+}
+
+// columns returns the column names of the result, with duplicate names
+// suffixed by their count (ie, id, id_2).
+func columns(cts []*sql.ColumnType) []string {
+	cols, seen := make([]string, len(cts)), make(map[string]int, len(cts))
+	for i, ct := range cts {
+		name := ct.Name()
+		if name == "" {
+			name = "column" + strconv.Itoa(i+1)
+		}
+		key := strings.ToLower(name)
+		if seen[key]++; seen[key] > 1 {
+			name += "_" + strconv.Itoa(seen[key])
+		}
+		cols[i] = name
+	}
+	return cols
+}
+
+// columnType returns the SQLite column type of the kind.
+func columnType(kind export.ColumnKind) string {
+	switch kind {
+	case export.KindInt:
+		return "INTEGER"
+	case export.KindFloat:
+		return "REAL"
+	case export.KindBool:
+		return "BOOLEAN"
+	case export.KindTime:
+		return "TIMESTAMP"
+	case export.KindBinary:
+		return "BLOB"
+	}
+	return "TEXT"
+}
+
+// convert converts a value to store in a column of the kind. Drivers return
+// text as []byte, which SQLite would store as a blob.
+func convert(v interface{}, kind export.ColumnKind) interface{} {
+	if b, ok := v.([]byte); ok && kind != export.KindBinary {
+		return string(b)
+	}
+	return v
+}
@@ -0,0 +1,71 @@
+package federated
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRegister(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	// each connection has its own in-memory database
+	src, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer src.Close()
+	if _, err := src.ExecContext(ctx, `CREATE TABLE t (id INTEGER, name TEXT, data BLOB)`); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := src.ExecContext(ctx, `INSERT INTO t VALUES (1, 'a', X'00ff'), (2, NULL, NULL)`); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	rows, err := src.QueryContext(ctx, `SELECT id, name, data, id FROM t ORDER BY id`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer rows.Close()
+	dst, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer dst.Close()
+	dst.SetMaxOpenConns(1)
+	n, err := Register(ctx, dst, "my table", rows)
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case n != 2:
+		t.Errorf("expected 2 rows, got: %d", n)
+	}
+	res, err := dst.QueryContext(ctx, `SELECT id, name, typeof(data), id_2 FROM "my table" ORDER BY id`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer res.Close()
+	var got [][]interface{}
+	for res.Next() {
+		var id, id2 int64
+		var name sql.NullString
+		var typ string
+		if err := res.Scan(&id, &name, &typ, &id2); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		got = append(got, []interface{}{id, name.String, typ, id2})
+	}
+	exp := [][]interface{}{
+		{int64(1), "a", "blob", int64(1)},
+		{int64(2), "", "null", int64(2)},
+	}
+	if !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got: %v", exp, got)
+	}
+}
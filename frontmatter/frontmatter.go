@@ -0,0 +1,130 @@
+// Package frontmatter reads the YAML front matter of SQL files: the
+// directives between two --- lines at the start of a file, such as the
+// database alias and role the file is run with by usql runfile.
+package frontmatter
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// MaxSize is the maximum size of a front matter, larger blocks being read as
+// SQL.
+const MaxSize = 64 << 10
+
+// On error behaviors.
+const (
+	// OnErrorStop stops at the first failed statement.
+	OnErrorStop = "stop"
+	// OnErrorContinue executes the statements following failed ones.
+	OnErrorContinue = "continue"
+)
+
+// Directives are the directives of a front matter.
+type Directives struct {
+	// DB is the database alias the file is run on.
+	DB string `yaml:"db,omitempty"`
+	// Tag is the tag of the database alias the file is run on, when DB is
+	// not set.
+	Tag string `yaml:"tag,omitempty"`
+	// Role is the role of the connection.
+	Role string `yaml:"role,omitempty"`
+	// Timeout is the maximum duration of the run, after which the running
+	// statement is canceled.
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// OnError is the behavior on errors, stop or continue.
+	OnError string `yaml:"on_error,omitempty"`
+	// Format is the output format (see \pset format).
+	Format string `yaml:"format,omitempty"`
+	// Output is the output file, relative to the directory of the file.
+	Output string `yaml:"output,omitempty"`
+	// Variables are the variables set before running the file.
+	Variables map[string]string `yaml:"variables,omitempty"`
+}
+
+// Vars returns the NAME=VALUE variables, sorted by name.
+func (d *Directives) Vars() []string {
+	var vars []string
+	for k, v := range d.Variables {
+		vars = append(vars, k+"="+v)
+	}
+	sort.Strings(vars)
+	return vars
+}
+
+// Split returns the YAML of the front matter at the start of buf, and the
+// length of buf it spans, or nil and 0 when buf has none. A front matter is a
+// YAML mapping between --- lines, so that comment banners made of --- lines
+// are not mistaken for front matters.
+func Split(buf []byte) ([]byte, int) {
+	first, n := line(buf)
+	if string(first) != "---" {
+		return nil, 0
+	}
+	for i := n; i < len(buf) && i < MaxSize; {
+		l, m := line(buf[i:])
+		if string(l) == "---" {
+			block := buf[n:i]
+			var v map[string]interface{}
+			if err := yaml.Unmarshal(block, &v); err != nil || len(v) == 0 {
+				return nil, 0
+			}
+			return block, i + m
+		}
+		i += m
+	}
+	return nil, 0
+}
+
+// line returns the first line of buf, without its line ending, and its
+// length with the line ending.
+func line(buf []byte) ([]byte, int) {
+	i := bytes.IndexByte(buf, '\n')
+	if i == -1 {
+		return bytes.TrimSuffix(buf, []byte{'\r'}), len(buf)
+	}
+	return bytes.TrimSuffix(buf[:i], []byte{'\r'}), i + 1
+}
+
+// Parse parses the directives of the front matter at the start of buf,
+// returning the length of buf it spans, or nil and 0 when buf has none.
+func Parse(buf []byte) (*Directives, int, error) {
+	block, n := Split(buf)
+	if block == nil {
+		return nil, 0, nil
+	}
+	d := new(Directives)
+	if err := yaml.UnmarshalStrict(block, d); err != nil {
+		return nil, 0, fmt.Errorf("front matter: %w", err)
+	}
+	switch d.OnError {
+	case "", OnErrorStop, OnErrorContinue:
+	default:
+		return nil, 0, fmt.Errorf("front matter: invalid on_error %q: expected stop or continue", d.OnError)
+	}
+	if d.Timeout < 0 {
+		return nil, 0, fmt.Errorf("front matter: invalid timeout %s", d.Timeout)
+	}
+	return d, n, nil
+}
+
+// Skip skips the front matter at the start of r, when any. r must buffer at
+// least MaxSize bytes.
+func Skip(r *bufio.Reader) error {
+	buf, err := r.Peek(MaxSize)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, bufio.ErrBufferFull) {
+		return err
+	}
+	if _, n := Split(buf); n != 0 {
+		_, err := r.Discard(n)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package frontmatter
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		exp  *Directives
+		rest string
+	}{
+		{
+			"---\ndb: app_db\nrole: reader\ntimeout: 5m\non_error: stop\nformat: csv\noutput: out/report.csv\nvariables:\n  day: '2024-01-02'\n---\nselect 1;\n",
+			&Directives{DB: "app_db", Role: "reader", Timeout: 5 * time.Minute, OnError: OnErrorStop, Format: "csv", Output: "out/report.csv", Variables: map[string]string{"day": "2024-01-02"}},
+			"select 1;\n",
+		},
+		{
+			"---\r\ntag: reporting\r\n---\r\nselect 1;",
+			&Directives{Tag: "reporting"},
+			"select 1;",
+		},
+		{"select 1;\n", nil, "select 1;\n"},
+		// comment banners are SQL
+		{"---\n-- Monthly report\n---\nselect 1;\n", nil, "---\n-- Monthly report\n---\nselect 1;\n"},
+		{"---\nselect 1;\n", nil, "---\nselect 1;\n"},
+	}
+	for i, test := range tests {
+		d, n, err := Parse([]byte(test.s))
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if !reflect.DeepEqual(d, test.exp) {
+			t.Errorf("test %d expected %+v, got: %+v", i, test.exp, d)
+		}
+		if rest := test.s[n:]; rest != test.rest {
+			t.Errorf("test %d expected %q, got: %q", i, test.rest, rest)
+		}
+		r := bufio.NewReaderSize(strings.NewReader(test.s), MaxSize)
+		if err := Skip(r); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if buf, _ := io.ReadAll(r); string(buf) != test.rest {
+			t.Errorf("test %d expected %q after skipping, got: %q", i, test.rest, string(buf))
+		}
+	}
+	for i, s := range []string{
+		"---\ndb: app_db\nunknown: 1\n---\n",
+		"---\non_error: ignore\n---\n",
+		"---\ntimeout: soon\n---\n",
+	} {
+		if _, _, err := Parse([]byte(s)); err == nil {
+			t.Errorf("test %d expected an error", i)
+		}
+	}
+}
+
+func TestVars(t *testing.T) {
+	d := &Directives{Variables: map[string]string{"b": "2", "a": "1"}}
+	if exp, got := []string{"a=1", "b=2"}, d.Vars(); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got: %v", exp, got)
+	}
+}
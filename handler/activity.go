@@ -0,0 +1,37 @@
+package handler
+
+import (
+	"context"
+	"io"
+
+	"github.com/xo/usql/activity"
+	"github.com/xo/usql/drivers"
+)
+
+// activityWidth is the width of the text of the queries shown by \activity.
+const activityWidth = 80
+
+// Activity writes the queries running on the database, with the sessions
+// blocking them.
+func (h *Handler) Activity(ctx context.Context, w io.Writer) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	queries, err := activity.Read(ctx, h.u, h.db)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	return activity.Write(w, queries, activityWidth)
+}
+
+// Kill kills the session (or query) with the id on the database, as shown by
+// \activity.
+func (h *Handler) Kill(ctx context.Context, id string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	if err := activity.Kill(ctx, h.u, h.db, id); err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	return nil
+}
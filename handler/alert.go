@@ -0,0 +1,42 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/xo/usql/alert"
+	"github.com/xo/usql/cache"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/text"
+)
+
+// checkAlert checks the threshold of a watched query on the first value of
+// its result, taking the action of the alert when the value crosses it. The
+// exit action returns the alert as an error with code jsonout.CodeAlert,
+// ending the watch.
+func (h *Handler) checkAlert(ctx context.Context, a *alert.Alert, sqlstr string, res *cache.Result, d time.Duration) error {
+	var v interface{}
+	if len(res.Rows) != 0 && len(res.Rows[0]) != 0 {
+		v = res.Rows[0][0].V
+	}
+	if !a.Crossed(v) {
+		return nil
+	}
+	msg := fmt.Sprintf(text.WatchAlert, alert.Format(v), a)
+	switch a.Action {
+	case alert.ActionExit:
+		return jsonout.WithCode(jsonout.CodeAlert, errors.New(msg))
+	case alert.ActionWebhook:
+		e := h.notifyEvent(sqlstr, d, int64(len(res.Rows)), nil)
+		e.Alert = msg
+		if err := h.notifier.Notify(ctx, e); err != nil {
+			fmt.Fprintln(h.l.Stderr(), "error: notify:", err)
+		}
+	default:
+		fmt.Fprint(h.l.Stderr(), "\a")
+	}
+	fmt.Fprintln(h.l.Stderr(), msg)
+	return nil
+}
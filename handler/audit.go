@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/tblfmt"
+	"github.com/xo/usql/audit"
+	"github.com/xo/usql/metrics"
+	"github.com/xo/usql/redact"
+)
+
+// SetAlias sets the database alias of the config file the handler is
+// connected to, with its role and db_type, for the audit log, spans and
+// metrics.
+func (h *Handler) SetAlias(alias, role, dbType string) {
+	h.alias, h.role, h.dbType = alias, role, dbType
+	if h.db != nil {
+		metrics.Track(h.db, alias)
+	}
+}
+
+// Alias returns the database alias of the config file the handler is
+// connected to, if any.
+func (h *Handler) Alias() string {
+	return h.alias
+}
+
+// SetAudit sets the audit log of the executed statements.
+func (h *Handler) SetAudit(l *audit.Logger) {
+	h.audit = l
+}
+
+// auditor returns a func writing the audit log entry of an executed
+// statement, or nil when not auditing. rows is the number of rows returned
+// or affected by the statement, or -1 when unknown.
+func (h *Handler) auditor() func(sqlstr string, start time.Time, rows int64, err error) {
+	return h.auditorFor(h.alias, h.role, h.u)
+}
+
+// auditorFor returns the auditor of the statements executed on the database
+// of the URL, alias and role.
+func (h *Handler) auditorFor(alias, role string, u *dburl.URL) func(sqlstr string, start time.Time, rows int64, err error) {
+	if h.audit == nil {
+		return nil
+	}
+	l, stderr := h.audit, h.l.Stderr()
+	e := audit.Entry{
+		OSUser: h.user.Username,
+		Alias:  alias,
+		Role:   role,
+	}
+	if u != nil {
+		e.URL = u.Redacted()
+	}
+	return func(sqlstr string, start time.Time, rows int64, err error) {
+		e := e
+		e.Time, e.Duration = start, audit.Duration(time.Since(start))
+		if rows >= 0 {
+			e.Rows = &rows
+		}
+		if err != nil {
+			e.Error = redact.String(err.Error())
+		}
+		if err := l.Log(e, sqlstr); err != nil {
+			fmt.Fprintln(stderr, "error: audit log:", err)
+		}
+	}
+}
+
+// rowCounter counts the rows read from a result set, and keeps its columns.
+type rowCounter struct {
+	tblfmt.ResultSet
+	n    int64
+	cols []string
+}
+
+// Columns returns the column names.
+func (rc *rowCounter) Columns() ([]string, error) {
+	cols, err := rc.ResultSet.Columns()
+	if err == nil && rc.cols == nil {
+		rc.cols = cols
+	}
+	return cols, err
+}
+
+// Next advances to the next row.
+func (rc *rowCounter) Next() bool {
+	if rc.ResultSet.Next() {
+		rc.n++
+		return true
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xo/usql/blob"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/text"
+)
+
+// ExportBinary executes a query returning a single row, and writes the value
+// of its column to the file name, printing the size and checksum of the
+// written value (\lo_export).
+func (h *Handler) ExportBinary(ctx context.Context, sqlstr, column, name string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	sqlstr = strings.TrimSpace(sqlstr)
+	if sqlstr == "" {
+		return text.ErrMissingRequiredArgument
+	}
+	if err := h.checkPolicy(h.u, sqlstr); err != nil {
+		return err
+	}
+	rows, err := h.DB().QueryContext(ctx, sqlstr)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	i := -1
+	for j, c := range cols {
+		if strings.EqualFold(c, column) {
+			i = j
+			break
+		}
+	}
+	if i == -1 {
+		return fmt.Errorf(text.ColumnNotFound, column)
+	}
+	// scan the single row
+	var n int
+	values := make([]interface{}, len(cols))
+	for rows.Next() {
+		if n++; n > 1 {
+			return text.ErrTooManyRows
+		}
+		dest := make([]interface{}, len(cols))
+		for j := range dest {
+			dest[j] = &values[j]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+	}
+	switch err := rows.Err(); {
+	case err != nil:
+		return drivers.WrapErr(h.u.Driver, err)
+	case n == 0:
+		return text.ErrNoRows
+	}
+	var b []byte
+	switch v := values[i].(type) {
+	case nil:
+		return text.ErrNullValue
+	case []byte:
+		b = v
+	case string:
+		b = []byte(v)
+	default:
+		b = []byte(fmt.Sprint(v))
+	}
+	if err := os.WriteFile(name, b, 0o644); err != nil {
+		return err
+	}
+	h.Print(text.BinaryExported, name, blob.Summary(b))
+	return nil
+}
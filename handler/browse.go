@@ -0,0 +1,27 @@
+package handler
+
+import (
+	"context"
+	"os"
+
+	"github.com/xo/usql/browse"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/text"
+)
+
+// Browse browses the rows of the table in an interactive grid.
+func (h *Handler) Browse(ctx context.Context, table string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	if !h.l.Interactive() {
+		return text.ErrNotInteractive
+	}
+	b, err := browse.New(ctx, h.u, h.db, table, browse.Options{
+		Null: env.Pall()["null"],
+	})
+	if err != nil {
+		return err
+	}
+	return browse.Run(ctx, os.Stdin, os.Stdout, b)
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xo/usql/cache"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/text"
+)
+
+// SetCacheTTL enables caching the results of the queries on the current
+// connection for ttl, until another database is opened.
+func (h *Handler) SetCacheTTL(ttl time.Duration) {
+	h.cacheTTL = ttl
+}
+
+// GetCache returns whether query results are cached.
+func (h *Handler) GetCache() bool {
+	return h.cacheTTL > 0 && !h.cacheOff
+}
+
+// SetCache turns query result caching on or off.
+func (h *Handler) SetCache(on bool) error {
+	if on && h.cacheTTL == 0 {
+		return text.ErrCacheNotConfigured
+	}
+	h.cacheOff = !on
+	return nil
+}
+
+// ClearCache removes all cached query results.
+func (h *Handler) ClearCache() error {
+	c, err := h.resultCache()
+	if err != nil {
+		return err
+	}
+	return c.Clear()
+}
+
+// resultCache returns the query result cache.
+func (h *Handler) resultCache() (*cache.Cache, error) {
+	if h.cache == nil {
+		c, err := cache.New()
+		if err != nil {
+			return nil, err
+		}
+		h.cache = c
+	}
+	return h.cache, nil
+}
+
+// cachePrefixes are the prefixes of the read only statements whose results
+// are cached.
+var cachePrefixes = map[string]bool{
+	"SELECT": true,
+	"VALUES": true,
+	"TABLE":  true,
+	"SHOW":   true,
+}
+
+// cacheKey returns the cache key of the query, or an empty string when its
+// results cannot be cached. Only the results of read only statements outside
+// of transactions, displayed using the text formats, are cached.
+func (h *Handler) cacheKey(opt metacmd.Option, typ, sqlstr string) string {
+	switch {
+	case !h.GetCache() || h.tx != nil:
+		return ""
+	case opt.Exec == metacmd.ExecCrosstab || opt.Exec == metacmd.ExecWatch:
+		return ""
+	case drivers.UseColumnTypes(h.u):
+		return ""
+	}
+	if fields := strings.Fields(typ); len(fields) == 0 || !cachePrefixes[fields[0]] {
+		return ""
+	}
+	format := opt.Params["format"]
+	if format == "" {
+		format = env.Pall()["format"]
+	}
+	if binaryFormat(format, h.GetOutput()) != "" || binaryExt(opt.Params["pipe"]) != "" || format == "inserts" {
+		return ""
+	}
+	if len(opt.Args) != 0 {
+		sqlstr += fmt.Sprintf("\x00%#v", opt.Args)
+	}
+	return cache.Key(h.u.Redacted(), sqlstr)
+}
+
+// cachedResult returns the cached result of the key, or nil.
+func (h *Handler) cachedResult(key string) *cache.Result {
+	if key == "" {
+		return nil
+	}
+	c, err := h.resultCache()
+	if err != nil {
+		return nil
+	}
+	res, err := c.Get(key, h.cacheTTL)
+	if err != nil {
+		h.printErr(h.l.Stderr(), err)
+		return nil
+	}
+	return res
+}
+
+// cacheResult caches the result of the key, when not nil.
+func (h *Handler) cacheResult(key string, res *cache.Result) {
+	if res == nil {
+		return
+	}
+	c, err := h.resultCache()
+	if err == nil {
+		err = c.Put(key, res)
+	}
+	if err != nil {
+		h.printErr(h.l.Stderr(), err)
+	}
+}
@@ -0,0 +1,83 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xo/usql/cache"
+	"github.com/xo/usql/call"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/stmt"
+	"github.com/xo/usql/text"
+)
+
+// Call calls a stored procedure, binding its arguments as supported by the
+// driver, and writes its result sets followed by the values of its OUT
+// parameters.
+func (h *Handler) Call(ctx context.Context, s string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	c, err := call.Parse(s, env.All())
+	if err != nil {
+		return err
+	}
+	dialect := drivers.Caps(h.u).Dialect
+	st, err := c.Statement(dialect, func(n int) string {
+		return drivers.Placeholder(h.u, n)
+	})
+	if err != nil {
+		return fmt.Errorf(text.NotSupportedByDriver, "OUT parameters", h.u.Driver)
+	}
+	w := h.l.Stdout()
+	if h.out != nil {
+		w = h.out
+	}
+	opt := metacmd.Option{Exec: metacmd.ExecOnly, Args: st.Args}
+	if st.Query {
+		opt.Exec = metacmd.ExecQuery
+	}
+	// the OUT parameters selected after the call are session variables, read
+	// on the connection of a transaction
+	began := st.OutQuery != "" && h.tx == nil
+	if began {
+		if err := h.BeginTx(ctx, nil); err != nil {
+			return err
+		}
+		defer func() {
+			if h.tx != nil {
+				_ = h.Rollback()
+			}
+		}()
+	}
+	if err := h.Execute(ctx, w, opt, stmt.FindPrefix(st.SQL, true, true, true), st.SQL, false); err != nil {
+		return err
+	}
+	switch {
+	case st.OutQuery != "":
+		if err := h.query(ctx, w, metacmd.Option{}, "SELECT", st.OutQuery); err != nil {
+			return drivers.WrapErr(h.u.Driver, err)
+		}
+		if began {
+			return h.Commit()
+		}
+		return nil
+	case len(st.Outs) == 0 || dialect == "postgres":
+		// postgres returns the OUT parameters as the row of the call
+		return nil
+	}
+	values := st.OutValues()
+	res := &cache.Result{Columns: st.Outs, Rows: make([][]cache.Value, 1)}
+	for _, v := range values {
+		res.Rows[0] = append(res.Rows[0], cache.Value{V: v})
+	}
+	if err := encodeAll(w, res.ResultSet(), env.Pall()); err != nil {
+		return err
+	}
+	if env.Pall()["format"] == "aligned" {
+		fmt.Fprintln(w)
+	}
+	return nil
+}
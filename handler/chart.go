@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/gohxs/readline"
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/chart"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/text"
+)
+
+// execChart executes a query and writes its result as a bar or line chart
+// (\chart [bar|line] [X [Y]]), of the values of the Y column (by default the
+// second) labeled by the X column (by default the first).
+func (h *Handler) execChart(ctx context.Context, w io.Writer, opt metacmd.Option, _, sqlstr string, _ bool) error {
+	kind, params := chart.Bar, opt.Chart
+	if len(params) != 0 {
+		if k, err := chart.ParseKind(params[0]); err == nil {
+			kind, params = k, params[1:]
+		} else if len(params) == 3 {
+			return err
+		}
+	}
+	rows, err := h.DB().QueryContext(ctx, sqlstr, opt.Args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	cols, err := drivers.Columns(h.u, rows)
+	if err != nil {
+		return err
+	}
+	if len(cols) < 2 {
+		return text.ErrChartResultMustHaveAtLeast2Columns
+	}
+	x, y := 0, 1
+	if len(params) > 0 {
+		if x, err = chartColumn(cols, params[0]); err != nil {
+			return err
+		}
+	}
+	if len(params) > 1 {
+		if y, err = chartColumn(cols, params[1]); err != nil {
+			return err
+		}
+	}
+	// read the values, skipping nulls
+	var s chart.Series
+	var n int64
+	clen, tfmt := len(cols), env.GoTime()
+	for rows.Next() {
+		row, err := scan(h.u, rows, clen, tfmt)
+		if err != nil {
+			return err
+		}
+		n++
+		if row[y] == "" {
+			continue
+		}
+		if err := s.Add(row[x], row[y]); err != nil {
+			return fmt.Errorf("%s: %w", cols[y], err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	h.lastRows, h.lastCols = n, cols
+	if err := chart.Write(w, kind, s, chartWidth(w)); err != nil {
+		return err
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+// chartColumn returns the index of the named or numbered (from 1) column.
+func chartColumn(cols []string, name string) (int, error) {
+	if i, err := strconv.Atoi(name); err == nil && 0 < i && i <= len(cols) {
+		return i - 1, nil
+	}
+	for i, c := range cols {
+		if strings.EqualFold(c, name) {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf(text.ChartColumnNotInResult, name)
+}
+
+// chartWidth returns the width of the charts written to w: the columns
+// variable when set, the terminal width, or 80.
+func chartWidth(w io.Writer) int {
+	if i, _ := strconv.Atoi(env.Pall()["columns"]); i > 0 {
+		return i
+	}
+	if f, ok := w.(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+		if i := readline.GetScreenWidth(); i > 0 {
+			return i
+		}
+	}
+	return 80
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/text"
+)
+
+// checkPolicy checks the statements of sqlstr, split for the driver of the
+// URL, against the policy of the role.
+func (h *Handler) checkPolicy(u *dburl.URL, sqlstr string) error {
+	if h.policy == nil {
+		return nil
+	}
+	stmts, err := drivers.Statements(u, sqlstr)
+	if err != nil {
+		return err
+	}
+	return h.policy.Check(stmts...)
+}
+
+// confirmStatement asks to confirm the statement before it is executed on the
+// database of the URL (and alias), when
+// the confirmation policy requires it, returning the *policy.Unconfirmed of
+// the statement when declined. Without an interactive terminal to ask, the
+// statement is refused, unless executed without asking. The statements of dry
+// runs, rolled back at the end, need no confirmation.
+func (h *Handler) confirmStatement(u *dburl.URL, alias, sqlstr string) error {
+	c := h.confirm.Check(sqlstr, drivers.Caps(u).Dialect)
+	switch {
+	case c == nil, h.yes, h.dryRun != nil:
+		return nil
+	case !h.l.Interactive():
+		return c
+	}
+	if alias == "" {
+		alias = u.Host
+	}
+	h.l.Prompt(fmt.Sprintf(text.ConfirmStatement, strings.ReplaceAll(c.Kind, "_", " "), alias))
+	r, err := h.l.Next()
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(strings.TrimSpace(string(r))) {
+	case "y", "yes":
+		return nil
+	}
+	c.Declined = true
+	return c
+}
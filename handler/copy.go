@@ -0,0 +1,84 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/text"
+)
+
+// copyToStdoutRE matches COPY ... TO STDOUT statements.
+var copyToStdoutRE = regexp.MustCompile(`(?is)\bTO\s+STDOUT\b`)
+
+// CopyFile streams the data of the file name to the COPY ... FROM STDIN
+// statement sqlstr when from is true, or the data of the COPY ... TO STDOUT
+// statement sqlstr to the file name otherwise, with the database's copy
+// protocol (\copy). The standard input, or the handler's output, is used when
+// name is empty.
+func (h *Handler) CopyFile(ctx context.Context, sqlstr, name string, from bool) (int64, error) {
+	switch {
+	case h.db == nil:
+		return 0, h.errNotConnected()
+	case !drivers.Caps(h.u).CopyStream:
+		return 0, fmt.Errorf(text.NotSupportedByDriver, `\copy`, h.u.Driver)
+	case h.tx != nil:
+		// the data is copied on another connection than the transaction's
+		return 0, text.ErrCopyInTransaction
+	}
+	if err := h.checkPolicy(h.u, sqlstr); err != nil {
+		return 0, err
+	}
+	if from {
+		var r io.Reader = os.Stdin
+		if name != "" {
+			f, err := os.Open(name)
+			if err != nil {
+				return 0, err
+			}
+			defer f.Close()
+			p := h.Progress(`\copy from ` + name)
+			defer p.Done()
+			if fi, err := f.Stat(); err == nil {
+				p.SetSize(fi.Size())
+			}
+			r = p.Reader(f)
+		}
+		return drivers.CopyFrom(ctx, h.u, h.db, sqlstr, r)
+	}
+	if name == "" {
+		return drivers.CopyTo(ctx, h.u, h.db, sqlstr, h.GetOutput())
+	}
+	f, err := os.OpenFile(name, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	p := h.Progress(`\copy to ` + name)
+	n, err := drivers.CopyTo(ctx, h.u, h.db, sqlstr, p.Writer(f))
+	p.Done()
+	if e := f.Close(); err == nil {
+		err = e
+	}
+	return n, err
+}
+
+// copyToStdout returns true when a statement is a COPY ... TO STDOUT
+// statement streamed by the driver, outside of a transaction.
+func (h *Handler) copyToStdout(prefix, sqlstr string) bool {
+	return strings.HasPrefix(prefix, "COPY") && h.tx == nil && drivers.Caps(h.u).CopyStream && copyToStdoutRE.MatchString(sqlstr)
+}
+
+// copyTo streams the data of a COPY ... TO STDOUT statement to w.
+func (h *Handler) copyTo(ctx context.Context, w io.Writer, _ metacmd.Option, _, sqlstr string) error {
+	n, err := drivers.CopyTo(ctx, h.u, h.db, sqlstr, w)
+	if err != nil {
+		return err
+	}
+	h.lastRows = n
+	return nil
+}
@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/xo/usql/cursor"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/explain"
+	"github.com/xo/usql/metacmd"
+)
+
+// openCursor declares a server-side cursor of the query sqlstr written to a
+// file, when the driver supports cursors and the number of rows estimated by
+// the planner reaches CURSOR_THRESHOLD, so neither usql nor the database hold
+// all the rows. Returns nil when the query is to be executed as is.
+func (h *Handler) openCursor(ctx context.Context, opt metacmd.Option, typ, sqlstr string) (*cursor.Rows, error) {
+	threshold, _ := strconv.Atoi(env.Get("CURSOR_THRESHOLD"))
+	caps := drivers.Caps(h.u)
+	switch {
+	case threshold <= 0 || !caps.Cursors || h.txAborted || len(opt.Args) != 0 || !isSelect(typ):
+		return nil, nil
+	case h.out == nil && opt.Params["pipe"] == "":
+		return nil, nil
+	case opt.Exec != metacmd.ExecNone && opt.Exec != metacmd.ExecOnly && opt.Exec != metacmd.ExecPipe:
+		return nil, nil
+	}
+	sqlstr = strings.TrimRight(strings.TrimSpace(sqlstr), ";")
+	// errors of the estimate are those of the query
+	if n, err := h.estimateRows(ctx, caps.Dialect, sqlstr); err != nil || n < float64(threshold) {
+		return nil, nil
+	}
+	tx, commit := h.tx, false
+	if tx == nil {
+		var err error
+		if tx, err = h.db.BeginTx(ctx, nil); err != nil {
+			return nil, err
+		}
+		commit = true
+	}
+	size, _ := strconv.Atoi(env.Get("FETCH_COUNT"))
+	return cursor.Declare(ctx, tx, commit, sqlstr, size)
+}
+
+// estimateRows returns the number of rows of the result of the query
+// estimated by the planner of the SQL dialect.
+func (h *Handler) estimateRows(ctx context.Context, dialect, sqlstr string) (float64, error) {
+	q, err := explain.Statement(dialect, sqlstr, false)
+	if err != nil {
+		return 0, err
+	}
+	rows, err := h.DB().QueryContext(ctx, q)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	plan, err := explain.Read(dialect, rows)
+	if err != nil {
+		return 0, err
+	}
+	var n float64
+	for _, node := range plan.Nodes {
+		n += node.Rows
+	}
+	return n, nil
+}
+
+// isSelect returns whether the statement of the prefix is a query whose
+// results can be paged or read with a cursor.
+func isSelect(prefix string) bool {
+	switch typ, _, _ := strings.Cut(prefix, " "); typ {
+	case "SELECT", "WITH", "VALUES", "TABLE":
+		return true
+	}
+	return false
+}
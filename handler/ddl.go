@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+)
+
+// ShowCreate writes the CREATE statement of a table or view to the handler's
+// output.
+func (h *Handler) ShowCreate(ctx context.Context, name string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	stmt, err := dump.ShowCreate(ctx, h.u, h.db, name)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	_, err = fmt.Fprintln(h.GetOutput(), stmt)
+	return err
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"io"
+
+	"github.com/xo/usql/cache"
+	"github.com/xo/usql/mask"
+	"github.com/xo/usql/resultdiff"
+	"github.com/xo/usql/text"
+)
+
+// DiffResults writes the differences between the results of the previous and
+// last queries, matching their rows by the key columns (default the first
+// column). The values of masked columns are masked.
+func (h *Handler) DiffResults(w io.Writer, key []string) error {
+	if h.results[0] == nil && h.results[1] == nil {
+		return text.ErrNoResultsToDiff
+	}
+	if h.results[0] == nil || h.results[1] == nil {
+		return text.ErrResultsNotRecorded
+	}
+	d, err := resultdiff.Compare(h.diffResult(h.results[0]), h.diffResult(h.results[1]), key)
+	if err != nil {
+		return err
+	}
+	return d.Write(w, "previous", "last")
+}
+
+// diffResult returns the result of a query to compare, with the values of
+// its masked columns masked.
+func (h *Handler) diffResult(res *cache.Result) *resultdiff.Result {
+	masked := make([]bool, len(res.Columns))
+	for i, c := range res.Columns {
+		masked[i] = mask.Match(h.mask, c)
+	}
+	r := &resultdiff.Result{Columns: res.Columns, Rows: make([][]interface{}, len(res.Rows))}
+	for i, row := range res.Rows {
+		r.Rows[i] = make([]interface{}, len(row))
+		for j, v := range row {
+			if r.Rows[i][j] = v.V; masked[j] {
+				r.Rows[i][j] = mask.Mask
+			}
+		}
+	}
+	return r
+}
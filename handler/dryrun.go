@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/text"
+)
+
+// dryRun is the state of a dry run.
+type dryRun struct {
+	// stmts is the number of executed statements not returning rows, and
+	// rows the number of rows they affected.
+	stmts int
+	rows  int64
+}
+
+// BeginDryRun begins the transaction of a dry run, rolled back by EndDryRun.
+// Until then, the statements ending the transaction, or committing it
+// implicitly, are refused.
+func (h *Handler) BeginDryRun(ctx context.Context) error {
+	if err := h.BeginTx(ctx, nil); err != nil {
+		return err
+	}
+	h.dryRun = new(dryRun)
+	return nil
+}
+
+// EndDryRun rolls back the transaction of the dry run, writing the number of
+// statements executed and of rows they would have affected to stderr.
+func (h *Handler) EndDryRun() error {
+	d := h.dryRun
+	if d == nil {
+		return nil
+	}
+	h.dryRun = nil
+	if h.tx != nil {
+		if err := h.Rollback(); err != nil {
+			return err
+		}
+	}
+	fmt.Fprintf(h.l.Stderr(), text.DryRunRolledBack+"\n", d.stmts, d.rows)
+	return nil
+}
+
+// checkDryRun returns an error when the statement with the prefix cannot be
+// executed in the transaction of the dry run.
+func (h *Handler) checkDryRun(prefix string) error {
+	if h.dryRun == nil {
+		return nil
+	}
+	words := strings.Fields(strings.ToUpper(prefix))
+	if len(words) == 0 {
+		return nil
+	}
+	if endsTransaction(words) {
+		return text.ErrDryRunEndsTransaction
+	}
+	switch drivers.Caps(h.u).Dialect {
+	case "mysql", "oracle":
+		// definition statements commit the current transaction
+		switch words[0] {
+		case "CREATE", "ALTER", "DROP", "TRUNCATE", "RENAME", "GRANT", "REVOKE":
+			return fmt.Errorf(text.DryRunImplicitCommit, words[0], h.u.Driver)
+		}
+	}
+	return nil
+}
+
+// addDryRun adds the rows affected by the last executed statement to the dry
+// run.
+func (h *Handler) addDryRun(qtyp bool) {
+	if h.dryRun != nil && !qtyp && h.lastRows >= 0 {
+		h.dryRun.stmts++
+		h.dryRun.rows += h.lastRows
+	}
+}
+
+// endsTransaction returns true when the words of a statement's prefix start
+// or end a transaction.
+func endsTransaction(words []string) bool {
+	second := ""
+	if len(words) > 1 {
+		second = words[1]
+	}
+	switch words[0] {
+	case "COMMIT", "ABORT":
+		return true
+	case "ROLLBACK":
+		// ROLLBACK TO SAVEPOINT does not end the transaction
+		for _, w := range words[1:] {
+			if w == "TO" {
+				return false
+			}
+		}
+		return true
+	case "BEGIN", "END":
+		// BEGIN also starts anonymous blocks (ie, PL/SQL)
+		switch second {
+		case "", "TRAN", "TRANSACTION", "WORK", "ISOLATION", "DEFERRED", "IMMEDIATE", "EXCLUSIVE":
+			return true
+		}
+	case "START":
+		return second == "TRANSACTION"
+	}
+	return false
+}
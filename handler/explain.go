@@ -0,0 +1,41 @@
+package handler
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/explain"
+	"github.com/xo/usql/text"
+)
+
+// Explain writes the query plan of a query to the handler's output, executing
+// the query when analyze is true. Sequential scans and misestimated row
+// counts are highlighted when the output is a terminal.
+func (h *Handler) Explain(ctx context.Context, sqlstr string, analyze bool) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	sqlstr = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(sqlstr), ";"))
+	if sqlstr == "" {
+		return text.ErrMissingRequiredArgument
+	}
+	q, err := explain.Statement(drivers.Caps(h.u).Dialect, sqlstr, analyze)
+	if err != nil {
+		return err
+	}
+	rows, err := h.db.QueryContext(ctx, q)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	defer rows.Close()
+	plan, err := explain.Read(drivers.Caps(h.u).Dialect, rows)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	w := h.GetOutput()
+	f, ok := w.(*os.File)
+	return plan.Write(w, ok && isatty.IsTerminal(f.Fd()) && os.Getenv("NO_COLOR") == "")
+}
@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/federated"
+	"github.com/xo/usql/pkg/conn"
+	"github.com/xo/usql/text"
+	"github.com/xo/usql/tracing"
+)
+
+// SetAliasPool sets the connection pools of the database aliases of the
+// config file, used with the credentials of the role to fetch query results
+// in federated mode.
+func (h *Handler) SetAliasPool(p *conn.Pool, role string) {
+	h.pool, h.poolRole = p, role
+}
+
+// PoolStatus writes the open and idle connections of the connection pools of
+// the database aliases.
+func (h *Handler) PoolStatus(w io.Writer) error {
+	if h.pool == nil {
+		return text.ErrNoDatabaseAliases
+	}
+	stats := h.pool.Stats()
+	if len(stats) == 0 {
+		fmt.Fprintln(w, text.NoConnectionPools)
+		return nil
+	}
+	for _, s := range stats {
+		alias, max := s.Alias, "unlimited"
+		if s.Role != "" {
+			alias += " (" + s.Role + ")"
+		}
+		if s.MaxOpenConnections > 0 {
+			max = strconv.Itoa(s.MaxOpenConnections)
+		}
+		fmt.Fprintf(w, "%-24s open %d, in use %d, idle %d, max %s, waited %d (%v)\n", alias, s.OpenConnections, s.InUse, s.Idle, max, s.WaitCount, s.WaitDuration)
+	}
+	return nil
+}
+
+// Fetch executes a query on a database alias, and registers its results as a
+// table of the embedded federated database, connecting to the federated
+// database when not already connected to it.
+func (h *Handler) Fetch(ctx context.Context, alias, table, sqlstr string) (err error) {
+	ctx, span := tracing.Start(ctx, "fetch", tracing.AliasKey.String(alias))
+	defer func() { tracing.End(span, err) }()
+	if h.pool == nil {
+		return text.ErrNoDatabaseAliases
+	}
+	sqlstr = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(sqlstr), ";"))
+	if alias == "" || table == "" || sqlstr == "" {
+		return text.ErrMissingRequiredArgument
+	}
+	u, db, err := h.pool.Get(ctx, alias, h.poolRole)
+	if err != nil {
+		return err
+	}
+	rows, err := db.QueryContext(ctx, sqlstr)
+	if err != nil {
+		return drivers.WrapErr(u.Driver, err)
+	}
+	defer rows.Close()
+	if h.federated == "" {
+		if h.tx != nil {
+			return text.ErrPreviousTransactionExists
+		}
+		dir, urlstr, err := federated.Create()
+		if err != nil {
+			return err
+		}
+		if err := h.Close(); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+		if err := h.Open(ctx, urlstr); err != nil {
+			os.RemoveAll(dir)
+			return err
+		}
+		h.federated = dir
+	}
+	n, err := federated.Register(ctx, h.db, table, rows)
+	if err != nil {
+		return drivers.WrapErr(u.Driver, err)
+	}
+	span.SetAttributes(tracing.RowsKey.Int64(n))
+	h.Print(text.FetchedRows, table, n)
+	return nil
+}
@@ -28,18 +28,43 @@ import (
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters"
 	"github.com/alecthomas/chroma/v2/styles"
+	isatty "github.com/mattn/go-isatty"
 	"github.com/xo/dburl"
 	"github.com/xo/dburl/passfile"
 	"github.com/xo/tblfmt"
+	"github.com/xo/usql/alert"
+	"github.com/xo/usql/audit"
+	"github.com/xo/usql/blob"
+	"github.com/xo/usql/cache"
+	"github.com/xo/usql/cursor"
+	"github.com/xo/usql/display"
 	"github.com/xo/usql/drivers"
 	"github.com/xo/usql/drivers/completer"
 	"github.com/xo/usql/drivers/metadata"
 	"github.com/xo/usql/env"
+	"github.com/xo/usql/export"
+	"github.com/xo/usql/frontmatter"
+	"github.com/xo/usql/history"
+	"github.com/xo/usql/hooks"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/logging"
+	"github.com/xo/usql/mask"
 	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/metrics"
+	"github.com/xo/usql/notify"
+	"github.com/xo/usql/offline"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/pkg/conn"
+	"github.com/xo/usql/policy"
+	"github.com/xo/usql/redact"
 	"github.com/xo/usql/rline"
+	"github.com/xo/usql/session"
 	"github.com/xo/usql/stmt"
 	ustyles "github.com/xo/usql/styles"
+	"github.com/xo/usql/suggest"
 	"github.com/xo/usql/text"
+	"github.com/xo/usql/theme"
+	"github.com/xo/usql/tracing"
 )
 
 // Handler is a input process handler.
@@ -72,38 +97,210 @@ type Handler struct {
 	u  *dburl.URL
 	db *sql.DB
 	tx *sql.Tx
+	// txAborted is set when a statement failed in the current transaction
+	// and the database requires a rollback
+	txAborted bool
+	// onConnect are the statements executed on each connection to the
+	// database of onConnectDB (see SetOnConnect)
+	onConnect   []string
+	onConnectDB string
+	// keepalive is the interval of the pings of the connections, stopped
+	// with stopKeepalive
+	keepalive     time.Duration
+	stopKeepalive context.CancelFunc
 	// out file or pipe
 	out io.WriteCloser
+	// background jobs
+	jobs jobs
+	// open xlsx workbook, and the output it is written to
+	workbook    *export.Workbook
+	workbookOut io.Writer
+	// pool is the connection pools of the database aliases from the config
+	// file, opened with the credentials of poolRole
+	pool     *conn.Pool
+	poolRole string
+	// pageSize is the number of rows of the pages of the results of queries,
+	// when paging, and page the paged query
+	pageSize int
+	page     *page
+	// federated is the directory of the embedded federated database, when
+	// connected to it
+	federated string
+	// offline is the metadata snapshot of the database, in offline mode
+	offline *offline.Snapshot
+	// query result cache, used when cacheTTL is set and caching was not
+	// turned off
+	cache    *cache.Cache
+	cacheTTL time.Duration
+	cacheOff bool
+	// maxResultBytes is the maximum size in bytes of the results fetched,
+	// when set
+	maxResultBytes int64
+	// mask are the patterns of the columns whose values are masked
+	mask []string
+	// policy is the statement policy of the role
+	policy *policy.Policy
+	// confirm is the confirmation policy of the database, and yes is set
+	// when its statements are executed without asking
+	confirm *policy.Confirm
+	yes     bool
+	// hooks are the hooks of the database
+	hooks *hooks.Hooks
+	// appName is the application name of the opened connections, when
+	// overridden
+	appName string
+	// comment is the comment prepended to the statements executed on the
+	// database, attributing them
+	comment string
+	// showProgress is set when the progress of the imports, exports and
+	// copies is reported
+	showProgress bool
+	// theme is the color theme of the interactive session
+	theme theme.Theme
+	// color is the color of the prompt on the database, overriding the color
+	// of the theme
+	color theme.Color
+	// dryRun is the dry run in progress, whose transaction is rolled back
+	// at the end
+	dryRun *dryRun
+	// deadline is the deadline of the executed statements, when not zero
+	deadline time.Time
+	// database alias of the config file the handler is connected to, with
+	// its role and db_type
+	alias  string
+	role   string
+	dbType string
+	// audit log of the executed statements
+	audit *audit.Logger
+	// recorder records the executed statements to a session file
+	recorder *session.Recorder
+	// history is the shared query history
+	history *history.Store
+	// suggestions is the history of the statements executed on the alias
+	// the suggestions were opened for, suggesting the completions of the
+	// statements of the interactive input
+	suggestions  *suggest.History
+	suggestAlias string
+	// notifier notifies the long-running statements
+	notifier *notify.Notifier
+	// reloadConfig reloads the config file
+	reloadConfig func() error
+	// configChanged is signaled when the config file changed, to reload it
+	// before the next prompt
+	configChanged chan struct{}
+	// queries returns the query templates of the config file
+	queries func(string) (*config.QueryConfig, error)
+	// lastRows is the number of rows returned or affected by the last
+	// statement, or -1, and lastCols are the columns of the last query, when
+	// auditing, tracing, recording or collecting the metadata of the result
+	lastRows int64
+	lastCols []string
+	// bindings are the key bindings of the interactive input
+	bindings string
+	// reader is the connection to the reader host of the database, when
+	// routing the read only statements to it, with the side (r or w) that
+	// served the last statement, and reading set while executing a statement
+	// routed to the reader
+	reader  *sql.DB
+	route   string
+	reading bool
+	// results are the results of the previous and last queries, compared by
+	// \diff, nil when not recorded
+	results [2]*cache.Result
+	// json is set when writing the errors and the results of the statements
+	// not returning rows as JSON objects
+	json bool
+	// meta is the metadata of the result of the executed statement, when
+	// collected (see startMeta), and serverVersion is the version of the
+	// connected database, fetched for the metadata
+	meta          *resultMeta
+	serverVersion string
+	// prepared are the statements prepared by the driver, by name
+	prepared map[string]*prepared
+	// args are the arguments bound to the statements of the commands
+	// (--param)
+	args []interface{}
 }
 
 // New creates a new input handler.
 func New(l rline.IO, user *user.User, wd string, nopw bool) *Handler {
+	h := &Handler{
+		l:             l,
+		user:          user,
+		wd:            wd,
+		nopw:          nopw,
+		configChanged: make(chan struct{}, 1),
+	}
 	f, iactive := l.Next, l.Interactive()
 	if iactive {
+		// lines of the statement being read
+		var lines []string
 		f = func() ([]rune, error) {
+			// save history of the previous statement, once complete
+			if h.buf.Len == 0 {
+				saveHistory(l, lines)
+				lines = lines[:0]
+			}
+			h.setEditing()
+			h.setSuggestions()
 			// next line
 			r, err := l.Next()
 			if err != nil {
+				saveHistory(l, lines)
+				lines = lines[:0]
 				return nil, err
 			}
-			// save history
-			_ = l.Save(string(r))
+			lines = append(lines, string(r))
 			return r, nil
 		}
 	}
-	h := &Handler{
-		l:    l,
-		user: user,
-		wd:   wd,
-		nopw: nopw,
-		buf:  stmt.New(f),
-	}
+	h.buf = stmt.New(f)
 	if iactive {
 		l.SetOutput(h.outputHighlighter)
+		l.Suggester(h.suggest)
 	}
 	return h
 }
 
+// saveHistory saves the lines of a statement as a single history entry, so
+// that it is recalled whole. Lines with comments, that would comment out the
+// following lines, are saved as separate entries.
+func saveHistory(l rline.IO, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	for _, line := range lines {
+		if strings.Contains(line, "--") || strings.Contains(line, "#") {
+			for _, line := range lines {
+				_ = l.Save(line)
+			}
+			return
+		}
+	}
+	s := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line = strings.TrimSpace(line); line != "" {
+			s = append(s, line)
+		}
+	}
+	if len(s) != 0 {
+		_ = l.Save(strings.Join(s, " "))
+	}
+}
+
+// setEditing sets the editing mode and key bindings of the interactive
+// input.
+func (h *Handler) setEditing() {
+	vars := env.All()
+	h.l.SetEditMode(vars["EDITING_MODE"] == "vi")
+	if s := vars["KEY_BINDINGS"]; s != h.bindings {
+		h.bindings = s
+		if m, err := rline.ParseBindings(s); err == nil {
+			h.l.SetBindings(m)
+		}
+	}
+}
+
 // SetSingleLineMode sets the single line mode toggle.
 func (h *Handler) SetSingleLineMode(singleLineMode bool) {
 	h.singleLineMode = singleLineMode
@@ -119,6 +316,187 @@ func (h *Handler) SetTiming(timing bool) {
 	h.timing = timing
 }
 
+// SetMask masks the values of the columns matching the patterns (see
+// mask.Match) in the results of the queries on the current connection, until
+// another database is opened.
+func (h *Handler) SetMask(patterns []string) {
+	h.mask = patterns
+}
+
+// SetPolicy sets the policy checked before executing statements on the
+// current connection, until another database is opened.
+func (h *Handler) SetPolicy(p *policy.Policy) {
+	h.policy = p
+}
+
+// SetConfirm sets the confirmation policy of the statements executed on the
+// current connection, until another database is opened.
+func (h *Handler) SetConfirm(c *policy.Confirm) {
+	h.confirm = c
+}
+
+// SetYes sets whether the statements of the confirmation policy are executed
+// without asking.
+func (h *Handler) SetYes(yes bool) {
+	h.yes = yes
+}
+
+// SetQueryComment sets the comment prepended to the statements executed on
+// the current connection, until another database is opened.
+func (h *Handler) SetQueryComment(comment string) {
+	h.comment = comment
+}
+
+// SetTheme sets the color theme of the interactive session.
+func (h *Handler) SetTheme(t theme.Theme) {
+	h.theme = t
+}
+
+// SetPromptColor sets the color of the prompt on the current connection,
+// overriding the color of the theme until another database is opened.
+func (h *Handler) SetPromptColor(color theme.Color) {
+	h.color = color
+}
+
+// SetApplicationName overrides the application name of the connections
+// opened by the handler, usql/VERSION by default, when not set by their DSN.
+func (h *Handler) SetApplicationName(name string) {
+	h.appName = name
+}
+
+// SetArgs sets the arguments bound by the driver to the parameters of the
+// statements executed by Run, without arguments of their own.
+func (h *Handler) SetArgs(args []interface{}) {
+	h.args = args
+}
+
+// SetHooks sets the hooks called when executing statements on the current
+// connection, until another database is opened.
+func (h *Handler) SetHooks(hs *hooks.Hooks) {
+	h.hooks = hs
+}
+
+// SetRecorder sets the recorder of the executed statements.
+func (h *Handler) SetRecorder(r *session.Recorder) {
+	h.recorder = r
+}
+
+// SetNotifier sets the notifier of the long-running statements.
+func (h *Handler) SetNotifier(n *notify.Notifier) {
+	h.notifier = n
+}
+
+// SetDeadline sets the deadline of the executed statements, after which they
+// are canceled.
+func (h *Handler) SetDeadline(deadline time.Time) {
+	h.deadline = deadline
+}
+
+// SetJSON sets whether the errors, and the results of the statements not
+// returning rows, are written as JSON objects (see jsonout).
+func (h *Handler) SetJSON(json bool) {
+	h.json = json
+}
+
+// printErr prints an error, as a JSON object when set (see SetJSON).
+func (h *Handler) printErr(w io.Writer, err error) {
+	if h.json {
+		_ = jsonout.WriteError(w, err)
+		return
+	}
+	fmt.Fprintln(w, h.theme.Error.Wrap("error: "+err.Error()))
+}
+
+// notifyEvent returns the notification event of a statement, named after
+// the database alias, or the connection when not an alias.
+func (h *Handler) notifyEvent(sqlstr string, d time.Duration, rows int64, err error) notify.Event {
+	name := h.alias
+	if name == "" && h.u != nil {
+		name = h.u.Short()
+	}
+	return notify.Event{Name: name, SQL: sqlstr, Duration: d, Rows: rows, Err: err}
+}
+
+// SetKeepalive pings the connections to the database every interval, until
+// they are closed, including the connections reopened with \c.
+func (h *Handler) SetKeepalive(interval time.Duration) {
+	h.keepalive = interval
+	h.startKeepalive()
+}
+
+// startKeepalive starts pinging the connections to the database, stopping
+// the pings of the previous connections.
+func (h *Handler) startKeepalive() {
+	if h.stopKeepalive != nil {
+		h.stopKeepalive()
+		h.stopKeepalive = nil
+	}
+	if h.db != nil && h.keepalive > 0 {
+		var ctx context.Context
+		ctx, h.stopKeepalive = context.WithCancel(context.Background())
+		go conn.Keepalive(ctx, h.db, h.keepalive)
+	}
+}
+
+// SetOnConnect sets the statements executed on each connection to the
+// database opened next, such as the on_connect statements of its alias, and
+// to the same database when reopened with \c.
+func (h *Handler) SetOnConnect(stmts []string) {
+	h.onConnect, h.onConnectDB = stmts, ""
+}
+
+// initStatements returns the statements executed on each connection to the
+// database of the URL (see SetOnConnect). The database is identified by the
+// driver, user, host and path of the URL, whatever its scheme alias and
+// parameters.
+func (h *Handler) initStatements(u *dburl.URL) []string {
+	var user string
+	if u.User != nil {
+		user = u.User.Username()
+	}
+	switch db := u.Driver + ":" + user + "@" + u.Host + u.Path + u.Opaque; {
+	case h.onConnectDB == "":
+		h.onConnectDB = db
+	case h.onConnectDB != db:
+		return nil
+	}
+	return h.onConnect
+}
+
+// SetConfigReloader sets the func reloading the config file (\reload).
+func (h *Handler) SetConfigReloader(f func() error) {
+	h.reloadConfig = f
+}
+
+// ReloadConfig reloads the config file.
+func (h *Handler) ReloadConfig() error {
+	if h.reloadConfig == nil {
+		return text.ErrNoConfigFile
+	}
+	return h.reloadConfig()
+}
+
+// ConfigChanged reloads the config file before the next prompt, as \reload.
+// It is safe to call from other goroutines, such as a watcher of the config
+// file.
+func (h *Handler) ConfigChanged() {
+	select {
+	case h.configChanged <- struct{}{}:
+	default:
+	}
+}
+
+// reloadChanged reloads the config file when changed, writing the error to w.
+func (h *Handler) reloadChanged(w io.Writer) {
+	select {
+	case <-h.configChanged:
+		if err := h.ReloadConfig(); err != nil {
+			h.printErr(w, fmt.Errorf("config file: %w", redact.Error(err)))
+		}
+	default:
+	}
+}
+
 // outputHighlighter returns s as a highlighted string, based on the current
 // buffer and syntax highlighting settings.
 func (h *Handler) outputHighlighter(s string) string {
@@ -234,10 +612,14 @@ func (h *Handler) Run() error {
 		var execute bool
 		// set prompt
 		if iactive {
-			h.l.Prompt(h.Prompt(env.Get("PROMPT1")))
+			h.reportJobs(stderr)
+			if h.buf.Len == 0 {
+				h.reloadChanged(stderr)
+			}
+			h.l.Prompt(h.promptColor().Wrap(h.Prompt(env.Get("PROMPT1"))))
 		}
 		// read next statement/command
-		cmd, paramstr, err := h.buf.Next(env.Unquote(h.user, false, env.All()))
+		cmd, paramstr, err := h.buf.Next(h.unquote())
 		switch {
 		case h.singleLineMode && err == nil:
 			execute = h.buf.Len != 0
@@ -259,12 +641,14 @@ func (h *Handler) Run() error {
 			if err != nil {
 				lastErr = WrapErr(cmd, err)
 				switch {
+				case h.json:
+					h.printErr(stderr, fmt.Errorf(`\%s: %w`, cmd, err))
 				case err == text.ErrUnknownCommand:
 					fmt.Fprintln(stderr, fmt.Sprintf(text.InvalidCommand, cmd))
 				case err == text.ErrMissingRequiredArgument:
 					fmt.Fprintln(stderr, fmt.Sprintf(text.MissingRequiredArg, cmd))
 				default:
-					fmt.Fprintln(stderr, "error:", err)
+					h.printErr(stderr, err)
 				}
 				continue
 			}
@@ -272,7 +656,7 @@ func (h *Handler) Run() error {
 			opt, err = r.Run(h)
 			if err != nil && err != rline.ErrInterrupt {
 				lastErr = WrapErr(cmd, err)
-				fmt.Fprintln(stderr, "error:", err)
+				h.printErr(stderr, err)
 				continue
 			}
 			// print unused command parameters
@@ -281,7 +665,7 @@ func (h *Handler) Run() error {
 					return true, s, nil
 				})
 				if err != nil {
-					fmt.Fprintln(stderr, "error:", err)
+					h.printErr(stderr, err)
 				}
 				if !ok {
 					break
@@ -328,13 +712,13 @@ func (h *Handler) Run() error {
 				case h.batch && batch:
 					err = fmt.Errorf("cannot perform %s in existing batch", typ)
 					lastErr = WrapErr(h.buf.String(), err)
-					fmt.Fprintln(stderr, "error:", err)
+					h.printErr(stderr, err)
 					continue
 				// cannot use \g* while accumulating statements for batch queries
 				case h.batch && typ != h.batchEnd && opt.Exec != metacmd.ExecNone:
 					err = errors.New("cannot force batch execution")
 					lastErr = WrapErr(h.buf.String(), err)
-					fmt.Fprintln(stderr, "error:", err)
+					h.printErr(stderr, err)
 					continue
 				case batch:
 					h.batch, h.batchEnd = true, end
@@ -373,12 +757,21 @@ func (h *Handler) Run() error {
 				if h.out != nil {
 					out = h.out
 				}
+				if len(opt.Args) == 0 {
+					opt.Args = h.args
+				}
 				ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+				if !h.deadline.IsZero() {
+					var cancel context.CancelFunc
+					ctx, cancel = context.WithDeadline(ctx, h.deadline)
+					stopSignal := stop
+					stop = func() { cancel(); stopSignal() }
+				}
 				if err = h.Execute(ctx, out, opt, h.lastPrefix, h.last, forceBatch); err != nil {
 					lastErr = WrapErr(h.last, err)
 					if env.All()["ON_ERROR_STOP"] == "on" {
 						if iactive {
-							fmt.Fprintln(stderr, "error:", err)
+							h.printErr(stderr, err)
 							h.buf.Reset([]rune{}) // empty the buffer so no other statements are run
 							continue
 						} else {
@@ -386,7 +779,7 @@ func (h *Handler) Run() error {
 							return err
 						}
 					} else {
-						fmt.Fprintln(stderr, "error:", err)
+						h.printErr(stderr, err)
 					}
 				}
 				stop()
@@ -397,14 +790,42 @@ func (h *Handler) Run() error {
 
 // Execute executes a query against the connected database.
 func (h *Handler) Execute(ctx context.Context, w io.Writer, opt metacmd.Option, prefix, sqlstr string, forceTrans bool) error {
+	if opt.Alias != "" {
+		return h.setAliasVars(ctx, opt.Alias, opt.Params["prefix"], sqlstr)
+	}
 	if h.db == nil {
-		return text.ErrNotConnected
+		return h.errNotConnected()
+	}
+	rawPrefix, rawSQL := prefix, sqlstr
+	prefix, sqlstr, err := h.prepareStatement(prefix, sqlstr)
+	if err != nil {
+		return err
+	}
+	if err := h.checkDryRun(prefix); err != nil {
+		return err
 	}
+	// the processed prefix of ROLLBACK TO statements is ROLLBACK
+	rollbackTo := isRollbackTo(prefix)
 	// determine type and pre process string
 	prefix, sqlstr, qtyp, err := drivers.Process(h.u, prefix, sqlstr)
 	if err != nil {
 		return drivers.WrapErr(h.u.Driver, err)
 	}
+	if opt.Exec == metacmd.ExecQuery {
+		qtyp = true
+	}
+	// fetch a page of the results, when paging
+	sqlstr = h.pageQuery(opt, rawPrefix, rawSQL, prefix, sqlstr, qtyp)
+	// show the estimated cost of interactive queries, the errors of the
+	// estimate being those of the query
+	if qtyp && opt.Exec != metacmd.ExecWatch && h.l.Interactive() {
+		if msg, err := drivers.Estimate(ctx, h.u, sqlstr); err == nil && msg != "" {
+			fmt.Fprintln(h.l.Stderr(), msg)
+		}
+	}
+	if opt.Alert != nil && opt.Alert.Action == alert.ActionWebhook && h.notifier == nil {
+		return text.ErrWatchAlertRequiresNotifier
+	}
 	// start a transaction if forced
 	if forceTrans {
 		if err = h.BeginTx(ctx, nil); err != nil {
@@ -419,20 +840,131 @@ func (h *Handler) Execute(ctx context.Context, w io.Writer, opt metacmd.Option,
 		f = h.execSet
 	case metacmd.ExecWatch:
 		f = h.execWatch
+	case metacmd.ExecChart:
+		f = h.execChart
+	}
+	finish := h.finishStatement(rawPrefix, rawSQL, sqlstr, opt.Args, qtyp, opt.Exec == metacmd.ExecWatch)
+	start := time.Now()
+	h.lastRows, h.lastCols = -1, nil
+	defer h.routeStatement(prefix)()
+	ctx, span := tracing.Start(ctx, "query", append(tracing.Attrs(h.u, h.alias, h.dbType), tracing.Operation(prefix))...)
+	execSQL := h.commentStatement(sqlstr)
+	// watched queries are executed repeatedly
+	var meta *resultMeta
+	if opt.Exec != metacmd.ExecWatch {
+		meta = h.startMeta(ctx)
+	}
+	err = f(ctx, w, opt, prefix, execSQL, qtyp)
+	// the alerts of watched queries are not errors of the database
+	alerted := jsonout.Code(err) == jsonout.CodeAlert
+	if !alerted {
+		err = drivers.WrapErr(h.u.Driver, err)
+	}
+	if err == nil {
+		h.addDryRun(qtyp)
+	}
+	if meta != nil {
+		if err := h.endMeta(ctx, w, meta, prefix, qtyp, err); err != nil {
+			fmt.Fprintln(h.l.Stderr(), "error: meta:", err)
+		}
 	}
-	if err = drivers.WrapErr(h.u.Driver, f(ctx, w, opt, prefix, sqlstr, qtyp)); err != nil {
-		if forceTrans {
+	if h.lastRows >= 0 {
+		span.SetAttributes(tracing.RowsKey.Int64(h.lastRows))
+	}
+	tracing.End(span, err)
+	finish(start, h.lastRows, h.lastCols, err)
+	if err != nil {
+		switch {
+		case forceTrans:
 			defer h.tx.Rollback()
 			h.tx = nil
+		case h.tx != nil && !alerted && drivers.AbortTxOnError(h.u):
+			h.txAborted = true
 		}
 		return err
 	}
+	if h.tx != nil && rollbackTo {
+		// the statements since the savepoint, including the failed one, were
+		// rolled back
+		h.txAborted = false
+	}
 	if forceTrans {
 		return h.Commit()
 	}
 	return nil
 }
 
+// prepareStatement rewrites the statement with the hooks, and checks it
+// against the policy and the confirmation policy, returning the prefix of the
+// rewritten statement and the statement.
+func (h *Handler) prepareStatement(prefix, sqlstr string) (string, string, error) {
+	s, err := h.hooks.PreQuery(sqlstr)
+	if err != nil {
+		return "", "", err
+	}
+	if s != sqlstr {
+		prefix, sqlstr = stmt.FindPrefix(s, true, true, true), s
+	}
+	if err := h.checkPolicy(h.u, sqlstr); err != nil {
+		return "", "", err
+	}
+	if err := h.confirmStatement(h.u, h.alias, sqlstr); err != nil {
+		return "", "", err
+	}
+	return prefix, sqlstr, nil
+}
+
+// commentStatement returns the statement prefixed with the query comment,
+// attributing it in the logs of the database.
+func (h *Handler) commentStatement(sqlstr string) string {
+	if h.comment == "" {
+		return sqlstr
+	}
+	return h.comment + " " + sqlstr
+}
+
+// finishStatement returns the func finishing the execution of the processed
+// statement sqlstr, of the raw statement entered: it logs, observes, audits
+// and notifies the execution, and adds the raw statement to the histories
+// and the recorded session. The func uses the settings of the handler when
+// finishStatement was called, so that background jobs can call it. Watched
+// statements are observed on every execution, and run until canceled, so
+// they are neither observed nor notified.
+func (h *Handler) finishStatement(rawPrefix, rawSQL, sqlstr string, args []interface{}, qtyp, watch bool) func(start time.Time, rows int64, cols []string, err error) {
+	u, alias, execSQL, stderr := h.u, h.alias, h.commentStatement(sqlstr), h.l.Stderr()
+	auditf, historyf, suggestf := h.auditor(), h.historian(), h.suggester()
+	hs, n, e, rec := h.hooks, h.notifier, h.notifyEvent(sqlstr, 0, -1, nil), h.recorder
+	return func(start time.Time, rows int64, cols []string, err error) {
+		d := time.Since(start)
+		logging.Debugf("%s: %s (%s, error: %v)", u.Driver, execSQL, d, err)
+		if !watch {
+			metrics.Observe(alias, d, err)
+		}
+		if auditf != nil {
+			auditf(sqlstr, start, rows, err)
+		}
+		if err := hs.PostQuery(sqlstr, rows, d, err); err != nil {
+			fmt.Fprintln(stderr, "error: hooks:", err)
+		}
+		if e.Duration, e.Rows, e.Err = d, rows, err; !watch && n.Long(d) {
+			if err := n.Notify(context.Background(), e); err != nil {
+				fmt.Fprintln(stderr, "error: notify:", err)
+			}
+		}
+		if historyf != nil {
+			historyf(rawSQL, start, rows, err)
+		}
+		if suggestf != nil {
+			suggestf(rawSQL, start, err)
+		}
+		if rec != nil {
+			if err := rec.Record(rawPrefix, rawSQL, args, qtyp, start, cols, rows, err); err != nil {
+				fmt.Fprintln(stderr, "error: record:", err)
+			}
+		}
+	}
+}
+
 // Reset resets the handler's query statement buffer.
 func (h *Handler) Reset(r []rune) {
 	h.buf.Reset(r)
@@ -516,7 +1048,7 @@ func (h *Handler) Reset(r []rune) {
 // To insert a percent sign into your prompt, write %%. The default prompts are
 // '%/%R%x%# ' for prompts 1 and 2, and '>> ' for prompt 3.
 func (h *Handler) Prompt(prompt string) string {
-	r, connected := []rune(prompt), h.db != nil
+	r, connected := []rune(prompt), h.db != nil || h.offline != nil
 	end := len(r)
 	var buf []byte
 	for i := 0; i < end; i++ {
@@ -528,6 +1060,9 @@ func (h *Handler) Prompt(prompt string) string {
 		case '%': // literal
 			buf = append(buf, '%')
 		case 'S': // short driver name
+			if h.offline != nil {
+				buf = append(buf, text.Offline...)
+			}
 			if connected {
 				buf = append(buf, dburl.ShortAlias(h.u.Scheme)+":"...)
 			} else {
@@ -624,7 +1159,17 @@ func (h *Handler) Prompt(prompt string) string {
 		// case 'p': // the process id of the connected backend -- never going to be supported
 		case 'R': // statement state
 			buf = append(buf, h.buf.State()...)
+		case 'r': // [r] or [w], the reader or writer host that served the last statement, when routing
+			if h.route != "" {
+				buf = append(buf, "["+h.route+"]"...)
+			}
 		case 'x': // empty when not in a transaction block, * in transaction block, ! in failed transaction block, or ? when indeterminate
+			switch {
+			case h.tx != nil && h.txAborted:
+				buf = append(buf, '!')
+			case h.tx != nil:
+				buf = append(buf, '*')
+			}
 		case 'l': // line number
 		case ':': // variable value
 		case '`': // value of the evaluated command
@@ -636,6 +1181,15 @@ func (h *Handler) Prompt(prompt string) string {
 	return string(buf)
 }
 
+// promptColor returns the color of the prompt: the color of the database, or
+// the color of the theme.
+func (h *Handler) promptColor() theme.Color {
+	if h.color != "" {
+		return h.color
+	}
+	return h.theme.Prompt
+}
+
 // IO returns the io for the handler.
 func (h *Handler) IO() rline.IO {
 	return h.l
@@ -653,8 +1207,13 @@ func (h *Handler) URL() *dburl.URL {
 
 // DB returns the sql.DB for the handler.
 func (h *Handler) DB() drivers.DB {
-	if h.tx != nil {
+	switch {
+	case h.tx != nil:
 		return h.tx
+	case h.meta != nil && h.meta.conn != nil:
+		return connDB{h.meta.conn}
+	case h.reading:
+		return h.reader
 	}
 	return h.db
 }
@@ -710,9 +1269,34 @@ func (h *Handler) Open(ctx context.Context, params ...string) error {
 		h.l.Completer(completer.NewDefaultCompleter(completer.WithConnStrings(connStrings)))
 		return nil
 	}
+	if h.offline != nil {
+		return text.ErrOffline
+	}
 	if h.tx != nil {
 		return text.ErrPreviousTransactionExists
 	}
+	// results are cached and limited only for the connection they were
+	// enabled for
+	h.cacheTTL, h.maxResultBytes = 0, 0
+	// statements are routed only for the connection they were routed for
+	h.closeReader()
+	h.closePrepared()
+	// columns are masked only for the connection they were set for
+	h.mask = nil
+	h.policy = nil
+	h.confirm = nil
+	h.hooks = nil
+	h.comment = ""
+	h.color = ""
+	// the alias is set again by the caller, when opening an alias
+	h.alias, h.role, h.dbType = "", "", ""
+	h.serverVersion = ""
+	// leave federated mode
+	if h.federated != "" {
+		if err := h.Close(); err != nil {
+			return err
+		}
+	}
 	if len(params) < 2 {
 		urlstr := params[0]
 		// parse dsn
@@ -744,7 +1328,12 @@ func (h *Handler) Open(ctx context.Context, params ...string) error {
 	}
 	// open connection
 	var err error
-	h.db, err = drivers.Open(h.u, h.GetOutput, h.IO().Stderr)
+	h.db, err = drivers.Open(h.u, h.GetOutput, func() io.Writer {
+		return noticeWriter{h}
+	}, h.initStatements(h.u)...)
+	if h.db != nil {
+		metrics.Track(h.db, "")
+	}
 	if err != nil && !drivers.IsPasswordErr(h.u, err) {
 		defer h.Close()
 		return err
@@ -754,6 +1343,7 @@ func (h *Handler) Open(ctx context.Context, params ...string) error {
 	// force error/check connection
 	if err == nil {
 		if err = drivers.Ping(ctx, h.u, h.db); err == nil {
+			h.startKeepalive()
 			h.l.Completer(drivers.NewCompleter(ctx, h.u, h.db, readerOpts(), completer.WithConnStrings(connStrings)))
 			return h.Version(ctx)
 		}
@@ -764,7 +1354,7 @@ func (h *Handler) Open(ctx context.Context, params ...string) error {
 		return err
 	}
 	// print the error
-	fmt.Fprintln(h.l.Stderr(), "error:", err)
+	h.printErr(h.l.Stderr(), err)
 	// otherwise, try to collect a password ...
 	dsn, err := h.Password(params[0])
 	if err != nil {
@@ -820,11 +1410,17 @@ func (h *Handler) connStrings() []string {
 func (h *Handler) forceParams(u *dburl.URL) {
 	// force driver parameters
 	drivers.ForceParams(u)
+	// identify the connection in the monitoring of the server
+	appName := h.appName
+	if appName == "" {
+		appName = config.DefaultApplicationName("")
+	}
+	drivers.SetApplicationName(u, appName)
 	// see if password entry is present
 	user, err := passfile.Match(u, h.user.HomeDir, text.PassfileName)
 	switch {
 	case err != nil:
-		fmt.Fprintln(h.l.Stderr(), "error:", err)
+		h.printErr(h.l.Stderr(), err)
 	case user != nil:
 		u.User = user
 	}
@@ -860,10 +1456,22 @@ func (h *Handler) Close() error {
 	if h.tx != nil {
 		return text.ErrPreviousTransactionExists
 	}
+	h.closeReader()
+	h.closePrepared()
 	if h.db != nil {
+		h.cancelJobs()
+		if h.stopKeepalive != nil {
+			h.stopKeepalive()
+			h.stopKeepalive = nil
+		}
+		metrics.Untrack(h.db)
 		err := h.db.Close()
 		drv := h.u.Driver
 		h.db, h.u = nil, nil
+		if h.federated != "" {
+			os.RemoveAll(h.federated)
+			h.federated = ""
+		}
 		return drivers.WrapErr(drv, err)
 	}
 	return nil
@@ -959,12 +1567,13 @@ func (h *Handler) ChangePassword(user string) (string, error) {
 }
 
 // Version prints the database version information after a successful connection.
+// It is not printed in JSON mode, keeping the output parsable.
 func (h *Handler) Version(ctx context.Context) error {
-	if env.Get("SHOW_HOST_INFORMATION") != "true" {
+	if env.Get("SHOW_HOST_INFORMATION") != "true" || h.json {
 		return nil
 	}
 	if h.db == nil {
-		return text.ErrNotConnected
+		return h.errNotConnected()
 	}
 	ver, err := drivers.Version(ctx, h.u, h.DB())
 	if err != nil {
@@ -991,9 +1600,17 @@ func (h *Handler) execWatch(ctx context.Context, w io.Writer, opt metacmd.Option
 		// fmt.Fprintf(w, "%s (every %fs)\n\n", time.Now().Format("Mon Jan 2006 3:04:05 PM MST"), float64(opt.Watch)/float64(time.Second))
 		fmt.Fprintf(w, "%s (every %v)\n", time.Now().Format(time.RFC1123), opt.Watch)
 		fmt.Fprintln(w)
-		if err := h.execSingle(ctx, w, opt, prefix, sqlstr, qtyp); err != nil {
+		start, res := time.Now(), h.results[1]
+		err := h.execSingle(ctx, w, opt, prefix, sqlstr, qtyp)
+		metrics.Observe(h.alias, time.Since(start), err)
+		if err != nil {
 			return err
 		}
+		if opt.Alert != nil && h.results[1] != res {
+			if err := h.checkAlert(ctx, opt.Alert, sqlstr, h.results[1], time.Since(start)); err != nil {
+				return err
+			}
+		}
 		select {
 		case <-ctx.Done():
 			if err := ctx.Err(); err != nil && !errors.Is(err, context.Canceled) {
@@ -1009,7 +1626,10 @@ func (h *Handler) execWatch(ctx context.Context, w io.Writer, opt metacmd.Option
 func (h *Handler) execSingle(ctx context.Context, w io.Writer, opt metacmd.Option, prefix, sqlstr string, qtyp bool) error {
 	// exec or query
 	f := h.exec
-	if qtyp {
+	switch {
+	case h.copyToStdout(prefix, sqlstr):
+		f = h.copyTo
+	case qtyp:
 		f = h.query
 	}
 	// exec
@@ -1019,12 +1639,19 @@ func (h *Handler) execSingle(ctx context.Context, w io.Writer, opt metacmd.Optio
 // execSet executes a SQL query, setting all returned columns as variables.
 func (h *Handler) execSet(ctx context.Context, w io.Writer, opt metacmd.Option, prefix, sqlstr string, _ bool) error {
 	// query
-	rows, err := h.DB().QueryContext(ctx, sqlstr)
+	rows, err := h.DB().QueryContext(ctx, sqlstr, opt.Args...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
+	return setVars(h.u, rows, opt.Params["prefix"])
+}
+
+// setVars sets the columns of the single row of rows, of a database of the
+// URL, as variables named with the prefix.
+func setVars(u *dburl.URL, rows *sql.Rows, prefix string) error {
 	// get cols
-	cols, err := drivers.Columns(h.u, rows)
+	cols, err := drivers.Columns(u, rows)
 	if err != nil {
 		return err
 	}
@@ -1034,19 +1661,25 @@ func (h *Handler) execSet(ctx context.Context, w io.Writer, opt metacmd.Option,
 	clen, tfmt := len(cols), env.GoTime()
 	for rows.Next() {
 		if i == 0 {
-			row, err = h.scan(rows, clen, tfmt)
+			row, err = scan(u, rows, clen, tfmt)
 			if err != nil {
 				return err
 			}
 		}
 		i++
 	}
-	if i > 1 {
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	switch {
+	case i == 0:
+		return text.ErrNoRows
+	case i > 1:
 		return text.ErrTooManyRows
 	}
 	// set vars
 	for i, c := range cols {
-		n := opt.Params["prefix"] + c
+		n := prefix + c
 		if err = env.ValidIdentifier(n); err != nil {
 			return fmt.Errorf(text.CouldNotSetVariable, n)
 		}
@@ -1057,12 +1690,13 @@ func (h *Handler) execSet(ctx context.Context, w io.Writer, opt metacmd.Option,
 
 // execExec executes a query and re-executes all columns of all rows as if they
 // were their own queries.
-func (h *Handler) execExec(ctx context.Context, w io.Writer, _ metacmd.Option, prefix, sqlstr string, qtyp bool) error {
+func (h *Handler) execExec(ctx context.Context, w io.Writer, opt metacmd.Option, prefix, sqlstr string, qtyp bool) error {
 	// query
-	rows, err := h.DB().QueryContext(ctx, sqlstr)
+	rows, err := h.DB().QueryContext(ctx, sqlstr, opt.Args...)
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 	// execRows
 	if err := h.execRows(ctx, w, rows); err != nil {
 		return err
@@ -1079,12 +1713,31 @@ func (h *Handler) execExec(ctx context.Context, w io.Writer, _ metacmd.Option, p
 // query executes a query against the database.
 func (h *Handler) query(ctx context.Context, w io.Writer, opt metacmd.Option, typ, sqlstr string) error {
 	start := time.Now()
-	// run query
-	rows, err := h.DB().QueryContext(ctx, sqlstr)
-	if err != nil {
-		return err
+	// run query, unless its results are cached
+	key := h.cacheKey(opt, typ, sqlstr)
+	cached := h.cachedResult(key)
+	var rows *sql.Rows
+	var cur *cursor.Rows
+	var err error
+	if cached == nil {
+		// large results written to files are read with a cursor
+		if cur, err = h.openCursor(ctx, opt, typ, sqlstr); err != nil {
+			return err
+		}
+		if cur != nil {
+			defer cur.Close()
+		} else {
+			if opt.Stmt != nil {
+				rows, err = h.preparedStmt(ctx, opt).QueryContext(ctx, opt.Args...)
+			} else {
+				rows, err = h.DB().QueryContext(ctx, sqlstr, opt.Args...)
+			}
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+		}
 	}
-	defer rows.Close()
 	params := env.Pall()
 	params["time"] = env.GoTime()
 	for k, v := range opt.Params {
@@ -1111,9 +1764,107 @@ func (h *Handler) query(ctx context.Context, w io.Writer, opt metacmd.Option, ty
 	} else if opt.Exec != metacmd.ExecWatch {
 		params["pager_cmd"] = env.All()["PAGER"]
 	}
+	// report the progress of the results written to a file
+	prog := h.exportProgress(w)
+	defer prog.Done()
+	// binary formats are written directly to the output file
+	if format := binaryFormat(params["format"], w); format != "" {
+		if f, ok := w.(*os.File); ok && isatty.IsTerminal(f.Fd()) {
+			return text.ErrOutputFileRequired
+		}
+		var r interface {
+			export.Rows
+			tblfmt.ResultSet
+		} = rows
+		if cur != nil {
+			r = cur
+		}
+		if len(h.mask) != 0 {
+			r = mask.New(r, h.mask)
+		}
+		limiter := h.newByteLimiter(r)
+		if limiter != nil {
+			r = limiter
+		}
+		if prog != nil {
+			r = &progressRows{ResultSet: r, p: prog}
+		}
+		if err := h.writeBinary(format, w, pipe != nil, r, params); err != nil {
+			return err
+		}
+		h.warnExceeded(limiter)
+		if pipe != nil {
+			pipe.Close()
+			if cmd != nil {
+				env.SetShellResult(cmd.Wait())
+			}
+		}
+		return nil
+	}
+	// the inserts format writes the rows as INSERT statements
+	if params["format"] == "inserts" {
+		var r interface {
+			export.Rows
+			tblfmt.ResultSet
+		} = rows
+		if cur != nil {
+			r = cur
+		}
+		if len(h.mask) != 0 {
+			r = mask.New(r, h.mask)
+		}
+		limiter := h.newByteLimiter(r)
+		if limiter != nil {
+			r = limiter
+		}
+		if prog != nil {
+			r = &progressRows{ResultSet: r, p: prog}
+		}
+		if err := h.writeInserts(w, r, sqlstr, params); err != nil {
+			return err
+		}
+		h.warnExceeded(limiter)
+		if pipe != nil {
+			pipe.Close()
+			if cmd != nil {
+				env.SetShellResult(cmd.Wait())
+			}
+		}
+		return nil
+	}
 	useColumnTypes := drivers.UseColumnTypes(h.u)
+	var resultSet tblfmt.ResultSet = rows
+	var rec *cache.Recorder
+	switch {
+	case cached != nil:
+		resultSet = cached.ResultSet()
+	case cur != nil:
+		resultSet = cur
+	default:
+		// recorded for the result cache and \diff
+		rec = cache.NewRecorder(rows)
+		resultSet = rec
+	}
+	// stop fetching the results once they exceed max_result_bytes
+	var limiter *byteLimiter
+	if cached == nil {
+		if limiter = h.newByteLimiter(resultSet); limiter != nil {
+			resultSet = limiter
+		}
+	}
+	// summarize binary values, or write them to files, while their column
+	// types are known
+	if mode := params["binary"]; mode == "summary" || mode == "files" {
+		resultSet, useColumnTypes = blob.New(resultSet, params["binary_dir"], mode == "files"), false
+	}
+	// limit the rows to the page, when paging
+	var pageRS *pageResultSet
+	if h.page != nil {
+		h.page.more = false
+		pageRS = &pageResultSet{ResultSet: resultSet, p: h.page}
+		resultSet = pageRS
+	}
 	// wrap query with crosstab
-	resultSet := tblfmt.ResultSet(rows)
 	if opt.Exec == metacmd.ExecCrosstab {
 		var err error
 		resultSet, err = tblfmt.NewCrosstabView(rows, tblfmt.WithParams(opt.Crosstab...), tblfmt.WithUseColumnTypes(useColumnTypes))
@@ -1122,26 +1873,70 @@ func (h *Handler) query(ctx context.Context, w io.Writer, opt metacmd.Option, ty
 		}
 		useColumnTypes = false
 	}
+	// format rows after caching them, as scanned to generic values
+	if h.hooks.Has(hooks.FormatRow) {
+		resultSet, useColumnTypes = h.hooks.NewResultSet(resultSet), false
+	}
+	// mask values after caching and formatting them
+	if len(h.mask) != 0 {
+		resultSet, useColumnTypes = mask.New(resultSet, h.mask), false
+	}
+	// prepare the values for their display in text tables, once masked
+	if textTable(params) {
+		maxWidth, _ := strconv.Atoi(params["max_col_width"])
+		marker := display.ASCIIMarker
+		if params["linestyle"] == "unicode" {
+			marker = display.Marker
+		}
+		resultSet = display.New(resultSet, maxWidth, marker)
+	}
+	if prog != nil {
+		resultSet = &progressRows{ResultSet: resultSet, p: prog}
+	}
+	// color the tables written to the terminal, unless paged
+	var opts []tblfmt.Option
+	if (h.theme.Header != "" || h.theme.Null != "") && params["format"] == "aligned" && w == h.l.Stdout() && (params["pager"] == "off" || params["pager_cmd"] == "") {
+		opts = append(opts, h.themeFormatter(params))
+	}
 	if drivers.LowerColumnNames(h.u) {
 		params["lower_column_names"] = "true"
 	}
 	if useColumnTypes {
 		params["use_column_types"] = "true"
 	}
+	// count rows for the audit log, spans, session recording, hooks,
+	// notifications and result metadata
+	if h.audit != nil || h.recorder != nil || h.hooks.Has(hooks.PostQuery) || h.notifier != nil || h.meta != nil || tracing.Enabled() {
+		rc := &rowCounter{ResultSet: resultSet}
+		defer func() { h.lastRows, h.lastCols = rc.n, rc.cols }()
+		resultSet = rc
+	}
 	// encode and handle error conditions
-	switch err := tblfmt.EncodeAll(w, resultSet, params); {
+	switch err := encodeAll(w, resultSet, params, opts...); {
 	case err != nil && cmd != nil && errors.Is(err, syscall.EPIPE):
 		// broken pipe means pager quit before consuming all data, which might be expected
 		return nil
-	case err != nil && h.u.Driver == "sqlserver" && err == tblfmt.ErrResultSetHasNoColumns && strings.HasPrefix(typ, "EXEC"):
-		// sqlserver EXEC statements sometimes do not have results, fake that
-		// it was executed as a exec and not a query
+	case err != nil && err == tblfmt.ErrResultSetHasNoColumns && (opt.Exec == metacmd.ExecQuery || h.u.Driver == "sqlserver" && strings.HasPrefix(typ, "EXEC")):
+		// sqlserver EXEC statements and called procedures sometimes do not
+		// have results, fake that it was executed as a exec and not a query
 		fmt.Fprintln(w, typ)
 	case err != nil:
 		return err
 	case params["format"] == "aligned":
 		fmt.Fprintln(w)
 	}
+	res := cached
+	if rec != nil {
+		res = rec.Result()
+	}
+	if rec != nil && key != "" && !limiter.exceeded() {
+		h.cacheResult(key, res)
+	}
+	h.warnExceeded(limiter)
+	h.results = [2]*cache.Result{h.results[1], res}
+	if pageRS != nil {
+		h.printPage(pageRS.n)
+	}
 	if h.timing {
 		d := time.Since(start)
 		format := text.TimingDesc
@@ -1150,17 +1945,135 @@ func (h *Handler) query(ctx context.Context, w io.Writer, opt metacmd.Option, ty
 			format += " (%v)"
 			v = append(v, d.Round(1*time.Millisecond))
 		}
+		if cached != nil {
+			format += " " + text.TimingCached
+		}
 		h.Print(format, v...)
 	}
 	if pipe != nil {
 		pipe.Close()
 		if cmd != nil {
-			cmd.Wait()
+			env.SetShellResult(cmd.Wait())
 		}
 	}
 	return err
 }
 
+// textTable returns true when the results are written as text tables.
+func textTable(params map[string]string) bool {
+	switch params["format"] {
+	case "aligned", "vertical":
+		return true
+	}
+	return false
+}
+
+// themeFormatter returns the option formatting the values of the aligned
+// tables as their default formatter, coloring their column names and NULL
+// values with the theme.
+func (h *Handler) themeFormatter(params map[string]string) tblfmt.Option {
+	timeFormat := params["time"]
+	if timeFormat == "" {
+		timeFormat = time.RFC3339
+	}
+	locale := params["locale"]
+	if locale == "" {
+		locale = "en-US"
+	}
+	numericLocale := params["numericlocale"] == "true" || params["numericlocale"] == "on"
+	f := tblfmt.NewEscapeFormatter(
+		tblfmt.WithHeaderAlign(tblfmt.AlignCenter),
+		tblfmt.WithTimeFormat(timeFormat),
+		tblfmt.WithNumericLocale(numericLocale, locale),
+	)
+	return tblfmt.WithFormatter(theme.NewFormatter(f, h.theme.Header, h.theme.Null, params["null"]))
+}
+
+// encodeAll encodes all the result sets to w. Tables are streamed in batches
+// of FETCH_COUNT rows, when not 0, instead of buffering all rows to compute
+// the widths of their columns, which grow with the following batches.
+//
+// The result sets following the first one, such as the results of stored
+// procedures, are numbered in aligned output, and written as an array of
+// arrays of objects in JSON. Result sets without columns, such as the status
+// of procedures, are skipped, and tblfmt.ErrResultSetHasNoColumns is returned
+// when no result set has columns. The extra options are applied after the
+// options of the params.
+func encodeAll(w io.Writer, resultSet tblfmt.ResultSet, params map[string]string, extra ...tblfmt.Option) error {
+	for {
+		cols, err := resultSet.Columns()
+		switch {
+		case err != nil:
+			return err
+		case len(cols) != 0:
+		case resultSet.NextResultSet():
+			continue
+		case resultSet.Err() != nil:
+			return resultSet.Err()
+		default:
+			return tblfmt.ErrResultSetHasNoColumns
+		}
+		break
+	}
+	f, opts := tblfmt.FromMap(params)
+	opts = append(opts, extra...)
+	if n, err := strconv.Atoi(env.Get("FETCH_COUNT")); err == nil && n > 0 {
+		opts = append(opts, tblfmt.WithCount(n))
+	}
+	enc, err := f(resultSet, opts...)
+	if err != nil {
+		return err
+	}
+	format := params["format"]
+	// the first result set is kept until known to be the only one, to wrap
+	// multiple JSON result sets in an array
+	var first bytes.Buffer
+	out := w
+	if format == "json" {
+		out = &first
+	}
+	if err := enc.Encode(out); err != nil {
+		return err
+	}
+	n := 1
+	for resultSet.NextResultSet() {
+		switch cols, err := resultSet.Columns(); {
+		case err != nil:
+			return err
+		case len(cols) == 0:
+			continue
+		}
+		n++
+		var sep string
+		switch {
+		case format == "json" && n == 2:
+			if _, err := fmt.Fprint(w, "[", first.String()); err != nil {
+				return err
+			}
+			sep = ",\n"
+		case format == "json":
+			sep = ",\n"
+		case format == "aligned":
+			sep = "\n" + fmt.Sprintf(text.ResultSetNumber, n) + "\n"
+		default:
+			sep = "\n"
+		}
+		if _, err := io.WriteString(w, sep); err != nil {
+			return err
+		}
+		if err := enc.Encode(w); err != nil {
+			return err
+		}
+	}
+	switch {
+	case format == "json" && n == 1:
+		_, err = fmt.Fprintln(w, first.String())
+	case format == "json":
+		_, err = fmt.Fprintln(w, "]")
+	}
+	return err
+}
+
 // execRows executes all the columns in the row.
 func (h *Handler) execRows(ctx context.Context, w io.Writer, rows *sql.Rows) error {
 	// get columns
@@ -1173,23 +2086,26 @@ func (h *Handler) execRows(ctx context.Context, w io.Writer, rows *sql.Rows) err
 	clen, tfmt := len(cols), env.GoTime()
 	for rows.Next() {
 		if clen != 0 {
-			row, err := h.scan(rows, clen, tfmt)
+			row, err := scan(h.u, rows, clen, tfmt)
 			if err != nil {
 				return err
 			}
-			// execute
+			// execute, skipping empty (and NULL) values
 			for _, sqlstr := range row {
+				if strings.TrimSpace(sqlstr) == "" {
+					continue
+				}
 				if err = h.Execute(ctx, w, res, stmt.FindPrefix(sqlstr, true, true, true), sqlstr, false); err != nil {
 					return err
 				}
 			}
 		}
 	}
-	return nil
+	return rows.Err()
 }
 
-// scan scans a row.
-func (h *Handler) scan(rows *sql.Rows, clen int, tfmt string) ([]string, error) {
+// scan scans a row of a database of the URL.
+func scan(u *dburl.URL, rows *sql.Rows, clen int, tfmt string) ([]string, error) {
 	// scan to []interface{}
 	r := make([]interface{}, clen)
 	for i := range r {
@@ -1199,7 +2115,7 @@ func (h *Handler) scan(rows *sql.Rows, clen int, tfmt string) ([]string, error)
 		return nil, err
 	}
 	// get conversion funcs
-	cb, cm, cs, cd := drivers.ConvertBytes(h.u), drivers.ConvertMap(h.u), drivers.ConvertSlice(h.u), drivers.ConvertDefault(h.u)
+	cb, cm, cs, cd := drivers.ConvertBytes(u), drivers.ConvertMap(u), drivers.ConvertSlice(u), drivers.ConvertDefault(u)
 	row := make([]string, clen)
 	for n, z := range r {
 		j := z.(*interface{})
@@ -1244,8 +2160,14 @@ func (h *Handler) scan(rows *sql.Rows, clen int, tfmt string) ([]string, error)
 }
 
 // exec does a database exec.
-func (h *Handler) exec(ctx context.Context, w io.Writer, _ metacmd.Option, typ, sqlstr string) error {
-	res, err := h.DB().ExecContext(ctx, sqlstr)
+func (h *Handler) exec(ctx context.Context, w io.Writer, opt metacmd.Option, typ, sqlstr string) error {
+	var res sql.Result
+	var err error
+	if opt.Stmt != nil {
+		res, err = h.preparedStmt(ctx, opt).ExecContext(ctx, opt.Args...)
+	} else {
+		res, err = h.DB().ExecContext(ctx, sqlstr, opt.Args...)
+	}
 	if err != nil {
 		_ = env.Set("ROW_COUNT", "0")
 		return err
@@ -1256,6 +2178,13 @@ func (h *Handler) exec(ctx context.Context, w io.Writer, _ metacmd.Option, typ,
 		_ = env.Set("ROW_COUNT", "0")
 		return err
 	}
+	h.lastRows = count
+	if h.json {
+		if err := jsonout.Write(w, jsonout.Result{Command: typ, RowsAffected: count}); err != nil {
+			return err
+		}
+		return env.Set("ROW_COUNT", strconv.FormatInt(count, 10))
+	}
 	// print name
 	fmt.Fprint(w, typ)
 	// print count
@@ -1274,29 +2203,36 @@ func (h *Handler) Begin(txOpts *sql.TxOptions) error {
 // Begin begins a transaction in a context.
 func (h *Handler) BeginTx(ctx context.Context, txOpts *sql.TxOptions) error {
 	if h.db == nil {
-		return text.ErrNotConnected
+		return h.errNotConnected()
 	}
 	if h.tx != nil {
 		return text.ErrPreviousTransactionExists
 	}
+	if !drivers.Caps(h.u).Transactions {
+		return fmt.Errorf(text.NotSupportedByDriver, "transactions", h.u.Driver)
+	}
 	var err error
 	h.tx, err = h.db.BeginTx(ctx, txOpts)
 	if err != nil {
 		return drivers.WrapErr(h.u.Driver, err)
 	}
+	h.txAborted = false
 	return nil
 }
 
 // Commit commits a transaction.
 func (h *Handler) Commit() error {
 	if h.db == nil {
-		return text.ErrNotConnected
+		return h.errNotConnected()
 	}
 	if h.tx == nil {
 		return text.ErrNoPreviousTransactionExists
 	}
+	if h.dryRun != nil {
+		return text.ErrDryRunEndsTransaction
+	}
 	tx := h.tx
-	h.tx = nil
+	h.tx, h.txAborted = nil, false
 	if err := tx.Commit(); err != nil {
 		return drivers.WrapErr(h.u.Driver, err)
 	}
@@ -1306,19 +2242,79 @@ func (h *Handler) Commit() error {
 // Rollback rollbacks a transaction.
 func (h *Handler) Rollback() error {
 	if h.db == nil {
-		return text.ErrNotConnected
+		return h.errNotConnected()
 	}
 	if h.tx == nil {
 		return text.ErrNoPreviousTransactionExists
 	}
+	if h.dryRun != nil {
+		return text.ErrDryRunEndsTransaction
+	}
 	tx := h.tx
-	h.tx = nil
+	h.tx, h.txAborted = nil, false
 	if err := tx.Rollback(); err != nil {
 		return drivers.WrapErr(h.u.Driver, err)
 	}
 	return nil
 }
 
+// Savepoint defines a new savepoint in the current transaction.
+func (h *Handler) Savepoint(name string) error {
+	return h.savepoint(drivers.SavepointCreate, name)
+}
+
+// RollbackTo rolls back the current transaction to a savepoint.
+func (h *Handler) RollbackTo(name string) error {
+	if err := h.savepoint(drivers.SavepointRollback, name); err != nil {
+		return err
+	}
+	h.txAborted = false
+	return nil
+}
+
+// isRollbackTo returns true when prefix is the prefix of a ROLLBACK TO
+// SAVEPOINT statement.
+func isRollbackTo(prefix string) bool {
+	words := strings.Fields(prefix)
+	if len(words) == 0 || words[0] != "ROLLBACK" {
+		return false
+	}
+	words = words[1:]
+	if len(words) != 0 && (words[0] == "WORK" || words[0] == "TRANSACTION") {
+		words = words[1:]
+	}
+	return len(words) != 0 && words[0] == "TO"
+}
+
+// ReleaseSavepoint releases a savepoint in the current transaction.
+func (h *Handler) ReleaseSavepoint(name string) error {
+	return h.savepoint(drivers.SavepointRelease, name)
+}
+
+// savepoint executes the savepoint query of type typ for the named savepoint.
+func (h *Handler) savepoint(typ drivers.SavepointType, name string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	if h.tx == nil {
+		return text.ErrNoPreviousTransactionExists
+	}
+	if !drivers.Caps(h.u).Savepoints {
+		return fmt.Errorf(text.NotSupportedByDriver, "savepoints", h.u.Driver)
+	}
+	if err := env.ValidIdentifier(name); err != nil {
+		return err
+	}
+	sqlstr, err := drivers.SavepointQuery(h.u, typ, name)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	if _, err := h.tx.Exec(sqlstr); err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	return nil
+}
+
 // Include includes the specified path.
 func (h *Handler) Include(path string, relative bool) error {
 	if relative && !filepath.IsAbs(path) {
@@ -1330,7 +2326,11 @@ func (h *Handler) Include(path string, relative bool) error {
 		return err
 	}
 	defer f.Close()
-	r := bufio.NewReader(f)
+	// skip the front matter, whose directives are read by usql runfile
+	r := bufio.NewReaderSize(f, frontmatter.MaxSize)
+	if err := frontmatter.Skip(r); err != nil {
+		return err
+	}
 	// setup rline
 	l := &rline.Rline{
 		N: func() ([]rune, error) {
@@ -1368,20 +2368,106 @@ func (h *Handler) Include(path string, relative bool) error {
 	}
 	p := New(l, h.user, filepath.Dir(path), h.nopw)
 	p.db, p.u = h.db, h.u
+	p.tx, p.txAborted, p.dryRun, p.deadline = h.tx, h.txAborted, h.dryRun, h.deadline
+	p.confirm, p.yes = h.confirm, h.yes
+	p.pool, p.poolRole = h.pool, h.poolRole
+	p.workbook, p.workbookOut = h.workbook, h.workbookOut
 	drivers.ConfigStmt(p.u, p.buf)
 	err = p.Run()
 	h.db, h.u = p.db, p.u
+	h.tx, h.txAborted = p.tx, p.txAborted
+	h.workbook, h.workbookOut = p.workbook, p.workbookOut
 	return err
 }
 
 // MetadataWriter loads the metadata writer for the
 func (h *Handler) MetadataWriter(ctx context.Context) (metadata.Writer, error) {
+	if h.offline != nil {
+		return metadata.NewDefaultWriter(h.offline.Reader())(nil, h.l.Stdout()), nil
+	}
 	if h.db == nil {
 		return nil, text.ErrNotConnected
 	}
 	return drivers.NewMetadataWriter(ctx, h.u, h.db, h.l.Stdout(), readerOpts()...)
 }
 
+// binaryFormat returns the binary output format (parquet, xlsx) for the
+// format param, or the file name of w. Returns an empty string for text
+// formats.
+func binaryFormat(format string, w io.Writer) string {
+	switch format {
+	case "parquet", "xlsx":
+		return format
+	}
+	if f, ok := w.(interface{ Name() string }); ok {
+		return binaryExt(f.Name())
+	}
+	return ""
+}
+
+// binaryExt returns the binary format of a file name's extension.
+func binaryExt(name string) string {
+	switch ext := strings.ToLower(filepath.Ext(name)); ext {
+	case ".parquet", ".xlsx":
+		return ext[1:]
+	}
+	return ""
+}
+
+// writeBinary writes rows to w in a binary format. Result sets written to
+// the same xlsx output are added to the same workbook as separate sheets,
+// until the output is changed or flushed, unless single is true.
+func (h *Handler) writeBinary(format string, w io.Writer, single bool, rows export.Rows, params map[string]string) error {
+	if format == "parquet" {
+		if _, err := export.Parquet(w, rows); err != nil {
+			return err
+		}
+		if moreResultSets(rows) {
+			return text.ErrMultipleResultSets
+		}
+		return nil
+	}
+	if single {
+		wb := export.NewWorkbook(w)
+		if _, err := wb.AddSheets(params["title"], rows); err != nil {
+			return err
+		}
+		return wb.Close()
+	}
+	if h.workbook != nil && h.workbookOut != w {
+		if err := h.Flush(); err != nil {
+			return err
+		}
+	}
+	if h.workbook == nil {
+		h.workbook, h.workbookOut = export.NewWorkbook(w), w
+	}
+	_, err := h.workbook.AddSheets(params["title"], rows)
+	return err
+}
+
+// moreResultSets returns true when rows have a following result set with
+// columns.
+func moreResultSets(rows export.Rows) bool {
+	rs, ok := rows.(interface{ NextResultSet() bool })
+	for ok && rs.NextResultSet() {
+		if cts, err := rows.ColumnTypes(); err != nil || len(cts) != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Flush finishes writing pending output, such as an open xlsx workbook.
+func (h *Handler) Flush() error {
+	if h.workbook == nil {
+		return nil
+	}
+	err := h.workbook.Close()
+	h.workbook, h.workbookOut = nil, nil
+	return err
+}
+
 // GetOutput gets the output writer.
 func (h *Handler) GetOutput() io.Writer {
 	if h.out == nil {
@@ -1392,6 +2478,9 @@ func (h *Handler) GetOutput() io.Writer {
 
 // SetOutput sets the output writer.
 func (h *Handler) SetOutput(o io.WriteCloser) {
+	if err := h.Flush(); err != nil {
+		h.printErr(h.l.Stderr(), err)
+	}
 	if h.out != nil {
 		h.out.Close()
 	}
@@ -0,0 +1,168 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xo/tblfmt"
+	"github.com/xo/usql/cache"
+	"github.com/xo/usql/text"
+)
+
+// resultSets are the result sets of cached results.
+type resultSets struct {
+	*cache.ResultSet
+	next []*cache.Result
+}
+
+// newResultSets creates the result sets of the results.
+func newResultSets(results ...*cache.Result) *resultSets {
+	return &resultSets{ResultSet: results[0].ResultSet(), next: results[1:]}
+}
+
+// NextResultSet advances to the next result.
+func (rs *resultSets) NextResultSet() bool {
+	if len(rs.next) == 0 {
+		return false
+	}
+	rs.ResultSet, rs.next = rs.next[0].ResultSet(), rs.next[1:]
+	return true
+}
+
+func TestEncodeAll(t *testing.T) {
+	a := &cache.Result{Columns: []string{"a"}, Rows: [][]cache.Value{{{V: int64(1)}}}}
+	b := &cache.Result{Columns: []string{"b"}, Rows: [][]cache.Value{{{V: "x"}}, {{V: "y"}}}}
+	empty := new(cache.Result)
+	tests := []struct {
+		results []*cache.Result
+		format  string
+		exp     string
+	}{
+		{[]*cache.Result{a, b}, "aligned", " a \n---\n 1 \n(1 row)\n\nResult set 2:\n b \n---\n x \n y \n(2 rows)\n"},
+		{[]*cache.Result{empty, a, empty, b}, "aligned", " a \n---\n 1 \n(1 row)\n\nResult set 2:\n b \n---\n x \n y \n(2 rows)\n"},
+		{[]*cache.Result{a}, "json", "[{\"a\":1}]\n"},
+		{[]*cache.Result{a, b}, "json", "[[{\"a\":1}],\n[{\"b\":\"x\"},{\"b\":\"y\"}]]\n"},
+		{[]*cache.Result{empty, a, empty, b}, "json", "[[{\"a\":1}],\n[{\"b\":\"x\"},{\"b\":\"y\"}]]\n"},
+		{[]*cache.Result{empty, a}, "json", "[{\"a\":1}]\n"},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		if err := encodeAll(&buf, newResultSets(test.results...), map[string]string{"format": test.format}); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if s := buf.String(); s != test.exp {
+			t.Errorf("test %d expected:\n%q\ngot:\n%q", i, test.exp, s)
+		}
+	}
+	// result sets without columns
+	var buf bytes.Buffer
+	if err := encodeAll(&buf, newResultSets(empty, empty), map[string]string{"format": "aligned"}); err != tblfmt.ErrResultSetHasNoColumns {
+		t.Errorf("expected error %v, got: %v", tblfmt.ErrResultSetHasNoColumns, err)
+	}
+}
+
+func TestPrepare(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	ctx := context.Background()
+	execute(t, h, "CREATE TABLE t (a int, b text)")
+	tests := []struct {
+		f   func() error
+		exp string
+	}{
+		{func() error { return h.Prepare(ctx, "ins AS INSERT INTO t VALUES (?, ?)") }, "PREPARE\n"},
+		{func() error { return h.ExecutePrepared(ctx, "ins", []string{"1", "one"}) }, "INSERT 1\n"},
+		{func() error { return h.ExecutePrepared(ctx, "ins", []string{"2", "two"}) }, "INSERT 1\n"},
+		{func() error { return h.Prepare(ctx, "sel AS SELECT b FROM t WHERE a = ?") }, "PREPARE\n"},
+		{func() error { return h.ExecutePrepared(ctx, "sel", []string{"2"}) }, "  b  \n-----\n two \n(1 row)\n\n"},
+		// the statements are executed in the transaction
+		{func() error { return h.Begin(nil) }, ""},
+		{func() error { return h.ExecutePrepared(ctx, "ins", []string{"3", "three"}) }, "INSERT 1\n"},
+		{func() error { return h.Rollback() }, ""},
+		{func() error { return h.ExecutePrepared(ctx, "sel", []string{"3"}) }, "(0 rows)\n\n"},
+		{func() error { return h.Prepare(ctx, "") }, "ins AS INSERT INTO t VALUES (?, ?)\nsel AS SELECT b FROM t WHERE a = ?\n"},
+	}
+	for i, test := range tests {
+		stdout.Reset()
+		if err := test.f(); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if s := stdout.String(); s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+	}
+	if err := h.Prepare(ctx, "ins"); err != text.ErrInvalidPrepare {
+		t.Errorf("expected error %v, got: %v", text.ErrInvalidPrepare, err)
+	}
+	if err := h.ExecutePrepared(ctx, "upd", nil); err == nil || err.Error() != fmt.Sprintf(text.NoSuchPrepared, "upd") {
+		t.Errorf("expected no such prepared statement error, got: %v", err)
+	}
+	if err := h.ExecutePrepared(ctx, "ins", []string{"4"}); err == nil {
+		t.Errorf("expected error for missing argument, got nil")
+	}
+	// the args are bound to the statements of the commands (--param)
+	stdout.Reset()
+	h.SetSingleLineMode(true)
+	h.SetArgs([]interface{}{"1"})
+	h.Reset([]rune("SELECT b FROM t WHERE a = ?"))
+	if err := h.Run(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s := stdout.String(); !strings.Contains(s, " one \n(1 row)") {
+		t.Errorf("expected the bound row, got: %q", s)
+	}
+}
+
+func TestPage(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	ctx := context.Background()
+	execute(t, h, "CREATE TABLE t (a int)")
+	execute(t, h, "INSERT INTO t VALUES (1), (2), (3), (4), (5)")
+	h.SetPageSize(2)
+	stdout.Reset()
+	if s, exp := execute(t, h, "SELECT a FROM t ORDER BY a"), " a \n---\n 1 \n 2 \n(2 rows)\n\n"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	if s, exp := stdout.String(), "Page 1, rows 1 to 2.\nUse \\next for the next page.\n"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	tests := []struct {
+		delta int
+		err   error
+		exp   string
+	}{
+		{-1, text.ErrFirstPage, ""},
+		{1, nil, " a \n---\n 3 \n 4 \n(2 rows)\n\nPage 2, rows 3 to 4.\nUse \\next or \\prev for the next or previous page.\n"},
+		{1, nil, " a \n---\n 5 \n(1 row)\n\nPage 3, rows 5 to 5.\nUse \\prev for the previous page.\n"},
+		{1, text.ErrLastPage, ""},
+		{-2, nil, " a \n---\n 1 \n 2 \n(2 rows)\n\nPage 1, rows 1 to 2.\nUse \\next for the next page.\n"},
+	}
+	for i, test := range tests {
+		stdout.Reset()
+		if err := h.Page(ctx, test.delta); err != test.err {
+			t.Fatalf("test %d expected error %v, got: %v", i, test.err, err)
+		}
+		if s := stdout.String(); s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+	}
+	// the results fitting a page, and the statements not returning rows, are
+	// not paged
+	stdout.Reset()
+	if s, exp := execute(t, h, "SELECT a FROM t WHERE a = 1"), " a \n---\n 1 \n(1 row)\n\n"; s != exp || stdout.Len() != 0 {
+		t.Errorf("expected %q, got: %q %q", exp, s, stdout.String())
+	}
+	execute(t, h, "DELETE FROM t WHERE a = 1")
+	if err := h.Page(ctx, 1); err != text.ErrNoPagedQuery {
+		t.Errorf("expected error %v, got: %v", text.ErrNoPagedQuery, err)
+	}
+	// without paging, all the rows are shown
+	h.SetPageSize(0)
+	if s, exp := execute(t, h, "SELECT a FROM t ORDER BY a"), " a \n---\n 2 \n 3 \n 4 \n 5 \n(4 rows)\n\n"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/xo/usql/audit"
+	"github.com/xo/usql/history"
+	"github.com/xo/usql/redact"
+)
+
+// historyTimeout is the timeout of adding a statement to the shared query
+// history.
+const historyTimeout = 5 * time.Second
+
+// SetHistory sets the shared query history the executed statements are added
+// to.
+func (h *Handler) SetHistory(s *history.Store) {
+	h.history = s
+}
+
+// historian returns the func adding the executed raw statements to the
+// shared query history, or nil when not set. rows is the number of rows
+// returned or affected by a statement, or -1 when unknown.
+func (h *Handler) historian() func(sqlstr string, start time.Time, rows int64, err error) {
+	if h.history == nil {
+		return nil
+	}
+	s, stderr := h.history, h.l.Stderr()
+	e := history.Entry{
+		OSUser: h.user.Username,
+		Alias:  h.alias,
+		Role:   h.role,
+	}
+	return func(sqlstr string, start time.Time, rows int64, err error) {
+		e := e
+		e.Time, e.Statement, e.Duration = start, redact.String(sqlstr), audit.Duration(time.Since(start))
+		if rows >= 0 {
+			e.Rows = &rows
+		}
+		if err != nil {
+			e.Error = redact.String(err.Error())
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), historyTimeout)
+		defer cancel()
+		if err := s.Add(ctx, e); err != nil {
+			fmt.Fprintln(stderr, "error: history backend:", err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/export"
+)
+
+// fromRE matches the first table of the FROM clause of a query.
+var fromRE = regexp.MustCompile("(?i)\\bFROM\\s+([\\w.$]+|\"[^\"]+\"|`[^`]+`)")
+
+// writeInserts writes rows as INSERT statements into the insert_table param,
+// or the first table of the FROM clause of the query when not set, of
+// insert_batch rows each, quoting literals as the database's dialect.
+func (h *Handler) writeInserts(w io.Writer, rows export.Rows, sqlstr string, params map[string]string) error {
+	table := params["insert_table"]
+	if table == "" {
+		table = "results"
+		if m := fromRE.FindStringSubmatch(sqlstr); m != nil {
+			table = strings.Trim(m[1], "\"`")
+		}
+	}
+	batch, _ := strconv.Atoi(params["insert_batch"])
+	_, err := dump.DialectFor(drivers.Caps(h.u).Dialect).Inserts(w, rows, table, batch)
+	return err
+}
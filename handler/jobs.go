@@ -0,0 +1,289 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xo/tblfmt"
+	"github.com/xo/usql/blob"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/hooks"
+	"github.com/xo/usql/mask"
+	"github.com/xo/usql/stmt"
+	"github.com/xo/usql/text"
+	"github.com/xo/usql/tracing"
+)
+
+// job is a query executing in the background.
+type job struct {
+	id       int
+	sqlstr   string
+	start    time.Time
+	end      time.Time
+	cancel   context.CancelFunc
+	done     chan struct{}
+	buf      bytes.Buffer
+	err      error
+	reported bool
+}
+
+// status returns the job's status.
+func (j *job) status() string {
+	select {
+	case <-j.done:
+	default:
+		return "running"
+	}
+	switch {
+	case errors.Is(j.err, context.Canceled):
+		return "canceled"
+	case j.err != nil:
+		return "failed"
+	}
+	return "done"
+}
+
+// duration returns how long the job has been (or was) running.
+func (j *job) duration() time.Duration {
+	select {
+	case <-j.done:
+		return j.end.Sub(j.start).Round(time.Millisecond)
+	default:
+	}
+	return time.Since(j.start).Round(time.Millisecond)
+}
+
+// jobs are the background jobs of a handler.
+type jobs struct {
+	sync.Mutex
+	last int
+	m    map[int]*job
+}
+
+// get returns the job with id.
+func (js *jobs) get(id int) (*job, error) {
+	js.Lock()
+	defer js.Unlock()
+	j, ok := js.m[id]
+	if !ok {
+		return nil, fmt.Errorf(text.NoSuchJob, id)
+	}
+	return j, nil
+}
+
+// Background executes a query in the background on a separate connection
+// from the connection pool, returning the id of the started job. Results are
+// buffered until displayed with Result.
+func (h *Handler) Background(sqlstr string) (int, error) {
+	switch {
+	case h.db == nil:
+		return 0, h.errNotConnected()
+	case h.tx != nil:
+		// jobs are executed on another connection than the transaction's
+		return 0, text.ErrBackgroundInTransaction
+	}
+	sqlstr = strings.TrimSpace(sqlstr)
+	if sqlstr == "" {
+		return 0, text.ErrMissingRequiredArgument
+	}
+	rawPrefix, rawSQL := stmt.FindPrefix(sqlstr, true, true, true), sqlstr
+	prefix, sqlstr, err := h.prepareStatement(rawPrefix, sqlstr)
+	if err != nil {
+		return 0, err
+	}
+	prefix, sqlstr, qtyp, err := drivers.Process(h.u, prefix, sqlstr)
+	if err != nil {
+		return 0, drivers.WrapErr(h.u.Driver, err)
+	}
+	// snapshot the formatting params, as env is not safe for concurrent use
+	params := env.Pall()
+	params["time"] = env.GoTime()
+	if params["expanded"] == "auto" && params["columns"] == "" {
+		params["expanded"] = "off"
+	}
+	if drivers.LowerColumnNames(h.u) {
+		params["lower_column_names"] = "true"
+	}
+	binary := params["binary"] == "summary" || params["binary"] == "files"
+	if drivers.UseColumnTypes(h.u) && len(h.mask) == 0 && !h.hooks.Has(hooks.FormatRow) && !binary {
+		params["use_column_types"] = "true"
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	h.jobs.Lock()
+	if h.jobs.m == nil {
+		h.jobs.m = make(map[int]*job)
+	}
+	h.jobs.last++
+	j := &job{
+		id:     h.jobs.last,
+		sqlstr: sqlstr,
+		start:  time.Now(),
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	h.jobs.m[j.id] = j
+	h.jobs.Unlock()
+	db, u, patterns, hs, maxBytes := h.db, h.u, h.mask, h.hooks, h.maxResultBytes
+	attrs := append(tracing.Attrs(h.u, h.alias, h.dbType), tracing.Operation(prefix))
+	execSQL := h.commentStatement(sqlstr)
+	finish := h.finishStatement(rawPrefix, rawSQL, sqlstr, nil, qtyp, false)
+	go func() {
+		defer close(j.done)
+		defer cancel()
+		ctx, span := tracing.Start(ctx, "query", attrs...)
+		count, cols := int64(-1), []string(nil)
+		if qtyp {
+			rows, err := db.QueryContext(ctx, execSQL)
+			if err == nil {
+				var rs tblfmt.ResultSet = rows
+				var limiter *byteLimiter
+				if maxBytes > 0 {
+					limiter = &byteLimiter{ResultSet: rs, max: maxBytes}
+					rs = limiter
+				}
+				if binary {
+					rs = blob.New(rs, params["binary_dir"], params["binary"] == "files")
+				}
+				rs = hs.NewResultSet(rs)
+				if len(patterns) != 0 {
+					rs = mask.New(rs, patterns)
+				}
+				rc := &rowCounter{ResultSet: rs}
+				err = encodeAll(&j.buf, rc, params)
+				rows.Close()
+				limiter.warn(&j.buf)
+				count, cols = rc.n, rc.cols
+			}
+			j.err = err
+		} else {
+			res, err := db.ExecContext(ctx, execSQL)
+			if err == nil {
+				if count, err = drivers.RowsAffected(u, res); err == nil {
+					fmt.Fprint(&j.buf, prefix)
+					if count > 0 {
+						fmt.Fprint(&j.buf, " ", count)
+					}
+					fmt.Fprintln(&j.buf)
+				}
+			}
+			j.err = err
+		}
+		j.err = drivers.WrapErr(u.Driver, j.err)
+		if count >= 0 {
+			span.SetAttributes(tracing.RowsKey.Int64(count))
+		}
+		tracing.End(span, j.err)
+		j.end = time.Now()
+		finish(j.start, count, cols, j.err)
+	}()
+	return j.id, nil
+}
+
+// Jobs writes the list of background jobs to w.
+func (h *Handler) Jobs(w io.Writer) {
+	h.jobs.Lock()
+	ids := make([]int, 0, len(h.jobs.m))
+	for id := range h.jobs.m {
+		ids = append(ids, id)
+	}
+	h.jobs.Unlock()
+	sort.Ints(ids)
+	for _, id := range ids {
+		j, err := h.jobs.get(id)
+		if err != nil {
+			continue
+		}
+		fmt.Fprintf(w, "[%d] %-8s %10v  %s\n", j.id, j.status(), j.duration(), abbrev(j.sqlstr, 60))
+	}
+}
+
+// Wait waits for a background job to finish, and writes its result.
+func (h *Handler) Wait(ctx context.Context, id int) error {
+	j, err := h.jobs.get(id)
+	if err != nil {
+		return err
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-j.done:
+	}
+	return h.Result(id)
+}
+
+// Cancel cancels a running background job.
+func (h *Handler) Cancel(id int) error {
+	j, err := h.jobs.get(id)
+	if err != nil {
+		return err
+	}
+	j.cancel()
+	<-j.done
+	return nil
+}
+
+// Result writes the result of a finished background job to the handler's
+// output, and forgets the job.
+func (h *Handler) Result(id int) error {
+	j, err := h.jobs.get(id)
+	if err != nil {
+		return err
+	}
+	if j.status() == "running" {
+		return fmt.Errorf(text.JobStillRunning, id)
+	}
+	h.jobs.Lock()
+	delete(h.jobs.m, id)
+	h.jobs.Unlock()
+	if j.err != nil {
+		return j.err
+	}
+	_, err = io.Copy(h.GetOutput(), &j.buf)
+	return err
+}
+
+// cancelJobs cancels all running background jobs.
+func (h *Handler) cancelJobs() {
+	h.jobs.Lock()
+	defer h.jobs.Unlock()
+	for _, j := range h.jobs.m {
+		j.cancel()
+		<-j.done
+	}
+}
+
+// reportJobs writes a notice for every background job that finished since
+// the last call.
+func (h *Handler) reportJobs(w io.Writer) {
+	h.jobs.Lock()
+	defer h.jobs.Unlock()
+	var ids []int
+	for id, j := range h.jobs.m {
+		if !j.reported && j.status() != "running" {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		j := h.jobs.m[id]
+		j.reported = true
+		fmt.Fprintf(w, text.JobFinished+"\n", j.id, j.status(), j.duration())
+	}
+}
+
+// abbrev abbreviates s to n characters on a single line.
+func abbrev(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > n {
+		return string(r[:n-3]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,167 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xo/usql/policy"
+	"github.com/xo/usql/session"
+	"github.com/xo/usql/text"
+)
+
+func TestBackground(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	execute(t, h, "CREATE TABLE t (a int)")
+	execute(t, h, "INSERT INTO t VALUES (1)")
+	id, err := h.Background("SELECT a FROM t")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.Wait(context.Background(), id); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s := stdout.String(); !strings.Contains(s, "(1 row)") {
+		t.Errorf("expected the result of the job, got: %q", s)
+	}
+	// the jobs would not see the changes of the transaction
+	if err := h.Begin(nil); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	execute(t, h, "INSERT INTO t VALUES (2)")
+	if _, err := h.Background("SELECT a FROM t"); err != text.ErrBackgroundInTransaction {
+		t.Errorf("expected error %v, got: %v", text.ErrBackgroundInTransaction, err)
+	}
+	if err := h.Rollback(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestBackgroundConfirm(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	c, err := policy.NewConfirm(map[string]string{"drop": "always"}, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	h.SetConfirm(c)
+	execute(t, h, "CREATE TABLE t (a int)")
+	// without a terminal to ask, the statements to confirm are refused
+	var u *policy.Unconfirmed
+	if _, err := h.Background("DROP TABLE t"); !errors.As(err, &u) {
+		t.Fatalf("expected the statement to be unconfirmed, got: %v", err)
+	}
+	execute(t, h, "SELECT a FROM t")
+	h.SetYes(true)
+	id, err := h.Background("DROP TABLE t")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.Wait(context.Background(), id); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+}
+
+func TestJobs(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	execute(t, h, "CREATE TABLE t (a text)")
+	execute(t, h, "INSERT INTO t VALUES ('aaaa'), ('bbbb'), ('cccc')")
+	path := filepath.Join(t.TempDir(), "session.json")
+	r, err := session.NewRecorder(path, new(session.Session))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	h.SetRecorder(r)
+	ctx := context.Background()
+	// a finished job
+	id, err := h.Background("INSERT INTO t VALUES ('dddd')")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.Wait(ctx, id); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s := stdout.String(); s != "INSERT 1\n" {
+		t.Errorf("expected the result of the job, got: %q", s)
+	}
+	if err := h.Result(id); err == nil || err.Error() != fmt.Sprintf(text.NoSuchJob, id) {
+		t.Errorf("expected the result to be forgotten, got: %v", err)
+	}
+	// a failed job
+	if id, err = h.Background("SELECT * FROM missing"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.Wait(ctx, id); err == nil || !strings.Contains(err.Error(), "no such table: missing") {
+		t.Errorf("expected the error of the job, got: %v", err)
+	}
+	// a canceled job
+	if id, err = h.Background("WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT max(x) FROM c"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.Result(id); err == nil || err.Error() != fmt.Sprintf(text.JobStillRunning, id) {
+		t.Errorf("expected the job to be running, got: %v", err)
+	}
+	var buf bytes.Buffer
+	h.Jobs(&buf)
+	if s := buf.String(); !strings.HasPrefix(s, fmt.Sprintf("[%d] running ", id)) {
+		t.Errorf("expected the job listed as running, got: %q", s)
+	}
+	if err := h.Cancel(id); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	buf.Reset()
+	h.Jobs(&buf)
+	if s := buf.String(); !strings.HasPrefix(s, fmt.Sprintf("[%d] canceled ", id)) {
+		t.Errorf("expected the job listed as canceled, got: %q", s)
+	}
+	if err := h.Result(id); err == nil {
+		t.Errorf("expected the error of the canceled job, got nil")
+	}
+	// the results are limited to max_result_bytes
+	stdout.Reset()
+	h.SetMaxResultBytes(6)
+	if id, err = h.Background("SELECT a FROM t ORDER BY a"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.Wait(ctx, id); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s, exp := stdout.String(), fmt.Sprintf(text.ResultBytesExceeded, 2, "6 B"); !strings.Contains(s, "(2 rows)") || !strings.Contains(s, exp) {
+		t.Errorf("expected a partial result, got: %q", s)
+	}
+	h.SetMaxResultBytes(0)
+	// the statements are prefixed with the query comment
+	h.SetQueryComment("not_a_comment")
+	if id, err = h.Background("SELECT 1"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.Wait(ctx, id); err == nil || !strings.Contains(err.Error(), `near "not_a_comment"`) {
+		t.Errorf("expected the query comment to be prepended, got: %v", err)
+	}
+	h.SetQueryComment("")
+	// the jobs are recorded
+	s, err := session.Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var stmts []string
+	for _, st := range s.Statements {
+		stmts = append(stmts, fmt.Sprintf("%s %t %q", st.SQL, st.Error != "", st.Columns))
+	}
+	exp := []string{
+		`INSERT INTO t VALUES ('dddd') false []`,
+		`SELECT * FROM missing true []`,
+		`WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x+1 FROM c) SELECT max(x) FROM c true []`,
+		`SELECT a FROM t ORDER BY a false ["a"]`,
+		`SELECT 1 true []`,
+	}
+	if strings.Join(stmts, "\n") != strings.Join(exp, "\n") {
+		t.Errorf("expected statements:\n%s\ngot:\n%s", strings.Join(exp, "\n"), strings.Join(stmts, "\n"))
+	}
+}
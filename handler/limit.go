@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"database/sql"
+	"fmt"
+	"io"
+
+	"github.com/xo/tblfmt"
+	"github.com/xo/usql/sizes"
+	"github.com/xo/usql/text"
+)
+
+// SetMaxResultBytes sets the maximum size in bytes of the results of the
+// queries on the current connection, until another database is opened: the
+// rows of a result are no longer fetched once their values exceed it, with a
+// warning that the result is partial. No maximum is set when n is 0.
+func (h *Handler) SetMaxResultBytes(n int64) {
+	h.maxResultBytes = n
+}
+
+// newByteLimiter wraps the result set, limiting its size to max_result_bytes,
+// or returns nil when not set.
+func (h *Handler) newByteLimiter(resultSet tblfmt.ResultSet) *byteLimiter {
+	if h.maxResultBytes <= 0 {
+		return nil
+	}
+	return &byteLimiter{ResultSet: resultSet, max: h.maxResultBytes}
+}
+
+// warnExceeded warns that the result of the limiter is partial, when it
+// exceeded max_result_bytes.
+func (h *Handler) warnExceeded(l *byteLimiter) {
+	l.warn(h.l.Stderr())
+}
+
+// byteLimiter wraps a result set, no longer fetching its rows once the size
+// of their values exceeds max.
+type byteLimiter struct {
+	tblfmt.ResultSet
+	max int64
+	// n is the size of the values of the rows, and rows their count
+	n, rows int64
+	stop    bool
+}
+
+// Next advances to the next row, unless the rows exceed the maximum size.
+func (l *byteLimiter) Next() bool {
+	if l.n > l.max {
+		l.stop = true
+		return false
+	}
+	if !l.ResultSet.Next() {
+		return false
+	}
+	l.rows++
+	return true
+}
+
+// Scan scans the values of the current row, adding their size.
+func (l *byteLimiter) Scan(dest ...interface{}) error {
+	if err := l.ResultSet.Scan(dest...); err != nil {
+		return err
+	}
+	for _, v := range dest {
+		l.n += valueSize(v)
+	}
+	return nil
+}
+
+// NextResultSet advances to the next result set, unless the rows exceeded
+// the maximum size.
+func (l *byteLimiter) NextResultSet() bool {
+	return !l.stop && l.ResultSet.NextResultSet()
+}
+
+// warn writes the warning that the result is partial to w, when the rows
+// exceeded the maximum size.
+func (l *byteLimiter) warn(w io.Writer) {
+	if l.exceeded() {
+		fmt.Fprintln(w, fmt.Sprintf(text.ResultBytesExceeded, l.rows, sizes.FormatSize(l.max)))
+	}
+}
+
+// exceeded returns true when the rows exceeded the maximum size, and were no
+// longer fetched.
+func (l *byteLimiter) exceeded() bool {
+	return l != nil && l.stop
+}
+
+// ColumnTypes returns the column types of the wrapped result set.
+func (l *byteLimiter) ColumnTypes() ([]*sql.ColumnType, error) {
+	z, ok := l.ResultSet.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+	if !ok {
+		return nil, tblfmt.ErrResultSetHasNoColumnTypes
+	}
+	return z.ColumnTypes()
+}
+
+// Masked returns true when the column i of the wrapped result set is masked.
+func (l *byteLimiter) Masked(i int) bool {
+	m, ok := l.ResultSet.(interface{ Masked(int) bool })
+	return ok && m.Masked(i)
+}
+
+// valueSize returns the size in bytes of a scanned value: the length of
+// strings and byte slices, and 8 bytes for the other values.
+func valueSize(v interface{}) int64 {
+	switch x := v.(type) {
+	case nil:
+		return 0
+	case *interface{}:
+		return valueSize(*x)
+	case string:
+		return int64(len(x))
+	case *string:
+		return int64(len(*x))
+	case []byte:
+		return int64(len(x))
+	case *[]byte:
+		return int64(len(*x))
+	case sql.RawBytes:
+		return int64(len(x))
+	case *sql.RawBytes:
+		return int64(len(*x))
+	case *sql.NullString:
+		return int64(len(x.String))
+	}
+	return 8
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/jsonout"
+)
+
+// resultMeta is the metadata of the result of a statement collected while it
+// is executed, written after it as a footer (RESULT_META), or as a JSON
+// object in JSON output mode.
+type resultMeta struct {
+	// footer is set when the metadata is written as a footer, showing the
+	// notices instead of writing them as they come
+	footer bool
+	// conn is the connection executing the statement outside transactions,
+	// so that its warnings are those of its session
+	conn    *sql.Conn
+	mu      sync.Mutex
+	notices []string
+}
+
+// startMeta starts collecting the metadata of the executed statement, when
+// written after it, returning nil otherwise. Outside transactions, the
+// statement is executed on a connection of the pool held until endMeta (see
+// DB).
+func (h *Handler) startMeta(ctx context.Context) *resultMeta {
+	footer := env.Get("RESULT_META") == "on"
+	if !footer && !h.json {
+		return nil
+	}
+	m := &resultMeta{footer: footer && !h.json}
+	if h.tx == nil {
+		db := h.db
+		if h.reading {
+			db = h.reader
+		}
+		// on error, the statement fails on the pool as well
+		if conn, err := db.Conn(ctx); err == nil {
+			m.conn = conn
+		}
+	}
+	h.meta = m
+	return m
+}
+
+// endMeta ends collecting the metadata of the executed statement, writing it
+// when the statement succeeded, and otherwise writing the notices kept for the
+// footer as they would have been.
+func (h *Handler) endMeta(ctx context.Context, w io.Writer, m *resultMeta, prefix string, qtyp bool, err error) error {
+	defer func() {
+		if m.conn != nil {
+			m.conn.Close()
+		}
+		h.meta = nil
+	}()
+	if err == nil {
+		return h.writeMeta(ctx, w, m, prefix, qtyp)
+	}
+	if m.footer {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		for _, s := range m.notices {
+			fmt.Fprintln(h.l.Stderr(), s)
+		}
+	}
+	return nil
+}
+
+// writeMeta writes the metadata of the result of the executed statement: its
+// returned or affected rows, the warnings of the driver, the notices of the
+// database, and the server version.
+func (h *Handler) writeMeta(ctx context.Context, w io.Writer, m *resultMeta, prefix string, qtyp bool) error {
+	m.mu.Lock()
+	res := jsonout.Meta{
+		Command: prefix,
+		Notices: append([]string(nil), m.notices...),
+	}
+	m.mu.Unlock()
+	if rows := h.lastRows; rows >= 0 && qtyp {
+		res.RowsReturned = &rows
+	} else if rows >= 0 {
+		res.RowsAffected = &rows
+	}
+	warnings, err := drivers.Warnings(ctx, h.u, h.DB())
+	if err != nil {
+		fmt.Fprintln(h.l.Stderr(), "error: warnings:", err)
+	}
+	res.Warnings = warnings
+	if h.serverVersion == "" {
+		if ver, err := drivers.Version(ctx, h.u, h.DB()); err == nil {
+			h.serverVersion = ver
+		}
+	}
+	res.ServerVersion = h.serverVersion
+	if h.json {
+		return jsonout.WriteMeta(w, res)
+	}
+	var info []string
+	switch {
+	case res.RowsReturned != nil:
+		info = append(info, fmt.Sprintf("%d rows returned", *res.RowsReturned))
+	case res.RowsAffected != nil:
+		info = append(info, fmt.Sprintf("%d rows affected", *res.RowsAffected))
+	}
+	info = append(info, fmt.Sprintf("%d warnings", len(res.Warnings)), fmt.Sprintf("%d notices", len(res.Notices)))
+	if res.ServerVersion != "" {
+		info = append(info, "server "+res.ServerVersion)
+	}
+	fmt.Fprintf(w, "-- %s: %s\n", prefix, strings.Join(info, ", "))
+	for _, s := range res.Warnings {
+		fmt.Fprintln(w, "-- warning:", s)
+	}
+	for _, s := range res.Notices {
+		fmt.Fprintln(w, "-- notice:", s)
+	}
+	return nil
+}
+
+// connDB is a connection of a pool satisfying the drivers.DB interface.
+type connDB struct {
+	*sql.Conn
+}
+
+// Exec satisfies the drivers.DB interface.
+func (c connDB) Exec(sqlstr string, args ...interface{}) (sql.Result, error) {
+	return c.ExecContext(context.Background(), sqlstr, args...)
+}
+
+// Query satisfies the drivers.DB interface.
+func (c connDB) Query(sqlstr string, args ...interface{}) (*sql.Rows, error) {
+	return c.QueryContext(context.Background(), sqlstr, args...)
+}
+
+// QueryRow satisfies the drivers.DB interface.
+func (c connDB) QueryRow(sqlstr string, args ...interface{}) *sql.Row {
+	return c.QueryRowContext(context.Background(), sqlstr, args...)
+}
+
+// Prepare satisfies the drivers.DB interface.
+func (c connDB) Prepare(sqlstr string) (*sql.Stmt, error) {
+	return c.PrepareContext(context.Background(), sqlstr)
+}
+
+// noticeWriter is the standard error of the connection (see drivers.Open),
+// collecting the notices of the database for the metadata of the executed
+// statement (see drivers.NoticeWriter).
+type noticeWriter struct {
+	h *Handler
+}
+
+// Write satisfies the io.Writer interface.
+func (w noticeWriter) Write(p []byte) (int, error) {
+	return w.h.l.Stderr().Write(p)
+}
+
+// Notice satisfies the drivers.NoticeWriter interface.
+func (w noticeWriter) Notice(s string) {
+	if m := w.h.meta; m != nil {
+		m.mu.Lock()
+		m.notices = append(m.notices, s)
+		m.mu.Unlock()
+		// shown in the footer
+		if m.footer {
+			return
+		}
+	}
+	fmt.Fprintln(w.h.l.Stderr(), s)
+}
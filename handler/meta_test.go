@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os/user"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xo/usql/drivers"
+	_ "github.com/xo/usql/drivers/sqlite3"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/rline"
+	"github.com/xo/usql/stmt"
+)
+
+// newTestHandler creates a handler connected to a new SQLite3 database,
+// writing to stdout and stderr.
+func newTestHandler(t *testing.T, stdout, stderr *bytes.Buffer) *Handler {
+	t.Helper()
+	u, err := user.Current()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	h := New(&rline.Rline{Out: stdout, Err: stderr}, u, t.TempDir(), true)
+	if err := h.Open(context.Background(), "sqlite3:"+filepath.Join(t.TempDir(), "test.db")); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	t.Cleanup(func() { h.Close() })
+	stdout.Reset()
+	stderr.Reset()
+	return h
+}
+
+// execute executes sqlstr with the handler, returning its output.
+func execute(t *testing.T, h *Handler, sqlstr string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := h.Execute(context.Background(), &buf, metacmd.Option{}, stmt.FindPrefix(sqlstr, true, true, true), sqlstr, false); err != nil {
+		t.Fatalf("expected no error executing %q, got: %v", sqlstr, err)
+	}
+	return buf.String()
+}
+
+func TestMeta(t *testing.T) {
+	// the warnings are the temporary tables of the session, only seen on the
+	// connection executing the statement
+	d := drivers.Available()["sqlite3"]
+	defer func(d drivers.Driver) { drivers.Available()["sqlite3"] = d }(d)
+	d.Warnings = func(ctx context.Context, db drivers.DB) ([]string, error) {
+		rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_temp_master WHERE type = 'table' ORDER BY name`)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		var warnings []string
+		for rows.Next() {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return nil, err
+			}
+			warnings = append(warnings, "temporary table "+name)
+		}
+		return warnings, rows.Err()
+	}
+	d.Version = func(context.Context, drivers.DB) (string, error) {
+		return "SQLite3 test", nil
+	}
+	drivers.Available()["sqlite3"] = d
+	defer env.Set("RESULT_META", "off")
+	tests := []struct {
+		json   bool
+		sqlstr string
+		exp    string
+	}{
+		{false, `CREATE TEMP TABLE a (id int)`, "-- CREATE TABLE: 0 rows affected, 1 warnings, 0 notices, server SQLite3 test\n-- warning: temporary table a\n"},
+		{false, `SELECT 1 AS n`, "-- SELECT: 1 rows returned, 0 warnings, 0 notices, server SQLite3 test\n"},
+		{true, `CREATE TEMP TABLE b (id int)`, `{"meta":{"command":"CREATE TABLE","rows_affected":0,"warnings":["temporary table b"],"notices":[],"server_version":"SQLite3 test"}}` + "\n"},
+		{true, `SELECT 1 AS n`, `{"meta":{"command":"SELECT","rows_returned":1,"warnings":[],"notices":[],"server_version":"SQLite3 test"}}` + "\n"},
+	}
+	for i, test := range tests {
+		var stdout, stderr bytes.Buffer
+		h := newTestHandler(t, &stdout, &stderr)
+		// connections are closed when returned to the pool, so that the
+		// warnings are not those of another connection
+		h.db.SetMaxIdleConns(0)
+		h.SetJSON(test.json)
+		if err := env.Set("RESULT_META", "on"); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		s := execute(t, h, test.sqlstr)
+		if !strings.HasSuffix(s, test.exp) {
+			t.Errorf("test %d expected output ending with:\n%s\ngot:\n%s", i, test.exp, s)
+		}
+		if stderr.Len() != 0 {
+			t.Errorf("test %d expected no errors, got: %s", i, stderr.String())
+		}
+	}
+}
+
+func TestConfigChanged(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	var reloads int
+	h.SetConfigReloader(func() error {
+		reloads++
+		return nil
+	})
+	// the changes are reloaded once, before the prompt of the next statement
+	var reloaded []int
+	lines := []string{"SELECT", "1;", `\echo done`}
+	h.l = &rline.Rline{Out: &stdout, Err: &stderr, Int: true, N: func() ([]rune, error) {
+		reloaded = append(reloaded, reloads)
+		if len(lines) == 0 {
+			return nil, io.EOF
+		}
+		line := lines[0]
+		if lines = lines[1:]; line == "SELECT" {
+			h.ConfigChanged()
+			h.ConfigChanged()
+		}
+		return []rune(line), nil
+	}}
+	h.buf = stmt.New(h.l.Next)
+	if err := h.Run(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := []int{0, 0, 1, 1}; !reflect.DeepEqual(reloaded, exp) {
+		t.Errorf("expected reloads %v, got: %v", exp, reloaded)
+	}
+	if s := stderr.String(); s != "" {
+		t.Errorf("expected no errors, got: %q", s)
+	}
+}
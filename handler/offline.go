@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers/completer"
+	"github.com/xo/usql/offline"
+	"github.com/xo/usql/text"
+)
+
+// OpenOffline opens the database of the URL in offline mode, without
+// connecting to it: its objects are described and completed from the metadata
+// snapshot, and its statements are refused with text.ErrOffline.
+func (h *Handler) OpenOffline(u *dburl.URL, s *offline.Snapshot) {
+	h.u, h.offline = u, s
+	h.l.Completer(completer.NewDefaultCompleter(
+		completer.WithReader(s.Reader()),
+		completer.WithConnStrings(h.connStrings()),
+	))
+	if h.l.Interactive() {
+		h.Print(text.OfflineInfo, s.Alias, s.Taken.Format("2006-01-02 15:04"))
+	}
+}
+
+// errNotConnected returns the error of the statements executed without a
+// connection: text.ErrOffline in offline mode, and otherwise
+// text.ErrNotConnected.
+func (h *Handler) errNotConnected() error {
+	if h.offline != nil {
+		return text.ErrOffline
+	}
+	return text.ErrNotConnected
+}
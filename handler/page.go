@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/xo/tblfmt"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/text"
+)
+
+// pageQueries are the queries of a page of the results of a query, by
+// dialect, formatted with the query, the number of rows and the offset of the
+// page. Queries of the other dialects are not paged.
+var pageQueries = map[string]string{
+	"postgres":   "SELECT * FROM (%[1]s) AS page LIMIT %[2]d OFFSET %[3]d",
+	"mysql":      "SELECT * FROM (%[1]s) AS page LIMIT %[2]d OFFSET %[3]d",
+	"sqlite3":    "SELECT * FROM (%[1]s) AS page LIMIT %[2]d OFFSET %[3]d",
+	"duckdb":     "SELECT * FROM (%[1]s) AS page LIMIT %[2]d OFFSET %[3]d",
+	"clickhouse": "SELECT * FROM (%[1]s) AS page LIMIT %[2]d OFFSET %[3]d",
+	"snowflake":  "SELECT * FROM (%[1]s) AS page LIMIT %[2]d OFFSET %[3]d",
+	"bigquery":   "SELECT * FROM (%[1]s) AS page LIMIT %[2]d OFFSET %[3]d",
+	"trino":      "SELECT * FROM (%[1]s) AS page OFFSET %[3]d ROWS FETCH NEXT %[2]d ROWS ONLY",
+	"oracle":     "SELECT * FROM (%[1]s) page OFFSET %[3]d ROWS FETCH NEXT %[2]d ROWS ONLY",
+	"sqlserver":  "SELECT * FROM (%[1]s) AS page ORDER BY (SELECT NULL) OFFSET %[3]d ROWS FETCH NEXT %[2]d ROWS ONLY",
+}
+
+// page is the paged query whose results are browsed with \next and \prev.
+type page struct {
+	// opt, prefix and sqlstr are the execution options and the raw statement
+	// of the query, executed again for every page.
+	opt            metacmd.Option
+	prefix, sqlstr string
+	// size is the number of rows of a page, n the number of the page, from 0,
+	// and more is set when the page is followed by another one.
+	size, n int
+	more    bool
+	// resume is set when executing the query for \next or \prev.
+	resume bool
+}
+
+// GetPageSize returns the number of rows of the pages of the results of
+// queries, or 0 when paging is off.
+func (h *Handler) GetPageSize() int {
+	return h.pageSize
+}
+
+// SetPageSize sets the number of rows of the pages of the results of queries,
+// turning paging off when 0.
+func (h *Handler) SetPageSize(n int) {
+	h.pageSize, h.page = n, nil
+}
+
+// Page executes the paged query again, showing the page delta pages after the
+// current one.
+func (h *Handler) Page(ctx context.Context, delta int) error {
+	p := h.page
+	switch {
+	case p == nil:
+		return text.ErrNoPagedQuery
+	case delta > 0 && !p.more:
+		return text.ErrLastPage
+	case p.n+delta < 0:
+		return text.ErrFirstPage
+	}
+	p.n, p.resume = p.n+delta, true
+	return h.Execute(ctx, h.GetOutput(), p.opt, p.prefix, p.sqlstr, false)
+}
+
+// pageQuery returns the query of the current page of the results of the
+// processed query sqlstr, when paging the results of queries shown on the
+// terminal. The raw prefix and statement are kept to execute the query again
+// with \next and \prev.
+func (h *Handler) pageQuery(opt metacmd.Option, rawPrefix, rawSQL, prefix, sqlstr string, qtyp bool) string {
+	if p := h.page; p != nil && p.resume {
+		p.resume = false
+	} else {
+		h.page = nil
+		if h.pageSize <= 0 || !qtyp || opt.Stmt != nil || !isSelect(prefix) || h.out != nil || (opt.Exec != metacmd.ExecNone && opt.Exec != metacmd.ExecOnly) {
+			return sqlstr
+		}
+		h.page = &page{opt: opt, prefix: rawPrefix, sqlstr: rawSQL, size: h.pageSize}
+	}
+	format, ok := pageQueries[drivers.Caps(h.u).Dialect]
+	if !ok {
+		h.page = nil
+		return sqlstr
+	}
+	// fetch an additional row, to know whether another page follows
+	sqlstr = strings.TrimRight(strings.TrimSpace(sqlstr), ";")
+	return fmt.Sprintf(format, sqlstr, h.page.size+1, h.page.n*h.page.size)
+}
+
+// printPage prints the rows of the current page, and how to browse the other
+// pages, when the results have more than one page.
+func (h *Handler) printPage(n int64) {
+	p := h.page
+	if !p.more && p.n == 0 {
+		return
+	}
+	from := int64(p.n*p.size) + 1
+	if n == 0 {
+		from = 0
+	}
+	h.Print(text.PageDesc, p.n+1, from, int64(p.n*p.size)+n)
+	switch {
+	case p.more && p.n > 0:
+		h.Print(text.PageNextPrev)
+	case p.more:
+		h.Print(text.PageNext)
+	case p.n > 0:
+		h.Print(text.PagePrev)
+	}
+}
+
+// pageResultSet limits a result set to the rows of a page, keeping whether
+// more rows follow.
+type pageResultSet struct {
+	tblfmt.ResultSet
+	p *page
+	n int64
+}
+
+// Next advances to the next row of the page.
+func (rs *pageResultSet) Next() bool {
+	if !rs.ResultSet.Next() {
+		return false
+	}
+	if rs.n == int64(rs.p.size) {
+		rs.p.more = true
+		return false
+	}
+	rs.n++
+	return true
+}
+
+// NextResultSet returns false, as only the first result set is paged.
+func (rs *pageResultSet) NextResultSet() bool {
+	return false
+}
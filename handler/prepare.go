@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/stmt"
+	"github.com/xo/usql/text"
+)
+
+// prepared is a statement prepared by the driver (\prepare).
+type prepared struct {
+	sqlstr string
+	stmt   *sql.Stmt
+}
+
+// prepareRE matches the NAME AS QUERY argument of \prepare.
+var prepareRE = regexp.MustCompile(`(?is)^([\pL_][\pL\pN_]*)\s+AS\s+(.+)$`)
+
+// Prepare prepares the query of the NAME AS QUERY string with the driver,
+// replacing the statement previously prepared with the name. Without query,
+// the prepared statements are written.
+func (h *Handler) Prepare(ctx context.Context, s string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	if s = strings.TrimSpace(s); s == "" {
+		return h.listPrepared()
+	}
+	m := prepareRE.FindStringSubmatch(s)
+	if m == nil {
+		return text.ErrInvalidPrepare
+	}
+	name, sqlstr := m[1], strings.TrimSpace(m[2])
+	if err := h.checkPolicy(h.u, sqlstr); err != nil {
+		return err
+	}
+	_, sqlstr, _, err := drivers.Process(h.u, stmt.FindPrefix(sqlstr, true, true, true), sqlstr)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	execSQL := sqlstr
+	if h.comment != "" {
+		execSQL = h.comment + " " + sqlstr
+	}
+	st, err := h.db.PrepareContext(ctx, execSQL)
+	if err != nil {
+		return drivers.WrapErr(h.u.Driver, err)
+	}
+	if p, ok := h.prepared[name]; ok {
+		p.stmt.Close()
+	}
+	if h.prepared == nil {
+		h.prepared = make(map[string]*prepared)
+	}
+	h.prepared[name] = &prepared{sqlstr: sqlstr, stmt: st}
+	h.Print("PREPARE")
+	return nil
+}
+
+// ExecutePrepared executes the statement prepared with the name, binding the
+// arguments to its parameters.
+func (h *Handler) ExecutePrepared(ctx context.Context, name string, args []string) error {
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	p, ok := h.prepared[name]
+	if !ok {
+		return fmt.Errorf(text.NoSuchPrepared, name)
+	}
+	opt := metacmd.Option{Exec: metacmd.ExecOnly, Stmt: p.stmt}
+	for _, arg := range args {
+		opt.Args = append(opt.Args, arg)
+	}
+	w := h.l.Stdout()
+	if h.out != nil {
+		w = h.out
+	}
+	return h.Execute(ctx, w, opt, stmt.FindPrefix(p.sqlstr, true, true, true), p.sqlstr, false)
+}
+
+// listPrepared writes the names and queries of the prepared statements.
+func (h *Handler) listPrepared() error {
+	names := make([]string, 0, len(h.prepared))
+	for name := range h.prepared {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	w := h.GetOutput()
+	for _, name := range names {
+		fmt.Fprintf(w, "%s AS %s\n", name, h.prepared[name].sqlstr)
+	}
+	return nil
+}
+
+// closePrepared closes the prepared statements.
+func (h *Handler) closePrepared() {
+	for _, p := range h.prepared {
+		p.stmt.Close()
+	}
+	h.prepared = nil
+}
+
+// preparedStmt returns the prepared statement of the option for the
+// transaction, when executing a prepared statement.
+func (h *Handler) preparedStmt(ctx context.Context, opt metacmd.Option) *sql.Stmt {
+	if h.tx != nil {
+		return h.tx.StmtContext(ctx, opt.Stmt)
+	}
+	return opt.Stmt
+}
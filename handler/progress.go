@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"database/sql"
+	"io"
+	"os"
+
+	"github.com/xo/tblfmt"
+	"github.com/xo/usql/progress"
+)
+
+// SetProgress sets whether the progress of the imports, exports and copies is
+// reported to stderr.
+func (h *Handler) SetProgress(show bool) {
+	h.showProgress = show
+}
+
+// Progress starts reporting the progress of the operation with the name to
+// stderr, returning nil when the progress is not reported.
+func (h *Handler) Progress(name string) *progress.Progress {
+	if !h.showProgress {
+		return nil
+	}
+	return progress.New(h.l.Stderr(), name)
+}
+
+// exportProgress starts reporting the progress of the results written to w,
+// when a regular file.
+func (h *Handler) exportProgress(w io.Writer) *progress.Progress {
+	f, ok := w.(*os.File)
+	if !ok || !h.showProgress {
+		return nil
+	}
+	if fi, err := f.Stat(); err != nil || !fi.Mode().IsRegular() {
+		return nil
+	}
+	return h.Progress("export " + f.Name())
+}
+
+// progressRows wraps a result set, adding its rows to the progress.
+type progressRows struct {
+	tblfmt.ResultSet
+	p *progress.Progress
+}
+
+// Next advances to the next row.
+func (r *progressRows) Next() bool {
+	if r.ResultSet.Next() {
+		r.p.Add(1)
+		return true
+	}
+	return false
+}
+
+// ColumnTypes returns the column types of the wrapped result set.
+func (r *progressRows) ColumnTypes() ([]*sql.ColumnType, error) {
+	z, ok := r.ResultSet.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+	if !ok {
+		return nil, tblfmt.ErrResultSetHasNoColumnTypes
+	}
+	return z.ColumnTypes()
+}
+
+// Masked returns true when the column i of the wrapped result set is masked.
+func (r *progressRows) Masked(i int) bool {
+	m, ok := r.ResultSet.(interface{ Masked(int) bool })
+	return ok && m.Masked(i)
+}
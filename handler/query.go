@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/stmt"
+	"github.com/xo/usql/text"
+)
+
+// SetQueries sets the func returning the query templates of the config file
+// by name.
+func (h *Handler) SetQueries(f func(string) (*config.QueryConfig, error)) {
+	h.queries = f
+}
+
+// RunQuery executes the query template of the config file, binding the values
+// of its parameters. In interactive mode, the parameters without value or
+// default are prompted for.
+func (h *Handler) RunQuery(ctx context.Context, name string, values map[string]string) error {
+	if h.queries == nil {
+		return text.ErrNoConfigFile
+	}
+	if h.db == nil {
+		return h.errNotConnected()
+	}
+	q, err := h.queries(name)
+	if err != nil {
+		return err
+	}
+	names, err := q.ParamNames()
+	if err != nil {
+		return err
+	}
+	if h.l.Interactive() {
+		for _, name := range names {
+			if _, ok := values[name]; ok || q.Param(name).Default != nil {
+				continue
+			}
+			if values == nil {
+				values = make(map[string]string)
+			}
+			if values[name], err = h.ReadVar("string", fmt.Sprintf(text.QueryParamPrompt, name)); err != nil {
+				return err
+			}
+		}
+	}
+	sqlstr, args, err := q.Render(values, func(n int) string {
+		return drivers.Placeholder(h.u, n)
+	})
+	if err != nil {
+		return fmt.Errorf("query %s: %w", name, err)
+	}
+	w := h.l.Stdout()
+	if h.out != nil {
+		w = h.out
+	}
+	return h.Execute(ctx, w, metacmd.Option{Args: args}, stmt.FindPrefix(sqlstr, true, true, true), sqlstr, false)
+}
@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"database/sql"
+	"strings"
+)
+
+// Sides of the connections serving the statements routed between the reader
+// and writer hosts of a database.
+const (
+	routeReader = "r"
+	routeWriter = "w"
+)
+
+// SetReader sets the connection to the reader host of the database, to which
+// the read only statements (SELECT, VALUES, TABLE and SHOW) are routed
+// outside of transactions, the other statements being executed on the
+// connection to its host. The connection is closed with the handler's.
+func (h *Handler) SetReader(db *sql.DB) {
+	h.closeReader()
+	h.reader, h.route = db, ""
+}
+
+// closeReader closes the connection to the reader host, when set.
+func (h *Handler) closeReader() {
+	if h.reader != nil {
+		_ = h.reader.Close()
+		h.reader, h.route, h.reading = nil, "", false
+	}
+}
+
+// routeStatement routes the statement of type typ to the reader or writer
+// host, when routing, returning a func ending the routing of the statement.
+func (h *Handler) routeStatement(typ string) func() {
+	if h.reader == nil {
+		return func() {}
+	}
+	h.route = routeWriter
+	if fields := strings.Fields(typ); h.tx == nil && len(fields) != 0 && cachePrefixes[fields[0]] {
+		h.route, h.reading = routeReader, true
+	}
+	return func() {
+		h.reading = false
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/text"
+)
+
+// RestoreState restores the session state of the database alias the handler
+// is connected to (see env.State).
+func (h *Handler) RestoreState() error {
+	if h.alias == "" {
+		return nil
+	}
+	s, err := env.LoadState(env.StateFile(h.user, h.alias))
+	if err != nil {
+		return err
+	}
+	return s.Apply(h.SetTiming)
+}
+
+// SaveState updates the session state of the database alias the handler is
+// connected to with f, in interactive sessions.
+func (h *Handler) SaveState(f func(*env.State)) error {
+	if h.alias == "" || !h.l.Interactive() {
+		return nil
+	}
+	path := env.StateFile(h.user, h.alias)
+	s, err := env.LoadState(path)
+	if err != nil {
+		return err
+	}
+	f(s)
+	return s.Save(path)
+}
+
+// SaveSession saves the connection, the variables and the query buffer of
+// the session as the named session (see env.Session), returning the path of
+// its file.
+func (h *Handler) SaveSession(name string) (string, error) {
+	if h.db == nil {
+		return "", text.ErrNotConnected
+	}
+	s := &env.Session{
+		Saved: time.Now(),
+		Alias: h.alias,
+		Role:  h.role,
+		State: env.State{
+			Pset: env.Pall(),
+			Set:  env.All(),
+		},
+		Buffer: h.buf.RawString(),
+	}
+	s.SetTiming(h.timing)
+	if s.Alias == "" {
+		u := h.u.URL
+		if u.User != nil {
+			u.User = url.User(u.User.Username())
+		}
+		s.URL = u.String()
+	}
+	if s.Buffer == "" {
+		s.Buffer = h.lastRaw
+	}
+	path := env.SessionFile(h.user, name)
+	if err := s.Save(path); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// RestoreSession restores the variables and the query buffer of the named
+// session.
+func (h *Handler) RestoreSession(s *env.Session) error {
+	err := s.Apply(h.SetTiming)
+	if s.Buffer != "" {
+		h.buf.Reset([]rune(s.Buffer))
+	}
+	return err
+}
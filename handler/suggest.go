@@ -0,0 +1,56 @@
+package handler
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/suggest"
+)
+
+// setSuggestions opens the history of the statements executed on the
+// database alias the handler is connected to, suggesting the completions of
+// the statements of the interactive input, when the alias changed.
+func (h *Handler) setSuggestions() {
+	if h.suggestAlias == h.alias {
+		return
+	}
+	h.suggestions, h.suggestAlias = nil, h.alias
+	if h.alias == "" {
+		return
+	}
+	s, err := suggest.Open(env.SuggestFile(h.user, h.alias))
+	if err != nil {
+		fmt.Fprintln(h.l.Stderr(), "error: suggestions:", err)
+		return
+	}
+	h.suggestions = s
+}
+
+// suggest returns the completion of the first line of a statement, from the
+// history of the database alias, when SUGGEST is true.
+func (h *Handler) suggest(line string) string {
+	if h.suggestions == nil || h.buf.Len != 0 || env.All()["SUGGEST"] != "true" {
+		return ""
+	}
+	return h.suggestions.Suggest(line, time.Now())
+}
+
+// suggester returns the func adding the raw statements executed without
+// error to the history of the database alias, or nil when SUGGEST is not
+// true.
+func (h *Handler) suggester() func(sqlstr string, start time.Time, err error) {
+	if h.suggestions == nil || env.All()["SUGGEST"] != "true" {
+		return nil
+	}
+	s, stderr := h.suggestions, h.l.Stderr()
+	return func(sqlstr string, start time.Time, err error) {
+		if err != nil {
+			return
+		}
+		if err := s.Add(redact.String(sqlstr), start); err != nil {
+			fmt.Fprintln(stderr, "error: suggestions:", err)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/metacmd"
+	"github.com/xo/usql/stmt"
+	"github.com/xo/usql/text"
+)
+
+func TestTransactionPrompt(t *testing.T) {
+	// failed statements abort the transactions, as on PostgreSQL
+	d := drivers.Available()["sqlite3"]
+	defer func(d drivers.Driver) { drivers.Available()["sqlite3"] = d }(d)
+	d.AbortTxOnError = true
+	drivers.Available()["sqlite3"] = d
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	execute(t, h, "CREATE TABLE t (a int)")
+	exec := func(sqlstr string) func() error {
+		return func() error {
+			var buf bytes.Buffer
+			return h.Execute(context.Background(), &buf, metacmd.Option{}, stmt.FindPrefix(sqlstr, true, true, true), sqlstr, false)
+		}
+	}
+	tests := []struct {
+		f   func() error
+		err bool
+		exp string
+	}{
+		{func() error { return h.Savepoint("a") }, true, ""},
+		{func() error { return h.Begin(nil) }, false, "*"},
+		{exec("INSERT INTO t VALUES (1)"), false, "*"},
+		{func() error { return h.Savepoint("a b") }, true, "*"},
+		{func() error { return h.Savepoint("a") }, false, "*"},
+		{exec("INSERT INTO t VALUES (2)"), false, "*"},
+		{exec("INSERT INTO missing VALUES (1)"), true, "!"},
+		{func() error { return h.RollbackTo("a") }, false, "*"},
+		{func() error { return h.Savepoint("b") }, false, "*"},
+		{exec("INSERT INTO t VALUES (3)"), false, "*"},
+		{exec("INSERT INTO missing VALUES (1)"), true, "!"},
+		{exec("ROLLBACK TO SAVEPOINT b"), false, "*"},
+		{exec("INSERT INTO missing VALUES (1)"), true, "!"},
+		{exec("ROLLBACK TO a"), false, "*"},
+		{func() error { return h.ReleaseSavepoint("a") }, false, "*"},
+		{func() error { return h.RollbackTo("a") }, true, "*"},
+		{exec("INSERT INTO t VALUES (4)"), false, "*"},
+		{func() error { return h.Commit() }, false, ""},
+		{func() error { return h.Begin(nil) }, false, "*"},
+		{exec("INSERT INTO t VALUES (5)"), false, "*"},
+		{exec("INSERT INTO missing VALUES (1)"), true, "!"},
+		{func() error { return h.Rollback() }, false, ""},
+	}
+	for i, test := range tests {
+		if err := test.f(); (err != nil) != test.err {
+			t.Fatalf("test %d expected error %t, got: %v", i, test.err, err)
+		}
+		if s := h.Prompt("%x"); s != test.exp {
+			t.Errorf("test %d expected prompt %q, got: %q", i, test.exp, s)
+		}
+	}
+	var a []int
+	rows, err := h.db.Query("SELECT a FROM t ORDER BY a")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		a = append(a, v)
+	}
+	if len(a) != 2 || a[0] != 1 || a[1] != 4 {
+		t.Errorf("expected rows [1 4], got: %v", a)
+	}
+}
+
+func TestDryRun(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	h := newTestHandler(t, &stdout, &stderr)
+	ctx := context.Background()
+	execute(t, h, "CREATE TABLE t (a int)")
+	execute(t, h, "INSERT INTO t VALUES (1)")
+	if err := h.BeginDryRun(ctx); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exec := func(sqlstr string) error {
+		var buf bytes.Buffer
+		return h.Execute(ctx, &buf, metacmd.Option{}, stmt.FindPrefix(sqlstr, true, true, true), sqlstr, false)
+	}
+	tests := []struct {
+		sqlstr string
+		err    error
+	}{
+		{"INSERT INTO t VALUES (2), (3)", nil},
+		{"SELECT a FROM t", nil},
+		{"UPDATE t SET a = a + 1", nil},
+		{"DELETE FROM t WHERE a = 4", nil},
+		{"COMMIT", text.ErrDryRunEndsTransaction},
+		{"ROLLBACK", text.ErrDryRunEndsTransaction},
+		{"BEGIN TRANSACTION", text.ErrDryRunEndsTransaction},
+		{"END", text.ErrDryRunEndsTransaction},
+	}
+	for i, test := range tests {
+		if err := exec(test.sqlstr); err != test.err {
+			t.Fatalf("test %d expected error %v, got: %v", i, test.err, err)
+		}
+	}
+	if err := h.Commit(); err != text.ErrDryRunEndsTransaction {
+		t.Errorf("expected error %v, got: %v", text.ErrDryRunEndsTransaction, err)
+	}
+	// the definition statements are refused on databases committing them
+	d := drivers.Available()["sqlite3"]
+	defer func(d drivers.Driver) { drivers.Available()["sqlite3"] = d }(d)
+	d.Dialect = "mysql"
+	drivers.Available()["sqlite3"] = d
+	if err := exec("CREATE TABLE u (a int)"); err == nil || err.Error() != fmt.Sprintf(text.DryRunImplicitCommit, "CREATE", "sqlite3") {
+		t.Errorf("expected implicit commit error, got: %v", err)
+	}
+	stderr.Reset()
+	if err := h.EndDryRun(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// the rows of the statements not returning rows are counted
+	if s, exp := stderr.String(), fmt.Sprintf(text.DryRunRolledBack+"\n", 3, 6); s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	if h.tx != nil {
+		t.Errorf("expected the transaction to be rolled back")
+	}
+	if s := execute(t, h, "SELECT count(*) || ',' || sum(a) AS a FROM t"); !strings.Contains(s, " 1,1 ") {
+		t.Errorf("expected the changes to be rolled back, got: %q", s)
+	}
+}
+
+func TestEndsTransaction(t *testing.T) {
+	tests := []struct {
+		prefix string
+		exp    bool
+	}{
+		{"COMMIT", true},
+		{"ABORT", true},
+		{"ROLLBACK", true},
+		{"ROLLBACK WORK", true},
+		{"ROLLBACK TO SAVEPOINT", false},
+		{"ROLLBACK TO", false},
+		{"BEGIN", true},
+		{"BEGIN TRANSACTION", true},
+		{"BEGIN IMMEDIATE", true},
+		{"BEGIN DBMS_OUTPUT", false},
+		{"END", true},
+		{"START TRANSACTION", true},
+		{"START", false},
+		{"SAVEPOINT A", false},
+		{"SELECT", false},
+	}
+	for i, test := range tests {
+		if b := endsTransaction(strings.Fields(test.prefix)); b != test.exp {
+			t.Errorf("test %d (%s) expected %t, got: %t", i, test.prefix, test.exp, b)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/text"
+	"github.com/xo/usql/tracing"
+)
+
+// unquote returns the func unquoting the strings and interpolating the
+// variables of statements, as env.Unquote, but with the values of the
+// :'NAME' and :"NAME" variables quoted as string literals and identifiers of
+// the connected database, escaping their quotes.
+func (h *Handler) unquote() func(string, bool) (bool, string, error) {
+	vars := env.All()
+	f := env.Unquote(h.user, false, vars)
+	var d dump.Dialect
+	if h.u != nil {
+		d = dump.DialectFor(drivers.Caps(h.u).Dialect)
+	}
+	return func(s string, isvar bool) (bool, string, error) {
+		if !isvar || s[0] != '\'' && s[0] != '"' {
+			return f(s, isvar)
+		}
+		name, err := env.Dequote(s, s[0])
+		if err != nil {
+			return false, "", err
+		}
+		v, ok := vars[name]
+		switch {
+		case !ok:
+			return false, s, nil
+		case s[0] == '"':
+			return true, d.QuoteIdent(v), nil
+		}
+		return true, d.Quote(v), nil
+	}
+}
+
+// setAliasVars executes a query on a database alias, setting the columns of
+// its single row as variables named with the prefix (\gset (alias=NAME)), to
+// interpolate them in the statements executed on the connected database. The
+// query is checked, confirmed and logged as the statements of the connected
+// database.
+func (h *Handler) setAliasVars(ctx context.Context, alias, prefix, sqlstr string) (err error) {
+	ctx, span := tracing.Start(ctx, "gset", tracing.AliasKey.String(alias))
+	defer func() { tracing.End(span, err) }()
+	if h.pool == nil {
+		return text.ErrNoDatabaseAliases
+	}
+	sqlstr = strings.TrimSpace(strings.TrimRight(strings.TrimSpace(sqlstr), ";"))
+	if sqlstr == "" {
+		return text.ErrMissingRequiredArgument
+	}
+	if sqlstr, err = h.hooks.PreQuery(sqlstr); err != nil {
+		return err
+	}
+	u, db, err := h.pool.Get(ctx, alias, h.poolRole)
+	if err != nil {
+		return err
+	}
+	if err := h.checkPolicy(u, sqlstr); err != nil {
+		return err
+	}
+	if err := h.confirmStatement(u, alias, sqlstr); err != nil {
+		return err
+	}
+	start, n := time.Now(), int64(-1)
+	defer func() {
+		if f := h.auditorFor(alias, h.poolRole, u); f != nil {
+			f(sqlstr, start, n, err)
+		}
+		if err := h.hooks.PostQuery(sqlstr, n, time.Since(start), err); err != nil {
+			fmt.Fprintln(h.l.Stderr(), "error: hooks:", err)
+		}
+	}()
+	rows, err := db.QueryContext(ctx, sqlstr)
+	if err != nil {
+		return drivers.WrapErr(u.Driver, err)
+	}
+	defer rows.Close()
+	if err := setVars(u, rows, prefix); err != nil {
+		return drivers.WrapErr(u.Driver, err)
+	}
+	n = 1
+	return nil
+}
@@ -0,0 +1,191 @@
+// Package history syncs the statements executed in interactive sessions to a
+// query history shared by a team, stored in a table of a SQLite database
+// (such as a file on a shared drive) or of a PostgreSQL database, and searches
+// them.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+// DefaultTable is the default table of the history.
+const DefaultTable = "usql_history"
+
+// Entry is an executed statement of the history.
+type Entry struct {
+	Time   time.Time `json:"time"`
+	OSUser string    `json:"os_user"`
+	Alias  string    `json:"alias,omitempty"`
+	Role   string    `json:"role,omitempty"`
+	// Statement is the raw (non-interpolated) statement, with the passwords
+	// redacted.
+	Statement string  `json:"statement"`
+	Duration  float64 `json:"duration_ms"`
+	// Rows is the number of rows returned or affected, when known.
+	Rows  *int64 `json:"rows,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// createStmts are the statements creating the table of the history, by
+// driver.
+var createStmts = map[string]string{
+	"sqlite3": `CREATE TABLE IF NOT EXISTS %s (
+  id INTEGER PRIMARY KEY AUTOINCREMENT,
+  executed_at TIMESTAMP NOT NULL,
+  os_user TEXT NOT NULL,
+  alias TEXT NOT NULL,
+  role TEXT NOT NULL,
+  statement TEXT NOT NULL,
+  duration_ms REAL NOT NULL,
+  rows INTEGER,
+  error TEXT NOT NULL
+)`,
+	"postgres": `CREATE TABLE IF NOT EXISTS %s (
+  id BIGSERIAL PRIMARY KEY,
+  executed_at TIMESTAMPTZ NOT NULL,
+  os_user TEXT NOT NULL,
+  alias TEXT NOT NULL,
+  role TEXT NOT NULL,
+  statement TEXT NOT NULL,
+  duration_ms DOUBLE PRECISION NOT NULL,
+  rows BIGINT,
+  error TEXT NOT NULL
+)`,
+}
+
+// tableRE matches the valid, optionally schema qualified, table names.
+var tableRE = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// Store is the history stored in a database. A Store is safe for concurrent
+// use.
+type Store struct {
+	db     *sql.DB
+	driver string
+	table  string
+	dollar bool
+}
+
+// Open opens the history stored in the table (default DefaultTable) of the
+// database at urlstr, creating the table when missing.
+func Open(ctx context.Context, urlstr, table string) (*Store, error) {
+	if table == "" {
+		table = DefaultTable
+	}
+	if !tableRE.MatchString(table) {
+		return nil, fmt.Errorf("invalid history table %q", table)
+	}
+	u, err := dburl.Parse(urlstr)
+	if err != nil {
+		return nil, err
+	}
+	create, ok := createStmts[u.Unaliased]
+	if !ok {
+		return nil, fmt.Errorf("history backend: %s databases not supported, expected sqlite3 or postgres", u.Unaliased)
+	}
+	discard := func() io.Writer { return io.Discard }
+	db, err := drivers.Open(u, discard, discard)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.ExecContext(ctx, fmt.Sprintf(create, table)); err != nil {
+		db.Close()
+		return nil, drivers.WrapErr(u.Driver, err)
+	}
+	return &Store{db: db, driver: u.Driver, table: table, dollar: u.Unaliased == "postgres"}, nil
+}
+
+// Close closes the history.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// placeholder returns the n-th (from 1) placeholder of the statements.
+func (s *Store) placeholder(n int) string {
+	if s.dollar {
+		return "$" + strconv.Itoa(n)
+	}
+	return "?"
+}
+
+// Add adds the entry to the history.
+func (s *Store) Add(ctx context.Context, e Entry) error {
+	var p []string
+	for i := 1; i <= 8; i++ {
+		p = append(p, s.placeholder(i))
+	}
+	sqlstr := fmt.Sprintf(`INSERT INTO %s (executed_at, os_user, alias, role, statement, duration_ms, rows, error) VALUES (%s)`, s.table, strings.Join(p, ", "))
+	var rows interface{}
+	if e.Rows != nil {
+		rows = *e.Rows
+	}
+	_, err := s.db.ExecContext(ctx, sqlstr, e.Time.UTC(), e.OSUser, e.Alias, e.Role, strings.TrimSpace(e.Statement), e.Duration, rows, e.Error)
+	return drivers.WrapErr(s.driver, err)
+}
+
+// Filter filters the searched entries.
+type Filter struct {
+	// Pattern is a case insensitive substring of the statements, when set.
+	Pattern string
+	// Alias and OSUser are the database alias and OS user of the entries,
+	// when set.
+	Alias  string
+	OSUser string
+	// Limit is the maximum number of entries, when set.
+	Limit int
+}
+
+// Search returns the entries of the history matching the filter, newest
+// first.
+func (s *Store) Search(ctx context.Context, f Filter) ([]Entry, error) {
+	var where []string
+	var args []interface{}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		where = append(where, fmt.Sprintf(cond, s.placeholder(len(args))))
+	}
+	if f.Pattern != "" {
+		add("LOWER(statement) LIKE %s", "%"+strings.ToLower(f.Pattern)+"%")
+	}
+	if f.Alias != "" {
+		add("alias = %s", f.Alias)
+	}
+	if f.OSUser != "" {
+		add("os_user = %s", f.OSUser)
+	}
+	sqlstr := `SELECT executed_at, os_user, alias, role, statement, duration_ms, rows, error FROM ` + s.table
+	if len(where) != 0 {
+		sqlstr += " WHERE " + strings.Join(where, " AND ")
+	}
+	sqlstr += " ORDER BY id DESC"
+	if f.Limit > 0 {
+		sqlstr += " LIMIT " + strconv.Itoa(f.Limit)
+	}
+	rows, err := s.db.QueryContext(ctx, sqlstr, args...)
+	if err != nil {
+		return nil, drivers.WrapErr(s.driver, err)
+	}
+	defer rows.Close()
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		var n sql.NullInt64
+		if err := rows.Scan(&e.Time, &e.OSUser, &e.Alias, &e.Role, &e.Statement, &e.Duration, &n, &e.Error); err != nil {
+			return nil, err
+		}
+		if n.Valid {
+			e.Rows = &n.Int64
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
@@ -0,0 +1,78 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	_ "github.com/xo/usql/drivers/sqlite3"
+)
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "history.db")
+	s, err := Open(ctx, "sqlite3:"+path, "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer s.Close()
+	start := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	rows := int64(3)
+	entries := []Entry{
+		{Time: start, OSUser: "alice", Alias: "app_db", Statement: "select * from orders", Duration: 1.5, Rows: &rows},
+		{Time: start.Add(time.Minute), OSUser: "bob", Alias: "app_db", Statement: "delete from ORDERS where id = 1", Error: "denied"},
+		{Time: start.Add(2 * time.Minute), OSUser: "bob", Alias: "billing", Statement: "select 1"},
+	}
+	for _, e := range entries {
+		if err := s.Add(ctx, e); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	// reopening keeps the entries
+	s.Close()
+	if s, err = Open(ctx, "sqlite3:"+path, ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer s.Close()
+	tests := []struct {
+		f   Filter
+		exp []string
+	}{
+		{Filter{}, []string{"select 1", "delete from ORDERS where id = 1", "select * from orders"}},
+		{Filter{Pattern: "Orders"}, []string{"delete from ORDERS where id = 1", "select * from orders"}},
+		{Filter{Alias: "app_db", OSUser: "bob"}, []string{"delete from ORDERS where id = 1"}},
+		{Filter{Limit: 1}, []string{"select 1"}},
+	}
+	for i, test := range tests {
+		res, err := s.Search(ctx, test.f)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		var stmts []string
+		for _, e := range res {
+			stmts = append(stmts, e.Statement)
+		}
+		if len(stmts) != len(test.exp) {
+			t.Fatalf("test %d expected %v, got: %v", i, test.exp, stmts)
+		}
+		for j := range stmts {
+			if stmts[j] != test.exp[j] {
+				t.Errorf("test %d expected %v, got: %v", i, test.exp, stmts)
+			}
+		}
+	}
+	res, err := s.Search(ctx, Filter{OSUser: "alice"})
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case len(res) != 1 || !res[0].Time.Equal(start) || res[0].Rows == nil || *res[0].Rows != 3 || res[0].Duration != 1.5:
+		t.Errorf("expected the entry of alice, got: %+v", res)
+	}
+	if _, err := Open(ctx, "sqlite3:"+path, "drop table x;"); err == nil {
+		t.Errorf("expected error for invalid table, got nil")
+	}
+	if _, err := Open(ctx, "mysql://localhost/app", ""); err == nil {
+		t.Errorf("expected error for unsupported database, got nil")
+	}
+}
@@ -0,0 +1,263 @@
+// Package hooks runs the Starlark hooks of a database, called when
+// connecting, before and after executing statements, and to format the rows
+// of query results.
+//
+// A hooks file defines any of the functions:
+//
+//	def pre_connect(url): return the URL to connect to, or None to keep it
+//	def pre_query(sql): return the statement to execute, or None to keep it
+//	def post_query(sql, rows, seconds, error): called after executing a statement
+//	def format_row(columns, row): return the values of the row, or None to keep them
+//
+// Calling fail("reason") in pre_connect or pre_query aborts the connection or
+// the statement. rows is the number of rows returned or affected by the
+// statement, or None when unknown, and error is the error of the statement,
+// or None.
+package hooks
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/xo/tblfmt"
+	"go.starlark.net/starlark"
+)
+
+// Hook names.
+const (
+	PreConnect = "pre_connect"
+	PreQuery   = "pre_query"
+	PostQuery  = "post_query"
+	FormatRow  = "format_row"
+)
+
+// ErrCannotFormat is the error returned when a formatted row is scanned to a
+// value that cannot hold the formatted value.
+var ErrCannotFormat = errors.New("cannot format column value")
+
+// Hooks are the hooks of a hooks file. A nil *Hooks has no hooks. It's safe
+// for concurrent use.
+type Hooks struct {
+	mu      sync.Mutex
+	thread  *starlark.Thread
+	globals starlark.StringDict
+}
+
+// Load loads the hooks file at path. The output of print is written to w.
+func Load(path string, w io.Writer) (*Hooks, error) {
+	thread := &starlark.Thread{
+		Name: path,
+		Print: func(_ *starlark.Thread, msg string) {
+			fmt.Fprintln(w, msg)
+		},
+	}
+	globals, err := starlark.ExecFile(thread, path, nil, nil)
+	if err != nil {
+		return nil, fmt.Errorf("hooks %s: %w", path, err)
+	}
+	for _, name := range []string{PreConnect, PreQuery, PostQuery, FormatRow} {
+		if v, ok := globals[name]; ok {
+			if _, ok := v.(starlark.Callable); !ok {
+				return nil, fmt.Errorf("hooks %s: %s is a %s, not a function", path, name, v.Type())
+			}
+		}
+	}
+	return &Hooks{thread: thread, globals: globals}, nil
+}
+
+// Has returns true when the hook is defined.
+func (h *Hooks) Has(name string) bool {
+	if h == nil {
+		return false
+	}
+	_, ok := h.globals[name]
+	return ok
+}
+
+// call calls the hook with args, returning None when it isn't defined.
+func (h *Hooks) call(name string, args ...starlark.Value) (starlark.Value, error) {
+	if !h.Has(name) {
+		return starlark.None, nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	v, err := starlark.Call(h.thread, h.globals[name], args, nil)
+	if err != nil {
+		var e *starlark.EvalError
+		if errors.As(err, &e) {
+			return nil, fmt.Errorf("%s: %s", name, e.Msg)
+		}
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return v, nil
+}
+
+// callString calls the hook with s, returning the string it returned, or s
+// when it returned None.
+func (h *Hooks) callString(name, s string) (string, error) {
+	v, err := h.call(name, starlark.String(s))
+	if err != nil {
+		return "", err
+	}
+	switch x := v.(type) {
+	case starlark.NoneType:
+		return s, nil
+	case starlark.String:
+		return string(x), nil
+	}
+	return "", fmt.Errorf("%s: returned a %s, expected a string or None", name, v.Type())
+}
+
+// PreConnect returns the URL to connect to, as returned by the pre_connect
+// hook.
+func (h *Hooks) PreConnect(urlstr string) (string, error) {
+	return h.callString(PreConnect, urlstr)
+}
+
+// PreQuery returns the statement to execute, as returned by the pre_query
+// hook.
+func (h *Hooks) PreQuery(sqlstr string) (string, error) {
+	return h.callString(PreQuery, sqlstr)
+}
+
+// PostQuery calls the post_query hook with the executed statement, the number
+// of rows it returned or affected (or -1 when unknown), its duration and its
+// error.
+func (h *Hooks) PostQuery(sqlstr string, rows int64, d time.Duration, err error) error {
+	var r, e starlark.Value = starlark.None, starlark.None
+	if rows >= 0 {
+		r = starlark.MakeInt64(rows)
+	}
+	if err != nil {
+		e = starlark.String(err.Error())
+	}
+	_, err = h.call(PostQuery, starlark.String(sqlstr), r, starlark.Float(d.Seconds()), e)
+	return err
+}
+
+// NewResultSet wraps the result set, formatting its rows with the format_row
+// hook, or returns it when the hook isn't defined.
+func (h *Hooks) NewResultSet(resultSet tblfmt.ResultSet) tblfmt.ResultSet {
+	if !h.Has(FormatRow) {
+		return resultSet
+	}
+	return &ResultSet{ResultSet: resultSet, h: h}
+}
+
+// ResultSet wraps a result set, formatting its rows with the format_row hook.
+type ResultSet struct {
+	tblfmt.ResultSet
+	h *Hooks
+	// columns are the columns of the current result set.
+	columns starlark.Tuple
+}
+
+// Columns returns the column names.
+func (rs *ResultSet) Columns() ([]string, error) {
+	cols, err := rs.ResultSet.Columns()
+	if err != nil {
+		return nil, err
+	}
+	rs.columns = make(starlark.Tuple, len(cols))
+	for i, col := range cols {
+		rs.columns[i] = starlark.String(col)
+	}
+	return cols, nil
+}
+
+// ColumnTypes returns the column types of the wrapped result set.
+func (rs *ResultSet) ColumnTypes() ([]*sql.ColumnType, error) {
+	z, ok := rs.ResultSet.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+	if !ok {
+		return nil, tblfmt.ErrResultSetHasNoColumnTypes
+	}
+	return z.ColumnTypes()
+}
+
+// Scan scans the values of the current row to dest, formatted by the
+// format_row hook.
+func (rs *ResultSet) Scan(dest ...interface{}) error {
+	if err := rs.ResultSet.Scan(dest...); err != nil {
+		return err
+	}
+	if rs.columns == nil {
+		if _, err := rs.Columns(); err != nil {
+			return err
+		}
+	}
+	row := make([]starlark.Value, len(dest))
+	for i, d := range dest {
+		p, ok := d.(*interface{})
+		if !ok {
+			return ErrCannotFormat
+		}
+		row[i] = toValue(*p)
+	}
+	v, err := rs.h.call(FormatRow, rs.columns, starlark.NewList(row))
+	if err != nil {
+		return err
+	}
+	if v == starlark.None {
+		return nil
+	}
+	seq, ok := v.(starlark.Indexable)
+	if !ok || seq.Len() != len(dest) {
+		return fmt.Errorf("%s: returned a %s, expected a list of %d values or None", FormatRow, v.Type(), len(dest))
+	}
+	for i, d := range dest {
+		*d.(*interface{}) = fromValue(seq.Index(i))
+	}
+	return nil
+}
+
+// NextResultSet prepares the next result set.
+func (rs *ResultSet) NextResultSet() bool {
+	rs.columns = nil
+	return rs.ResultSet.NextResultSet()
+}
+
+// toValue converts a scanned value to a Starlark value.
+func toValue(v interface{}) starlark.Value {
+	switch x := v.(type) {
+	case nil:
+		return starlark.None
+	case bool:
+		return starlark.Bool(x)
+	case int64:
+		return starlark.MakeInt64(x)
+	case float64:
+		return starlark.Float(x)
+	case string:
+		return starlark.String(x)
+	case []byte:
+		return starlark.String(x)
+	case time.Time:
+		return starlark.String(x.Format(time.RFC3339Nano))
+	}
+	return starlark.String(fmt.Sprint(v))
+}
+
+// fromValue converts a Starlark value to a scanned value.
+func fromValue(v starlark.Value) interface{} {
+	switch x := v.(type) {
+	case starlark.NoneType:
+		return nil
+	case starlark.Bool:
+		return bool(x)
+	case starlark.Int:
+		if i, ok := x.Int64(); ok {
+			return i
+		}
+	case starlark.Float:
+		return float64(x)
+	case starlark.String:
+		return string(x)
+	}
+	return v.String()
+}
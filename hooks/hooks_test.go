@@ -0,0 +1,125 @@
+package hooks
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const testHooks = `
+def pre_connect(url):
+    if "prod" in url:
+        fail("connecting to prod is not allowed")
+
+def pre_query(sql):
+    if sql.lower().startswith("select") and "limit" not in sql.lower():
+        return sql + " limit 100"
+
+def post_query(sql, rows, seconds, error):
+    print("post_query", sql, rows, error)
+
+def format_row(columns, row):
+    return [v.upper() if c == "name" and v != None else v for c, v in zip(columns, row)]
+`
+
+func TestHooks(t *testing.T) {
+	var buf bytes.Buffer
+	h, err := Load(writeHooks(t, testHooks), &buf)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if u, err := h.PreConnect("pg://localhost/app"); err != nil || u != "pg://localhost/app" {
+		t.Errorf("expected unchanged url, got: %q, %v", u, err)
+	}
+	if _, err := h.PreConnect("pg://prod/app"); err == nil || err.Error() != "pre_connect: fail: connecting to prod is not allowed" {
+		t.Errorf("expected fail error, got: %v", err)
+	}
+	tests := []struct {
+		sqlstr, exp string
+	}{
+		{"select * from t", "select * from t limit 100"},
+		{"select * from t limit 5", "select * from t limit 5"},
+		{"delete from t", "delete from t"},
+	}
+	for i, test := range tests {
+		sqlstr, err := h.PreQuery(test.sqlstr)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if sqlstr != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, sqlstr)
+		}
+	}
+	if err := h.PostQuery("delete from t", 2, time.Second, nil); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := h.PostQuery("select", -1, time.Second, errors.New("failed")); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s, exp := buf.String(), "post_query delete from t 2 None\npost_query select None failed\n"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+}
+
+func TestResultSet(t *testing.T) {
+	h, err := Load(writeHooks(t, testHooks), &bytes.Buffer{})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT 1 AS id, 'alice' AS name UNION ALL SELECT 2, NULL`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer rows.Close()
+	rs := h.NewResultSet(rows)
+	var res [][]interface{}
+	for rs.Next() {
+		var id, name interface{}
+		if err := rs.Scan(&id, &name); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		res = append(res, []interface{}{id, name})
+	}
+	if exp := [][]interface{}{{int64(1), "ALICE"}, {int64(2), nil}}; !reflect.DeepEqual(res, exp) {
+		t.Errorf("expected %v, got: %v", exp, res)
+	}
+}
+
+func TestNil(t *testing.T) {
+	var h *Hooks
+	if sqlstr, err := h.PreQuery("select 1"); err != nil || sqlstr != "select 1" {
+		t.Errorf("expected unchanged statement, got: %q, %v", sqlstr, err)
+	}
+	if err := h.PostQuery("select 1", 1, time.Second, nil); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	_, err := Load(writeHooks(t, "pre_query = 1\n"), &bytes.Buffer{})
+	if err == nil || !strings.HasSuffix(err.Error(), "pre_query is a int, not a function") {
+		t.Errorf("expected not a function error, got: %v", err)
+	}
+}
+
+// writeHooks writes a hooks file, returning its path.
+func writeHooks(t *testing.T, src string) string {
+	path := filepath.Join(t.TempDir(), "hooks.star")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	return path
+}
@@ -0,0 +1,459 @@
+// Package importer imports delimited text files (CSV, TSV) and JSON files into
+// database tables.
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/progress"
+)
+
+// Header is the header detection mode.
+type Header int
+
+// Header detection modes.
+const (
+	// HeaderAuto treats the first record as a header when its values look
+	// like column names.
+	HeaderAuto Header = iota
+	// HeaderOn always treats the first record as a header.
+	HeaderOn
+	// HeaderOff never treats the first record as a header.
+	HeaderOff
+)
+
+// ParseHeader parses a header detection mode.
+func ParseHeader(s string) (Header, error) {
+	switch strings.ToLower(s) {
+	case "", "auto":
+		return HeaderAuto, nil
+	case "on", "true", "yes", "1":
+		return HeaderOn, nil
+	case "off", "false", "no", "0":
+		return HeaderOff, nil
+	}
+	return HeaderAuto, fmt.Errorf("invalid header mode %q", s)
+}
+
+// Format is the format of imported files.
+type Format int
+
+// Formats.
+const (
+	// FormatCSV is delimited text (CSV, TSV).
+	FormatCSV Format = iota
+	// FormatJSON is a JSON array of objects, or JSON lines of objects, the
+	// keys of the first object being the header.
+	FormatJSON
+)
+
+// ParseFormat parses a file format.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "csv", "tsv":
+		return FormatCSV, nil
+	case "json", "jsonl", "ndjson":
+		return FormatJSON, nil
+	}
+	return FormatCSV, fmt.Errorf("invalid format %q", s)
+}
+
+// DefaultFormat returns the default format for the named file: JSON for
+// .json, .jsonl and .ndjson files, and CSV otherwise.
+func DefaultFormat(name string) Format {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json", ".jsonl", ".ndjson":
+		return FormatJSON
+	}
+	return FormatCSV
+}
+
+// ParseDelimiter parses a delimiter or quote character, accepting escaped
+// tabs (\t) and the names "tab", "comma", "semicolon", and "pipe".
+func ParseDelimiter(s string) (rune, error) {
+	switch strings.ToLower(s) {
+	case `\t`, "tab":
+		return '\t', nil
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "pipe":
+		return '|', nil
+	}
+	if r := []rune(s); len(r) == 1 && r[0] != '\n' && r[0] != '\r' {
+		return r[0], nil
+	}
+	return 0, fmt.Errorf("invalid delimiter %q", s)
+}
+
+// DefaultDelimiter returns the default delimiter for the named file: a tab
+// for .tsv and .tab files, and a comma otherwise.
+func DefaultDelimiter(name string) rune {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".tsv", ".tab":
+		return '\t'
+	}
+	return ','
+}
+
+// ParseTable parses a table name with an optional column list, as in
+// TABLE(A,B,...).
+func ParseTable(s string) (string, []string) {
+	i := strings.IndexRune(s, '(')
+	if i == -1 || !strings.HasSuffix(s, ")") {
+		return s, nil
+	}
+	var columns []string
+	for _, c := range strings.Split(s[i+1:len(s)-1], ",") {
+		if c = strings.TrimSpace(c); c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return strings.TrimSpace(s[:i]), columns
+}
+
+// DefaultBatchSize is the default number of records inserted per statement,
+// when the driver has no native bulk load path.
+const DefaultBatchSize = 100
+
+// Options are the import options.
+type Options struct {
+	// Table is the target table.
+	Table string
+	// Columns are the target columns. When empty, the columns are taken from
+	// the header, or are all the columns of the table. The columns are
+	// matched to the table's columns, case insensitively when not exactly.
+	Columns []string
+	// Format is the format of the file. The delimiter, quote, header and null
+	// options only apply to CSV files.
+	Format Format
+	// Delimiter is the field delimiter. Defaults to ','.
+	Delimiter rune
+	// Quote is the quote character. Defaults to '"'.
+	Quote rune
+	// Header is the header detection mode.
+	Header Header
+	// Keys are the keys of the JSON objects, in the order of their fields,
+	// instead of the keys of the first object (see Schema.Keys).
+	Keys []string
+	// Null is the string representing a NULL value. Unquoted fields equal to
+	// Null are imported as NULL. Defaults to the empty string. JSON null values
+	// and missing keys are always imported as NULL.
+	Null string
+	// BatchSize is the number of records per insert statement.
+	BatchSize int
+	// NoBulk disables the driver's native bulk load path.
+	NoBulk bool
+	// Progress is the progress the read bytes and records are added to, when
+	// not nil.
+	Progress *progress.Progress
+}
+
+// Import reads the records from r and loads them into the table, returning
+// the number of imported records.
+func Import(ctx context.Context, u *dburl.URL, db *sql.DB, r io.Reader, opts Options) (int64, error) {
+	if opts.Table == "" {
+		return 0, fmt.Errorf("missing table")
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if err := CheckTable(opts.Table); err != nil {
+		return 0, err
+	}
+	tableColumns, types, err := columnTypes(ctx, db, opts.Table)
+	if err != nil {
+		return 0, err
+	}
+	read := newRecordReader(opts.Progress.Reader(r), opts)
+	first, err := read()
+	switch {
+	case err == io.EOF:
+		return 0, nil
+	case err != nil:
+		return 0, err
+	}
+	pending := []record{first}
+	var isHeader bool
+	switch {
+	case opts.Format == FormatJSON, opts.Header == HeaderOn:
+		isHeader = true
+	case opts.Header == HeaderAuto:
+		isHeader = matchesColumns(first.fields, tableColumns)
+		if !isHeader {
+			// peek at the second record to compare value shapes
+			second, err := read()
+			switch {
+			case err == io.EOF:
+			case err != nil:
+				return 0, err
+			default:
+				pending = append(pending, second)
+				isHeader = looksLikeHeader(first.fields, second.fields)
+			}
+		}
+	}
+	if isHeader {
+		pending = pending[1:]
+	}
+	columns := opts.Columns
+	switch {
+	case len(columns) == 0 && isHeader:
+		columns = make([]string, len(first.fields))
+		for i, f := range first.fields {
+			columns[i] = strings.TrimSpace(f.s)
+		}
+	case len(columns) == 0:
+		columns = tableColumns
+	}
+	if len(opts.Columns) != 0 || isHeader {
+		if columns, types, err = resolveColumns(columns, tableColumns, types); err != nil {
+			return 0, err
+		}
+	}
+	coercers := make([]coercer, len(types))
+	for i, typ := range types {
+		coercers[i] = coercerFor(typ)
+	}
+	next := func() ([]interface{}, error) {
+		var rec record
+		if len(pending) != 0 {
+			rec, pending = pending[0], pending[1:]
+		} else {
+			var err error
+			if rec, err = read(); err != nil {
+				return nil, err
+			}
+		}
+		if len(rec.fields) != len(columns) {
+			return nil, fmt.Errorf("line %d: expected %d fields, got: %d", rec.line, len(columns), len(rec.fields))
+		}
+		values := make([]interface{}, len(rec.fields))
+		for i, f := range rec.fields {
+			if f.null || !f.quoted && f.s == opts.Null {
+				continue
+			}
+			v, err := coercers[i](f.s)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: column %s: %w", rec.line, columns[i], err)
+			}
+			values[i] = v
+		}
+		opts.Progress.Add(1)
+		return values, nil
+	}
+	if opts.NoBulk {
+		return drivers.ImportWithInsert(ctx, u, db, opts.Table, columns, opts.BatchSize, next)
+	}
+	return drivers.Import(ctx, u, db, opts.Table, columns, opts.BatchSize, next)
+}
+
+// record is a read record and the line it started on.
+type record struct {
+	fields []field
+	line   int
+}
+
+// newRecordReader returns a func reading the records of r, in the format of
+// the options.
+func newRecordReader(r io.Reader, opts Options) func() (record, error) {
+	if opts.Format == FormatJSON {
+		jr := newJSONReader(r, opts.Keys)
+		return func() (record, error) {
+			fields, err := jr.Read()
+			return record{fields, jr.start}, err
+		}
+	}
+	if opts.Delimiter == 0 {
+		opts.Delimiter = ','
+	}
+	if opts.Quote == 0 {
+		opts.Quote = '"'
+	}
+	rd := newReader(r, opts.Delimiter, opts.Quote)
+	return func() (record, error) {
+		fields, err := rd.Read()
+		return record{fields, rd.start}, err
+	}
+}
+
+// CheckTable checks that table is a table name, optionally schema qualified,
+// of identifiers or quoted identifiers, as it is used as is in the statements
+// of the import.
+func CheckTable(table string) error {
+	r := []rune(table)
+	for i := 0; ; i++ {
+		if i >= len(r) {
+			return fmt.Errorf("invalid table name %q", table)
+		}
+		switch c := r[i]; {
+		case c == '"' || c == '`' || c == '[':
+			end := c
+			if c == '[' {
+				end = ']'
+			}
+			// quotes are escaped by doubling them
+			for i++; i < len(r) && (r[i] != end || i+1 < len(r) && r[i+1] == end); i++ {
+				if r[i] == end {
+					i++
+				}
+			}
+			if i >= len(r) {
+				return fmt.Errorf("invalid table name %q", table)
+			}
+		case c == '_' || unicode.IsLetter(c):
+			for ; i+1 < len(r) && (r[i+1] == '_' || r[i+1] == '$' || unicode.IsLetter(r[i+1]) || unicode.IsDigit(r[i+1])); i++ {
+			}
+		default:
+			return fmt.Errorf("invalid table name %q", table)
+		}
+		switch {
+		case i+1 == len(r):
+			return nil
+		case r[i+1] != '.':
+			return fmt.Errorf("invalid table name %q", table)
+		}
+		i++
+	}
+}
+
+// columnTypes returns the names and database type names of the columns of
+// table.
+func columnTypes(ctx context.Context, db *sql.DB, table string) ([]string, []string, error) {
+	rows, err := db.QueryContext(ctx, "SELECT * FROM "+table+" WHERE 1=0")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to determine target table columns: %w", err)
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch target table columns: %w", err)
+	}
+	names, types := make([]string, len(cts)), make([]string, len(cts))
+	for i, ct := range cts {
+		names[i], types[i] = ct.Name(), ct.DatabaseTypeName()
+	}
+	return names, types, nil
+}
+
+// resolveColumns returns the names and types of the table's columns named
+// columns, matched exactly or else case insensitively, as the columns of the
+// header and options are not quoted identifiers.
+func resolveColumns(columns, tableColumns, tableTypes []string) ([]string, []string, error) {
+	names, types := make([]string, len(columns)), make([]string, len(columns))
+	for i, c := range columns {
+		j := -1
+		for k, name := range tableColumns {
+			if name == c {
+				j = k
+				break
+			}
+			if j == -1 && strings.EqualFold(name, c) {
+				j = k
+			}
+		}
+		if j == -1 {
+			return nil, nil, fmt.Errorf("unknown column %q", c)
+		}
+		names[i], types[i] = tableColumns[j], tableTypes[j]
+	}
+	return names, types, nil
+}
+
+// matchesColumns returns true when every field of rec is the name of one of
+// the columns.
+func matchesColumns(rec []field, columns []string) bool {
+	m := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		m[strings.ToLower(c)] = true
+	}
+	for _, f := range rec {
+		if !m[strings.ToLower(strings.TrimSpace(f.s))] {
+			return false
+		}
+	}
+	return len(rec) != 0
+}
+
+// looksLikeHeader returns true when first has no numeric or empty values,
+// but second has a numeric value in the same position.
+func looksLikeHeader(first, second []field) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	var numeric bool
+	for i, f := range first {
+		if f.s == "" || isNumber(f.s) {
+			return false
+		}
+		numeric = numeric || isNumber(second[i].s)
+	}
+	return numeric
+}
+
+// isNumber returns true when s is a number.
+func isNumber(s string) bool {
+	_, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	return err == nil
+}
+
+// coercer converts a field value to the type of a column.
+type coercer func(string) (interface{}, error)
+
+// timeLayouts are the layouts accepted for date and time columns.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+// coercerFor returns the coercer for a database type name.
+func coercerFor(typ string) coercer {
+	typ = strings.ToUpper(typ)
+	switch {
+	case strings.Contains(typ, "INT") && !strings.Contains(typ, "INTERVAL") && !strings.Contains(typ, "POINT"):
+		return func(s string) (interface{}, error) {
+			return strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+		}
+	case strings.Contains(typ, "FLOAT"), strings.Contains(typ, "DOUBLE"), typ == "REAL":
+		return func(s string) (interface{}, error) {
+			return strconv.ParseFloat(strings.TrimSpace(s), 64)
+		}
+	case strings.HasPrefix(typ, "BOOL"):
+		return func(s string) (interface{}, error) {
+			switch strings.ToLower(strings.TrimSpace(s)) {
+			case "t", "true", "y", "yes", "on", "1":
+				return true, nil
+			case "f", "false", "n", "no", "off", "0":
+				return false, nil
+			}
+			return nil, fmt.Errorf("invalid boolean %q", s)
+		}
+	case typ == "DATE", strings.HasPrefix(typ, "TIMESTAMP"), strings.HasPrefix(typ, "DATETIME"):
+		return func(s string) (interface{}, error) {
+			s = strings.TrimSpace(s)
+			for _, layout := range timeLayouts {
+				if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+					return t, nil
+				}
+			}
+			return nil, fmt.Errorf("invalid time %q", s)
+		}
+	}
+	return func(s string) (interface{}, error) {
+		return s, nil
+	}
+}
@@ -0,0 +1,403 @@
+package importer
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xo/dburl"
+	_ "github.com/xo/usql/drivers/sqlite3"
+)
+
+func TestReader(t *testing.T) {
+	tests := []struct {
+		s     string
+		comma rune
+		quote rune
+		exp   [][]string
+		lines []int
+	}{
+		{"a,b\n1,2\n", ',', '"', [][]string{{"a", "b"}, {"1", "2"}}, []int{1, 2}},
+		{"a,b\r\n1,2", ',', '"', [][]string{{"a", "b"}, {"1", "2"}}, []int{1, 2}},
+		{"a\tb\n\n1\t2\n", '\t', '"', [][]string{{"a", "b"}, {"1", "2"}}, []int{1, 3}},
+		{`"a,b","c""d"` + "\n3,4", ',', '"', [][]string{{"a,b", `c"d`}, {"3", "4"}}, []int{1, 2}},
+		{"'multi\nline',x\ny,z", ',', '\'', [][]string{{"multi\nline", "x"}, {"y", "z"}}, []int{1, 3}},
+		{"a,,\n", ',', '"', [][]string{{"a", "", ""}}, []int{1}},
+	}
+	for i, test := range tests {
+		r := newReader(strings.NewReader(test.s), test.comma, test.quote)
+		var recs [][]string
+		var lines []int
+		for {
+			rec, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("test %d expected no error, got: %v", i, err)
+			}
+			var strs []string
+			for _, f := range rec {
+				strs = append(strs, f.s)
+			}
+			recs, lines = append(recs, strs), append(lines, r.start)
+		}
+		if !reflect.DeepEqual(recs, test.exp) {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, recs)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("test %d expected lines %v, got: %v", i, test.lines, lines)
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	for i, s := range []string{`"abc`, `"abc"d,e`} {
+		r := newReader(strings.NewReader(s), ',', '"')
+		if _, err := r.Read(); err == nil {
+			t.Errorf("test %d expected error, got nil", i)
+		}
+	}
+}
+
+func TestLooksLikeHeader(t *testing.T) {
+	tests := []struct {
+		first, second []string
+		exp           bool
+	}{
+		{[]string{"id", "name"}, []string{"1", "bob"}, true},
+		{[]string{"alice", "bob"}, []string{"carol", "dave"}, false},
+		{[]string{"1", "bob"}, []string{"2", "alice"}, false},
+		{[]string{"id", ""}, []string{"1", "2"}, false},
+	}
+	for i, test := range tests {
+		if b := looksLikeHeader(fields(test.first), fields(test.second)); b != test.exp {
+			t.Errorf("test %d expected %t, got: %t", i, test.exp, b)
+		}
+	}
+}
+
+func TestCoercerFor(t *testing.T) {
+	tests := []struct {
+		typ string
+		s   string
+		exp interface{}
+	}{
+		{"INTEGER", "42", int64(42)},
+		{"int8", " 7 ", int64(7)},
+		{"DOUBLE", "1.5", 1.5},
+		{"BOOL", "yes", true},
+		{"TEXT", "abc", "abc"},
+		{"VARCHAR", "12", "12"},
+	}
+	for i, test := range tests {
+		v, err := coercerFor(test.typ)(test.s)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if v != test.exp {
+			t.Errorf("test %d expected %v (%T), got: %v (%T)", i, test.exp, test.exp, v, v)
+		}
+	}
+	if _, err := coercerFor("INTEGER")("abc"); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func fields(strs []string) []field {
+	var f []field
+	for _, s := range strs {
+		f = append(f, field{s: s})
+	}
+	return f
+}
+
+func TestJSONReader(t *testing.T) {
+	tests := []struct {
+		s     string
+		exp   [][]string
+		nulls [][]bool
+		lines []int
+	}{
+		{
+			`[{"id": 1, "name": "a"}, {"name": "b", "id": 2}]`,
+			[][]string{{"id", "name"}, {"1", "a"}, {"2", "b"}},
+			[][]bool{{false, false}, {false, false}, {false, false}},
+			[]int{1, 1, 1},
+		},
+		{
+			"{\"id\": 1, \"tags\": [\"x\", \"y\"]}\n\n{\"id\": null}\n{\"id\": 3, \"tags\": {\"a\": true}}\n",
+			[][]string{{"id", "tags"}, {"1", `["x","y"]`}, {"", ""}, {"3", `{"a":true}`}},
+			[][]bool{{false, false}, {false, false}, {true, true}, {false, false}},
+			[]int{1, 1, 3, 4},
+		},
+		{
+			"[\n  {\"s\": \"x\\ny\"},\n  {\"s\": false}\n]\n",
+			[][]string{{"s"}, {"x\ny"}, {"false"}},
+			[][]bool{{false}, {false}, {false}},
+			[]int{2, 2, 3},
+		},
+		{"", nil, nil, nil},
+	}
+	for i, test := range tests {
+		r := newJSONReader(strings.NewReader(test.s), nil)
+		var recs [][]string
+		var nulls [][]bool
+		var lines []int
+		for {
+			rec, err := r.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("test %d expected no error, got: %v", i, err)
+			}
+			var strs []string
+			var null []bool
+			for _, f := range rec {
+				strs, null = append(strs, f.s), append(null, f.null)
+			}
+			recs, nulls, lines = append(recs, strs), append(nulls, null), append(lines, r.start)
+		}
+		if !reflect.DeepEqual(recs, test.exp) {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, recs)
+		}
+		if !reflect.DeepEqual(nulls, test.nulls) {
+			t.Errorf("test %d expected nulls %v, got: %v", i, test.nulls, nulls)
+		}
+		if !reflect.DeepEqual(lines, test.lines) {
+			t.Errorf("test %d expected lines %v, got: %v", i, test.lines, lines)
+		}
+	}
+}
+
+func TestJSONReaderErrors(t *testing.T) {
+	for i, s := range []string{`[1, 2]`, `{"a": 1}` + "\n" + `{"b": 2}`, `[{"a": 1}`, `{"a": 1, "a": 2}`} {
+		r := newJSONReader(strings.NewReader(s), nil)
+		var err error
+		for err == nil {
+			_, err = r.Read()
+		}
+		if err == io.EOF {
+			t.Errorf("test %d expected error, got EOF", i)
+		}
+	}
+}
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		s       string
+		dialect string
+		opts    Options
+		header  bool
+		exp     string
+	}{
+		{
+			"ID,First Name,score,active,born,seen\n1,bob,1.5,true,2001-02-03,2024-01-02 03:04:05\n2,,2,false,,2024-01-03\n",
+			"postgres", Options{}, true,
+			"CREATE TABLE t (\n  id BIGINT NOT NULL,\n  first_name TEXT,\n  score DOUBLE PRECISION NOT NULL,\n  active BOOLEAN NOT NULL,\n  born DATE,\n  seen TIMESTAMP NOT NULL\n)",
+		},
+		{
+			"1,x,\n2,3,\n",
+			"sqlite3", Options{Header: HeaderOff}, false,
+			"CREATE TABLE t (\n  column1 INTEGER NOT NULL,\n  column2 TEXT NOT NULL,\n  column3 TEXT\n)",
+		},
+		{
+			`[{"order": 1, "key": "a", "ok": true}, {"order": 2, "key": null, "ok": false}]`,
+			"mysql", Options{Format: FormatJSON}, true,
+			"CREATE TABLE t (\n  order_ BIGINT NOT NULL,\n  key_ TEXT,\n  ok TEXT NOT NULL\n)",
+		},
+		{
+			"{\"id\": 1}\n{\"id\": 2, \"name\": \"b\"}\n{\"score\": 1.5, \"id\": 3}\n",
+			"postgres", Options{Format: FormatJSON}, true,
+			"CREATE TABLE t (\n  id BIGINT NOT NULL,\n  name TEXT,\n  score DOUBLE PRECISION\n)",
+		},
+		{
+			"a,a,b\n1,2,3\n",
+			"sqlite3", Options{Header: HeaderOn, Columns: []string{"x", "y", "z"}}, true,
+			"CREATE TABLE t (\n  x INTEGER NOT NULL,\n  y INTEGER NOT NULL,\n  z INTEGER NOT NULL\n)",
+		},
+		{
+			"1,2,3\n",
+			"mysql", Options{Header: HeaderOff, Columns: []string{"First Name", "order", "x`y"}}, false,
+			"CREATE TABLE t (\n  `First Name` BIGINT NOT NULL,\n  `order` BIGINT NOT NULL,\n  `x``y` BIGINT NOT NULL\n)",
+		},
+		{
+			"1\n",
+			"postgres", Options{Header: HeaderOff, Columns: []string{`a) VALUES (1); DROP TABLE t; --"`}}, false,
+			"CREATE TABLE t (\n  \"a) VALUES (1); DROP TABLE t; --\"\"\" BIGINT NOT NULL\n)",
+		},
+		{
+			"a,a,2b\nNULL,2,3\n",
+			"sqlite3", Options{Header: HeaderOn, Null: "NULL"}, true,
+			"CREATE TABLE t (\n  a TEXT,\n  a_2 INTEGER NOT NULL,\n  _2b INTEGER NOT NULL\n)",
+		},
+	}
+	for i, test := range tests {
+		s, err := Infer(strings.NewReader(test.s), test.dialect, test.opts, 0)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if s.Header != test.header {
+			t.Errorf("test %d expected header %t, got: %t", i, test.header, s.Header)
+		}
+		if stmt := s.CreateTable("t"); stmt != test.exp {
+			t.Errorf("test %d expected:\n%s\ngot:\n%s", i, test.exp, stmt)
+		}
+	}
+}
+
+func TestInferKeys(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.db")
+	u, err := dburl.Parse("sqlite3:" + file)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	tests := []struct {
+		s   string
+		exp []string
+		err string
+	}{
+		{"{\"id\": 1}\n{\"Full Name\": \"b\", \"id\": 2}\n{\"id\": 3}\n", []string{"1 NULL", "2 b", "3 NULL"}, ""},
+		{"{\"id\": 1}\n{\"id\": 2}\n{\"id\": 3, \"Full Name\": \"c\"}\n", nil, `line 3: unexpected key "Full Name", not in the sampled objects`},
+	}
+	for i, test := range tests {
+		// the keys of the sampled objects are the columns
+		s, err := Infer(strings.NewReader(test.s), "sqlite3", Options{Format: FormatJSON}, 2)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if _, err := db.Exec("DROP TABLE IF EXISTS t; " + s.CreateTable("t")); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		opts := Options{Table: "t", Format: FormatJSON, Columns: s.ColumnNames(), Keys: s.Keys}
+		_, err = Import(context.Background(), u, db, strings.NewReader(test.s), opts)
+		switch {
+		case test.err != "" && (err == nil || err.Error() != test.err):
+			t.Fatalf("test %d expected error %q, got: %v", i, test.err, err)
+		case test.err == "" && err != nil:
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		case test.err != "":
+			continue
+		}
+		var rows []string
+		r, err := db.Query(`SELECT id || ' ' || coalesce(full_name, 'NULL') FROM t ORDER BY id`)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		for r.Next() {
+			var s string
+			if err := r.Scan(&s); err != nil {
+				t.Fatalf("test %d expected no error, got: %v", i, err)
+			}
+			rows = append(rows, s)
+		}
+		r.Close()
+		if !reflect.DeepEqual(rows, test.exp) {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, rows)
+		}
+	}
+}
+
+func TestCheckTable(t *testing.T) {
+	tests := []struct {
+		s  string
+		ok bool
+	}{
+		{"t", true},
+		{"public.t_1", true},
+		{`"my table"`, true},
+		{`"public"."a""b"`, true},
+		{"`db`.`a``b`", true},
+		{"[dbo].[a]]b]", true},
+		{"", false},
+		{"t;", false},
+		{"t WHERE 1=1; DROP TABLE t", false},
+		{"public.", false},
+		{".t", false},
+		{"1t", false},
+		{`"a""`, false},
+		{`"a"b`, false},
+		{"t --", false},
+	}
+	for i, test := range tests {
+		if err := CheckTable(test.s); (err == nil) != test.ok {
+			t.Errorf("test %d expected ok %t for %q, got: %v", i, test.ok, test.s, err)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "test.db")
+	u, err := dburl.Parse("sqlite3:" + file)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db, err := sql.Open("sqlite3", file)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	ctx := context.Background()
+	tests := []struct {
+		s    string
+		opts Options
+		exp  string
+		err  string
+	}{
+		{"First Name,order\nbob,1\n", Options{}, "bob 1", ""},
+		{"ORDER,first name\n2,alice\n", Options{}, "alice 2", ""},
+		{"carol,3\n", Options{Header: HeaderOff, Columns: []string{"FIRST NAME", "Order"}}, "carol 3", ""},
+		{"first name,order) VALUES (1); DROP TABLE t; --\nbob,1\n", Options{Header: HeaderOn}, "", `unknown column "order) VALUES (1); DROP TABLE t; --"`},
+		{"dave,4\n", Options{Header: HeaderOff, Columns: []string{"first name", "x"}}, "", `unknown column "x"`},
+		{"dave,4\n", Options{Table: "t WHERE 1=1; DROP TABLE t; --", Header: HeaderOff}, "", `invalid table name "t WHERE 1=1; DROP TABLE t; --"`},
+	}
+	for i, test := range tests {
+		if _, err := db.Exec(`DROP TABLE IF EXISTS t; CREATE TABLE t ("first name" TEXT, "order" INTEGER)`); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		opts := test.opts
+		if opts.Table == "" {
+			opts.Table = "t"
+		}
+		for _, noBulk := range []bool{false, true} {
+			opts.NoBulk = noBulk
+			_, err := Import(ctx, u, db, strings.NewReader(test.s), opts)
+			switch {
+			case test.err != "" && (err == nil || err.Error() != test.err):
+				t.Fatalf("test %d expected error %q, got: %v", i, test.err, err)
+			case test.err == "" && err != nil:
+				t.Fatalf("test %d expected no error, got: %v", i, err)
+			}
+		}
+		var rows []string
+		r, err := db.Query(`SELECT "first name" || ' ' || "order" FROM t`)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		for r.Next() {
+			var s string
+			if err := r.Scan(&s); err != nil {
+				t.Fatalf("test %d expected no error, got: %v", i, err)
+			}
+			rows = append(rows, s)
+		}
+		r.Close()
+		var exp []string
+		if test.exp != "" {
+			exp = []string{test.exp, test.exp}
+		}
+		if !reflect.DeepEqual(rows, exp) {
+			t.Errorf("test %d expected %q, got: %q", i, exp, rows)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/sqlfmt"
+)
+
+// DefaultSample is the default number of records sampled to infer the schema
+// of a file.
+const DefaultSample = 1000
+
+// Schema is the schema of a table inferred from a file.
+type Schema struct {
+	// Columns are the columns of the table.
+	Columns []Column
+	// Header is set when the first record of the file is a header.
+	Header bool
+	// Keys are the keys of the sampled JSON objects, in the order of the
+	// columns, to import the objects with (see Options.Keys).
+	Keys []string
+	// dialect is the dialect the schema was inferred in.
+	dialect string
+}
+
+// Column is a column of an inferred schema.
+type Column struct {
+	// Name is the name of the column, a lower case identifier unless named
+	// after opts.Columns.
+	Name string
+	// Type is the database type of the column.
+	Type string
+	// Nullable is set when a sampled value of the column is NULL.
+	Nullable bool
+}
+
+// ColumnNames returns the names of the columns of the schema.
+func (s *Schema) ColumnNames() []string {
+	names := make([]string, len(s.Columns))
+	for i, c := range s.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// CreateTable returns the CREATE TABLE statement of the table for the schema.
+// The table name is used as is, as by Import (see CheckTable), and the column
+// names other than lower case identifiers are quoted.
+func (s *Schema) CreateTable(table string) string {
+	d, keywords := dump.DialectFor(s.dialect), sqlfmt.Keywords(s.dialect)
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE " + table + " (")
+	for i, c := range s.Columns {
+		if i != 0 {
+			sb.WriteString(",")
+		}
+		name := c.Name
+		if !isIdentifier(name) || keywords[strings.ToUpper(name)] {
+			name = d.QuoteIdent(name)
+		}
+		sb.WriteString("\n  " + name + " " + c.Type)
+		if !c.Nullable {
+			sb.WriteString(" NOT NULL")
+		}
+	}
+	sb.WriteString("\n)")
+	return sb.String()
+}
+
+// kind is a kind of inferred values, from the narrowest to the widest.
+type kind int
+
+// Inferred kinds.
+const (
+	kindBool kind = iota
+	kindInt
+	kindFloat
+	kindDate
+	kindTimestamp
+	kindText
+)
+
+// typeNames are the database types of the inferred kinds, by dialect (see
+// drivers.Capabilities). Kinds without a type are not inferred, as booleans
+// stored as integers.
+var typeNames = map[string][]string{
+	"":          {"BOOLEAN", "BIGINT", "DOUBLE PRECISION", "DATE", "TIMESTAMP", "TEXT"},
+	"mysql":     {"", "BIGINT", "DOUBLE", "DATE", "DATETIME(6)", "TEXT"},
+	"sqlite3":   {"BOOLEAN", "INTEGER", "REAL", "DATE", "TIMESTAMP", "TEXT"},
+	"sqlserver": {"BIT", "BIGINT", "FLOAT", "DATE", "DATETIME2", "NVARCHAR(MAX)"},
+	"oracle":    {"", "NUMBER(19)", "BINARY_DOUBLE", "DATE", "TIMESTAMP", "CLOB"},
+}
+
+// Infer samples the first n records of r to infer the schema of a table for
+// them, in the dialect (see drivers.Capabilities). The type of each column is
+// the narrowest of boolean, integer, floating point, date, timestamp and text
+// matching all its sampled values, and columns with NULL values are nullable.
+//
+// The columns are named after opts.Columns, or after the header (lower cased,
+// with the characters other than letters and digits replaced by
+// underscores), or are named column1, column2, ... when there is no header.
+// The header of JSON objects is the keys of all the sampled objects, the keys
+// missing from the first object following its keys in the order they appear,
+// and the columns of keys missing from an object are nullable.
+func Infer(r io.Reader, dialect string, opts Options, n int) (*Schema, error) {
+	if n <= 0 {
+		n = DefaultSample
+	}
+	read := newRecordReader(r, opts)
+	var jr *jsonReader
+	if opts.Format == FormatJSON {
+		jr = newJSONReader(r, opts.Keys)
+		jr.grow = true
+		read = func() (record, error) {
+			fields, err := jr.Read()
+			return record{fields, jr.start}, err
+		}
+	}
+	first, err := read()
+	switch {
+	case err == io.EOF:
+		return nil, fmt.Errorf("no records to infer the schema of")
+	case err != nil:
+		return nil, err
+	}
+	pending := []record{first}
+	s := &Schema{dialect: dialect}
+	switch {
+	case opts.Format == FormatJSON, opts.Header == HeaderOn:
+		s.Header = true
+	case opts.Header == HeaderAuto:
+		second, err := read()
+		switch {
+		case err == io.EOF:
+		case err != nil:
+			return nil, err
+		default:
+			pending = append(pending, second)
+			s.Header = looksLikeHeader(first.fields, second.fields)
+		}
+	}
+	if s.Header {
+		pending = pending[1:]
+	}
+	if jr != nil {
+		// the sampled objects are read before naming the columns after their
+		// keys
+		for len(pending) < n {
+			rec, err := read()
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+			pending = append(pending, rec)
+		}
+		s.Keys = jr.keys
+		first.fields = make([]field, len(jr.keys))
+		for i, k := range jr.keys {
+			first.fields[i] = field{s: k, quoted: true}
+		}
+		for i := range pending {
+			for len(pending[i].fields) < len(jr.keys) {
+				pending[i].fields = append(pending[i].fields, field{null: true})
+			}
+		}
+		read = func() (record, error) {
+			return record{}, io.EOF
+		}
+	}
+	names, err := columnNames(first.fields, s.Header, opts.Columns, dialect)
+	if err != nil {
+		return nil, err
+	}
+	// the kinds each column's values match, as a bit set
+	matches := make([]int, len(names))
+	nullable, valued := make([]bool, len(names)), make([]bool, len(names))
+	for i := range matches {
+		matches[i] = 1<<kindText - 1
+	}
+	for count := 0; count < n; count++ {
+		var rec record
+		if len(pending) != 0 {
+			rec, pending = pending[0], pending[1:]
+		} else if rec, err = read(); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if len(rec.fields) != len(names) {
+			return nil, fmt.Errorf("line %d: expected %d fields, got: %d", rec.line, len(names), len(rec.fields))
+		}
+		for i, f := range rec.fields {
+			if f.null || !f.quoted && f.s == opts.Null {
+				nullable[i] = true
+				continue
+			}
+			matches[i], valued[i] = matches[i]&kinds(f.s), true
+		}
+	}
+	types := typeNames[dialect]
+	if types == nil {
+		types = typeNames[""]
+	}
+	for i, name := range names {
+		// columns without values are text
+		k := kindBool
+		for ; k < kindText && (!valued[i] || matches[i]&(1<<k) == 0 || types[k] == ""); k++ {
+		}
+		s.Columns = append(s.Columns, Column{Name: name, Type: types[k], Nullable: nullable[i]})
+	}
+	return s, nil
+}
+
+// kinds returns the kinds matching the value, as a bit set.
+func kinds(s string) int {
+	s = strings.TrimSpace(s)
+	var m int
+	switch strings.ToLower(s) {
+	case "true", "false":
+		m |= 1 << kindBool
+	}
+	// ParseFloat accepts infinities and NaNs, kept as text
+	if s != "" && strings.IndexFunc(s, notNumber) == -1 {
+		if _, err := strconv.ParseInt(s, 10, 64); err == nil {
+			m |= 1 << kindInt
+		}
+		if _, err := strconv.ParseFloat(s, 64); err == nil {
+			m |= 1 << kindFloat
+		}
+	}
+	if _, err := time.Parse("2006-01-02", s); err == nil {
+		m |= 1 << kindDate
+	}
+	for _, layout := range timeLayouts {
+		if _, err := time.Parse(layout, s); err == nil {
+			m |= 1 << kindTimestamp
+			break
+		}
+	}
+	return m
+}
+
+// notNumber returns true when c is not a character of a decimal number.
+func notNumber(c rune) bool {
+	return !strings.ContainsRune("0123456789+-.eE", c)
+}
+
+// columnNames returns the names of the columns of the first record.
+func columnNames(first []field, header bool, columns []string, dialect string) ([]string, error) {
+	if len(columns) != 0 {
+		if len(columns) != len(first) {
+			return nil, fmt.Errorf("expected %d columns, got: %d", len(first), len(columns))
+		}
+		return columns, nil
+	}
+	keywords := sqlfmt.Keywords(dialect)
+	names, seen := make([]string, len(first)), make(map[string]bool, len(first))
+	for i, f := range first {
+		name := fmt.Sprintf("column%d", i+1)
+		if header {
+			name = identifier(f.s, i)
+		}
+		// avoid keywords and duplicate names
+		if keywords[strings.ToUpper(name)] {
+			name += "_"
+		}
+		for j, base := 2, name; seen[name]; j++ {
+			name = fmt.Sprintf("%s_%d", base, j)
+		}
+		names[i], seen[name] = name, true
+	}
+	return names, nil
+}
+
+// isIdentifier returns true when s is a lower case identifier, as returned by
+// identifier.
+func isIdentifier(s string) bool {
+	for i, c := range s {
+		if c != '_' && (c < 'a' || c > 'z') && (i == 0 || c < '0' || c > '9') {
+			return false
+		}
+	}
+	return s != ""
+}
+
+// identifier returns the header value as a lower case identifier, of the
+// letters, digits and underscores of s, with the other characters replaced
+// by underscores.
+func identifier(s string, i int) string {
+	var sb strings.Builder
+	var under bool
+	for _, c := range strings.ToLower(strings.TrimSpace(s)) {
+		switch {
+		case c < unicode.MaxASCII && (unicode.IsLetter(c) || unicode.IsDigit(c)):
+			sb.WriteRune(c)
+			under = false
+		case !under && sb.Len() != 0:
+			sb.WriteRune('_')
+			under = true
+		}
+	}
+	name := strings.TrimSuffix(sb.String(), "_")
+	switch {
+	case name == "":
+		return fmt.Sprintf("column%d", i+1)
+	case unicode.IsDigit(rune(name[0])):
+		return "_" + name
+	}
+	return name
+}
@@ -0,0 +1,262 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonReader reads the objects of a JSON array, or of a stream of JSON values
+// (ie, JSON lines), as records. The first record is the header, the keys of
+// the first object unless set, and the values of the objects are returned in
+// the order of the header. Missing keys and null values are NULL fields,
+// strings are quoted fields, and nested objects and arrays are fields of
+// their JSON.
+type jsonReader struct {
+	r   *bufio.Reader
+	dec *json.Decoder
+	// buf is the input read by the decoder after off.
+	buf []byte
+	off int64
+	// line is the line the input after off starts on.
+	line int
+	// start is the line the last read record started on.
+	start int
+	// keys are the keys of the header, and index their index. preset is set
+	// when they are not the keys of the first object.
+	keys   []string
+	index  map[string]int
+	preset bool
+	// grow adds the keys missing from the header to it, instead of failing,
+	// the records read before having fewer fields.
+	grow bool
+	// next are the fields of the first object, returned after the header.
+	next    []field
+	started bool
+	array   bool
+	done    bool
+}
+
+// newJSONReader creates a new JSON record reader, with the keys of the header
+// when not nil.
+func newJSONReader(r io.Reader, keys []string) *jsonReader {
+	jr := &jsonReader{r: bufio.NewReader(r), line: 1}
+	jr.dec = json.NewDecoder(io.TeeReader(jr.r, jr))
+	if keys != nil {
+		jr.keys, jr.index, jr.preset = keys, make(map[string]int, len(keys)), true
+		for i, k := range keys {
+			jr.index[k] = i
+		}
+	}
+	return jr
+}
+
+// Write satisfies the io.Writer interface, keeping the input read by the
+// decoder to count its lines.
+func (r *jsonReader) Write(p []byte) (int, error) {
+	r.buf = append(r.buf, p...)
+	return len(p), nil
+}
+
+// Read reads the next record, returning io.EOF when there are no more
+// records.
+func (r *jsonReader) Read() ([]field, error) {
+	switch {
+	case r.done:
+		return nil, io.EOF
+	case r.next != nil:
+		rec := r.next
+		r.next = nil
+		return rec, nil
+	case !r.started:
+		return r.header()
+	}
+	raw, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	keys, values, err := r.object(raw)
+	if err != nil {
+		return nil, err
+	}
+	return r.record(keys, values)
+}
+
+// record returns the fields of the values of an object, in the order of the
+// header.
+func (r *jsonReader) record(keys []string, values []json.RawMessage) ([]field, error) {
+	rec := make([]field, len(r.keys))
+	for i := range rec {
+		rec[i].null = true
+	}
+	for i, k := range keys {
+		j, ok := r.index[k]
+		switch {
+		case !ok && r.grow:
+			j = len(r.keys)
+			r.keys, r.index[k], rec = append(r.keys, k), j, append(rec, field{})
+		case !ok && r.preset:
+			return nil, fmt.Errorf("line %d: unexpected key %q, not in the sampled objects", r.start, k)
+		case !ok:
+			return nil, fmt.Errorf("line %d: unexpected key %q, not in the first object", r.start, k)
+		}
+		var err error
+		if rec[j], err = jsonField(values[i]); err != nil {
+			return nil, fmt.Errorf("line %d: key %s: %w", r.start, k, err)
+		}
+	}
+	return rec, nil
+}
+
+// header reads the first object, returning the keys of the header, and
+// keeping its values as the next record.
+func (r *jsonReader) header() ([]field, error) {
+	// a JSON array of objects, or a stream of objects
+	if err := r.skipSpace(); err != nil {
+		return nil, err
+	}
+	if b, err := r.r.Peek(1); err == nil && b[0] == '[' {
+		if _, err := r.dec.Token(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		r.advance(0)
+		r.array = true
+	}
+	raw, err := r.value()
+	if err != nil {
+		return nil, err
+	}
+	keys, values, err := r.object(raw)
+	switch {
+	case err != nil:
+		return nil, err
+	case len(keys) == 0:
+		return nil, fmt.Errorf("line %d: expected an object with keys", r.start)
+	}
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if seen[k] {
+			return nil, fmt.Errorf("line %d: duplicate key %q", r.start, k)
+		}
+		seen[k] = true
+	}
+	r.started = true
+	if !r.preset {
+		r.keys, r.index = append([]string(nil), keys...), make(map[string]int, len(keys))
+		for i, k := range keys {
+			r.index[k] = i
+		}
+	}
+	hdr := make([]field, len(r.keys))
+	for i, k := range r.keys {
+		hdr[i] = field{s: k, quoted: true}
+	}
+	if r.next, err = r.record(keys, values); err != nil {
+		return nil, err
+	}
+	return hdr, nil
+}
+
+// value reads the next value, returning io.EOF at the end of the input, or of
+// the array.
+func (r *jsonReader) value() (json.RawMessage, error) {
+	if r.array && !r.dec.More() {
+		r.done = true
+		if _, err := r.dec.Token(); err != nil {
+			return nil, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return nil, io.EOF
+	}
+	var raw json.RawMessage
+	switch err := r.dec.Decode(&raw); {
+	case err == io.EOF && !r.array:
+		r.done = true
+		return nil, io.EOF
+	case err == io.EOF:
+		return nil, fmt.Errorf("line %d: unterminated array", r.line)
+	case err != nil:
+		return nil, fmt.Errorf("line %d: %w", r.line, err)
+	}
+	r.advance(len(raw))
+	return raw, nil
+}
+
+// advance advances the read input to the end of the last decoded value of n
+// bytes, setting the line it started on.
+func (r *jsonReader) advance(n int) {
+	off := r.dec.InputOffset()
+	end := int(off - r.off)
+	r.start = r.line + bytes.Count(r.buf[:end-n], []byte{'\n'})
+	r.line += bytes.Count(r.buf[:end], []byte{'\n'})
+	r.buf, r.off = append(r.buf[:0], r.buf[end:]...), off
+}
+
+// skipSpace skips the leading white space of the input, before it is read by
+// the decoder.
+func (r *jsonReader) skipSpace() error {
+	for {
+		b, err := r.r.Peek(1)
+		switch {
+		case err == io.EOF:
+			return nil
+		case err != nil:
+			return err
+		}
+		switch b[0] {
+		case '\n':
+			r.line++
+		case ' ', '\t', '\r':
+		default:
+			return nil
+		}
+		_, _ = r.r.Discard(1)
+	}
+}
+
+// object returns the keys and values of an object, in order.
+func (r *jsonReader) object(raw json.RawMessage) ([]string, []json.RawMessage, error) {
+	if len(raw) == 0 || raw[0] != '{' {
+		return nil, nil, fmt.Errorf("line %d: expected an object", r.start)
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	if _, err := dec.Token(); err != nil {
+		return nil, nil, err
+	}
+	var keys []string
+	var values []json.RawMessage
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", r.start, err)
+		}
+		var v json.RawMessage
+		if err := dec.Decode(&v); err != nil {
+			return nil, nil, fmt.Errorf("line %d: %w", r.start, err)
+		}
+		keys, values = append(keys, tok.(string)), append(values, v)
+	}
+	return keys, values, nil
+}
+
+// jsonField returns the field of a JSON value.
+func jsonField(v json.RawMessage) (field, error) {
+	switch v[0] {
+	case 'n':
+		return field{null: true}, nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			return field{}, err
+		}
+		return field{s: s, quoted: true}, nil
+	case '{', '[':
+		var buf bytes.Buffer
+		if err := json.Compact(&buf, v); err != nil {
+			return field{}, err
+		}
+		return field{s: buf.String(), quoted: true}, nil
+	}
+	return field{s: string(v), quoted: true}, nil
+}
@@ -0,0 +1,106 @@
+package importer
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// field is a single field of a record.
+type field struct {
+	s      string
+	quoted bool
+	// null is set for the NULL fields of formats with NULL values (ie, JSON).
+	null bool
+}
+
+// reader reads delimited records, where fields may be enclosed in quote
+// characters. A quote character inside of a quoted field is escaped by
+// doubling it.
+type reader struct {
+	r     *bufio.Reader
+	comma rune
+	quote rune
+	// line is the current line.
+	line int
+	// start is the line the last read record started on.
+	start int
+}
+
+// newReader creates a new record reader.
+func newReader(r io.Reader, comma, quote rune) *reader {
+	return &reader{
+		r:     bufio.NewReader(r),
+		comma: comma,
+		quote: quote,
+		line:  1,
+	}
+}
+
+// Read reads the next record, returning io.EOF when there are no more
+// records. Empty lines are skipped.
+func (r *reader) Read() ([]field, error) {
+	var rec []field
+	var sb strings.Builder
+	var quoted, inQuote, afterQuote, any bool
+	r.start = r.line
+	flush := func() {
+		rec = append(rec, field{s: sb.String(), quoted: quoted})
+		sb.Reset()
+		quoted, afterQuote = false, false
+	}
+	for {
+		c, _, err := r.r.ReadRune()
+		switch {
+		case errors.Is(err, io.EOF):
+			if inQuote {
+				return nil, fmt.Errorf("line %d: unterminated quoted field", r.start)
+			}
+			if !any {
+				return nil, io.EOF
+			}
+			flush()
+			return rec, nil
+		case err != nil:
+			return nil, err
+		}
+		switch {
+		case inQuote && c == r.quote:
+			// doubled quote is an escaped quote
+			if n, _, err := r.r.ReadRune(); err == nil && n == r.quote {
+				sb.WriteRune(c)
+				continue
+			} else if err == nil {
+				_ = r.r.UnreadRune()
+			}
+			inQuote, afterQuote = false, true
+		case inQuote:
+			if c == '\n' {
+				r.line++
+			}
+			sb.WriteRune(c)
+		case c == r.comma:
+			any = true
+			flush()
+		case c == '\r':
+			// handled with \n
+		case c == '\n':
+			r.line++
+			if !any {
+				r.start = r.line
+				continue
+			}
+			flush()
+			return rec, nil
+		case c == r.quote && sb.Len() == 0 && !afterQuote:
+			any, quoted, inQuote = true, true, true
+		case afterQuote:
+			return nil, fmt.Errorf("line %d: unexpected %q after quoted field", r.line, c)
+		default:
+			any = true
+			sb.WriteRune(c)
+		}
+	}
+}
@@ -28,6 +28,7 @@ func KnownBuildTags() map[string]string {
 		"impala":        "impala",        // github.com/bippio/go-impala
 		"maxcompute":    "maxcompute",    // sqlflow.org/gomaxcompute
 		"moderncsqlite": "moderncsqlite", // modernc.org/sqlite
+		"mongodb":       "mongodb",       // github.com/xo/usql/drivers/mongodb/mongosql
 		"mymysql":       "mymysql",       // github.com/ziutek/mymysql/godrv
 		"mysql":         "mysql",         // github.com/go-sql-driver/mysql
 		"netezza":       "nzgo",          // github.com/IBM/nzgo/v12
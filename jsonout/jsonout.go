@@ -0,0 +1,177 @@
+// Package jsonout writes the JSON output of usql with --json: the errors,
+// with their machine-readable codes, and the metadata of the results, one
+// JSON object per line.
+package jsonout
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/offline"
+	"github.com/xo/usql/policy"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/text"
+)
+
+// Error codes.
+const (
+	// CodeError is the code of the errors without a more specific code.
+	CodeError = "error"
+	// CodeConfig is the code of the errors of the config file.
+	CodeConfig = "config_error"
+	// CodeConnection is the code of the errors connecting to a database.
+	CodeConnection = "connection_error"
+	// CodeDatabase is the code of the errors returned by a database.
+	CodeDatabase = "database_error"
+	// CodeDriverNotAvailable is the code of the errors of the drivers not
+	// built in usql.
+	CodeDriverNotAvailable = "driver_not_available"
+	// CodeNotConnected is the code of the statements executed without a
+	// connection.
+	CodeNotConnected = "not_connected"
+	// CodeUnknownCommand is the code of the unknown backslash commands.
+	CodeUnknownCommand = "unknown_command"
+	// CodeMissingArgument is the code of the backslash commands missing a
+	// required argument.
+	CodeMissingArgument = "missing_argument"
+	// CodePolicyViolation is the code of the statements denied by the
+	// statement policy of the role, or not confirmed.
+	CodePolicyViolation = "policy_violation"
+	// CodeCanceled is the code of the canceled statements.
+	CodeCanceled = "canceled"
+	// CodePartialFailure is the code of the operations on several
+	// databases, files or tables of which some failed.
+	CodePartialFailure = "partial_failure"
+	// CodeAlert is the code of the watched queries whose value crossed the
+	// threshold of an exit alert.
+	CodeAlert = "alert"
+	// CodeOffline is the code of the statements refused in offline mode, and
+	// of the database aliases without a metadata snapshot.
+	CodeOffline = "offline"
+	// CodeDifferent is the code of the comparisons of databases finding
+	// differences, such as usql datadiff.
+	CodeDifferent = "different"
+)
+
+// codeError is an error with a code.
+type codeError struct {
+	code string
+	err  error
+}
+
+// WithCode wraps err with the code, when not nil.
+func WithCode(code string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return &codeError{code, err}
+}
+
+// Error satisfies the error interface.
+func (e *codeError) Error() string { return e.err.Error() }
+
+// Unwrap returns the original error.
+func (e *codeError) Unwrap() error { return e.err }
+
+// Code returns the code of err: the code it was wrapped with (see WithCode),
+// or else the code of the known errors, or CodeError.
+func Code(err error) string {
+	var ce *codeError
+	var v *policy.Violation
+	var u *policy.Unconfirmed
+	var de *drivers.Error
+	switch {
+	case errors.Is(err, text.ErrDriverNotAvailable):
+		return CodeDriverNotAvailable
+	case errors.As(err, &ce):
+		return ce.code
+	case errors.Is(err, text.ErrNotConnected):
+		return CodeNotConnected
+	case errors.Is(err, text.ErrOffline), errors.Is(err, offline.ErrNoSnapshot):
+		return CodeOffline
+	case errors.Is(err, text.ErrUnknownCommand):
+		return CodeUnknownCommand
+	case errors.Is(err, text.ErrMissingRequiredArgument):
+		return CodeMissingArgument
+	case errors.Is(err, context.Canceled):
+		return CodeCanceled
+	case errors.As(err, &v), errors.As(err, &u):
+		return CodePolicyViolation
+	case errors.As(err, &de), sqlState(err) != "":
+		return CodeDatabase
+	}
+	return CodeError
+}
+
+// sqlState returns the SQLSTATE of the database errors reporting it.
+func sqlState(err error) string {
+	var e interface{ SQLState() string }
+	if errors.As(err, &e) {
+		return e.SQLState()
+	}
+	return ""
+}
+
+// ErrorInfo is the JSON object of an error.
+type ErrorInfo struct {
+	Code     string `json:"code"`
+	Message  string `json:"message"`
+	SQLState string `json:"sqlstate,omitempty"`
+}
+
+// Info returns the JSON object of err, with its secrets redacted.
+func Info(err error) *ErrorInfo {
+	return &ErrorInfo{
+		Code:     Code(err),
+		Message:  redact.String(err.Error()),
+		SQLState: sqlState(err),
+	}
+}
+
+// WriteError writes err as an {"error": {"code", "message", "sqlstate"}}
+// object, with its secrets redacted.
+func WriteError(w io.Writer, err error) error {
+	return Write(w, map[string]*ErrorInfo{"error": Info(err)})
+}
+
+// Result is the JSON object of the result of a statement executed without
+// returning rows.
+type Result struct {
+	Command      string `json:"command"`
+	RowsAffected int64  `json:"rows_affected"`
+}
+
+// Meta is the JSON object of the metadata of the result of a statement,
+// written after it.
+type Meta struct {
+	Command string `json:"command"`
+	// RowsReturned and RowsAffected are the number of rows returned by a
+	// query, or affected by a statement not returning rows, when known.
+	RowsReturned *int64 `json:"rows_returned,omitempty"`
+	RowsAffected *int64 `json:"rows_affected,omitempty"`
+	// Warnings are the warnings reported by the driver (ie, SHOW WARNINGS).
+	Warnings []string `json:"warnings"`
+	// Notices are the notices of the database (ie, RAISE NOTICE).
+	Notices       []string `json:"notices"`
+	ServerVersion string   `json:"server_version,omitempty"`
+}
+
+// WriteMeta writes m as a {"meta": {...}} object, with nil warnings and
+// notices written as empty arrays.
+func WriteMeta(w io.Writer, m Meta) error {
+	if m.Warnings == nil {
+		m.Warnings = []string{}
+	}
+	if m.Notices == nil {
+		m.Notices = []string{}
+	}
+	return Write(w, map[string]Meta{"meta": m})
+}
+
+// Write writes v as a line of JSON.
+func Write(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,67 @@
+package jsonout
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/xo/usql/policy"
+	"github.com/xo/usql/text"
+)
+
+type sqlStateError struct{}
+
+func (sqlStateError) Error() string    { return "relation does not exist" }
+func (sqlStateError) SQLState() string { return "42P01" }
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		err error
+		exp string
+	}{
+		{errors.New("failed"), CodeError},
+		{WithCode(CodeConfig, errors.New("invalid config file")), CodeConfig},
+		{fmt.Errorf("wrapped: %w", text.ErrNotConnected), CodeNotConnected},
+		{&policy.Violation{Role: "reader"}, CodePolicyViolation},
+		{text.ErrOffline, CodeOffline},
+		{sqlStateError{}, CodeDatabase},
+	}
+	for i, test := range tests {
+		if code := Code(test.err); code != test.exp {
+			t.Errorf("test %d expected %s, got: %s", i, test.exp, code)
+		}
+	}
+}
+
+func TestWriteError(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteError(&buf, fmt.Errorf("query: %w", sqlStateError{})); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := `{"error":{"code":"database_error","message":"query: relation does not exist","sqlstate":"42P01"}}` + "\n"
+	if s := buf.String(); s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+}
+
+func TestWriteMeta(t *testing.T) {
+	rows := int64(2)
+	tests := []struct {
+		m   Meta
+		exp string
+	}{
+		{Meta{Command: "SELECT", RowsReturned: &rows, ServerVersion: "PostgreSQL 15.2"}, `{"meta":{"command":"SELECT","rows_returned":2,"warnings":[],"notices":[],"server_version":"PostgreSQL 15.2"}}`},
+		{Meta{Command: "INSERT", RowsAffected: &rows, Warnings: []string{"Warning 1265: Data truncated"}}, `{"meta":{"command":"INSERT","rows_affected":2,"warnings":["Warning 1265: Data truncated"],"notices":[]}}`},
+		{Meta{Command: "DO", Notices: []string{"NOTICE: hello"}}, `{"meta":{"command":"DO","warnings":[],"notices":["NOTICE: hello"]}}`},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteMeta(&buf, test.m); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if s := buf.String(); s != test.exp+"\n" {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp+"\n", s)
+		}
+	}
+}
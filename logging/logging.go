@@ -0,0 +1,77 @@
+// Package logging writes the diagnostic messages of usql to stderr, for
+// troubleshooting the config file and the connections: with --verbose, the
+// chosen config file and the resolved database, role and (redacted) DSN, and
+// with --debug, the config file discovery steps and the statements sent to
+// the drivers too.
+package logging
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/xo/usql/redact"
+)
+
+// Level is a logging level.
+type Level int
+
+// Logging levels.
+const (
+	// LevelDefault logs no diagnostic messages.
+	LevelDefault Level = iota
+	// LevelVerbose logs the config file and the connections.
+	LevelVerbose
+	// LevelDebug logs the config file discovery steps and the statements
+	// sent to the drivers too.
+	LevelDebug
+)
+
+// logger is the logger of the messages.
+var logger = struct {
+	sync.Mutex
+	level Level
+	w     io.Writer
+}{w: os.Stderr}
+
+// SetLevel sets the logging level.
+func SetLevel(level Level) {
+	logger.Lock()
+	defer logger.Unlock()
+	logger.level = level
+}
+
+// SetOutput sets the output of the messages (default stderr).
+func SetOutput(w io.Writer) {
+	logger.Lock()
+	defer logger.Unlock()
+	logger.w = w
+}
+
+// Enabled returns true when the messages of the level are logged.
+func Enabled(level Level) bool {
+	logger.Lock()
+	defer logger.Unlock()
+	return level <= logger.level
+}
+
+// Verbosef logs a message with --verbose or --debug.
+func Verbosef(format string, v ...interface{}) {
+	logf(LevelVerbose, "verbose", format, v...)
+}
+
+// Debugf logs a message with --debug.
+func Debugf(format string, v ...interface{}) {
+	logf(LevelDebug, "debug", format, v...)
+}
+
+// logf logs a message of the level, with its secrets redacted.
+func logf(level Level, prefix, format string, v ...interface{}) {
+	logger.Lock()
+	defer logger.Unlock()
+	if level > logger.level {
+		return
+	}
+	fmt.Fprintf(logger.w, "%s: %s\n", prefix, redact.String(fmt.Sprintf(format, v...)))
+}
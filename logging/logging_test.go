@@ -0,0 +1,30 @@
+package logging
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/xo/usql/redact"
+)
+
+func TestLogging(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(os.Stderr)
+	defer SetLevel(LevelDefault)
+	redact.Add("s3cr3t")
+	Verbosef("hidden")
+	SetLevel(LevelVerbose)
+	Verbosef("connecting to %s", "pg://admin:s3cr3t@localhost/app")
+	Debugf("hidden")
+	SetLevel(LevelDebug)
+	Debugf("config file %s not found", ".dbconfig.yaml")
+	exp := "verbose: connecting to pg://admin:" + redact.Mask + "@localhost/app\ndebug: config file .dbconfig.yaml not found\n"
+	if s := buf.String(); s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	if !Enabled(LevelVerbose) || Enabled(LevelDebug+1) {
+		t.Errorf("expected verbose enabled at the debug level")
+	}
+}
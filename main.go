@@ -5,23 +5,50 @@ package main
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/http"
 	"os"
+	"os/signal"
 	"os/user"
+	"runtime/debug"
 	"strings"
+	"time"
 
+	"github.com/xo/dburl"
+	"github.com/xo/usql/audit"
 	"github.com/xo/usql/drivers"
 	"github.com/xo/usql/env"
 	"github.com/xo/usql/handler"
+	"github.com/xo/usql/hooks"
 	"github.com/xo/usql/internal"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/logging"
+	"github.com/xo/usql/metrics"
+	"github.com/xo/usql/notify"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/pkg/conn"
+	"github.com/xo/usql/policy"
+	"github.com/xo/usql/redact"
 	"github.com/xo/usql/rline"
+	"github.com/xo/usql/session"
 	"github.com/xo/usql/text"
+	"github.com/xo/usql/tracing"
 )
 
 func main() {
-	// get available drivers and known build tags
+	// never show secrets in panics
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Fprintf(os.Stderr, "panic: %s\n\n%s", redact.String(fmt.Sprint(r)), redact.String(string(debug.Stack())))
+			os.Exit(exitPanic)
+		}
+	}()
+	// register driver plugins, then get available drivers and known build tags
+	registerPlugins()
 	available, known := drivers.Available(), internal.KnownBuildTags()
 	// report if database is supported
 	if len(os.Args) == 2 &&
@@ -38,6 +65,22 @@ func main() {
 		fmt.Fprintf(os.Stdout, "%d", out)
 		return
 	}
+	// export spans
+	shutdown, err := tracing.Init(context.Background())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	// run subcommand
+	if len(os.Args) > 1 && isSubcmd(os.Args[1]) {
+		err := runSubcmd(os.Args[1:])
+		_ = shutdown(context.Background())
+		if err != nil {
+			printErr(err, subcmdArgs.JSON)
+			os.Exit(exitCode(err))
+		}
+		return
+	}
 	// load current user
 	cur, err := user.Current()
 	if err != nil {
@@ -45,12 +88,14 @@ func main() {
 		os.Exit(1)
 	}
 	args := NewArgs()
+	logging.SetLevel(args.logLevel())
 	// run
 	err = run(args, cur)
+	_ = shutdown(context.Background())
 	if err != nil && err != io.EOF && err != rline.ErrInterrupt {
 		var he *handler.Error
 		if !errors.As(err, &he) {
-			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			printErr(err, args.JSON)
 		}
 		var e *drivers.Error
 		if errors.As(err, &e) && e.Err == text.ErrDriverNotAvailable {
@@ -68,10 +113,20 @@ func main() {
 			}
 			fmt.Fprintf(os.Stderr, "\ntry:\n\n  go install -tags %s github.com/xo/usql@%s\n\n", tag, rev)
 		}
-		os.Exit(1)
+		os.Exit(exitCode(err))
 	}
 }
 
+// printErr prints an error to stderr, with its secrets redacted, as a JSON
+// object when json is set.
+func printErr(err error, json bool) {
+	if json {
+		_ = jsonout.WriteError(os.Stderr, err)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "error: %v\n", redact.Error(err))
+}
+
 // run processes args, processing args.CommandOrFiles if non-empty, if
 // specified, otherwise launch an interactive readline from stdin.
 func run(args *Args, u *user.User) error {
@@ -80,6 +135,12 @@ func run(args *Args, u *user.User) error {
 	if err != nil {
 		return err
 	}
+	// set the environment variables of the .env file
+	if args.EnvFile != "" {
+		if err := config.LoadEnvFile(args.EnvFile); err != nil {
+			return err
+		}
+	}
 	// handle variables
 	for _, v := range args.Variables {
 		if i := strings.Index(v, "="); i != -1 {
@@ -108,26 +169,100 @@ func run(args *Args, u *user.User) error {
 		}
 	}
 
+	// the URL of an ad-hoc connection passed as --db
+	if config.IsDSN(args.DB) {
+		args.DSN, args.DB = args.DB, ""
+	}
+
+	// offer to create the config file on the first run
+	if args.List || args.DB != "" {
+		if err := offerSetup(args); err != nil {
+			return err
+		}
+	}
+
+	// the database alias matching a pattern, picked when several match
+	if config.IsPattern(args.DB) {
+		if args.DB, err = selectAlias(args, args.DB); err != nil {
+			return err
+		}
+	}
+
 	// print list of databases from config file and exit
 	if args.List {
-		DbList, err := listDBAliasesFromConfig(args)
+		cfg, err := loadConfig(args)
 		if err != nil {
-			return nil
+			return err
 		}
-
-		for _, d := range DbList {
-			fmt.Println(d)
+		if err := printAliases(os.Stdout, cfg, cfg.Aliases(), false, "", args.JSON); err != nil {
+			return err
 		}
 		os.Exit(0)
 	}
 
 	// extra wrapper to update args from config file
+	var cfg *config.Config
+	var dbConfig *config.DatabaseConfig
 	if args.DB != "" {
-		err := supplyArgsFromConfig(args)
+		cfg, dbConfig, err = supplyArgsFromConfig(args)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// masked columns, statement policy and hooks
+	var maskPatterns []string
+	var stmtPolicy *policy.Policy
+	var confirmPolicy *policy.Confirm
+	var dbHooks *hooks.Hooks
+	if dbConfig != nil {
+		if maskPatterns, err = dbConfig.MaskPatterns(args.Role, args.Unmask); err != nil {
+			return err
+		}
+		if stmtPolicy, err = rolePolicy(dbConfig, args.Role); err != nil {
+			return err
+		}
+		if confirmPolicy, err = roleConfirm(cfg, args.DB, args.Role); err != nil {
+			return err
+		}
+		if dbHooks, err = loadHooks(cfg, args.DB); err != nil {
+			return err
+		}
+	}
 
+	// open audit log
+	var auditLog *audit.Logger
+	if dbConfig != nil && dbConfig.Audit {
+		if auditLog, err = openAuditLog(cfg.AuditLog); err != nil {
+			return err
+		}
+		defer auditLog.Close()
+	}
+
+	// notifier of long-running statements
+	var notifier *notify.Notifier
+	if args.Notify != "" {
+		c, err := loadConfig(args)
 		if err != nil {
 			return err
 		}
+		if notifier, err = newNotifier(c, args.Notify); err != nil {
+			return err
+		}
+	}
+
+	// serve metrics
+	if args.MetricsListen != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics.Handler())
+		srv := &http.Server{Addr: args.MetricsListen, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+		ln, err := net.Listen("tcp", args.MetricsListen)
+		if err != nil {
+			return err
+		}
+		go srv.Serve(ln)
+		defer srv.Close()
 	}
 
 	// create input/output
@@ -138,20 +273,158 @@ func run(args *Args, u *user.User) error {
 	defer l.Close()
 	// create handler
 	h := handler.New(l, u, wd, args.NoPassword)
-	// force a password ...
-	dsn := args.DSN
-	if args.ForcePassword {
-		dsn, err = h.Password(dsn)
+	h.SetJSON(args.JSON)
+	h.SetApplicationName(args.AppName)
+	h.SetProgress(!args.NoProgress)
+	// color theme of the interactive session
+	if colored(h) && (args.ConfigFilePath != "" || config.Find() != "") {
+		c, err := loadConfig(args)
+		if err != nil {
+			return err
+		}
+		t, err := c.ColorTheme()
 		if err != nil {
 			return err
 		}
+		h.SetTheme(t)
 	}
-	// open dsn
-	if err = h.Open(context.Background(), dsn); err != nil {
-		return err
+	defer h.Flush()
+	defer h.Close()
+	// keep the connections to the database aliases open for the session
+	pool := &conn.Pool{
+		Open: func(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error) {
+			cfg, err := aliasConfig(args, alias)
+			if err != nil {
+				return nil, nil, err
+			}
+			return newOpener(cfg).Open(ctx, alias, role)
+		},
+	}
+	defer pool.Close()
+	h.SetAliasPool(pool, args.Role)
+	h.SetQueries(func(name string) (*config.QueryConfig, error) {
+		cfg, err := loadConfig(args)
+		if err != nil {
+			return nil, err
+		}
+		return cfg.Query(name)
+	})
+	if args.Offline {
+		// describe the database from its metadata snapshot, without connecting
+		if err = openOffline(h, args); err != nil {
+			return err
+		}
+	} else {
+		// force a password ...
+		dsn := args.DSN
+		if args.ForcePassword {
+			dsn, err = h.Password(dsn)
+			if err != nil {
+				return err
+			}
+		}
+		// open dsn
+		if dsn, err = dbHooks.PreConnect(dsn); err != nil {
+			return err
+		}
+		// run the init statements from the config file on each connection
+		if dbConfig != nil {
+			h.SetOnConnect(dbConfig.OnConnectStatements(args.Role))
+		}
+		if err = openWithRetry(context.Background(), h, args.DB, dsn, dbConfig); err != nil {
+			return jsonout.WithCode(jsonout.CodeConnection, err)
+		}
+		// remember ad-hoc connections for usql config save-last
+		if args.DB == "" && config.IsDSN(args.DSN) {
+			_ = config.SaveLastDSN(args.DSN)
+		}
+	}
+	if dbConfig != nil {
+		h.SetAlias(args.DB, args.Role, dbConfig.DbType)
+		h.SetKeepalive(dbConfig.KeepaliveInterval)
+		h.SetMaxResultBytes(int64(dbConfig.MaxResultBytes))
+	}
+	// route the read only statements to the reader host
+	if dbConfig != nil && dbConfig.AutoRoute && !args.Offline {
+		_, reader, err := newOpener(cfg).OpenReader(context.Background(), args.DB, args.Role)
+		if err != nil {
+			return fmt.Errorf("auto_route: %w", err)
+		}
+		h.SetReader(reader)
+	}
+	if dbConfig != nil && dbConfig.CacheTTL > 0 && !args.NoCache {
+		h.SetCacheTTL(dbConfig.CacheTTL)
+	}
+	h.SetMask(maskPatterns)
+	h.SetPolicy(stmtPolicy)
+	h.SetConfirm(confirmPolicy)
+	h.SetYes(args.Yes)
+	h.SetHooks(dbHooks)
+	if dbConfig != nil {
+		h.SetQueryComment(cfg.QueryComment(args.DB, args.Role, u.Username))
+		if err := setPromptColor(h, dbConfig); err != nil {
+			return err
+		}
+	}
+	if auditLog != nil {
+		h.SetAudit(auditLog)
+	}
+	// shared query history of the interactive sessions
+	if dbConfig != nil && cfg.HistoryBackend != nil && dbConfig.SharesHistory() && h.IO().Interactive() {
+		s, err := openHistory(context.Background(), cfg.HistoryBackend)
+		if err != nil {
+			fmt.Fprintf(l.Stderr(), "error: history backend: %v\n", redact.Error(err))
+		} else {
+			defer s.Close()
+			h.SetHistory(s)
+		}
+	}
+	if args.Record != "" {
+		r, err := session.NewRecorder(args.Record, &session.Session{
+			OSUser: u.Username,
+			Alias:  args.DB,
+			Role:   args.Role,
+			URL:    h.URL().Redacted(),
+		})
+		if err != nil {
+			return err
+		}
+		h.SetRecorder(r)
+	}
+	h.SetNotifier(notifier)
+	// reload config file with \reload, and when changed in interactive mode
+	if cfg != nil {
+		h.SetConfigReloader(func() error {
+			// reconnect to the database aliases with the reloaded config
+			if err := pool.Close(); err != nil {
+				return err
+			}
+			return reloadConfig(h, args)
+		})
+		if h.IO().Interactive() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			// the changes are applied by the handler before the next prompt
+			if err = args.configs.Watch(ctx, func(_ *config.Config, err error) {
+				if err != nil {
+					fmt.Fprintf(l.Stderr(), "error: config file: %v\n", redact.Error(err))
+					return
+				}
+				h.ConfigChanged()
+			}); err != nil {
+				return err
+			}
+		}
 	}
 	// start transaction
-	if args.SingleTransaction {
+	switch {
+	case args.DryRun && h.IO().Interactive():
+		return text.ErrDryRunCannotBeUsedWithInteractiveMode
+	case args.DryRun:
+		if err = h.BeginDryRun(context.Background()); err != nil {
+			return err
+		}
+	case args.SingleTransaction:
 		if h.IO().Interactive() {
 			return text.ErrSingleTransactionCannotBeUsedWithInteractiveMode
 		}
@@ -159,19 +432,49 @@ func run(args *Args, u *user.User) error {
 			return err
 		}
 	}
+	if args.timeout > 0 {
+		h.SetDeadline(time.Now().Add(args.timeout))
+	}
 	// rc file
 	if rc := env.RCFile(u); !args.NoRC && rc != "" {
 		if err = h.Include(rc, false); err != nil && err != text.ErrNoSuchFileOrDirectory {
 			return err
 		}
 	}
+	// restore the \pset, \set and \timing settings of the alias
+	if h.IO().Interactive() {
+		if err := h.RestoreState(); err != nil {
+			fmt.Fprintf(l.Stderr(), "error: state file: %v\n", err)
+		}
+	}
+	// resume the named session
+	if args.session != nil {
+		if err := h.RestoreSession(args.session); err != nil {
+			fmt.Fprintf(l.Stderr(), "error: session: %v\n", err)
+		}
+	}
 	// setup runner
 	f := h.Run
-	if len(args.CommandOrFiles) != 0 {
-		f = runCommandOrFiles(h, args.CommandOrFiles)
+	switch {
+	case args.Query != "" && len(args.CommandOrFiles) != 0:
+		return errors.New("--query cannot be used with --command or --file")
+	case args.Query != "":
+		f = runQuery(h, args.Query, args.Params)
+	case len(args.Params) != 0 && !hasCommand(args.CommandOrFiles):
+		return errors.New("--param requires --query or --command")
+	case len(args.CommandOrFiles) != 0:
+		f = runCommandOrFiles(h, args.CommandOrFiles, args.Params)
 	}
 	// run
-	if err = f(); err != nil {
+	err = f()
+	// roll back the dry run, even on errors
+	if args.DryRun {
+		if rerr := h.EndDryRun(); err == nil {
+			err = rerr
+		}
+		return err
+	}
+	if err != nil {
 		return err
 	}
 	// commit
@@ -181,17 +484,24 @@ func run(args *Args, u *user.User) error {
 	return nil
 }
 
-// runCommandOrFiles processes all the supplied commands or files.
-func runCommandOrFiles(h *handler.Handler, commandsOrFiles []CommandOrFile) func() error {
+// runCommandOrFiles processes all the supplied commands or files, binding the
+// params to the parameters of the statements of the commands.
+func runCommandOrFiles(h *handler.Handler, commandsOrFiles []CommandOrFile, params []string) func() error {
+	args := make([]interface{}, len(params))
+	for i, param := range params {
+		args[i] = param
+	}
 	return func() error {
 		for _, x := range commandsOrFiles {
 			h.SetSingleLineMode(x.Command)
 			if x.Command {
+				h.SetArgs(args)
 				h.Reset([]rune(x.Value))
 				if err := h.Run(); err != nil {
 					return err
 				}
 			} else {
+				h.SetArgs(nil)
 				if err := h.Include(x.Value, false); err != nil {
 					return err
 				}
@@ -201,15 +511,125 @@ func runCommandOrFiles(h *handler.Handler, commandsOrFiles []CommandOrFile) func
 	}
 }
 
-func supplyArgsFromConfig(args *Args) error {
+// hasCommand returns whether a command is supplied.
+func hasCommand(commandsOrFiles []CommandOrFile) bool {
+	for _, x := range commandsOrFiles {
+		if x.Command {
+			return true
+		}
+	}
+	return false
+}
+
+// runQuery executes the query template name with the NAME=VALUE params.
+func runQuery(h *handler.Handler, name string, params []string) func() error {
+	return func() error {
+		values, err := config.ParseParams(params)
+		if err != nil {
+			return err
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return h.RunQuery(ctx, name, values)
+	}
+}
 
-	DSN, err := GetDsnForDB(args.DB, args)
+// reloadConfig reloads the config file, and applies its color theme, and the
+// masked columns, statement and confirmation policies, hooks and prompt color
+// of args.DB when still connected to it.
+func reloadConfig(h *handler.Handler, args *Args) error {
+	cfg, err := args.configs.Reload()
 	if err != nil {
 		return err
 	}
+	if colored(h) {
+		t, err := cfg.ColorTheme()
+		if err != nil {
+			return err
+		}
+		h.SetTheme(t)
+	}
+	if h.Alias() != args.DB {
+		return nil
+	}
+	dbConfig, err := cfg.Database(args.DB)
+	if err != nil {
+		return err
+	}
+	maskPatterns, err := dbConfig.MaskPatterns(args.Role, args.Unmask)
+	if err != nil {
+		return err
+	}
+	stmtPolicy, err := rolePolicy(dbConfig, args.Role)
+	if err != nil {
+		return err
+	}
+	confirmPolicy, err := roleConfirm(cfg, args.DB, args.Role)
+	if err != nil {
+		return err
+	}
+	dbHooks, err := loadHooks(cfg, args.DB)
+	if err != nil {
+		return err
+	}
+	h.SetMask(maskPatterns)
+	h.SetPolicy(stmtPolicy)
+	h.SetConfirm(confirmPolicy)
+	h.SetHooks(dbHooks)
+	h.SetMaxResultBytes(int64(dbConfig.MaxResultBytes))
+	h.SetQueryComment(cfg.QueryComment(args.DB, args.Role, h.User().Username))
+	return setPromptColor(h, dbConfig)
+}
 
-	if DSN != "" {
-		args.DSN = DSN
+// colored returns true when the session is interactive and colors are not
+// disabled with NO_COLOR or --no-color.
+func colored(h *handler.Handler) bool {
+	return h.IO().Interactive() && os.Getenv("NO_COLOR") == ""
+}
+
+// setPromptColor sets the color of the prompt on the database, when the
+// session is colored.
+func setPromptColor(h *handler.Handler, dbConfig *config.DatabaseConfig) error {
+	if !colored(h) {
+		return nil
 	}
+	color, err := dbConfig.PromptColor()
+	if err != nil {
+		return err
+	}
+	h.SetPromptColor(color)
 	return nil
 }
+
+// supplyArgsFromConfig sets the DSN of args to the DSN of the database alias
+// args.DB, returning the config and the database's config.
+func supplyArgsFromConfig(args *Args) (*config.Config, *config.DatabaseConfig, error) {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	dsn, err := cfg.DSN(args.DB, args.Role)
+	if err != nil {
+		return nil, nil, err
+	}
+	if db := cfg.Databases[args.DB]; db != nil {
+		logging.Verbosef("database %s: db_type %s, role %q", args.DB, db.DbType, args.Role)
+		if _, choice, err := db.ChooseRole(args.Role); err == nil {
+			logging.Debugf("database %s: role %q, credentials %d (%s)", args.DB, choice.Role, choice.Index, choice.Reason)
+		}
+		appName := args.AppName
+		if appName == "" {
+			appName = cfg.ApplicationName(args.DB)
+		}
+		if u, err := dburl.Parse(dsn); err == nil && drivers.SetApplicationName(u, appName) {
+			dsn = u.String()
+		}
+		if dsn, err = conn.ProxyDSN(db, dsn); err != nil {
+			return nil, nil, err
+		}
+	}
+	if dsn != "" {
+		args.DSN = dsn
+	}
+	return cfg, cfg.Databases[args.DB], nil
+}
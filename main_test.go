@@ -1,5 +1,44 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
 	_ "github.com/google/goexpect"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/policy"
+	"github.com/xo/usql/text"
 )
+
+func TestExitCode(t *testing.T) {
+	err := errors.New("failed")
+	tests := []struct {
+		err error
+		exp int
+	}{
+		{err, exitError},
+		{context.Canceled, exitError},
+		{text.ErrUnknownCommand, exitError},
+		{jsonout.WithCode(jsonout.CodeConfig, err), exitConfig},
+		{jsonout.WithCode(jsonout.CodeConnection, err), exitConnection},
+		{fmt.Errorf("open: %w", text.ErrDriverNotAvailable), exitConnection},
+		{text.ErrNotConnected, exitConnection},
+		{drivers.WrapErr("sqlite3", err), exitSQL},
+		{fmt.Errorf("line 2: %w", drivers.WrapErr("sqlite3", err)), exitSQL},
+		{&policy.Violation{Role: "ci", Deny: true}, exitSQL},
+		{jsonout.WithCode(jsonout.CodePartialFailure, err), exitPartial},
+		{jsonout.WithCode(jsonout.CodeAlert, err), exitAlert},
+		{text.ErrOffline, exitOffline},
+		{jsonout.WithCode(jsonout.CodeDifferent, err), exitDifferent},
+		// the codes the errors were wrapped with take precedence
+		{jsonout.WithCode(jsonout.CodeConnection, drivers.WrapErr("sqlite3", err)), exitConnection},
+	}
+	for i, test := range tests {
+		if code := exitCode(test.err); code != test.exp {
+			t.Errorf("test %d expected exit code %d for %v, got: %d", i, test.exp, test.err, code)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+// Package mask masks the values of result set columns matching patterns.
+package mask
+
+import (
+	"database/sql"
+	"errors"
+	"path"
+	"strings"
+
+	"github.com/xo/tblfmt"
+)
+
+// Mask replaces the values of the masked columns.
+const Mask = "*****"
+
+// ErrCannotMask is the error returned when a masked column is scanned to a
+// value that cannot hold the mask.
+var ErrCannotMask = errors.New("cannot mask column value")
+
+// Match returns true when the column name matches one of the patterns.
+// Patterns are case insensitive globs (see path.Match) on the column name,
+// optionally qualified by a table, such as "*.email". As result columns don't
+// carry their table, the table of a pattern is not checked, erring on the
+// side of masking.
+func Match(patterns []string, name string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if i := strings.LastIndex(pattern, "."); i != -1 {
+			pattern = pattern[i+1:]
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// ResultSet wraps a result set, masking the values of the columns matching
+// the patterns.
+type ResultSet struct {
+	tblfmt.ResultSet
+	patterns []string
+	// masked are the masked columns of the current result set.
+	masked []bool
+}
+
+// New wraps the result set, masking the values of the columns matching the
+// patterns.
+func New(resultSet tblfmt.ResultSet, patterns []string) *ResultSet {
+	return &ResultSet{ResultSet: resultSet, patterns: patterns}
+}
+
+// Columns returns the column names.
+func (rs *ResultSet) Columns() ([]string, error) {
+	cols, err := rs.ResultSet.Columns()
+	if err != nil {
+		return nil, err
+	}
+	rs.masked = make([]bool, len(cols))
+	for i, col := range cols {
+		rs.masked[i] = Match(rs.patterns, col)
+	}
+	return cols, nil
+}
+
+// ColumnTypes returns the column types of the wrapped result set.
+func (rs *ResultSet) ColumnTypes() ([]*sql.ColumnType, error) {
+	z, ok := rs.ResultSet.(interface {
+		ColumnTypes() ([]*sql.ColumnType, error)
+	})
+	if !ok {
+		return nil, tblfmt.ErrResultSetHasNoColumnTypes
+	}
+	return z.ColumnTypes()
+}
+
+// Masked returns true when the column i is masked.
+func (rs *ResultSet) Masked(i int) bool {
+	if rs.masked == nil {
+		if _, err := rs.Columns(); err != nil {
+			return false
+		}
+	}
+	return i < len(rs.masked) && rs.masked[i]
+}
+
+// Scan scans the values of the current row to dest, replacing the values of
+// the masked columns.
+func (rs *ResultSet) Scan(dest ...interface{}) error {
+	if err := rs.ResultSet.Scan(dest...); err != nil {
+		return err
+	}
+	for i, d := range dest {
+		if !rs.Masked(i) {
+			continue
+		}
+		switch p := d.(type) {
+		case *interface{}:
+			if *p != nil {
+				*p = Mask
+			}
+		case *string:
+			*p = Mask
+		case *[]byte:
+			if *p != nil {
+				*p = []byte(Mask)
+			}
+		case *sql.NullString:
+			if p.Valid {
+				p.String = Mask
+			}
+		default:
+			return ErrCannotMask
+		}
+	}
+	return nil
+}
+
+// NextResultSet prepares the next result set.
+func (rs *ResultSet) NextResultSet() bool {
+	rs.masked = nil
+	return rs.ResultSet.NextResultSet()
+}
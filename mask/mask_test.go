@@ -0,0 +1,66 @@
+package mask
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestMatch(t *testing.T) {
+	patterns := []string{"password", "SSN", "*.email", "users.token_*"}
+	tests := []struct {
+		name string
+		exp  bool
+	}{
+		{"password", true},
+		{"Password", true},
+		{"ssn", true},
+		{"email", true},
+		{"token_hash", true},
+		{"id", false},
+		{"email_verified", false},
+		{"passwords", false},
+	}
+	for i, test := range tests {
+		if ok := Match(patterns, test.name); ok != test.exp {
+			t.Errorf("test %d expected %s to be masked %t, got: %t", i, test.name, test.exp, ok)
+		}
+	}
+}
+
+func TestResultSet(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query(`SELECT 1 AS id, 'a@b.c' AS email, NULL AS password`)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer rows.Close()
+	rs := New(rows, []string{"*.email", "password"})
+	cols, err := rs.Columns()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !rs.Next() {
+		t.Fatalf("expected a row, got: %v", rs.Err())
+	}
+	row := make([]interface{}, len(cols))
+	for i := range row {
+		row[i] = new(interface{})
+	}
+	if err := rs.Scan(row...); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var values []interface{}
+	for _, v := range row {
+		values = append(values, *(v.(*interface{})))
+	}
+	if exp := []interface{}{int64(1), Mask, nil}; !reflect.DeepEqual(values, exp) {
+		t.Errorf("expected %v, got: %v", exp, values)
+	}
+}
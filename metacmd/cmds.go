@@ -16,8 +16,14 @@ import (
 
 	"github.com/alecthomas/kingpin/v2"
 	"github.com/xo/dburl"
+	"github.com/xo/usql/alert"
 	"github.com/xo/usql/drivers"
 	"github.com/xo/usql/env"
+	"github.com/xo/usql/importer"
+	"github.com/xo/usql/pgcopy"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/sqlfmt"
 	"github.com/xo/usql/text"
 )
 
@@ -39,6 +45,10 @@ var cmdMap map[string]Metacmd
 // sectMap is the map of sections to its respective commands.
 var sectMap map[Section][]Metacmd
 
+// DefaultPageSize is the number of rows per page when paging is turned on
+// with \page.
+const DefaultPageSize = 100
+
 func init() {
 	cmds = []Cmd{
 		Question: {
@@ -93,7 +103,7 @@ func init() {
 			Desc:    Desc{"display information about the current database connection", ""},
 			Process: func(p *Params) error {
 				if db, u := p.Handler.DB(), p.Handler.URL(); db != nil && u != nil {
-					p.Handler.Print(text.ConnInfo, u.Driver, u.DSN)
+					p.Handler.Print(text.ConnInfo, u.Driver, redact.String(u.DSN))
 				} else {
 					p.Handler.Print(text.NotConnected)
 				}
@@ -185,11 +195,12 @@ func init() {
 			Desc:    Desc{"execute query (and send results to file or |pipe)", "[(OPTIONS)] [FILE] or ;"},
 			Aliases: map[string]Desc{
 				"gexec":        {"execute query and execute each value of the result", ""},
-				"gset":         {"execute query and store results in " + text.CommandName + " variables", "[PREFIX]"},
+				"gset":         {"execute query (on database alias) and store results in " + text.CommandName + " variables", "[(alias=NAME)] [PREFIX]"},
 				"gx":           {`as \g, but forces expanded output mode`, `[(OPTIONS)] [FILE]`},
 				"G":            {`as \g, but forces vertical output mode`, `[(OPTIONS)] [FILE]`},
 				"crosstabview": {"execute query and display results in crosstab", "[(OPTIONS)] [COLUMNS]"},
-				"watch":        {"execute query every specified interval", "[(OPTIONS)] [DURATION]"},
+				"chart":        {"execute query and display results as a bar or line chart", "[bar|line] [X [Y]]"},
+				"watch":        {"execute query every specified interval, alerting when its first value crosses a threshold", "[(OPTIONS)] [DURATION] [THRESHOLD [bell|exit|webhook]]"},
 			},
 			Process: func(p *Params) error {
 				p.Option.Exec = ExecOnly
@@ -208,7 +219,10 @@ func init() {
 					if err != nil {
 						return err
 					}
-					p.Option.ParseParams(params, "prefix")
+					if err := p.Option.ParseParams(params, "prefix"); err != nil {
+						return err
+					}
+					p.Option.Alias = p.Option.Params["alias"]
 				case "G":
 					params, err := p.GetAll(true)
 					if err != nil {
@@ -235,6 +249,18 @@ func init() {
 							break
 						}
 					}
+				case "chart":
+					p.Option.Exec = ExecChart
+					for i := 0; i < 3; i++ {
+						ok, s, err := p.GetOK(true)
+						if err != nil {
+							return err
+						}
+						if !ok {
+							break
+						}
+						p.Option.Chart = append(p.Option.Chart, s)
+					}
 				case "watch":
 					p.Option.Exec = ExecWatch
 					p.Option.Watch = 2 * time.Second
@@ -242,17 +268,29 @@ func init() {
 					switch {
 					case err != nil:
 						return err
-					case ok:
-						d, err := time.ParseDuration(s)
-						if err != nil {
-							if f, err := strconv.ParseFloat(s, 64); err == nil {
-								d = time.Duration(f * float64(time.Second))
-							}
-						}
-						if d == 0 {
-							return text.ErrInvalidWatchDuration
+					case !ok:
+						return nil
+					}
+					d, err := time.ParseDuration(s)
+					if err != nil {
+						if f, err := strconv.ParseFloat(s, 64); err == nil {
+							d = time.Duration(f * float64(time.Second))
 						}
+					}
+					threshold := strings.TrimSpace(p.GetRaw())
+					switch {
+					case d != 0:
 						p.Option.Watch = d
+					case threshold == "" && !strings.ContainsAny(s, "<>=!") && !strings.EqualFold(s, "value"):
+						return text.ErrInvalidWatchDuration
+					default:
+						// the threshold follows the query, without interval
+						threshold = s + " " + threshold
+					}
+					if threshold != "" {
+						if p.Option.Alert, err = alert.Parse(threshold); err != nil {
+							return err
+						}
 					}
 				}
 				return nil
@@ -447,7 +485,10 @@ func init() {
 					setting = "on"
 				}
 				p.Handler.Print(text.TimingSet, setting)
-				return nil
+				timing := p.Handler.GetTiming()
+				return p.Handler.SaveState(func(s *env.State) {
+					s.SetTiming(timing)
+				})
 			},
 		},
 		Shell: {
@@ -514,17 +555,45 @@ func init() {
 			Name:    "begin",
 			Desc:    Desc{"begin a transaction", ""},
 			Aliases: map[string]Desc{
-				"begin":    {"begin a transaction with isolation level", "[-read-only] [ISOLATION]"},
-				"commit":   {"commit current transaction", ""},
-				"rollback": {"rollback (abort) current transaction", ""},
-				"abort":    {},
+				"begin":     {"begin a transaction with isolation level", "[-read-only] [ISOLATION]"},
+				"commit":    {"commit current transaction", ""},
+				"rollback":  {"rollback (abort) current transaction, or rollback to savepoint", "[to NAME]"},
+				"abort":     {},
+				"savepoint": {"define a savepoint within the current transaction", "NAME"},
+				"release":   {"release a savepoint within the current transaction", "NAME"},
 			},
 			Process: func(p *Params) error {
 				switch p.Name {
 				case "commit":
 					return p.Handler.Commit()
 				case "rollback", "abort":
-					return p.Handler.Rollback()
+					ok, n, err := p.GetOK(true)
+					switch {
+					case err != nil:
+						return err
+					case !ok:
+						return p.Handler.Rollback()
+					case strings.ToLower(n) != "to":
+						return fmt.Errorf(text.InvalidOption, n)
+					}
+					if n, err = p.Get(true); err != nil {
+						return err
+					}
+					if n == "" {
+						return text.ErrMissingRequiredArgument
+					}
+					return p.Handler.RollbackTo(n)
+				case "savepoint", "release":
+					n, err := p.Get(true)
+					switch {
+					case err != nil:
+						return err
+					case n == "":
+						return text.ErrMissingRequiredArgument
+					case p.Name == "release":
+						return p.Handler.ReleaseSavepoint(n)
+					}
+					return p.Handler.Savepoint(n)
 				}
 				// read begin params
 				readOnly := false
@@ -632,7 +701,13 @@ func init() {
 				if err != nil {
 					return err
 				}
-				return env.Set(n, strings.Join(vals, ""))
+				v := strings.Join(vals, "")
+				if err := env.Set(n, v); err != nil {
+					return err
+				}
+				return p.Handler.SaveState(func(s *env.State) {
+					s.SetVar(n, v)
+				})
 			},
 		},
 		Unset: {
@@ -644,7 +719,12 @@ func init() {
 				if err != nil {
 					return err
 				}
-				return env.Unset(n)
+				if err := env.Unset(n); err != nil {
+					return err
+				}
+				return p.Handler.SaveState(func(s *env.State) {
+					s.UnsetVar(n)
+				})
 			},
 		},
 		SetFormatVar: {
@@ -708,6 +788,11 @@ func init() {
 						return err
 					}
 				}
+				if err := p.Handler.SaveState(func(s *env.State) {
+					s.SetPset(field, val)
+				}); err != nil {
+					return err
+				}
 				// special replacement name for expanded field, when 'auto'
 				if field == "expanded" && val == "auto" {
 					field = "expanded_auto"
@@ -828,11 +913,28 @@ func init() {
 		Copy: {
 			Section: SectionInputOutput,
 			Name:    "copy",
-			Desc:    Desc{"copy query from source url to table on destination url", "SRC DST QUERY TABLE"},
+			Desc:    Desc{"stream file to table, or table or query to file, with the copy protocol", "TABLE|(QUERY) FROM|TO FILE [OPTIONS]"},
 			Aliases: map[string]Desc{
-				"copy": {"copy query from source url to columns of table on destination url", "SRC DST QUERY TABLE(A,...)"},
+				"copy": {"copy query from source url to table (or its columns) on destination url", "SRC DST QUERY TABLE[(A,...)]"},
 			},
 			Process: func(p *Params) error {
+				// psql's \copy, streamed with the copy protocol
+				if raw := string(p.Params.R[:p.Params.Len]); pgcopy.Match(raw) {
+					c, err := pgcopy.Parse(p.GetRaw())
+					if err != nil {
+						return err
+					}
+					ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+					defer cancel()
+					n, err := p.Handler.CopyFile(ctx, c.Statement, c.File, c.From)
+					if err != nil {
+						return err
+					}
+					if c.From || c.File != "" {
+						p.Handler.Print("COPY %d", n)
+					}
+					return nil
+				}
 				stdout, stderr := p.Handler.IO().Stdout, p.Handler.IO().Stderr
 				srcDsn, err := p.Get(true)
 				if err != nil {
@@ -876,7 +978,9 @@ func init() {
 					return err
 				}
 				defer r.Close()
-				n, err := drivers.Copy(ctx, destURL, stdout, stderr, r, table)
+				prog := p.Handler.Progress(`\copy ` + table)
+				n, err := drivers.Copy(ctx, destURL, stdout, stderr, &progressRows{CopyRows: r, p: prog}, table)
+				prog.Done()
 				if err != nil {
 					return err
 				}
@@ -884,6 +988,550 @@ func init() {
 				return nil
 			},
 		},
+		Import: {
+			Section: SectionInputOutput,
+			Name:    "import",
+			Desc:    Desc{"import a CSV/TSV or JSON file into table", "[-OPT]... FILE TABLE"},
+			Aliases: map[string]Desc{
+				"import": {"import a CSV/TSV or JSON file into columns of table", "[-OPT]... FILE TABLE(A,...)"},
+			},
+			Process: func(p *Params) error {
+				var opts importer.Options
+				var format, delimiter string
+				ok, name, err := p.GetOptional(true)
+				for ; err == nil && ok; ok, name, err = p.GetOptional(true) {
+					opt, val := name, ""
+					if i := strings.IndexRune(name, '='); i != -1 {
+						opt, val = name[:i], name[i+1:]
+					}
+					switch opt {
+					case "header":
+						opts.Header, err = importer.ParseHeader(val)
+					case "no-header":
+						opts.Header = importer.HeaderOff
+					case "format":
+						format = val
+					case "delimiter":
+						delimiter = val
+					case "quote":
+						opts.Quote, err = importer.ParseDelimiter(val)
+					case "null":
+						opts.Null = val
+					case "batch":
+						opts.BatchSize, err = strconv.Atoi(val)
+					case "no-bulk":
+						opts.NoBulk = true
+					default:
+						return fmt.Errorf(text.InvalidOption, name)
+					}
+					if err != nil {
+						return err
+					}
+				}
+				if err != nil {
+					return err
+				}
+				table, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case name == "" || table == "":
+					return text.ErrMissingRequiredArgument
+				}
+				opts.Table, opts.Columns = importer.ParseTable(table)
+				opts.Format, opts.Delimiter = importer.DefaultFormat(name), importer.DefaultDelimiter(name)
+				if format != "" {
+					if opts.Format, err = importer.ParseFormat(format); err != nil {
+						return err
+					}
+				}
+				if delimiter != "" {
+					if opts.Delimiter, err = importer.ParseDelimiter(delimiter); err != nil {
+						return err
+					}
+				}
+				db, ok := p.Handler.DB().(*sql.DB)
+				switch {
+				case p.Handler.URL() == nil, ok && db == nil:
+					return text.ErrNotConnected
+				case !ok:
+					return text.ErrPreviousTransactionExists
+				}
+				_, f, err := env.OpenFile(p.Handler.User(), name, false)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				opts.Progress = p.Handler.Progress(`\import ` + name)
+				if fi, err := f.Stat(); err == nil {
+					opts.Progress.SetSize(fi.Size())
+				}
+				n, err := importer.Import(ctx, p.Handler.URL(), db, f, opts)
+				opts.Progress.Done()
+				if err != nil {
+					return err
+				}
+				p.Handler.Print("IMPORT %d", n)
+				return nil
+			},
+		},
+		Background: {
+			Section: SectionQueryExecute,
+			Name:    "bg",
+			Desc:    Desc{"execute query in the background", "QUERY"},
+			Aliases: map[string]Desc{
+				"jobs":   {"list background jobs", ""},
+				"wait":   {"wait for background job to finish and show its result", "ID"},
+				"cancel": {"cancel background job", "ID"},
+				"result": {"show result of finished background job", "ID"},
+			},
+			Process: func(p *Params) error {
+				switch p.Name {
+				case "bg":
+					id, err := p.Handler.Background(p.GetRaw())
+					if err != nil {
+						return err
+					}
+					p.Handler.Print(text.JobStarted, id)
+					return nil
+				case "jobs":
+					p.Handler.Jobs(p.Handler.IO().Stdout())
+					return nil
+				}
+				s, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case s == "":
+					return text.ErrMissingRequiredArgument
+				}
+				id, err := strconv.Atoi(strings.TrimPrefix(s, "%"))
+				if err != nil {
+					return fmt.Errorf(text.InvalidOption, s)
+				}
+				switch p.Name {
+				case "wait":
+					ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+					defer cancel()
+					return p.Handler.Wait(ctx, id)
+				case "cancel":
+					return p.Handler.Cancel(id)
+				}
+				return p.Handler.Result(id)
+			},
+		},
+		Explain: {
+			Section: SectionQueryExecute,
+			Name:    "explain",
+			Desc:    Desc{"show query plan of query (or last query)", "[analyze] [QUERY]"},
+			Process: func(p *Params) error {
+				sqlstr, analyze := strings.TrimSpace(p.GetRaw()), false
+				if fields := strings.Fields(sqlstr); len(fields) != 0 && strings.EqualFold(fields[0], "analyze") {
+					sqlstr, analyze = strings.TrimSpace(sqlstr[len(fields[0]):]), true
+				}
+				if sqlstr == "" {
+					sqlstr = p.Handler.Last()
+				}
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				return p.Handler.Explain(ctx, sqlstr, analyze)
+			},
+		},
+		Fetch: {
+			Section: SectionQueryExecute,
+			Name:    "fetch",
+			Desc:    Desc{"fetch query results from database alias into federated table", "ALIAS [TABLE]: QUERY"},
+			Process: func(p *Params) error {
+				raw := p.GetRaw()
+				i := strings.Index(raw, ":")
+				if i == -1 {
+					return text.ErrMissingRequiredArgument
+				}
+				fields := strings.Fields(raw[:i])
+				var alias, table string
+				switch len(fields) {
+				case 0:
+					return text.ErrMissingRequiredArgument
+				case 1:
+					alias, table = fields[0], fields[0]
+				case 2:
+					alias, table = fields[0], fields[1]
+				default:
+					return text.ErrWrongNumberOfArguments
+				}
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				return p.Handler.Fetch(ctx, alias, table, raw[i+1:])
+			},
+		},
+		Cache: {
+			Section: SectionOperatingSystem,
+			Name:    "cache",
+			Desc:    Desc{"toggle caching of query results, or clear cache", "[on|off|clear]"},
+			Process: func(p *Params) error {
+				v, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case v == "clear":
+					if err := p.Handler.ClearCache(); err != nil {
+						return err
+					}
+					p.Handler.Print(text.CacheCleared)
+					return nil
+				case v == "":
+					err = p.Handler.SetCache(!p.Handler.GetCache())
+				default:
+					var s string
+					if s, err = env.ParseBool(v, "\\cache"); err == nil {
+						err = p.Handler.SetCache(s == "on")
+					}
+				}
+				if err != nil {
+					return err
+				}
+				setting := "off"
+				if p.Handler.GetCache() {
+					setting = "on"
+				}
+				p.Handler.Print(text.CacheSet, setting)
+				return nil
+			},
+		},
+		Query: {
+			Section: SectionQueryExecute,
+			Name:    "query",
+			Desc:    Desc{"execute query template from config file", "NAME [PARAM=VALUE]..."},
+			Process: func(p *Params) error {
+				name, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case name == "":
+					return text.ErrMissingRequiredArgument
+				}
+				params, err := p.GetAll(true)
+				if err != nil {
+					return err
+				}
+				values, err := config.ParseParams(params)
+				if err != nil {
+					return err
+				}
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				return p.Handler.RunQuery(ctx, name, values)
+			},
+		},
+		Reload: {
+			Section: SectionConnection,
+			Name:    "reload",
+			Desc:    Desc{"reload the config file", ""},
+			Process: func(p *Params) error {
+				if err := p.Handler.ReloadConfig(); err != nil {
+					return err
+				}
+				p.Handler.Print(text.ConfigReloaded)
+				return nil
+			},
+		},
+		Pool: {
+			Section: SectionConnection,
+			Name:    "pool",
+			Desc:    Desc{"show open and idle connections of database alias pools", "[status]"},
+			Process: func(p *Params) error {
+				switch s, err := p.Get(true); {
+				case err != nil:
+					return err
+				case s != "" && s != "status":
+					return fmt.Errorf(text.InvalidOption, s)
+				}
+				return p.Handler.PoolStatus(p.Handler.IO().Stdout())
+			},
+		},
+		Page: {
+			Section: SectionQueryExecute,
+			Name:    "page",
+			Desc:    Desc{"toggle paging of query results, or set rows per page", "[N|off]"},
+			Aliases: map[string]Desc{
+				"next": {"show next page of paged query results", ""},
+				"prev": {"show previous page of paged query results", ""},
+			},
+			Process: func(p *Params) error {
+				if p.Name != "page" {
+					delta := 1
+					if p.Name == "prev" {
+						delta = -1
+					}
+					ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+					defer cancel()
+					return p.Handler.Page(ctx, delta)
+				}
+				v, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case v == "" && p.Handler.GetPageSize() == 0:
+					p.Handler.SetPageSize(DefaultPageSize)
+				case v == "" || v == "off":
+					p.Handler.SetPageSize(0)
+				default:
+					n, err := strconv.Atoi(v)
+					if err != nil || n < 0 {
+						return fmt.Errorf(text.InvalidOption, v)
+					}
+					p.Handler.SetPageSize(n)
+				}
+				setting := "off"
+				if n := p.Handler.GetPageSize(); n != 0 {
+					setting = fmt.Sprintf("on (%d rows per page)", n)
+				}
+				p.Handler.Print(text.PageSet, setting)
+				return nil
+			},
+		},
+		LoExport: {
+			Section: SectionLargeObjects,
+			Name:    "lo_export",
+			Desc:    Desc{"write the value of a column of the single row of the query buffer (or last query) to a file", "COLUMN FILE"},
+			Process: func(p *Params) error {
+				column, err := p.Get(true)
+				if err != nil {
+					return err
+				}
+				name, err := p.Get(true)
+				if err != nil {
+					return err
+				}
+				if column == "" || name == "" {
+					return text.ErrMissingRequiredArgument
+				}
+				sqlstr, buf := p.Handler.Last(), p.Handler.Buf()
+				if buf.Len != 0 {
+					sqlstr = buf.String()
+					buf.Reset(nil)
+				}
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				return p.Handler.ExportBinary(ctx, sqlstr, column, name)
+			},
+		},
+		Format: {
+			Section: SectionQueryBuffer,
+			Name:    "format",
+			Desc:    Desc{"format the query buffer (or last query) or QUERY, replacing the query buffer", "[QUERY]"},
+			Process: func(p *Params) error {
+				sqlstr, buf := strings.TrimSpace(p.GetRaw()), p.Handler.Buf()
+				switch {
+				case sqlstr != "":
+				case buf.Len != 0:
+					sqlstr = buf.String()
+				default:
+					sqlstr = p.Handler.Last()
+				}
+				if sqlstr == "" {
+					fmt.Fprintln(p.Handler.IO().Stdout(), text.QueryBufferEmpty)
+					return nil
+				}
+				opts := sqlfmt.Options{}
+				if u := p.Handler.URL(); u != nil {
+					opts.Dialect = drivers.Caps(u).Dialect
+				}
+				var err error
+				if opts.Case, err = sqlfmt.ParseCase(env.Get("FORMAT_KEYWORD_CASE")); err != nil {
+					return err
+				}
+				opts.Indent, _ = strconv.Atoi(env.Get("FORMAT_INDENT"))
+				s, err := sqlfmt.Format(sqlstr, opts)
+				if err != nil {
+					return err
+				}
+				s = strings.TrimRight(s, "\n")
+				buf.Reset([]rune(s))
+				fmt.Fprintln(p.Handler.IO().Stdout(), s)
+				return nil
+			},
+		},
+		History: {
+			Section: SectionQueryBuffer,
+			Name:    "history",
+			Desc:    Desc{"show the command history, or its last N entries", "[N]"},
+			Process: func(p *Params) error {
+				ok, val, err := p.GetOK(true)
+				if err != nil {
+					return err
+				}
+				n := -1
+				if ok {
+					if n, err = strconv.Atoi(val); err != nil || n < 0 {
+						return fmt.Errorf(text.InvalidOption, val)
+					}
+				}
+				lines, err := p.Handler.IO().History()
+				if err != nil {
+					return err
+				}
+				if n != -1 && n < len(lines) {
+					lines = lines[len(lines)-n:]
+				}
+				hl := p.Handler.IO().Interactive() && env.All()["SYNTAX_HL"] == "true"
+				stdout := p.Handler.IO().Stdout()
+				for _, s := range lines {
+					if hl {
+						b := new(bytes.Buffer)
+						if p.Handler.Highlight(b, s) == nil {
+							s = b.String()
+						}
+					}
+					fmt.Fprintln(stdout, s)
+				}
+				return nil
+			},
+		},
+		Diff: {
+			Section: SectionQueryExecute,
+			Name:    "diff",
+			Desc:    Desc{"compare the results of the last two queries, keyed by KEY columns (default the first)", "[KEY,...]"},
+			Process: func(p *Params) error {
+				_, val, err := p.GetOK(true)
+				if err != nil {
+					return err
+				}
+				var key []string
+				for _, s := range strings.Split(val, ",") {
+					if s = strings.TrimSpace(s); s != "" {
+						key = append(key, s)
+					}
+				}
+				return p.Handler.DiffResults(p.Handler.IO().Stdout(), key)
+			},
+		},
+		Activity: {
+			Section: SectionQueryExecute,
+			Name:    "activity",
+			Desc:    Desc{"show the queries running on the database, with the sessions blocking them", ""},
+			Aliases: map[string]Desc{
+				"kill": {"kill the session (or query) with the ID shown by \\activity", "ID"},
+			},
+			Process: func(p *Params) error {
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				if p.Name == "activity" {
+					return p.Handler.Activity(ctx, p.Handler.IO().Stdout())
+				}
+				id, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case id == "":
+					return text.ErrMissingRequiredArgument
+				}
+				if err := p.Handler.Kill(ctx, id); err != nil {
+					return err
+				}
+				fmt.Fprintf(p.Handler.IO().Stdout(), "killed %s\n", id)
+				return nil
+			},
+		},
+		ShowCreate: {
+			Section: SectionInformational,
+			Name:    "sd",
+			Desc:    Desc{"show the CREATE statement of a table or view", "NAME"},
+			Process: func(p *Params) error {
+				name, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case name == "":
+					return text.ErrMissingRequiredArgument
+				}
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				return p.Handler.ShowCreate(ctx, name)
+			},
+		},
+		Session: {
+			Section: SectionConnection,
+			Name:    "session",
+			Desc:    Desc{"save the connection, variables and query buffer as a named session", "save NAME"},
+			Process: func(p *Params) error {
+				cmd, err := p.Get(true)
+				if err != nil {
+					return err
+				}
+				name, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case cmd != "save":
+					return fmt.Errorf(text.InvalidOption, cmd)
+				case name == "":
+					return text.ErrMissingRequiredArgument
+				}
+				path, err := p.Handler.SaveSession(name)
+				if err != nil {
+					return err
+				}
+				p.Handler.Print(text.SessionSaved, name, path)
+				return nil
+			},
+		},
+		Call: {
+			Section: SectionQueryExecute,
+			Name:    "call",
+			Desc:    Desc{"call a stored procedure, printing its result sets and OUT parameters", "NAME(ARG, ...)"},
+			Process: func(p *Params) error {
+				s := strings.TrimSpace(p.GetRaw())
+				if s == "" {
+					return text.ErrMissingRequiredArgument
+				}
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				return p.Handler.Call(ctx, s)
+			},
+		},
+		Prepare: {
+			Section: SectionQueryExecute,
+			Name:    "prepare",
+			Desc:    Desc{"prepare a statement with the driver (or list the prepared statements)", "[NAME AS QUERY]"},
+			Aliases: map[string]Desc{
+				"execute": {"execute a prepared statement, binding the arguments to its parameters", "NAME [ARG]..."},
+			},
+			Process: func(p *Params) error {
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				if p.Name == "prepare" {
+					return p.Handler.Prepare(ctx, p.GetRaw())
+				}
+				args, err := p.GetAll(true)
+				switch {
+				case err != nil:
+					return err
+				case len(args) == 0:
+					return text.ErrMissingRequiredArgument
+				}
+				return p.Handler.ExecutePrepared(ctx, args[0], args[1:])
+			},
+		},
+		Browse: {
+			Section: SectionInformational,
+			Name:    "browse",
+			Desc:    Desc{"browse the rows of a table in an interactive grid", "TABLE"},
+			Process: func(p *Params) error {
+				name, err := p.Get(true)
+				switch {
+				case err != nil:
+					return err
+				case name == "":
+					return text.ErrMissingRequiredArgument
+				}
+				ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+				defer cancel()
+				return p.Handler.Browse(ctx, name)
+			},
+		},
 	}
 	// set up map
 	cmdMap = make(map[string]Metacmd, len(cmds))
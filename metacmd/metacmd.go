@@ -42,6 +42,8 @@ const (
 	Connect
 	// Copy is the copy meta command (\copy).
 	Copy
+	// Import is the import file meta command (\import).
+	Import
 	// Disconnect is the disconnect meta command (\Z).
 	Disconnect
 	// Password is the change password meta command (\password).
@@ -88,4 +90,41 @@ const (
 	Timing
 	// Stats is the show stats meta command (\ss and variants).
 	Stats
+	// Background is the background job meta command (\bg, \jobs, \wait,
+	// \cancel, \result).
+	Background
+	// Explain is the explain meta command (\explain).
+	Explain
+	// Fetch is the federated fetch meta command (\fetch).
+	Fetch
+	// Cache is the result cache meta command (\cache).
+	Cache
+	// Reload is the reload config file meta command (\reload).
+	Reload
+	// Query is the query template meta command (\query).
+	Query
+	// Pool is the connection pool meta command (\pool).
+	Pool
+	// Page is the result paging meta command (\page, \next, \prev).
+	Page
+	// LoExport is the binary value export meta command (\lo_export).
+	LoExport
+	// Format is the SQL formatter meta command (\format).
+	Format
+	// History is the history meta command (\history).
+	History
+	// Diff is the query result comparison meta command (\diff).
+	Diff
+	// Activity is the running queries meta command (\activity, \kill).
+	Activity
+	// ShowCreate is the show CREATE statement meta command (\sd).
+	ShowCreate
+	// Session is the named session meta command (\session).
+	Session
+	// Call is the stored procedure call meta command (\call).
+	Call
+	// Prepare is the prepared statement meta command (\prepare, \execute).
+	Prepare
+	// Browse is the interactive table browser meta command (\browse).
+	Browse
 )
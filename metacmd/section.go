@@ -29,7 +29,7 @@ const (
 	SectionConnection      Section = "Connection"
 	SectionOperatingSystem Section = "Operating System"
 	SectionVariables       Section = "Variables"
-	// SectionLargeObjects    Section = "Large Objects"
+	SectionLargeObjects    Section = "Large Objects"
 )
 
 // String satisfies stringer.
@@ -43,6 +43,7 @@ var SectionOrder = []Section{
 	SectionInputOutput, SectionInformational, SectionFormatting,
 	SectionTransaction,
 	SectionConnection, SectionOperatingSystem, SectionVariables,
+	SectionLargeObjects,
 }
 
 // Listing writes the formatted command listing to w, separated into different
@@ -9,9 +9,11 @@ import (
 	"time"
 
 	"github.com/xo/dburl"
+	"github.com/xo/usql/alert"
 	"github.com/xo/usql/drivers"
 	"github.com/xo/usql/drivers/metadata"
 	"github.com/xo/usql/env"
+	"github.com/xo/usql/progress"
 	"github.com/xo/usql/rline"
 	"github.com/xo/usql/stmt"
 	"github.com/xo/usql/text"
@@ -51,12 +53,28 @@ type Handler interface {
 	Commit() error
 	// Rollback aborts the current transaction.
 	Rollback() error
+	// Savepoint defines a savepoint in the current transaction.
+	Savepoint(string) error
+	// RollbackTo rolls back the current transaction to a savepoint.
+	RollbackTo(string) error
+	// ReleaseSavepoint releases a savepoint in the current transaction.
+	ReleaseSavepoint(string) error
 	// Highlight highlights the statement.
 	Highlight(io.Writer, string) error
 	// GetTiming mode.
 	GetTiming() bool
 	// SetTiming mode.
 	SetTiming(bool)
+	// SaveState updates the session state of the database alias.
+	SaveState(func(*env.State)) error
+	// SaveSession saves the session as a named session.
+	SaveSession(string) (string, error)
+	// GetCache returns whether query results are cached.
+	GetCache() bool
+	// SetCache turns query result caching on or off.
+	SetCache(bool) error
+	// ClearCache removes all cached query results.
+	ClearCache() error
 	// GetOutput writer.
 	GetOutput() io.Writer
 	// SetOutput writer.
@@ -65,6 +83,62 @@ type Handler interface {
 	MetadataWriter(context.Context) (metadata.Writer, error)
 	// Print formats according to a format specifier and writes to handler's standard output.
 	Print(string, ...interface{})
+	// Background executes a query in the background, returning its job id.
+	Background(string) (int, error)
+	// Jobs writes the list of background jobs.
+	Jobs(io.Writer)
+	// Wait waits for a background job to finish, writing its result.
+	Wait(context.Context, int) error
+	// Cancel cancels a background job.
+	Cancel(int) error
+	// Result writes the result of a finished background job.
+	Result(int) error
+	// Explain writes the query plan of a query.
+	Explain(context.Context, string, bool) error
+	// Fetch registers the results of a query on a database alias as a table
+	// of the federated database.
+	Fetch(context.Context, string, string, string) error
+	// ReloadConfig reloads the config file.
+	ReloadConfig() error
+	// GetPageSize returns the number of rows of the pages of query results,
+	// or 0 when paging is off.
+	GetPageSize() int
+	// SetPageSize sets the number of rows of the pages of query results.
+	SetPageSize(int)
+	// Page shows the page of the results of the paged query, relative to the
+	// current one.
+	Page(context.Context, int) error
+	// PoolStatus writes the status of the connection pools of the database
+	// aliases.
+	PoolStatus(io.Writer) error
+	// CopyFile streams the data of a file to a COPY ... FROM STDIN statement,
+	// or the data of a COPY ... TO STDOUT statement to a file.
+	CopyFile(context.Context, string, string, bool) (int64, error)
+	// ExportBinary writes the value of a column of the single row of a query
+	// to a file.
+	ExportBinary(context.Context, string, string, string) error
+	// RunQuery executes a query template of the config file.
+	RunQuery(context.Context, string, map[string]string) error
+	// DiffResults writes the differences between the results of the previous
+	// and last queries, keyed by columns.
+	DiffResults(io.Writer, []string) error
+	// Activity writes the queries running on the database.
+	Activity(context.Context, io.Writer) error
+	// Kill kills a session or query running on the database.
+	Kill(context.Context, string) error
+	// ShowCreate writes the CREATE statement of a table or view.
+	ShowCreate(context.Context, string) error
+	// Call calls a stored procedure.
+	Call(context.Context, string) error
+	// Prepare prepares a named statement with the driver.
+	Prepare(context.Context, string) error
+	// ExecutePrepared executes a prepared statement, binding its arguments.
+	ExecutePrepared(context.Context, string, []string) error
+	// Progress starts reporting the progress of an operation, returning nil
+	// when not reported.
+	Progress(string) *progress.Progress
+	// Browse browses the rows of a table in an interactive grid.
+	Browse(context.Context, string) error
 }
 
 // Runner is a runner interface type.
@@ -99,6 +173,12 @@ const (
 	ExecCrosstab
 	// ExecWatch indicates repeated execution with a fixed time interval.
 	ExecWatch
+	// ExecChart indicates execution displaying the results as a chart
+	// (\chart).
+	ExecChart
+	// ExecQuery indicates execution querying the result sets of a statement
+	// whatever its prefix (\call).
+	ExecQuery
 )
 
 // Option contains parsed result options of a metacmd.
@@ -109,10 +189,22 @@ type Option struct {
 	Exec ExecType
 	// Params are accompanying string parameters for execution.
 	Params map[string]string
+	// Args are the query arguments bound by the driver.
+	Args []interface{}
+	// Stmt is the prepared statement executed instead of the query
+	// (\execute).
+	Stmt *sql.Stmt
+	// Alias is the database alias the query is executed on, instead of the
+	// connected database (\gset (alias=NAME)).
+	Alias string
 	// Crosstab are the crosstab column parameters.
 	Crosstab []string
+	// Chart are the chart kind and column parameters.
+	Chart []string
 	// Watch is the watch duration interval.
 	Watch time.Duration
+	// Alert is the threshold of the watched query, when not nil.
+	Alert *alert.Alert
 }
 
 func (opt *Option) ParseParams(params []string, defaultKey string) error {
@@ -211,3 +303,18 @@ func (p *Params) GetAll(exec bool) ([]string, error) {
 func (p *Params) GetRaw() string {
 	return p.Params.GetRaw()
 }
+
+// progressRows wraps the rows copied to a table, adding them to the progress.
+type progressRows struct {
+	drivers.CopyRows
+	p *progress.Progress
+}
+
+// Next advances to the next row.
+func (r *progressRows) Next() bool {
+	if r.CopyRows.Next() {
+		r.p.Add(1)
+		return true
+	}
+	return false
+}
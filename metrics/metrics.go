@@ -0,0 +1,112 @@
+// Package metrics collects Prometheus metrics of the executed queries and
+// open connections.
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	// queries is the number of executed queries per alias.
+	queries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usql",
+		Name:      "queries_total",
+		Help:      "Number of executed queries.",
+	}, []string{"alias"})
+	// queryErrors is the number of failed queries per alias.
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "usql",
+		Name:      "query_errors_total",
+		Help:      "Number of queries that failed.",
+	}, []string{"alias"})
+	// durations are the durations of the queries per alias.
+	durations = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "usql",
+		Name:      "query_duration_seconds",
+		Help:      "Duration of the executed queries.",
+		Buckets:   prometheus.ExponentialBuckets(0.001, 4, 10),
+	}, []string{"alias"})
+)
+
+// registry is the registry of the usql metrics.
+var registry = prometheus.NewRegistry()
+
+// conns are the tracked connections, with their alias.
+var conns = &connCollector{
+	desc: prometheus.NewDesc(
+		"usql_open_connections",
+		"Number of open connections to the databases.",
+		[]string{"alias"}, nil,
+	),
+	dbs: make(map[*sql.DB]string),
+}
+
+func init() {
+	registry.MustRegister(
+		queries, queryErrors, durations, conns,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Observe records a query executed on the alias.
+func Observe(alias string, d time.Duration, err error) {
+	queries.WithLabelValues(alias).Inc()
+	if err != nil {
+		queryErrors.WithLabelValues(alias).Inc()
+	}
+	durations.WithLabelValues(alias).Observe(d.Seconds())
+}
+
+// Track reports the open connections of db for the alias, until Untrack is
+// called. Tracking an already tracked db changes its alias.
+func Track(db *sql.DB, alias string) {
+	conns.Lock()
+	defer conns.Unlock()
+	conns.dbs[db] = alias
+}
+
+// Untrack stops reporting the open connections of db.
+func Untrack(db *sql.DB) {
+	conns.Lock()
+	defer conns.Unlock()
+	delete(conns.dbs, db)
+}
+
+// Handler returns the HTTP handler of the metrics, in the Prometheus text
+// format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// connCollector collects the open connections of the tracked databases.
+type connCollector struct {
+	sync.Mutex
+	desc *prometheus.Desc
+	dbs  map[*sql.DB]string
+}
+
+// Describe satisfies the prometheus.Collector interface.
+func (c *connCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+// Collect satisfies the prometheus.Collector interface.
+func (c *connCollector) Collect(ch chan<- prometheus.Metric) {
+	c.Lock()
+	defer c.Unlock()
+	open := make(map[string]int)
+	for db, alias := range c.dbs {
+		open[alias] += db.Stats().OpenConnections
+	}
+	for alias, n := range open {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n), alias)
+	}
+}
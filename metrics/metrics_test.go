@@ -0,0 +1,44 @@
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestHandler(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	if err := db.Ping(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	Track(db, "test")
+	defer Untrack(db)
+	Observe("test", 10*time.Millisecond, nil)
+	Observe("test", time.Second, errors.New("failed"))
+	w := httptest.NewRecorder()
+	Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	buf, err := io.ReadAll(w.Body)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for _, exp := range []string{
+		`usql_queries_total{alias="test"} 2`,
+		`usql_query_errors_total{alias="test"} 1`,
+		`usql_query_duration_seconds_count{alias="test"} 2`,
+		`usql_open_connections{alias="test"} 1`,
+	} {
+		if !strings.Contains(string(buf), exp) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", exp, buf)
+		}
+	}
+}
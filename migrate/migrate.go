@@ -0,0 +1,259 @@
+// Package migrate applies versioned SQL migrations to a database.
+package migrate
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+// DefaultTable is the default name of the table tracking the applied
+// migrations.
+const DefaultTable = "schema_migrations"
+
+// Latest is the target version of the latest migration.
+const Latest int64 = -1
+
+// Migration is a versioned migration.
+type Migration struct {
+	Version int64
+	Name    string
+	// Up and Down are the paths of the up and down SQL files. Down is empty
+	// when the migration cannot be reverted.
+	Up, Down string
+}
+
+// fileRE matches migration file names (ie, 0001_create_users.up.sql).
+var fileRE = regexp.MustCompile(`^(\d+)(?:_(.*?))?\.(up|down)\.sql$`)
+
+// Load loads the migrations of dir, ordered by their version.
+func Load(dir string) ([]*Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	m := make(map[int64]*Migration)
+	for _, entry := range entries {
+		match := fileRE.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+		mig, ok := m[version]
+		switch {
+		case !ok:
+			mig = &Migration{Version: version, Name: match[2]}
+			m[version] = mig
+		case mig.Name != match[2]:
+			return nil, fmt.Errorf("migrations %s and %s have the same version", mig.Name, match[2])
+		}
+		path := filepath.Join(dir, entry.Name())
+		if match[3] == "up" {
+			mig.Up = path
+		} else {
+			mig.Down = path
+		}
+	}
+	migrations := make([]*Migration, 0, len(m))
+	for _, mig := range m {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %d has no up file", mig.Version)
+		}
+		migrations = append(migrations, mig)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// String satisfies the fmt.Stringer interface.
+func (mig *Migration) String() string {
+	if mig.Name == "" {
+		return strconv.FormatInt(mig.Version, 10)
+	}
+	return strconv.FormatInt(mig.Version, 10) + "_" + mig.Name
+}
+
+// Options are the migration options.
+type Options struct {
+	// Dir is the migrations directory.
+	Dir string
+	// To is the target version, or Latest.
+	To int64
+	// Table is the tracking table, DefaultTable when empty.
+	Table string
+	// DryRun displays the statements instead of executing them.
+	DryRun bool
+}
+
+// Run migrates the database to the target version, applying the up files of
+// the pending migrations up to the target version, and the down files of the
+// applied migrations after it. Each migration is executed in a transaction,
+// along with the update of the tracking table.
+func Run(ctx context.Context, w io.Writer, u *dburl.URL, db *sql.DB, opts Options) error {
+	migrations, err := Load(opts.Dir)
+	if err != nil {
+		return err
+	}
+	if opts.Table == "" {
+		opts.Table = DefaultTable
+	}
+	applied, err := readApplied(ctx, u, db, opts.Table, !opts.DryRun)
+	if err != nil {
+		return err
+	}
+	to := opts.To
+	if to == Latest && len(migrations) != 0 {
+		to = migrations[len(migrations)-1].Version
+	}
+	byVersion := make(map[int64]*Migration, len(migrations))
+	for _, mig := range migrations {
+		byVersion[mig.Version] = mig
+	}
+	// revert applied migrations after the target, newest first
+	var down []*Migration
+	for _, version := range applied {
+		if version <= to {
+			continue
+		}
+		switch mig, ok := byVersion[version]; {
+		case !ok:
+			return fmt.Errorf("applied migration %d not found in %s", version, opts.Dir)
+		case mig.Down == "":
+			return fmt.Errorf("migration %s has no down file", mig)
+		default:
+			down = append([]*Migration{mig}, down...)
+		}
+	}
+	m := &migrator{w: w, u: u, db: db, table: opts.Table, dryRun: opts.DryRun}
+	for _, mig := range down {
+		if err := m.run(ctx, mig, false); err != nil {
+			return err
+		}
+	}
+	// apply pending migrations up to the target, oldest first
+	isApplied := make(map[int64]bool, len(applied))
+	for _, version := range applied {
+		isApplied[version] = true
+	}
+	var n int
+	for _, mig := range migrations {
+		if mig.Version > to || isApplied[mig.Version] {
+			continue
+		}
+		if err := m.run(ctx, mig, true); err != nil {
+			return err
+		}
+		n++
+	}
+	if n == 0 && len(down) == 0 {
+		fmt.Fprintln(w, "no migrations to run")
+	}
+	return nil
+}
+
+// readApplied returns the versions of the applied migrations, ordered. The
+// tracking table is created when missing and create is true.
+func readApplied(ctx context.Context, u *dburl.URL, db *sql.DB, table string, create bool) ([]int64, error) {
+	rows, err := db.QueryContext(ctx, "SELECT version FROM "+table+" ORDER BY version")
+	switch {
+	case err != nil && !create:
+		return nil, nil
+	case err != nil:
+		if _, err := db.ExecContext(ctx, createTable(u, table)); err != nil {
+			return nil, fmt.Errorf("unable to create %s: %w", table, drivers.WrapErr(u.Driver, err))
+		}
+		return nil, nil
+	}
+	defer rows.Close()
+	var versions []int64
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
+
+// createTable returns the statement creating the tracking table.
+func createTable(u *dburl.URL, table string) string {
+	timestamp := "TIMESTAMP"
+	if drivers.Caps(u).Dialect == "sqlserver" {
+		// sqlserver's timestamp is a row version
+		timestamp = "DATETIME2"
+	}
+	return fmt.Sprintf("CREATE TABLE %s (version BIGINT NOT NULL PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at %s NOT NULL)", table, timestamp)
+}
+
+// migrator runs migrations.
+type migrator struct {
+	w      io.Writer
+	u      *dburl.URL
+	db     *sql.DB
+	table  string
+	dryRun bool
+}
+
+// run runs the up or down file of the migration.
+func (m *migrator) run(ctx context.Context, mig *Migration, up bool) error {
+	path, dir := mig.Up, "up"
+	if !up {
+		path, dir = mig.Down, "down"
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	stmts, err := drivers.Statements(m.u, string(buf))
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if m.dryRun {
+		fmt.Fprintf(m.w, "-- %s %s\n", dir, mig)
+		for _, s := range stmts {
+			fmt.Fprintln(m.w, s)
+		}
+		return nil
+	}
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, s := range stmts {
+		if _, err := tx.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("%s %s: %w", dir, mig, drivers.WrapErr(m.u.Driver, err))
+		}
+	}
+	p := func(n int) string { return drivers.Placeholder(m.u, n) }
+	if up {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("INSERT INTO %s (version, name, applied_at) VALUES (%s, %s, %s)", m.table, p(1), p(2), p(3)), mig.Version, mig.Name, time.Now().UTC())
+	} else {
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE version = %s", m.table, p(1)), mig.Version)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to update %s: %w", m.table, drivers.WrapErr(m.u.Driver, err))
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+	fmt.Fprintf(m.w, "%s %s\n", strings.ToUpper(dir), mig)
+	return nil
+}
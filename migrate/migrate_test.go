@@ -0,0 +1,63 @@
+package migrate
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"0002_add_email.up.sql",
+		"0001_create_users.up.sql",
+		"0001_create_users.down.sql",
+		"10.up.sql",
+		"README.md",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	migrations, err := Load(dir)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := []struct {
+		s       string
+		hasDown bool
+	}{
+		{"1_create_users", true},
+		{"2_add_email", false},
+		{"10", false},
+	}
+	if len(migrations) != len(exp) {
+		t.Fatalf("expected %d migrations, got: %d", len(exp), len(migrations))
+	}
+	for i, mig := range migrations {
+		if s := mig.String(); s != exp[i].s {
+			t.Errorf("migration %d expected %s, got: %s", i, exp[i].s, s)
+		}
+		if hasDown := mig.Down != ""; hasDown != exp[i].hasDown {
+			t.Errorf("migration %d expected down %t, got: %t", i, exp[i].hasDown, hasDown)
+		}
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	tests := [][]string{
+		{"1_a.up.sql", "1_b.up.sql"},
+		{"1_a.down.sql"},
+	}
+	for i, test := range tests {
+		dir := t.TempDir()
+		for _, name := range test {
+			if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if _, err := Load(dir); err == nil {
+			t.Errorf("test %d expected error, got nil", i)
+		}
+	}
+}
@@ -0,0 +1,179 @@
+// Package notify posts notifications of finished statements and scheduled
+// jobs to Slack incoming webhooks or to generic webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xo/usql/redact"
+)
+
+// Notifier types.
+const (
+	// TypeSlack posts Slack messages to an incoming webhook.
+	TypeSlack = "slack"
+	// TypeWebhook posts the JSON encoded events to a webhook.
+	TypeWebhook = "webhook"
+)
+
+// DefaultMinDuration is the default minimum duration of the notified
+// statements.
+const DefaultMinDuration = 30 * time.Second
+
+// timeout is the timeout of a notification.
+const timeout = 10 * time.Second
+
+// maxSQL is the maximum length of the statements of Slack messages.
+const maxSQL = 500
+
+// Event is a finished statement or scheduled job, or the alert of a watched
+// query.
+type Event struct {
+	// Name is the database alias of the statement, or the name of the job.
+	Name string
+	// SQL is the statement.
+	SQL string
+	// Duration is the duration of the statement.
+	Duration time.Duration
+	// Rows is the number of rows returned or affected by the statement, or
+	// -1 when unknown.
+	Rows int64
+	// Err is the error of the statement.
+	Err error
+	// Alert is the message of the alert of a watched query, when not empty.
+	Alert string
+}
+
+// Notifier posts notifications to a webhook.
+type Notifier struct {
+	// Type is the type of the notifier, slack or webhook.
+	Type string
+	// URL is the URL of the webhook.
+	URL string
+	// MinDuration is the minimum duration of the notified statements,
+	// DefaultMinDuration when 0.
+	MinDuration time.Duration
+	// Client is the client of the webhook, http.DefaultClient when nil.
+	Client *http.Client
+}
+
+// New creates a notifier of the type, posting to the webhook URL.
+func New(typ, urlstr string, minDuration time.Duration) (*Notifier, error) {
+	switch {
+	case typ != TypeSlack && typ != TypeWebhook:
+		return nil, fmt.Errorf("invalid notifier type %q", typ)
+	case urlstr == "":
+		return nil, fmt.Errorf("notifier url is required")
+	}
+	return &Notifier{Type: typ, URL: urlstr, MinDuration: minDuration}, nil
+}
+
+// Long returns true when a statement taking d is notified. A nil notifier
+// notifies nothing.
+func (n *Notifier) Long(d time.Duration) bool {
+	if n == nil {
+		return false
+	}
+	min := n.MinDuration
+	if min == 0 {
+		min = DefaultMinDuration
+	}
+	return d >= min
+}
+
+// Notify posts the notification of the event.
+func (n *Notifier) Notify(ctx context.Context, e Event) error {
+	var v interface{}
+	if n.Type == TypeSlack {
+		v = map[string]string{"text": slackText(e)}
+	} else {
+		v = newPayload(e)
+	}
+	buf, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.URL, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := n.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+// payload is the body of a webhook notification.
+type payload struct {
+	Name     string  `json:"name"`
+	SQL      string  `json:"sql,omitempty"`
+	Duration float64 `json:"duration_seconds"`
+	Rows     *int64  `json:"rows,omitempty"`
+	Error    string  `json:"error,omitempty"`
+	Alert    string  `json:"alert,omitempty"`
+}
+
+// newPayload creates the webhook payload of the event.
+func newPayload(e Event) payload {
+	p := payload{
+		Name:     e.Name,
+		SQL:      redact.String(e.SQL),
+		Duration: e.Duration.Seconds(),
+		Alert:    redact.String(e.Alert),
+	}
+	if e.Rows >= 0 {
+		p.Rows = &e.Rows
+	}
+	if e.Err != nil {
+		p.Error = redact.String(e.Err.Error())
+	}
+	return p
+}
+
+// slackText returns the text of the Slack message of the event.
+func slackText(e Event) string {
+	var sb strings.Builder
+	d := e.Duration.Round(time.Millisecond)
+	switch {
+	case e.Alert != "":
+		fmt.Fprintf(&sb, ":rotating_light: `%s` alert: %s", e.Name, redact.String(e.Alert))
+	case e.Err != nil:
+		fmt.Fprintf(&sb, ":x: `%s` failed after %s: %s", e.Name, d, redact.String(e.Err.Error()))
+	default:
+		fmt.Fprintf(&sb, ":white_check_mark: `%s` finished in %s", e.Name, d)
+		switch {
+		case e.Rows == 1:
+			sb.WriteString(", 1 row")
+		case e.Rows >= 0:
+			fmt.Fprintf(&sb, ", %d rows", e.Rows)
+		}
+	}
+	if sqlstr := strings.TrimSpace(redact.String(e.SQL)); sqlstr != "" {
+		if utf8.RuneCountInString(sqlstr) > maxSQL {
+			sqlstr = string([]rune(sqlstr)[:maxSQL]) + "…"
+		}
+		fmt.Fprintf(&sb, "\n```%s```", sqlstr)
+	}
+	return sb.String()
+}
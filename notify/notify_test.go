@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	var body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		buf, _ := io.ReadAll(req.Body)
+		body = string(buf)
+	}))
+	defer srv.Close()
+	tests := []struct {
+		typ string
+		e   Event
+		exp string
+	}{
+		{
+			TypeSlack,
+			Event{Name: "app_db", SQL: "select * from t", Duration: 1500 * time.Millisecond, Rows: 42},
+			`{"text":":white_check_mark: ` + "`app_db`" + ` finished in 1.5s, 42 rows\n` + "```select * from t```" + `"}`,
+		},
+		{
+			TypeSlack,
+			Event{Name: "nightly", Duration: 2 * time.Second, Rows: -1, Err: errors.New("boom")},
+			`{"text":":x: ` + "`nightly`" + ` failed after 2s: boom"}`,
+		},
+		{
+			TypeWebhook,
+			Event{Name: "app_db", SQL: "delete from t", Duration: time.Second, Rows: 3},
+			`{"name":"app_db","sql":"delete from t","duration_seconds":1,"rows":3}`,
+		},
+		{
+			TypeWebhook,
+			Event{Name: "app_db", Duration: time.Second, Rows: -1, Err: errors.New("boom")},
+			`{"name":"app_db","duration_seconds":1,"error":"boom"}`,
+		},
+		{
+			TypeSlack,
+			Event{Name: "app_db", SQL: "select count(*) from jobs", Rows: 1, Alert: "value 1500 crossed value > 1000"},
+			`{"text":":rotating_light: ` + "`app_db`" + ` alert: value 1500 crossed value \u003e 1000\n` + "```select count(*) from jobs```" + `"}`,
+		},
+		{
+			TypeWebhook,
+			Event{Name: "app_db", SQL: "select count(*) from jobs", Rows: 1, Alert: "value 1500 crossed value > 1000"},
+			`{"name":"app_db","sql":"select count(*) from jobs","duration_seconds":0,"rows":1,"alert":"value 1500 crossed value \u003e 1000"}`,
+		},
+	}
+	for i, test := range tests {
+		n, err := New(test.typ, srv.URL, 0)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if err := n.Notify(context.Background(), test.e); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if body != test.exp {
+			t.Errorf("test %d expected %s, got: %s", i, test.exp, body)
+		}
+	}
+}
+
+func TestNotifyError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+	n := &Notifier{Type: TypeSlack, URL: srv.URL}
+	if err := n.Notify(context.Background(), Event{Name: "x"}); err == nil || err.Error() != "unexpected status 404 Not Found" {
+		t.Errorf("expected status error, got: %v", err)
+	}
+	if _, err := New("email", srv.URL, 0); err == nil || err.Error() != `invalid notifier type "email"` {
+		t.Errorf("expected type error, got: %v", err)
+	}
+}
+
+func TestLong(t *testing.T) {
+	var n *Notifier
+	if n.Long(time.Hour) {
+		t.Errorf("expected nil notifier to notify nothing")
+	}
+	n = &Notifier{Type: TypeSlack}
+	if n.Long(time.Second) || !n.Long(DefaultMinDuration) {
+		t.Errorf("expected default minimum duration %s", DefaultMinDuration)
+	}
+	n.MinDuration = time.Second
+	if !n.Long(time.Second) {
+		t.Errorf("expected minimum duration %s", n.MinDuration)
+	}
+}
@@ -0,0 +1,294 @@
+// Package offline caches snapshots of the metadata of databases, to describe
+// and complete their objects, and diagram their tables, without connecting to
+// them.
+package offline
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/text"
+)
+
+// ErrNoSnapshot is the error of the aliases without a metadata snapshot.
+var ErrNoSnapshot = errors.New("no metadata snapshot")
+
+// Snapshot is a snapshot of the metadata of a database, without the system
+// objects.
+type Snapshot struct {
+	// Alias is the database alias of the snapshot.
+	Alias string `json:"alias"`
+	// Driver is the driver of the database.
+	Driver string `json:"driver"`
+	// URL is the redacted URL of the database.
+	URL string `json:"url"`
+	// Taken is the time of the snapshot.
+	Taken time.Time `json:"taken"`
+
+	Schemas           []metadata.Schema           `json:"schemas,omitempty"`
+	Tables            []metadata.Table            `json:"tables,omitempty"`
+	Columns           []metadata.Column           `json:"columns,omitempty"`
+	Indexes           []metadata.Index            `json:"indexes,omitempty"`
+	IndexColumns      []metadata.IndexColumn      `json:"index_columns,omitempty"`
+	Constraints       []metadata.Constraint       `json:"constraints,omitempty"`
+	ConstraintColumns []metadata.ConstraintColumn `json:"constraint_columns,omitempty"`
+	Functions         []metadata.Function         `json:"functions,omitempty"`
+	FunctionColumns   []metadata.FunctionColumn   `json:"function_columns,omitempty"`
+	Sequences         []metadata.Sequence         `json:"sequences,omitempty"`
+	// Definitions are the definitions of the tables, for their diagrams.
+	Definitions []*dump.Table `json:"definitions,omitempty"`
+}
+
+// Take takes a snapshot of the metadata of the database of the alias, reading
+// the objects the metadata reader of the driver supports, and skipping those
+// not supported by the database.
+func Take(ctx context.Context, alias string, u *dburl.URL, db *sql.DB) (*Snapshot, error) {
+	r, err := drivers.NewMetadataReader(ctx, u, db, nil)
+	if err != nil {
+		return nil, err
+	}
+	s := &Snapshot{
+		Alias:  alias,
+		Driver: u.Driver,
+		URL:    u.Redacted(),
+		Taken:  time.Now(),
+	}
+	if r, ok := r.(metadata.SchemaReader); ok {
+		res, err := r.Schemas(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("schemas: %w", err)
+		default:
+			for res.Next() {
+				s.Schemas = append(s.Schemas, *res.Get())
+			}
+		}
+	}
+	if r, ok := r.(metadata.TableReader); ok {
+		res, err := r.Tables(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("tables: %w", err)
+		default:
+			for res.Next() {
+				// skip the system tables of the readers ignoring WithSystem
+				if t := res.Get(); !strings.HasPrefix(t.Type, "SYSTEM") {
+					s.Tables = append(s.Tables, *t)
+				}
+			}
+		}
+	}
+	// the columns are read by table, as some readers read the columns of all
+	// the tables, the system ones included, when not filtered by table
+	if r, ok := r.(metadata.ColumnReader); ok {
+		for _, t := range s.Tables {
+			res, err := r.Columns(metadata.Filter{Catalog: t.Catalog, Schema: t.Schema, Parent: t.Name, WithSystem: true})
+			if err != nil {
+				return nil, fmt.Errorf("columns of %s: %w", t.Name, err)
+			}
+			for res.Next() {
+				if c := res.Get(); c.Table == t.Name {
+					s.Columns = append(s.Columns, *c)
+				}
+			}
+		}
+	}
+	if r, ok := r.(metadata.IndexReader); ok {
+		res, err := r.Indexes(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("indexes: %w", err)
+		default:
+			for res.Next() {
+				s.Indexes = append(s.Indexes, *res.Get())
+			}
+		}
+	}
+	if r, ok := r.(metadata.IndexColumnReader); ok {
+		res, err := r.IndexColumns(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("index columns: %w", err)
+		default:
+			for res.Next() {
+				s.IndexColumns = append(s.IndexColumns, *res.Get())
+			}
+		}
+	}
+	if r, ok := r.(metadata.ConstraintReader); ok {
+		res, err := r.Constraints(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("constraints: %w", err)
+		default:
+			for res.Next() {
+				s.Constraints = append(s.Constraints, *res.Get())
+			}
+		}
+	}
+	if r, ok := r.(metadata.ConstraintColumnReader); ok {
+		res, err := r.ConstraintColumns(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("constraint columns: %w", err)
+		default:
+			for res.Next() {
+				s.ConstraintColumns = append(s.ConstraintColumns, *res.Get())
+			}
+		}
+	}
+	if r, ok := r.(metadata.FunctionReader); ok {
+		res, err := r.Functions(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("functions: %w", err)
+		default:
+			for res.Next() {
+				s.Functions = append(s.Functions, *res.Get())
+			}
+		}
+	}
+	if r, ok := r.(metadata.FunctionColumnReader); ok {
+		res, err := r.FunctionColumns(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("function columns: %w", err)
+		default:
+			for res.Next() {
+				s.FunctionColumns = append(s.FunctionColumns, *res.Get())
+			}
+		}
+	}
+	if r, ok := r.(metadata.SequenceReader); ok {
+		res, err := r.Sequences(metadata.Filter{})
+		switch {
+		case errors.Is(err, text.ErrNotSupported):
+		case err != nil:
+			return nil, fmt.Errorf("sequences: %w", err)
+		default:
+			for res.Next() {
+				s.Sequences = append(s.Sequences, *res.Get())
+			}
+		}
+	}
+	if s.Definitions, err = dump.ReadTables(ctx, u, db, nil); err != nil {
+		return nil, fmt.Errorf("table definitions: %w", err)
+	}
+	return s, nil
+}
+
+// Store is a store of metadata snapshots in a directory, a snapshot per
+// database alias.
+type Store struct {
+	Dir string
+}
+
+// New creates a store in the usql directory of the user's cache directory.
+func New() (*Store, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	return &Store{Dir: filepath.Join(dir, "usql", "metadata")}, nil
+}
+
+// Save saves the snapshot, replacing the previous snapshot of its alias.
+func (st *Store) Save(s *Snapshot) error {
+	if err := os.MkdirAll(st.Dir, 0o700); err != nil {
+		return err
+	}
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	// write the snapshot atomically, so that a failed save keeps the last one
+	name := st.path(s.Alias)
+	f, err := os.CreateTemp(st.Dir, ".snapshot-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
+
+// Load loads the last snapshot of the alias, returning an error wrapping
+// ErrNoSnapshot when there is none.
+func (st *Store) Load(alias string) (*Snapshot, error) {
+	buf, err := os.ReadFile(st.path(alias))
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, fmt.Errorf("%w of %s, take one with usql offline sync %s", ErrNoSnapshot, alias, alias)
+	case err != nil:
+		return nil, err
+	}
+	s := new(Snapshot)
+	if err := json.Unmarshal(buf, s); err != nil {
+		return nil, fmt.Errorf("invalid metadata snapshot of %s: %w", alias, err)
+	}
+	return s, nil
+}
+
+// List returns the snapshots of the store, sorted by alias, without their
+// metadata.
+func (st *Store) List() ([]*Snapshot, error) {
+	entries, err := os.ReadDir(st.Dir)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	var v []*Snapshot
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || strings.HasPrefix(name, ".") || filepath.Ext(name) != ".json" {
+			continue
+		}
+		alias, err := url.PathUnescape(strings.TrimSuffix(name, ".json"))
+		if err != nil {
+			continue
+		}
+		s, err := st.Load(alias)
+		if err != nil {
+			return nil, err
+		}
+		v = append(v, &Snapshot{Alias: s.Alias, Driver: s.Driver, URL: s.URL, Taken: s.Taken})
+	}
+	sort.Slice(v, func(i, j int) bool {
+		return v[i].Alias < v[j].Alias
+	})
+	return v, nil
+}
+
+// path returns the path of the snapshot of the alias.
+func (st *Store) path(alias string) string {
+	return filepath.Join(st.Dir, url.PathEscape(alias)+".json")
+}
@@ -0,0 +1,109 @@
+package offline
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers/metadata"
+	_ "github.com/xo/usql/drivers/sqlite3"
+)
+
+func TestTake(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "test.db")
+	u, err := dburl.Parse("sqlite3:" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, s := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL)`,
+		`CREATE UNIQUE INDEX users_email ON users (email)`,
+		`CREATE TABLE orders (id INTEGER PRIMARY KEY, user_id INTEGER REFERENCES users (id))`,
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s, err := Take(ctx, "app", u, db)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s.Alias != "app" || s.Driver != "sqlite3" || len(s.Definitions) != 2 {
+		t.Fatalf("expected the snapshot of the 2 tables of app, got: %s %s %d", s.Alias, s.Driver, len(s.Definitions))
+	}
+	st := &Store{Dir: filepath.Join(t.TempDir(), "metadata")}
+	if _, err := st.Load("app"); !errors.Is(err, ErrNoSnapshot) {
+		t.Errorf("expected no snapshot, got: %v", err)
+	}
+	if err := st.Save(s); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s, err = st.Load("app"); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	r := s.Reader()
+	tables, err := r.Tables(metadata.Filter{Name: "us%"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for tables.Next() {
+		names = append(names, tables.Get().Name)
+	}
+	if exp := []string{"users"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected tables %v, got: %v", exp, names)
+	}
+	columns, err := r.Columns(metadata.Filter{Parent: "USERS"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	names = nil
+	for columns.Next() {
+		names = append(names, columns.Get().Name)
+	}
+	if exp := []string{"email", "id"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected columns %v, got: %v", exp, names)
+	}
+	list, err := st.List()
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case len(list) != 1 || list[0].Alias != "app" || list[0].Tables != nil:
+		t.Errorf("expected the snapshot of app without its metadata, got: %v", list)
+	}
+}
+
+func TestLike(t *testing.T) {
+	tests := []struct {
+		pattern, s string
+		exp        bool
+	}{
+		{"", "users", true},
+		{"users", "Users", true},
+		{"us%", "users", true},
+		{"%er%", "users", true},
+		{"u_ers", "users", true},
+		{"u_ers", "uers", false},
+		{"%s", "users", true},
+		{"%x", "users", false},
+		{"user", "users", false},
+		{"%", "", true},
+		{"a%b%c", "aXbYc", true},
+		{"a%b%c", "aXbY", false},
+	}
+	for i, test := range tests {
+		if ok := like(test.pattern, test.s); ok != test.exp {
+			t.Errorf("test %d expected %q to match %q: %t, got: %t", i, test.s, test.pattern, test.exp, ok)
+		}
+	}
+}
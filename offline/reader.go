@@ -0,0 +1,194 @@
+package offline
+
+import (
+	"strings"
+
+	"github.com/xo/usql/drivers/metadata"
+)
+
+// Reader is a metadata reader of a snapshot. The name patterns of the filters
+// are matched as LIKE patterns, ignoring case. As the snapshot has no system
+// objects, nor the search path of the database, the WithSystem and
+// OnlyVisible filters are ignored.
+type Reader struct {
+	s *Snapshot
+}
+
+// Reader returns the metadata reader of the snapshot.
+func (s *Snapshot) Reader() *Reader {
+	return &Reader{s: s}
+}
+
+var _ interface {
+	metadata.BasicReader
+	metadata.IndexReader
+	metadata.IndexColumnReader
+	metadata.ConstraintReader
+	metadata.ConstraintColumnReader
+	metadata.FunctionReader
+	metadata.FunctionColumnReader
+	metadata.SequenceReader
+} = (*Reader)(nil)
+
+// Schemas satisfies the metadata.SchemaReader interface.
+func (r *Reader) Schemas(f metadata.Filter) (*metadata.SchemaSet, error) {
+	var v []metadata.Schema
+	for _, s := range r.s.Schemas {
+		if like(f.Catalog, s.Catalog) && like(f.Name, s.Schema) {
+			v = append(v, s)
+		}
+	}
+	return metadata.NewSchemaSet(v), nil
+}
+
+// Tables satisfies the metadata.TableReader interface.
+func (r *Reader) Tables(f metadata.Filter) (*metadata.TableSet, error) {
+	var v []metadata.Table
+	for _, t := range r.s.Tables {
+		if like(f.Catalog, t.Catalog) && like(f.Schema, t.Schema) && like(f.Name, t.Name) && hasType(f.Types, t.Type) {
+			v = append(v, t)
+		}
+	}
+	return metadata.NewTableSet(v), nil
+}
+
+// Columns satisfies the metadata.ColumnReader interface.
+func (r *Reader) Columns(f metadata.Filter) (*metadata.ColumnSet, error) {
+	var v []metadata.Column
+	for _, c := range r.s.Columns {
+		if like(f.Catalog, c.Catalog) && like(f.Schema, c.Schema) && like(f.Parent, c.Table) && like(f.Name, c.Name) {
+			v = append(v, c)
+		}
+	}
+	return metadata.NewColumnSet(v), nil
+}
+
+// Indexes satisfies the metadata.IndexReader interface.
+func (r *Reader) Indexes(f metadata.Filter) (*metadata.IndexSet, error) {
+	var v []metadata.Index
+	for _, i := range r.s.Indexes {
+		if like(f.Catalog, i.Catalog) && like(f.Schema, i.Schema) && like(f.Parent, i.Table) && like(f.Name, i.Name) {
+			v = append(v, i)
+		}
+	}
+	return metadata.NewIndexSet(v), nil
+}
+
+// IndexColumns satisfies the metadata.IndexColumnReader interface.
+func (r *Reader) IndexColumns(f metadata.Filter) (*metadata.IndexColumnSet, error) {
+	var v []metadata.IndexColumn
+	for _, c := range r.s.IndexColumns {
+		if like(f.Catalog, c.Catalog) && like(f.Schema, c.Schema) && like(f.Parent, c.Table) && like(f.Name, c.IndexName) {
+			v = append(v, c)
+		}
+	}
+	return metadata.NewIndexColumnSet(v), nil
+}
+
+// Constraints satisfies the metadata.ConstraintReader interface.
+func (r *Reader) Constraints(f metadata.Filter) (*metadata.ConstraintSet, error) {
+	var v []metadata.Constraint
+	for _, c := range r.s.Constraints {
+		if like(f.Catalog, c.Catalog) && like(f.Schema, c.Schema) && like(f.Parent, c.Table) && like(f.Reference, c.ForeignTable) && like(f.Name, c.Name) && hasType(f.Types, c.Type) {
+			v = append(v, c)
+		}
+	}
+	return metadata.NewConstraintSet(v), nil
+}
+
+// ConstraintColumns satisfies the metadata.ConstraintColumnReader interface.
+func (r *Reader) ConstraintColumns(f metadata.Filter) (*metadata.ConstraintColumnSet, error) {
+	var v []metadata.ConstraintColumn
+	for _, c := range r.s.ConstraintColumns {
+		if like(f.Catalog, c.Catalog) && like(f.Schema, c.Schema) && like(f.Parent, c.Table) && like(f.Name, c.Constraint) {
+			v = append(v, c)
+		}
+	}
+	return metadata.NewConstraintColumnSet(v), nil
+}
+
+// Functions satisfies the metadata.FunctionReader interface.
+func (r *Reader) Functions(f metadata.Filter) (*metadata.FunctionSet, error) {
+	var v []metadata.Function
+	for _, fn := range r.s.Functions {
+		if like(f.Catalog, fn.Catalog) && like(f.Schema, fn.Schema) && like(f.Name, fn.Name) && hasType(f.Types, fn.Type) {
+			v = append(v, fn)
+		}
+	}
+	return metadata.NewFunctionSet(v), nil
+}
+
+// FunctionColumns satisfies the metadata.FunctionColumnReader interface.
+func (r *Reader) FunctionColumns(f metadata.Filter) (*metadata.FunctionColumnSet, error) {
+	var v []metadata.FunctionColumn
+	for _, c := range r.s.FunctionColumns {
+		if like(f.Catalog, c.Catalog) && like(f.Schema, c.Schema) && like(f.Parent, c.FunctionName) && like(f.Name, c.Name) {
+			v = append(v, c)
+		}
+	}
+	return metadata.NewFunctionColumnSet(v), nil
+}
+
+// Sequences satisfies the metadata.SequenceReader interface.
+func (r *Reader) Sequences(f metadata.Filter) (*metadata.SequenceSet, error) {
+	var v []metadata.Sequence
+	for _, s := range r.s.Sequences {
+		if like(f.Catalog, s.Catalog) && like(f.Schema, s.Schema) && like(f.Name, s.Name) {
+			v = append(v, s)
+		}
+	}
+	return metadata.NewSequenceSet(v), nil
+}
+
+// hasType returns true when types is empty or has typ.
+func hasType(types []string, typ string) bool {
+	if len(types) == 0 {
+		return true
+	}
+	for _, t := range types {
+		if strings.EqualFold(t, typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// like returns true when s matches the LIKE pattern, ignoring case, % matching
+// any characters and _ any single character. An empty pattern matches any s.
+func like(pattern, s string) bool {
+	if pattern == "" {
+		return true
+	}
+	return match([]rune(strings.ToLower(pattern)), []rune(strings.ToLower(s)))
+}
+
+// match returns true when s matches the LIKE pattern p.
+func match(p, s []rune) bool {
+	for len(p) != 0 {
+		switch p[0] {
+		case '%':
+			for len(p) != 0 && p[0] == '%' {
+				p = p[1:]
+			}
+			if len(p) == 0 {
+				return true
+			}
+			for i := range s {
+				if match(p, s[i:]) {
+					return true
+				}
+			}
+			return false
+		case '_':
+			if len(s) == 0 {
+				return false
+			}
+		default:
+			if len(s) == 0 || p[0] != s[0] {
+				return false
+			}
+		}
+		p, s = p[1:], s[1:]
+	}
+	return len(s) == 0
+}
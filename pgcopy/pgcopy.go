@@ -0,0 +1,185 @@
+// Package pgcopy parses psql's \copy command into the COPY statement
+// streaming its data from the standard input or to the standard output, with
+// PostgreSQL's copy protocol.
+package pgcopy
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Command is a parsed \copy command.
+type Command struct {
+	// Statement is the COPY ... FROM STDIN or COPY ... TO STDOUT statement of
+	// the command.
+	Statement string
+	// From is set when copying the data of the file to a table.
+	From bool
+	// File is the name of the file, or empty for the standard input or
+	// output.
+	File string
+}
+
+// Parse parses a \copy command:
+//
+//	TABLE [(COLUMN, ...)] FROM {'FILE' | STDIN | PSTDIN} [[WITH] OPTIONS]
+//	{TABLE [(COLUMN, ...)] | (QUERY)} TO {'FILE' | STDOUT | PSTDOUT} [[WITH] OPTIONS]
+//
+// The options are passed as is to the COPY statement.
+func Parse(s string) (*Command, error) {
+	target, s, err := table(strings.TrimRight(strings.TrimSpace(s), ";"))
+	if err != nil {
+		return nil, err
+	}
+	dir, s := word(s)
+	c := new(Command)
+	switch strings.ToLower(dir) {
+	case "from":
+		if target[0] == '(' {
+			return nil, fmt.Errorf("cannot copy from a file to a query")
+		}
+		c.From = true
+	case "to":
+	case "":
+		return nil, fmt.Errorf("missing FROM or TO")
+	default:
+		return nil, fmt.Errorf("expected FROM or TO, got: %s", dir)
+	}
+	if c.File, s, err = file(s, c.From); err != nil {
+		return nil, err
+	}
+	c.Statement = "COPY " + target + " TO STDOUT"
+	if c.From {
+		c.Statement = "COPY " + target + " FROM STDIN"
+	}
+	if s = strings.TrimSpace(s); s != "" {
+		c.Statement += " " + s
+	}
+	return c, nil
+}
+
+// Match returns true when s is a \copy command of psql, and not a copy of
+// the results of a query between databases.
+func Match(s string) bool {
+	_, s, err := table(s)
+	if err != nil {
+		return false
+	}
+	dir, _ := word(s)
+	return strings.EqualFold(dir, "from") || strings.EqualFold(dir, "to")
+}
+
+// table returns the table and its columns, or the query, at the start of s,
+// and the remainder of s.
+func table(s string) (string, string, error) {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "":
+		return "", "", fmt.Errorf("missing table")
+	case s[0] == '(':
+		i, err := closing(s)
+		if err != nil {
+			return "", "", err
+		}
+		return s[:i+1], s[i+1:], nil
+	}
+	i := identEnd(s)
+	target, s := s[:i], strings.TrimSpace(s[i:])
+	if strings.HasPrefix(s, "(") {
+		i, err := closing(s)
+		if err != nil {
+			return "", "", err
+		}
+		target, s = target+" "+s[:i+1], s[i+1:]
+	}
+	return target, s, nil
+}
+
+// file returns the file name at the start of s, and the remainder of s.
+func file(s string, from bool) (string, string, error) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if strings.HasPrefix(s, "'") {
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			if s[i] != '\'' {
+				b.WriteByte(s[i])
+				continue
+			}
+			if i+1 < len(s) && s[i+1] == '\'' {
+				b.WriteByte('\'')
+				i++
+				continue
+			}
+			return b.String(), s[i+1:], nil
+		}
+		return "", "", fmt.Errorf("unterminated quoted file name")
+	}
+	name, rest := word(s)
+	switch strings.ToLower(name) {
+	case "":
+		return "", "", fmt.Errorf("missing file name")
+	case "stdin", "pstdin":
+		if !from {
+			return "", "", fmt.Errorf("cannot copy to %s", name)
+		}
+		return "", rest, nil
+	case "stdout", "pstdout":
+		if from {
+			return "", "", fmt.Errorf("cannot copy from %s", name)
+		}
+		return "", rest, nil
+	case "program":
+		return "", "", fmt.Errorf("PROGRAM is not supported")
+	}
+	return name, rest, nil
+}
+
+// closing returns the index of the parenthesis closing the one s starts with,
+// skipping quoted strings and identifiers.
+func closing(s string) (int, error) {
+	var depth int
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			if depth--; depth == 0 {
+				return i, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unterminated parenthesis")
+}
+
+// identEnd returns the end of the (possibly qualified and quoted) table name
+// at the start of s.
+func identEnd(s string) int {
+	var quoted bool
+	for i, c := range s {
+		switch {
+		case c == '"':
+			quoted = !quoted
+		case !quoted && (c == '(' || unicode.IsSpace(c)):
+			return i
+		}
+	}
+	return len(s)
+}
+
+// word returns the word at the start of s, and the remainder of s.
+func word(s string) (string, string) {
+	s = strings.TrimLeftFunc(s, unicode.IsSpace)
+	if i := strings.IndexFunc(s, unicode.IsSpace); i != -1 {
+		return s[:i], s[i:]
+	}
+	return s, ""
+}
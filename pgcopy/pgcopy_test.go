@@ -0,0 +1,76 @@
+package pgcopy
+
+import (
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		s    string
+		exp  Command
+		fail bool
+	}{
+		{
+			`users FROM 'users.csv' CSV`,
+			Command{Statement: `COPY users FROM STDIN CSV`, From: true, File: "users.csv"},
+			false,
+		},
+		{
+			`public.users (id, name) from '/tmp/it''s.csv' WITH (FORMAT csv, HEADER)`,
+			Command{Statement: `COPY public.users (id, name) FROM STDIN WITH (FORMAT csv, HEADER)`, From: true, File: "/tmp/it's.csv"},
+			false,
+		},
+		{
+			`"My Table"(a) FROM stdin`,
+			Command{Statement: `COPY "My Table" (a) FROM STDIN`, From: true},
+			false,
+		},
+		{
+			`users TO users.tsv;`,
+			Command{Statement: `COPY users TO STDOUT`, File: "users.tsv"},
+			false,
+		},
+		{
+			`(SELECT * FROM users WHERE name = ')') TO STDOUT CSV HEADER`,
+			Command{Statement: `COPY (SELECT * FROM users WHERE name = ')') TO STDOUT CSV HEADER`},
+			false,
+		},
+		{`(SELECT 1) FROM 'a.csv'`, Command{}, true},
+		{`users FROM STDOUT`, Command{}, true},
+		{`users TO PROGRAM 'gzip'`, Command{}, true},
+		{`users INTO 'a.csv'`, Command{}, true},
+		{`users FROM 'a.csv`, Command{}, true},
+		{`(SELECT 1 TO 'a.csv'`, Command{}, true},
+		{``, Command{}, true},
+	}
+	for i, test := range tests {
+		c, err := Parse(test.s)
+		switch {
+		case test.fail && err == nil:
+			t.Errorf("test %d expected an error, got: %+v", i, c)
+		case !test.fail && err != nil:
+			t.Errorf("test %d expected no error, got: %v", i, err)
+		case !test.fail && *c != test.exp:
+			t.Errorf("test %d expected %+v, got: %+v", i, test.exp, *c)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp bool
+	}{
+		{`users FROM 'users.csv' CSV`, true},
+		{`(SELECT 1) to stdout`, true},
+		{`users(a, b) FROM stdin`, true},
+		{`pg://localhost/src pg://localhost/dst 'SELECT * FROM users' users`, false},
+		{`sq:a.db pg://localhost/dst "SELECT 1 FROM t" t(a)`, false},
+		{``, false},
+	}
+	for i, test := range tests {
+		if ok := Match(test.s); ok != test.exp {
+			t.Errorf("test %d expected %t, got: %t", i, test.exp, ok)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/rline"
+)
+
+// pickerSize is the maximum number of aliases listed by the picker.
+const pickerSize = 20
+
+// selectAlias returns the database alias of the config file of args matching
+// the pattern (see config.IsPattern), or all the aliases when empty. When
+// several aliases match and usql runs in a terminal, the alias is picked
+// interactively.
+func selectAlias(args *Args, pattern string) (string, error) {
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return "", err
+	}
+	aliases := cfg.Aliases()
+	if pattern != "" {
+		aliases = cfg.Match(pattern)
+	}
+	tty := isatty.IsTerminal(os.Stdin.Fd()) && isatty.IsTerminal(os.Stdout.Fd())
+	switch {
+	case len(aliases) == 0 && pattern == "":
+		return "", fmt.Errorf("no database aliases in config file %s", cfg.Path)
+	case len(aliases) == 0:
+		return "", fmt.Errorf("no database alias matches %s", pattern)
+	case len(aliases) == 1:
+		return aliases[0], nil
+	case !tty && pattern == "":
+		return "", fmt.Errorf("a database alias is required, one of: %s", strings.Join(aliases, ", "))
+	case !tty:
+		return "", fmt.Errorf("%d database aliases match %s: %s", len(aliases), pattern, strings.Join(aliases, ", "))
+	}
+	l, err := rline.New(false, "", "")
+	if err != nil {
+		return "", err
+	}
+	defer l.Close()
+	return pickAlias(l, cfg, aliases)
+}
+
+// pickAlias lets the user pick one of the aliases, by its number, filtering
+// them with a fuzzy search of the entered text.
+func pickAlias(l rline.IO, cfg *config.Config, aliases []string) (string, error) {
+	out, shown := l.Stdout(), aliases
+	for {
+		for i, alias := range shown {
+			if i == pickerSize {
+				fmt.Fprintf(out, "  ... %d more, enter text to filter\n", len(shown)-pickerSize)
+				break
+			}
+			fmt.Fprintf(out, "%3d) %s%s\n", i+1, alias, describeAlias(cfg.Databases[alias]))
+		}
+		l.Prompt("Database (number, or text to filter): ")
+		line, err := l.Next()
+		switch {
+		case errors.Is(err, io.EOF):
+			return "", errors.New("no database selected")
+		case err != nil:
+			return "", err
+		}
+		s := strings.TrimSpace(string(line))
+		if i, err := strconv.Atoi(s); err == nil && 0 < i && i <= len(shown) && i <= pickerSize {
+			return shown[i-1], nil
+		}
+		if s == "" {
+			if len(shown) == 1 {
+				return shown[0], nil
+			}
+			shown = aliases
+			continue
+		}
+		matches := fuzzyFilter(aliases, s)
+		switch len(matches) {
+		case 0:
+			fmt.Fprintf(out, "no database alias matches %s\n", s)
+		case 1:
+			return matches[0], nil
+		default:
+			shown = matches
+		}
+	}
+}
+
+// describeAlias returns the description of a database in the picker.
+func describeAlias(db *config.DatabaseConfig) string {
+	if db == nil || db.DbType == "" {
+		return ""
+	}
+	desc := db.DbType + " " + db.Name
+	if db.Host != "" {
+		desc = db.DbType + " " + db.Host + "/" + db.Name
+	}
+	if db.Branch != "" {
+		desc += ", branch " + db.Branch
+	}
+	return "  (" + desc + ")"
+}
+
+// fuzzyFilter returns the aliases matching the query, whose characters must
+// appear in order, sorted by their score.
+func fuzzyFilter(aliases []string, query string) []string {
+	type match struct {
+		alias string
+		score int
+	}
+	var matches []match
+	for _, alias := range aliases {
+		if score, ok := fuzzyScore(alias, query); ok {
+			matches = append(matches, match{alias, score})
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].score > matches[j].score
+	})
+	v := make([]string, len(matches))
+	for i, m := range matches {
+		v[i] = m.alias
+	}
+	return v
+}
+
+// fuzzyScore returns the score of s for the query, when the characters of
+// the query appear in order in s, case-insensitively. Consecutive characters,
+// and characters at the start of s or of its words, score higher.
+func fuzzyScore(s, query string) (int, bool) {
+	r, q := []rune(strings.ToLower(s)), []rune(strings.ToLower(query))
+	score, j, prev := 0, 0, -2
+	for i := 0; i < len(r) && j < len(q); i++ {
+		if r[i] != q[j] {
+			continue
+		}
+		score++
+		switch {
+		case i == prev+1:
+			score += 2
+		case i == 0 || !unicode.IsLetter(r[i-1]) && !unicode.IsDigit(r[i-1]):
+			score += 3
+		}
+		prev, j = i, j+1
+	}
+	return score, j == len(q)
+}
@@ -0,0 +1,23 @@
+package config
+
+import "github.com/xo/usql/text"
+
+// ApplicationName returns the application name of the connections to the
+// database alias: its application_name, or usql/VERSION ALIAS.
+func (c *Config) ApplicationName(alias string) string {
+	if db := c.Databases[alias]; db != nil && db.ApplicationName != "" {
+		return db.ApplicationName
+	}
+	return DefaultApplicationName(alias)
+}
+
+// DefaultApplicationName returns the default application name of the
+// connections to the database alias, usql/VERSION ALIAS, or usql/VERSION for
+// ad-hoc connections and connections without alias.
+func DefaultApplicationName(alias string) string {
+	name := text.CommandName + "/" + text.CommandVersion
+	if alias != "" && !IsDSN(alias) {
+		name += " " + alias
+	}
+	return name
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// AthenaConfig is the config of the Athena options of an athena database,
+// whose name is the Glue database of the queries.
+type AthenaConfig struct {
+	// Region is the AWS region of the database. It defaults to
+	// $AWS_REGION.
+	Region string `yaml:"region,omitempty"`
+	// Workgroup is the workgroup of the queries. It defaults to the primary
+	// workgroup.
+	Workgroup string `yaml:"workgroup,omitempty"`
+	// OutputLocation is the s3://BUCKET/PATH location of the query results.
+	OutputLocation string `yaml:"output_location,omitempty"`
+}
+
+// athenaDSN returns the DSN of the athena database db with the credentials of
+// the role: awsathena://BUCKET/PATH?region=REGION&db=NAME with the output
+// location as host and path. The username and password of the role are the
+// access key ID and the secret access key of IAM auth, the default AWS
+// credentials (environment, shared credentials or instance role) being used
+// otherwise.
+func (c *Config) athenaDSN(db *DatabaseConfig, rc RoleConfig, password string) (string, error) {
+	at := db.Athena
+	if at == nil {
+		at = &AthenaConfig{}
+	}
+	region := firstNonEmpty(at.Region, os.Getenv("AWS_REGION"))
+	if region == "" {
+		return "", fmt.Errorf("the region of the athena database %s is required", db.Name)
+	}
+	if at.OutputLocation == "" {
+		return "", fmt.Errorf("the output_location of the athena database %s is required", db.Name)
+	}
+	out, err := url.Parse(at.OutputLocation)
+	if err != nil || out.Scheme != "s3" || out.Host == "" {
+		return "", fmt.Errorf("invalid athena output_location %q: expected s3://BUCKET/PATH", at.OutputLocation)
+	}
+	q := url.Values{}
+	q.Set("region", region)
+	if db.Name != "" {
+		q.Set("db", db.Name)
+	}
+	if at.Workgroup != "" {
+		q.Set("workgroupName", at.Workgroup)
+	}
+	if rc.Username != "" {
+		q.Set("accessID", rc.Username)
+		q.Set("secretAccessKey", password)
+	}
+	u := &url.URL{
+		Scheme:   "awsathena",
+		Host:     out.Host,
+		Path:     "/" + strings.TrimPrefix(out.Path, "/"),
+		RawQuery: q.Encode(),
+	}
+	return u.String(), nil
+}
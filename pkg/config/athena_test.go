@@ -0,0 +1,62 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestAthenaDSN(t *testing.T) {
+	t.Setenv("AWS_REGION", "")
+	c, err := Parse("/tmp/.dbconfig.yaml", []byte(`
+databases:
+  athena:
+    name: sales
+    db_type: athena
+    athena:
+      region: us-east-1
+      workgroup: analysts
+      output_location: s3://query-results/usql/
+    credentials:
+      - role: default
+      - username: AKIAEXAMPLE
+        role: iam
+        password: wJalr/XUtnFEMI
+  no_region:
+    name: sales
+    db_type: athena
+    athena:
+      output_location: s3://query-results
+  bad_output:
+    name: sales
+    db_type: athena
+    athena:
+      region: us-east-1
+      output_location: query-results
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		role, exp string
+	}{
+		{"default", "awsathena://query-results/usql/?db=sales&region=us-east-1&workgroupName=analysts"},
+		{"iam", "awsathena://query-results/usql/?accessID=AKIAEXAMPLE&db=sales&region=us-east-1&secretAccessKey=wJalr%2FXUtnFEMI&workgroupName=analysts"},
+	}
+	for i, test := range tests {
+		dsn, err := c.DSN("athena", test.role)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if dsn != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, dsn)
+		}
+	}
+	for _, alias := range []string{"no_region", "bad_output"} {
+		if _, err := c.DSN(alias, ""); err == nil {
+			t.Errorf("expected error for %s, got nil", alias)
+		}
+	}
+	t.Setenv("AWS_REGION", "eu-west-1")
+	if dsn, err := c.DSN("no_region", ""); err != nil || dsn != "awsathena://query-results/?db=sales&region=eu-west-1" {
+		t.Errorf("expected region of AWS_REGION, got: %q, %v", dsn, err)
+	}
+}
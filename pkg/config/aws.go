@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+// awsRegion returns the AWS region of a database: region, when set, or else
+// the region of the endpoint host of the service, such as us-east-1 of
+// cluster.abc123.us-east-1.redshift.amazonaws.com, or $AWS_REGION.
+func awsRegion(region, host, service string) string {
+	if region != "" {
+		return region
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	labels := strings.Split(host, ".")
+	for i := 1; i < len(labels)-1; i++ {
+		if labels[i+1] == service && strings.HasSuffix(host, ".amazonaws.com") {
+			return labels[i]
+		}
+	}
+	return os.Getenv("AWS_REGION")
+}
+
+// awsSession returns a session of the default AWS credentials (environment,
+// shared credentials file or instance role) in the region.
+func awsSession(region string) (*session.Session, error) {
+	return session.NewSessionWithOptions(session.Options{
+		Config:            aws.Config{Region: aws.String(region)},
+		SharedConfigState: session.SharedConfigEnable,
+	})
+}
+
+// hostPort returns host with the port, or else the default port def, when it
+// has none.
+func hostPort(host string, port, def int) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	if port == 0 {
+		port = def
+	}
+	return net.JoinHostPort(host, strconv.Itoa(port))
+}
+
+// sslMode returns the sslmode of a database, defaulting to require.
+func sslMode(mode string) (string, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		return "require", nil
+	case "disable", "require", "verify-ca", "verify-full":
+		return strings.ToLower(mode), nil
+	}
+	return "", fmt.Errorf("invalid sslmode %q: expected disable, require, verify-ca or verify-full", mode)
+}
@@ -0,0 +1,42 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestAWSRegion(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	tests := []struct {
+		region, host, service, exp string
+	}{
+		{"us-west-2", "c.abc123.us-east-1.redshift.amazonaws.com", "redshift", "us-west-2"},
+		{"", "c.abc123.us-east-1.redshift.amazonaws.com", "redshift", "us-east-1"},
+		{"", "c.abc123.us-east-1.redshift.amazonaws.com:5439", "redshift", "us-east-1"},
+		{"", "app.cluster-abc123.ap-south-1.rds.amazonaws.com", "rds", "ap-south-1"},
+		{"", "app.cluster-abc123.ap-south-1.rds.amazonaws.com", "redshift", "eu-west-1"},
+		{"", "db.example.com", "rds", "eu-west-1"},
+	}
+	for i, test := range tests {
+		if region := awsRegion(test.region, test.host, test.service); region != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, region)
+		}
+	}
+}
+
+func TestHostPort(t *testing.T) {
+	tests := []struct {
+		host      string
+		port, def int
+		exp       string
+	}{
+		{"db.example.com", 0, 5439, "db.example.com:5439"},
+		{"db.example.com", 6000, 5439, "db.example.com:6000"},
+		{"db.example.com:7000", 6000, 5439, "db.example.com:7000"},
+		{"::1", 0, 5432, "[::1]:5432"},
+	}
+	for i, test := range tests {
+		if host := hostPort(test.host, test.port, test.def); host != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, host)
+		}
+	}
+}
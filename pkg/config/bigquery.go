@@ -0,0 +1,48 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// BigQueryConfig is the config of the BigQuery options of a bigquery
+// database, whose name is the project.
+type BigQueryConfig struct {
+	// Dataset is the default dataset of the queries.
+	Dataset string `yaml:"dataset,omitempty"`
+	// Location is the location of the dataset, such as US or europe-west1.
+	Location string `yaml:"location,omitempty"`
+}
+
+// bigQueryDSN returns the DSN of the bigquery database db with the
+// credentials of the role rc: bigquery://PROJECT/LOCATION/DATASET, with the
+// path of the service account key file of the role as the credentials_file
+// parameter, or the application default credentials when it has none.
+func (c *Config) bigQueryDSN(db *DatabaseConfig, rc RoleConfig, _ string) (string, error) {
+	bq := db.BigQuery
+	if bq == nil || bq.Dataset == "" {
+		return "", fmt.Errorf("the dataset of the bigquery database %s is required", db.Name)
+	}
+	if db.Name == "" {
+		return "", fmt.Errorf("the project of the bigquery database is required as its name")
+	}
+	path := "/" + bq.Dataset
+	if bq.Location != "" {
+		path = "/" + bq.Location + path
+	}
+	q := url.Values{}
+	if rc.KeyFile != "" {
+		keyFile, err := c.resolvePath(rc.KeyFile)
+		if err != nil {
+			return "", err
+		}
+		q.Set("credentials_file", keyFile)
+	}
+	u := &url.URL{
+		Scheme:   "bigquery",
+		Host:     db.Name,
+		Path:     path,
+		RawQuery: q.Encode(),
+	}
+	return u.String(), nil
+}
@@ -0,0 +1,45 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestBigQueryDSN(t *testing.T) {
+	c, err := Parse("/srv/app/.dbconfig.yaml", []byte(`
+databases:
+  bq:
+    name: my-project
+    db_type: bigquery
+    bigquery:
+      dataset: analytics
+      location: EU
+    credentials:
+      - role: adc
+      - role: etl
+        key_file: keys/etl.json
+  no_dataset:
+    name: my-project
+    db_type: bigquery
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		role, exp string
+	}{
+		{"adc", "bigquery://my-project/EU/analytics"},
+		{"etl", "bigquery://my-project/EU/analytics?credentials_file=%2Fsrv%2Fapp%2Fkeys%2Fetl.json"},
+	}
+	for i, test := range tests {
+		dsn, err := c.DSN("bq", test.role)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if dsn != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, dsn)
+		}
+	}
+	if _, err := c.DSN("no_dataset", ""); err == nil {
+		t.Errorf("expected error for missing dataset, got nil")
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// BranchEnv is the environment variable overriding the git branch of the
+// working directory, such as in CI systems checking out a detached HEAD.
+const BranchEnv = "USQL_BRANCH"
+
+// BranchConfig is the config of a database alias on the git branches matching
+// a pattern, overriding the fields of the database it sets. The {branch} of
+// its fields is replaced by the branch, lower cased, with its characters other
+// than letters and digits replaced by dashes, as in host names, and the
+// {branch_ident} by the same with underscores, as in database names.
+type BranchConfig struct {
+	// Branch is the pattern of the branches, with * wildcards matching any
+	// characters (ie, feature/*).
+	Branch     string `yaml:"branch,omitempty"`
+	Host       string `yaml:"host,omitempty"`
+	ReaderHost string `yaml:"reader_host,omitempty"`
+	Port       int    `yaml:"port,omitempty"`
+	Name       string `yaml:"name,omitempty"`
+	Schema     string `yaml:"schema,omitempty"`
+}
+
+// Match returns true when the branch matches the pattern of the config.
+func (bc *BranchConfig) Match(branch string) bool {
+	re := regexp.MustCompile("^" + strings.ReplaceAll(regexp.QuoteMeta(bc.Branch), `\*`, ".*") + "$")
+	return re.MatchString(branch)
+}
+
+// branchRE matches the characters replaced in the {branch} and
+// {branch_ident} tokens.
+var branchRE = regexp.MustCompile(`[^a-z0-9]+`)
+
+// apply applies the config to the database, for the branch.
+func (bc *BranchConfig) apply(db *DatabaseConfig, branch string) {
+	slug := strings.Trim(branchRE.ReplaceAllString(strings.ToLower(branch), "-"), "-")
+	r := strings.NewReplacer("{branch}", slug, "{branch_ident}", strings.ReplaceAll(slug, "-", "_"))
+	if bc.Host != "" {
+		db.Host = r.Replace(bc.Host)
+	}
+	if bc.ReaderHost != "" {
+		db.ReaderHost = r.Replace(bc.ReaderHost)
+	}
+	if bc.Port != 0 {
+		db.Port = bc.Port
+	}
+	if bc.Name != "" {
+		db.Name = r.Replace(bc.Name)
+	}
+	if bc.Schema != "" {
+		db.Schema = r.Replace(bc.Schema)
+	}
+	db.Branch = branch
+}
+
+// applyBranches applies the first entry of the branches of the databases
+// matching the git branch of the working directory (see GitBranch). The git
+// branch is only read when a database has branches.
+func (c *Config) applyBranches() error {
+	var found bool
+	for alias, db := range c.Databases {
+		if db == nil {
+			continue
+		}
+		for i, bc := range db.Branches {
+			if bc == nil || bc.Branch == "" {
+				return fmt.Errorf("database %s: branches %d: a branch pattern is required", alias, i)
+			}
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	branch, err := GitBranch()
+	if err != nil || branch == "" {
+		return err
+	}
+	for _, db := range c.Databases {
+		if db == nil {
+			continue
+		}
+		for _, bc := range db.Branches {
+			if bc.Match(branch) {
+				bc.apply(db, branch)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// GitBranch returns the git branch of the working directory, or the
+// USQL_BRANCH environment variable when set (see BranchEnv). It returns an
+// empty string outside of git repositories and on a detached HEAD.
+func GitBranch() (string, error) {
+	if branch, ok := os.LookupEnv(BranchEnv); ok {
+		return branch, nil
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		head, err := gitHead(filepath.Join(dir, ".git"))
+		switch {
+		case err == nil:
+			branch, ok := strings.CutPrefix(strings.TrimSpace(head), "ref: refs/heads/")
+			if !ok {
+				return "", nil
+			}
+			return branch, nil
+		case !os.IsNotExist(err):
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// gitHead returns the contents of the HEAD file of the .git directory at
+// path, or of the directory it points to when a file, as in worktrees and
+// submodules.
+func gitHead(path string) (string, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return "", err
+	}
+	if !fi.IsDir() {
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		gitdir, ok := strings.CutPrefix(strings.TrimSpace(string(buf)), "gitdir: ")
+		if !ok {
+			return "", fmt.Errorf("invalid .git file %s", path)
+		}
+		if !filepath.IsAbs(gitdir) {
+			gitdir = filepath.Join(filepath.Dir(path), gitdir)
+		}
+		path = gitdir
+	}
+	buf, err := os.ReadFile(filepath.Join(path, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	return string(buf), nil
+}
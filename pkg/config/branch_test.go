@@ -0,0 +1,118 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBranches(t *testing.T) {
+	const buf = `
+databases:
+  app:
+    name: app
+    host: localhost
+    db_type: postgres
+    branches:
+      - branch: main
+        host: prod.example.com
+      - branch: feature/*
+        host: preview-{branch}.example.com
+        name: app_{branch_ident}
+  other:
+    name: other
+    host: localhost
+    db_type: postgres
+`
+	tests := []struct {
+		branch string
+		exp    string
+	}{
+		{"main", "postgres://:@prod.example.com/app"},
+		{"feature/Add-Login", "postgres://:@preview-feature-add-login.example.com/app_feature_add_login"},
+		{"release/1.0", "postgres://:@localhost/app"},
+		{"", "postgres://:@localhost/app"},
+	}
+	for _, test := range tests {
+		t.Setenv(BranchEnv, test.branch)
+		c, err := Parse("/tmp/.dbconfig.yaml", []byte(buf))
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		dsn, err := c.DSN("app", "")
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if dsn != test.exp {
+			t.Errorf("branch %q expected %q, got: %q", test.branch, test.exp, dsn)
+		}
+		if dsn, _ := c.DSN("other", ""); dsn != "postgres://:@localhost/other" {
+			t.Errorf("branch %q expected other to be unchanged, got: %q", test.branch, dsn)
+		}
+	}
+	if _, err := Parse("/tmp/.dbconfig.yaml", []byte("databases:\n  x:\n    branches:\n      - host: h\n")); err == nil || !strings.Contains(err.Error(), "a branch pattern is required") {
+		t.Errorf("expected missing pattern error, got: %v", err)
+	}
+}
+
+func TestGitBranch(t *testing.T) {
+	t.Setenv(BranchEnv, "")
+	os.Unsetenv(BranchEnv)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "repo", ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "repo", ".git", "HEAD"), []byte("ref: refs/heads/feature/x\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// a worktree
+	if err := os.MkdirAll(filepath.Join(dir, "repo", ".git", "worktrees", "wt"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "repo", ".git", "worktrees", "wt", "HEAD"), []byte("ref: refs/heads/fix/y\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "wt", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wt", ".git"), []byte("gitdir: ../repo/.git/worktrees/wt\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "repo", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	// a detached HEAD
+	if err := os.MkdirAll(filepath.Join(dir, "detached", ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "detached", ".git", "HEAD"), []byte("0123456789abcdef0123456789abcdef01234567\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	tests := []struct {
+		dir string
+		exp string
+	}{
+		{filepath.Join(dir, "repo", "sub"), "feature/x"},
+		{filepath.Join(dir, "wt", "sub"), "fix/y"},
+		{filepath.Join(dir, "detached"), ""},
+		{dir, ""},
+	}
+	for _, test := range tests {
+		if err := os.Chdir(test.dir); err != nil {
+			t.Fatal(err)
+		}
+		branch, err := GitBranch()
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if branch != test.exp {
+			t.Errorf("%s expected %q, got: %q", test.dir, test.exp, branch)
+		}
+	}
+}
@@ -0,0 +1,30 @@
+package config
+
+import "strings"
+
+// DefaultQueryCommentFormat is the default query_comment_format of the
+// config file.
+const DefaultQueryCommentFormat = "usql user={user} alias={alias} role={role}"
+
+// QueryComment returns the comment prepended to the statements executed on
+// the database alias by the OS user with the role, attributing them in the
+// slow query logs of the database, or an empty string when the query_comment
+// of the alias isn't set. The {user}, {alias} and {role} of the
+// query_comment_format of the config file are replaced by their values.
+func (c *Config) QueryComment(alias, role, osUser string) string {
+	db := c.Databases[alias]
+	if db == nil || !db.QueryComment {
+		return ""
+	}
+	format := c.QueryCommentFormat
+	if format == "" {
+		format = DefaultQueryCommentFormat
+	}
+	s := strings.NewReplacer(
+		"{user}", osUser,
+		"{alias}", alias,
+		"{role}", role,
+	).Replace(format)
+	// never end the comment early
+	return "/* " + strings.ReplaceAll(s, "*/", "* /") + " */"
+}
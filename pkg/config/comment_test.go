@@ -0,0 +1,23 @@
+package config
+
+import "testing"
+
+func TestQueryComment(t *testing.T) {
+	c := &Config{Databases: map[string]*DatabaseConfig{
+		"prod": {DbType: "postgres", QueryComment: true},
+		"dev":  {DbType: "postgres"},
+	}}
+	if s, exp := c.QueryComment("prod", "reader", "jdoe"), "/* usql user=jdoe alias=prod role=reader */"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	if s := c.QueryComment("dev", "reader", "jdoe"); s != "" {
+		t.Errorf("expected no comment, got: %q", s)
+	}
+	if s := c.QueryComment("missing", "", "jdoe"); s != "" {
+		t.Errorf("expected no comment, got: %q", s)
+	}
+	c.QueryCommentFormat = "app=usql owner={user}*/ drop"
+	if s, exp := c.QueryComment("prod", "", "jdoe"), "/* app=usql owner=jdoe* / drop */"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+}
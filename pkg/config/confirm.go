@@ -0,0 +1,34 @@
+package config
+
+import "strings"
+
+// ConfirmRules returns the confirmation rules of the database alias for the
+// role: the confirm rules of the config file, overridden by the confirm_tags
+// rules of the tags of the database, in their order, and by the confirm rules
+// of the role, or of the default role when empty (see ChooseRole).
+func (c *Config) ConfirmRules(alias, role string) (map[string]string, error) {
+	db, err := c.Database(alias)
+	if err != nil {
+		return nil, err
+	}
+	rules := make(map[string]string)
+	for kind, value := range c.Confirm {
+		rules[kind] = value
+	}
+	for _, tag := range db.Tags {
+		for t, tagRules := range c.ConfirmTags {
+			if !strings.EqualFold(t, tag) {
+				continue
+			}
+			for kind, value := range tagRules {
+				rules[kind] = value
+			}
+		}
+	}
+	if rc, err := db.Role(role); err == nil {
+		for kind, value := range rc.Confirm {
+			rules[kind] = value
+		}
+	}
+	return rules, nil
+}
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConfirmRules(t *testing.T) {
+	c, err := Parse("/tmp/.dbconfig.yaml", []byte(`
+confirm:
+  drop: always
+  truncate: prod-only
+confirm_tags:
+  dev:
+    drop: never
+databases:
+  prod_db:
+    name: app
+    db_type: postgres
+    tags: [prod]
+    credentials:
+      - role: admin
+        confirm:
+          delete_without_where: always
+      - role: ci
+        confirm:
+          drop: never
+          truncate: never
+  dev_db:
+    name: app
+    db_type: postgres
+    tags: [dev]
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		alias, role string
+		exp         map[string]string
+	}{
+		{"prod_db", "", map[string]string{"drop": "always", "truncate": "prod-only", "delete_without_where": "always"}},
+		{"prod_db", "ci", map[string]string{"drop": "never", "truncate": "never"}},
+		{"dev_db", "", map[string]string{"drop": "never", "truncate": "prod-only"}},
+	}
+	for i, test := range tests {
+		rules, err := c.ConfirmRules(test.alias, test.role)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if !reflect.DeepEqual(rules, test.exp) {
+			t.Errorf("test %d expected %v, got: %v", i, test.exp, rules)
+		}
+	}
+	if _, err := c.ConfirmRules("missing_db", ""); err == nil {
+		t.Errorf("expected error for unknown alias, got nil")
+	}
+}
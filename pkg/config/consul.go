@@ -0,0 +1,160 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xo/usql/logging"
+)
+
+// ConsulPrefix is the prefix of the hosts resolved with Consul, such as
+// consul://postgres-primary.service.dc1.
+const ConsulPrefix = "consul://"
+
+// defaultConsulAddr is the address of the Consul agent when neither the
+// discovery address nor $CONSUL_HTTP_ADDR are set.
+const defaultConsulAddr = "127.0.0.1:8500"
+
+// ServiceDiscoveryConfig is the Consul service of a database, resolved to its
+// host every time the database is connected to.
+type ServiceDiscoveryConfig struct {
+	// Service is the name of the Consul service.
+	Service string `yaml:"service,omitempty"`
+	// Tag is the tag of the service instances, such as primary, when set.
+	Tag string `yaml:"tag,omitempty"`
+	// Datacenter is the datacenter of the service, defaulting to the
+	// datacenter of the agent.
+	Datacenter string `yaml:"datacenter,omitempty"`
+	// Address is the address of the Consul agent, defaulting to
+	// $CONSUL_HTTP_ADDR or 127.0.0.1:8500.
+	Address string `yaml:"address,omitempty"`
+	// Token is the ACL token of the Consul requests, defaulting to
+	// $CONSUL_HTTP_TOKEN.
+	Token string `yaml:"token,omitempty"`
+}
+
+// IsConsul returns true when the host is resolved with Consul.
+func IsConsul(host string) bool {
+	return strings.HasPrefix(host, ConsulPrefix)
+}
+
+// parseConsulHost parses a consul://[TAG.]SERVICE.service[.DATACENTER] host,
+// as the Consul DNS names.
+func parseConsulHost(host string) (ServiceDiscoveryConfig, error) {
+	name := strings.TrimSuffix(strings.TrimPrefix(host, ConsulPrefix), ".consul")
+	i := strings.LastIndex(name+".", ".service.")
+	if i <= 0 {
+		return ServiceDiscoveryConfig{}, fmt.Errorf("invalid host %q: expected %s[TAG.]SERVICE.service[.DATACENTER]", host, ConsulPrefix)
+	}
+	sd := ServiceDiscoveryConfig{Service: name[:i]}
+	if i+len(".service.") <= len(name) {
+		sd.Datacenter = name[i+len(".service."):]
+	}
+	if tag, service, ok := strings.Cut(sd.Service, "."); ok {
+		sd.Tag, sd.Service = tag, service
+	}
+	return sd, nil
+}
+
+// resolveHosts returns a copy of the database with its consul:// host, or
+// reader host when reader is set, resolved to the address and port of a
+// healthy instance of their service. The host of databases without host is
+// resolved from the service of their discovery.
+func (dc *DatabaseConfig) resolveHosts(ctx context.Context, reader bool) (*DatabaseConfig, error) {
+	db := *dc
+	host := &db.Host
+	if reader && db.ReaderHost != "" {
+		host = &db.ReaderHost
+	}
+	var agent ServiceDiscoveryConfig
+	if dc.Discovery != nil {
+		agent = *dc.Discovery
+	}
+	var err error
+	switch {
+	case IsConsul(*host):
+		var sd ServiceDiscoveryConfig
+		if sd, err = parseConsulHost(*host); err != nil {
+			return nil, err
+		}
+		sd.Address, sd.Token = agent.Address, agent.Token
+		*host, err = sd.resolve(ctx)
+	case db.Host == "" && dc.Discovery != nil:
+		db.Host, err = agent.resolve(ctx)
+	default:
+		return dc, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &db, nil
+}
+
+// consulEntry is an entry of the Consul health API.
+type consulEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		Address string
+		Port    int
+	}
+}
+
+// resolve returns the HOST:PORT address of the nearest healthy instance of
+// the service.
+func (sd ServiceDiscoveryConfig) resolve(ctx context.Context) (string, error) {
+	if sd.Service == "" {
+		return "", fmt.Errorf("discovery: no service")
+	}
+	addr := firstNonEmpty(sd.Address, os.Getenv("CONSUL_HTTP_ADDR"), defaultConsulAddr)
+	if !strings.Contains(addr, "://") {
+		addr = "http://" + addr
+	}
+	q := url.Values{"passing": {"1"}, "near": {"_agent"}}
+	if sd.Tag != "" {
+		q.Set("tag", sd.Tag)
+	}
+	if sd.Datacenter != "" {
+		q.Set("dc", sd.Datacenter)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(addr, "/")+"/v1/health/service/"+url.PathEscape(sd.Service)+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if token := firstNonEmpty(sd.Token, os.Getenv("CONSUL_HTTP_TOKEN")); token != "" {
+		req.Header.Set("X-Consul-Token", token)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("consul service %s: %w", sd.Service, err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("consul service %s: %s", sd.Service, res.Status)
+	}
+	var entries []consulEntry
+	if err := json.NewDecoder(res.Body).Decode(&entries); err != nil {
+		return "", fmt.Errorf("consul service %s: %w", sd.Service, err)
+	}
+	if len(entries) == 0 {
+		return "", fmt.Errorf("consul service %s: no healthy instances", sd.Service)
+	}
+	e := entries[0]
+	host := firstNonEmpty(e.Service.Address, e.Node.Address)
+	if e.Service.Port != 0 {
+		host = net.JoinHostPort(host, strconv.Itoa(e.Service.Port))
+	}
+	logging.Verbosef("consul service %s: %s", sd.Service, host)
+	return host, nil
+}
@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseConsulHost(t *testing.T) {
+	tests := []struct {
+		host string
+		exp  ServiceDiscoveryConfig
+	}{
+		{"consul://postgres.service", ServiceDiscoveryConfig{Service: "postgres"}},
+		{"consul://postgres-primary.service.dc1", ServiceDiscoveryConfig{Service: "postgres-primary", Datacenter: "dc1"}},
+		{"consul://replica.postgres.service.dc2.consul", ServiceDiscoveryConfig{Service: "postgres", Tag: "replica", Datacenter: "dc2"}},
+	}
+	for i, test := range tests {
+		sd, err := parseConsulHost(test.host)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if sd != test.exp {
+			t.Errorf("test %d expected %+v, got: %+v", i, test.exp, sd)
+		}
+	}
+	for _, host := range []string{"consul://postgres", "consul://.service.dc1"} {
+		if _, err := parseConsulHost(host); err == nil {
+			t.Errorf("host %q expected error", host)
+		}
+	}
+}
+
+func TestConsulDSN(t *testing.T) {
+	var n int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n++
+		if r.Header.Get("X-Consul-Token") != "tok" || r.URL.Query().Get("passing") != "1" {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path + "?" + r.URL.Query().Get("tag") + "@" + r.URL.Query().Get("dc") {
+		case "/v1/health/service/postgres-primary?@dc1":
+			fmt.Fprintf(w, `[{"Node":{"Address":"10.0.0.%d"},"Service":{"Port":5432}}]`, n)
+		case "/v1/health/service/postgres?replica@":
+			fmt.Fprint(w, `[{"Node":{"Address":"10.0.0.9"},"Service":{"Address":"10.0.1.9","Port":5433}}]`)
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer srv.Close()
+	buf := `databases:
+  app:
+    db_type: postgres
+    name: app
+    host: consul://postgres-primary.service.dc1
+    reader_host: consul://replica.postgres.service
+    discovery:
+      address: ` + srv.URL + `
+      token: tok
+    credentials:
+      - role: app
+        username: app
+      - role: reader
+        username: reader
+        reader: true
+  orders:
+    db_type: postgres
+    name: orders
+    discovery:
+      service: orders
+      address: ` + srv.URL + `
+      token: tok
+`
+	c, err := Parse("dbconfig.yaml", []byte(buf))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// resolved again on every connection
+	for i, exp := range []string{"postgres://app:@10.0.0.1:5432/app", "postgres://app:@10.0.0.2:5432/app"} {
+		if dsn, err := c.DSN("app", "app"); err != nil || dsn != exp {
+			t.Errorf("test %d expected %q, got: %q %v", i, exp, dsn, err)
+		}
+	}
+	if dsn, err := c.ReaderDSN("app", "reader"); err != nil || dsn != "postgres://reader:@10.0.1.9:5433/app" {
+		t.Errorf("expected reader DSN, got: %q %v", dsn, err)
+	}
+	if c.Databases["app"].Host != "consul://postgres-primary.service.dc1" {
+		t.Errorf("expected host not to be modified, got: %q", c.Databases["app"].Host)
+	}
+	if _, err := c.DSN("orders", ""); err == nil || !strings.Contains(err.Error(), "no healthy instances") {
+		t.Errorf("expected no healthy instances error, got: %v", err)
+	}
+	if _, err := Parse("dbconfig.yaml", []byte("databases:\n  app:\n    db_type: postgres\n    host: consul://postgres\n")); err == nil || !strings.Contains(err.Error(), "database app") {
+		t.Errorf("expected invalid host error, got: %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// AgeIdentityVar is the environment variable of the path of the age identity
+// file decrypting age encrypted config files. It defaults to
+// $SOPS_AGE_KEY_FILE, or else the sops/age/keys.txt file of the user's config
+// directory.
+const AgeIdentityVar = "USQL_AGE_IDENTITY"
+
+// the commands decrypting the config files.
+var (
+	sopsCommand = "sops"
+	ageCommand  = "age"
+)
+
+// age headers of the binary and armored encrypted files.
+var (
+	ageHeader      = []byte("age-encryption.org/v1\n")
+	ageArmorHeader = []byte("-----BEGIN AGE ENCRYPTED FILE-----")
+)
+
+// decrypt returns the decrypted contents of the config file buf when
+// encrypted with age or SOPS, decrypting them with the age or sops commands,
+// or else buf.
+func decrypt(buf []byte) ([]byte, error) {
+	switch {
+	case isAge(buf):
+		return decryptAge(buf)
+	case isSops(buf):
+		return decryptSops(buf)
+	}
+	return buf, nil
+}
+
+// isAge returns true when buf is encrypted with age.
+func isAge(buf []byte) bool {
+	return bytes.HasPrefix(buf, ageHeader) || bytes.HasPrefix(bytes.TrimSpace(buf), ageArmorHeader)
+}
+
+// isSops returns true when buf is a YAML file encrypted with SOPS, whose
+// metadata is stored under the sops key.
+func isSops(buf []byte) bool {
+	if !bytes.Contains(buf, []byte("sops:")) {
+		return false
+	}
+	var v struct {
+		Sops map[string]interface{} `yaml:"sops"`
+	}
+	if err := yaml.Unmarshal(buf, &v); err != nil {
+		return false
+	}
+	_, ok := v.Sops["mac"]
+	return ok
+}
+
+// decryptAge decrypts buf with the age identity file (see AgeIdentityVar).
+func decryptAge(buf []byte) ([]byte, error) {
+	identity, err := ageIdentity()
+	if err != nil {
+		return nil, err
+	}
+	return run(bytes.NewReader(buf), ageCommand, "--decrypt", "--identity", identity)
+}
+
+// ageIdentity returns the path of the age identity file.
+func ageIdentity() (string, error) {
+	for _, name := range []string{AgeIdentityVar, "SOPS_AGE_KEY_FILE"} {
+		if path := os.Getenv(name); path != "" {
+			return path, nil
+		}
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "sops", "age", "keys.txt")
+	if !exists(path) {
+		return "", fmt.Errorf("unable to find the age identity file: set %s", AgeIdentityVar)
+	}
+	return path, nil
+}
+
+// decryptSops decrypts buf with sops, which finds the keys of the file as
+// configured for sops (age identities, PGP, cloud KMS).
+func decryptSops(buf []byte) ([]byte, error) {
+	// the config file may not be a local file, so decrypt a temporary copy
+	f, err := os.CreateTemp("", "usql-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(f.Name())
+	_, err = f.Write(buf)
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return run(nil, sopsCommand, "--decrypt", "--input-type", "yaml", "--output-type", "yaml", f.Name())
+}
+
+// run runs the command name with args and stdin, returning its output.
+func run(stdin *bytes.Reader, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	if stdin != nil {
+		cmd.Stdin = stdin
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	buf, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("unable to decrypt with %s: %s", name, msg)
+		}
+		return nil, fmt.Errorf("unable to decrypt with %s: %w", name, err)
+	}
+	return buf, nil
+}
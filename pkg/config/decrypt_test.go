@@ -0,0 +1,58 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestDecrypt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a shell")
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "plain.yaml")
+	if err := os.WriteFile(plain, []byte(testConfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	// fake commands, writing the decrypted config file and their arguments
+	for _, name := range []string{"sops", "age"} {
+		script := "#!/bin/sh\necho \"$@\" > " + filepath.Join(dir, name+".args") + "\ncat " + plain + "\n"
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(script), 0o700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sops, age := sopsCommand, ageCommand
+	defer func() { sopsCommand, ageCommand = sops, age }()
+	sopsCommand, ageCommand = filepath.Join(dir, "sops"), filepath.Join(dir, "age")
+	t.Setenv(AgeIdentityVar, "/keys.txt")
+	tests := []struct {
+		name, buf, args string
+	}{
+		{"sops", "databases: ENC[AES256_GCM,data:x]\nsops:\n  mac: ENC[AES256_GCM,data:y]\n  version: 3.8.1\n", "--decrypt --input-type yaml --output-type yaml"},
+		{"age", "age-encryption.org/v1\n-> X25519 x\n", "--decrypt --identity /keys.txt"},
+		{"age", "-----BEGIN AGE ENCRYPTED FILE-----\nYWdl\n-----END AGE ENCRYPTED FILE-----\n", "--decrypt --identity /keys.txt"},
+	}
+	for i, test := range tests {
+		c, err := Parse("dbconfig.yaml", []byte(test.buf))
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if _, err := c.Database("app_db"); err != nil {
+			t.Errorf("test %d expected no error, got: %v", i, err)
+		}
+		args, err := os.ReadFile(filepath.Join(dir, test.name+".args"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasPrefix(string(args), test.args) {
+			t.Errorf("test %d expected %s arguments %q, got: %q", i, test.name, test.args, args)
+		}
+	}
+	// plain config files with a sops database are not decrypted
+	if isSops([]byte("databases:\n  sops:\n    host: localhost\n")) {
+		t.Errorf("expected a plain config file")
+	}
+}
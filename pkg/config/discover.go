@@ -0,0 +1,370 @@
+package config
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/xo/usql/logging"
+	sqladmin "google.golang.org/api/sqladmin/v1beta4"
+)
+
+// Cloud providers of the discovered databases.
+const (
+	// ProviderAWS discovers the RDS instances and Aurora clusters of a
+	// region.
+	ProviderAWS = "aws"
+	// ProviderGCP discovers the Cloud SQL instances of a project.
+	ProviderGCP = "gcp"
+)
+
+// defaultDiscoverTTL is how long the discovered databases are cached by
+// default.
+const defaultDiscoverTTL = 5 * time.Minute
+
+// DiscoverConfig is the config of the databases discovered at runtime from
+// the APIs of a cloud provider, added to the database aliases of the config
+// file.
+type DiscoverConfig struct {
+	// Provider is the cloud provider: aws or gcp.
+	Provider string `yaml:"provider,omitempty"`
+	// Filter selects the discovered instances by their tags (aws) or labels
+	// (gcp), as comma separated tag:KEY=VALUE or label:KEY=VALUE, all of
+	// which must match.
+	Filter string `yaml:"filter,omitempty"`
+	// Region is the AWS region, defaulting to $AWS_REGION.
+	Region string `yaml:"region,omitempty"`
+	// Project is the GCP project.
+	Project string `yaml:"project,omitempty"`
+	// Prefix is prepended to the identifiers of the instances to make their
+	// aliases.
+	Prefix string `yaml:"prefix,omitempty"`
+	// Database is the config of the discovered databases, such as their name
+	// and credentials, whose host, reader_host, port and db_type are set
+	// from the instances when not set.
+	Database DatabaseConfig `yaml:"database,omitempty"`
+	// CacheTTL is how long the discovered instances are cached (default
+	// 5m).
+	CacheTTL time.Duration `yaml:"cache_ttl,omitempty"`
+}
+
+// validate checks the provider and the filter of the discover entry.
+func (dc *DiscoverConfig) validate() error {
+	switch dc.Provider {
+	case ProviderAWS:
+	case ProviderGCP:
+		if dc.Project == "" {
+			return fmt.Errorf("no project")
+		}
+	default:
+		return fmt.Errorf("invalid provider %q: expected aws or gcp", dc.Provider)
+	}
+	_, err := parseDiscoverFilter(dc.Filter)
+	return err
+}
+
+// instance is a database instance of a cloud provider.
+type instance struct {
+	ID         string            `json:"id"`
+	DbType     string            `json:"db_type"`
+	Host       string            `json:"host"`
+	ReaderHost string            `json:"reader_host,omitempty"`
+	Port       int               `json:"port,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// DiscoverDatabases adds the databases discovered by the discover entries of
+// the config file to its database aliases, keeping the aliases of the config
+// file. The instances are cached for the cache_ttl of the entries, and the
+// expired cache of an entry is used when its cloud provider can't be
+// reached.
+func (c *Config) DiscoverDatabases(ctx context.Context) error {
+	var errs []string
+	for i, dc := range c.Discover {
+		if dc == nil {
+			continue
+		}
+		instances, err := dc.instances(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("discover %d (%s): %v", i, dc.Provider, err))
+			if instances == nil {
+				continue
+			}
+		}
+		if err := c.addDiscovered(dc, instances); err != nil {
+			errs = append(errs, fmt.Sprintf("discover %d (%s): %v", i, dc.Provider, err))
+		}
+	}
+	if len(errs) != 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// addDiscovered adds the instances matching the filter of the discover entry
+// to the databases of the config.
+func (c *Config) addDiscovered(dc *DiscoverConfig, instances []instance) error {
+	filters, err := parseDiscoverFilter(dc.Filter)
+	if err != nil {
+		return err
+	}
+	if c.Databases == nil {
+		c.Databases = make(map[string]*DatabaseConfig)
+	}
+	var n int
+	for _, inst := range instances {
+		if !matchTags(inst.Tags, filters) {
+			continue
+		}
+		alias := dc.Prefix + inst.ID
+		if c.Databases[alias] != nil {
+			continue
+		}
+		db := dc.Database
+		if db.Host == "" {
+			db.Host, db.ReaderHost = inst.Host, inst.ReaderHost
+		}
+		if db.Port == 0 {
+			db.Port = inst.Port
+		}
+		if db.DbType == "" {
+			db.DbType = inst.DbType
+		}
+		if db.Name == "" {
+			db.Name = inst.Name
+		}
+		c.Databases[alias] = &db
+		n++
+	}
+	logging.Verbosef("discover %s: %d databases", dc.Provider, n)
+	return nil
+}
+
+// parseDiscoverFilter parses the tag:KEY=VALUE or label:KEY=VALUE filters of
+// a discover entry.
+func parseDiscoverFilter(filter string) (map[string]string, error) {
+	filters := make(map[string]string)
+	for _, f := range strings.Split(filter, ",") {
+		if f = strings.TrimSpace(f); f == "" {
+			continue
+		}
+		kv := strings.TrimPrefix(strings.TrimPrefix(f, "tag:"), "label:")
+		i := strings.Index(kv, "=")
+		if kv == f || i <= 0 {
+			return nil, fmt.Errorf("invalid filter %q: expected tag:KEY=VALUE or label:KEY=VALUE", f)
+		}
+		filters[kv[:i]] = kv[i+1:]
+	}
+	return filters, nil
+}
+
+// matchTags returns true when the tags have all the filtered values.
+func matchTags(tags, filters map[string]string) bool {
+	for k, v := range filters {
+		if tv, ok := tags[k]; !ok || tv != v {
+			return false
+		}
+	}
+	return true
+}
+
+// instances returns the instances of the discover entry, cached for its
+// cache_ttl. The expired cache is returned with the error of the cloud
+// provider, when any.
+func (dc *DiscoverConfig) instances(ctx context.Context) ([]instance, error) {
+	ttl := dc.CacheTTL
+	if ttl == 0 {
+		ttl = defaultDiscoverTTL
+	}
+	cache, err := dc.cachePath()
+	if err != nil {
+		return nil, err
+	}
+	var cached []instance
+	if fi, err := os.Stat(cache); err == nil {
+		if buf, err := os.ReadFile(cache); err == nil && json.Unmarshal(buf, &cached) == nil && time.Since(fi.ModTime()) < ttl {
+			return cached, nil
+		}
+	}
+	var instances []instance
+	switch dc.Provider {
+	case ProviderAWS:
+		instances, err = dc.awsInstances(ctx)
+	case ProviderGCP:
+		instances, err = dc.gcpInstances(ctx)
+	default:
+		return nil, fmt.Errorf("invalid provider %q: expected aws or gcp", dc.Provider)
+	}
+	if err != nil {
+		return cached, err
+	}
+	buf, err := json.Marshal(instances)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(cache), 0o700); err != nil {
+		return instances, err
+	}
+	return instances, os.WriteFile(cache, buf, 0o600)
+}
+
+// cachePath returns the path of the cached instances of the discover entry.
+func (dc *DiscoverConfig) cachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(dc.Provider + "\x00" + dc.Region + "\x00" + dc.Project))
+	return filepath.Join(dir, "usql", "discover", hex.EncodeToString(sum[:])+".json"), nil
+}
+
+// awsInstances returns the RDS instances, but the instances of Aurora
+// clusters, and the Aurora clusters of the region.
+func (dc *DiscoverConfig) awsInstances(ctx context.Context) ([]instance, error) {
+	region := dc.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	sess, err := awsSession(region)
+	if err != nil {
+		return nil, err
+	}
+	svc := rds.New(sess)
+	var instances []instance
+	err = svc.DescribeDBClustersPagesWithContext(ctx, &rds.DescribeDBClustersInput{}, func(out *rds.DescribeDBClustersOutput, _ bool) bool {
+		for _, c := range out.DBClusters {
+			dbType, ok := rdsDbType(aws.StringValue(c.Engine))
+			if !ok || aws.StringValue(c.Endpoint) == "" {
+				continue
+			}
+			instances = append(instances, instance{
+				ID:         aws.StringValue(c.DBClusterIdentifier),
+				DbType:     dbType,
+				Host:       aws.StringValue(c.Endpoint),
+				ReaderHost: aws.StringValue(c.ReaderEndpoint),
+				Port:       int(aws.Int64Value(c.Port)),
+				Name:       aws.StringValue(c.DatabaseName),
+				Tags:       rdsTags(c.TagList),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = svc.DescribeDBInstancesPagesWithContext(ctx, &rds.DescribeDBInstancesInput{}, func(out *rds.DescribeDBInstancesOutput, _ bool) bool {
+		for _, i := range out.DBInstances {
+			dbType, ok := rdsDbType(aws.StringValue(i.Engine))
+			if !ok || i.Endpoint == nil || aws.StringValue(i.DBClusterIdentifier) != "" {
+				continue
+			}
+			instances = append(instances, instance{
+				ID:     aws.StringValue(i.DBInstanceIdentifier),
+				DbType: dbType,
+				Host:   aws.StringValue(i.Endpoint.Address),
+				Port:   int(aws.Int64Value(i.Endpoint.Port)),
+				Name:   aws.StringValue(i.DBName),
+				Tags:   rdsTags(i.TagList),
+			})
+		}
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
+
+// rdsTags returns the tags of a RDS instance or cluster.
+func rdsTags(tags []*rds.Tag) map[string]string {
+	m := make(map[string]string, len(tags))
+	for _, t := range tags {
+		m[aws.StringValue(t.Key)] = aws.StringValue(t.Value)
+	}
+	return m
+}
+
+// rdsDbType returns the db_type of the engine of a RDS instance or cluster.
+func rdsDbType(engine string) (string, bool) {
+	switch {
+	case engine == "postgres":
+		return "postgres", true
+	case engine == "mysql", engine == "mariadb":
+		return "mysql", true
+	case engine == "aurora-postgresql":
+		return "aurora-postgres", true
+	case engine == "aurora-mysql", engine == "aurora":
+		return "aurora-mysql", true
+	case strings.HasPrefix(engine, "sqlserver-"):
+		return "sqlserver", true
+	case strings.HasPrefix(engine, "oracle-"):
+		return "oracle", true
+	}
+	return "", false
+}
+
+// gcpInstances returns the Cloud SQL instances of the project, with their
+// public IP address, or else their private IP address.
+func (dc *DiscoverConfig) gcpInstances(ctx context.Context) ([]instance, error) {
+	if dc.Project == "" {
+		return nil, fmt.Errorf("no project")
+	}
+	svc, err := sqladmin.NewService(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var instances []instance
+	err = svc.Instances.List(dc.Project).Pages(ctx, func(res *sqladmin.InstancesListResponse) error {
+		for _, i := range res.Items {
+			dbType, ok := cloudSQLDbType(i.DatabaseVersion)
+			if !ok {
+				continue
+			}
+			var host string
+			for _, ip := range i.IpAddresses {
+				if ip.Type == "PRIMARY" || (ip.Type == "PRIVATE" && host == "") {
+					host = ip.IpAddress
+				}
+			}
+			if host == "" {
+				continue
+			}
+			inst := instance{ID: i.Name, DbType: dbType, Host: host}
+			if i.Settings != nil {
+				inst.Tags = i.Settings.UserLabels
+			}
+			instances = append(instances, inst)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(instances, func(i, j int) bool { return instances[i].ID < instances[j].ID })
+	return instances, nil
+}
+
+// cloudSQLDbType returns the db_type of the database version of a Cloud SQL
+// instance, such as POSTGRES_15.
+func cloudSQLDbType(version string) (string, bool) {
+	switch {
+	case strings.HasPrefix(version, "POSTGRES_"):
+		return "postgres", true
+	case strings.HasPrefix(version, "MYSQL_"):
+		return "mysql", true
+	case strings.HasPrefix(version, "SQLSERVER_"):
+		return "sqlserver", true
+	}
+	return "", false
+}
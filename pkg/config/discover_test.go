@@ -0,0 +1,117 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDiscoverFilter(t *testing.T) {
+	filters, err := parseDiscoverFilter("tag:Team=payments, label:env=prod")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := map[string]string{"Team": "payments", "env": "prod"}; !reflect.DeepEqual(filters, exp) {
+		t.Errorf("expected %v, got: %v", exp, filters)
+	}
+	for _, filter := range []string{"Team=payments", "tag:Team", "tag:=payments"} {
+		if _, err := parseDiscoverFilter(filter); err == nil {
+			t.Errorf("filter %q expected error", filter)
+		}
+	}
+	tags := map[string]string{"Team": "payments", "env": "prod"}
+	if !matchTags(tags, map[string]string{"Team": "payments"}) {
+		t.Errorf("expected tags to match")
+	}
+	if matchTags(tags, map[string]string{"Team": "billing"}) || matchTags(tags, map[string]string{"owner": ""}) {
+		t.Errorf("expected tags not to match")
+	}
+}
+
+func TestDiscoverDbType(t *testing.T) {
+	for engine, exp := range map[string]string{
+		"postgres":          "postgres",
+		"mariadb":           "mysql",
+		"aurora-postgresql": "aurora-postgres",
+		"aurora":            "aurora-mysql",
+		"sqlserver-se":      "sqlserver",
+		"oracle-ee":         "oracle",
+		"docdb":             "",
+	} {
+		if typ, _ := rdsDbType(engine); typ != exp {
+			t.Errorf("engine %s expected %q, got: %q", engine, exp, typ)
+		}
+	}
+	for version, exp := range map[string]string{
+		"POSTGRES_15":             "postgres",
+		"MYSQL_8_0":               "mysql",
+		"SQLSERVER_2019_STANDARD": "sqlserver",
+		"UNKNOWN":                 "",
+	} {
+		if typ, _ := cloudSQLDbType(version); typ != exp {
+			t.Errorf("version %s expected %q, got: %q", version, exp, typ)
+		}
+	}
+}
+
+func TestDiscoverDatabases(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	buf := `databases:
+  rds-orders:
+    db_type: postgres
+    host: orders.internal
+discover:
+  - provider: aws
+    region: us-east-1
+    filter: tag:Team=payments
+    prefix: rds-
+    database:
+      name: app
+      credentials:
+        - role: app
+          username: app
+`
+	c, err := Parse("dbconfig.yaml", []byte(buf))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	cache, err := c.Discover[0].cachePath()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	instances := []instance{
+		{ID: "billing", DbType: "aurora-postgres", Host: "billing.cluster", ReaderHost: "billing.cluster-ro", Port: 5432, Tags: map[string]string{"Team": "payments"}},
+		{ID: "orders", DbType: "mysql", Host: "orders.rds", Tags: map[string]string{"Team": "payments"}},
+		{ID: "search", DbType: "postgres", Host: "search.rds", Tags: map[string]string{"Team": "search"}},
+	}
+	b, _ := json.Marshal(instances)
+	if err := os.MkdirAll(filepath.Dir(cache), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(cache, b, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.DiscoverDatabases(context.Background()); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(c.Databases) != 2 || c.Databases["rds-search"] != nil {
+		t.Fatalf("expected 2 databases, got: %v", c.Databases)
+	}
+	if db := c.Databases["rds-orders"]; db.Host != "orders.internal" {
+		t.Errorf("expected rds-orders of the config file, got: %+v", db)
+	}
+	db := c.Databases["rds-billing"]
+	if db == nil || db.DbType != "aurora-postgres" || db.Host != "billing.cluster" || db.ReaderHost != "billing.cluster-ro" || db.Port != 5432 || db.Name != "app" || len(db.Credentials) != 1 {
+		t.Errorf("unexpected rds-billing: %+v", db)
+	}
+	for _, s := range []string{"provider: azure", "provider: gcp", "provider: aws\n    filter: Team"} {
+		if _, err := Parse("dbconfig.yaml", []byte("discover:\n  - "+s+"\n")); err == nil || !strings.Contains(err.Error(), "discover 0") {
+			t.Errorf("%q expected discover error, got: %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// LoadEnvFile sets the environment variables of the .env file at path, made
+// of KEY=VALUE lines, with optional export prefixes, # comments, and single
+// or double quoted values. As with the .env files of most app frameworks,
+// the variables already set are not overridden.
+func LoadEnvFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimSpace(strings.TrimPrefix(line, "export "))
+		i := strings.IndexByte(line, '=')
+		if i <= 0 {
+			return fmt.Errorf("%s: line %d: expected KEY=VALUE", path, n)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		switch {
+		case len(value) > 1 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		case len(value) > 1 && value[0] == '"' && value[len(value)-1] == '"':
+			if value, err = strconv.Unquote(value); err != nil {
+				return fmt.Errorf("%s: line %d: %w", path, n, err)
+			}
+		default:
+			// strip trailing comments of unquoted values
+			if j := strings.Index(value, " #"); j != -1 {
+				value = strings.TrimSpace(value[:j])
+			}
+		}
+		if _, ok := os.LookupEnv(key); ok {
+			continue
+		}
+		if err := os.Setenv(key, value); err != nil {
+			return fmt.Errorf("%s: line %d: %w", path, n, err)
+		}
+	}
+	return s.Err()
+}
+
+// envRefRE matches the ${VAR} and ${VAR:-DEFAULT} references of the values of
+// the config file.
+var envRefRE = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+
+// interpolate replaces the ${VAR} references of the values of the config file
+// buf by the value of the environment variable VAR, or by DEFAULT for
+// ${VAR:-DEFAULT} when VAR is unset or empty. Referencing an unset variable
+// without a default is an error.
+func interpolate(buf []byte) ([]byte, error) {
+	if !bytes.Contains(buf, []byte("${")) {
+		return buf, nil
+	}
+	// expand the parsed values, so that the variables need no YAML quoting
+	var v interface{}
+	if err := yaml.Unmarshal(buf, &v); err != nil {
+		return nil, err
+	}
+	v, err := expandEnv(v)
+	if err != nil {
+		return nil, err
+	}
+	return yaml.Marshal(v)
+}
+
+// expandEnv expands the references of the strings of v.
+func expandEnv(v interface{}) (interface{}, error) {
+	var err error
+	switch x := v.(type) {
+	case string:
+		return expandEnvString(x)
+	case []interface{}:
+		for i := range x {
+			if x[i], err = expandEnv(x[i]); err != nil {
+				return nil, err
+			}
+		}
+	case map[interface{}]interface{}:
+		for k := range x {
+			if x[k], err = expandEnv(x[k]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return v, nil
+}
+
+// expandEnvString expands the references of s. A value made of a single
+// reference keeps the type of the variable's value, such as a port number,
+// when it is written the same once typed.
+func expandEnvString(s string) (interface{}, error) {
+	var err error
+	res := envRefRE.ReplaceAllStringFunc(s, func(ref string) string {
+		m := envRefRE.FindStringSubmatch(ref)
+		value, ok := os.LookupEnv(m[1])
+		switch {
+		case m[2] != "" && value == "":
+			return m[3]
+		case !ok && err == nil:
+			err = fmt.Errorf("environment variable %s is not set", m[1])
+		}
+		return value
+	})
+	if err != nil {
+		return nil, err
+	}
+	if envRefRE.FindString(s) == s {
+		var typed interface{}
+		if yaml.Unmarshal([]byte(res), &typed) == nil {
+			switch typed.(type) {
+			case int, float64, bool:
+				if buf, err := yaml.Marshal(typed); err == nil && strings.TrimSpace(string(buf)) == res {
+					return typed, nil
+				}
+			}
+		}
+	}
+	return res, nil
+}
+
+// EnvOverride returns the environment variable overriding the field (ie,
+// PASSWORD) of the database alias, or of its role when not empty,
+// USQL_ALIAS_ROLE_FIELD.
+func EnvOverride(alias, role, field string) string {
+	name := "usql_" + alias
+	if role != "" {
+		name += "_" + role
+	}
+	return envVarRE.ReplaceAllString(strings.ToUpper(name+"_"+field), "_")
+}
+
+// applyEnvOverrides applies the environment overrides of the host of the
+// databases (USQL_<ALIAS>_HOST), and of the username and password of their
+// roles (USQL_<ALIAS>_<ROLE>_USERNAME and USQL_<ALIAS>_<ROLE>_PASSWORD), so
+// that secrets can be injected without writing them to the config file. An
+// overridden password replaces the role's password_secret.
+func (c *Config) applyEnvOverrides() {
+	for alias, db := range c.Databases {
+		if db == nil {
+			continue
+		}
+		if host, ok := os.LookupEnv(EnvOverride(alias, "", "HOST")); ok {
+			db.Host = host
+		}
+		for _, rc := range db.Credentials {
+			if rc == nil || rc.Name == "" {
+				continue
+			}
+			if username, ok := os.LookupEnv(EnvOverride(alias, rc.Name, "USERNAME")); ok {
+				rc.Username = username
+			}
+			if password, ok := os.LookupEnv(EnvOverride(alias, rc.Name, "PASSWORD")); ok {
+				rc.Password, rc.PasswordSecret = password, ""
+			}
+		}
+	}
+}
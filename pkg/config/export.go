@@ -0,0 +1,61 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Redact returns a copy of the config without its secrets, to be shared. The
+// passwords of the credentials are removed, or replaced by a password_secret
+// reference to the secret resolver plugin when not empty: env:VAR for the
+// built-in env resolver (see EnvVar), or PLUGIN:ALIAS/ROLE. The notifier
+// URLs, which carry their credentials, and the Oracle wallet passwords are
+// removed.
+func (c *Config) Redact(plugin string) (*Config, error) {
+	// copy the config
+	buf, err := yaml.Marshal(c)
+	if err != nil {
+		return nil, err
+	}
+	r := &Config{Path: c.Path}
+	if err := yaml.Unmarshal(buf, r); err != nil {
+		return nil, err
+	}
+	for alias, db := range r.Databases {
+		if db == nil {
+			continue
+		}
+		if db.Oracle != nil {
+			db.Oracle.WalletPassword = ""
+		}
+		for _, rc := range db.Credentials {
+			if rc == nil || rc.Password == "" {
+				continue
+			}
+			rc.Password = ""
+			switch role := firstNonEmpty(rc.Name, rc.Username); {
+			case plugin == EnvSecret:
+				rc.PasswordSecret = EnvSecret + ":" + EnvVar(alias, role)
+			case plugin != "":
+				rc.PasswordSecret = fmt.Sprintf("%s:%s/%s", plugin, alias, role)
+			}
+		}
+	}
+	for _, n := range r.Notify {
+		if n != nil {
+			n.URL = ""
+		}
+	}
+	return r, nil
+}
+
+// envVarRE matches the characters replaced in environment variable names.
+var envVarRE = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// EnvVar returns the environment variable of the password of the role of the
+// alias, USQL_ALIAS_ROLE_PASSWORD.
+func EnvVar(alias, role string) string {
+	return EnvOverride(alias, role, "PASSWORD")
+}
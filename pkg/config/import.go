@@ -0,0 +1,259 @@
+package config
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// Import formats.
+const (
+	// ImportPgpass is the PostgreSQL password file format (~/.pgpass).
+	ImportPgpass = "pgpass"
+	// ImportMycnf is the MySQL option file format (~/.my.cnf).
+	ImportMycnf = "mycnf"
+	// ImportDBeaver is the DBeaver data sources XML format
+	// (.dbeaver-data-sources.xml).
+	ImportDBeaver = "dbeaver"
+)
+
+// ImportFormats are the import formats.
+var ImportFormats = []string{ImportPgpass, ImportMycnf, ImportDBeaver}
+
+// Import reads the database aliases of a credential or connection file in
+// format. The aliases are named after the connections, and the roles of
+// their credentials after the usernames.
+func Import(format string, r io.Reader) (map[string]*DatabaseConfig, error) {
+	switch format {
+	case ImportPgpass:
+		return importPgpass(r)
+	case ImportMycnf:
+		return importMycnf(r)
+	case ImportDBeaver:
+		return importDBeaver(r)
+	}
+	return nil, fmt.Errorf("invalid import format %q", format)
+}
+
+// importPgpass reads the entries of a PostgreSQL password file, whose lines
+// are hostname:port:database:username:password. Entries of the same host,
+// port and database are the roles of the same alias. Entries with a *
+// hostname are skipped.
+func importPgpass(r io.Reader) (map[string]*DatabaseConfig, error) {
+	var im importer
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := splitPgpass(line)
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("line %d: expected hostname:port:database:username:password", n)
+		}
+		host, port, name, user, password := fields[0], fields[1], fields[2], fields[3], fields[4]
+		if host == "*" {
+			continue
+		}
+		if name == "*" {
+			name = ""
+		}
+		if port == "*" || port == "5432" {
+			port = ""
+		}
+		im.add(firstNonEmpty(name, host), "postgres", host, port, name, user, password)
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	return im.dbs, nil
+}
+
+// splitPgpass splits a pgpass line on unescaped colons.
+func splitPgpass(line string) []string {
+	var fields []string
+	var sb strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch c := line[i]; {
+		case c == '\\' && i+1 < len(line):
+			i++
+			sb.WriteByte(line[i])
+		case c == ':':
+			fields = append(fields, sb.String())
+			sb.Reset()
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return append(fields, sb.String())
+}
+
+// importMycnf reads the option groups of a MySQL option file. Every group
+// with a host or a user, such as [client] or [client_app], is an alias named
+// after the group suffix, or its database or host.
+func importMycnf(r io.Reader) (map[string]*DatabaseConfig, error) {
+	type group struct {
+		name string
+		opts map[string]string
+	}
+	var groups []*group
+	var g *group
+	s := bufio.NewScanner(r)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "!"):
+			continue
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf("line %d: invalid group %s", n, line)
+			}
+			g = &group{name: strings.TrimSpace(line[1 : len(line)-1]), opts: make(map[string]string)}
+			groups = append(groups, g)
+			continue
+		case g == nil:
+			return nil, fmt.Errorf("line %d: option outside of a group", n)
+		}
+		k, v, _ := strings.Cut(line, "=")
+		k = strings.ReplaceAll(strings.TrimSpace(k), "-", "_")
+		v = strings.TrimSpace(v)
+		if len(v) >= 2 && (v[0] == '"' || v[0] == '\'') && v[len(v)-1] == v[0] {
+			v = v[1 : len(v)-1]
+		}
+		g.opts[k] = v
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	var im importer
+	for _, g := range groups {
+		host, user := g.opts["host"], g.opts["user"]
+		if host == "" && user == "" {
+			continue
+		}
+		if host == "" {
+			host = "localhost"
+		}
+		port := g.opts["port"]
+		if port == "3306" {
+			port = ""
+		}
+		alias := strings.Trim(strings.TrimPrefix(strings.TrimPrefix(g.name, "client"), "mysql"), "_-")
+		im.add(firstNonEmpty(alias, g.opts["database"], host), "mysql", host, port, g.opts["database"], user, g.opts["password"])
+	}
+	return im.dbs, nil
+}
+
+// dbeaverProviders are the db_type of the DBeaver providers and drivers.
+var dbeaverProviders = map[string]string{
+	"postgresql": "postgres",
+	"mysql":      "mysql",
+	"mariadb":    "mysql",
+	"oracle":     "oracle",
+	"mssql":      "sqlserver",
+	"sqlserver":  "sqlserver",
+	"sqlite":     "sqlite3",
+	"clickhouse": "clickhouse",
+	"snowflake":  "snowflake",
+	"db2":        "db2",
+	"vertica":    "vertica",
+	"exasol":     "exasol",
+	"h2":         "h2",
+}
+
+// importDBeaver reads the data sources of a DBeaver data sources XML file.
+// Data sources of unknown providers are skipped.
+func importDBeaver(r io.Reader) (map[string]*DatabaseConfig, error) {
+	var v struct {
+		DataSources []struct {
+			Name       string `xml:"name,attr"`
+			Provider   string `xml:"provider,attr"`
+			Driver     string `xml:"driver,attr"`
+			Connection struct {
+				Host     string `xml:"host,attr"`
+				Port     string `xml:"port,attr"`
+				Database string `xml:"database,attr"`
+				User     string `xml:"user,attr"`
+				Password string `xml:"password,attr"`
+			} `xml:"connection"`
+		} `xml:"data-source"`
+	}
+	if err := xml.NewDecoder(r).Decode(&v); err != nil {
+		return nil, fmt.Errorf("invalid DBeaver data sources: %w", err)
+	}
+	var im importer
+	for _, ds := range v.DataSources {
+		typ, ok := dbeaverProviders[ds.Provider]
+		if !ok {
+			if typ, ok = dbeaverProviders[ds.Driver]; !ok {
+				continue
+			}
+		}
+		c := ds.Connection
+		host := c.Host
+		if typ == "sqlite3" {
+			host = c.Database
+		}
+		im.add(firstNonEmpty(ds.Name, c.Database, c.Host), typ, host, c.Port, c.Database, c.User, c.Password)
+	}
+	return im.dbs, nil
+}
+
+// importer collects imported database aliases.
+type importer struct {
+	dbs map[string]*DatabaseConfig
+}
+
+// add adds the credentials of user to the alias of the database, creating
+// it when needed. Different databases with the same name get a numbered
+// alias.
+func (im *importer) add(name, typ, host, port, database, user, password string) {
+	if im.dbs == nil {
+		im.dbs = make(map[string]*DatabaseConfig)
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	base := aliasName(name)
+	alias := base
+	for i := 2; ; i++ {
+		db := im.dbs[alias]
+		if db == nil {
+			db = &DatabaseConfig{Name: database, Host: host, DbType: typ}
+			im.dbs[alias] = db
+		}
+		if db.Name == database && db.Host == host && db.DbType == typ {
+			// the first credentials of a user are used, as by the clients
+			if _, err := db.Role(user); user != "" && err != nil {
+				db.Credentials = append(db.Credentials, &RoleConfig{Username: user, Password: password, Name: user})
+			}
+			return
+		}
+		alias = fmt.Sprintf("%s_%d", base, i)
+	}
+}
+
+// aliasRE matches the characters replaced in alias names.
+var aliasRE = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// aliasName returns the alias name of a connection name.
+func aliasName(name string) string {
+	if s := strings.Trim(aliasRE.ReplaceAllString(strings.ToLower(name), "_"), "_"); s != "" {
+		return s
+	}
+	return "db"
+}
+
+// firstNonEmpty returns the first non empty string of v.
+func firstNonEmpty(v ...string) string {
+	for _, s := range v {
+		if s != "" {
+			return s
+		}
+	}
+	return ""
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	tests := []struct {
+		format, src string
+		exp         map[string]*DatabaseConfig
+	}{
+		{
+			ImportPgpass, `# comment
+db.example.com:5432:app:admin:s3cr\:et
+db.example.com:5432:app:reader:r34d
+db.example.com:5432:app:reader:ignored
+other.example.com:6432:app:admin:x
+*:*:*:postgres:pg
+localhost:*:*:me:pw
+`,
+			map[string]*DatabaseConfig{
+				"app": {Name: "app", Host: "db.example.com", DbType: "postgres", Credentials: []*RoleConfig{
+					{Username: "admin", Password: "s3cr:et", Name: "admin"},
+					{Username: "reader", Password: "r34d", Name: "reader"},
+				}},
+				"app_2":     {Name: "app", Host: "other.example.com:6432", DbType: "postgres", Credentials: []*RoleConfig{{Username: "admin", Password: "x", Name: "admin"}}},
+				"localhost": {Host: "localhost", DbType: "postgres", Credentials: []*RoleConfig{{Username: "me", Password: "pw", Name: "me"}}},
+			},
+		},
+		{
+			ImportMycnf, `[client]
+user = root
+password = "p w"
+
+[mysqldump]
+quick
+
+[client_Reporting-DB]
+host=reports.example.com
+port=3307
+user=reporter
+password=r
+database=reports
+`,
+			map[string]*DatabaseConfig{
+				"localhost":    {Host: "localhost", DbType: "mysql", Credentials: []*RoleConfig{{Username: "root", Password: "p w", Name: "root"}}},
+				"reporting_db": {Name: "reports", Host: "reports.example.com:3307", DbType: "mysql", Credentials: []*RoleConfig{{Username: "reporter", Password: "r", Name: "reporter"}}},
+			},
+		},
+		{
+			ImportDBeaver, `<?xml version="1.0" encoding="UTF-8"?>
+<data-sources>
+  <data-source id="postgres-jdbc-1" provider="postgresql" driver="postgres-jdbc" name="Prod Orders">
+    <connection host="orders.example.com" port="5433" database="orders" user="app" password="secret" type="prod"/>
+  </data-source>
+  <data-source id="sqlite-1" provider="generic" driver="sqlite" name="local">
+    <connection database="/tmp/local.db"/>
+  </data-source>
+  <data-source id="mongo-1" provider="mongo" driver="mongo" name="docs">
+    <connection host="mongo.example.com"/>
+  </data-source>
+</data-sources>
+`,
+			map[string]*DatabaseConfig{
+				"prod_orders": {Name: "orders", Host: "orders.example.com:5433", DbType: "postgres", Credentials: []*RoleConfig{{Username: "app", Password: "secret", Name: "app"}}},
+				"local":       {Name: "/tmp/local.db", Host: "/tmp/local.db", DbType: "sqlite3"},
+			},
+		},
+	}
+	for i, test := range tests {
+		dbs, err := Import(test.format, strings.NewReader(test.src))
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if !reflect.DeepEqual(dbs, test.exp) {
+			for alias, db := range dbs {
+				t.Logf("test %d %s: %+v", i, alias, db)
+			}
+			t.Errorf("test %d unexpected aliases", i)
+		}
+	}
+	if _, err := Import(ImportPgpass, strings.NewReader("host:5432:db\n")); err == nil || err.Error() != "line 1: expected hostname:port:database:username:password" {
+		t.Errorf("expected invalid line error, got: %v", err)
+	}
+}
@@ -0,0 +1,369 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// K8sPrefix is the prefix of the paths of config files stored in Kubernetes:
+// k8s://NAMESPACE/NAME for a secret, or k8s://NAMESPACE/KIND/NAME where KIND
+// is secret or configmap, with an optional #KEY of the entry holding the
+// config file.
+const K8sPrefix = "k8s://"
+
+// k8sTimeout is the timeout of the requests to the Kubernetes API server.
+const k8sTimeout = 30 * time.Second
+
+// k8sServiceAccount is the directory of the service account credentials
+// mounted in pods.
+var k8sServiceAccount = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// IsK8s returns true when path is a config file stored in Kubernetes.
+func IsK8s(path string) bool {
+	return strings.HasPrefix(path, K8sPrefix)
+}
+
+// k8sRef is a reference to a config file stored in Kubernetes.
+type k8sRef struct {
+	namespace, kind, name, key string
+}
+
+// parseK8sRef parses the reference path.
+func parseK8sRef(path string) (k8sRef, error) {
+	var ref k8sRef
+	s := strings.TrimPrefix(path, K8sPrefix)
+	if i := strings.IndexByte(s, '#'); i != -1 {
+		s, ref.key = s[:i], s[i+1:]
+	}
+	switch v := strings.Split(s, "/"); {
+	case len(v) == 2:
+		ref.namespace, ref.kind, ref.name = v[0], "secret", v[1]
+	case len(v) == 3 && (v[1] == "secret" || v[1] == "configmap"):
+		ref.namespace, ref.kind, ref.name = v[0], v[1], v[2]
+	default:
+		return ref, fmt.Errorf("invalid Kubernetes config path %s: expected %sNAMESPACE/NAME or %sNAMESPACE/secret|configmap/NAME", path, K8sPrefix, K8sPrefix)
+	}
+	if ref.namespace == "" || ref.name == "" {
+		return ref, fmt.Errorf("invalid Kubernetes config path %s: namespace and name are required", path)
+	}
+	return ref, nil
+}
+
+// loadK8s returns the contents of the config file stored in the Kubernetes
+// secret or ConfigMap of path, using the service account of the pod when
+// running in a cluster, or else the current context of the kubeconfig file.
+func loadK8s(path string) ([]byte, error) {
+	ref, err := parseK8sRef(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newK8sClient()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	data, err := c.get(ref)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	key := ref.key
+	if key == "" {
+		key, err = k8sDefaultKey(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	v, ok := data[key]
+	if !ok {
+		return nil, fmt.Errorf("%s: no key %s in %s %s", path, key, ref.kind, ref.name)
+	}
+	if ref.kind == "configmap" {
+		return []byte(v), nil
+	}
+	buf, err := base64.StdEncoding.DecodeString(v)
+	if err != nil {
+		return nil, fmt.Errorf("%s: key %s: %w", path, key, err)
+	}
+	return buf, nil
+}
+
+// k8sDefaultKey returns the key of the config file in data when not
+// specified: its only key, or .dbconfig.yaml or dbconfig.yaml.
+func k8sDefaultKey(data map[string]string) (string, error) {
+	if len(data) == 1 {
+		for key := range data {
+			return key, nil
+		}
+	}
+	for _, key := range []string{DefaultFilename, strings.TrimPrefix(DefaultFilename, ".")} {
+		if _, ok := data[key]; ok {
+			return key, nil
+		}
+	}
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return "", fmt.Errorf("unable to choose the key of the config file among %s, add #KEY to the path", strings.Join(keys, ", "))
+}
+
+// k8sClient is a minimal Kubernetes API client.
+type k8sClient struct {
+	server string
+	token  string
+	client *http.Client
+}
+
+// newK8sClient creates a client using the service account of the pod when
+// running in a cluster, or else the current context of the kubeconfig file.
+func newK8sClient() (*k8sClient, error) {
+	if host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT"); host != "" && port != "" {
+		token, err := os.ReadFile(filepath.Join(k8sServiceAccount, "token"))
+		if err == nil {
+			tlsConfig := new(tls.Config)
+			if err := addCAFile(tlsConfig, filepath.Join(k8sServiceAccount, "ca.crt")); err != nil {
+				return nil, err
+			}
+			return &k8sClient{
+				server: "https://" + net.JoinHostPort(host, port),
+				token:  strings.TrimSpace(string(token)),
+				client: newK8sHTTPClient(tlsConfig),
+			}, nil
+		}
+	}
+	return loadKubeconfig()
+}
+
+// newK8sHTTPClient creates the HTTP client of the API server.
+func newK8sHTTPClient(tlsConfig *tls.Config) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport, Timeout: k8sTimeout}
+}
+
+// get returns the data of the secret or ConfigMap of ref.
+func (c *k8sClient) get(ref k8sRef) (map[string]string, error) {
+	kind := "secrets"
+	if ref.kind == "configmap" {
+		kind = "configmaps"
+	}
+	req, err := http.NewRequest("GET", strings.TrimSuffix(c.server, "/")+"/api/v1/namespaces/"+url.PathEscape(ref.namespace)+"/"+kind+"/"+url.PathEscape(ref.name), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	buf, err := io.ReadAll(io.LimitReader(res.Body, 16<<20))
+	if err != nil {
+		return nil, err
+	}
+	var v struct {
+		Message string            `json:"message"`
+		Data    map[string]string `json:"data"`
+	}
+	if err := json.Unmarshal(buf, &v); err != nil && res.StatusCode == http.StatusOK {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK {
+		if v.Message != "" {
+			return nil, fmt.Errorf("%s: %s", res.Status, v.Message)
+		}
+		return nil, errors.New(res.Status)
+	}
+	return v.Data, nil
+}
+
+// kubeconfig is a kubeconfig file.
+type kubeconfig struct {
+	CurrentContext string `yaml:"current-context"`
+	Clusters       []struct {
+		Name    string `yaml:"name"`
+		Cluster struct {
+			Server                   string `yaml:"server"`
+			CertificateAuthority     string `yaml:"certificate-authority"`
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+			InsecureSkipTLSVerify    bool   `yaml:"insecure-skip-tls-verify"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+	Contexts []struct {
+		Name    string `yaml:"name"`
+		Context struct {
+			Cluster string `yaml:"cluster"`
+			User    string `yaml:"user"`
+		} `yaml:"context"`
+	} `yaml:"contexts"`
+	Users []struct {
+		Name string `yaml:"name"`
+		User struct {
+			Token                 string      `yaml:"token"`
+			TokenFile             string      `yaml:"tokenFile"`
+			ClientCertificate     string      `yaml:"client-certificate"`
+			ClientCertificateData string      `yaml:"client-certificate-data"`
+			ClientKey             string      `yaml:"client-key"`
+			ClientKeyData         string      `yaml:"client-key-data"`
+			Exec                  interface{} `yaml:"exec"`
+			AuthProvider          interface{} `yaml:"auth-provider"`
+		} `yaml:"user"`
+	} `yaml:"users"`
+}
+
+// loadKubeconfig creates a client from the current context of the first
+// kubeconfig file of the KUBECONFIG environment variable, or of
+// ~/.kube/config.
+func loadKubeconfig() (*k8sClient, error) {
+	var path string
+	for _, p := range filepath.SplitList(os.Getenv("KUBECONFIG")) {
+		if p != "" && exists(p) {
+			path = p
+			break
+		}
+	}
+	if path == "" {
+		u, err := user.Current()
+		if err != nil {
+			return nil, err
+		}
+		path = filepath.Join(u.HomeDir, ".kube", "config")
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("not running in a cluster and unable to read kubeconfig: %w", err)
+	}
+	var kc kubeconfig
+	if err := yaml.Unmarshal(buf, &kc); err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig %s: %w", path, err)
+	}
+	// relative paths are relative to the kubeconfig file
+	dir := filepath.Dir(path)
+	rel := func(p string) string {
+		if p != "" && !filepath.IsAbs(p) {
+			return filepath.Join(dir, p)
+		}
+		return p
+	}
+	var clusterName, userName string
+	for _, c := range kc.Contexts {
+		if c.Name == kc.CurrentContext {
+			clusterName, userName = c.Context.Cluster, c.Context.User
+			break
+		}
+	}
+	if clusterName == "" {
+		return nil, fmt.Errorf("kubeconfig %s: no current context", path)
+	}
+	c := &k8sClient{}
+	tlsConfig := new(tls.Config)
+	found := false
+	for _, cl := range kc.Clusters {
+		if cl.Name != clusterName {
+			continue
+		}
+		found, c.server = true, cl.Cluster.Server
+		tlsConfig.InsecureSkipVerify = cl.Cluster.InsecureSkipTLSVerify
+		switch {
+		case cl.Cluster.CertificateAuthorityData != "":
+			pem, err := base64.StdEncoding.DecodeString(cl.Cluster.CertificateAuthorityData)
+			if err != nil {
+				return nil, fmt.Errorf("kubeconfig %s: cluster %s: %w", path, clusterName, err)
+			}
+			if err := addCA(tlsConfig, pem); err != nil {
+				return nil, fmt.Errorf("kubeconfig %s: cluster %s: %w", path, clusterName, err)
+			}
+		case cl.Cluster.CertificateAuthority != "":
+			if err := addCAFile(tlsConfig, rel(cl.Cluster.CertificateAuthority)); err != nil {
+				return nil, fmt.Errorf("kubeconfig %s: cluster %s: %w", path, clusterName, err)
+			}
+		}
+		break
+	}
+	if !found || c.server == "" {
+		return nil, fmt.Errorf("kubeconfig %s: no server for cluster %s", path, clusterName)
+	}
+	for _, u := range kc.Users {
+		if u.Name != userName {
+			continue
+		}
+		switch {
+		case u.User.Exec != nil || u.User.AuthProvider != nil:
+			return nil, fmt.Errorf("kubeconfig %s: user %s: exec and auth-provider credentials are not supported, use a token or client certificate", path, userName)
+		case u.User.Token != "":
+			c.token = u.User.Token
+		case u.User.TokenFile != "":
+			token, err := os.ReadFile(rel(u.User.TokenFile))
+			if err != nil {
+				return nil, fmt.Errorf("kubeconfig %s: user %s: %w", path, userName, err)
+			}
+			c.token = strings.TrimSpace(string(token))
+		}
+		certPEM, keyPEM := []byte(u.User.ClientCertificateData), []byte(u.User.ClientKeyData)
+		if len(certPEM) == 0 && u.User.ClientCertificate == "" {
+			break
+		}
+		var err error
+		if len(certPEM) != 0 {
+			if certPEM, err = base64.StdEncoding.DecodeString(string(certPEM)); err == nil {
+				keyPEM, err = base64.StdEncoding.DecodeString(string(keyPEM))
+			}
+		} else {
+			if certPEM, err = os.ReadFile(rel(u.User.ClientCertificate)); err == nil {
+				keyPEM, err = os.ReadFile(rel(u.User.ClientKey))
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig %s: user %s: %w", path, userName, err)
+		}
+		cert, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			return nil, fmt.Errorf("kubeconfig %s: user %s: %w", path, userName, err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+		break
+	}
+	c.client = newK8sHTTPClient(tlsConfig)
+	return c, nil
+}
+
+// addCAFile adds the certificate authorities of the PEM file at path to the
+// root certificates of tlsConfig.
+func addCAFile(tlsConfig *tls.Config, path string) error {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	return addCA(tlsConfig, pem)
+}
+
+// addCA adds the PEM certificate authorities to the root certificates of
+// tlsConfig.
+func addCA(tlsConfig *tls.Config, pem []byte) error {
+	if tlsConfig.RootCAs == nil {
+		tlsConfig.RootCAs = x509.NewCertPool()
+	}
+	if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+		return errors.New("no certificate authority found")
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeoutStmts are the statements setting the statement timeout of the
+// sessions of the database types, by driver, formatted with the timeout in
+// milliseconds, or in seconds (rounded up) when seconds is set.
+var timeoutStmts = map[string]struct {
+	stmt    string
+	seconds bool
+}{
+	"postgres":    {"SET statement_timeout = %d", false},
+	"cockroachdb": {"SET statement_timeout = %d", false},
+	"redshift":    {"SET statement_timeout = %d", false},
+	"mysql":       {"SET SESSION max_execution_time = %d", false},
+	"clickhouse":  {"SET max_execution_time = %d", true},
+	"snowflake":   {"ALTER SESSION SET STATEMENT_TIMEOUT_IN_SECONDS = %d", true},
+	"trino":       {"SET SESSION query_max_execution_time = '%ds'", true},
+}
+
+// maxRowsStmts are the statements limiting the rows returned by the queries
+// of the sessions of the database types, by driver. The ROWCOUNT of sqlserver
+// is not used, as it also limits the rows affected by INSERT, UPDATE and
+// DELETE statements.
+var maxRowsStmts = map[string][]string{
+	"mysql":      {"SET SESSION sql_select_limit = %d"},
+	"clickhouse": {"SET max_result_rows = %d", "SET result_overflow_mode = 'break'"},
+}
+
+// LimitStatements returns the statements setting the statement timeout and
+// the maximum number of rows returned by the queries of the sessions of the
+// role, so that they are enforced by the database: the statement_timeout of
+// postgres, cockroachdb and redshift databases, the max_execution_time of
+// mysql (SELECT statements only) and clickhouse databases, the
+// statement_timeout_in_seconds of snowflake databases and the
+// query_max_execution_time of trino databases, and the sql_select_limit of
+// mysql databases and the max_result_rows of clickhouse databases.
+func (dc *DatabaseConfig) LimitStatements(role string) ([]string, error) {
+	rc, err := dc.Role(role)
+	if err != nil {
+		return nil, nil
+	}
+	return dc.limitStatements(rc)
+}
+
+// limitStatements returns the statements setting the limits of the role.
+func (dc *DatabaseConfig) limitStatements(rc RoleConfig) ([]string, error) {
+	driver := schemaDriver(dc.DbType)
+	var stmts []string
+	switch t, ok := timeoutStmts[driver]; {
+	case rc.StatementTimeout < 0:
+		return nil, fmt.Errorf("role %s: invalid statement_timeout %s", rc.Name, rc.StatementTimeout)
+	case rc.StatementTimeout == 0:
+	case !ok:
+		return nil, fmt.Errorf("role %s: the statement_timeout of %s databases can't be set", rc.Name, dc.DbType)
+	case t.seconds:
+		stmts = append(stmts, fmt.Sprintf(t.stmt, (rc.StatementTimeout+time.Second-1)/time.Second))
+	default:
+		ms := rc.StatementTimeout.Milliseconds()
+		if ms == 0 {
+			ms = 1
+		}
+		stmts = append(stmts, fmt.Sprintf(t.stmt, ms))
+	}
+	switch s, ok := maxRowsStmts[driver]; {
+	case rc.MaxRows < 0:
+		return nil, fmt.Errorf("role %s: invalid max_rows %d", rc.Name, rc.MaxRows)
+	case rc.MaxRows == 0:
+	case !ok:
+		return nil, fmt.Errorf("role %s: the max_rows of %s databases can't be set", rc.Name, dc.DbType)
+	default:
+		for _, stmt := range s {
+			if strings.Contains(stmt, "%d") {
+				stmt = fmt.Sprintf(stmt, rc.MaxRows)
+			}
+			stmts = append(stmts, stmt)
+		}
+	}
+	return stmts, nil
+}
+
+// ByteSize is a size in bytes, set in the config file as a number of bytes,
+// or as a number with a B, kB, MB, GB or TB unit, in multiples of 1024, such
+// as 100MB.
+type ByteSize int64
+
+// byteUnits are the units of byte sizes, by their lower case name.
+var byteUnits = map[string]int64{
+	"":   1,
+	"b":  1,
+	"k":  1 << 10,
+	"kb": 1 << 10,
+	"m":  1 << 20,
+	"mb": 1 << 20,
+	"g":  1 << 30,
+	"gb": 1 << 30,
+	"t":  1 << 40,
+	"tb": 1 << 40,
+}
+
+// ParseByteSize parses a byte size, such as 512kB or 1.5GB.
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool {
+		return (r < '0' || r > '9') && r != '.'
+	})
+	if i == -1 {
+		i = len(s)
+	}
+	f, err := strconv.ParseFloat(s[:i], 64)
+	unit, ok := byteUnits[strings.ToLower(strings.TrimSpace(s[i:]))]
+	if err != nil || !ok {
+		return 0, fmt.Errorf("invalid size %q: expected a number of bytes, with an optional B, kB, MB, GB or TB unit", s)
+	}
+	return ByteSize(f * float64(unit)), nil
+}
+
+// UnmarshalYAML satisfies the yaml.Unmarshaler interface.
+func (b *ByteSize) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	n, err := ParseByteSize(s)
+	if err != nil {
+		return err
+	}
+	*b = n
+	return nil
+}
@@ -0,0 +1,86 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLimitStatements(t *testing.T) {
+	tests := []struct {
+		typ     string
+		timeout time.Duration
+		maxRows int
+		exp     []string
+	}{
+		{"postgres", 30 * time.Second, 0, []string{"SET statement_timeout = 30000"}},
+		{"aurora-mysql", 1500 * time.Millisecond, 1000, []string{"SET SESSION max_execution_time = 1500", "SET SESSION sql_select_limit = 1000"}},
+		{"clickhouse", 1500 * time.Millisecond, 10, []string{"SET max_execution_time = 2", "SET max_result_rows = 10", "SET result_overflow_mode = 'break'"}},
+		{"trino", time.Minute, 0, []string{"SET SESSION query_max_execution_time = '60s'"}},
+		{"sqlite3", 0, 0, nil},
+	}
+	for i, test := range tests {
+		db := &DatabaseConfig{DbType: test.typ, Credentials: []*RoleConfig{{Name: "analyst", StatementTimeout: test.timeout, MaxRows: test.maxRows}}}
+		stmts, err := db.LimitStatements("analyst")
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if !reflect.DeepEqual(stmts, test.exp) {
+			t.Errorf("test %d expected %v, got: %v", i, test.exp, stmts)
+		}
+	}
+	db := &DatabaseConfig{DbType: "postgres", Schema: "app", OnConnect: []string{"SET TIME ZONE 'UTC'"}, Credentials: []*RoleConfig{{Name: "analyst", StatementTimeout: time.Second}}}
+	if stmts, exp := db.OnConnectStatements("analyst"), []string{"SET search_path TO app", "SET statement_timeout = 1000", "SET TIME ZONE 'UTC'"}; !reflect.DeepEqual(stmts, exp) {
+		t.Errorf("expected %v, got: %v", exp, stmts)
+	}
+	// the ROWCOUNT of sqlserver would truncate the writes
+	db = &DatabaseConfig{DbType: "sqlserver", Credentials: []*RoleConfig{{Name: "analyst", MaxRows: 500}}}
+	if _, err := db.LimitStatements("analyst"); err == nil {
+		t.Errorf("expected an error for the max_rows of sqlserver")
+	}
+	for _, role := range []string{"statement_timeout: 10s", "max_rows: 100", "max_rows: -1"} {
+		buf := "databases:\n  app:\n    db_type: sqlite3\n    name: app.db\n    credentials:\n      - role: admin\n      - role: analyst\n        " + role + "\n"
+		if _, err := Parse("dbconfig.yaml", []byte(buf)); err == nil || !strings.Contains(err.Error(), "role analyst") {
+			t.Errorf("expected an invalid limit error for %s, got: %v", role, err)
+		}
+	}
+}
+
+func TestByteSize(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp ByteSize
+	}{
+		{"1024", 1024},
+		{"512B", 512},
+		{"100kB", 100 << 10},
+		{"100 MB", 100 << 20},
+		{"1.5GB", 3 << 29},
+		{"2t", 2 << 40},
+	}
+	for i, test := range tests {
+		n, err := ParseByteSize(test.s)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if n != test.exp {
+			t.Errorf("test %d expected %d, got: %d", i, test.exp, n)
+		}
+	}
+	for _, s := range []string{"", "-1MB", "10XB", "MB"} {
+		if _, err := ParseByteSize(s); err == nil {
+			t.Errorf("expected error for %q, got nil", s)
+		}
+	}
+	c, err := Parse("/tmp/.dbconfig.yaml", []byte("databases:\n  a:\n    max_result_bytes: 100MB\n  b:\n    max_result_bytes: 4096\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if a, b := c.Databases["a"].MaxResultBytes, c.Databases["b"].MaxResultBytes; a != 100<<20 || b != 4096 {
+		t.Errorf("expected 100MB and 4096 bytes, got: %d %d", a, b)
+	}
+	if _, err := Parse("/tmp/.dbconfig.yaml", []byte("databases:\n  a:\n    max_result_bytes: lots\n")); err == nil || !strings.Contains(err.Error(), `invalid size "lots"`) {
+		t.Errorf("expected invalid size error, got: %v", err)
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestOracleDSN(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "wallet"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "wallet", "tnsnames.ora"), []byte(`# autonomous database
+adb_high, adb =
+  (description= (retry_count=20)
+    (address=(protocol=tcps)(port=1522)(host=adb.us-ashburn-1.oraclecloud.com))
+    (connect_data=(service_name=abc_adb_high.adb.oraclecloud.com))
+    (security=(ssl_server_dn_match=yes)(ssl_server_cert_dn="CN=adb.us-ashburn-1.oraclecloud.com, O=Oracle Corporation")))
+adb_low = (description=(address=(protocol=tcps)(port=1522)(host=adb.us-ashburn-1.oraclecloud.com))(connect_data=(service_name=abc_adb_low.adb.oraclecloud.com)))
+`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	c, err := Parse(filepath.Join(dir, ".dbconfig.yaml"), []byte(`
+databases:
+  service:
+    name: orclpdb1
+    host: ora.example.com
+    db_type: oracle
+    credentials:
+      - username: app
+        role: app
+        password: s3cr3t
+  sid:
+    host: ora.example.com:1522
+    db_type: oracle
+    oracle:
+      sid: ORCL
+  adb:
+    db_type: oracle
+    oracle:
+      wallet: wallet
+      wallet_password: w4ll3t
+      tns_alias: ADB
+    credentials:
+      - username: admin
+        role: admin
+  missing:
+    db_type: oracle
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		alias, role, host, path string
+		params                  map[string]string
+	}{
+		{"service", "app", "ora.example.com:1521", "/orclpdb1", map[string]string{
+			"connStr": "(DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=ora.example.com)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=orclpdb1)))",
+		}},
+		{"sid", "", "ora.example.com:1522", "", map[string]string{
+			"connStr": "(DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=ora.example.com)(PORT=1522))(CONNECT_DATA=(SID=ORCL)))",
+			"SID":     "ORCL",
+		}},
+		{"adb", "admin", "localhost:1521", "", map[string]string{
+			"connStr":         `(description= (retry_count=20) (address=(protocol=tcps)(port=1522)(host=adb.us-ashburn-1.oraclecloud.com)) (connect_data=(service_name=abc_adb_high.adb.oraclecloud.com)) (security=(ssl_server_dn_match=yes)(ssl_server_cert_dn="CN=adb.us-ashburn-1.oraclecloud.com, O=Oracle Corporation")))`,
+			"wallet":          filepath.Join(dir, "wallet"),
+			"wallet password": "w4ll3t",
+			"ssl":             "true",
+		}},
+	}
+	for i, test := range tests {
+		dsn, err := c.DSN(test.alias, test.role)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if u.Scheme != "oracle" || u.Host != test.host || u.Path != test.path {
+			t.Errorf("test %d expected oracle://%s%s, got: %s", i, test.host, test.path, dsn)
+		}
+		for k, v := range test.params {
+			if s := u.Query().Get(k); s != v {
+				t.Errorf("test %d expected %s %q, got: %q", i, k, v, s)
+			}
+		}
+	}
+	if dsn, _ := c.DSN("service", "app"); !strings.HasPrefix(dsn, "oracle://app:s3cr3t@") {
+		t.Errorf("expected credentials, got: %s", dsn)
+	}
+	if _, err := c.DSN("missing", ""); err == nil {
+		t.Errorf("expected error for missing host, got nil")
+	}
+}
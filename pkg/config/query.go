@@ -0,0 +1,211 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"text/template/parse"
+	"time"
+)
+
+// QueryConfig is a query template, whose {{.NAME}} parameters are bound by
+// the driver instead of being spliced in the statement.
+type QueryConfig struct {
+	// Description describes the query.
+	Description string `yaml:"description,omitempty"`
+	// SQL is the statement of the query, with {{.NAME}} parameters.
+	SQL string `yaml:"sql,omitempty"`
+	// Params are the parameters of the query, by name. Parameters that are
+	// not declared are strings without default.
+	Params map[string]*ParamConfig `yaml:"params,omitempty"`
+}
+
+// ParamConfig is the config of a query template parameter.
+type ParamConfig struct {
+	// Type is the type of the parameter: string (default), int, float, bool,
+	// date (2006-01-02) or timestamp (RFC 3339).
+	Type string `yaml:"type,omitempty"`
+	// Default is the default value of the parameter, when set.
+	Default *string `yaml:"default,omitempty"`
+	// Description describes the parameter.
+	Description string `yaml:"description,omitempty"`
+}
+
+// Query returns the query template name.
+func (c *Config) Query(name string) (*QueryConfig, error) {
+	if q := c.Queries[name]; q != nil {
+		return q, nil
+	}
+	return nil, fmt.Errorf("Didn't find entry for %s query in config file at %s. Ensure entry exists under queries key in config file", name, c.Path)
+}
+
+// QueryNames returns the sorted names of the query templates.
+func (c *Config) QueryNames() []string {
+	names := make([]string, 0, len(c.Queries))
+	for name := range c.Queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseParams parses NAME=VALUE query template parameters.
+func ParseParams(params []string) (map[string]string, error) {
+	values := make(map[string]string, len(params))
+	for _, param := range params {
+		name, value, ok := strings.Cut(param, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid parameter %q: expected NAME=VALUE", param)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// ParamNames returns the names of the parameters of the query, in order of
+// first use.
+func (q *QueryConfig) ParamNames() ([]string, error) {
+	nodes, err := q.nodes()
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	seen := make(map[string]bool)
+	for _, n := range nodes {
+		if n.param && !seen[n.s] {
+			names, seen[n.s] = append(names, n.s), true
+		}
+	}
+	return names, nil
+}
+
+// Param returns the config of the parameter name.
+func (q *QueryConfig) Param(name string) ParamConfig {
+	if p := q.Params[name]; p != nil {
+		return *p
+	}
+	return ParamConfig{}
+}
+
+// Render returns the statement of the query, with the placeholders of its
+// parameters, and their values converted to their types. Parameters without
+// value are set to their default. placeholder returns the placeholder of the
+// n'th (starting at 1) parameter of the statement.
+func (q *QueryConfig) Render(values map[string]string, placeholder func(int) string) (string, []interface{}, error) {
+	nodes, err := q.nodes()
+	if err != nil {
+		return "", nil, err
+	}
+	names, _ := q.ParamNames()
+	for name := range values {
+		if !contains(names, name) {
+			return "", nil, fmt.Errorf("unknown parameter %q", name)
+		}
+	}
+	var sb strings.Builder
+	var args []interface{}
+	for _, n := range nodes {
+		if !n.param {
+			sb.WriteString(n.s)
+			continue
+		}
+		p := q.Param(n.s)
+		v, ok := values[n.s]
+		switch {
+		case !ok && p.Default == nil:
+			return "", nil, fmt.Errorf("missing value for parameter %q", n.s)
+		case !ok:
+			v = *p.Default
+		}
+		arg, err := p.convert(v)
+		if err != nil {
+			return "", nil, fmt.Errorf("parameter %q: %w", n.s, err)
+		}
+		args = append(args, arg)
+		sb.WriteString(placeholder(len(args)))
+	}
+	return sb.String(), args, nil
+}
+
+// convert converts the value to the type of the parameter.
+func (p ParamConfig) convert(v string) (interface{}, error) {
+	var arg interface{}
+	var err error
+	switch p.Type {
+	case "", "string":
+		return v, nil
+	case "int":
+		arg, err = strconv.ParseInt(v, 10, 64)
+	case "float":
+		arg, err = strconv.ParseFloat(v, 64)
+	case "bool":
+		arg, err = strconv.ParseBool(v)
+	case "date":
+		arg, err = time.Parse("2006-01-02", v)
+	case "timestamp":
+		arg, err = time.Parse(time.RFC3339, v)
+	default:
+		return nil, fmt.Errorf("invalid type %q", p.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s value %q", p.Type, v)
+	}
+	return arg, nil
+}
+
+// node is a text or a parameter of a query template.
+type node struct {
+	s     string
+	param bool
+}
+
+// nodes parses the query template, which may only contain {{.NAME}} actions.
+func (q *QueryConfig) nodes() ([]node, error) {
+	t, err := template.New("query").Parse(q.SQL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid query template: %w", err)
+	}
+	if t.Tree == nil {
+		return nil, nil
+	}
+	var nodes []node
+	for _, n := range t.Tree.Root.Nodes {
+		switch x := n.(type) {
+		case *parse.TextNode:
+			nodes = append(nodes, node{s: string(x.Text)})
+		case *parse.ActionNode:
+			name, ok := paramName(x)
+			if !ok {
+				return nil, fmt.Errorf("invalid query template action %s: only {{.NAME}} parameters are supported", x)
+			}
+			nodes = append(nodes, node{s: name, param: true})
+		default:
+			return nil, fmt.Errorf("invalid query template action %s: only {{.NAME}} parameters are supported", n)
+		}
+	}
+	return nodes, nil
+}
+
+// paramName returns the name of the parameter of a {{.NAME}} action.
+func paramName(n *parse.ActionNode) (string, bool) {
+	if len(n.Pipe.Decl) != 0 || len(n.Pipe.Cmds) != 1 || len(n.Pipe.Cmds[0].Args) != 1 {
+		return "", false
+	}
+	f, ok := n.Pipe.Cmds[0].Args[0].(*parse.FieldNode)
+	if !ok || len(f.Ident) != 1 {
+		return "", false
+	}
+	return f.Ident[0], true
+}
+
+// contains returns true when v contains s.
+func contains(v []string, s string) bool {
+	for _, z := range v {
+		if z == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+)
+
+const testQueries = `
+queries:
+  signups:
+    description: daily signups
+    sql: select count(*) from users where created_at >= {{.start}} and created_at < {{.end}} and plan = {{ .plan }} and created_at >= {{.start}}
+    params:
+      start:
+        type: date
+      end:
+        type: date
+        default: 2024-02-01
+      plan:
+        default: free
+  invalid:
+    sql: select {{if .a}}1{{end}}
+`
+
+func TestQuery(t *testing.T) {
+	c, err := Parse("/tmp/.dbconfig.yaml", []byte(testQueries))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if names, exp := c.QueryNames(), []string{"invalid", "signups"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected %v, got: %v", exp, names)
+	}
+	if _, err := c.Query("missing"); err == nil {
+		t.Errorf("expected error for unknown query, got nil")
+	}
+	q, err := c.Query("signups")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	names, err := q.ParamNames()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := []string{"start", "end", "plan"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected %v, got: %v", exp, names)
+	}
+	placeholder := func(n int) string { return "$" + strconv.Itoa(n) }
+	sqlstr, args, err := q.Render(map[string]string{"start": "2024-01-01"}, placeholder)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := "select count(*) from users where created_at >= $1 and created_at < $2 and plan = $3 and created_at >= $4"; sqlstr != exp {
+		t.Errorf("expected %q, got: %q", exp, sqlstr)
+	}
+	start, end := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	if exp := []interface{}{start, end, "free", start}; !reflect.DeepEqual(args, exp) {
+		t.Errorf("expected %v, got: %v", exp, args)
+	}
+	tests := []struct {
+		values map[string]string
+		exp    string
+	}{
+		{nil, `missing value for parameter "start"`},
+		{map[string]string{"start": "yesterday"}, `parameter "start": invalid date value "yesterday"`},
+		{map[string]string{"start": "2024-01-01", "other": "1"}, `unknown parameter "other"`},
+	}
+	for i, test := range tests {
+		if _, _, err := q.Render(test.values, placeholder); err == nil || err.Error() != test.exp {
+			t.Errorf("test %d expected error %q, got: %v", i, test.exp, err)
+		}
+	}
+	if _, err := c.Queries["invalid"].ParamNames(); err == nil {
+		t.Errorf("expected error for invalid template, got nil")
+	}
+}
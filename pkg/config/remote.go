@@ -0,0 +1,175 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// RemotePrefix is the prefix of the paths of config files served over HTTPS
+// (https://HOST/PATH). Remote config files are cached locally (see
+// loadRemote).
+const RemotePrefix = "https://"
+
+// Environment variables of remote config files.
+const (
+	// RemoteAuthVar is the environment variable of the Authorization header
+	// sent with the requests of remote config files, such as Bearer TOKEN.
+	RemoteAuthVar = "USQL_DB_CONFIG_AUTH"
+	// RemoteTTLVar is the environment variable of the duration the remote
+	// config files are cached for, such as 15m (default 1h).
+	RemoteTTLVar = "USQL_DB_CONFIG_TTL"
+	// RemotePublicKeyVar is the environment variable of the base64 encoded
+	// ed25519 public key verifying the signatures of remote config files.
+	RemotePublicKeyVar = "USQL_DB_CONFIG_PUBLIC_KEY"
+)
+
+// remoteTTL is the default duration remote config files are cached for.
+const remoteTTL = time.Hour
+
+// remoteClient is the HTTP client of remote config files.
+var remoteClient = &http.Client{Timeout: 30 * time.Second}
+
+// IsRemote returns true when path is a config file served over HTTPS.
+func IsRemote(path string) bool {
+	return strings.HasPrefix(path, RemotePrefix)
+}
+
+// loadRemote returns the contents of the config file served at path, from
+// the user's cache directory when fetched less than the TTL ago. When the
+// public key is set, the config file is verified against its base64 encoded
+// ed25519 signature, served at path with a .sig suffix. The cached copy is
+// used, however old, when the server can't be reached.
+func loadRemote(path string) ([]byte, error) {
+	ttl := remoteTTL
+	if s := os.Getenv(RemoteTTLVar); s != "" {
+		var err error
+		if ttl, err = time.ParseDuration(s); err != nil {
+			return nil, fmt.Errorf("%s: %w", RemoteTTLVar, err)
+		}
+	}
+	var key ed25519.PublicKey
+	if s := os.Getenv(RemotePublicKeyVar); s != "" {
+		buf, err := base64.StdEncoding.DecodeString(s)
+		if err != nil || len(buf) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%s: invalid ed25519 public key", RemotePublicKeyVar)
+		}
+		key = buf
+	}
+	cache, err := remoteCachePath(path)
+	if err != nil {
+		return nil, err
+	}
+	// fresh cached copy
+	if fi, err := os.Stat(cache); err == nil && time.Since(fi.ModTime()) < ttl {
+		if buf, err := readRemoteCache(cache, key); err == nil {
+			return buf, nil
+		}
+	}
+	buf, sig, err := fetchRemote(path, key != nil)
+	if err != nil {
+		if buf, cerr := readRemoteCache(cache, key); cerr == nil {
+			return buf, nil
+		}
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if key != nil && !ed25519.Verify(key, buf, sig) {
+		return nil, fmt.Errorf("%s: invalid signature", path)
+	}
+	if err := writeRemoteCache(cache, buf, sig); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return buf, nil
+}
+
+// fetchRemote fetches the config file served at path, and its signature when
+// signed is true.
+func fetchRemote(path string, signed bool) ([]byte, []byte, error) {
+	buf, err := remoteGet(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !signed {
+		return buf, nil, nil
+	}
+	s, err := remoteGet(path + ".sig")
+	if err != nil {
+		return nil, nil, fmt.Errorf("signature: %w", err)
+	}
+	sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(s)))
+	if err != nil {
+		return nil, nil, fmt.Errorf("signature: %w", err)
+	}
+	return buf, sig, nil
+}
+
+// remoteGet returns the body of the response of a GET request to urlstr.
+func remoteGet(urlstr string) ([]byte, error) {
+	req, err := http.NewRequest("GET", urlstr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if auth := os.Getenv(RemoteAuthVar); auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	res, err := remoteClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errors.New(res.Status)
+	}
+	return io.ReadAll(io.LimitReader(res.Body, 16<<20))
+}
+
+// remoteCachePath returns the path of the cached copy of the config file
+// served at path.
+func remoteCachePath(path string) (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(path))
+	return filepath.Join(dir, "usql", "configs", hex.EncodeToString(sum[:])+".yaml"), nil
+}
+
+// readRemoteCache reads the cached copy of a config file, verifying its
+// cached signature when key is not nil.
+func readRemoteCache(cache string, key ed25519.PublicKey) ([]byte, error) {
+	buf, err := os.ReadFile(cache)
+	if err != nil || key == nil {
+		return buf, err
+	}
+	sig, err := os.ReadFile(cache + ".sig")
+	if err != nil {
+		return nil, err
+	}
+	if !ed25519.Verify(key, buf, sig) {
+		return nil, errors.New("invalid signature")
+	}
+	return buf, nil
+}
+
+// writeRemoteCache writes the cached copy of a config file, and its
+// signature when not nil.
+func writeRemoteCache(cache string, buf, sig []byte) error {
+	if err := os.MkdirAll(filepath.Dir(cache), 0o700); err != nil {
+		return err
+	}
+	if sig != nil {
+		if err := os.WriteFile(cache+".sig", sig, 0o600); err != nil {
+			return err
+		}
+	}
+	return os.WriteFile(cache, buf, 0o600)
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLoadRemote(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, requests := testConfig, 0
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Header.Get("Authorization") != "Bearer t0k3n" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch req.URL.Path {
+		case "/dbconfig.yaml":
+			requests++
+			_, _ = w.Write([]byte(body))
+		case "/dbconfig.yaml.sig":
+			_, _ = w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, []byte(body)))))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := remoteClient
+	defer func() { remoteClient = client }()
+	remoteClient = srv.Client()
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(RemoteAuthVar, "")
+	t.Setenv(RemoteTTLVar, "")
+	t.Setenv(RemotePublicKeyVar, "")
+	path := srv.URL + "/dbconfig.yaml"
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected an unauthorized error, got: %v", err)
+	}
+	t.Setenv(RemoteAuthVar, "Bearer t0k3n")
+	t.Setenv(RemotePublicKeyVar, base64.StdEncoding.EncodeToString(pub))
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if c.Path != path {
+		t.Errorf("expected path %s, got: %s", path, c.Path)
+	}
+	if _, err := c.Database("app_db"); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+	// cached
+	if _, err := Load(path); err != nil || requests != 1 {
+		t.Fatalf("expected the cached copy, got %d requests, error: %v", requests, err)
+	}
+	// expired, with a tampered signature
+	t.Setenv(RemoteTTLVar, "0s")
+	body = testConfig + "\n# changed\n"
+	priv = ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize))
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("expected an invalid signature error, got: %v", err)
+	}
+	// unreachable, uses the stale copy
+	srv.Close()
+	if _, err := Load(path); err != nil {
+		t.Errorf("expected the stale cached copy, got: %v", err)
+	}
+	if IsRemote("http://host/dbconfig.yaml") || !IsRemote(path) {
+		t.Errorf("expected only https paths to be remote")
+	}
+}
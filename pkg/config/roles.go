@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// inheritRoles merges the fields of the roles that the roles of the databases
+// of the config file buf inherit (inherits: ROLE) in the roles, when they
+// don't set them. Roles inherit the roles of their database, and the roles
+// that they inherit.
+func inheritRoles(buf []byte) ([]byte, error) {
+	if !bytes.Contains(buf, []byte("inherits:")) {
+		return buf, nil
+	}
+	var v map[interface{}]interface{}
+	if err := yaml.Unmarshal(buf, &v); err != nil {
+		return nil, err
+	}
+	dbs, _ := v["databases"].(map[interface{}]interface{})
+	for alias, db := range dbs {
+		m, _ := db.(map[interface{}]interface{})
+		creds, _ := m["credentials"].([]interface{})
+		roles := make(map[string]map[interface{}]interface{}, len(creds))
+		var names []string
+		for _, rc := range creds {
+			if r, ok := rc.(map[interface{}]interface{}); ok {
+				name := fmt.Sprint(r["role"])
+				roles[name], names = r, append(names, name)
+			}
+		}
+		for _, name := range names {
+			if err := inheritRole(roles, name, map[string]bool{}); err != nil {
+				return nil, fmt.Errorf("database %v: %w", alias, err)
+			}
+		}
+	}
+	return yaml.Marshal(v)
+}
+
+// inheritRole merges the fields of the role that the role name inherits in
+// the role, after those of its own inherited role, but its name and default.
+// Resolved roles have their inherits field removed.
+func inheritRole(roles map[string]map[interface{}]interface{}, name string, seen map[string]bool) error {
+	r := roles[name]
+	parent, ok := r["inherits"]
+	if !ok {
+		return nil
+	}
+	if seen[name] {
+		return fmt.Errorf("role %s inherits itself", name)
+	}
+	seen[name] = true
+	p := fmt.Sprint(parent)
+	if _, ok := roles[p]; !ok {
+		return fmt.Errorf("role %s inherits unknown role %s", name, p)
+	}
+	if err := inheritRole(roles, p, seen); err != nil {
+		return err
+	}
+	for k, v := range roles[p] {
+		if _, ok := r[k]; !ok && k != "role" && k != "default" {
+			r[k] = v
+		}
+	}
+	delete(r, "inherits")
+	return nil
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestInheritRoles(t *testing.T) {
+	c, err := Parse("/tmp/.dbconfig.yaml", []byte(`
+databases:
+  app_db:
+    name: app
+    host: localhost
+    db_type: postgres
+    credentials:
+      - role: admin
+        inherits: reader
+        username: admin
+        unmask: false
+      - role: reader
+        inherits: base
+        username: reader
+        unmask: true
+        deny_statements: [drop *]
+      - role: base
+        username: app
+        password: s3cr3t
+        on_connect: [SET search_path TO app]
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db := c.Databases["app_db"]
+	admin, err := db.Role("admin")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := RoleConfig{
+		Username:       "admin",
+		Password:       "s3cr3t",
+		Name:           "admin",
+		OnConnect:      []string{"SET search_path TO app"},
+		DenyStatements: []string{"drop *"},
+	}
+	if !reflect.DeepEqual(admin, exp) {
+		t.Errorf("expected %+v, got: %+v", exp, admin)
+	}
+	if reader, _ := db.Role("reader"); reader.Username != "reader" || !reader.Unmask || reader.Password != "s3cr3t" {
+		t.Errorf("unexpected reader role: %+v", reader)
+	}
+	for _, test := range []struct {
+		roles string
+		err   string
+	}{
+		{"[{role: a, inherits: b}, {role: b, inherits: a}]", "role a inherits itself"},
+		{"[{role: a, inherits: c}]", "role a inherits unknown role c"},
+	} {
+		_, err := Parse("/tmp/.dbconfig.yaml", []byte("databases:\n  x:\n    credentials: "+test.roles+"\n"))
+		if err == nil || !strings.Contains(err.Error(), "database x: "+test.err) {
+			t.Errorf("expected error %q, got: %v", test.err, err)
+		}
+	}
+}
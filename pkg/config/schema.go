@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xo/dburl"
+)
+
+// schemaStmts are the statements setting the default schema of the sessions
+// of the database types, by driver.
+var schemaStmts = map[string]string{
+	"postgres":    "SET search_path TO %s",
+	"cockroachdb": "SET search_path TO %s",
+	"redshift":    "SET search_path TO %s",
+	"mysql":       "USE %s",
+	"clickhouse":  "USE %s",
+	"snowflake":   "USE SCHEMA %s",
+	"trino":       "USE %s",
+	"oracle":      "ALTER SESSION SET CURRENT_SCHEMA = %s",
+	"db2":         "SET SCHEMA %s",
+	"duckdb":      "SET schema = '%s'",
+}
+
+// schemaDriver returns the driver of a database type, as used by
+// schemaStmts.
+func schemaDriver(dbType string) string {
+	switch dbType {
+	case "aurora-postgres":
+		return "postgres"
+	case "aurora-mysql":
+		return "mysql"
+	}
+	if u, err := dburl.Parse(dbType + ":"); err == nil {
+		return u.Unaliased
+	}
+	return dbType
+}
+
+// SchemaStatement returns the statement setting the default schema of the
+// sessions of the database to its schema, or an empty string when not set:
+// the search_path of postgres, cockroachdb and redshift databases (which
+// may be a list of schemas, such as app, public), the current database of
+// mysql and clickhouse databases, and the current schema of snowflake,
+// trino, oracle, db2 and duckdb databases.
+func (dc *DatabaseConfig) SchemaStatement() (string, error) {
+	if dc.Schema == "" {
+		return "", nil
+	}
+	stmt, ok := schemaStmts[schemaDriver(dc.DbType)]
+	if !ok {
+		return "", fmt.Errorf("the schema of %s databases can't be set", dc.DbType)
+	}
+	if strings.ContainsAny(dc.Schema, ";'") {
+		return "", fmt.Errorf("invalid schema %q", dc.Schema)
+	}
+	return fmt.Sprintf(stmt, dc.Schema), nil
+}
@@ -0,0 +1,37 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSchemaStatement(t *testing.T) {
+	tests := []struct {
+		typ, schema, exp string
+	}{
+		{"postgres", "app, public", "SET search_path TO app, public"},
+		{"aurora-postgres", "app", "SET search_path TO app"},
+		{"my", "shop", "USE shop"},
+		{"oracle", "HR", "ALTER SESSION SET CURRENT_SCHEMA = HR"},
+		{"snowflake", "analytics", "USE SCHEMA analytics"},
+		{"mysql", "", ""},
+	}
+	for i, test := range tests {
+		db := &DatabaseConfig{DbType: test.typ, Schema: test.schema}
+		stmt, err := db.SchemaStatement()
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if stmt != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, stmt)
+		}
+	}
+	db := &DatabaseConfig{DbType: "postgres", Schema: "app", OnConnect: []string{"SET TIME ZONE 'UTC'"}}
+	if stmts, exp := db.OnConnectStatements(""), []string{"SET search_path TO app", "SET TIME ZONE 'UTC'"}; !reflect.DeepEqual(stmts, exp) {
+		t.Errorf("expected %v, got: %v", exp, stmts)
+	}
+	if _, err := Parse("dbconfig.yaml", []byte("databases:\n  app:\n    db_type: sqlite3\n    name: app.db\n    schema: main\n")); err == nil || !strings.Contains(err.Error(), "can't be set") {
+		t.Errorf("expected an unsupported schema error, got: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/xo/usql/logging"
+	"github.com/xo/usql/redact"
+)
+
+// Store is a config file, loaded on first use and kept until reloaded. It is
+// safe for concurrent use.
+type Store struct {
+	// path is the path passed to Discover.
+	path string
+	mu   sync.RWMutex
+	c    *Config
+	// Init initializes the loaded configs before they are used, when set,
+	// failing to load them on error.
+	Init func(*Config) error
+}
+
+// NewStore creates a store of the config file at path, or of the discovered
+// config file when path is empty (see Discover).
+func NewStore(path string) *Store {
+	return &Store{path: path}
+}
+
+// Config returns the config, loading it on first use. The returned config
+// must not be modified, and is not changed by Reload.
+func (s *Store) Config() (*Config, error) {
+	s.mu.RLock()
+	c := s.c
+	s.mu.RUnlock()
+	if c != nil {
+		return c, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.c != nil {
+		return s.c, nil
+	}
+	return s.load()
+}
+
+// Reload loads the config file again, discovering it again when no path was
+// passed to NewStore. On error, the previously loaded config is kept.
+func (s *Store) Reload() (*Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// load loads the config file. s.mu must be held.
+func (s *Store) load() (*Config, error) {
+	path, err := Discover(s.path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Load(path)
+	if err != nil {
+		return nil, err
+	}
+	if s.Init != nil {
+		if err := s.Init(c); err != nil {
+			return nil, err
+		}
+	}
+	if len(c.Discover) != 0 {
+		if err := c.DiscoverDatabases(context.Background()); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: %v\n", redact.Error(err))
+		}
+	}
+	logging.Verbosef("config file %s: %d databases", c.Path, len(c.Databases))
+	s.c = c
+	return c, nil
+}
@@ -0,0 +1,103 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFilename)
+	write := func(s string) {
+		if err := os.WriteFile(path, []byte(s), 0o600); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	write("databases:\n  a_db:\n    db_type: postgres\n")
+	s := NewStore(path)
+	var wg sync.WaitGroup
+	configs := make([]*Config, 8)
+	for i := range configs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			c, err := s.Config()
+			if err != nil {
+				t.Errorf("expected no error, got: %v", err)
+			}
+			configs[i] = c
+		}(i)
+	}
+	wg.Wait()
+	for i, c := range configs {
+		if c != configs[0] {
+			t.Errorf("test %d expected the config to be loaded once", i)
+		}
+	}
+	// not re-read until reloaded
+	write("databases:\n  a_db:\n    db_type: postgres\n  b_db:\n    db_type: mysql\n")
+	c, err := s.Config()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if aliases, exp := c.Aliases(), []string{"a_db"}; !reflect.DeepEqual(aliases, exp) {
+		t.Errorf("expected %v, got: %v", exp, aliases)
+	}
+	if c, err = s.Reload(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if aliases, exp := c.Aliases(), []string{"a_db", "b_db"}; !reflect.DeepEqual(aliases, exp) {
+		t.Errorf("expected %v, got: %v", exp, aliases)
+	}
+	// invalid configs are not kept
+	write("databases: [")
+	if _, err := s.Reload(); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if c2, err := s.Config(); err != nil || c2 != c {
+		t.Errorf("expected previous config, got: %v", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), DefaultFilename)
+	if err := os.WriteFile(path, []byte("databases:\n  a_db:\n    db_type: postgres\n"), 0o600); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	s := NewStore(path)
+	if _, err := s.Config(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloaded := make(chan *Config, 1)
+	if err := s.Watch(ctx, func(c *Config, err error) {
+		if err != nil {
+			t.Errorf("expected no error, got: %v", err)
+			return
+		}
+		reloaded <- c
+	}); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// replace the file, as editors do
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte("databases:\n  b_db:\n    db_type: mysql\n"), 0o600); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	select {
+	case c := <-reloaded:
+		if aliases, exp := c.Aliases(), []string{"b_db"}; !reflect.DeepEqual(aliases, exp) {
+			t.Errorf("expected %v, got: %v", exp, aliases)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected config to be reloaded")
+	}
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// NameToken is the token of the values of the templates of the config file
+// replaced by the alias of the databases using them.
+const NameToken = "{name}"
+
+// applyTemplates merges the templates of the config file buf (templates:) in
+// the databases using them (template: NAME), when they don't set their
+// fields. The options of the databases, such as their clickhouse options, are
+// merged with those of the template, and the {name} tokens of the values of
+// the template are replaced by the alias of the database.
+func applyTemplates(buf []byte) ([]byte, error) {
+	if !bytes.Contains(buf, []byte("template:")) {
+		return buf, nil
+	}
+	var v map[interface{}]interface{}
+	if err := yaml.Unmarshal(buf, &v); err != nil {
+		return nil, err
+	}
+	templates, _ := v["templates"].(map[interface{}]interface{})
+	dbs, _ := v["databases"].(map[interface{}]interface{})
+	for alias, db := range dbs {
+		m, ok := db.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := m["template"]
+		if !ok {
+			continue
+		}
+		t, ok := templates[name].(map[interface{}]interface{})
+		if !ok {
+			return nil, fmt.Errorf("database %v: unknown template %v", alias, name)
+		}
+		delete(m, "template")
+		merge(m, replaceName(t, fmt.Sprint(alias)).(map[interface{}]interface{}))
+	}
+	delete(v, "templates")
+	return yaml.Marshal(v)
+}
+
+// merge merges the fields of src missing from dst in dst, merging their
+// fields when both are maps.
+func merge(dst, src map[interface{}]interface{}) {
+	for k, v := range src {
+		d, ok := dst[k]
+		if !ok {
+			dst[k] = v
+			continue
+		}
+		dm, ok1 := d.(map[interface{}]interface{})
+		sm, ok2 := v.(map[interface{}]interface{})
+		if ok1 && ok2 {
+			merge(dm, sm)
+		}
+	}
+}
+
+// replaceName returns a copy of v, with the {name} tokens of its strings
+// replaced by name.
+func replaceName(v interface{}, name string) interface{} {
+	switch x := v.(type) {
+	case string:
+		return strings.ReplaceAll(x, NameToken, name)
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for i := range x {
+			s[i] = replaceName(x[i], name)
+		}
+		return s
+	case map[interface{}]interface{}:
+		m := make(map[interface{}]interface{}, len(x))
+		for k, v := range x {
+			m[k] = replaceName(v, name)
+		}
+		return m
+	}
+	return v
+}
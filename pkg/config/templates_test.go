@@ -0,0 +1,59 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestApplyTemplates(t *testing.T) {
+	c, err := Parse("/tmp/.dbconfig.yaml", []byte(`
+templates:
+  rds-mysql:
+    name: "{name}"
+    host: "{name}.abc123.us-east-1.rds.amazonaws.com:3306"
+    db_type: mysql
+    max_connections: 4
+    clickhouse:
+      secure: true
+      compress: true
+    credentials:
+      - role: reader
+        username: "{name}_reader"
+        password_secret: "vault:tenants/{name}#reader"
+      - role: admin
+        inherits: reader
+        username: "{name}_admin"
+databases:
+  tenant1:
+    template: rds-mysql
+  tenant2:
+    template: rds-mysql
+    name: tenant2_prod
+    max_connections: 8
+    clickhouse:
+      compress: false
+`))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	t1, t2 := c.Databases["tenant1"], c.Databases["tenant2"]
+	if t1.Name != "tenant1" || t1.Host != "tenant1.abc123.us-east-1.rds.amazonaws.com:3306" || t1.DbType != "mysql" || t1.MaxConnections != 4 {
+		t.Errorf("unexpected tenant1 config: %+v", t1)
+	}
+	if t2.Name != "tenant2_prod" || t2.Host != "tenant2.abc123.us-east-1.rds.amazonaws.com:3306" || t2.MaxConnections != 8 {
+		t.Errorf("unexpected tenant2 config: %+v", t2)
+	}
+	if o := t2.ClickHouse; o == nil || !o.Secure || o.Compress {
+		t.Errorf("unexpected tenant2 clickhouse options: %+v", o)
+	}
+	admin, err := t2.Role("admin")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if admin.Username != "tenant2_admin" || admin.PasswordSecret != "vault:tenants/tenant2#reader" {
+		t.Errorf("unexpected tenant2 admin role: %+v", admin)
+	}
+	if _, err := Parse("/tmp/.dbconfig.yaml", []byte("databases:\n  x:\n    template: missing\n")); err == nil || !strings.Contains(err.Error(), "database x: unknown template missing") {
+		t.Errorf("expected unknown template error, got: %v", err)
+	}
+}
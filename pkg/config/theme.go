@@ -0,0 +1,63 @@
+package config
+
+import (
+	"fmt"
+
+	"github.com/xo/usql/theme"
+)
+
+// ThemeConfig is the config of a color theme, whose colors are parsed by
+// theme.ParseColor, such as "bold red" or "#ff8800".
+type ThemeConfig struct {
+	// Prompt is the color of the prompt.
+	Prompt string `yaml:"prompt,omitempty"`
+	// Header is the color of the column names of the results.
+	Header string `yaml:"header,omitempty"`
+	// Null is the color of the NULL values of the results.
+	Null string `yaml:"null,omitempty"`
+	// Error is the color of the errors.
+	Error string `yaml:"error,omitempty"`
+}
+
+// ColorTheme returns the color theme of the interactive sessions: the theme
+// of the config file, one of its themes or a builtin theme (see theme.Names).
+// Sessions are not colored when the config file has no theme.
+func (c *Config) ColorTheme() (theme.Theme, error) {
+	tc := c.Themes[c.Theme]
+	if tc == nil {
+		if c.Theme == "" {
+			return theme.Theme{}, nil
+		}
+		t, ok := theme.Builtin(c.Theme)
+		if !ok {
+			return theme.Theme{}, fmt.Errorf("unknown theme %q", c.Theme)
+		}
+		return t, nil
+	}
+	var t theme.Theme
+	for _, z := range []struct {
+		dest  *theme.Color
+		color string
+	}{
+		{&t.Prompt, tc.Prompt},
+		{&t.Header, tc.Header},
+		{&t.Null, tc.Null},
+		{&t.Error, tc.Error},
+	} {
+		var err error
+		if *z.dest, err = theme.ParseColor(z.color); err != nil {
+			return theme.Theme{}, fmt.Errorf("theme %s: %w", c.Theme, err)
+		}
+	}
+	return t, nil
+}
+
+// PromptColor returns the color of the prompt of the interactive sessions on
+// the database, overriding the color of the theme when not empty.
+func (dc *DatabaseConfig) PromptColor() (theme.Color, error) {
+	color, err := theme.ParseColor(dc.Color)
+	if err != nil {
+		return "", fmt.Errorf("color: %w", err)
+	}
+	return color, nil
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xo/usql/theme"
+)
+
+func TestColorTheme(t *testing.T) {
+	c := &Config{}
+	if th, err := c.ColorTheme(); err != nil || th != (theme.Theme{}) {
+		t.Errorf("expected no theme, got: %v %v", th, err)
+	}
+	c.Theme = "dark"
+	exp, _ := theme.Builtin("dark")
+	if th, err := c.ColorTheme(); err != nil || th != exp {
+		t.Errorf("expected %v, got: %v %v", exp, th, err)
+	}
+	c.Theme, c.Themes = "custom", map[string]*ThemeConfig{
+		"custom": {Prompt: "bold green", Null: "gray", Error: "#ff0000"},
+	}
+	th, err := c.ColorTheme()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := (theme.Theme{Prompt: "1;32", Null: "90", Error: "38;2;255;0;0"}); th != exp {
+		t.Errorf("expected %v, got: %v", exp, th)
+	}
+	c.Themes["custom"].Header = "purple"
+	if _, err := c.ColorTheme(); err == nil || !strings.Contains(err.Error(), `theme custom: invalid color "purple"`) {
+		t.Errorf("expected invalid color error, got: %v", err)
+	}
+	c.Theme = "missing"
+	if _, err := c.ColorTheme(); err == nil {
+		t.Errorf("expected unknown theme error")
+	}
+}
+
+func TestPromptColor(t *testing.T) {
+	if color, err := (&DatabaseConfig{Color: "bold red"}).PromptColor(); err != nil || color != "1;31" {
+		t.Errorf("expected 1;31, got: %q %v", color, err)
+	}
+	if color, err := (&DatabaseConfig{}).PromptColor(); err != nil || color != "" {
+		t.Errorf("expected no color, got: %q %v", color, err)
+	}
+	if _, err := (&DatabaseConfig{Color: "prod"}).PromptColor(); err == nil {
+		t.Errorf("expected invalid color error")
+	}
+}
+
+func TestParseColorTheme(t *testing.T) {
+	_, err := Parse("test.yaml", []byte("theme: nope\n"))
+	if err == nil || !strings.Contains(err.Error(), `unknown theme "nope"`) {
+		t.Errorf("expected unknown theme error, got: %v", err)
+	}
+	_, err = Parse("test.yaml", []byte("databases:\n  prod:\n    db_type: postgres\n    color: flashy\n"))
+	if err == nil || !strings.Contains(err.Error(), "database prod: color:") {
+		t.Errorf("expected invalid color error, got: %v", err)
+	}
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/xo/dburl"
+)
+
+// Connection formats of external tools.
+const (
+	// ToolPgcli is the postgresql:// URL of pgcli.
+	ToolPgcli = "pgcli"
+	// ToolDataGrip is the URL, user and password fields of DataGrip's data
+	// sources.
+	ToolDataGrip = "datagrip"
+	// ToolJDBC is the JDBC URL, with the credentials as its parameters.
+	ToolJDBC = "jdbc"
+	// ToolSQLAlchemy is the SQLAlchemy engine URL.
+	ToolSQLAlchemy = "sqlalchemy"
+)
+
+// ToolFormats are the connection formats of external tools.
+var ToolFormats = []string{ToolPgcli, ToolDataGrip, ToolJDBC, ToolSQLAlchemy}
+
+// jdbcSchemes are the JDBC URL schemes, by driver.
+var jdbcSchemes = map[string]string{
+	"postgres":    "jdbc:postgresql",
+	"cockroachdb": "jdbc:postgresql",
+	"redshift":    "jdbc:redshift",
+	"mysql":       "jdbc:mysql",
+	"clickhouse":  "jdbc:clickhouse",
+	"sqlserver":   "jdbc:sqlserver",
+	"oracle":      "jdbc:oracle:thin",
+	"sqlite3":     "jdbc:sqlite",
+	"duckdb":      "jdbc:duckdb",
+}
+
+// sqlalchemySchemes are the SQLAlchemy dialects, by driver.
+var sqlalchemySchemes = map[string]string{
+	"postgres":    "postgresql",
+	"cockroachdb": "cockroachdb",
+	"redshift":    "redshift+psycopg2",
+	"mysql":       "mysql+pymysql",
+	"clickhouse":  "clickhouse",
+	"sqlserver":   "mssql+pymssql",
+	"sqlite3":     "sqlite",
+	"duckdb":      "duckdb",
+}
+
+// toolConn is the connection info of a database for an external tool.
+type toolConn struct {
+	driver, host, name, username, password, query string
+	// descriptor is the connect descriptor of oracle databases.
+	descriptor string
+}
+
+// ToolDSN returns the connection info of the role of the alias in the format
+// of an external tool (see ToolFormats), so that the config file is the
+// single source of truth of the connections. The password is only included
+// when withPassword is true.
+func (c *Config) ToolDSN(tool, alias, role string, withPassword bool) (string, error) {
+	dsn, err := c.DSN(alias, role)
+	if err != nil {
+		return "", err
+	}
+	u, err := dburl.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	conn := toolConn{driver: u.Unaliased, host: u.Host, name: strings.TrimPrefix(u.Path, "/"), query: u.RawQuery}
+	q := u.Query()
+	switch {
+	case fileDbTypes[conn.driver]:
+		if conn.name = u.Opaque; conn.name == "" {
+			conn.name = u.Path
+		}
+	case conn.driver == "sqlserver":
+		conn.name = q.Get("database")
+	case conn.driver == "oracle":
+		conn.descriptor = q.Get("connStr")
+	}
+	if u.User != nil {
+		conn.username = u.User.Username()
+		if password, ok := u.User.Password(); ok && withPassword {
+			conn.password = password
+		}
+	}
+	var s string
+	var ok bool
+	switch tool {
+	case ToolPgcli:
+		s, ok = conn.pgcli()
+	case ToolDataGrip:
+		s, ok = conn.datagrip()
+	case ToolJDBC:
+		s, ok = conn.jdbc(true)
+	case ToolSQLAlchemy:
+		s, ok = conn.sqlalchemy()
+	default:
+		return "", fmt.Errorf("invalid connection format %q", tool)
+	}
+	if !ok {
+		return "", fmt.Errorf("the %s connection format doesn't support %s databases", tool, conn.driver)
+	}
+	return s, nil
+}
+
+// userinfo returns the userinfo of the URL of the connection.
+func (conn toolConn) userinfo() *url.Userinfo {
+	switch {
+	case conn.password != "":
+		return url.UserPassword(conn.username, conn.password)
+	case conn.username != "":
+		return url.User(conn.username)
+	}
+	return nil
+}
+
+// pgcli returns the postgresql:// URL of a PostgreSQL compatible database,
+// with the parameters of its DSN.
+func (conn toolConn) pgcli() (string, bool) {
+	switch conn.driver {
+	case "postgres", "cockroachdb", "redshift":
+	default:
+		return "", false
+	}
+	u := url.URL{Scheme: "postgresql", User: conn.userinfo(), Host: conn.host, Path: "/" + conn.name, RawQuery: conn.query}
+	return u.String(), true
+}
+
+// datagrip returns the URL, user and password fields of a DataGrip data
+// source.
+func (conn toolConn) datagrip() (string, bool) {
+	urlstr, ok := conn.jdbc(false)
+	if !ok {
+		return "", false
+	}
+	s := "URL: " + urlstr + "\n"
+	if conn.username != "" {
+		s += "User: " + conn.username + "\n"
+	}
+	if conn.password != "" {
+		s += "Password: " + conn.password + "\n"
+	}
+	return strings.TrimSuffix(s, "\n"), true
+}
+
+// jdbc returns the JDBC URL of the connection, with its credentials when
+// creds is true.
+func (conn toolConn) jdbc(creds bool) (string, bool) {
+	scheme, ok := jdbcSchemes[conn.driver]
+	if !ok {
+		return "", false
+	}
+	if !creds {
+		conn.username, conn.password = "", ""
+	}
+	switch conn.driver {
+	case "sqlite3", "duckdb":
+		return scheme + ":" + conn.name, true
+	case "sqlserver":
+		s := scheme + "://" + conn.host
+		for _, p := range [][2]string{{"databaseName", conn.name}, {"user", conn.username}, {"password", conn.password}} {
+			if p[1] != "" {
+				s += ";" + p[0] + "=" + p[1]
+			}
+		}
+		return s, true
+	case "oracle":
+		s := scheme + ":"
+		if conn.username != "" {
+			s += conn.username
+		}
+		if conn.password != "" {
+			s += "/" + conn.password
+		}
+		return s + "@" + conn.descriptor, true
+	}
+	v := make(url.Values)
+	if conn.username != "" {
+		v.Set("user", conn.username)
+	}
+	if conn.password != "" {
+		v.Set("password", conn.password)
+	}
+	s := scheme + "://" + conn.host + "/" + url.PathEscape(conn.name)
+	if len(v) != 0 {
+		s += "?" + v.Encode()
+	}
+	return s, true
+}
+
+// sqlalchemy returns the SQLAlchemy engine URL of the connection.
+func (conn toolConn) sqlalchemy() (string, bool) {
+	scheme, ok := sqlalchemySchemes[conn.driver]
+	if !ok {
+		return "", false
+	}
+	if fileDbTypes[conn.driver] {
+		// sqlite:///relative.db, sqlite:////absolute.db
+		return scheme + ":///" + conn.name, true
+	}
+	u := url.URL{Scheme: scheme, User: conn.userinfo(), Host: conn.host, Path: "/" + conn.name}
+	return u.String(), true
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDelay is the delay before reloading the config file after a change,
+// so that the multiple events of a write are handled once.
+const watchDelay = 100 * time.Millisecond
+
+// Watch reloads the config file when it changes, until ctx is done, calling
+// f with the reloaded config, or the error when it could not be reloaded.
+// The config must have been loaded. Config files stored in Kubernetes or
+// remotely are not watched.
+func (s *Store) Watch(ctx context.Context, f func(*Config, error)) error {
+	s.mu.RLock()
+	c := s.c
+	s.mu.RUnlock()
+	if c == nil {
+		return errors.New("config file not loaded")
+	}
+	if IsK8s(c.Path) || IsRemote(c.Path) {
+		return nil
+	}
+	w, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	// editors often replace the file, so watch its directory
+	if err := w.Add(filepath.Dir(c.Path)); err != nil {
+		w.Close()
+		return err
+	}
+	go func() {
+		defer w.Close()
+		var timer <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case ev, ok := <-w.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(ev.Name) == c.Path && ev.Op != fsnotify.Chmod {
+					timer = time.After(watchDelay)
+				}
+			case err, ok := <-w.Errors:
+				if !ok {
+					return
+				}
+				f(nil, err)
+			case <-timer:
+				timer = nil
+				f(s.Reload())
+			}
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,127 @@
+// Package conn opens connections to the database aliases of a config file.
+package conn
+
+import (
+	"context"
+	"database/sql"
+	"io"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/logging"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/tracing"
+)
+
+// Opener opens connections to the database aliases of a config.
+type Opener struct {
+	// Config is the config of the database aliases.
+	Config *config.Config
+	// Stdout and Stderr are the outputs of the drivers and of the connection
+	// retry messages. Output is discarded when nil.
+	Stdout, Stderr io.Writer
+}
+
+// URL returns the URL of the database alias, using the credentials of the
+// role.
+func (o *Opener) URL(alias, role string) (*dburl.URL, error) {
+	dsn, err := o.Config.DSN(alias, role)
+	if err != nil {
+		return nil, err
+	}
+	return parseURL(dsn, o.Config.ApplicationName(alias))
+}
+
+// parseURL parses the DSN, forcing the parameters of its driver and setting
+// the application name of its connections.
+func parseURL(dsn, appName string) (*dburl.URL, error) {
+	u, err := dburl.Parse(dsn)
+	if err != nil {
+		return nil, err
+	}
+	drivers.ForceParams(u)
+	drivers.SetApplicationName(u, appName)
+	return dburl.Parse(u.String())
+}
+
+// Open opens a connection to the database alias, using the credentials of
+// the role. The connection is retried and the on_connect statements are
+// executed on each connection of the pool, as for interactive sessions. The connection pool is sized by the
+// max_connections, max_idle_connections and idle_timeout of the alias, and
+// pinged every keepalive_interval.
+func (o *Opener) Open(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error) {
+	return o.open(ctx, alias, role, false)
+}
+
+// OpenReader opens a connection to the reader host of the database alias
+// (see config.Config.ReaderDSN), as Open.
+func (o *Opener) OpenReader(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error) {
+	return o.open(ctx, alias, role, true)
+}
+
+// open opens a connection to the database alias, or to its reader host when
+// reader is set.
+func (o *Opener) open(ctx context.Context, alias, role string, reader bool) (_ *dburl.URL, _ *sql.DB, err error) {
+	ctx, span := tracing.Start(ctx, "connect", tracing.AliasKey.String(alias))
+	defer func() { tracing.End(span, err) }()
+	dsnf := o.Config.DSN
+	if reader {
+		dsnf = o.Config.ReaderDSN
+	}
+	dsn, err := dsnf(alias, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	dbConfig, err := o.Config.Database(alias)
+	if err != nil {
+		return nil, nil, err
+	}
+	if dsn, err = ProxyDSN(dbConfig, dsn); err != nil {
+		return nil, nil, err
+	}
+	u, err := parseURL(dsn, o.Config.ApplicationName(alias))
+	if err != nil {
+		return nil, nil, err
+	}
+	logging.Verbosef("connecting to %s with role %q: %s", alias, role, u.Redacted())
+	span.SetAttributes(tracing.Attrs(u, "", dbConfig.DbType)...)
+	stdout := func() io.Writer { return writer(o.Stdout) }
+	stderr := func() io.Writer { return writer(o.Stderr) }
+	// the statements are executed on each connection of the pool
+	init := dbConfig.OnConnectStatements(role)
+	var db *sql.DB
+	err = Retry(ctx, dbConfig, o.Stderr, func() error {
+		var err error
+		if db, err = drivers.Open(u, stdout, stderr, init...); err != nil {
+			return err
+		}
+		if err = drivers.Ping(ctx, u, db); err != nil {
+			db.Close()
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if dbConfig.MaxConnections > 0 {
+		db.SetMaxOpenConns(dbConfig.MaxConnections)
+	}
+	if dbConfig.MaxIdleConnections > 0 {
+		db.SetMaxIdleConns(dbConfig.MaxIdleConnections)
+	}
+	if dbConfig.IdleTimeout > 0 {
+		db.SetConnMaxIdleTime(dbConfig.IdleTimeout)
+	}
+	if dbConfig.KeepaliveInterval > 0 {
+		go Keepalive(context.Background(), db, dbConfig.KeepaliveInterval)
+	}
+	return u, db, nil
+}
+
+// writer returns w, or a writer discarding output when nil.
+func writer(w io.Writer) io.Writer {
+	if w == nil {
+		return io.Discard
+	}
+	return w
+}
@@ -0,0 +1,49 @@
+package conn
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/xo/usql/drivers/sqlite3"
+	"github.com/xo/usql/pkg/config"
+)
+
+func TestOpenOnConnect(t *testing.T) {
+	// temporary tables are only visible to the connection creating them
+	buf := "databases:\n  app:\n    db_type: sqlite3\n    name: " + filepath.Join(t.TempDir(), "app.db") + "\n    on_connect:\n      - CREATE TEMP TABLE session (a int)\n"
+	cfg, err := config.Parse("dbconfig.yaml", []byte(buf))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	ctx := context.Background()
+	_, db, err := (&Opener{Config: cfg}).Open(ctx, "app", "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	// check the connections opened concurrently, and reopened after being
+	// closed, as when idle
+	for i := 0; i < 2; i++ {
+		c1, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		c2, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		if _, err := c1.ExecContext(ctx, "INSERT INTO session VALUES (1)"); err != nil {
+			t.Errorf("test %d expected the on_connect statements executed on the first connection, got: %v", i, err)
+		}
+		if _, err := c2.ExecContext(ctx, "INSERT INTO session VALUES (2)"); err != nil {
+			t.Errorf("test %d expected the on_connect statements executed on the second connection, got: %v", i, err)
+		}
+		db.SetMaxIdleConns(0)
+		c1.Close()
+		c2.Close()
+	}
+	if n := db.Stats().MaxIdleClosed; n != 4 {
+		t.Errorf("expected 4 closed connections, got: %d", n)
+	}
+}
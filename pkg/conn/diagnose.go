@@ -0,0 +1,226 @@
+package conn
+
+import (
+	"context"
+	"crypto/tls"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+// Diagnosis steps.
+const (
+	StepDNS   = "dns"
+	StepTCP   = "tcp"
+	StepTLS   = "tls"
+	StepAuth  = "auth"
+	StepQuery = "query"
+)
+
+// diagnoseTimeout is the timeout of each diagnosis step.
+const diagnoseTimeout = 10 * time.Second
+
+// defaultPorts are the default ports of the drivers, by unaliased scheme.
+var defaultPorts = map[string]string{
+	"postgres":    "5432",
+	"redshift":    "5439",
+	"cockroachdb": "26257",
+	"mysql":       "3306",
+	"sqlserver":   "1433",
+	"oracle":      "1521",
+	"clickhouse":  "9000",
+}
+
+// Step is the result of a step of the diagnosis of a connection.
+type Step struct {
+	// Name is the name of the step: dns, tcp, tls, auth or query.
+	Name string
+	// Duration is the time the step took.
+	Duration time.Duration
+	// Detail describes what the step found, or why it was skipped.
+	Detail string
+	// Skipped is set when the step does not apply to the database, or when
+	// a previous step failed.
+	Skipped bool
+	// Err is the error of the failed step, with Hint suggesting a fix.
+	Err  error
+	Hint string
+}
+
+// Diagnose checks the connection to the database alias step by step, using
+// the credentials of the role: the resolution of its host, the reachability
+// of its port, the TLS handshake (of PostgreSQL compatible databases), the
+// authentication, and a trivial query. The steps after a failed step are
+// skipped. An error is returned when the DSN of the alias can't be built.
+func (o *Opener) Diagnose(ctx context.Context, alias, role string) ([]Step, error) {
+	u, err := o.URL(alias, role)
+	if err != nil {
+		return nil, err
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = defaultPorts[u.Unaliased]
+	}
+	var db *sql.DB
+	var failed bool
+	steps := []struct {
+		name string
+		f    func(context.Context) (string, bool, error)
+		hint string
+	}{
+		{StepDNS, func(ctx context.Context) (string, bool, error) {
+			if host == "" {
+				return "no host", true, nil
+			}
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return "", false, err
+			}
+			return host + " -> " + strings.Join(addrs, ", "), false, nil
+		}, "check the host of the database, and the DNS or VPN of this machine"},
+		{StepTCP, func(ctx context.Context) (string, bool, error) {
+			switch {
+			case host == "":
+				return "no host", true, nil
+			case port == "":
+				return "unknown port of " + u.Unaliased + " databases", true, nil
+			}
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+			if err != nil {
+				return "", false, err
+			}
+			defer conn.Close()
+			return conn.RemoteAddr().String(), false, nil
+		}, "check the port of the database, that the server is running, and the firewalls between this machine and the server"},
+		{StepTLS, func(ctx context.Context) (string, bool, error) {
+			return postgresTLS(ctx, u, host, port)
+		}, "check the sslmode of the database, and the certificate of the server"},
+		{StepAuth, func(ctx context.Context) (string, bool, error) {
+			var err error
+			if db, err = drivers.Open(u, func() io.Writer { return writer(o.Stdout) }, func() io.Writer { return writer(o.Stderr) }); err != nil {
+				return "", false, err
+			}
+			if err := drivers.Ping(ctx, u, db); err != nil {
+				return "", false, err
+			}
+			if u.User != nil && u.User.Username() != "" {
+				return "as " + u.User.Username(), false, nil
+			}
+			return "", false, nil
+		}, "check the username and password of the role, and that the database exists"},
+		{StepQuery, func(ctx context.Context) (string, bool, error) {
+			var v interface{}
+			if err := db.QueryRowContext(ctx, trivialQuery(u)).Scan(&v); err != nil {
+				return "", false, drivers.WrapErr(u.Driver, err)
+			}
+			ver, _ := drivers.Version(ctx, u, db)
+			return ver, false, nil
+		}, "check the permissions of the role"},
+	}
+	defer func() {
+		if db != nil {
+			db.Close()
+		}
+	}()
+	var res []Step
+	for _, s := range steps {
+		step := Step{Name: s.name}
+		if failed {
+			step.Skipped, step.Detail = true, "a previous step failed"
+			res = append(res, step)
+			continue
+		}
+		ctx, cancel := context.WithTimeout(ctx, diagnoseTimeout)
+		start := time.Now()
+		step.Detail, step.Skipped, step.Err = s.f(ctx)
+		step.Duration = time.Since(start)
+		cancel()
+		if step.Err != nil {
+			step.Hint, failed = s.hint, true
+		}
+		res = append(res, step)
+	}
+	return res, nil
+}
+
+// trivialQuery returns a query returning a single value on the database.
+func trivialQuery(u *dburl.URL) string {
+	switch u.Unaliased {
+	case "oracle":
+		return "SELECT 1 FROM DUAL"
+	case "db2":
+		return "SELECT 1 FROM SYSIBM.SYSDUMMY1"
+	}
+	return "SELECT 1"
+}
+
+// postgresTLS negotiates TLS with a PostgreSQL compatible server, as libpq
+// does with its sslmode: the server is asked to switch to TLS, and the TLS
+// handshake is made when it accepts. The certificate of the server is only
+// verified with sslmode verify-ca and verify-full.
+func postgresTLS(ctx context.Context, u *dburl.URL, host, port string) (string, bool, error) {
+	switch u.Unaliased {
+	case "postgres", "redshift", "cockroachdb":
+	default:
+		return "checked by the " + u.Unaliased + " driver during authentication", true, nil
+	}
+	mode := u.Query().Get("sslmode")
+	switch {
+	case host == "":
+		return "no host", true, nil
+	case mode == "disable":
+		return "sslmode=disable", true, nil
+	case mode == "":
+		mode = "prefer"
+	}
+	conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	if err != nil {
+		return "", false, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// SSLRequest: length 8, code 80877103
+	req := make([]byte, 8)
+	binary.BigEndian.PutUint32(req[0:4], 8)
+	binary.BigEndian.PutUint32(req[4:8], 80877103)
+	if _, err := conn.Write(req); err != nil {
+		return "", false, err
+	}
+	b := make([]byte, 1)
+	if _, err := io.ReadFull(conn, b); err != nil {
+		return "", false, err
+	}
+	switch {
+	case b[0] == 'N' && (mode == "allow" || mode == "prefer"):
+		return "not supported by the server, sslmode=" + mode, false, nil
+	case b[0] == 'N':
+		return "", false, fmt.Errorf("the server doesn't support TLS, required by sslmode=%s", mode)
+	case b[0] != 'S':
+		return "", false, errors.New("unexpected response of the server to the TLS request")
+	}
+	verify := mode == "verify-ca" || mode == "verify-full"
+	c := tls.Client(conn, &tls.Config{ServerName: host, InsecureSkipVerify: !verify})
+	if err := c.HandshakeContext(ctx); err != nil {
+		return "", false, err
+	}
+	state := c.ConnectionState()
+	detail := tls.VersionName(state.Version)
+	if len(state.PeerCertificates) != 0 {
+		cert := state.PeerCertificates[0]
+		detail += fmt.Sprintf(", certificate %s expires %s", cert.Subject.CommonName, cert.NotAfter.Format("2006-01-02"))
+	}
+	if !verify {
+		detail += ", not verified with sslmode=" + mode
+	}
+	return detail, false, nil
+}
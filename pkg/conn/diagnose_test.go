@@ -0,0 +1,69 @@
+package conn
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/xo/usql/pkg/config"
+)
+
+func TestDiagnose(t *testing.T) {
+	// a server refusing the TLS requests, as PostgreSQL without ssl
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				buf := make([]byte, 8)
+				if _, err := io.ReadFull(conn, buf); err == nil {
+					conn.Write([]byte("N"))
+				}
+			}()
+		}
+	}()
+	// ad-hoc connections are diagnosed as aliases
+	prefer, require := "pg://"+ln.Addr().String()+"/app", "pg://"+ln.Addr().String()+"/app?sslmode=require"
+	o := &Opener{Config: &config.Config{}}
+	steps, err := o.Diagnose(context.Background(), require, "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := []struct {
+		name            string
+		failed, skipped bool
+	}{
+		{StepDNS, false, false},
+		{StepTCP, false, false},
+		{StepTLS, true, false},
+		{StepAuth, false, true},
+		{StepQuery, false, true},
+	}
+	if len(steps) != len(exp) {
+		t.Fatalf("expected %d steps, got: %d", len(exp), len(steps))
+	}
+	for i, step := range steps {
+		if step.Name != exp[i].name || (step.Err != nil) != exp[i].failed || step.Skipped != exp[i].skipped {
+			t.Errorf("step %d expected %s (failed: %t, skipped: %t), got: %s (error: %v, skipped: %t)", i, exp[i].name, exp[i].failed, exp[i].skipped, step.Name, step.Err, step.Skipped)
+		}
+	}
+	if steps[2].Hint == "" {
+		t.Errorf("expected a hint for the failed step")
+	}
+	// TLS is optional with the default sslmode
+	if steps, err = o.Diagnose(context.Background(), prefer, ""); err != nil || steps[2].Err != nil {
+		t.Errorf("expected the tls step to pass, got: %v %v", err, steps[2].Err)
+	}
+	if _, err := o.Diagnose(context.Background(), "missing_db", ""); err == nil {
+		t.Errorf("expected error for unknown alias, got nil")
+	}
+}
@@ -0,0 +1,57 @@
+package conn
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+// Keepalive pings db every interval until it's closed or ctx is done, so its
+// idle connections aren't dropped by NAT gateways and firewalls. Pings are
+// skipped while connections are in use, as they are active, and to not open
+// another connection. Failed pings are ignored: the connections are reopened
+// by the next statement.
+func Keepalive(ctx context.Context, db *sql.DB, interval time.Duration) {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+		if db.Stats().InUse != 0 {
+			continue
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, interval)
+		err := db.PingContext(pingCtx)
+		cancel()
+		if errors.Is(err, errClosed) {
+			return
+		}
+	}
+}
+
+// errClosed is the error of the pings of closed databases, which
+// database/sql doesn't export.
+var errClosed = func() error {
+	db := sql.OpenDB(closedConnector{})
+	db.Close()
+	return db.PingContext(context.Background())
+}()
+
+// closedConnector is the connector of the closed database of errClosed,
+// never connecting.
+type closedConnector struct{}
+
+// Connect satisfies the driver.Connector interface.
+func (closedConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, driver.ErrBadConn
+}
+
+// Driver satisfies the driver.Connector interface.
+func (closedConnector) Driver() driver.Driver {
+	return nil
+}
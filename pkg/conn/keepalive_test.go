@@ -0,0 +1,52 @@
+package conn
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestKeepalive(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		Keepalive(context.Background(), db, 5*time.Millisecond)
+		close(done)
+	}()
+	time.Sleep(20 * time.Millisecond)
+	if n := db.Stats().OpenConnections; n != 1 {
+		t.Errorf("expected 1 open connection, got: %d", n)
+	}
+	db.Close()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected keepalive to stop once closed")
+	}
+}
+
+func TestKeepaliveCancel(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		Keepalive(ctx, db, 5*time.Millisecond)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected keepalive to stop once canceled")
+	}
+}
@@ -0,0 +1,94 @@
+package conn
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"sync"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/metrics"
+)
+
+// Pool keeps the connection pools of the database aliases open for the life
+// of a process, reusing them across commands instead of reconnecting.
+type Pool struct {
+	// Open opens a connection to a database alias, using the credentials of
+	// a role.
+	Open func(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error)
+	mu   sync.Mutex
+	dbs  map[poolKey]*pooled
+}
+
+// poolKey is the key of a connection pool.
+type poolKey struct {
+	alias, role string
+}
+
+// pooled is a connection pool.
+type pooled struct {
+	u  *dburl.URL
+	db *sql.DB
+}
+
+// Stats are the stats of the connection pool of a database alias.
+type Stats struct {
+	Alias, Role string
+	sql.DBStats
+}
+
+// Get returns the connection pool of the database alias with the role,
+// opening it on first use. The pool must not be closed by the caller.
+func (p *Pool) Get(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := poolKey{alias, role}
+	if c, ok := p.dbs[key]; ok {
+		return c.u, c.db, nil
+	}
+	u, db, err := p.Open(ctx, alias, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.dbs == nil {
+		p.dbs = make(map[poolKey]*pooled)
+	}
+	metrics.Track(db, alias)
+	p.dbs[key] = &pooled{u: u, db: db}
+	return u, db, nil
+}
+
+// Stats returns the stats of the open connection pools, sorted by alias and
+// role.
+func (p *Pool) Stats() []Stats {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stats := make([]Stats, 0, len(p.dbs))
+	for key, c := range p.dbs {
+		stats = append(stats, Stats{Alias: key.alias, Role: key.role, DBStats: c.db.Stats()})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Alias != stats[j].Alias {
+			return stats[i].Alias < stats[j].Alias
+		}
+		return stats[i].Role < stats[j].Role
+	})
+	return stats
+}
+
+// Close closes the open connection pools. The pool can be used again
+// afterwards, reopening the connection pools, such as after reloading the
+// config file.
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var err error
+	for key, c := range p.dbs {
+		metrics.Untrack(c.db)
+		if e := c.db.Close(); e != nil && err == nil {
+			err = e
+		}
+		delete(p.dbs, key)
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package conn
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+)
+
+func TestPool(t *testing.T) {
+	var opened int
+	p := &Pool{
+		Open: func(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error) {
+			opened++
+			u, err := dburl.Parse("sqlite3::memory:")
+			if err != nil {
+				return nil, nil, err
+			}
+			db, err := sql.Open("sqlite3", ":memory:")
+			return u, db, err
+		},
+	}
+	defer p.Close()
+	ctx := context.Background()
+	for _, key := range [][2]string{{"b", ""}, {"a", "reader"}, {"b", ""}, {"a", ""}} {
+		if _, db, err := p.Get(ctx, key[0], key[1]); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		} else if err := db.Ping(); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	if opened != 3 {
+		t.Errorf("expected 3 opened pools, got: %d", opened)
+	}
+	stats := p.Stats()
+	var keys [][2]string
+	for _, s := range stats {
+		keys = append(keys, [2]string{s.Alias, s.Role})
+	}
+	if exp := [][2]string{{"a", ""}, {"a", "reader"}, {"b", ""}}; !reflect.DeepEqual(keys, exp) {
+		t.Errorf("expected stats of %v, got: %v", exp, keys)
+	}
+	if stats[0].OpenConnections != 1 || stats[0].Idle != 1 {
+		t.Errorf("expected 1 open idle connection, got: %d open, %d idle", stats[0].OpenConnections, stats[0].Idle)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n := len(p.Stats()); n != 0 {
+		t.Errorf("expected no pools after close, got: %d", n)
+	}
+	if _, _, err := p.Get(ctx, "a", ""); err != nil || opened != 4 {
+		t.Errorf("expected the pool to be reopened, got: %d %v", opened, err)
+	}
+}
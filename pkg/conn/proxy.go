@@ -0,0 +1,39 @@
+package conn
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/proxy"
+)
+
+// ProxyDSN returns the DSN of the database routed through its proxy, when
+// set: the host of the DSN is replaced by a local address forwarded to it
+// through the proxy (see proxy.Forward).
+func ProxyDSN(dbConfig *config.DatabaseConfig, dsn string) (string, error) {
+	if dbConfig == nil || dbConfig.Proxy == "" {
+		return dsn, nil
+	}
+	u, err := dburl.Parse(dsn)
+	if err != nil {
+		return "", err
+	}
+	host, port := u.Hostname(), u.Port()
+	if port == "" {
+		port = defaultPorts[u.Unaliased]
+	}
+	switch {
+	case host == "":
+		return "", fmt.Errorf("proxy: %s databases have no host", u.Unaliased)
+	case port == "":
+		return "", fmt.Errorf("proxy: unknown port of %s databases", u.Unaliased)
+	}
+	local, err := proxy.Forward(dbConfig.Proxy, net.JoinHostPort(host, port))
+	if err != nil {
+		return "", err
+	}
+	u.URL.Host = local
+	return u.URL.String(), nil
+}
@@ -0,0 +1,33 @@
+package conn
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/xo/usql/pkg/config"
+)
+
+func TestProxyDSN(t *testing.T) {
+	if dsn, err := ProxyDSN(&config.DatabaseConfig{}, "postgres://db:5432/app"); err != nil || dsn != "postgres://db:5432/app" {
+		t.Errorf("expected the DSN unchanged without proxy, got: %q %v", dsn, err)
+	}
+	dbConfig := &config.DatabaseConfig{Proxy: "socks5://proxy:1080"}
+	dsn, err := ProxyDSN(dbConfig, "postgres://user:pass@db/app?sslmode=disable")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	host, _, err := net.SplitHostPort(strings.TrimSuffix(strings.TrimPrefix(dsn, "postgres://user:pass@"), "/app?sslmode=disable"))
+	if err != nil || host != "127.0.0.1" {
+		t.Errorf("expected a local forwarded address, got: %q %v", dsn, err)
+	}
+	if again, _ := ProxyDSN(dbConfig, "postgres://other:pass@db:5432/app?sslmode=disable"); !strings.HasSuffix(again, strings.TrimPrefix(dsn, "postgres://user:pass")) {
+		t.Errorf("expected the forwarder to be reused, got: %q and %q", dsn, again)
+	}
+	if _, err := ProxyDSN(dbConfig, "sqlite3:/tmp/app.db"); err == nil {
+		t.Errorf("expected error for a database without host, got nil")
+	}
+	if _, err := ProxyDSN(&config.DatabaseConfig{Proxy: "ftp://proxy"}, "mysql://db/app"); err == nil {
+		t.Errorf("expected error for an invalid proxy, got nil")
+	}
+}
@@ -0,0 +1,95 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/xo/usql/pkg/config"
+)
+
+// DefaultRetryBackoff is the delay before the first connection retry, when
+// retry_backoff is not set in the config file.
+const DefaultRetryBackoff = 500 * time.Millisecond
+
+// MaxRetryBackoff caps the delay between two connection attempts.
+const MaxRetryBackoff = 30 * time.Second
+
+// Retry calls f, retrying up to dbConfig.Retries times with exponential
+// backoff while f fails with a transient error. Retries are reported to w,
+// when not nil.
+func Retry(ctx context.Context, dbConfig *config.DatabaseConfig, w io.Writer, f func() error) error {
+	err := f()
+	if dbConfig == nil {
+		return err
+	}
+	for attempt := 0; err != nil && attempt < dbConfig.Retries && IsTransient(err); attempt++ {
+		d := RetryDelay(dbConfig.RetryBackoff, attempt)
+		if w != nil {
+			fmt.Fprintf(w, "error: %v (retrying in %v, %d/%d)\n", err, d, attempt+1, dbConfig.Retries)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(d):
+		}
+		err = f()
+	}
+	return err
+}
+
+// RetryDelay returns the delay before the retry attempt (starting at 0),
+// doubling base for every attempt.
+func RetryDelay(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		base = DefaultRetryBackoff
+	}
+	d := base
+	for i := 0; i < attempt && d < MaxRetryBackoff; i++ {
+		d *= 2
+	}
+	if d > MaxRetryBackoff {
+		d = MaxRetryBackoff
+	}
+	return d
+}
+
+// IsTransient returns true when err looks like a network level error that
+// may go away by itself, such as a timeout or a refused connection.
+func IsTransient(err error) bool {
+	var netErr net.Error
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.DeadlineExceeded),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ETIMEDOUT),
+		errors.Is(err, syscall.EHOSTUNREACH),
+		errors.Is(err, syscall.ENETUNREACH):
+		return true
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	}
+	// not all drivers wrap the underlying network error
+	s := strings.ToLower(err.Error())
+	for _, msg := range []string{
+		"connection refused",
+		"connection reset",
+		"i/o timeout",
+		"no route to host",
+		"network is unreachable",
+		"server closed the connection",
+		"the database system is starting up",
+	} {
+		if strings.Contains(s, msg) {
+			return true
+		}
+	}
+	return false
+}
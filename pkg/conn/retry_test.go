@@ -0,0 +1,47 @@
+package conn
+
+import (
+	"errors"
+	"fmt"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		base    time.Duration
+		attempt int
+		exp     time.Duration
+	}{
+		{0, 0, DefaultRetryBackoff},
+		{0, 1, 2 * DefaultRetryBackoff},
+		{time.Second, 0, time.Second},
+		{time.Second, 3, 8 * time.Second},
+		{time.Second, 10, MaxRetryBackoff},
+		{time.Minute, 0, MaxRetryBackoff},
+	}
+	for i, test := range tests {
+		if d := RetryDelay(test.base, test.attempt); d != test.exp {
+			t.Errorf("test %d expected %v, got: %v", i, test.exp, d)
+		}
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	tests := []struct {
+		err error
+		exp bool
+	}{
+		{nil, false},
+		{errors.New("password authentication failed for user \"x\""), false},
+		{fmt.Errorf("dial tcp: %w", syscall.ECONNREFUSED), true},
+		{errors.New("dial tcp 10.0.0.1:5432: i/o timeout"), true},
+		{errors.New("pq: the database system is starting up"), true},
+	}
+	for i, test := range tests {
+		if b := IsTransient(test.err); b != test.exp {
+			t.Errorf("test %d expected %t, got: %t", i, test.exp, b)
+		}
+	}
+}
@@ -0,0 +1,314 @@
+package plugin
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"unicode/utf8"
+
+	"github.com/xo/dburl"
+)
+
+// RegisterDriver registers the database driver plugin name at path with
+// database/sql and as a dburl scheme. Its short alias is the first free two
+// letter alias starting with the first letter of the name.
+func RegisterDriver(name, path string) error {
+	if !nameRE.MatchString(name) {
+		return fmt.Errorf("invalid driver name %q", name)
+	}
+	for _, d := range sql.Drivers() {
+		if d == name {
+			return fmt.Errorf("driver %s is already registered", name)
+		}
+	}
+	if d, _ := dburl.SchemeDriverAndAliases(name); d != "" {
+		return fmt.Errorf("scheme %s is already registered", name)
+	}
+	short := ""
+	for i := 1; i < len(name) && short == ""; i++ {
+		if d, _ := dburl.SchemeDriverAndAliases(name[:1] + name[i:i+1]); d == "" {
+			short = name[:1] + name[i:i+1]
+		}
+	}
+	if short == "" {
+		return fmt.Errorf("no free short alias for scheme %s", name)
+	}
+	sql.Register(name, &Driver{Path: path})
+	dburl.Register(dburl.Scheme{
+		Driver: name,
+		Generator: func(u *dburl.URL) (string, error) {
+			z := u.URL
+			z.Scheme = name
+			return z.String(), nil
+		},
+		Aliases: []string{short},
+	})
+	return nil
+}
+
+// Driver is a database driver plugin.
+type Driver struct {
+	// Path is the path of the plugin executable.
+	Path string
+}
+
+// request is a request to a driver plugin.
+type request struct {
+	Method string        `json:"method"`
+	DSN    string        `json:"dsn,omitempty"`
+	SQL    string        `json:"sql,omitempty"`
+	Args   []interface{} `json:"args,omitempty"`
+}
+
+// response is the response of a driver plugin.
+type response struct {
+	Error        string          `json:"error,omitempty"`
+	Columns      []string        `json:"columns,omitempty"`
+	Rows         [][]interface{} `json:"rows,omitempty"`
+	RowsAffected int64           `json:"rows_affected,omitempty"`
+	LastInsertID int64           `json:"last_insert_id,omitempty"`
+}
+
+// Open satisfies the driver.Driver interface.
+func (d *Driver) Open(dsn string) (driver.Conn, error) {
+	cmd := exec.Command(d.Path)
+	cmd.Stderr = os.Stderr
+	in, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(out)
+	dec.UseNumber()
+	c := &conn{cmd: cmd, in: in, enc: json.NewEncoder(in), dec: dec}
+	if _, err := c.call(context.Background(), request{Method: "open", DSN: dsn}); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// conn is a connection to a driver plugin.
+type conn struct {
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	enc *json.Encoder
+	dec *json.Decoder
+	// broken is set when the plugin failed to respond
+	broken bool
+}
+
+// call sends the request to the plugin, returning its response.
+func (c *conn) call(ctx context.Context, req request) (*response, error) {
+	if c.broken {
+		return nil, driver.ErrBadConn
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	var res response
+	if err := c.enc.Encode(req); err != nil {
+		c.broken = true
+		return nil, fmt.Errorf("plugin %s: %w", c.cmd.Path, err)
+	}
+	if err := c.dec.Decode(&res); err != nil {
+		c.broken = true
+		return nil, fmt.Errorf("plugin %s: %w", c.cmd.Path, err)
+	}
+	if res.Error != "" {
+		return nil, errors.New(res.Error)
+	}
+	return &res, nil
+}
+
+// Prepare satisfies the driver.Conn interface.
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return &stmt{c: c, query: query}, nil
+}
+
+// Close satisfies the driver.Conn interface.
+func (c *conn) Close() error {
+	c.in.Close()
+	return c.cmd.Wait()
+}
+
+// Begin satisfies the driver.Conn interface.
+func (c *conn) Begin() (driver.Tx, error) {
+	if _, err := c.call(context.Background(), request{Method: "begin"}); err != nil {
+		return nil, err
+	}
+	return &tx{c: c}, nil
+}
+
+// ExecContext satisfies the driver.ExecerContext interface.
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.call(ctx, request{Method: "exec", SQL: query, Args: values(args)})
+	if err != nil {
+		return nil, err
+	}
+	return result{res.RowsAffected, res.LastInsertID}, nil
+}
+
+// QueryContext satisfies the driver.QueryerContext interface.
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.call(ctx, request{Method: "query", SQL: query, Args: values(args)})
+	if err != nil {
+		return nil, err
+	}
+	return &rows{columns: res.Columns, rows: res.Rows}, nil
+}
+
+// stmt is a statement of a driver plugin. Statements are sent to the plugin
+// when executed.
+type stmt struct {
+	c     *conn
+	query string
+}
+
+// Close satisfies the driver.Stmt interface.
+func (s *stmt) Close() error {
+	return nil
+}
+
+// NumInput satisfies the driver.Stmt interface.
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+// Exec satisfies the driver.Stmt interface.
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), named(args))
+}
+
+// Query satisfies the driver.Stmt interface.
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), named(args))
+}
+
+// ExecContext satisfies the driver.StmtExecContext interface.
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.c.ExecContext(ctx, s.query, args)
+}
+
+// QueryContext satisfies the driver.StmtQueryContext interface.
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.c.QueryContext(ctx, s.query, args)
+}
+
+// tx is a transaction of a driver plugin.
+type tx struct {
+	c *conn
+}
+
+// Commit satisfies the driver.Tx interface.
+func (t *tx) Commit() error {
+	_, err := t.c.call(context.Background(), request{Method: "commit"})
+	return err
+}
+
+// Rollback satisfies the driver.Tx interface.
+func (t *tx) Rollback() error {
+	_, err := t.c.call(context.Background(), request{Method: "rollback"})
+	return err
+}
+
+// result is the result of an exec.
+type result struct {
+	rowsAffected, lastInsertID int64
+}
+
+// LastInsertId satisfies the driver.Result interface.
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+// RowsAffected satisfies the driver.Result interface.
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// rows are the rows returned by a query.
+type rows struct {
+	columns []string
+	rows    [][]interface{}
+}
+
+// Columns satisfies the driver.Rows interface.
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+// Close satisfies the driver.Rows interface.
+func (r *rows) Close() error {
+	r.rows = nil
+	return nil
+}
+
+// Next satisfies the driver.Rows interface.
+func (r *rows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	row := r.rows[0]
+	r.rows = r.rows[1:]
+	if len(row) != len(dest) {
+		return fmt.Errorf("expected %d values, got %d", len(dest), len(row))
+	}
+	for i, v := range row {
+		dest[i] = value(v)
+	}
+	return nil
+}
+
+// named converts args to named values.
+func named(args []driver.Value) []driver.NamedValue {
+	v := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		v[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return v
+}
+
+// values converts args to values encoded as JSON, encoding valid UTF-8
+// []byte as strings.
+func values(args []driver.NamedValue) []interface{} {
+	v := make([]interface{}, len(args))
+	for i, arg := range args {
+		if b, ok := arg.Value.([]byte); ok && utf8.Valid(b) {
+			v[i] = string(b)
+		} else {
+			v[i] = arg.Value
+		}
+	}
+	return v
+}
+
+// value converts a value decoded from JSON to a driver value: integers to
+// int64, other numbers to float64, and objects and arrays to their JSON
+// encoding.
+func value(v interface{}) driver.Value {
+	switch x := v.(type) {
+	case nil, string, bool:
+		return x
+	case json.Number:
+		if i, err := x.Int64(); err == nil {
+			return i
+		}
+		f, _ := x.Float64()
+		return f
+	}
+	buf, _ := json.Marshal(v)
+	return buf
+}
@@ -0,0 +1,130 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	// act as the driver plugin of TestDriver
+	if os.Getenv("USQL_PLUGIN_TEST_DRIVER") != "" {
+		serveTestDriver()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestSecret(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script plugins are not supported on windows")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\n" +
+		`if [ "$1" = get ] && [ "$2" = app/db ]; then echo s3cr3t; exit 0; fi` + "\n" +
+		`echo "no secret $2" >&2; exit 1` + "\n"
+	if err := os.WriteFile(filepath.Join(dir, SecretPrefix+"test"), []byte(script), 0o755); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	t.Setenv("PATH", dir)
+	if plugins, exp := Find(SecretPrefix), map[string]string{"test": filepath.Join(dir, SecretPrefix+"test")}; !reflect.DeepEqual(plugins, exp) {
+		t.Errorf("expected %v, got: %v", exp, plugins)
+	}
+	secret, err := Secret(context.Background(), "test", "app/db")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if secret != "s3cr3t" {
+		t.Errorf("expected s3cr3t, got: %q", secret)
+	}
+	if _, err := Secret(context.Background(), "test", "other"); err == nil || err.Error() != "secret resolver test: no secret other" {
+		t.Errorf("expected no secret error, got: %v", err)
+	}
+	if _, err := Secret(context.Background(), "missing", "app/db"); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestDriver(t *testing.T) {
+	t.Setenv("USQL_PLUGIN_TEST_DRIVER", "1")
+	if err := RegisterDriver("plugintest", os.Args[0]); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if err := RegisterDriver("plugintest", os.Args[0]); err == nil {
+		t.Errorf("expected already registered error, got nil")
+	}
+	db, err := sql.Open("plugintest", "plugintest://user@host/db")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query("select id, name from t where id > ?", 0)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer rows.Close()
+	var res [][]interface{}
+	for rows.Next() {
+		var id, name interface{}
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		res = append(res, []interface{}{id, name})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := [][]interface{}{{int64(1), "a"}, {2.5, nil}}; !reflect.DeepEqual(res, exp) {
+		t.Errorf("expected %v, got: %v", exp, res)
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	r, err := tx.Exec("delete from t")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if n, _ := r.RowsAffected(); n != 2 {
+		t.Errorf("expected 2 rows affected, got: %d", n)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := db.Exec("invalid"); err == nil || err.Error() != `syntax error at "invalid"` {
+		t.Errorf("expected syntax error, got: %v", err)
+	}
+}
+
+// serveTestDriver serves the requests of TestDriver.
+func serveTestDriver() {
+	enc := json.NewEncoder(os.Stdout)
+	s := bufio.NewScanner(os.Stdin)
+	for s.Scan() {
+		var req request
+		if err := json.Unmarshal(s.Bytes(), &req); err != nil {
+			_ = enc.Encode(response{Error: err.Error()})
+			continue
+		}
+		var res response
+		switch {
+		case req.Method == "open" && req.DSN != "plugintest://user@host/db":
+			res.Error = "invalid dsn " + req.DSN
+		case req.Method == "open", req.Method == "begin", req.Method == "commit", req.Method == "rollback":
+		case req.Method == "query" && len(req.Args) == 1:
+			res.Columns = []string{"id", "name"}
+			res.Rows = [][]interface{}{{1, "a"}, {2.5, nil}}
+		case req.Method == "exec" && req.SQL == "delete from t":
+			res.RowsAffected = 2
+		default:
+			res.Error = `syntax error at "` + req.SQL + `"`
+		}
+		_ = enc.Encode(res)
+	}
+}
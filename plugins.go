@@ -0,0 +1,26 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/plugin"
+)
+
+// registerPlugins registers the database driver plugins found in $PATH.
+// Built-in drivers take precedence over plugins with the same name.
+func registerPlugins() {
+	for name, path := range plugin.Find(plugin.DriverPrefix) {
+		if drivers.Registered(name) {
+			continue
+		}
+		if err := plugin.RegisterDriver(name, path); err != nil {
+			fmt.Fprintf(os.Stderr, "warning: driver plugin %s: %v\n", path, err)
+			continue
+		}
+		drivers.Register(name, drivers.Driver{
+			AllowMultilineComments: true,
+		})
+	}
+}
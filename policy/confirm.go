@@ -0,0 +1,224 @@
+package policy
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xo/usql/sqlfmt"
+	"github.com/xo/usql/sqllint"
+)
+
+// Confirmation values of the kinds of statements.
+const (
+	// ConfirmAlways confirms the statements on all databases.
+	ConfirmAlways = "always"
+	// ConfirmNever never confirms the statements.
+	ConfirmNever = "never"
+	// ConfirmOnlySuffix is the suffix of the TAG-only values, confirming the
+	// statements on the databases with the tag (ie, prod-only).
+	ConfirmOnlySuffix = "-only"
+)
+
+// Kinds of statements confirmed.
+const (
+	KindDrop               = "drop"
+	KindTruncate           = "truncate"
+	KindAlter              = "alter"
+	KindCreate             = "create"
+	KindDelete             = "delete"
+	KindUpdate             = "update"
+	KindInsert             = "insert"
+	KindMerge              = "merge"
+	KindGrant              = "grant"
+	KindRevoke             = "revoke"
+	KindDeleteWithoutWhere = "delete_without_where"
+	KindUpdateWithoutWhere = "update_without_where"
+)
+
+// kinds are the kinds of statements, by their verb.
+var kinds = map[string]string{
+	"DROP":     KindDrop,
+	"TRUNCATE": KindTruncate,
+	"ALTER":    KindAlter,
+	"CREATE":   KindCreate,
+	"DELETE":   KindDelete,
+	"UPDATE":   KindUpdate,
+	"INSERT":   KindInsert,
+	"MERGE":    KindMerge,
+	"GRANT":    KindGrant,
+	"REVOKE":   KindRevoke,
+}
+
+// Kinds returns the sorted kinds of statements.
+func Kinds() []string {
+	v := []string{KindDeleteWithoutWhere, KindUpdateWithoutWhere}
+	for _, kind := range kinds {
+		v = append(v, kind)
+	}
+	sort.Strings(v)
+	return v
+}
+
+// Confirm is the confirmation policy of a database: the kinds of statements
+// confirmed before they are executed.
+type Confirm struct {
+	// rules are the confirmation values of the confirmed kinds.
+	rules map[string]string
+}
+
+// NewConfirm returns the confirmation policy of the rules, the confirmation
+// values of the kinds of statements (always, never or TAG-only), on a
+// database with the tags, or nil when no statement is confirmed on it.
+func NewConfirm(rules map[string]string, tags []string) (*Confirm, error) {
+	if err := ValidateConfirm(rules); err != nil {
+		return nil, err
+	}
+	c := &Confirm{rules: make(map[string]string)}
+	for kind, value := range rules {
+		value = strings.ToLower(value)
+		switch {
+		case value == ConfirmAlways:
+			c.rules[kind] = value
+		case strings.HasSuffix(value, ConfirmOnlySuffix):
+			tag := strings.TrimSuffix(value, ConfirmOnlySuffix)
+			for _, t := range tags {
+				if strings.EqualFold(t, tag) {
+					c.rules[kind] = value
+					break
+				}
+			}
+		}
+	}
+	if len(c.rules) == 0 {
+		return nil, nil
+	}
+	return c, nil
+}
+
+// ValidateConfirm checks the kinds and the confirmation values of the rules.
+func ValidateConfirm(rules map[string]string) error {
+	for kind, value := range rules {
+		if !validKind(kind) {
+			return fmt.Errorf("invalid confirm kind %q: expected one of %s", kind, strings.Join(Kinds(), ", "))
+		}
+		switch value = strings.ToLower(value); {
+		case value == ConfirmAlways, value == ConfirmNever:
+		case strings.HasSuffix(value, ConfirmOnlySuffix) && value != ConfirmOnlySuffix:
+		default:
+			return fmt.Errorf("invalid confirm %s value %q: expected always, never or TAG-only", kind, value)
+		}
+	}
+	return nil
+}
+
+// validKind returns true when kind is a kind of statements.
+func validKind(kind string) bool {
+	for _, k := range Kinds() {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Check returns an *Unconfirmed for the statement, in the dialect (a driver
+// name), when it needs to be confirmed, or nil. A nil policy confirms no
+// statement. UPDATE and DELETE statements without a WHERE clause are checked
+// against the update_without_where and delete_without_where rules first.
+func (c *Confirm) Check(sqlstr, dialect string) *Unconfirmed {
+	if c == nil {
+		return nil
+	}
+	kind := statementKind(sqlstr, dialect)
+	if kind == "" {
+		return nil
+	}
+	if kind == KindDelete || kind == KindUpdate {
+		if value, ok := c.rules[kind+"_without_where"]; ok && missingWhere(sqlstr, dialect) {
+			return &Unconfirmed{Kind: kind + "_without_where", Value: value}
+		}
+	}
+	if value, ok := c.rules[kind]; ok {
+		return &Unconfirmed{Kind: kind, Value: value}
+	}
+	return nil
+}
+
+// statementKind returns the kind of the statement, from its verb: its first
+// word, or the first verb following the common table expressions of WITH
+// statements.
+func statementKind(sqlstr, dialect string) string {
+	stmts, err := sqlfmt.Statements(sqlstr, dialect)
+	if err != nil || len(stmts) == 0 {
+		return ""
+	}
+	// name is set when the next word is the name of a common table
+	// expression
+	var with, name bool
+	depth := 0
+	for _, t := range stmts[0] {
+		switch t.Kind {
+		case sqlfmt.LineComment, sqlfmt.BlockComment:
+			continue
+		case sqlfmt.Open:
+			depth++
+		case sqlfmt.Close:
+			depth--
+		case sqlfmt.Comma:
+			name = with && depth == 0
+			continue
+		case sqlfmt.Word:
+			w := strings.ToUpper(t.Text)
+			switch {
+			case !with && w == "WITH":
+				with, name = true, true
+				continue
+			case !with:
+				return kinds[w]
+			case name && w == "RECURSIVE":
+				continue
+			case !name && depth == 0 && (kinds[w] != "" || w == "SELECT"):
+				return kinds[w]
+			}
+		default:
+			if !with {
+				return ""
+			}
+		}
+		name = false
+	}
+	return ""
+}
+
+// missingWhere returns true when the statement is an UPDATE or DELETE
+// statement without a WHERE clause.
+func missingWhere(sqlstr, dialect string) bool {
+	var disable []string
+	for rule := range sqllint.Rules {
+		if rule != sqllint.MissingWhere {
+			disable = append(disable, rule)
+		}
+	}
+	findings, err := sqllint.Lint(sqlstr, sqllint.Options{Dialect: dialect, Disable: disable})
+	return err == nil && len(findings) != 0
+}
+
+// Unconfirmed is a statement that needs to be confirmed before it is
+// executed.
+type Unconfirmed struct {
+	// Kind is the kind of the statement.
+	Kind string
+	// Value is the confirmation value of the kind, always or TAG-only.
+	Value string
+	// Declined is set when the confirmation was asked and declined.
+	Declined bool
+}
+
+// Error satisfies the error interface.
+func (u *Unconfirmed) Error() string {
+	if u.Declined {
+		return fmt.Sprintf("statement not executed: %s statements are not confirmed (confirm %s: %s)", u.Kind, u.Kind, u.Value)
+	}
+	return fmt.Sprintf("statement not executed: %s statements require a confirmation (confirm %s: %s), use --yes when not interactive", u.Kind, u.Kind, u.Value)
+}
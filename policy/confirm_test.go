@@ -0,0 +1,66 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConfirm(t *testing.T) {
+	c, err := NewConfirm(map[string]string{
+		"drop":                 "always",
+		"truncate":             "prod-only",
+		"alter":                "staging-only",
+		"delete_without_where": "Always",
+		"insert":               "never",
+	}, []string{"eu", "Prod"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		sqlstr string
+		kind   string
+	}{
+		{"select * from users", ""},
+		{"drop table users", "drop"},
+		{"-- cleanup\n  DROP INDEX users_idx", "drop"},
+		{"truncate users", "truncate"},
+		{"alter table users add column x int", ""},
+		{"delete from users", "delete_without_where"},
+		{"delete from users where id = 1", ""},
+		{"with old as (select id from users) delete from users", "delete_without_where"},
+		{"with drop as (select 1) select * from drop", ""},
+		{"insert into users values (1)", ""},
+		{"update users set x = 1", ""},
+	}
+	for i, test := range tests {
+		u := c.Check(test.sqlstr, "postgres")
+		switch {
+		case test.kind == "" && u != nil:
+			t.Errorf("test %d expected %q to need no confirmation, got: %v", i, test.sqlstr, u)
+		case test.kind != "" && (u == nil || u.Kind != test.kind):
+			t.Errorf("test %d expected %q to need a %s confirmation, got: %v", i, test.sqlstr, test.kind, u)
+		}
+	}
+	var u *Unconfirmed
+	if err := error(c.Check("drop table users", "postgres")); !errors.As(err, &u) || u.Value != "always" {
+		t.Errorf("expected an unconfirmed error, got: %v", err)
+	}
+	if c := (*Confirm)(nil).Check("drop table users", "postgres"); c != nil {
+		t.Errorf("expected no confirmation, got: %v", c)
+	}
+}
+
+func TestNewConfirm(t *testing.T) {
+	if c, err := NewConfirm(map[string]string{"drop": "never", "truncate": "prod-only"}, []string{"dev"}); c != nil || err != nil {
+		t.Errorf("expected no policy, got: %v %v", c, err)
+	}
+	for _, rules := range []map[string]string{
+		{"dropp": "always"},
+		{"drop": "sometimes"},
+		{"drop": "-only"},
+	} {
+		if _, err := NewConfirm(rules, nil); err == nil {
+			t.Errorf("expected an error for %v, got nil", rules)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+// Package policy checks statements against the allow and deny lists of a
+// role, and the statements to confirm before they are executed.
+package policy
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/xo/usql/stmt"
+)
+
+// Policy is the statement policy of a role.
+type Policy struct {
+	role  string
+	allow []*regexp.Regexp
+	deny  []*regexp.Regexp
+}
+
+// New compiles the allow and deny patterns of the role, returning nil when
+// there are none. Patterns are case insensitive regular expressions matched
+// from the start of statements, after their leading comments, so
+// DROP|TRUNCATE denies the DROP and TRUNCATE statements, but not a query of a
+// dropoff column.
+func New(role string, allow, deny []string) (*Policy, error) {
+	if len(allow) == 0 && len(deny) == 0 {
+		return nil, nil
+	}
+	p := &Policy{role: role}
+	var err error
+	if p.allow, err = compile("allow_statements", allow); err != nil {
+		return nil, err
+	}
+	if p.deny, err = compile("deny_statements", deny); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// compile compiles patterns.
+func compile(name string, patterns []string) ([]*regexp.Regexp, error) {
+	var res []*regexp.Regexp
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(`(?is)^(?:` + pattern + `)`)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", name, pattern, err)
+		}
+		res = append(res, re)
+	}
+	return res, nil
+}
+
+// Check returns a *Violation when one of the statements, as split for the
+// driver (see drivers.Statements), is not allowed. A nil policy allows all
+// statements.
+func (p *Policy) Check(stmts ...string) error {
+	if p == nil {
+		return nil
+	}
+	for _, s := range stmts {
+		if err := p.check(stmt.TrimComments(s, true, true, true)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// check returns a *Violation when the statement is not allowed.
+func (p *Policy) check(sqlstr string) error {
+	for _, re := range p.deny {
+		if re.MatchString(sqlstr) {
+			return &Violation{Role: p.role, Pattern: pattern(re), Deny: true}
+		}
+	}
+	if len(p.allow) == 0 {
+		return nil
+	}
+	for _, re := range p.allow {
+		if re.MatchString(sqlstr) {
+			return nil
+		}
+	}
+	return &Violation{Role: p.role}
+}
+
+// pattern returns the pattern of a compiled regexp.
+func pattern(re *regexp.Regexp) string {
+	s := strings.TrimPrefix(re.String(), `(?is)^(?:`)
+	return strings.TrimSuffix(s, `)`)
+}
+
+// Violation is a statement policy violation.
+type Violation struct {
+	// Role is the role of the policy.
+	Role string
+	// Pattern is the matching deny_statements pattern.
+	Pattern string
+	// Deny is set when the statement matches a deny_statements pattern, and
+	// unset when it matches no allow_statements pattern.
+	Deny bool
+}
+
+// Error satisfies the error interface.
+func (v *Violation) Error() string {
+	if v.Deny {
+		return fmt.Sprintf("statement denied for role %q: matches deny_statements pattern %q", v.Role, v.Pattern)
+	}
+	return fmt.Sprintf("statement denied for role %q: matches no allow_statements pattern", v.Role)
+}
@@ -0,0 +1,71 @@
+package policy
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+func TestCheck(t *testing.T) {
+	p, err := New("analyst", []string{`SELECT|WITH|EXPLAIN`, `SET\s+search_path`}, []string{`DROP|TRUNCATE`, `SELECT .* FROM\s+secrets\b`})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	u, err := dburl.Parse("postgres://localhost/app")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	tests := []struct {
+		sqlstr  string
+		allowed bool
+		pattern string
+	}{
+		{"select * from users", true, ""},
+		{"  with x as (select 1) select * from x", true, ""},
+		{"select dropoff from trips", true, ""},
+		{"set search_path to app", true, ""},
+		{"drop table users", false, "DROP|TRUNCATE"},
+		{"Truncate users", false, "DROP|TRUNCATE"},
+		{"select * from\nsecrets", false, `SELECT .* FROM\s+secrets\b`},
+		{"delete from users", false, ""},
+		{"/* x */ DROP TABLE t", false, "DROP|TRUNCATE"},
+		{"-- c\nDROP TABLE t", false, "DROP|TRUNCATE"},
+		{"select 1; drop table t", false, "DROP|TRUNCATE"},
+		{"select 1;\n/* x */ -- c\ndelete from t", false, ""},
+		{"-- c\nselect 1; select 'drop table t'", true, ""},
+	}
+	for i, test := range tests {
+		stmts, err := drivers.Statements(u, test.sqlstr)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		err = p.Check(stmts...)
+		if test.allowed {
+			if err != nil {
+				t.Errorf("test %d expected %q to be allowed, got: %v", i, test.sqlstr, err)
+			}
+			continue
+		}
+		var v *Violation
+		if !errors.As(err, &v) {
+			t.Fatalf("test %d expected violation for %q, got: %v", i, test.sqlstr, err)
+		}
+		if v.Pattern != test.pattern || v.Deny != (test.pattern != "") {
+			t.Errorf("test %d expected pattern %q, got: %q", i, test.pattern, v.Pattern)
+		}
+	}
+}
+
+func TestNew(t *testing.T) {
+	if p, err := New("reader", nil, nil); p != nil || err != nil {
+		t.Errorf("expected no policy, got: %v %v", p, err)
+	}
+	if err := (*Policy)(nil).Check("drop table users"); err != nil {
+		t.Errorf("expected no error, got: %v", err)
+	}
+	if _, err := New("reader", nil, []string{"DROP("}); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
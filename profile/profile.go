@@ -0,0 +1,356 @@
+// Package profile profiles the data of tables from a sample of their rows:
+// the null rate, distinct count estimate, minimum, maximum and most frequent
+// values of their columns.
+package profile
+
+import (
+	"container/heap"
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/export"
+	"github.com/xo/usql/mask"
+)
+
+// DefaultSample is the default number of sampled rows.
+const DefaultSample = 10000
+
+// sampleQueries are the queries of the first rows of a table, by dialect,
+// formatted with the table and the number of rows. The rows of the tables of
+// the other dialects are read until the sample is complete.
+var sampleQueries = map[string]string{
+	"postgres":   "SELECT * FROM %s LIMIT %d",
+	"mysql":      "SELECT * FROM %s LIMIT %d",
+	"sqlite3":    "SELECT * FROM %s LIMIT %d",
+	"duckdb":     "SELECT * FROM %s LIMIT %d",
+	"clickhouse": "SELECT * FROM %s LIMIT %d",
+	"snowflake":  "SELECT * FROM %s LIMIT %d",
+	"bigquery":   "SELECT * FROM %s LIMIT %d",
+	"trino":      "SELECT * FROM %s FETCH FIRST %d ROWS ONLY",
+	"oracle":     "SELECT * FROM %s FETCH FIRST %d ROWS ONLY",
+	"sqlserver":  "SELECT TOP %[2]d * FROM %[1]s",
+}
+
+// Profile is the profile of the sampled rows of a table.
+type Profile struct {
+	Table string
+	// Rows is the number of sampled rows.
+	Rows    int64
+	Columns []*Column
+}
+
+// Column is the profile of a column.
+type Column struct {
+	Name string
+	// Type is the database type of the column.
+	Type  string
+	Nulls int64
+	// Distinct is the estimated number of distinct values, exact up to
+	// distinctK values.
+	Distinct int64
+	// Min and Max are the minimum and maximum values, empty when all the
+	// values are null.
+	Min, Max string
+	// Top are the most frequent values, by decreasing count.
+	Top []Value
+
+	kind     export.ColumnKind
+	min, max interface{}
+	counts   map[string]int64
+	kmv      kmv
+}
+
+// Value is a value and the number of its occurrences.
+type Value struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// NullRate returns the rate of null values of the column.
+func (c *Column) NullRate(rows int64) float64 {
+	if rows == 0 {
+		return 0
+	}
+	return float64(c.Nulls) / float64(rows)
+}
+
+// maxCounted is the maximum number of distinct values counted for the most
+// frequent values of a column. The values first seen after are not counted.
+const maxCounted = 10000
+
+// Read profiles the first sample rows of the table, optionally schema
+// qualified, keeping the top most frequent values of each column.
+func Read(ctx context.Context, u *dburl.URL, db *sql.DB, table string, sample, top int) (*Profile, error) {
+	dialect := drivers.Caps(u).Dialect
+	schema, name := "", table
+	if i := strings.LastIndex(table, "."); i != -1 {
+		schema, name = table[:i], table[i+1:]
+	}
+	q, ok := sampleQueries[dialect]
+	if !ok {
+		q = "SELECT * FROM %s"
+	}
+	q = fmt.Sprintf(q, dump.DialectFor(dialect).Qualify(schema, name), sample)
+	rows, err := db.QueryContext(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	p := &Profile{Table: table, Columns: make([]*Column, len(cts))}
+	for i, ct := range cts {
+		p.Columns[i] = &Column{Name: ct.Name(), Type: ct.DatabaseTypeName(), kind: kind(ct), counts: make(map[string]int64)}
+	}
+	vals := make([]interface{}, len(cts))
+	ptrs := make([]interface{}, len(cts))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	for p.Rows < int64(sample) && rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		p.Rows++
+		for i, c := range p.Columns {
+			c.add(vals[i])
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, c := range p.Columns {
+		c.finish(top)
+	}
+	return p, nil
+}
+
+// kind returns the kind of values of a column, numeric and decimal columns
+// being compared as floats.
+func kind(ct *sql.ColumnType) export.ColumnKind {
+	typ := strings.ToUpper(ct.DatabaseTypeName())
+	if strings.Contains(typ, "NUMERIC") || strings.Contains(typ, "DECIMAL") || typ == "NUMBER" {
+		return export.KindFloat
+	}
+	return export.Kind(ct)
+}
+
+// add adds a value of the column.
+func (c *Column) add(v interface{}) {
+	if v == nil {
+		c.Nulls++
+		return
+	}
+	s := format(v)
+	c.kmv.add(s)
+	if _, ok := c.counts[s]; ok || len(c.counts) < maxCounted {
+		c.counts[s]++
+	}
+	if c.kind == export.KindBinary {
+		return
+	}
+	x := c.comparable(v, s)
+	if c.min == nil || less(x, c.min) {
+		c.min = x
+	}
+	if c.max == nil || less(c.max, x) {
+		c.max = x
+	}
+}
+
+// comparable returns the value of the column as a float64, time.Time or
+// string, compared by less.
+func (c *Column) comparable(v interface{}, s string) interface{} {
+	switch x := v.(type) {
+	case int64:
+		return float64(x)
+	case float64:
+		return x
+	case time.Time:
+		return x
+	}
+	if c.kind == export.KindInt || c.kind == export.KindFloat {
+		if f, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+			return f
+		}
+	}
+	return s
+}
+
+// less returns true when a is less than b, comparing values of different
+// types as strings.
+func less(a, b interface{}) bool {
+	switch x := a.(type) {
+	case float64:
+		if y, ok := b.(float64); ok {
+			return x < y
+		}
+	case time.Time:
+		if y, ok := b.(time.Time); ok {
+			return x.Before(y)
+		}
+	}
+	return fmt.Sprint(a) < fmt.Sprint(b)
+}
+
+// finish computes the distinct count, minimum, maximum and top most
+// frequent values of the column.
+func (c *Column) finish(top int) {
+	c.Distinct = c.kmv.estimate()
+	if c.min != nil {
+		c.Min, c.Max = format(c.min), format(c.max)
+	}
+	for s, n := range c.counts {
+		c.Top = append(c.Top, Value{s, n})
+	}
+	sort.Slice(c.Top, func(i, j int) bool {
+		if c.Top[i].Count != c.Top[j].Count {
+			return c.Top[i].Count > c.Top[j].Count
+		}
+		return c.Top[i].Value < c.Top[j].Value
+	})
+	if len(c.Top) > top {
+		c.Top = c.Top[:top]
+	}
+	c.counts = nil
+}
+
+// format formats a value as text.
+func format(v interface{}) string {
+	switch x := v.(type) {
+	case []byte:
+		return string(x)
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// distinctK is the number of minimum hashes kept to estimate the distinct
+// values of a column.
+const distinctK = 1024
+
+// kmv is a k minimum values estimator of the number of distinct values: the
+// k smallest hashes of the values are kept, and the number of distinct
+// values estimated from the k-th smallest.
+type kmv struct {
+	h    hashHeap
+	seen map[uint64]bool
+}
+
+// add adds a value.
+func (k *kmv) add(s string) {
+	f := fnv.New64a()
+	_, _ = f.Write([]byte(s))
+	// mix the bits of the hash, as fnv hashes of similar values are not
+	// uniformly distributed
+	x := f.Sum64()
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+	if k.seen == nil {
+		k.seen = make(map[uint64]bool)
+	}
+	switch {
+	case k.seen[x]:
+		return
+	case len(k.h) < distinctK:
+		heap.Push(&k.h, x)
+	case x < k.h[0]:
+		delete(k.seen, k.h[0])
+		k.h[0] = x
+		heap.Fix(&k.h, 0)
+	default:
+		return
+	}
+	k.seen[x] = true
+}
+
+// estimate returns the estimated number of distinct values, exact when
+// fewer than distinctK.
+func (k *kmv) estimate() int64 {
+	if len(k.h) < distinctK {
+		return int64(len(k.h))
+	}
+	return int64(math.Round(float64(distinctK-1) / (float64(k.h[0]) / math.MaxUint64)))
+}
+
+// hashHeap is a max-heap of hashes.
+type hashHeap []uint64
+
+func (h hashHeap) Len() int            { return len(h) }
+func (h hashHeap) Less(i, j int) bool  { return h[i] > h[j] }
+func (h hashHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *hashHeap) Push(x interface{}) { *h = append(*h, x.(uint64)) }
+func (h *hashHeap) Pop() interface{} {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// Mask masks the minimum, maximum and most frequent values of the columns
+// matching the mask patterns (see mask.Match).
+func (p *Profile) Mask(patterns []string) {
+	for _, c := range p.Columns {
+		if !mask.Match(patterns, c.Name) {
+			continue
+		}
+		if c.Min != "" {
+			c.Min, c.Max = mask.Mask, mask.Mask
+		}
+		for i := range c.Top {
+			c.Top[i].Value = mask.Mask
+		}
+	}
+}
+
+// valueWidth is the maximum width of the values written by Write.
+const valueWidth = 24
+
+// Write writes the profile as a table.
+func (p *Profile) Write(w io.Writer) error {
+	fmt.Fprintf(w, "table %s: %d rows sampled\n", p.Table, p.Rows)
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "column\ttype\tnulls\tdistinct\tmin\tmax\ttop values")
+	for _, c := range p.Columns {
+		top := make([]string, len(c.Top))
+		for i, v := range c.Top {
+			top[i] = fmt.Sprintf("%s (%d)", truncate(v.Value), v.Count)
+		}
+		distinct := strconv.FormatInt(c.Distinct, 10)
+		if c.Distinct >= distinctK {
+			distinct = "~" + distinct
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%s\t%s\t%s\t%s\n", c.Name, c.Type, 100*c.NullRate(p.Rows), distinct, truncate(c.Min), truncate(c.Max), strings.Join(top, ", "))
+	}
+	return tw.Flush()
+}
+
+// truncate collapses the whitespace of a value, truncating it to valueWidth
+// characters.
+func truncate(s string) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if r := []rune(s); len(r) > valueWidth {
+		s = string(r[:valueWidth-1]) + "…"
+	}
+	return s
+}
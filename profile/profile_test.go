@@ -0,0 +1,84 @@
+package profile
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+)
+
+func TestRead(t *testing.T) {
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE users (id INTEGER, status TEXT, email TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	for i, status := range []string{"active", "active", "active", "banned", "pending", "pending"} {
+		email := sql.NullString{String: fmt.Sprintf("u%d@example.com", i), Valid: i%2 == 0}
+		if _, err := db.Exec(`INSERT INTO users VALUES (?, ?, ?)`, 10-i, status, email); err != nil {
+			t.Fatal(err)
+		}
+	}
+	p, err := Read(context.Background(), u, db, "users", 5, 2)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if p.Rows != 5 || len(p.Columns) != 3 {
+		t.Fatalf("expected 5 rows of 3 columns, got: %d rows of %d columns", p.Rows, len(p.Columns))
+	}
+	id, status, email := p.Columns[0], p.Columns[1], p.Columns[2]
+	if id.Min != "6" || id.Max != "10" || id.Distinct != 5 || id.Nulls != 0 {
+		t.Errorf("expected id 6 to 10 with 5 distinct values, got: %s to %s with %d", id.Min, id.Max, id.Distinct)
+	}
+	if exp := []Value{{"active", 3}, {"banned", 1}}; !reflect.DeepEqual(status.Top, exp) {
+		t.Errorf("expected top statuses %v, got: %v", exp, status.Top)
+	}
+	if email.Nulls != 2 || email.NullRate(p.Rows) != 0.4 || email.Distinct != 3 {
+		t.Errorf("expected 2 null and 3 distinct emails, got: %d and %d", email.Nulls, email.Distinct)
+	}
+	buf := new(bytes.Buffer)
+	if err := p.Write(buf); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s := buf.String(); !strings.HasPrefix(s, "table users: 5 rows sampled\n") || !strings.Contains(s, "active (3), banned (1)") {
+		t.Errorf("unexpected profile:\n%s", s)
+	}
+	p.Mask([]string{"email"})
+	if email.Min != "*****" || email.Max != "*****" || email.Top[0].Value != "*****" || status.Min != "active" {
+		t.Errorf("expected masked emails, got: %s, %s, %v", email.Min, email.Max, email.Top)
+	}
+	if _, err := Read(context.Background(), u, db, "missing", 5, 2); err == nil {
+		t.Errorf("expected error for missing table, got nil")
+	}
+}
+
+func TestKMV(t *testing.T) {
+	for _, n := range []int{0, 10, 1000, 100000} {
+		var k kmv
+		for i := 0; i < n; i++ {
+			k.add(fmt.Sprint(i))
+			k.add(fmt.Sprint(i))
+		}
+		est := k.estimate()
+		if n < distinctK && est != int64(n) {
+			t.Errorf("expected exactly %d distinct values, got: %d", n, est)
+		}
+		if d := float64(est-int64(n)) / float64(n); n >= distinctK && (d < -0.1 || d > 0.1) {
+			t.Errorf("expected about %d distinct values, got: %d", n, est)
+		}
+	}
+}
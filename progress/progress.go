@@ -0,0 +1,212 @@
+// Package progress reports the progress of long operations, such as imports,
+// exports, copies and dumps: their rows processed, throughput and estimated
+// time remaining. The report is updated in place on terminals, and written as
+// periodic log lines otherwise.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/sizes"
+)
+
+// Intervals of the reports on terminals, and of the log lines otherwise. The
+// operations finished before the first report are not reported.
+var (
+	TerminalInterval = 250 * time.Millisecond
+	LogInterval      = 10 * time.Second
+	TerminalDelay    = 1 * time.Second
+)
+
+// Progress reports the progress of an operation, until done. The methods of
+// a nil Progress do nothing, so that reporting is optional.
+type Progress struct {
+	w        io.Writer
+	name     string
+	tty      bool
+	start    time.Time
+	rows     int64
+	bytes    int64
+	size     int64
+	mu       sync.Mutex
+	reported bool
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// New starts reporting the progress of the operation with the name to w,
+// in place when w is a terminal.
+func New(w io.Writer, name string) *Progress {
+	p := &Progress{
+		w:     w,
+		name:  name,
+		start: time.Now(),
+		done:  make(chan struct{}),
+	}
+	if f, ok := w.(*os.File); ok {
+		p.tty = isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+	}
+	p.wg.Add(1)
+	go p.run()
+	return p
+}
+
+// run reports the progress every interval, until done.
+func (p *Progress) run() {
+	defer p.wg.Done()
+	delay, interval := LogInterval, LogInterval
+	if p.tty {
+		delay, interval = TerminalDelay, TerminalInterval
+	}
+	t := time.NewTimer(delay)
+	defer t.Stop()
+	for {
+		select {
+		case <-p.done:
+			return
+		case <-t.C:
+			p.report()
+			t.Reset(interval)
+		}
+	}
+}
+
+// report writes the progress.
+func (p *Progress) report() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.reported = true
+	if p.tty {
+		fmt.Fprint(p.w, "\r\x1b[K"+p.String())
+		return
+	}
+	fmt.Fprintln(p.w, "progress: "+p.String())
+}
+
+// Add adds n processed rows.
+func (p *Progress) Add(n int64) {
+	if p != nil {
+		atomic.AddInt64(&p.rows, n)
+	}
+}
+
+// SetSize sets the size in bytes of the input or output of the operation, to
+// estimate its remaining time from the bytes read or written.
+func (p *Progress) SetSize(size int64) {
+	if p != nil {
+		atomic.StoreInt64(&p.size, size)
+	}
+}
+
+// Reader returns a reader counting the bytes read from r.
+func (p *Progress) Reader(r io.Reader) io.Reader {
+	if p == nil {
+		return r
+	}
+	return &reader{r: r, p: p}
+}
+
+// Writer returns a writer counting the bytes written to w.
+func (p *Progress) Writer(w io.Writer) io.Writer {
+	if p == nil {
+		return w
+	}
+	return &writer{w: w, p: p}
+}
+
+// Done stops reporting the progress, clearing the report on terminals and
+// writing the last log line otherwise, when reported.
+func (p *Progress) Done() {
+	if p == nil {
+		return
+	}
+	select {
+	case <-p.done:
+		return
+	default:
+	}
+	close(p.done)
+	p.wg.Wait()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	switch {
+	case !p.reported:
+	case p.tty:
+		fmt.Fprint(p.w, "\r\x1b[K")
+	default:
+		fmt.Fprintln(p.w, "progress: "+p.String())
+	}
+}
+
+// String returns the progress, as the rows processed, the throughput, and the
+// bytes processed with the estimated remaining time when the size is known.
+func (p *Progress) String() string {
+	rows, bytes, size := atomic.LoadInt64(&p.rows), atomic.LoadInt64(&p.bytes), atomic.LoadInt64(&p.size)
+	return format(p.name, rows, bytes, size, time.Since(p.start))
+}
+
+// format formats the progress of an operation.
+func format(name string, rows, bytes, size int64, d time.Duration) string {
+	secs := d.Seconds()
+	var parts []string
+	if rows != 0 || bytes == 0 {
+		s := fmt.Sprintf("%d rows", rows)
+		if secs >= 1 {
+			s += fmt.Sprintf(" (%d rows/s)", int64(float64(rows)/secs))
+		}
+		parts = append(parts, s)
+	}
+	switch {
+	case size > 0:
+		pct := float64(bytes) / float64(size) * 100
+		if pct > 100 {
+			pct = 100
+		}
+		parts = append(parts, fmt.Sprintf("%s of %s (%.0f%%)", sizes.FormatSize(bytes), sizes.FormatSize(size), pct))
+		if bytes != 0 && bytes < size {
+			eta := time.Duration(float64(d) * float64(size-bytes) / float64(bytes))
+			parts = append(parts, "ETA "+eta.Round(time.Second).String())
+		}
+	case bytes != 0:
+		s := sizes.FormatSize(bytes)
+		if rows == 0 && secs >= 1 {
+			s += fmt.Sprintf(" (%s/s)", sizes.FormatSize(int64(float64(bytes)/secs)))
+		}
+		parts = append(parts, s)
+	}
+	parts = append(parts, d.Round(time.Second).String())
+	return name + ": " + strings.Join(parts, ", ")
+}
+
+// reader counts the bytes read.
+type reader struct {
+	r io.Reader
+	p *Progress
+}
+
+// Read satisfies the io.Reader interface.
+func (r *reader) Read(b []byte) (int, error) {
+	n, err := r.r.Read(b)
+	atomic.AddInt64(&r.p.bytes, int64(n))
+	return n, err
+}
+
+// writer counts the bytes written.
+type writer struct {
+	w io.Writer
+	p *Progress
+}
+
+// Write satisfies the io.Writer interface.
+func (w *writer) Write(b []byte) (int, error) {
+	n, err := w.w.Write(b)
+	atomic.AddInt64(&w.p.bytes, int64(n))
+	return n, err
+}
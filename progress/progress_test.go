@@ -0,0 +1,81 @@
+package progress
+
+import (
+	"bytes"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		rows, bytes, size int64
+		d                 time.Duration
+		exp               string
+	}{
+		{0, 0, 0, 0, "op: 0 rows, 0s"},
+		{1500, 0, 0, 3 * time.Second, "op: 1500 rows (500 rows/s), 3s"},
+		{100, 0, 0, 500 * time.Millisecond, "op: 100 rows, 1s"},
+		{1000, 1 << 20, 4 << 20, 10 * time.Second, "op: 1000 rows (100 rows/s), 1.0 MB of 4.0 MB (25%), ETA 30s, 10s"},
+		{1000, 4 << 20, 4 << 20, 10 * time.Second, "op: 1000 rows (100 rows/s), 4.0 MB of 4.0 MB (100%), 10s"},
+		{0, 20 << 20, 0, 10 * time.Second, "op: 20.0 MB (2.0 MB/s), 10s"},
+		{10, 2048, 0, 2 * time.Second, "op: 10 rows (5 rows/s), 2.0 kB, 2s"},
+	}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			if s := format("op", test.rows, test.bytes, test.size, test.d); s != test.exp {
+				t.Errorf("expected %q, got: %q", test.exp, s)
+			}
+		})
+	}
+}
+
+func TestProgress(t *testing.T) {
+	interval := LogInterval
+	defer func() { LogInterval = interval }()
+	LogInterval = 10 * time.Millisecond
+	var buf bytes.Buffer
+	p := New(&buf, "import a.csv")
+	p.SetSize(8)
+	r := p.Reader(strings.NewReader("abcdefgh"))
+	if _, err := io.ReadAll(r); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	p.Add(2)
+	time.Sleep(50 * time.Millisecond)
+	p.Done()
+	p.Done()
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) < 2 {
+		t.Fatalf("expected log lines, got: %q", buf.String())
+	}
+	if s, exp := lines[len(lines)-1], "progress: import a.csv: 2 rows, 8 B of 8 B (100%), 0s"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+}
+
+func TestProgressNotReported(t *testing.T) {
+	var buf bytes.Buffer
+	p := New(&buf, "export")
+	w := p.Writer(io.Discard)
+	if _, err := w.Write([]byte("abc")); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	p.Done()
+	if buf.Len() != 0 {
+		t.Errorf("expected no report, got: %q", buf.String())
+	}
+}
+
+func TestNil(t *testing.T) {
+	var p *Progress
+	p.Add(1)
+	p.SetSize(1)
+	r := strings.NewReader("")
+	if p.Reader(r) != io.Reader(r) {
+		t.Errorf("expected reader")
+	}
+	p.Done()
+}
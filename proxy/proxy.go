@@ -0,0 +1,171 @@
+// Package proxy routes the connections to databases through SOCKS5 and HTTP
+// proxies, forwarding a local port to the database through the proxy, so that
+// the connections of any driver can be routed.
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/xo/usql/logging"
+	xproxy "golang.org/x/net/proxy"
+)
+
+// Dialer dials addresses through a proxy.
+type Dialer interface {
+	DialContext(ctx context.Context, network, addr string) (net.Conn, error)
+}
+
+// New returns the dialer of the proxy at urlstr: socks5://, socks5h://, or
+// http:// (with the CONNECT method), with optional USER:PASSWORD
+// credentials.
+func New(urlstr string) (Dialer, error) {
+	u, err := url.Parse(urlstr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy %q: %w", urlstr, err)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid proxy %q: no host", u.Redacted())
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h":
+		d, err := xproxy.FromURL(u, &net.Dialer{})
+		if err != nil {
+			return nil, err
+		}
+		if cd, ok := d.(xproxy.ContextDialer); ok {
+			return cd, nil
+		}
+		return nil, fmt.Errorf("invalid proxy %q: unsupported dialer", u.Redacted())
+	case "http":
+		return &httpDialer{u: u}, nil
+	}
+	return nil, fmt.Errorf("invalid proxy %q: expected socks5:// or http://", u.Redacted())
+}
+
+// httpDialer dials addresses through an HTTP proxy, with the CONNECT method.
+type httpDialer struct {
+	u *url.URL
+}
+
+// DialContext satisfies the Dialer interface.
+func (d *httpDialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	conn, err := (&net.Dialer{}).DialContext(ctx, network, d.u.Host)
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: make(http.Header),
+	}
+	if d.u.User != nil {
+		password, _ := d.u.User.Password()
+		auth := base64.StdEncoding.EncodeToString([]byte(d.u.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s: CONNECT %s: %s", d.u.Host, addr, res.Status)
+	}
+	_ = conn.SetDeadline(time.Time{})
+	if br.Buffered() != 0 {
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn is a connection whose first bytes were buffered while reading
+// the response of the proxy.
+type bufferedConn struct {
+	net.Conn
+	r io.Reader
+}
+
+// Read satisfies the net.Conn interface.
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.r.Read(b)
+}
+
+// forwarders are the local forwarders, by proxy and address.
+var forwarders = struct {
+	sync.Mutex
+	m map[[2]string]string
+}{m: make(map[[2]string]string)}
+
+// Forward returns the local address forwarded to addr through the proxy at
+// urlstr, listening on a port of the loopback interface until the process
+// exits. The forwarder of the proxy and address is reused.
+func Forward(urlstr, addr string) (string, error) {
+	forwarders.Lock()
+	defer forwarders.Unlock()
+	key := [2]string{urlstr, addr}
+	if local, ok := forwarders.m[key]; ok {
+		return local, nil
+	}
+	d, err := New(urlstr)
+	if err != nil {
+		return "", err
+	}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return "", err
+	}
+	go serve(l, d, addr)
+	local := l.Addr().String()
+	forwarders.m[key] = local
+	return local, nil
+}
+
+// serve accepts the connections of the listener, forwarding them to addr
+// through the dialer.
+func serve(l net.Listener, d Dialer, addr string) {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			remote, err := d.DialContext(context.Background(), "tcp", addr)
+			if err != nil {
+				logging.Verbosef("proxy: %v", err)
+				return
+			}
+			defer remote.Close()
+			done := make(chan struct{}, 2)
+			go func() {
+				_, _ = io.Copy(remote, conn)
+				done <- struct{}{}
+			}()
+			go func() {
+				_, _ = io.Copy(conn, remote)
+				done <- struct{}{}
+			}()
+			<-done
+		}()
+	}
+}
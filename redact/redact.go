@@ -0,0 +1,116 @@
+// Package redact removes secrets, such as passwords and tokens, from the
+// errors and messages written by usql.
+package redact
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Mask replaces redacted secrets.
+const Mask = "xxxxx"
+
+// secrets are the registered secrets.
+var secrets struct {
+	sync.RWMutex
+	m map[string]bool
+	// r replaces the secrets, longest first
+	r *strings.Replacer
+}
+
+// Add registers secrets to redact, such as the passwords of the config file.
+func Add(v ...string) {
+	secrets.Lock()
+	defer secrets.Unlock()
+	if secrets.m == nil {
+		secrets.m = make(map[string]bool)
+	}
+	for _, s := range v {
+		if s != "" {
+			secrets.m[s] = true
+		}
+	}
+	strs := make([]string, 0, len(secrets.m))
+	for s := range secrets.m {
+		strs = append(strs, s)
+	}
+	sort.Slice(strs, func(i, j int) bool {
+		return len(strs[i]) > len(strs[j])
+	})
+	var oldnew []string
+	for _, s := range strs {
+		oldnew = append(oldnew, s, Mask)
+	}
+	secrets.r = strings.NewReplacer(oldnew...)
+}
+
+var (
+	// urlPasswordRE matches the password of a URL.
+	urlPasswordRE = regexp.MustCompile(`(://[^/:@\s]*:)[^@\s]+@`)
+	// paramRE matches password or token parameters of a DSN.
+	paramRE = regexp.MustCompile(`(?i)\b((?:password|passwd|pwd|token|secret|access_?key)\s*=\s*)('[^']*'|"[^"]*"|[^\s&;,]+)`)
+)
+
+// String redacts the registered secrets, the passwords of URLs, and the
+// password and token parameters of DSNs in s.
+func String(s string) string {
+	s = urlPasswordRE.ReplaceAllString(s, "${1}"+Mask+"@")
+	s = paramRE.ReplaceAllString(s, "${1}"+Mask)
+	secrets.RLock()
+	defer secrets.RUnlock()
+	if secrets.r != nil {
+		s = secrets.r.Replace(s)
+	}
+	return s
+}
+
+// Error returns err with its message redacted. The returned error unwraps to
+// err.
+func Error(err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := err.Error()
+	if s := String(msg); s != msg {
+		return &redactedError{err: err, msg: s}
+	}
+	return err
+}
+
+// redactedError is an error with a redacted message.
+type redactedError struct {
+	err error
+	msg string
+}
+
+// Error satisfies the error interface.
+func (err *redactedError) Error() string {
+	return err.msg
+}
+
+// Unwrap returns the original error.
+func (err *redactedError) Unwrap() error {
+	return err.err
+}
+
+// Writer returns a writer redacting what is written to w.
+func Writer(w io.Writer) io.Writer {
+	return writer{w}
+}
+
+// writer redacts what is written to a writer.
+type writer struct {
+	w io.Writer
+}
+
+// Write satisfies the io.Writer interface.
+func (w writer) Write(p []byte) (int, error) {
+	s := String(string(p))
+	if _, err := io.WriteString(w.w, s); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,106 @@
+// Package replication reads the replication status of database hosts.
+package replication
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/xo/dburl"
+)
+
+// Status is the replication status of a database host.
+type Status struct {
+	// Replica is set when the host is a replica.
+	Replica bool
+	// Lag is how far the replica is behind its primary, or -1 when unknown,
+	// such as when replication is stopped.
+	Lag time.Duration
+}
+
+// Supported returns true when the replication status of the database can be
+// read.
+func Supported(u *dburl.URL) bool {
+	switch u.Unaliased {
+	case "postgres", "mysql":
+		return true
+	}
+	return false
+}
+
+// Read returns the replication status of the database host: whether it is a
+// replica (pg_is_in_recovery of postgres databases, the replica status of
+// mysql databases), and its lag.
+func Read(ctx context.Context, u *dburl.URL, db *sql.DB) (Status, error) {
+	switch u.Unaliased {
+	case "postgres":
+		return readPostgres(ctx, db)
+	case "mysql":
+		return readMySQL(ctx, db)
+	}
+	return Status{}, fmt.Errorf("the replication status of %s databases is not supported", u.Unaliased)
+}
+
+// postgresQuery is the query of the replication status of postgres hosts.
+// The lag of a replica that replayed all the WAL it received is 0, as the
+// time since the last replayed transaction only grows without writes on the
+// primary.
+const postgresQuery = `SELECT pg_is_in_recovery(), CASE ` +
+	`WHEN NOT pg_is_in_recovery() THEN 0 ` +
+	`WHEN pg_last_wal_receive_lsn() = pg_last_wal_replay_lsn() THEN 0 ` +
+	`ELSE EXTRACT(EPOCH FROM now() - pg_last_xact_replay_timestamp()) END`
+
+// readPostgres reads the replication status of a postgres host.
+func readPostgres(ctx context.Context, db *sql.DB) (Status, error) {
+	var st Status
+	var lag sql.NullFloat64
+	if err := db.QueryRowContext(ctx, postgresQuery).Scan(&st.Replica, &lag); err != nil {
+		return Status{}, err
+	}
+	st.Lag = -1
+	if lag.Valid {
+		st.Lag = time.Duration(lag.Float64 * float64(time.Second))
+	}
+	return st, nil
+}
+
+// readMySQL reads the replication status of a mysql host, with SHOW REPLICA
+// STATUS, or SHOW SLAVE STATUS before MySQL 8.0.22 and on MariaDB.
+func readMySQL(ctx context.Context, db *sql.DB) (Status, error) {
+	rows, err := db.QueryContext(ctx, "SHOW REPLICA STATUS")
+	if err != nil {
+		if rows, err = db.QueryContext(ctx, "SHOW SLAVE STATUS"); err != nil {
+			return Status{}, err
+		}
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return Status{}, err
+	}
+	if !rows.Next() {
+		// not a replica
+		return Status{}, rows.Err()
+	}
+	vals := make([]sql.NullString, len(cols))
+	ptrs := make([]interface{}, len(cols))
+	for i := range vals {
+		ptrs[i] = &vals[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return Status{}, err
+	}
+	st := Status{Replica: true, Lag: -1}
+	for i, col := range cols {
+		if (col == "Seconds_Behind_Source" || col == "Seconds_Behind_Master") && vals[i].Valid {
+			secs, err := strconv.ParseInt(vals[i].String, 10, 64)
+			if err != nil {
+				return Status{}, fmt.Errorf("invalid %s %q", col, vals[i].String)
+			}
+			st.Lag = time.Duration(secs) * time.Second
+		}
+	}
+	return st, nil
+}
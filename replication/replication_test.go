@@ -0,0 +1,22 @@
+package replication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/xo/dburl"
+)
+
+func TestSupported(t *testing.T) {
+	for driver, exp := range map[string]bool{"postgres": true, "mysql": true, "sqlite3": false, "sqlserver": false} {
+		u := &dburl.URL{Unaliased: driver}
+		if ok := Supported(u); ok != exp {
+			t.Errorf("expected %s supported %t, got: %t", driver, exp, ok)
+		}
+		if !exp {
+			if _, err := Read(context.Background(), u, nil); err == nil {
+				t.Errorf("expected error for %s, got nil", driver)
+			}
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Package resultdiff compares two results of a query, matching their rows by
+// key columns, and writes the added, removed and changed rows as a unified
+// diff.
+package resultdiff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Result is the result of a query.
+type Result struct {
+	Columns []string
+	Rows    [][]interface{}
+}
+
+// Change is an added, removed or changed row.
+type Change struct {
+	// Key are the values of the key columns of the row.
+	Key []string
+	// A is the row of the first result, nil when added, and B the row of
+	// the second result, nil when removed.
+	A, B []string
+}
+
+// Diff are the differences between two results.
+type Diff struct {
+	// Columns are the columns of the results, and Key the key columns.
+	Columns, Key []string
+	// RowsA and RowsB are the row counts of each result.
+	RowsA, RowsB int
+	// Changes are the removed and changed rows, in the order of the first
+	// result, followed by the added rows, in the order of the second result.
+	Changes []Change
+}
+
+// Compare compares the rows of results a and b, matched by the values of the
+// key columns (default the first column). Rows with the same key are matched
+// in order.
+func Compare(a, b *Result, key []string) (*Diff, error) {
+	if strings.Join(a.Columns, "\x00") != strings.Join(b.Columns, "\x00") {
+		return nil, fmt.Errorf("the results have different columns: %s and %s", strings.Join(a.Columns, ", "), strings.Join(b.Columns, ", "))
+	}
+	if len(a.Columns) == 0 {
+		return nil, fmt.Errorf("the results have no columns")
+	}
+	if len(key) == 0 {
+		key = a.Columns[:1]
+	}
+	idx := make([]int, len(key))
+	for i, k := range key {
+		idx[i] = -1
+		for j, c := range a.Columns {
+			if strings.EqualFold(c, k) {
+				idx[i] = j
+				break
+			}
+		}
+		if idx[i] == -1 {
+			return nil, fmt.Errorf("no column %s in the results", k)
+		}
+	}
+	rowsA, rowsB := format(a.Rows), format(b.Rows)
+	d := &Diff{Columns: a.Columns, Key: key, RowsA: len(rowsA), RowsB: len(rowsB)}
+	// the unmatched rows of b, by key
+	unmatched := make(map[string][]int)
+	for i, row := range rowsB {
+		k := keyOf(row, idx)
+		unmatched[k] = append(unmatched[k], i)
+	}
+	matched := make([]bool, len(rowsB))
+	for _, row := range rowsA {
+		k := keyOf(row, idx)
+		v := unmatched[k]
+		if len(v) == 0 {
+			d.Changes = append(d.Changes, Change{Key: values(row, idx), A: row})
+			continue
+		}
+		i := v[0]
+		unmatched[k], matched[i] = v[1:], true
+		if !equal(row, rowsB[i]) {
+			d.Changes = append(d.Changes, Change{Key: values(row, idx), A: row, B: rowsB[i]})
+		}
+	}
+	for i, row := range rowsB {
+		if !matched[i] {
+			d.Changes = append(d.Changes, Change{Key: values(row, idx), B: row})
+		}
+	}
+	return d, nil
+}
+
+// Counts returns the number of added, removed and changed rows.
+func (d *Diff) Counts() (added, removed, changed int) {
+	for _, c := range d.Changes {
+		switch {
+		case c.A == nil:
+			added++
+		case c.B == nil:
+			removed++
+		default:
+			changed++
+		}
+	}
+	return
+}
+
+// Write writes the differences as a unified diff of the results named a and
+// b: a hunk per row, headed by its key, with the row of a prefixed by - and
+// the row of b by +.
+func (d *Diff) Write(w io.Writer, a, b string) error {
+	fmt.Fprintf(w, "--- %s (%d rows)\n", a, d.RowsA)
+	fmt.Fprintf(w, "+++ %s (%d rows)\n", b, d.RowsB)
+	fmt.Fprintf(w, " %s\n", strings.Join(d.Columns, " | "))
+	for _, c := range d.Changes {
+		kv := make([]string, len(d.Key))
+		for i, k := range d.Key {
+			kv[i] = k + "=" + c.Key[i]
+		}
+		fmt.Fprintf(w, "@@ %s @@\n", strings.Join(kv, ", "))
+		if c.A != nil {
+			fmt.Fprintf(w, "-%s\n", strings.Join(c.A, " | "))
+		}
+		if c.B != nil {
+			fmt.Fprintf(w, "+%s\n", strings.Join(c.B, " | "))
+		}
+	}
+	added, removed, changed := d.Counts()
+	_, err := fmt.Fprintf(w, "(%d added, %d removed, %d changed)\n", added, removed, changed)
+	return err
+}
+
+// format formats the values of the rows.
+func format(rows [][]interface{}) [][]string {
+	v := make([][]string, len(rows))
+	for i, row := range rows {
+		v[i] = make([]string, len(row))
+		for j, x := range row {
+			v[i][j] = formatValue(x)
+		}
+	}
+	return v
+}
+
+// formatValue formats a value.
+func formatValue(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return "NULL"
+	case []byte:
+		return string(x)
+	case time.Time:
+		return x.Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(v)
+}
+
+// keyOf returns the key of a row, of the values of the columns idx.
+func keyOf(row []string, idx []int) string {
+	return strings.Join(values(row, idx), "\x00")
+}
+
+// values returns the values of the columns idx of a row.
+func values(row []string, idx []int) []string {
+	v := make([]string, len(idx))
+	for i, j := range idx {
+		v[i] = row[j]
+	}
+	return v
+}
+
+// equal returns true when the rows are equal.
+func equal(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,59 @@
+package resultdiff
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestCompare(t *testing.T) {
+	cols := []string{"id", "name", "total"}
+	a := &Result{Columns: cols, Rows: [][]interface{}{
+		{int64(1), "alice", int64(5)},
+		{int64(2), "bob", int64(10)},
+		{int64(3), "carol", nil},
+	}}
+	b := &Result{Columns: cols, Rows: [][]interface{}{
+		{int64(1), "alice", int64(5)},
+		{int64(2), "bob", int64(12)},
+		{int64(4), []byte("dave"), int64(1)},
+	}}
+	d, err := Compare(a, b, nil)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if added, removed, changed := d.Counts(); added != 1 || removed != 1 || changed != 1 {
+		t.Errorf("expected 1 added, removed and changed rows, got: %d %d %d", added, removed, changed)
+	}
+	var buf bytes.Buffer
+	if err := d.Write(&buf, "previous", "last"); err != nil {
+		t.Fatal(err)
+	}
+	exp := `--- previous (3 rows)
++++ last (3 rows)
+ id | name | total
+@@ id=2 @@
+-2 | bob | 10
++2 | bob | 12
+@@ id=3 @@
+-3 | carol | NULL
+@@ id=4 @@
++4 | dave | 1
+(1 added, 1 removed, 1 changed)
+`
+	if s := buf.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+	// keyed by all the columns, rows are only added or removed
+	if d, err = Compare(a, b, cols); err != nil {
+		t.Fatal(err)
+	}
+	if added, removed, changed := d.Counts(); added != 2 || removed != 2 || changed != 0 {
+		t.Errorf("expected 2 added and removed rows, got: %d %d %d", added, removed, changed)
+	}
+	if _, err := Compare(a, b, []string{"missing"}); err == nil {
+		t.Errorf("expected error for unknown key column, got nil")
+	}
+	if _, err := Compare(a, &Result{Columns: cols[:2]}, nil); err == nil {
+		t.Errorf("expected error for different columns, got nil")
+	}
+}
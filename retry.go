@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/handler"
+	"github.com/xo/usql/logging"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/pkg/conn"
+	"github.com/xo/usql/tracing"
+)
+
+// openWithRetry opens dsn on the handler, retrying up to dbConfig.Retries
+// times with exponential backoff when the connection fails with a transient
+// error. alias is the database alias of dsn in the config file, if any.
+func openWithRetry(ctx context.Context, h *handler.Handler, alias, dsn string, dbConfig *config.DatabaseConfig) (err error) {
+	var dbType string
+	if dbConfig != nil {
+		dbType = dbConfig.DbType
+	}
+	ctx, span := tracing.Start(ctx, "connect", tracing.Attrs(nil, alias, dbType)...)
+	defer func() {
+		if err == nil {
+			span.SetAttributes(tracing.Attrs(h.URL(), "", "")...)
+		}
+		tracing.End(span, err)
+	}()
+	if u, err := dburl.Parse(dsn); err == nil {
+		logging.Verbosef("connecting to %s", u.Redacted())
+	}
+	return conn.Retry(ctx, dbConfig, os.Stderr, func() error {
+		return h.Open(ctx, dsn)
+	})
+}
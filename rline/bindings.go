@@ -0,0 +1,86 @@
+package rline
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/gohxs/readline"
+)
+
+// actions are the editing actions that can be bound to keys.
+var actions = map[string]rune{
+	"abort":                  readline.CharBell,
+	"accept-line":            readline.CharEnter,
+	"backward-char":          readline.CharBackward,
+	"backward-delete-char":   readline.CharBackspace,
+	"backward-kill-word":     readline.MetaBackspace,
+	"backward-word":          readline.MetaBackward,
+	"beginning-of-line":      readline.CharLineStart,
+	"clear-screen":           readline.CharCtrlL,
+	"complete":               readline.CharTab,
+	"delete-char":            readline.CharDelete,
+	"end-of-line":            readline.CharLineEnd,
+	"forward-char":           readline.CharForward,
+	"forward-search-history": readline.CharFwdSearch,
+	"forward-word":           readline.MetaForward,
+	"kill-line":              readline.CharKill,
+	"kill-word":              readline.MetaDelete,
+	"next-history":           readline.CharNext,
+	"previous-history":       readline.CharPrev,
+	"reverse-search-history": readline.CharBckSearch,
+	"transpose-chars":        readline.CharTranspose,
+	"unix-line-discard":      readline.CharCtrlU,
+	"unix-word-rubout":       readline.CharCtrlW,
+	// none disables the key
+	"none": 0,
+}
+
+// metaKeys are the meta keys that can be bound.
+var metaKeys = map[string]rune{
+	"M-b":         readline.MetaBackward,
+	"M-f":         readline.MetaForward,
+	"M-d":         readline.MetaDelete,
+	"M-t":         readline.MetaTranspose,
+	"M-backspace": readline.MetaBackspace,
+}
+
+// ParseBindings parses key bindings, in the form of comma separated KEY=ACTION
+// pairs (ie, C-o=accept-line,M-b=none), returning the actions of the keys.
+//
+// Keys are control keys (C-a through C-z) or meta keys (M-b, M-f, M-d, M-t,
+// M-backspace), and actions are named after their GNU Readline equivalent.
+func ParseBindings(s string) (map[rune]rune, error) {
+	m := make(map[rune]rune)
+	for _, b := range strings.Split(s, ",") {
+		if b = strings.TrimSpace(b); b == "" {
+			continue
+		}
+		key, action, ok := strings.Cut(b, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid key binding %q", b)
+		}
+		k, err := parseKey(strings.TrimSpace(key))
+		if err != nil {
+			return nil, err
+		}
+		r, ok := actions[strings.ToLower(strings.TrimSpace(action))]
+		if !ok {
+			return nil, fmt.Errorf("unknown editing action %q", strings.TrimSpace(action))
+		}
+		m[k] = r
+	}
+	return m, nil
+}
+
+// parseKey parses a key name.
+func parseKey(s string) (rune, error) {
+	if r, ok := metaKeys[s]; ok {
+		return r, nil
+	}
+	if len(s) == 3 && (s[0] == 'C' || s[0] == 'c') && s[1] == '-' {
+		if c := s[2] | 0x20; 'a' <= c && c <= 'z' {
+			return rune(c-'a') + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown key %q", s)
+}
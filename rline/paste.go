@@ -0,0 +1,58 @@
+package rline
+
+import (
+	"bytes"
+	"io"
+)
+
+// bracketed paste terminal sequences.
+var (
+	pasteOn    = []byte("\x1b[?2004h")
+	pasteOff   = []byte("\x1b[?2004l")
+	pasteStart = []byte("\x1b[200~")
+	pasteEnd   = []byte("\x1b[201~")
+)
+
+// pasteReader handles the bracketed pastes of a terminal, stripping their
+// start and end sequences, and replacing the tabs of the pasted text with
+// spaces, so that they do not trigger completion.
+type pasteReader struct {
+	io.ReadCloser
+	pasting bool
+}
+
+// Read satisfies the io.Reader interface.
+func (r *pasteReader) Read(p []byte) (int, error) {
+	for {
+		n, err := r.ReadCloser.Read(p)
+		if n == 0 {
+			return n, err
+		}
+		// retry when only read the sequences
+		if n = r.strip(p[:n]); n != 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// strip strips the bracketed paste sequences of buf in place, returning the
+// resulting length.
+func (r *pasteReader) strip(buf []byte) int {
+	j := 0
+	for i := 0; i < len(buf); {
+		switch {
+		case bytes.HasPrefix(buf[i:], pasteStart):
+			r.pasting, i = true, i+len(pasteStart)
+			continue
+		case bytes.HasPrefix(buf[i:], pasteEnd):
+			r.pasting, i = false, i+len(pasteEnd)
+			continue
+		case r.pasting && buf[i] == '\t':
+			buf[j] = ' '
+		default:
+			buf[j] = buf[i]
+		}
+		i, j = i+1, j+1
+	}
+	return j
+}
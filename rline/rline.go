@@ -5,9 +5,12 @@ import (
 	"errors"
 	"io"
 	"os"
+	"strings"
+	"sync"
 
 	"github.com/gohxs/readline"
 	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/redact"
 )
 
 var (
@@ -37,10 +40,19 @@ type IO interface {
 	Completer(readline.AutoCompleter)
 	// Save saves a line of history.
 	Save(string) error
+	// History returns the saved lines of history.
+	History() ([]string, error)
 	// Password prompts for a password.
 	Password(string) (string, error)
 	// SetOutput sets the output filter func.
 	SetOutput(func(string) string)
+	// Suggester sets the func suggesting the completion of the line being
+	// edited, shown after it and accepted with the right arrow.
+	Suggester(func(string) string)
+	// SetEditMode sets the vi (or emacs) editing mode.
+	SetEditMode(vi bool)
+	// SetBindings sets the key bindings (see ParseBindings).
+	SetBindings(map[rune]rune)
 }
 
 // Rline provides a type compatible with the IO interface.
@@ -55,7 +67,12 @@ type Rline struct {
 	P    func(string)
 	A    func(readline.AutoCompleter)
 	S    func(string) error
+	H    func() ([]string, error)
 	Pw   func(string) (string, error)
+	O    func(func(string) string)
+	Sg   func(func(string) string)
+	V    func(bool)
+	B    func(map[rune]rune)
 }
 
 // Next returns the next line of runes (excluding '\n') from the input.
@@ -116,6 +133,14 @@ func (l *Rline) Save(s string) error {
 	return nil
 }
 
+// History returns the saved lines of history.
+func (l *Rline) History() ([]string, error) {
+	if l.H != nil {
+		return l.H()
+	}
+	return nil, nil
+}
+
 // Password prompts for a password.
 func (l *Rline) Password(prompt string) (string, error) {
 	if l.Pw != nil {
@@ -126,7 +151,31 @@ func (l *Rline) Password(prompt string) (string, error) {
 
 // SetOutput sets the output format func.
 func (l *Rline) SetOutput(f func(string) string) {
-	l.Inst.Config.Output = f
+	if l.O != nil {
+		l.O(f)
+	}
+}
+
+// Suggester sets the func suggesting the completion of the line being edited,
+// shown after it and accepted with the right arrow.
+func (l *Rline) Suggester(f func(string) string) {
+	if l.Sg != nil {
+		l.Sg(f)
+	}
+}
+
+// SetEditMode sets the vi (or emacs) editing mode.
+func (l *Rline) SetEditMode(vi bool) {
+	if l.V != nil {
+		l.V(vi)
+	}
+}
+
+// SetBindings sets the key bindings (see ParseBindings).
+func (l *Rline) SetBindings(bindings map[rune]rune) {
+	if l.B != nil {
+		l.B(bindings)
+	}
 }
 
 // New creates a new readline input/output handler.
@@ -156,20 +205,32 @@ func New(forceNonInteractive bool, out, histfile string) (IO, error) {
 		}
 		closers = append(closers, stdout.Close)
 		interactive = false
-	case cygwin:
+	case cygwin, enableVT(os.Stdout):
+		// write the escape sequences as is, instead of translating them to
+		// the console api calls of older windows consoles
 		stdout = os.Stdout
 	default:
 		stdout = readline.Stdout
 	}
 	// configure stderr
 	var stderr io.Writer = os.Stderr
-	if !cygwin {
+	if !cygwin && !enableVT(os.Stderr) {
 		stderr = readline.Stderr
 	}
 	if interactive {
-		// wrap it with cancelable stdin
-		stdin = readline.NewCancelableStdin(stdin)
+		// enable bracketed paste, and wrap it with cancelable stdin
+		_, _ = stdout.Write(pasteOn)
+		closers = append(closers, func() error {
+			_, err := stdout.Write(pasteOff)
+			return err
+		})
+		stdin = readline.NewCancelableStdin(&pasteReader{ReadCloser: stdin})
 	}
+	// key bindings, changed while reading lines
+	var mu sync.RWMutex
+	var bindings map[rune]rune
+	// suggested completions, shown after the output of the line
+	g := new(ghost)
 	// create readline instance
 	l, err := readline.NewEx(&readline.Config{
 		HistoryFile:            histfile,
@@ -182,7 +243,14 @@ func New(forceNonInteractive bool, out, histfile string) (IO, error) {
 		FuncIsTerminal: func() bool {
 			return interactive || cygwin
 		},
+		Output:   g.output,
+		Listener: readline.FuncListener(g.listen),
 		FuncFilterInputRune: func(r rune) (rune, bool) {
+			mu.RLock()
+			defer mu.RUnlock()
+			if b, ok := bindings[r]; ok {
+				return b, b != 0
+			}
 			if r == readline.CharCtrlZ {
 				return r, false
 			}
@@ -193,6 +261,7 @@ func New(forceNonInteractive bool, out, histfile string) (IO, error) {
 		return nil, err
 	}
 	closers = append(closers, l.Close)
+	g.inst = l
 	n := l.Operation.Runes
 	pw := func(prompt string) (string, error) {
 		buf, err := l.ReadPassword(prompt)
@@ -214,16 +283,40 @@ func New(forceNonInteractive bool, out, histfile string) (IO, error) {
 			return nil
 		},
 		Out: stdout,
-		Err: stderr,
+		Err: redact.Writer(stderr),
 		Int: interactive || cygwin,
 		Cyg: cygwin,
-		P:   l.SetPrompt,
+		P: func(prompt string) {
+			g.setPrompt(prompt)
+			l.SetPrompt(prompt)
+		},
 		A: func(a readline.AutoCompleter) {
 			cfg := l.Config.Clone()
 			cfg.AutoComplete = a
 			l.SetConfig(cfg)
 		},
-		S:  l.SaveHistory,
+		S: l.SaveHistory,
+		H: func() ([]string, error) {
+			if histfile == "" {
+				return nil, nil
+			}
+			buf, err := os.ReadFile(histfile)
+			switch {
+			case os.IsNotExist(err):
+				return nil, nil
+			case err != nil:
+				return nil, err
+			}
+			return strings.Split(strings.TrimSuffix(string(buf), "\n"), "\n"), nil
+		},
 		Pw: pw,
+		O:  g.setFilter,
+		Sg: g.setSuggest,
+		V:  l.SetVimMode,
+		B: func(m map[rune]rune) {
+			mu.Lock()
+			defer mu.Unlock()
+			bindings = m
+		},
 	}, nil
 }
@@ -0,0 +1,111 @@
+package rline
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/gohxs/readline"
+	"github.com/gohxs/readline/runes"
+)
+
+// ghost is the suggested completion of the line being edited, shown after the
+// line as dimmed text (fish-style), and accepted with the forward-char (→)
+// and end-of-line keys at the end of the line.
+type ghost struct {
+	sync.Mutex
+	inst *readline.Instance
+	// filter is the output filter of the line (see Rline.SetOutput).
+	filter func(string) string
+	// suggest returns the completion of a line.
+	suggest func(string) string
+	// prompt is the prompt of the line.
+	prompt string
+	// line is the line the completion was suggested for.
+	line string
+	text string
+}
+
+// setFilter sets the output filter of the line.
+func (g *ghost) setFilter(f func(string) string) {
+	g.Lock()
+	defer g.Unlock()
+	g.filter = f
+}
+
+// setSuggest sets the func suggesting the completion of the line.
+func (g *ghost) setSuggest(f func(string) string) {
+	g.Lock()
+	defer g.Unlock()
+	g.suggest, g.line, g.text = f, "", ""
+}
+
+// setPrompt sets the prompt of the line.
+func (g *ghost) setPrompt(prompt string) {
+	g.Lock()
+	defer g.Unlock()
+	g.prompt = prompt
+}
+
+// listen is the listener of the keys of the line, suggesting the completion
+// of the line after each key, when the cursor is at its end, and accepting
+// it.
+func (g *ghost) listen(line []rune, pos int, key rune) ([]rune, int, bool) {
+	g.Lock()
+	defer g.Unlock()
+	if g.suggest == nil || key == 0 || !g.inst.Operation.IsNormalMode() {
+		g.line, g.text = "", ""
+		return nil, 0, false
+	}
+	s, text := string(line), g.text
+	if (key == readline.CharForward || key == readline.CharLineEnd) && pos == len(line) && s == g.line && text != "" {
+		line = append(line, []rune(text)...)
+		g.line = string(line)
+		g.text = g.suggest(g.line)
+		return line, len(line), true
+	}
+	g.line, g.text = s, ""
+	if pos == len(line) {
+		g.text = g.suggest(s)
+	}
+	// redraw the line with the changed completion, except after the line
+	// was accepted
+	return line, pos, g.text != text && s != ""
+}
+
+// output is the output of the line displayed as s (see
+// readline.Config.Output): the filtered line, followed by its dimmed
+// completion truncated to the width of the terminal, and the moves of the
+// cursor back to the end of the line.
+func (g *ghost) output(s string) string {
+	g.Lock()
+	filter, prompt, line, completion := g.filter, g.prompt, g.line, g.text
+	g.Unlock()
+	out := s
+	if filter != nil {
+		out = filter(s)
+	}
+	if completion == "" || s != strings.ReplaceAll(line, "\t", strings.Repeat(" ", readline.TabWidth)) {
+		return out
+	}
+	width := g.inst.Config.FuncGetWidth()
+	if width <= 0 {
+		return out
+	}
+	// the rest of the current line of the terminal, keeping the last column
+	// for the cursor
+	n := runes.WidthAll(runes.ColorFilter([]rune(prompt))) + runes.WidthAll([]rune(s))
+	n = width - n%width - 1
+	var text []rune
+	for _, r := range completion {
+		w := runes.Width(r)
+		if r == '\t' || r == '\n' || n < w {
+			break
+		}
+		text, n = append(text, r), n-w
+	}
+	if len(text) == 0 {
+		return out
+	}
+	return fmt.Sprintf("%s\x1b[90m%s\x1b[0m\x1b[%dD", out, string(text), runes.WidthAll(text))
+}
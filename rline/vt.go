@@ -0,0 +1,10 @@
+//go:build !windows
+
+package rline
+
+import "os"
+
+// enableVT returns true, as terminals process ANSI escape sequences.
+func enableVT(*os.File) bool {
+	return true
+}
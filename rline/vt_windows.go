@@ -0,0 +1,22 @@
+package rline
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// enableVT enables the processing of the ANSI escape sequences written to the
+// console of f (the virtual terminal of Windows 10 and later, and of ConPTY),
+// returning false when the console does not support it.
+func enableVT(f *os.File) bool {
+	h := windows.Handle(f.Fd())
+	var mode uint32
+	if err := windows.GetConsoleMode(h, &mode); err != nil {
+		return false
+	}
+	if mode&windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING != 0 {
+		return true
+	}
+	return windows.SetConsoleMode(h, mode|windows.ENABLE_VIRTUAL_TERMINAL_PROCESSING) == nil
+}
@@ -0,0 +1,172 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed cron expression.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// domAny and dowAny are set when the day of month or day of week field is
+	// *, as a day matches either field when both are restricted
+	domAny, dowAny bool
+	// every is the interval of @every expressions
+	every time.Duration
+}
+
+// descriptors are the predefined cron expressions.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// cronFields are the bounds of the fields of a cron expression.
+var cronFields = []struct {
+	name     string
+	min, max int
+	names    []string
+}{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}},
+}
+
+// ParseCron parses a cron expression: the 5 minute, hour, day of month,
+// month and day of week fields, a descriptor such as @daily, or @every
+// DURATION.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if s := strings.TrimPrefix(expr, "@every "); s != expr {
+		d, err := time.ParseDuration(strings.TrimSpace(s))
+		if err != nil || d < time.Second {
+			return nil, fmt.Errorf("invalid cron expression %q: invalid duration", expr)
+		}
+		return &Cron{every: d}, nil
+	}
+	if s, ok := descriptors[expr]; ok {
+		expr = s
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid cron expression %q: expected %d fields", expr, len(cronFields))
+	}
+	var bits [5]uint64
+	for i, field := range fields {
+		var err error
+		if bits[i], err = parseField(field, i); err != nil {
+			return nil, fmt.Errorf("invalid cron expression %q: %w", expr, err)
+		}
+	}
+	// sunday is both 0 and 7
+	if bits[4]&(1<<7) != 0 {
+		bits[4] |= 1
+	}
+	return &Cron{
+		minute: bits[0],
+		hour:   bits[1],
+		dom:    bits[2],
+		month:  bits[3],
+		dow:    bits[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}, nil
+}
+
+// parseField parses the i'th field of a cron expression to a bit set.
+func parseField(field string, i int) (uint64, error) {
+	f := cronFields[i]
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, 1
+		if j := strings.Index(part, "/"); j != -1 {
+			var err error
+			if step, err = strconv.Atoi(part[j+1:]); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid %s step %q", f.name, part[j+1:])
+			}
+			rng = part[:j]
+		}
+		lo, hi := f.min, f.max
+		if rng != "*" {
+			var err error
+			s, e, isRange := strings.Cut(rng, "-")
+			if lo, err = fieldValue(s, i); err != nil {
+				return 0, err
+			}
+			hi = lo
+			if isRange {
+				if hi, err = fieldValue(e, i); err != nil {
+					return 0, err
+				}
+			} else if step != 1 {
+				hi = f.max
+			}
+			if hi < lo {
+				return 0, fmt.Errorf("invalid %s range %q", f.name, rng)
+			}
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// fieldValue parses a value of the i'th field of a cron expression.
+func fieldValue(s string, i int) (int, error) {
+	f := cronFields[i]
+	for j, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + j, nil
+		}
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil || v < f.min || v > f.max {
+		return 0, fmt.Errorf("invalid %s %q", f.name, s)
+	}
+	return v, nil
+}
+
+// Next returns the first time matching the expression after t, or the zero
+// time when none is found within 5 years.
+func (c *Cron) Next(t time.Time) time.Time {
+	if c.every != 0 {
+		return t.Add(c.every).Truncate(time.Second)
+	}
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	end := t.AddDate(5, 0, 0)
+	for t.Before(end) {
+		switch {
+		case c.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !c.matchDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case c.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case c.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchDay returns true when the day of t matches the day of month and day
+// of week fields.
+func (c *Cron) matchDay(t time.Time) bool {
+	dom, dow := c.dom&(1<<uint(t.Day())) != 0, c.dow&(1<<uint(t.Weekday())) != 0
+	if c.domAny || c.dowAny {
+		return dom && dow
+	}
+	return dom || dow
+}
@@ -0,0 +1,392 @@
+// Package schedule runs the jobs of a schedules file, executing statements
+// against database aliases on cron expressions and writing their results to
+// files or webhooks.
+package schedule
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/tblfmt"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/notify"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/stmt"
+	"gopkg.in/yaml.v2"
+)
+
+// DefaultFormat is the default output format of jobs.
+const DefaultFormat = "csv"
+
+// Config is a schedules file.
+type Config struct {
+	// Path is the absolute path of the schedules file.
+	Path string `yaml:"-"`
+	// Jobs are the jobs, by name.
+	Jobs map[string]*Job `yaml:"jobs"`
+}
+
+// Job is a statement executed against a database alias on a cron
+// expression.
+type Job struct {
+	// Cron is the cron expression of the job.
+	Cron string `yaml:"cron"`
+	// DB is the database alias of the config file.
+	DB string `yaml:"db"`
+	// Role is the role of the database alias, when not the default role.
+	Role string `yaml:"role"`
+	// SQL is the statement of the job.
+	SQL string `yaml:"sql"`
+	// Query is the name of a query template of the config file, used instead
+	// of SQL.
+	Query string `yaml:"query"`
+	// Params are the values of the query template parameters.
+	Params map[string]string `yaml:"params"`
+	// Format is the output format of the results (csv, json, aligned, ...).
+	Format string `yaml:"format"`
+	// Output is the path of the file the results are written to, relative to
+	// the schedules file. It's a template, with the job name as {{.Job}} and
+	// the run time as {{.Time}}.
+	Output string `yaml:"output"`
+	// Webhook is the URL the results are posted to.
+	Webhook string `yaml:"webhook"`
+
+	cron   *Cron
+	output *template.Template
+}
+
+// Load loads the schedules file at path.
+func Load(path string) (*Config, error) {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Config{Path: path}
+	if err := yaml.Unmarshal(buf, c); err != nil {
+		return nil, fmt.Errorf("invalid schedules file %s: %w", path, err)
+	}
+	if len(c.Jobs) == 0 {
+		return nil, fmt.Errorf("no jobs in schedules file %s", path)
+	}
+	for name, job := range c.Jobs {
+		if err := job.init(); err != nil {
+			return nil, fmt.Errorf("job %s: %w", name, err)
+		}
+	}
+	return c, nil
+}
+
+// init validates the job, parsing its cron expression and output.
+func (job *Job) init() error {
+	switch {
+	case job == nil:
+		return fmt.Errorf("empty job")
+	case job.DB == "":
+		return fmt.Errorf("db is required")
+	case (job.SQL == "") == (job.Query == ""):
+		return fmt.Errorf("exactly one of sql or query is required")
+	case job.Params != nil && job.Query == "":
+		return fmt.Errorf("params require a query")
+	}
+	var err error
+	if job.cron, err = ParseCron(job.Cron); err != nil {
+		return err
+	}
+	if job.Output != "" {
+		if job.output, err = template.New("output").Parse(job.Output); err != nil {
+			return fmt.Errorf("invalid output: %w", err)
+		}
+	}
+	return nil
+}
+
+// Names returns the sorted names of the jobs.
+func (c *Config) Names() []string {
+	names := make([]string, 0, len(c.Jobs))
+	for name := range c.Jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Opener opens a connection to a database alias, using the credentials of
+// the role (or the default role, when empty).
+type Opener func(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error)
+
+// Runner runs the jobs of a schedules file.
+type Runner struct {
+	Config *Config
+	// Open opens a connection to an alias.
+	Open Opener
+	// Query returns a query template of the config file.
+	Query func(name string) (*config.QueryConfig, error)
+	// Stdout is where the results of jobs without output or webhook are
+	// written.
+	Stdout io.Writer
+	// Log is where the runs of the jobs are logged.
+	Log io.Writer
+	// Client is the client of webhooks, http.DefaultClient when nil.
+	Client *http.Client
+	// Notifier, when set, notifies the finished runs of the jobs.
+	Notifier *notify.Notifier
+}
+
+// Run runs the jobs on their cron expressions until the context is done,
+// waiting for the running jobs. The runs of the jobs are logged to Log.
+func (r *Runner) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	var mu sync.Mutex
+	running := make(map[string]bool)
+	now := time.Now()
+	next := make(map[string]time.Time, len(r.Config.Jobs))
+	for name, job := range r.Config.Jobs {
+		next[name] = job.cron.Next(now)
+	}
+	for {
+		var at time.Time
+		for _, t := range next {
+			if !t.IsZero() && (at.IsZero() || t.Before(at)) {
+				at = t
+			}
+		}
+		if at.IsZero() {
+			return fmt.Errorf("no job is scheduled")
+		}
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		for _, name := range r.Config.Names() {
+			if next[name] != at {
+				continue
+			}
+			next[name] = r.Config.Jobs[name].cron.Next(at)
+			mu.Lock()
+			skip := running[name]
+			running[name] = true
+			mu.Unlock()
+			if skip {
+				fmt.Fprintf(r.Log, "job %s: still running, skipped run at %s\n", name, at.Format(time.RFC3339))
+				continue
+			}
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				start := time.Now()
+				if err := r.RunJob(ctx, name, at); err != nil {
+					fmt.Fprintf(r.Log, "error: job %s: %v\n", name, err)
+				} else {
+					fmt.Fprintf(r.Log, "job %s: done in %s\n", name, time.Since(start).Round(time.Millisecond))
+				}
+				mu.Lock()
+				delete(running, name)
+				mu.Unlock()
+			}(name)
+		}
+	}
+}
+
+// RunJob runs the job name for the run time t, writing its results.
+func (r *Runner) RunJob(ctx context.Context, name string, t time.Time) error {
+	job, ok := r.Config.Jobs[name]
+	if !ok {
+		return fmt.Errorf("unknown job %q", name)
+	}
+	start := time.Now()
+	rows, err := r.run(ctx, name, job, t)
+	if r.Notifier != nil {
+		e := notify.Event{Name: name, SQL: job.SQL, Duration: time.Since(start), Rows: rows, Err: err}
+		if err := r.Notifier.Notify(ctx, e); err != nil {
+			fmt.Fprintf(r.Log, "error: job %s: notify: %v\n", name, err)
+		}
+	}
+	return err
+}
+
+// run executes the statement of the job and writes its results, returning
+// the number of rows it returned or affected, or -1 when unknown.
+func (r *Runner) run(ctx context.Context, name string, job *Job, t time.Time) (int64, error) {
+	u, db, err := r.Open(ctx, job.DB, job.Role)
+	if err != nil {
+		return -1, err
+	}
+	defer db.Close()
+	sqlstr, args := job.SQL, []interface{}(nil)
+	if job.Query != "" {
+		q, err := r.Query(job.Query)
+		if err != nil {
+			return -1, err
+		}
+		if sqlstr, args, err = q.Render(job.Params, func(n int) string {
+			return drivers.Placeholder(u, n)
+		}); err != nil {
+			return -1, fmt.Errorf("query %s: %w", job.Query, err)
+		}
+	}
+	stmts, err := drivers.Statements(u, sqlstr)
+	switch {
+	case err != nil:
+		return -1, err
+	case len(stmts) != 1:
+		return -1, fmt.Errorf("expected exactly 1 statement, got %d", len(stmts))
+	}
+	typ, sqlstr, isQuery, err := drivers.Process(u, stmt.FindPrefix(stmts[0], true, true, true), stmts[0])
+	if err != nil {
+		return -1, err
+	}
+	var buf bytes.Buffer
+	var n int64
+	if isQuery {
+		n, err = execQuery(ctx, &buf, db, job, sqlstr, args)
+	} else {
+		n, err = execExec(ctx, &buf, u, db, typ, sqlstr, args)
+	}
+	if err != nil {
+		return -1, drivers.WrapErr(u.Driver, err)
+	}
+	return n, r.write(ctx, name, job, t, buf.Bytes())
+}
+
+// execQuery executes a query, writing its results to w and returning the
+// number of rows.
+func execQuery(ctx context.Context, w io.Writer, db *sql.DB, job *Job, sqlstr string, args []interface{}) (int64, error) {
+	rows, err := db.QueryContext(ctx, sqlstr, args...)
+	if err != nil {
+		return -1, err
+	}
+	defer rows.Close()
+	rc := &rowCounter{Rows: rows}
+	if err := tblfmt.EncodeAll(w, rc, map[string]string{"format": job.format()}); err != nil {
+		return -1, err
+	}
+	return rc.n, rows.Err()
+}
+
+// execExec executes a statement that returns no rows, writing and returning
+// the number of affected rows.
+func execExec(ctx context.Context, w io.Writer, u *dburl.URL, db *sql.DB, typ, sqlstr string, args []interface{}) (int64, error) {
+	res, err := db.ExecContext(ctx, sqlstr, args...)
+	if err != nil {
+		return -1, err
+	}
+	n, err := drivers.RowsAffected(u, res)
+	if err != nil {
+		return -1, err
+	}
+	fmt.Fprintf(w, "%s %d\n", typ, n)
+	return n, nil
+}
+
+// rowCounter counts the rows read from a result set.
+type rowCounter struct {
+	*sql.Rows
+	n int64
+}
+
+// Next prepares the next row, counting it.
+func (rc *rowCounter) Next() bool {
+	if !rc.Rows.Next() {
+		return false
+	}
+	rc.n++
+	return true
+}
+
+// write writes the results of the job to its output file and webhook, or to
+// Stdout when it has neither.
+func (r *Runner) write(ctx context.Context, name string, job *Job, t time.Time, buf []byte) error {
+	if job.Output == "" && job.Webhook == "" {
+		_, err := r.Stdout.Write(buf)
+		return err
+	}
+	if job.Output != "" {
+		var sb strings.Builder
+		if err := job.output.Execute(&sb, struct {
+			Job  string
+			Time time.Time
+		}{name, t}); err != nil {
+			return fmt.Errorf("output: %w", err)
+		}
+		path := sb.String()
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(filepath.Dir(r.Config.Path), path)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, buf, 0o644); err != nil {
+			return err
+		}
+	}
+	if job.Webhook != "" {
+		if err := r.post(ctx, job, buf); err != nil {
+			return fmt.Errorf("webhook: %w", err)
+		}
+	}
+	return nil
+}
+
+// post posts the results of the job to its webhook.
+func (r *Runner) post(ctx context.Context, job *Job, buf []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, job.Webhook, bytes.NewReader(buf))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType(job.format()))
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, res.Body)
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", res.Status)
+	}
+	return nil
+}
+
+// format returns the output format of the job.
+func (job *Job) format() string {
+	if job.Format != "" {
+		return job.Format
+	}
+	return DefaultFormat
+}
+
+// contentType returns the content type of the output format.
+func contentType(format string) string {
+	switch format {
+	case "csv":
+		return "text/csv"
+	case "json":
+		return "application/json"
+	case "html":
+		return "text/html"
+	}
+	return "text/plain; charset=utf-8"
+}
@@ -0,0 +1,174 @@
+package schedule
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/pkg/config"
+)
+
+func TestCron(t *testing.T) {
+	start := time.Date(2023, 3, 15, 10, 30, 20, 0, time.UTC)
+	tests := []struct {
+		expr string
+		exp  []string
+	}{
+		{"* * * * *", []string{"2023-03-15T10:31:00Z", "2023-03-15T10:32:00Z"}},
+		{"*/20 * * * *", []string{"2023-03-15T10:40:00Z", "2023-03-15T11:00:00Z"}},
+		{"0 9-17/4 * * *", []string{"2023-03-15T13:00:00Z", "2023-03-15T17:00:00Z", "2023-03-16T09:00:00Z"}},
+		{"@daily", []string{"2023-03-16T00:00:00Z", "2023-03-17T00:00:00Z"}},
+		{"0 8 * * mon,fri", []string{"2023-03-17T08:00:00Z", "2023-03-20T08:00:00Z"}},
+		{"0 0 1,15 * 7", []string{"2023-03-19T00:00:00Z", "2023-03-26T00:00:00Z", "2023-04-01T00:00:00Z"}},
+		{"0 0 29 feb *", []string{"2024-02-29T00:00:00Z", "2028-02-29T00:00:00Z"}},
+		{"@every 90s", []string{"2023-03-15T10:31:50Z", "2023-03-15T10:33:20Z"}},
+	}
+	for i, test := range tests {
+		c, err := ParseCron(test.expr)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		next := start
+		for _, exp := range test.exp {
+			next = c.Next(next)
+			if s := next.Format(time.RFC3339); s != exp {
+				t.Errorf("test %d %q expected %s, got: %s", i, test.expr, exp, s)
+			}
+		}
+	}
+}
+
+func TestCronError(t *testing.T) {
+	tests := []struct {
+		expr, exp string
+	}{
+		{"* * * *", `invalid cron expression "* * * *": expected 5 fields`},
+		{"60 * * * *", `invalid cron expression "60 * * * *": invalid minute "60"`},
+		{"* 5-2 * * *", `invalid cron expression "* 5-2 * * *": invalid hour range "5-2"`},
+		{"*/0 * * * *", `invalid cron expression "*/0 * * * *": invalid minute step "0"`},
+		{"* * * foo *", `invalid cron expression "* * * foo *": invalid month "foo"`},
+		{"@every 1ms", `invalid cron expression "@every 1ms": invalid duration`},
+	}
+	for i, test := range tests {
+		if _, err := ParseCron(test.expr); err == nil || err.Error() != test.exp {
+			t.Errorf("test %d expected error %q, got: %v", i, test.exp, err)
+		}
+	}
+}
+
+func TestRunJob(t *testing.T) {
+	dir := t.TempDir()
+	dbpath := filepath.Join(dir, "test.db")
+	db, err := sql.Open("sqlite3", dbpath)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER, name TEXT); INSERT INTO t VALUES (1, 'a'), (2, 'b')`); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db.Close()
+	u, err := dburl.Parse("sqlite3:" + dbpath)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var posted, contentType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		buf, _ := io.ReadAll(req.Body)
+		posted, contentType = string(buf), req.Header.Get("Content-Type")
+	}))
+	defer srv.Close()
+	path := filepath.Join(dir, "schedules.yaml")
+	src := `jobs:
+  report:
+    cron: "0 6 * * *"
+    db: test
+    query: names
+    params:
+      min: "1"
+    output: out/{{.Job}}-{{.Time.Format "2006-01-02"}}.csv
+  hook:
+    cron: "@hourly"
+    db: test
+    sql: select count(*) as n from t
+    format: json
+    webhook: ` + srv.URL + `
+  cleanup:
+    cron: "@daily"
+    db: test
+    sql: delete from t where id > 1
+`
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	c, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var stdout, log strings.Builder
+	r := &Runner{
+		Config: c,
+		Open: func(context.Context, string, string) (*dburl.URL, *sql.DB, error) {
+			// the runner closes the connection after each run
+			db, err := sql.Open("sqlite3", dbpath)
+			return u, db, err
+		},
+		Query: func(name string) (*config.QueryConfig, error) {
+			return &config.QueryConfig{
+				SQL:    "select id, name from t where id >= {{.min}} order by id",
+				Params: map[string]*config.ParamConfig{"min": {Type: "int"}},
+			}, nil
+		},
+		Stdout: &stdout,
+		Log:    &log,
+	}
+	at := time.Date(2023, 3, 15, 6, 0, 0, 0, time.UTC)
+	for _, name := range []string{"report", "hook", "cleanup"} {
+		if err := r.RunJob(context.Background(), name, at); err != nil {
+			t.Fatalf("job %s expected no error, got: %v", name, err)
+		}
+	}
+	buf, err := os.ReadFile(filepath.Join(dir, "out", "report-2023-03-15.csv"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s, exp := string(buf), "id,name\n1,a\n2,b\n"; s != exp {
+		t.Errorf("expected output %q, got: %q", exp, s)
+	}
+	if exp := `[{"n":2}]`; strings.TrimSpace(posted) != exp || contentType != "application/json" {
+		t.Errorf("expected posted %q as application/json, got: %q as %s", exp, posted, contentType)
+	}
+	if s, exp := stdout.String(), "DELETE 1\n"; s != exp {
+		t.Errorf("expected stdout %q, got: %q", exp, s)
+	}
+	if err := r.RunJob(context.Background(), "other", at); err == nil || err.Error() != `unknown job "other"` {
+		t.Errorf("expected unknown job error, got: %v", err)
+	}
+}
+
+func TestLoadError(t *testing.T) {
+	tests := []struct {
+		src, exp string
+	}{
+		{"jobs:\n  a:\n    cron: '@daily'\n    sql: select 1\n", "job a: db is required"},
+		{"jobs:\n  a:\n    cron: '@daily'\n    db: x\n", "job a: exactly one of sql or query is required"},
+		{"jobs:\n  a:\n    cron: '* *'\n    db: x\n    sql: select 1\n", `job a: invalid cron expression "* *": expected 5 fields`},
+	}
+	for i, test := range tests {
+		path := filepath.Join(t.TempDir(), "schedules.yaml")
+		if err := os.WriteFile(path, []byte(test.src), 0o644); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if _, err := Load(path); err == nil || err.Error() != test.exp {
+			t.Errorf("test %d expected error %q, got: %v", i, test.exp, err)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+// Package schemadiff compares the table definitions of two databases.
+package schemadiff
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/dump"
+)
+
+// TableDiff are the differences of a table between two databases.
+type TableDiff struct {
+	// Name is the table name, schema qualified when the database's tables are
+	// in more than one schema.
+	Name string
+	// A and B are the definitions of the table in each database, nil when
+	// missing.
+	A, B *dump.Table
+	// Columns are the changed columns.
+	Columns []ColumnDiff
+	// PrimaryKey is set when the primary key columns differ.
+	PrimaryKey bool
+	// Indexes are the indexes only in one of the databases.
+	Indexes []IndexDiff
+	// Constraints are the constraints only in one of the databases.
+	Constraints []ConstraintDiff
+}
+
+// ColumnDiff is a changed column. A or B is nil when the column is missing.
+type ColumnDiff struct {
+	Name string
+	A, B *metadata.Column
+}
+
+// IndexDiff is an index only in one database. The other is nil.
+type IndexDiff struct {
+	A, B *dump.Index
+}
+
+// ConstraintDiff is a constraint only in one database. The other is nil.
+type ConstraintDiff struct {
+	A, B *dump.Constraint
+}
+
+// Diff compares the tables of database A to the tables of database B.
+// Indexes and constraints are compared by their definition, so that
+// differently named but otherwise equal objects are not reported.
+func Diff(a, b []*dump.Table) []*TableDiff {
+	ta, tb := tableMap(a), tableMap(b)
+	var diffs []*TableDiff
+	for _, name := range union(keys(ta), keys(tb)) {
+		d := &TableDiff{Name: name, A: ta[name], B: tb[name]}
+		if d.A != nil && d.B != nil {
+			d.Columns = diffColumns(d.A, d.B)
+			d.PrimaryKey = !equalFold(d.A.PrimaryKey, d.B.PrimaryKey)
+			d.Indexes = diffIndexes(d.A.Indexes, d.B.Indexes)
+			d.Constraints = diffConstraints(d.A.Constraints, d.B.Constraints)
+			if len(d.Columns) == 0 && !d.PrimaryKey && len(d.Indexes) == 0 && len(d.Constraints) == 0 {
+				continue
+			}
+		}
+		diffs = append(diffs, d)
+	}
+	return diffs
+}
+
+// tableMap returns the tables keyed by their name, schema qualified only when
+// the tables are in more than one schema. This allows comparing databases
+// whose schema is the database name (ie, MySQL).
+func tableMap(tables []*dump.Table) map[string]*dump.Table {
+	qualify := false
+	for _, t := range tables {
+		qualify = qualify || t.Schema != tables[0].Schema
+	}
+	m := make(map[string]*dump.Table, len(tables))
+	for _, t := range tables {
+		name := t.Name
+		if qualify && t.Schema != "" {
+			name = t.Schema + "." + t.Name
+		}
+		m[name] = t
+	}
+	return m
+}
+
+// diffColumns returns the changed columns of a and b, in the order of a,
+// followed by the columns only in b.
+func diffColumns(a, b *dump.Table) []ColumnDiff {
+	cb := make(map[string]*metadata.Column, len(b.Columns))
+	for i := range b.Columns {
+		cb[strings.ToLower(b.Columns[i].Name)] = &b.Columns[i]
+	}
+	var diffs []ColumnDiff
+	seen := make(map[string]bool)
+	for i := range a.Columns {
+		ca := &a.Columns[i]
+		name := strings.ToLower(ca.Name)
+		seen[name] = true
+		switch c, ok := cb[name]; {
+		case !ok:
+			diffs = append(diffs, ColumnDiff{Name: ca.Name, A: ca})
+		case !strings.EqualFold(ca.DataType, c.DataType) || ca.IsNullable != c.IsNullable || ca.Default != c.Default:
+			diffs = append(diffs, ColumnDiff{Name: ca.Name, A: ca, B: c})
+		}
+	}
+	for i := range b.Columns {
+		if c := &b.Columns[i]; !seen[strings.ToLower(c.Name)] {
+			diffs = append(diffs, ColumnDiff{Name: c.Name, B: c})
+		}
+	}
+	return diffs
+}
+
+// diffIndexes returns the indexes only in a or b.
+func diffIndexes(a, b []dump.Index) []IndexDiff {
+	key := func(i dump.Index) string {
+		return fmt.Sprintf("%t %s", i.Unique, strings.ToLower(strings.Join(i.Columns, ",")))
+	}
+	var diffs []IndexDiff
+	onlyA, onlyB := only(len(a), len(b), func(i int) string { return key(a[i]) }, func(i int) string { return key(b[i]) })
+	for _, i := range onlyA {
+		diffs = append(diffs, IndexDiff{A: &a[i]})
+	}
+	for _, i := range onlyB {
+		diffs = append(diffs, IndexDiff{B: &b[i]})
+	}
+	return diffs
+}
+
+// diffConstraints returns the constraints only in a or b.
+func diffConstraints(a, b []dump.Constraint) []ConstraintDiff {
+	key := func(c dump.Constraint) string {
+		return strings.ToLower(c.Type + " " + constraintDef(c))
+	}
+	var diffs []ConstraintDiff
+	onlyA, onlyB := only(len(a), len(b), func(i int) string { return key(a[i]) }, func(i int) string { return key(b[i]) })
+	for _, i := range onlyA {
+		diffs = append(diffs, ConstraintDiff{A: &a[i]})
+	}
+	for _, i := range onlyB {
+		diffs = append(diffs, ConstraintDiff{B: &b[i]})
+	}
+	return diffs
+}
+
+// only returns the positions of the keys only in a and only in b.
+func only(na, nb int, ka, kb func(int) string) ([]int, []int) {
+	ma, mb := make(map[string]bool, na), make(map[string]bool, nb)
+	for i := 0; i < na; i++ {
+		ma[ka(i)] = true
+	}
+	for i := 0; i < nb; i++ {
+		mb[kb(i)] = true
+	}
+	var onlyA, onlyB []int
+	for i := 0; i < na; i++ {
+		if !mb[ka(i)] {
+			onlyA = append(onlyA, i)
+		}
+	}
+	for i := 0; i < nb; i++ {
+		if !ma[kb(i)] {
+			onlyB = append(onlyB, i)
+		}
+	}
+	return onlyA, onlyB
+}
+
+// Write writes the differences as a readable list, with - for objects only in
+// A, + for objects only in B and ~ for changed objects.
+func Write(w io.Writer, nameA, nameB string, diffs []*TableDiff) error {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", nameA, nameB)
+	for _, d := range diffs {
+		switch {
+		case d.B == nil:
+			fmt.Fprintf(w, "- table %s\n", d.Name)
+			continue
+		case d.A == nil:
+			fmt.Fprintf(w, "+ table %s\n", d.Name)
+			continue
+		}
+		fmt.Fprintf(w, "~ table %s\n", d.Name)
+		for _, c := range d.Columns {
+			switch {
+			case c.B == nil:
+				fmt.Fprintf(w, "  - column %s %s\n", c.Name, columnType(c.A))
+			case c.A == nil:
+				fmt.Fprintf(w, "  + column %s %s\n", c.Name, columnType(c.B))
+			default:
+				fmt.Fprintf(w, "  ~ column %s: %s -> %s\n", c.Name, columnType(c.A), columnType(c.B))
+			}
+		}
+		if d.PrimaryKey {
+			fmt.Fprintf(w, "  ~ primary key: (%s) -> (%s)\n", strings.Join(d.A.PrimaryKey, ", "), strings.Join(d.B.PrimaryKey, ", "))
+		}
+		for _, i := range d.Indexes {
+			if i.A != nil {
+				fmt.Fprintf(w, "  - %s\n", indexDesc(i.A))
+			} else {
+				fmt.Fprintf(w, "  + %s\n", indexDesc(i.B))
+			}
+		}
+		for _, c := range d.Constraints {
+			if c.A != nil {
+				fmt.Fprintf(w, "  - constraint %s %s\n", c.A.Name, constraintDef(*c.A))
+			} else {
+				fmt.Fprintf(w, "  + constraint %s %s\n", c.B.Name, constraintDef(*c.B))
+			}
+		}
+	}
+	return nil
+}
+
+// columnType returns the type, nullability and default of the column.
+func columnType(c *metadata.Column) string {
+	s := c.DataType
+	if c.IsNullable == metadata.NO {
+		s += " NOT NULL"
+	}
+	if c.Default != "" {
+		s += " DEFAULT " + c.Default
+	}
+	return strings.TrimSpace(s)
+}
+
+// indexDesc returns a description of the index.
+func indexDesc(i *dump.Index) string {
+	s := "index "
+	if i.Unique {
+		s = "unique index "
+	}
+	return s + i.Name + " (" + strings.Join(i.Columns, ", ") + ")"
+}
+
+// constraintDef returns the definition of the constraint.
+func constraintDef(c dump.Constraint) string {
+	if c.Type == "CHECK" {
+		return "CHECK (" + c.CheckClause + ")"
+	}
+	ref := c.ForeignTable
+	if c.ForeignSchema != "" {
+		ref = c.ForeignSchema + "." + ref
+	}
+	return fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", strings.Join(c.Columns, ", "), ref, strings.Join(c.ForeignColumns, ", "))
+}
+
+// keys returns the sorted keys of m.
+func keys(m map[string]*dump.Table) []string {
+	var strs []string
+	for k := range m {
+		strs = append(strs, k)
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// union returns the sorted union of a and b.
+func union(a, b []string) []string {
+	m := make(map[string]bool)
+	var strs []string
+	for _, s := range append(a, b...) {
+		if !m[s] {
+			m[s] = true
+			strs = append(strs, s)
+		}
+	}
+	sort.Strings(strs)
+	return strs
+}
+
+// equalFold returns true when a and b are equal, ignoring case.
+func equalFold(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !strings.EqualFold(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package schemadiff
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/dump"
+)
+
+func TestDiff(t *testing.T) {
+	a := []*dump.Table{
+		{Schema: "app_prod", Name: "users", Columns: []metadata.Column{
+			{Name: "id", DataType: "int", IsNullable: metadata.NO},
+			{Name: "name", DataType: "text", IsNullable: metadata.YES},
+			{Name: "legacy", DataType: "text", IsNullable: metadata.YES},
+		}, PrimaryKey: []string{"id"}, Indexes: []dump.Index{
+			{Name: "users_name_idx", Columns: []string{"name"}},
+		}},
+		{Schema: "app_prod", Name: "archive"},
+	}
+	b := []*dump.Table{
+		{Schema: "app_staging", Name: "users", Columns: []metadata.Column{
+			{Name: "id", DataType: "INT", IsNullable: metadata.NO},
+			{Name: "name", DataType: "varchar(100)", IsNullable: metadata.NO},
+			{Name: "email", DataType: "text", IsNullable: metadata.YES},
+		}, PrimaryKey: []string{"id"}, Indexes: []dump.Index{
+			{Name: "name_idx", Columns: []string{"name"}},
+			{Name: "users_email_key", Unique: true, Columns: []string{"email"}},
+		}},
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, "prod", "staging", Diff(a, b)); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	exp := `--- prod
++++ staging
+- table archive
+~ table users
+  ~ column name: text -> varchar(100) NOT NULL
+  - column legacy text
+  + column email text
+  + unique index users_email_key (email)
+`
+	if s := buf.String(); s != exp {
+		t.Errorf("expected:\n%s\ngot:\n%s", exp, s)
+	}
+	if diffs := Diff(a, a); len(diffs) != 0 {
+		t.Errorf("expected no differences, got: %d", len(diffs))
+	}
+}
+
+func TestWriteSQL(t *testing.T) {
+	a := []*dump.Table{
+		{Name: "users", Columns: []metadata.Column{
+			{Name: "name", DataType: "text", IsNullable: metadata.YES},
+		}, Constraints: []dump.Constraint{
+			{Name: "users_org_fk", Type: "FOREIGN KEY", Columns: []string{"org"}, ForeignTable: "orgs", ForeignColumns: []string{"id"}},
+		}},
+	}
+	b := []*dump.Table{
+		{Name: "users", Columns: []metadata.Column{
+			{Name: "name", DataType: "varchar(100)", IsNullable: metadata.NO, Default: "''"},
+		}},
+		{Name: "orgs", Columns: []metadata.Column{
+			{Name: "id", DataType: "integer", IsNullable: metadata.NO},
+		}, PrimaryKey: []string{"id"}},
+	}
+	tests := []struct {
+		driver string
+		exp    string
+	}{
+		{"postgres", `ALTER TABLE "users" DROP CONSTRAINT "users_org_fk";
+CREATE TABLE "orgs" (
+  "id" integer NOT NULL,
+  PRIMARY KEY ("id")
+);
+ALTER TABLE "users" ALTER COLUMN "name" TYPE varchar(100);
+ALTER TABLE "users" ALTER COLUMN "name" SET NOT NULL;
+ALTER TABLE "users" ALTER COLUMN "name" SET DEFAULT '';
+`},
+		{"mysql", "ALTER TABLE `users` DROP FOREIGN KEY `users_org_fk`;\n" +
+			"CREATE TABLE `orgs` (\n  `id` integer NOT NULL,\n  PRIMARY KEY (`id`)\n);\n" +
+			"ALTER TABLE `users` MODIFY COLUMN `name` varchar(100) NOT NULL DEFAULT '';\n"},
+	}
+	for i, test := range tests {
+		var buf bytes.Buffer
+		if err := WriteSQL(&buf, test.driver, Diff(a, b)); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if s := buf.String(); s != test.exp {
+			t.Errorf("test %d expected:\n%s\ngot:\n%s", i, test.exp, s)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package schemadiff
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/dump"
+)
+
+// WriteSQL writes candidate DDL statements that change the tables of database
+// A to match database B, using the SQL dialect of A's driver (see
+// drivers.Capabilities). Changes that cannot be expressed in the dialect are
+// written as comments.
+func WriteSQL(w io.Writer, dialect string, diffs []*TableDiff) error {
+	g := &generator{d: dump.DialectFor(dialect), dialect: dialect}
+	// drop constraints and indexes first, as they may depend on dropped
+	// columns, and drop tables last, as other tables may reference them
+	for _, td := range diffs {
+		for _, c := range td.Constraints {
+			if c.A != nil {
+				g.dropConstraint(td, c.A)
+			}
+		}
+		for _, i := range td.Indexes {
+			if i.A != nil {
+				g.dropIndex(td, i.A)
+			}
+		}
+	}
+	for _, td := range diffs {
+		if td.A == nil {
+			g.add(g.d.CreateTable(g.table(td, td.B)))
+			for _, i := range td.B.Indexes {
+				g.add(g.d.CreateIndex(g.table(td, td.B), i))
+			}
+		}
+	}
+	for _, td := range diffs {
+		for _, c := range td.Columns {
+			g.alterColumn(td, c)
+		}
+		if td.PrimaryKey {
+			g.comment("primary key of %s changed from (%s) to (%s)", td.Name, strings.Join(td.A.PrimaryKey, ", "), strings.Join(td.B.PrimaryKey, ", "))
+		}
+	}
+	for _, td := range diffs {
+		for _, i := range td.Indexes {
+			if i.B != nil {
+				g.add(g.d.CreateIndex(g.table(td, td.A), *i.B))
+			}
+		}
+		for _, c := range td.Constraints {
+			if c.B != nil {
+				g.addConstraint(td, c.B)
+			}
+		}
+	}
+	for _, td := range diffs {
+		if td.B == nil {
+			g.add("DROP TABLE " + g.name(td) + ";")
+		}
+	}
+	for _, s := range g.stmts {
+		if _, err := fmt.Fprintln(w, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generator generates DDL statements.
+type generator struct {
+	d       dump.Dialect
+	dialect string
+	stmts   []string
+}
+
+// add adds a statement.
+func (g *generator) add(stmt string) {
+	g.stmts = append(g.stmts, stmt)
+}
+
+// comment adds a comment.
+func (g *generator) comment(format string, v ...interface{}) {
+	g.stmts = append(g.stmts, "-- "+fmt.Sprintf(format, v...))
+}
+
+// table returns t, without its schema when the table name is not schema
+// qualified.
+func (g *generator) table(td *TableDiff, t *dump.Table) *dump.Table {
+	if !strings.Contains(td.Name, ".") {
+		c := *t
+		c.Schema = ""
+		return &c
+	}
+	return t
+}
+
+// name returns the quoted name of the table.
+func (g *generator) name(td *TableDiff) string {
+	t := td.A
+	if t == nil {
+		t = td.B
+	}
+	t = g.table(td, t)
+	return g.d.Qualify(t.Schema, t.Name)
+}
+
+// sqlite returns true when the dialect is sqlite's.
+func (g *generator) sqlite() bool {
+	return g.dialect == "sqlite3"
+}
+
+// alterColumn adds the statements changing a column.
+func (g *generator) alterColumn(td *TableDiff, c ColumnDiff) {
+	table := g.name(td)
+	switch {
+	case c.A == nil:
+		g.add("ALTER TABLE " + table + " ADD COLUMN " + g.d.ColumnDef(*c.B) + ";")
+		return
+	case c.B == nil:
+		g.add("ALTER TABLE " + table + " DROP COLUMN " + g.d.QuoteIdent(c.Name) + ";")
+		return
+	}
+	col := g.d.QuoteIdent(c.Name)
+	switch g.dialect {
+	case "postgres":
+		prefix := "ALTER TABLE " + table + " ALTER COLUMN " + col
+		if !strings.EqualFold(c.A.DataType, c.B.DataType) {
+			g.add(prefix + " TYPE " + c.B.DataType + ";")
+		}
+		switch {
+		case c.A.IsNullable == c.B.IsNullable:
+		case c.B.IsNullable == metadata.NO:
+			g.add(prefix + " SET NOT NULL;")
+		default:
+			g.add(prefix + " DROP NOT NULL;")
+		}
+		switch {
+		case c.A.Default == c.B.Default:
+		case c.B.Default == "":
+			g.add(prefix + " DROP DEFAULT;")
+		default:
+			g.add(prefix + " SET DEFAULT " + c.B.Default + ";")
+		}
+	case "mysql":
+		g.add("ALTER TABLE " + table + " MODIFY COLUMN " + g.d.ColumnDef(*c.B) + ";")
+	default:
+		g.comment("column %s of %s changed from %s to %s", c.Name, td.Name, columnType(c.A), columnType(c.B))
+	}
+}
+
+// dropIndex adds the statement dropping an index.
+func (g *generator) dropIndex(td *TableDiff, i *dump.Index) {
+	switch {
+	case g.dialect == "mysql":
+		g.add("DROP INDEX " + g.d.QuoteIdent(i.Name) + " ON " + g.name(td) + ";")
+	case g.sqlite() && strings.HasPrefix(i.Name, "sqlite_"):
+		g.comment("%s of %s is implicit and cannot be dropped", indexDesc(i), td.Name)
+	default:
+		t := g.table(td, td.A)
+		g.add("DROP INDEX " + g.d.Qualify(t.Schema, i.Name) + ";")
+	}
+}
+
+// addConstraint adds the statement adding a constraint.
+func (g *generator) addConstraint(td *TableDiff, c *dump.Constraint) {
+	if g.sqlite() {
+		g.comment("constraint %s %s of %s cannot be added", c.Name, constraintDef(*c), td.Name)
+		return
+	}
+	def := "CHECK (" + c.CheckClause + ")"
+	if c.Type == "FOREIGN KEY" {
+		schema := c.ForeignSchema
+		if !strings.Contains(td.Name, ".") {
+			schema = ""
+		}
+		def = fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", g.d.QuoteIdents(c.Columns), g.d.Qualify(schema, c.ForeignTable), g.d.QuoteIdents(c.ForeignColumns))
+	}
+	g.add("ALTER TABLE " + g.name(td) + " ADD CONSTRAINT " + g.d.QuoteIdent(c.Name) + " " + def + ";")
+}
+
+// dropConstraint adds the statement dropping a constraint.
+func (g *generator) dropConstraint(td *TableDiff, c *dump.Constraint) {
+	drop := "CONSTRAINT"
+	switch {
+	case g.sqlite():
+		g.comment("constraint %s %s of %s cannot be dropped", c.Name, constraintDef(*c), td.Name)
+		return
+	case g.dialect == "mysql" && c.Type == "FOREIGN KEY":
+		drop = "FOREIGN KEY"
+	case g.dialect == "mysql":
+		drop = "CHECK"
+	}
+	g.add("ALTER TABLE " + g.name(td) + " DROP " + drop + " " + g.d.QuoteIdent(c.Name) + ";")
+}
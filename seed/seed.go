@@ -0,0 +1,355 @@
+// Package seed loads YAML, CSV and SQL fixture files into database tables.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/deps"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/text"
+	"gopkg.in/yaml.v2"
+)
+
+// Mode is the seed mode.
+type Mode int
+
+// Seed modes.
+const (
+	// ModeInsert inserts the fixture rows.
+	ModeInsert Mode = iota
+	// ModeTruncate deletes the rows of the fixture tables before inserting
+	// the fixture rows.
+	ModeTruncate
+	// ModeUpsert inserts the fixture rows, updating the existing rows with
+	// the same primary key.
+	ModeUpsert
+)
+
+// Fixture is a fixture file.
+type Fixture struct {
+	// Table is the table of the fixture, the file name without extension.
+	Table string
+	Path  string
+	// Ext is the lower case file extension.
+	Ext string
+}
+
+// Load returns the fixtures of dir, ordered by their file name.
+func Load(dir string) ([]*Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var fixtures []*Fixture
+	seen := make(map[string]string)
+	for _, entry := range entries {
+		ext := strings.ToLower(filepath.Ext(entry.Name()))
+		switch {
+		case entry.IsDir():
+			continue
+		case ext != ".yaml" && ext != ".yml" && ext != ".csv" && ext != ".sql":
+			continue
+		}
+		table := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		if prev, ok := seen[strings.ToLower(table)]; ok {
+			return nil, fmt.Errorf("fixtures %s and %s are for the same table", prev, entry.Name())
+		}
+		seen[strings.ToLower(table)] = entry.Name()
+		fixtures = append(fixtures, &Fixture{
+			Table: table,
+			Path:  filepath.Join(dir, entry.Name()),
+			Ext:   ext,
+		})
+	}
+	return fixtures, nil
+}
+
+// Run loads the fixtures of dir in a single transaction, ordering the tables
+// so that tables are loaded after the tables their foreign keys reference.
+func Run(ctx context.Context, w io.Writer, u *dburl.URL, db *sql.DB, dir string, mode Mode) error {
+	fixtures, err := Load(dir)
+	if err != nil {
+		return err
+	}
+	if len(fixtures) == 0 {
+		return fmt.Errorf("no fixtures in %s", dir)
+	}
+	names := make([]string, len(fixtures))
+	for i, f := range fixtures {
+		names[i] = f.Table
+	}
+	tables, err := readTables(ctx, u, db, names)
+	if err != nil {
+		return err
+	}
+	if fixtures, err = sortFixtures(fixtures, tables); err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	s := &seeder{tx: tx, u: u, d: dump.DialectFor(drivers.Caps(u).Dialect), mode: mode}
+	if mode == ModeTruncate {
+		for i := len(fixtures) - 1; i >= 0; i-- {
+			t := tables[strings.ToLower(fixtures[i].Table)]
+			if _, err := tx.ExecContext(ctx, "DELETE FROM "+s.d.Qualify(t.Schema, t.Name)); err != nil {
+				return fmt.Errorf("%s: %w", t.Name, drivers.WrapErr(u.Driver, err))
+			}
+		}
+	}
+	for _, f := range fixtures {
+		n, err := s.load(ctx, f, tables[strings.ToLower(f.Table)])
+		if err != nil {
+			return fmt.Errorf("%s: %w", f.Path, err)
+		}
+		fmt.Fprintf(w, "SEED %s %d\n", f.Table, n)
+	}
+	return tx.Commit()
+}
+
+// readTables reads the definitions of the tables, keyed by their lower cased
+// name.
+func readTables(ctx context.Context, u *dburl.URL, db *sql.DB, names []string) (map[string]*dump.Table, error) {
+	res, err := dump.ReadTables(ctx, u, db, names)
+	if err != nil {
+		return nil, err
+	}
+	tables := make(map[string]*dump.Table, len(names))
+	for _, name := range names {
+		table := name
+		if i := strings.LastIndex(name, "."); i != -1 {
+			table = name[i+1:]
+		}
+		// table names are matched as patterns, so keep the exact match
+		for _, t := range res {
+			if strings.EqualFold(t.Name, table) {
+				tables[strings.ToLower(name)] = t
+			}
+		}
+		if tables[strings.ToLower(name)] == nil {
+			return nil, fmt.Errorf("table %s does not exist", name)
+		}
+	}
+	return tables, nil
+}
+
+// sortFixtures orders the fixtures so that the tables referenced by foreign
+// keys are loaded first, keeping the file name order otherwise.
+func sortFixtures(fixtures []*Fixture, tables map[string]*dump.Table) ([]*Fixture, error) {
+	ts := make([]*dump.Table, len(fixtures))
+	byTable := make(map[string][]*Fixture)
+	for i, f := range fixtures {
+		ts[i] = tables[strings.ToLower(f.Table)]
+		name := strings.ToLower(ts[i].Name)
+		byTable[name] = append(byTable[name], f)
+	}
+	order, err := deps.New(ts).Order()
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]*Fixture, 0, len(fixtures))
+	for _, name := range order {
+		sorted = append(sorted, byTable[strings.ToLower(name)]...)
+	}
+	return sorted, nil
+}
+
+// seeder loads fixtures.
+type seeder struct {
+	tx   *sql.Tx
+	u    *dburl.URL
+	d    dump.Dialect
+	mode Mode
+}
+
+// load loads the fixture into the table, returning the number of loaded rows.
+func (s *seeder) load(ctx context.Context, f *Fixture, t *dump.Table) (int64, error) {
+	buf, err := os.ReadFile(f.Path)
+	if err != nil {
+		return 0, err
+	}
+	if f.Ext == ".sql" {
+		return s.exec(ctx, string(buf))
+	}
+	var cols [][]string
+	var rows [][]interface{}
+	if f.Ext == ".csv" {
+		cols, rows, err = readCSV(buf)
+	} else {
+		cols, rows, err = readYAML(buf)
+	}
+	if err != nil {
+		return 0, err
+	}
+	for i, row := range rows {
+		q, err := s.insert(t, cols[i])
+		if err != nil {
+			return 0, err
+		}
+		if _, err := s.tx.ExecContext(ctx, q, row...); err != nil {
+			return 0, fmt.Errorf("row %d: %w", i+1, drivers.WrapErr(s.u.Driver, err))
+		}
+	}
+	return int64(len(rows)), nil
+}
+
+// exec executes the statements of a SQL fixture, returning the number of
+// affected rows.
+func (s *seeder) exec(ctx context.Context, sqlstr string) (int64, error) {
+	stmts, err := drivers.Statements(s.u, sqlstr)
+	if err != nil {
+		return 0, err
+	}
+	var n int64
+	for _, stmt := range stmts {
+		res, err := s.tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return 0, drivers.WrapErr(s.u.Driver, err)
+		}
+		if count, err := drivers.RowsAffected(s.u, res); err == nil {
+			n += count
+		}
+	}
+	return n, nil
+}
+
+// insert returns the insert statement of a row of the columns.
+func (s *seeder) insert(t *dump.Table, cols []string) (string, error) {
+	pholders := make([]string, len(cols))
+	for i := range cols {
+		pholders[i] = drivers.Placeholder(s.u, i+1)
+	}
+	q := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", s.d.Qualify(t.Schema, t.Name), s.d.QuoteIdents(cols), strings.Join(pholders, ", "))
+	if s.mode != ModeUpsert {
+		return q, nil
+	}
+	if len(t.PrimaryKey) == 0 {
+		return "", fmt.Errorf("table %s has no primary key to upsert on", t.Name)
+	}
+	isKey := make(map[string]bool, len(t.PrimaryKey))
+	for _, col := range t.PrimaryKey {
+		isKey[strings.ToLower(col)] = true
+	}
+	var set []string
+	switch drivers.Caps(s.u).Dialect {
+	case "postgres", "sqlite3":
+		for _, col := range cols {
+			if !isKey[strings.ToLower(col)] {
+				set = append(set, s.d.QuoteIdent(col)+" = EXCLUDED."+s.d.QuoteIdent(col))
+			}
+		}
+		if len(set) == 0 {
+			return q + " ON CONFLICT (" + s.d.QuoteIdents(t.PrimaryKey) + ") DO NOTHING", nil
+		}
+		return q + " ON CONFLICT (" + s.d.QuoteIdents(t.PrimaryKey) + ") DO UPDATE SET " + strings.Join(set, ", "), nil
+	case "mysql":
+		for _, col := range cols {
+			if !isKey[strings.ToLower(col)] {
+				set = append(set, s.d.QuoteIdent(col)+" = VALUES("+s.d.QuoteIdent(col)+")")
+			}
+		}
+		if len(set) == 0 {
+			key := s.d.QuoteIdent(t.PrimaryKey[0])
+			set = append(set, key+" = "+key)
+		}
+		return q + " ON DUPLICATE KEY UPDATE " + strings.Join(set, ", "), nil
+	}
+	return "", fmt.Errorf(text.NotSupportedByDriver, "upsert", s.u.Driver)
+}
+
+// readCSV reads the rows of a CSV fixture, whose first record is the header.
+// Empty values are NULL.
+func readCSV(buf []byte) ([][]string, [][]interface{}, error) {
+	records, err := csv.NewReader(strings.NewReader(string(buf))).ReadAll()
+	switch {
+	case err != nil:
+		return nil, nil, err
+	case len(records) == 0:
+		return nil, nil, nil
+	}
+	header := records[0]
+	var cols [][]string
+	var rows [][]interface{}
+	for _, record := range records[1:] {
+		row := make([]interface{}, len(record))
+		for i, v := range record {
+			if v != "" {
+				row[i] = v
+			}
+		}
+		cols, rows = append(cols, header), append(rows, row)
+	}
+	return cols, rows, nil
+}
+
+// readYAML reads the rows of a YAML fixture, a list of mappings of column
+// names to values. Nested values are encoded as JSON.
+func readYAML(buf []byte) ([][]string, [][]interface{}, error) {
+	var records []yaml.MapSlice
+	if err := yaml.Unmarshal(buf, &records); err != nil {
+		return nil, nil, err
+	}
+	cols, rows := make([][]string, len(records)), make([][]interface{}, len(records))
+	for i, record := range records {
+		for _, item := range record {
+			v, err := yamlValue(item.Value)
+			if err != nil {
+				return nil, nil, fmt.Errorf("row %d: %s: %w", i+1, item.Key, err)
+			}
+			cols[i], rows[i] = append(cols[i], fmt.Sprint(item.Key)), append(rows[i], v)
+		}
+	}
+	return cols, rows, nil
+}
+
+// yamlValue converts a YAML value to a query parameter.
+func yamlValue(v interface{}) (interface{}, error) {
+	switch x := v.(type) {
+	case int:
+		return int64(x), nil
+	case yaml.MapSlice, map[interface{}]interface{}, []interface{}:
+		buf, err := json.Marshal(jsonValue(x))
+		if err != nil {
+			return nil, err
+		}
+		return string(buf), nil
+	}
+	return v, nil
+}
+
+// jsonValue converts YAML mappings to values that can be encoded as JSON.
+func jsonValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case yaml.MapSlice:
+		m := make(map[string]interface{}, len(x))
+		for _, item := range x {
+			m[fmt.Sprint(item.Key)] = jsonValue(item.Value)
+		}
+		return m
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(x))
+		for k, v := range x {
+			m[fmt.Sprint(k)] = jsonValue(v)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(x))
+		for i, v := range x {
+			s[i] = jsonValue(v)
+		}
+		return s
+	}
+	return v
+}
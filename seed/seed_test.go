@@ -0,0 +1,64 @@
+package seed
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/xo/usql/dump"
+)
+
+func TestSortFixtures(t *testing.T) {
+	fk := func(table string) dump.Constraint {
+		return dump.Constraint{Type: "FOREIGN KEY", ForeignTable: table}
+	}
+	tables := map[string]*dump.Table{
+		"customers":   {Name: "customers"},
+		"order_items": {Name: "order_items", Constraints: []dump.Constraint{fk("orders"), fk("products")}},
+		"orders":      {Name: "orders", Constraints: []dump.Constraint{fk("customers"), fk("orders")}},
+	}
+	var fixtures []*Fixture
+	for _, name := range []string{"customers", "order_items", "orders"} {
+		fixtures = append(fixtures, &Fixture{Table: name})
+	}
+	sorted, err := sortFixtures(fixtures, tables)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var names []string
+	for _, f := range sorted {
+		names = append(names, f.Table)
+	}
+	if exp := []string{"customers", "orders", "order_items"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected %v, got: %v", exp, names)
+	}
+	tables["customers"].Constraints = []dump.Constraint{fk("order_items")}
+	if _, err := sortFixtures(fixtures, tables); err == nil {
+		t.Errorf("expected error, got nil")
+	}
+}
+
+func TestReadYAML(t *testing.T) {
+	cols, rows, err := readYAML([]byte("- id: 1\n  name: alice\n  meta: {a: [1, 2]}\n- id: 2\n  active: true\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := [][]string{{"id", "name", "meta"}, {"id", "active"}}; !reflect.DeepEqual(cols, exp) {
+		t.Errorf("expected columns %v, got: %v", exp, cols)
+	}
+	if exp := [][]interface{}{{int64(1), "alice", `{"a":[1,2]}`}, {int64(2), true}}; !reflect.DeepEqual(rows, exp) {
+		t.Errorf("expected rows %v, got: %v", exp, rows)
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	cols, rows, err := readCSV([]byte("id,name\n1,\"a,b\"\n2,\n"))
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if exp := [][]string{{"id", "name"}, {"id", "name"}}; !reflect.DeepEqual(cols, exp) {
+		t.Errorf("expected columns %v, got: %v", exp, cols)
+	}
+	if exp := [][]interface{}{{"1", "a,b"}, {"2", nil}}; !reflect.DeepEqual(rows, exp) {
+		t.Errorf("expected rows %v, got: %v", exp, rows)
+	}
+}
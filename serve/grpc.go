@@ -0,0 +1,165 @@
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/metrics"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/tracing"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// batchSize is the number of rows sent per query response.
+const batchSize = 100
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative query.proto
+
+// queryService is the query service of query.proto, served by a server.
+type queryService struct {
+	UnimplementedQueryServiceServer
+	s *Server
+}
+
+// Query satisfies the QueryServiceServer interface.
+func (q queryService) Query(req *QueryRequest, stream QueryService_QueryServer) error {
+	return q.s.streamQuery(req, stream)
+}
+
+// NewGRPCServer creates a gRPC server serving the query service of the
+// server. Requests must carry the server's token as a bearer token in the
+// authorization metadata.
+func (s *Server) NewGRPCServer(opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts, grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if !s.authorizedContext(stream.Context()) {
+			return status.Error(codes.Unauthenticated, "invalid or missing token")
+		}
+		return handler(srv, stream)
+	}))
+	g := grpc.NewServer(opts...)
+	RegisterQueryServiceServer(g, queryService{s: s})
+	return g
+}
+
+// authorizedContext returns true when the metadata of the context carries
+// the server's token.
+func (s *Server) authorizedContext(ctx context.Context) bool {
+	md, _ := metadata.FromIncomingContext(ctx)
+	for _, v := range md.Get("authorization") {
+		token := strings.TrimPrefix(v, "Bearer ")
+		if s.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// streamQuery executes the query of a request, streaming its results.
+func (s *Server) streamQuery(req *QueryRequest, stream QueryService_QueryServer) (err error) {
+	ctx := stream.Context()
+	c, sqlstr, isQuery, code, err := s.prepare(ctx, req.Alias, req.Role, req.Sql)
+	if err != nil {
+		return status.Error(grpcCode(code), redact.String(err.Error()))
+	}
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "query", tracing.Attrs(c.u, req.Alias, "")...)
+	var n int
+	defer func() {
+		metrics.Observe(req.Alias, time.Since(start), err)
+		if isQuery {
+			span.SetAttributes(tracing.RowsKey.Int(n))
+		}
+		tracing.End(span, err)
+	}()
+	params := make([]interface{}, len(req.Params))
+	for i, v := range req.Params {
+		params[i] = v.Interface()
+	}
+	db, end, err := s.begin(ctx, c)
+	if err != nil {
+		return status.Error(codes.Unknown, redact.String(drivers.WrapErr(c.u.Driver, err).Error()))
+	}
+	defer end()
+	if !isQuery {
+		res, err := execExec(ctx, c, db, sqlstr, params)
+		if err != nil {
+			return status.Error(codes.Unknown, redact.String(drivers.WrapErr(c.u.Driver, err).Error()))
+		}
+		span.SetAttributes(tracing.RowsKey.Int64(*res.RowsAffected))
+		return stream.Send(&QueryResponse{RowsAffected: *res.RowsAffected})
+	}
+	rows, err := db.QueryContext(ctx, sqlstr, params...)
+	if err != nil {
+		return status.Error(codes.Unknown, redact.String(drivers.WrapErr(c.u.Driver, err).Error()))
+	}
+	defer rows.Close()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	cols := make([]*Column, len(types))
+	for i, typ := range types {
+		cols[i] = &Column{Name: typ.Name(), DatabaseType: typ.DatabaseTypeName()}
+	}
+	if err := stream.Send(&QueryResponse{Columns: cols}); err != nil {
+		return err
+	}
+	max := s.MaxRows
+	if max <= 0 {
+		max = DefaultMaxRows
+	}
+	res := new(QueryResponse)
+	for rows.Next() {
+		if n == max {
+			res.Truncated = true
+			break
+		}
+		vals := make([]interface{}, len(cols))
+		for i := range vals {
+			vals[i] = new(interface{})
+		}
+		if err := rows.Scan(vals...); err != nil {
+			return err
+		}
+		row := &Row{Values: make([]*Value, len(vals))}
+		for i, v := range vals {
+			row.Values[i] = NewValue(*(v.(*interface{})))
+		}
+		res.Rows, n = append(res.Rows, row), n+1
+		if len(res.Rows) == batchSize {
+			if err := stream.Send(res); err != nil {
+				return err
+			}
+			res = new(QueryResponse)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return status.Error(codes.Unknown, redact.String(drivers.WrapErr(c.u.Driver, err).Error()))
+	}
+	if len(res.Rows) != 0 || res.Truncated {
+		return stream.Send(res)
+	}
+	return nil
+}
+
+// grpcCode returns the gRPC code of a HTTP status.
+func grpcCode(code int) codes.Code {
+	switch code {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusBadGateway:
+		return codes.Unavailable
+	}
+	return codes.Unknown
+}
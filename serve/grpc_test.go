@@ -0,0 +1,158 @@
+package serve
+
+import (
+	"context"
+	"database/sql"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xo/dburl"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGRPC(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER, name TEXT, data BLOB)`); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	for i := 0; i < 150; i++ {
+		if _, err := db.Exec(`INSERT INTO t VALUES (?, ?, ?)`, i, nil, []byte{byte(i)}); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	s := &Server{
+		Aliases: []string{"test"},
+		Token:   "secret",
+		MaxRows: 120,
+		Open: func(context.Context, string, string) (*dburl.URL, *sql.DB, error) {
+			return u, db, nil
+		},
+	}
+	defer s.Close()
+	l := bufconn.Listen(1 << 20)
+	g := s.NewGRPCServer()
+	go g.Serve(l)
+	defer g.Stop()
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer cc.Close()
+	client := NewQueryServiceClient(cc)
+	ctx := context.Background()
+	// unauthenticated
+	stream, err := client.Query(ctx, &QueryRequest{Alias: "test", Sql: "select 1"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected unauthenticated error, got: %v", err)
+	}
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer secret")
+	// read only
+	stream, err = client.Query(ctx, &QueryRequest{Alias: "test", Sql: "delete from t"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected permission denied error, got: %v", err)
+	}
+	// query
+	stream, err = client.Query(ctx, &QueryRequest{
+		Alias:  "test",
+		Sql:    "select id, name, data from t where id >= ? order by id",
+		Params: []*Value{NewValue(int64(10))},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var responses []*QueryResponse
+	for {
+		res, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+		responses = append(responses, res)
+	}
+	if len(responses) != 3 {
+		t.Fatalf("expected 3 responses, got: %d", len(responses))
+	}
+	var names []string
+	for _, col := range responses[0].Columns {
+		names = append(names, col.Name)
+	}
+	if exp := []string{"id", "name", "data"}; !reflect.DeepEqual(names, exp) {
+		t.Errorf("expected columns %v, got: %v", exp, names)
+	}
+	if n := len(responses[1].Rows); n != batchSize {
+		t.Errorf("expected %d rows, got: %d", batchSize, n)
+	}
+	if n := len(responses[2].Rows); n != 20 || !responses[2].Truncated {
+		t.Errorf("expected 20 truncated rows, got: %d %t", n, responses[2].Truncated)
+	}
+	var row []interface{}
+	for _, v := range responses[1].Rows[0].Values {
+		row = append(row, v.Interface())
+	}
+	if exp := []interface{}{int64(10), nil, []byte{10}}; !reflect.DeepEqual(row, exp) {
+		t.Errorf("expected row %v, got: %v", exp, row)
+	}
+}
+
+func TestGRPCReadOnly(t *testing.T) {
+	db := sql.OpenDB(readOnlyConnector{})
+	defer db.Close()
+	u, err := dburl.Parse("postgres://localhost/test")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	s := &Server{
+		Aliases: []string{"test"},
+		Token:   "secret",
+		Open: func(context.Context, string, string) (*dburl.URL, *sql.DB, error) {
+			return u, db, nil
+		},
+	}
+	l := bufconn.Listen(1 << 20)
+	g := s.NewGRPCServer()
+	go g.Serve(l)
+	defer g.Stop()
+	cc, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return l.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer cc.Close()
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer secret")
+	stream, err := NewQueryServiceClient(cc).Query(ctx, &QueryRequest{Alias: "test", Sql: "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if _, err := stream.Recv(); err == nil || !strings.Contains(err.Error(), "read-only transaction") {
+		t.Errorf("expected a read-only transaction error, got: %v", err)
+	}
+}
@@ -0,0 +1,375 @@
+// Package serve exposes the database aliases of the config file over HTTP.
+package serve
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/metrics"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/stmt"
+	"github.com/xo/usql/tracing"
+)
+
+// DefaultMaxRows is the default maximum number of rows returned by a query.
+const DefaultMaxRows = 10000
+
+// Opener opens a connection to a database alias, using the credentials of
+// the role (or the default role, when empty).
+type Opener func(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error)
+
+// Server serves the database aliases.
+//
+// Endpoints:
+//
+//	GET  /aliases               list the database aliases
+//	POST /aliases/ALIAS/query   execute a statement on the alias
+//	GET  /metrics               Prometheus metrics
+//
+// Requests must carry the token as a bearer token in the Authorization header.
+type Server struct {
+	// Aliases are the served database aliases. Use SetAliases to change them
+	// while serving.
+	Aliases []string
+	// Open opens a connection to an alias.
+	Open Opener
+	// Token is the token required to authenticate requests.
+	Token string
+	// AllowWrite allows executing statements other than queries.
+	AllowWrite bool
+	// MaxRows is the maximum number of rows returned by a query.
+	MaxRows int
+	// Check, when set, returns an error when the statement is not allowed
+	// for the alias and role. It is called before connecting to the alias.
+	Check func(alias, role, sqlstr string) error
+
+	// amu guards Aliases
+	amu   sync.RWMutex
+	mu    sync.Mutex
+	conns map[connKey]*conn
+}
+
+// connKey is the key of an open connection.
+type connKey struct {
+	alias, role string
+}
+
+// conn is an open connection to an alias.
+type conn struct {
+	u  *dburl.URL
+	db *sql.DB
+}
+
+// queryRequest is the body of a query request.
+type queryRequest struct {
+	Role   string        `json:"role,omitempty"`
+	SQL    string        `json:"sql"`
+	Params []interface{} `json:"params,omitempty"`
+}
+
+// queryResponse is the body of a query response.
+type queryResponse struct {
+	Columns      []string        `json:"columns,omitempty"`
+	Rows         [][]interface{} `json:"rows,omitempty"`
+	Truncated    bool            `json:"truncated,omitempty"`
+	RowsAffected *int64          `json:"rows_affected,omitempty"`
+	Duration     string          `json:"duration"`
+}
+
+// errorResponse is the body of an error response.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// ServeHTTP satisfies the http.Handler interface.
+func (s *Server) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if !s.authorized(req) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="usql"`)
+		writeError(w, http.StatusUnauthorized, errors.New("invalid or missing token"))
+		return
+	}
+	path := strings.Trim(req.URL.Path, "/")
+	switch {
+	case path == "aliases":
+		if req.Method != http.MethodGet {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+			return
+		}
+		s.amu.RLock()
+		aliases := s.Aliases
+		s.amu.RUnlock()
+		writeJSON(w, http.StatusOK, map[string][]string{"aliases": aliases})
+	case path == "metrics":
+		metrics.Handler().ServeHTTP(w, req)
+	case strings.HasPrefix(path, "aliases/") && strings.HasSuffix(path, "/query"):
+		if req.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, fmt.Errorf("method %s not allowed", req.Method))
+			return
+		}
+		s.query(w, req, strings.TrimSuffix(strings.TrimPrefix(path, "aliases/"), "/query"))
+	default:
+		writeError(w, http.StatusNotFound, fmt.Errorf("unknown endpoint /%s", path))
+	}
+}
+
+// authorized returns true when the request carries the server's token.
+func (s *Server) authorized(req *http.Request) bool {
+	token := strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+	return s.Token != "" && subtle.ConstantTimeCompare([]byte(token), []byte(s.Token)) == 1
+}
+
+// query handles a query request.
+func (s *Server) query(w http.ResponseWriter, req *http.Request, alias string) {
+	var qr queryRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, req.Body, 1<<20)).Decode(&qr); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request: %w", err))
+		return
+	}
+	ctx := req.Context()
+	c, sqlstr, isQuery, status, err := s.prepare(ctx, alias, qr.Role, qr.SQL)
+	if err != nil {
+		writeError(w, status, err)
+		return
+	}
+	start := time.Now()
+	ctx, span := tracing.Start(ctx, "query", tracing.Attrs(c.u, alias, "")...)
+	var res *queryResponse
+	db, end, err := s.begin(ctx, c)
+	if err == nil {
+		if isQuery {
+			res, err = s.execQuery(ctx, db, sqlstr, qr.Params)
+		} else {
+			res, err = execExec(ctx, c, db, sqlstr, qr.Params)
+		}
+		end()
+	}
+	metrics.Observe(alias, time.Since(start), err)
+	if err != nil {
+		err = drivers.WrapErr(c.u.Driver, err)
+		tracing.End(span, err)
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	if res.RowsAffected != nil {
+		span.SetAttributes(tracing.RowsKey.Int64(*res.RowsAffected))
+	} else {
+		span.SetAttributes(tracing.RowsKey.Int(len(res.Rows)))
+	}
+	tracing.End(span, nil)
+	res.Duration = time.Since(start).String()
+	writeJSON(w, http.StatusOK, res)
+}
+
+// prepare returns the connection to the alias with the role, and the
+// processed statement of sqlstr. On error, the HTTP status of the error is
+// returned.
+func (s *Server) prepare(ctx context.Context, alias, role, sqlstr string) (*conn, string, bool, int, error) {
+	if !s.served(alias) {
+		return nil, "", false, http.StatusNotFound, fmt.Errorf("unknown alias %q", alias)
+	}
+	if s.Check != nil {
+		if err := s.Check(alias, role, sqlstr); err != nil {
+			return nil, "", false, http.StatusForbidden, err
+		}
+	}
+	c, err := s.conn(ctx, alias, role)
+	if err != nil {
+		return nil, "", false, http.StatusBadGateway, err
+	}
+	stmts, err := drivers.Statements(c.u, sqlstr)
+	switch {
+	case err != nil:
+		return nil, "", false, http.StatusBadRequest, err
+	case len(stmts) != 1:
+		return nil, "", false, http.StatusBadRequest, fmt.Errorf("expected exactly 1 statement, got %d", len(stmts))
+	}
+	typ, sqlstr, isQuery, err := drivers.Process(c.u, stmt.FindPrefix(stmts[0], true, true, true), stmts[0])
+	switch {
+	case err != nil:
+		return nil, "", false, http.StatusBadRequest, err
+	case !isQuery && !s.AllowWrite:
+		return nil, "", false, http.StatusForbidden, fmt.Errorf("%s statements are not allowed in read-only mode", typ)
+	}
+	return c, sqlstr, isQuery, http.StatusOK, nil
+}
+
+// begin returns the database executing the statement of a request: the
+// connection to the alias when writes are allowed or when the driver has no
+// read-only transactions, and otherwise a read-only transaction, so that the
+// statements classified as queries that write (such as WITH ... DELETE, EXEC
+// and functions writing) are refused by the database. The returned func ends
+// the transaction, rolling it back.
+func (s *Server) begin(ctx context.Context, c *conn) (drivers.DB, func(), error) {
+	if s.AllowWrite || !drivers.Caps(c.u).ReadOnly {
+		return c.db, func() {}, nil
+	}
+	tx, err := c.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("read-only mode: %w", err)
+	}
+	return tx, func() { _ = tx.Rollback() }, nil
+}
+
+// execQuery executes a query, returning at most MaxRows rows.
+func (s *Server) execQuery(ctx context.Context, db drivers.DB, sqlstr string, params []interface{}) (*queryResponse, error) {
+	rows, err := db.QueryContext(ctx, sqlstr, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	max := s.MaxRows
+	if max <= 0 {
+		max = DefaultMaxRows
+	}
+	res := &queryResponse{Columns: cols, Rows: [][]interface{}{}}
+	for rows.Next() {
+		if len(res.Rows) == max {
+			res.Truncated = true
+			break
+		}
+		row := make([]interface{}, len(cols))
+		for i := range row {
+			row[i] = new(interface{})
+		}
+		if err := rows.Scan(row...); err != nil {
+			return nil, err
+		}
+		for i, v := range row {
+			row[i] = jsonValue(*(v.(*interface{})))
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return res, rows.Err()
+}
+
+// execExec executes a statement that returns no rows.
+func execExec(ctx context.Context, c *conn, db drivers.DB, sqlstr string, params []interface{}) (*queryResponse, error) {
+	r, err := db.ExecContext(ctx, sqlstr, params...)
+	if err != nil {
+		return nil, err
+	}
+	n, err := drivers.RowsAffected(c.u, r)
+	if err != nil {
+		return nil, err
+	}
+	return &queryResponse{RowsAffected: &n}, nil
+}
+
+// served returns true when the alias is served.
+func (s *Server) served(alias string) bool {
+	s.amu.RLock()
+	defer s.amu.RUnlock()
+	for _, a := range s.Aliases {
+		if a == alias {
+			return true
+		}
+	}
+	return false
+}
+
+// conn returns the connection to the alias with the role, opening it on
+// first use. The connection is opened without holding the lock, so that
+// opening it doesn't block the other requests.
+func (s *Server) conn(ctx context.Context, alias, role string) (*conn, error) {
+	key := connKey{alias, role}
+	s.mu.Lock()
+	c, ok := s.conns[key]
+	s.mu.Unlock()
+	if ok {
+		return c, nil
+	}
+	u, db, err := s.Open(ctx, alias, role)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", alias, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch c, ok := s.conns[key]; {
+	case ok:
+		// opened concurrently
+		_ = db.Close()
+		return c, nil
+	case !s.served(alias):
+		// no longer served
+		_ = db.Close()
+		return nil, fmt.Errorf("unknown alias %q", alias)
+	}
+	if s.conns == nil {
+		s.conns = make(map[connKey]*conn)
+	}
+	metrics.Track(db, alias)
+	c = &conn{u: u, db: db}
+	s.conns[key] = c
+	return c, nil
+}
+
+// SetAliases changes the served database aliases, closing the open
+// connections to the aliases no longer served.
+func (s *Server) SetAliases(aliases []string) {
+	s.amu.Lock()
+	s.Aliases = aliases
+	s.amu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for key, c := range s.conns {
+		if !s.served(key.alias) {
+			metrics.Untrack(c.db)
+			_ = c.db.Close()
+			delete(s.conns, key)
+		}
+	}
+}
+
+// Close closes the open connections.
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var err error
+	for key, c := range s.conns {
+		metrics.Untrack(c.db)
+		if e := c.db.Close(); e != nil && err == nil {
+			err = e
+		}
+		delete(s.conns, key)
+	}
+	return err
+}
+
+// jsonValue converts a scanned value to a value encoded as JSON.
+func jsonValue(v interface{}) interface{} {
+	if b, ok := v.([]byte); ok {
+		if utf8.Valid(b) {
+			return string(b)
+		}
+		// encoding/json encodes []byte as base64
+		return b
+	}
+	return v
+}
+
+// writeJSON writes v as the JSON response.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes err as the JSON response.
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: redact.String(err.Error())})
+}
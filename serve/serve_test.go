@@ -0,0 +1,365 @@
+package serve
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+)
+
+func TestServer(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(`CREATE TABLE t (id INTEGER, name TEXT); INSERT INTO t VALUES (1, 'a'), (2, 'b'), (3, NULL)`); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	opened := make(map[string]int)
+	s := &Server{
+		Aliases: []string{"test"},
+		Token:   "secret",
+		MaxRows: 2,
+		Open: func(_ context.Context, _, role string) (*dburl.URL, *sql.DB, error) {
+			opened[role]++
+			return u, db, nil
+		},
+		Check: func(_, role, sqlstr string) error {
+			if role == "analyst" && strings.Contains(sqlstr, "name") {
+				return errors.New("denied")
+			}
+			return nil
+		},
+	}
+	defer s.Close()
+	tests := []struct {
+		method, path, token, body string
+		status                    int
+		exp                       string
+	}{
+		{"GET", "/aliases", "", "", http.StatusUnauthorized, `{"error":"invalid or missing token"}`},
+		{"GET", "/aliases", "wrong", "", http.StatusUnauthorized, `{"error":"invalid or missing token"}`},
+		{"GET", "/aliases", "secret", "", http.StatusOK, `{"aliases":["test"]}`},
+		{"POST", "/aliases/other/query", "secret", `{"sql":"select 1"}`, http.StatusNotFound, `{"error":"unknown alias \"other\""}`},
+		{"POST", "/aliases/test/query", "secret", `{"sql":"select id, name from t where id > ? order by id","params":[0]}`, http.StatusOK, `{"columns":["id","name"],"rows":[[1,"a"],[2,"b"]],"truncated":true}`},
+		{"POST", "/aliases/test/query", "secret", `{"sql":"select name from t where id = ?","params":[3]}`, http.StatusOK, `{"columns":["name"],"rows":[[null]]}`},
+		{"POST", "/aliases/test/query", "secret", `{"sql":"delete from t"}`, http.StatusForbidden, `{"error":"DELETE statements are not allowed in read-only mode"}`},
+		{"POST", "/aliases/test/query", "secret", `{"sql":"select 1; select 2"}`, http.StatusBadRequest, `{"error":"expected exactly 1 statement, got 2"}`},
+		{"POST", "/aliases/test/query", "secret", `{"sql":"select name from t","role":"analyst"}`, http.StatusForbidden, `{"error":"denied"}`},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("test %d expected status %d, got: %d", i, test.status, w.Code)
+		}
+		var res, exp map[string]interface{}
+		if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if err := json.Unmarshal([]byte(test.exp), &exp); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		delete(res, "duration")
+		if !reflect.DeepEqual(res, exp) {
+			t.Errorf("test %d expected %s, got: %s", i, test.exp, w.Body.String())
+		}
+	}
+	// the statements are checked before connecting
+	if n := opened["analyst"]; n != 0 {
+		t.Errorf("expected no connection of the analyst role, got: %d", n)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	s := &Server{
+		Aliases: []string{"metrics_test"},
+		Token:   "secret",
+		Open: func(context.Context, string, string) (*dburl.URL, *sql.DB, error) {
+			return u, db, nil
+		},
+	}
+	defer s.Close()
+	for _, sqlstr := range []string{"select 1", "select * from missing"} {
+		req := httptest.NewRequest("POST", "/aliases/metrics_test/query", strings.NewReader(`{"sql":"`+sqlstr+`"}`))
+		req.Header.Set("Authorization", "Bearer secret")
+		s.ServeHTTP(httptest.NewRecorder(), req)
+	}
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("expected status %d, got: %d", http.StatusUnauthorized, w.Code)
+	}
+	req.Header.Set("Authorization", "Bearer secret")
+	w = httptest.NewRecorder()
+	s.ServeHTTP(w, req)
+	for _, exp := range []string{
+		`usql_queries_total{alias="metrics_test"} 2`,
+		`usql_query_errors_total{alias="metrics_test"} 1`,
+		`usql_open_connections{alias="metrics_test"} 1`,
+	} {
+		if !strings.Contains(w.Body.String(), exp) {
+			t.Errorf("expected metrics to contain %q, got:\n%s", exp, w.Body.String())
+		}
+	}
+}
+
+func TestSetAliases(t *testing.T) {
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	var opened []*sql.DB
+	s := &Server{
+		Aliases: []string{"a"},
+		Token:   "secret",
+		Open: func(context.Context, string, string) (*dburl.URL, *sql.DB, error) {
+			db, err := sql.Open("sqlite3", ":memory:")
+			opened = append(opened, db)
+			return u, db, err
+		},
+	}
+	defer s.Close()
+	query := func(alias string) int {
+		req := httptest.NewRequest("POST", "/aliases/"+alias+"/query", strings.NewReader(`{"sql":"select 1"}`))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		return w.Code
+	}
+	if status := query("a"); status != http.StatusOK {
+		t.Fatalf("expected status %d, got: %d", http.StatusOK, status)
+	}
+	s.SetAliases([]string{"b"})
+	if err := opened[0].Ping(); err == nil {
+		t.Errorf("expected connection to removed alias to be closed")
+	}
+	if status := query("a"); status != http.StatusNotFound {
+		t.Errorf("expected status %d, got: %d", http.StatusNotFound, status)
+	}
+	if status := query("b"); status != http.StatusOK {
+		t.Errorf("expected status %d, got: %d", http.StatusOK, status)
+	}
+}
+
+func init() {
+	// the driver of readOnlyConnector, beginning read-only transactions as
+	// postgres does
+	drivers.Register("postgres", drivers.Driver{ReadOnlyTransactions: true})
+}
+
+func TestReadOnly(t *testing.T) {
+	db := sql.OpenDB(readOnlyConnector{})
+	defer db.Close()
+	u, err := dburl.Parse("postgres://localhost/test")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	// statements classified as queries, but writing
+	writes := []string{
+		"WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d",
+		"EXEC proc",
+		"SELECT write_fn()",
+	}
+	for _, allowWrite := range []bool{false, true} {
+		s := &Server{
+			Aliases:    []string{"test"},
+			Token:      "secret",
+			AllowWrite: allowWrite,
+			Open: func(context.Context, string, string) (*dburl.URL, *sql.DB, error) {
+				return u, db, nil
+			},
+		}
+		for i, sqlstr := range append(writes, "SELECT n FROM t") {
+			body, err := json.Marshal(queryRequest{SQL: sqlstr})
+			if err != nil {
+				t.Fatalf("expected no error, got: %v", err)
+			}
+			req := httptest.NewRequest("POST", "/aliases/test/query", strings.NewReader(string(body)))
+			req.Header.Set("Authorization", "Bearer secret")
+			w := httptest.NewRecorder()
+			s.ServeHTTP(w, req)
+			switch {
+			case !allowWrite && i < len(writes):
+				if w.Code != http.StatusUnprocessableEntity || !strings.Contains(w.Body.String(), "read-only transaction") {
+					t.Errorf("test %d expected a read-only transaction error, got: %d %s", i, w.Code, w.Body.String())
+				}
+			case w.Code != http.StatusOK:
+				t.Errorf("test %d (allow write %t) expected status %d, got: %d %s", i, allowWrite, http.StatusOK, w.Code, w.Body.String())
+			}
+		}
+	}
+	if n := db.Stats().InUse; n != 0 {
+		t.Errorf("expected the transactions to be ended, got %d connections in use", n)
+	}
+}
+
+func TestReadOnlyUnsupported(t *testing.T) {
+	db := sql.OpenDB(readOnlyConnector{unsupported: true})
+	defer db.Close()
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	s := &Server{
+		Aliases: []string{"test"},
+		Token:   "secret",
+		Open: func(context.Context, string, string) (*dburl.URL, *sql.DB, error) {
+			return u, db, nil
+		},
+	}
+	defer s.Close()
+	tests := []struct {
+		sqlstr string
+		status int
+	}{
+		{"SELECT n FROM t", http.StatusOK},
+		{"DELETE FROM t", http.StatusForbidden},
+	}
+	for i, test := range tests {
+		req := httptest.NewRequest("POST", "/aliases/test/query", strings.NewReader(`{"sql":"`+test.sqlstr+`"}`))
+		req.Header.Set("Authorization", "Bearer secret")
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, req)
+		if w.Code != test.status {
+			t.Errorf("test %d expected status %d, got: %d %s", i, test.status, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestConn(t *testing.T) {
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	opening, release := make(chan struct{}), make(chan struct{})
+	s := &Server{
+		Aliases: []string{"slow", "fast"},
+		Token:   "secret",
+		Open: func(_ context.Context, alias, _ string) (*dburl.URL, *sql.DB, error) {
+			if alias == "slow" {
+				close(opening)
+				<-release
+			}
+			db, err := sql.Open("sqlite3", ":memory:")
+			return u, db, err
+		},
+	}
+	defer s.Close()
+	errc := make(chan error, 1)
+	go func() {
+		_, err := s.conn(context.Background(), "slow", "")
+		errc <- err
+	}()
+	<-opening
+	// the other aliases are opened while the slow alias is being opened
+	if _, err := s.conn(context.Background(), "fast", ""); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	s.SetAliases([]string{"fast"})
+	close(release)
+	if err := <-errc; err == nil || err.Error() != `unknown alias "slow"` {
+		t.Errorf("expected the alias no longer served, got: %v", err)
+	}
+}
+
+// readOnlyConnector is the connector of a database refusing the statements
+// writing in read-only transactions, as postgres and mysql do, or refusing
+// read-only transactions when unsupported, as sqlserver does.
+type readOnlyConnector struct {
+	unsupported bool
+}
+
+func (c readOnlyConnector) Connect(context.Context) (driver.Conn, error) {
+	return &readOnlyConn{unsupported: c.unsupported}, nil
+}
+func (readOnlyConnector) Driver() driver.Driver { return nil }
+
+type readOnlyConn struct {
+	unsupported bool
+	readOnly    bool
+}
+
+func (c *readOnlyConn) Prepare(query string) (driver.Stmt, error) {
+	return &readOnlyStmt{c: c, query: query}, nil
+}
+func (c *readOnlyConn) Close() error              { return nil }
+func (c *readOnlyConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *readOnlyConn) BeginTx(_ context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if opts.ReadOnly && c.unsupported {
+		return nil, errors.New("read-only transactions are not supported")
+	}
+	c.readOnly = opts.ReadOnly
+	return c, nil
+}
+func (c *readOnlyConn) Commit() error   { c.readOnly = false; return nil }
+func (c *readOnlyConn) Rollback() error { c.readOnly = false; return nil }
+
+type readOnlyStmt struct {
+	c     *readOnlyConn
+	query string
+}
+
+func (s *readOnlyStmt) Close() error  { return nil }
+func (s *readOnlyStmt) NumInput() int { return -1 }
+func (s *readOnlyStmt) Exec([]driver.Value) (driver.Result, error) {
+	return driver.RowsAffected(0), s.check()
+}
+func (s *readOnlyStmt) Query([]driver.Value) (driver.Rows, error) {
+	if err := s.check(); err != nil {
+		return nil, err
+	}
+	return &readOnlyRows{}, nil
+}
+
+// check returns the error of the statements writing in read-only
+// transactions.
+func (s *readOnlyStmt) check() error {
+	if s.c.readOnly && !strings.HasPrefix(s.query, "SELECT n ") {
+		return errors.New("cannot execute statement in a read-only transaction")
+	}
+	return nil
+}
+
+type readOnlyRows struct {
+	done bool
+}
+
+func (r *readOnlyRows) Columns() []string { return []string{"n"} }
+func (r *readOnlyRows) Close() error      { return nil }
+func (r *readOnlyRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	dest[0], r.done = int64(1), true
+	return nil
+}
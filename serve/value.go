@@ -0,0 +1,55 @@
+package serve
+
+import (
+	"fmt"
+	"time"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// NewValue creates a value for a value scanned from database/sql or passed as
+// a parameter. Values of other types are converted to strings.
+func NewValue(v interface{}) *Value {
+	switch x := v.(type) {
+	case nil:
+		return &Value{Kind: &Value_Null{Null: true}}
+	case string:
+		return &Value{Kind: &Value_String_{String_: x}}
+	case int64:
+		return &Value{Kind: &Value_Int{Int: x}}
+	case int:
+		return &Value{Kind: &Value_Int{Int: int64(x)}}
+	case int32:
+		return &Value{Kind: &Value_Int{Int: int64(x)}}
+	case float64:
+		return &Value{Kind: &Value_Float{Float: x}}
+	case float32:
+		return &Value{Kind: &Value_Float{Float: float64(x)}}
+	case bool:
+		return &Value{Kind: &Value_Bool{Bool: x}}
+	case []byte:
+		return &Value{Kind: &Value_Bytes{Bytes: x}}
+	case time.Time:
+		return &Value{Kind: &Value_Time{Time: timestamppb.New(x)}}
+	}
+	return &Value{Kind: &Value_String_{String_: fmt.Sprint(v)}}
+}
+
+// Interface returns the Go value of the value.
+func (m *Value) Interface() interface{} {
+	switch x := m.GetKind().(type) {
+	case *Value_String_:
+		return x.String_
+	case *Value_Int:
+		return x.Int
+	case *Value_Float:
+		return x.Float
+	case *Value_Bool:
+		return x.Bool
+	case *Value_Bytes:
+		return x.Bytes
+	case *Value_Time:
+		return x.Time.AsTime()
+	}
+	return nil
+}
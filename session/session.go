@@ -0,0 +1,239 @@
+// Package session records the statements executed in a session to a JSON
+// file, and replays them on another database.
+package session
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/redact"
+)
+
+// Session is a recorded session.
+type Session struct {
+	Started time.Time `json:"started"`
+	OSUser  string    `json:"os_user,omitempty"`
+	Alias   string    `json:"alias,omitempty"`
+	Role    string    `json:"role,omitempty"`
+	// URL is the URL of the database, with its password redacted.
+	URL        string       `json:"url,omitempty"`
+	Statements []*Statement `json:"statements"`
+}
+
+// Statement is a recorded statement, with the metadata of its result.
+type Statement struct {
+	Time   time.Time `json:"time"`
+	Prefix string    `json:"prefix,omitempty"`
+	SQL    string    `json:"sql"`
+	// Args are the arguments bound to the statement.
+	Args []interface{} `json:"args,omitempty"`
+	// Query is set when the statement returns rows.
+	Query   bool     `json:"query"`
+	Columns []string `json:"columns,omitempty"`
+	// Rows is the number of rows returned by a query or affected by another
+	// statement, when known.
+	Rows *int64 `json:"rows,omitempty"`
+	// Duration is the duration in milliseconds.
+	Duration float64 `json:"duration_ms"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// Load loads a recorded session.
+func Load(path string) (*Session, error) {
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	s := new(Session)
+	if err := json.Unmarshal(buf, s); err != nil {
+		return nil, fmt.Errorf("invalid session file %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Recorder records the statements of a session to a file, which is rewritten
+// after every statement so that it is complete even when usql is killed.
+type Recorder struct {
+	mu   sync.Mutex
+	path string
+	s    *Session
+}
+
+// NewRecorder creates a recorder writing the session s to path.
+func NewRecorder(path string, s *Session) (*Recorder, error) {
+	if s.Started.IsZero() {
+		s.Started = time.Now()
+	}
+	if s.Statements == nil {
+		s.Statements = []*Statement{}
+	}
+	r := &Recorder{path: path, s: s}
+	if err := r.write(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record records a statement, executed at start with the bound args, with the
+// columns and number of rows of its result (or -1 when unknown).
+func (r *Recorder) Record(prefix, sqlstr string, args []interface{}, query bool, start time.Time, cols []string, rows int64, err error) error {
+	st := &Statement{
+		Time:     start,
+		Prefix:   prefix,
+		SQL:      sqlstr,
+		Args:     args,
+		Query:    query,
+		Columns:  cols,
+		Duration: ms(time.Since(start)),
+	}
+	if rows >= 0 {
+		st.Rows = &rows
+	}
+	if err != nil {
+		st.Error = redact.String(err.Error())
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.s.Statements = append(r.s.Statements, st)
+	return r.write()
+}
+
+// write writes the session to a temporary file, renamed to the path.
+func (r *Recorder) write() error {
+	buf, err := json.MarshalIndent(r.s, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := filepath.Join(filepath.Dir(r.path), "."+filepath.Base(r.path)+".tmp")
+	if err := os.WriteFile(tmp, append(buf, '\n'), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, r.path)
+}
+
+// Options are the replay options.
+type Options struct {
+	// ContinueOnError continues with the next statements when a statement
+	// fails, unless it failed in the recording as well.
+	ContinueOnError bool
+	// QueriesOnly skips the statements that don't return rows.
+	QueriesOnly bool
+	// Check, when set, returns an error when a statement is not allowed.
+	Check func(u *dburl.URL, sqlstr string) error
+}
+
+// Replay executes the statements of the session on the database, writing
+// the result of every statement compared with the recording to w. An error
+// is returned when results differ from the recording.
+func Replay(ctx context.Context, w io.Writer, u *dburl.URL, db *sql.DB, s *Session, opts Options) error {
+	var diffs, skipped int
+	for i, st := range s.Statements {
+		fmt.Fprintf(w, "[%d/%d] ", i+1, len(s.Statements))
+		if opts.QueriesOnly && !st.Query {
+			fmt.Fprintf(w, "%s skipped\n", st.Prefix)
+			skipped++
+			continue
+		}
+		start := time.Now()
+		var cols []string
+		var rows int64
+		var err error
+		if opts.Check != nil {
+			err = opts.Check(u, st.SQL)
+		}
+		if err == nil {
+			cols, rows, err = execute(ctx, u, db, st)
+		}
+		d := ms(time.Since(start))
+		switch {
+		case err != nil && st.Error != "":
+			fmt.Fprintf(w, "%s failed as recorded: %v\n", st.Prefix, redact.Error(err))
+			continue
+		case err != nil:
+			fmt.Fprintf(w, "%s failed: %v\n", st.Prefix, redact.Error(err))
+			diffs++
+			if !opts.ContinueOnError {
+				return fmt.Errorf("statement %d failed: %w", i+1, err)
+			}
+			continue
+		case st.Error != "":
+			fmt.Fprintf(w, "%s succeeded, but failed in the recording: %s\n", st.Prefix, st.Error)
+			diffs++
+			continue
+		}
+		fmt.Fprintf(w, "%s %d rows in %.3fms", st.Prefix, rows, d)
+		if st.Rows != nil {
+			fmt.Fprintf(w, " (recorded: %d rows in %.3fms)", *st.Rows, st.Duration)
+		}
+		var diff []string
+		if st.Rows != nil && *st.Rows != rows {
+			diff = append(diff, "rows")
+		}
+		if st.Query && len(st.Columns) != 0 && !reflect.DeepEqual(st.Columns, cols) {
+			diff = append(diff, fmt.Sprintf("columns %v (recorded: %v)", cols, st.Columns))
+		}
+		if len(diff) != 0 {
+			fmt.Fprintf(w, " differs: %v", diff)
+			diffs++
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "replayed %d statements, %d skipped, %d differ\n", len(s.Statements)-skipped, skipped, diffs)
+	if diffs != 0 {
+		return fmt.Errorf("%d statements differ from the recording", diffs)
+	}
+	return nil
+}
+
+// execute executes a recorded statement, returning the columns and the
+// number of rows of its result.
+func execute(ctx context.Context, u *dburl.URL, db *sql.DB, st *Statement) ([]string, int64, error) {
+	_, sqlstr, query, err := drivers.Process(u, st.Prefix, st.SQL)
+	if err != nil {
+		return nil, 0, err
+	}
+	if !query {
+		res, err := db.ExecContext(ctx, sqlstr, st.Args...)
+		if err != nil {
+			return nil, 0, drivers.WrapErr(u.Driver, err)
+		}
+		n, err := drivers.RowsAffected(u, res)
+		return nil, n, err
+	}
+	rows, err := db.QueryContext(ctx, sqlstr, st.Args...)
+	if err != nil {
+		return nil, 0, drivers.WrapErr(u.Driver, err)
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, 0, err
+	}
+	vals := make([]interface{}, len(cols))
+	for i := range vals {
+		vals[i] = new(interface{})
+	}
+	var n int64
+	for rows.Next() {
+		if err := rows.Scan(vals...); err != nil {
+			return nil, 0, err
+		}
+		n++
+	}
+	return cols, n, drivers.WrapErr(u.Driver, rows.Err())
+}
+
+// ms returns d in milliseconds.
+func ms(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,79 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+)
+
+func TestRecordReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "session.json")
+	r, err := NewRecorder(path, &Session{Alias: "test"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	start := time.Now()
+	for _, st := range []struct {
+		prefix, sqlstr string
+		query          bool
+		cols           []string
+		rows           int64
+		err            error
+	}{
+		{"CREATE TABLE", "create table t (id integer)", false, nil, 0, nil},
+		{"INSERT", "insert into t values (1), (2)", false, nil, 2, nil},
+		{"SELECT", "select id from t", true, []string{"id"}, 2, nil},
+		{"SELECT", "select * from missing", true, nil, -1, errors.New("no such table: missing")},
+	} {
+		if err := r.Record(st.prefix, st.sqlstr, nil, st.query, start, st.cols, st.rows, st.err); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	s, err := Load(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s.Alias != "test" || len(s.Statements) != 4 {
+		t.Fatalf("expected 4 statements of test, got: %s %d", s.Alias, len(s.Statements))
+	}
+	if st := s.Statements[2]; !st.Query || !reflect.DeepEqual(st.Columns, []string{"id"}) || st.Rows == nil || *st.Rows != 2 {
+		t.Errorf("expected query with id column and 2 rows, got: %+v", st)
+	}
+	if st := s.Statements[3]; st.Rows != nil || st.Error == "" {
+		t.Errorf("expected error without rows, got: %+v", st)
+	}
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	var buf bytes.Buffer
+	if err := Replay(context.Background(), &buf, u, db, s, Options{}); err != nil {
+		t.Fatalf("expected no error, got: %v\n%s", err, buf.String())
+	}
+	if !strings.Contains(buf.String(), "replayed 4 statements, 0 skipped, 0 differ") {
+		t.Errorf("expected no differences, got:\n%s", buf.String())
+	}
+	// replaying again fails to create the table, and inserts more rows
+	buf.Reset()
+	if err := Replay(context.Background(), &buf, u, db, s, Options{ContinueOnError: true}); err == nil {
+		t.Fatalf("expected error, got nil")
+	}
+	if !strings.Contains(buf.String(), "replayed 4 statements, 0 skipped, 2 differ") {
+		t.Errorf("expected 2 differences, got:\n%s", buf.String())
+	}
+}
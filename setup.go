@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/rline"
+	"gopkg.in/yaml.v2"
+)
+
+// defaultPorts are the default ports of the database types, suggested by the
+// setup wizard.
+var defaultPorts = map[string]string{
+	"postgres":   "5432",
+	"mysql":      "3306",
+	"sqlserver":  "1433",
+	"oracle":     "1521",
+	"clickhouse": "9000",
+	"redshift":   "5439",
+}
+
+// offerSetup offers to create the config file with the setup wizard when
+// none was found and usql runs in a terminal, so that --db can be used on the
+// first run. The config file path of args is set to the created config file.
+func offerSetup(args *Args) error {
+	if args.ConfigFilePath != "" || config.Find() != "" || !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+		return nil
+	}
+	l, err := rline.New(false, "", "")
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	w := &wizard{l: l}
+	if !w.confirm("No config file found. Create one now?", true) {
+		return w.err
+	}
+	path, err := w.run(args.DB, "")
+	if err != nil || path == "" {
+		return err
+	}
+	args.ConfigFilePath, args.configs = path, nil
+	return nil
+}
+
+// wizard is the interactive setup wizard of the config file.
+type wizard struct {
+	l   rline.IO
+	err error
+}
+
+// run asks for the connection of a database alias, tests it, and writes the
+// config file at path, asking for it when empty. It returns the path of the
+// written config file, or an empty string when it was not written.
+func (w *wizard) run(alias, path string) (string, error) {
+	out := w.l.Stdout()
+	var err error
+	db := &config.DatabaseConfig{
+		DbType: w.ask("Database type ("+strings.Join(dbTypeNames(), ", ")+")", "postgres"),
+	}
+	if !drivers.Registered(db.DbType) && w.err == nil {
+		fmt.Fprintf(out, "warning: the %s driver is not available in this build\n", db.DbType)
+	}
+	var rc *config.RoleConfig
+	if db.DbType == "sqlite3" || db.DbType == "duckdb" {
+		if db.Name = w.ask("Database file", ""); db.Name != "" && db.Name != ":memory:" {
+			if db.Name, err = filepath.Abs(db.Name); err != nil {
+				return "", err
+			}
+		}
+	} else {
+		db.Host = w.ask("Host", "localhost")
+		if port := w.ask("Port", defaultPorts[db.DbType]); port != "" {
+			db.Host = net.JoinHostPort(db.Host, port)
+		}
+		db.Name = w.ask("Database name", "")
+		if username := w.ask("Username", ""); username != "" {
+			rc = &config.RoleConfig{Username: username, Name: w.ask("Role", username)}
+			if w.err == nil {
+				rc.Password, w.err = w.l.Password("Password: ")
+			}
+			db.Credentials = []*config.RoleConfig{rc}
+		}
+	}
+	if alias == "" {
+		alias = w.ask("Alias", aliasOf(db))
+	}
+	if path == "" {
+		path = w.ask("Config file", filepath.Join("~", config.DefaultFilename))
+	}
+	if w.err != nil {
+		return "", w.err
+	}
+	if path, err = config.ExpandHome(path); err != nil {
+		return "", err
+	}
+	if path, err = filepath.Abs(path); err != nil {
+		return "", err
+	}
+	// test the connection
+	cfg := &config.Config{Path: path, Databases: map[string]*config.DatabaseConfig{alias: db}}
+	role := ""
+	if rc != nil {
+		role = rc.Name
+	}
+	fmt.Fprintf(out, "Connecting to %s...\n", alias)
+	if _, conn, err := newOpener(cfg).Open(context.Background(), alias, role); err != nil {
+		fmt.Fprintf(w.l.Stderr(), "error: %v\n", err)
+		if !w.confirm("Save the config file anyway?", false) {
+			return "", w.err
+		}
+	} else {
+		conn.Close()
+		fmt.Fprintln(out, "Connected.")
+	}
+	if exists(path) && !w.confirm(path+" exists. Overwrite it?", false) {
+		return "", w.err
+	}
+	buf, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	// the config file holds passwords
+	if err := os.WriteFile(path, buf, 0o600); err != nil {
+		return "", err
+	}
+	fmt.Fprintf(out, "Wrote %s. Connect with: usql --db %s", path, alias)
+	if role != "" {
+		fmt.Fprintf(out, " --role %s", role)
+	}
+	fmt.Fprintln(out)
+	return path, nil
+}
+
+// ask asks for a value, returning def when none is entered.
+func (w *wizard) ask(prompt, def string) string {
+	if w.err != nil {
+		return ""
+	}
+	if def != "" {
+		prompt += " [" + def + "]"
+	}
+	w.l.Prompt(prompt + ": ")
+	line, err := w.l.Next()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			err = errors.New("setup canceled")
+		}
+		w.err = err
+		return ""
+	}
+	if s := strings.TrimSpace(string(line)); s != "" {
+		return s
+	}
+	return def
+}
+
+// confirm asks a yes or no question, returning def when not answered.
+func (w *wizard) confirm(prompt string, def bool) bool {
+	choices := "y/N"
+	if def {
+		choices = "Y/n"
+	}
+	switch strings.ToLower(w.ask(prompt+" ("+choices+")", "")) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	}
+	return def && w.err == nil
+}
+
+// dbTypeNames returns the names of the most common database types available
+// in this build.
+func dbTypeNames() []string {
+	var names []string
+	for _, name := range []string{"postgres", "mysql", "sqlserver", "oracle", "sqlite3", "clickhouse"} {
+		if drivers.Registered(name) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// aliasOf returns the suggested alias of a database: its name, without the
+// directory and extension of database files, or its type.
+func aliasOf(db *config.DatabaseConfig) string {
+	name := strings.TrimSuffix(filepath.Base(db.Name), filepath.Ext(db.Name))
+	if name == "" || name == "." {
+		return db.DbType
+	}
+	return name
+}
+
+// exists returns true when the file at path exists.
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
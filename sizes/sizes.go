@@ -0,0 +1,182 @@
+// Package sizes reads the row counts and sizes of the tables of databases from
+// their catalogs.
+package sizes
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/xo/dburl"
+)
+
+// Table is the row count and size of a table.
+type Table struct {
+	Schema, Name string
+	// Rows is the number of rows of the table, counted on sqlite3 databases
+	// and estimated from the statistics of the database on the others.
+	Rows int64
+	// DataSize and IndexSize are the sizes in bytes of the data and the
+	// indexes of the table, or -1 when unknown.
+	DataSize, IndexSize int64
+}
+
+// Size returns the total size of the table, ignoring unknown sizes, or -1
+// when both are unknown.
+func (t Table) Size() int64 {
+	if t.DataSize < 0 && t.IndexSize < 0 {
+		return -1
+	}
+	var n int64
+	for _, v := range []int64{t.DataSize, t.IndexSize} {
+		if v > 0 {
+			n += v
+		}
+	}
+	return n
+}
+
+// queries are the catalog queries of the table row counts and sizes, by
+// driver, returning the schema, name, rows, data size and index size of the
+// tables.
+var queries = map[string]string{
+	"postgres": `SELECT n.nspname, c.relname, GREATEST(c.reltuples, 0)::bigint, pg_table_size(c.oid), pg_indexes_size(c.oid) ` +
+		`FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace ` +
+		`WHERE c.relkind IN ('r', 'p', 'm') AND n.nspname NOT IN ('pg_catalog', 'information_schema') AND n.nspname NOT LIKE 'pg_toast%'`,
+	"redshift": `SELECT "schema", "table", COALESCE(tbl_rows, 0)::bigint, COALESCE(size, 0)::bigint * 1048576, -1 FROM svv_table_info`,
+	"cockroachdb": `SELECT schema_name, table_name, COALESCE(estimated_row_count, 0), -1, -1 ` +
+		`FROM crdb_internal.tables t JOIN information_schema.tables i ON i.table_schema = t.schema_name AND i.table_name = t.name ` +
+		`WHERE t.database_name = current_database() AND i.table_type = 'BASE TABLE'`,
+	"mysql": `SELECT table_schema, table_name, COALESCE(table_rows, 0), COALESCE(data_length, 0), COALESCE(index_length, 0) ` +
+		`FROM information_schema.tables ` +
+		`WHERE table_type = 'BASE TABLE' AND table_schema NOT IN ('mysql', 'information_schema', 'performance_schema', 'sys')`,
+	"sqlserver": `SELECT s.name, t.name, ` +
+		`CAST(SUM(CASE WHEN p.index_id IN (0, 1) THEN p.row_count ELSE 0 END) AS BIGINT), ` +
+		`CAST(SUM(CASE WHEN p.index_id IN (0, 1) THEN p.used_page_count ELSE 0 END) AS BIGINT) * 8192, ` +
+		`CAST(SUM(CASE WHEN p.index_id > 1 THEN p.used_page_count ELSE 0 END) AS BIGINT) * 8192 ` +
+		`FROM sys.tables t JOIN sys.schemas s ON s.schema_id = t.schema_id JOIN sys.dm_db_partition_stats p ON p.object_id = t.object_id ` +
+		`GROUP BY s.name, t.name`,
+	"oracle": `SELECT USER, t.table_name, NVL(t.num_rows, 0), ` +
+		`NVL((SELECT SUM(s.bytes) FROM user_segments s WHERE s.segment_name = t.table_name), 0), ` +
+		`NVL((SELECT SUM(s.bytes) FROM user_segments s JOIN user_indexes i ON i.index_name = s.segment_name WHERE i.table_name = t.table_name), 0) ` +
+		`FROM user_tables t`,
+	"clickhouse": `SELECT database, table, toInt64(sum(rows)), toInt64(sum(data_compressed_bytes)), toInt64(sum(primary_key_bytes_in_memory)) ` +
+		`FROM system.parts WHERE active AND database NOT IN ('system', 'INFORMATION_SCHEMA', 'information_schema') GROUP BY database, table`,
+	"duckdb": `SELECT schema_name, table_name, estimated_size, -1, -1 FROM duckdb_tables() WHERE NOT internal`,
+}
+
+// Read returns the row counts and sizes of the tables of the database, or of
+// its schema when not empty, sorted by decreasing size and row count.
+func Read(ctx context.Context, u *dburl.URL, db *sql.DB, schema string) ([]Table, error) {
+	var tables []Table
+	var err error
+	switch query, ok := queries[u.Unaliased]; {
+	case u.Unaliased == "sqlite3" || u.Unaliased == "moderncsqlite":
+		tables, err = readSQLite(ctx, db)
+	case ok:
+		tables, err = readTables(ctx, db, query)
+	default:
+		return nil, fmt.Errorf("the table sizes of %s databases are not supported", u.Unaliased)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if schema != "" {
+		var filtered []Table
+		for _, t := range tables {
+			if strings.EqualFold(t.Schema, schema) {
+				filtered = append(filtered, t)
+			}
+		}
+		tables = filtered
+	}
+	sort.SliceStable(tables, func(i, j int) bool {
+		a, b := tables[i], tables[j]
+		switch {
+		case a.Size() != b.Size():
+			return a.Size() > b.Size()
+		case a.Rows != b.Rows:
+			return a.Rows > b.Rows
+		case a.Schema != b.Schema:
+			return a.Schema < b.Schema
+		}
+		return a.Name < b.Name
+	})
+	return tables, nil
+}
+
+// readTables reads the tables returned by a catalog query.
+func readTables(ctx context.Context, db *sql.DB, query string) ([]Table, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []Table
+	for rows.Next() {
+		var t Table
+		if err := rows.Scan(&t.Schema, &t.Name, &t.Rows, &t.DataSize, &t.IndexSize); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+// readSQLite reads the tables of a sqlite3 database, counting their rows. The
+// sizes are read from the dbstat virtual table, when available.
+func readSQLite(ctx context.Context, db *sql.DB) ([]Table, error) {
+	names, err := sqliteTables(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]Table, len(names))
+	for i, name := range names {
+		tables[i] = Table{Schema: "main", Name: name, DataSize: -1, IndexSize: -1}
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM "`+strings.ReplaceAll(name, `"`, `""`)+`"`).Scan(&tables[i].Rows); err != nil {
+			return nil, err
+		}
+		const q = `SELECT COALESCE(SUM(pgsize), 0) FROM dbstat WHERE name IN (SELECT name FROM sqlite_master WHERE tbl_name = ? AND type = ?)`
+		var data, index int64
+		if db.QueryRowContext(ctx, q, name, "table").Scan(&data) == nil && db.QueryRowContext(ctx, q, name, "index").Scan(&index) == nil {
+			tables[i].DataSize, tables[i].IndexSize = data, index
+		}
+	}
+	return tables, nil
+}
+
+// sqliteTables returns the names of the tables of a sqlite3 database.
+func sqliteTables(ctx context.Context, db *sql.DB) ([]string, error) {
+	rows, err := db.QueryContext(ctx, `SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// FormatSize returns a size in bytes in a human-readable form, or - when
+// unknown.
+func FormatSize(n int64) string {
+	switch {
+	case n < 0:
+		return "-"
+	case n < 1<<10:
+		return fmt.Sprintf("%d B", n)
+	}
+	units, f := "kMGTP", float64(n)/(1<<10)
+	for ; f >= 1<<10 && len(units) > 1; f /= 1 << 10 {
+		units = units[1:]
+	}
+	return fmt.Sprintf("%.1f %cB", f, units[0])
+}
@@ -0,0 +1,73 @@
+package sizes
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+)
+
+func TestRead(t *testing.T) {
+	u, err := dburl.Parse("sqlite3::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	for _, s := range []string{
+		`CREATE TABLE small (id INTEGER PRIMARY KEY)`,
+		`CREATE TABLE "big ""one""" (id INTEGER PRIMARY KEY, name TEXT)`,
+		`CREATE INDEX big_name ON "big ""one""" (name)`,
+		`INSERT INTO small VALUES (1)`,
+		`INSERT INTO "big ""one""" VALUES (1, 'a'), (2, 'b'), (3, 'c')`,
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tables, err := Read(context.Background(), u, db, "")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 tables, got: %v", tables)
+	}
+	for i, exp := range []Table{{Schema: "main", Name: `big "one"`, Rows: 3}, {Schema: "main", Name: "small", Rows: 1}} {
+		if tables[i].Schema != exp.Schema || tables[i].Name != exp.Name || tables[i].Rows != exp.Rows {
+			t.Errorf("test %d expected %s.%s with %d rows, got: %s.%s with %d rows", i, exp.Schema, exp.Name, exp.Rows, tables[i].Schema, tables[i].Name, tables[i].Rows)
+		}
+	}
+	if tables, err := Read(context.Background(), u, db, "other"); err != nil || len(tables) != 0 {
+		t.Errorf("expected no tables in schema other, got: %v %v", tables, err)
+	}
+	if _, err := Read(context.Background(), &dburl.URL{Unaliased: "csvq"}, db, ""); err == nil {
+		t.Errorf("expected error for unsupported database, got nil")
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	tests := []struct {
+		n   int64
+		exp string
+	}{
+		{-1, "-"},
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{2048, "2.0 kB"},
+		{3 << 20, "3.0 MB"},
+		{5 << 30, "5.0 GB"},
+		{7 << 40, "7.0 TB"},
+		{3 << 60, "3072.0 PB"},
+	}
+	for i, test := range tests {
+		if s := FormatSize(test.n); s != test.exp {
+			t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+		}
+	}
+}
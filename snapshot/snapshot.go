@@ -0,0 +1,328 @@
+// Package snapshot captures the definitions and rows of tables as a zstd
+// compressed tar archive, and restores them, such as before and after risky
+// changes.
+package snapshot
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/drivers/metadata"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/export"
+	"github.com/xo/usql/importer"
+	"github.com/xo/usql/progress"
+)
+
+// manifestName is the name of the manifest in the archive, its first file.
+const manifestName = "manifest.json"
+
+// Manifest is the manifest of a snapshot.
+type Manifest struct {
+	// Driver is the driver of the snapshotted database.
+	Driver string `json:"driver"`
+	// Created is the time of the snapshot.
+	Created time.Time `json:"created"`
+	// Tables are the snapshotted tables.
+	Tables []Table `json:"tables"`
+}
+
+// Table is the definition of a snapshotted table.
+type Table struct {
+	Schema     string   `json:"schema,omitempty"`
+	Name       string   `json:"name"`
+	Columns    []Column `json:"columns"`
+	PrimaryKey []string `json:"primary_key,omitempty"`
+	Indexes    []Index  `json:"indexes,omitempty"`
+	// Data is the name of the CSV file of the rows in the archive.
+	Data string `json:"data"`
+}
+
+// Column is a column of a snapshotted table.
+type Column struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	NotNull bool   `json:"not_null,omitempty"`
+	Default string `json:"default,omitempty"`
+}
+
+// Index is an index of a snapshotted table.
+type Index struct {
+	Name    string   `json:"name"`
+	Unique  bool     `json:"unique,omitempty"`
+	Columns []string `json:"columns"`
+}
+
+// String returns the schema qualified name of the table.
+func (t Table) String() string {
+	if t.Schema == "" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+// newTable returns the snapshot of the definition of t.
+func newTable(t *dump.Table, data string) Table {
+	st := Table{Schema: t.Schema, Name: t.Name, PrimaryKey: t.PrimaryKey, Data: data}
+	for _, c := range t.Columns {
+		st.Columns = append(st.Columns, Column{
+			Name:    c.Name,
+			Type:    c.DataType,
+			NotNull: c.IsNullable == metadata.NO,
+			Default: c.Default,
+		})
+	}
+	for _, index := range t.Indexes {
+		st.Indexes = append(st.Indexes, Index{Name: index.Name, Unique: index.Unique, Columns: index.Columns})
+	}
+	return st
+}
+
+// table returns the definition of the table, as dumped.
+func (t Table) table() *dump.Table {
+	dt := &dump.Table{Schema: t.Schema, Name: t.Name, PrimaryKey: t.PrimaryKey}
+	for i, c := range t.Columns {
+		col := metadata.Column{Name: c.Name, OrdinalPosition: i + 1, DataType: c.Type, Default: c.Default, IsNullable: metadata.YES}
+		if c.NotNull {
+			col.IsNullable = metadata.NO
+		}
+		dt.Columns = append(dt.Columns, col)
+	}
+	for _, index := range t.Indexes {
+		dt.Indexes = append(dt.Indexes, dump.Index{Name: index.Name, Unique: index.Unique, Columns: index.Columns})
+	}
+	return dt
+}
+
+// Result is the number of rows of a snapshotted or restored table.
+type Result struct {
+	Table string
+	Rows  int64
+}
+
+// Snapshot writes the definitions and rows of the tables, optionally schema
+// qualified, to w as a zstd compressed tar archive of a manifest and of a CSV
+// file per table. The rows of each table are held in memory while written, so
+// snapshots are meant for small tables.
+func Snapshot(ctx context.Context, w io.Writer, u *dburl.URL, db *sql.DB, tables []string) ([]Result, error) {
+	if len(tables) == 0 {
+		return nil, errors.New("no tables to snapshot")
+	}
+	dts, err := dump.ReadTables(ctx, u, db, tables)
+	if err != nil {
+		return nil, err
+	}
+	m := Manifest{Driver: u.Driver, Created: time.Now().UTC()}
+	for i, t := range dts {
+		m.Tables = append(m.Tables, newTable(t, "data/"+strconv.Itoa(i+1)+".csv"))
+	}
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return nil, err
+	}
+	tw := tar.NewWriter(zw)
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := writeFile(tw, manifestName, append(buf, '\n'), m.Created); err != nil {
+		return nil, err
+	}
+	d := dump.DialectFor(drivers.Caps(u).Dialect)
+	var res []Result
+	for i, t := range dts {
+		n, buf, err := readRows(ctx, d, db, t)
+		if err != nil {
+			return nil, fmt.Errorf("table %s: %w", m.Tables[i], err)
+		}
+		if err := writeFile(tw, m.Tables[i].Data, buf, m.Created); err != nil {
+			return nil, err
+		}
+		res = append(res, Result{Table: m.Tables[i].String(), Rows: n})
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	return res, zw.Close()
+}
+
+// readRows reads the rows of the table as CSV.
+func readRows(ctx context.Context, d dump.Dialect, db *sql.DB, t *dump.Table) (int64, []byte, error) {
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	rows, err := db.QueryContext(ctx, "SELECT "+d.QuoteIdents(names)+" FROM "+d.Qualify(t.Schema, t.Name))
+	if err != nil {
+		return 0, nil, err
+	}
+	defer rows.Close()
+	buf := new(bytes.Buffer)
+	n, err := export.CSV(buf, rows)
+	if err != nil {
+		return 0, nil, err
+	}
+	return n, buf.Bytes(), nil
+}
+
+// writeFile writes a file to the archive.
+func writeFile(tw *tar.Writer, name string, buf []byte, modTime time.Time) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0o644,
+		Size:     int64(len(buf)),
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(buf)
+	return err
+}
+
+// RestoreOptions are the restore options.
+type RestoreOptions struct {
+	// Tables are the tables to restore, optionally schema qualified. All the
+	// tables of the snapshot are restored when empty.
+	Tables []string
+	// Replace drops the existing tables before restoring them.
+	Replace bool
+	// Progress is the progress the restored rows are added to, when not nil.
+	Progress *progress.Progress
+}
+
+// Restore restores the tables of the snapshot read from r: the tables are
+// created, dropping the existing tables when replaced, their rows imported,
+// and their indexes created.
+func Restore(ctx context.Context, r io.Reader, u *dburl.URL, db *sql.DB, opts RestoreOptions) ([]Result, error) {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	tr := tar.NewReader(zr)
+	m, err := readManifest(tr)
+	if err != nil {
+		return nil, err
+	}
+	tables, err := selectTables(m.Tables, opts.Tables)
+	if err != nil {
+		return nil, err
+	}
+	d := dump.DialectFor(drivers.Caps(u).Dialect)
+	// check all the tables before changing any
+	exists := make(map[string]bool)
+	for _, t := range tables {
+		name := d.Qualify(t.Schema, t.Name)
+		rows, err := db.QueryContext(ctx, "SELECT 1 FROM "+name+" WHERE 1=0")
+		if err != nil {
+			continue
+		}
+		rows.Close()
+		if !opts.Replace {
+			return nil, fmt.Errorf("table %s exists: use --replace to drop it", t)
+		}
+		exists[t.Data] = true
+	}
+	for _, t := range tables {
+		if exists[t.Data] {
+			if _, err := db.ExecContext(ctx, "DROP TABLE "+d.Qualify(t.Schema, t.Name)); err != nil {
+				return nil, fmt.Errorf("table %s: %w", t, err)
+			}
+		}
+		if _, err := db.ExecContext(ctx, strings.TrimSuffix(d.CreateTable(t.table()), ";")); err != nil {
+			return nil, fmt.Errorf("table %s: %w", t, err)
+		}
+	}
+	byData := make(map[string]Table)
+	for _, t := range tables {
+		byData[t.Data] = t
+	}
+	var res []Result
+	for {
+		h, err := tr.Next()
+		switch {
+		case err == io.EOF:
+			return res, createIndexes(ctx, d, db, tables)
+		case err != nil:
+			return res, err
+		}
+		t, ok := byData[h.Name]
+		if !ok {
+			continue
+		}
+		n, err := importer.Import(ctx, u, db, tr, importer.Options{
+			Table:     d.Qualify(t.Schema, t.Name),
+			Delimiter: ',',
+			Quote:     '"',
+			Header:    importer.HeaderOn,
+			Progress:  opts.Progress,
+		})
+		if err != nil {
+			return res, fmt.Errorf("table %s: %w", t, err)
+		}
+		res = append(res, Result{Table: t.String(), Rows: n})
+	}
+}
+
+// readManifest reads the manifest, the first file of the archive.
+func readManifest(tr *tar.Reader) (*Manifest, error) {
+	h, err := tr.Next()
+	switch {
+	case err != nil:
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	case h.Name != manifestName:
+		return nil, fmt.Errorf("invalid snapshot: missing %s", manifestName)
+	}
+	m := new(Manifest)
+	if err := json.NewDecoder(tr).Decode(m); err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %s: %w", manifestName, err)
+	}
+	return m, nil
+}
+
+// selectTables returns the tables of the snapshot with the names, optionally
+// schema qualified, or all the tables when names is empty.
+func selectTables(tables []Table, names []string) ([]Table, error) {
+	if len(names) == 0 {
+		return tables, nil
+	}
+	var res []Table
+	for _, name := range names {
+		var found bool
+		for _, t := range tables {
+			if strings.EqualFold(name, t.Name) || strings.EqualFold(name, t.String()) {
+				res, found = append(res, t), true
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("table %s is not in the snapshot", name)
+		}
+	}
+	return res, nil
+}
+
+// createIndexes creates the indexes of the tables.
+func createIndexes(ctx context.Context, d dump.Dialect, db *sql.DB, tables []Table) error {
+	for _, t := range tables {
+		dt := t.table()
+		for _, index := range dt.Indexes {
+			if _, err := db.ExecContext(ctx, strings.TrimSuffix(d.CreateIndex(dt, index), ";")); err != nil {
+				return fmt.Errorf("table %s: index %s: %w", t, index.Name, err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,94 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/xo/dburl"
+	_ "github.com/xo/usql/drivers/sqlite3"
+)
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := context.Background()
+	name := filepath.Join(t.TempDir(), "test.db")
+	u, err := dburl.Parse("sqlite3:" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, s := range []string{
+		`CREATE TABLE users (id INTEGER PRIMARY KEY, email TEXT NOT NULL, score REAL)`,
+		`CREATE UNIQUE INDEX users_email ON users (email)`,
+		`INSERT INTO users VALUES (1, 'a@example.com', 1.5), (2, 'b@"example".com', NULL), (3, '', 0)`,
+		`CREATE TABLE other (id INTEGER)`,
+	} {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exp := readUsers(t, db)
+	buf := new(bytes.Buffer)
+	res, err := Snapshot(ctx, buf, u, db, []string{"users"})
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case len(res) != 1 || res[0].Rows != 3:
+		t.Fatalf("expected 3 rows, got: %v", res)
+	}
+	// the risky change
+	if _, err := db.Exec(`DELETE FROM users WHERE id < 3`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Restore(ctx, bytes.NewReader(buf.Bytes()), u, db, RestoreOptions{}); err == nil {
+		t.Errorf("expected an error restoring an existing table without replacing it")
+	}
+	if _, err := Restore(ctx, bytes.NewReader(buf.Bytes()), u, db, RestoreOptions{Tables: []string{"other"}}); err == nil {
+		t.Errorf("expected an error restoring a table not in the snapshot")
+	}
+	res, err = Restore(ctx, bytes.NewReader(buf.Bytes()), u, db, RestoreOptions{Replace: true})
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case len(res) != 1 || res[0].Rows != 3:
+		t.Fatalf("expected 3 rows, got: %v", res)
+	}
+	if got := readUsers(t, db); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got: %v", exp, got)
+	}
+	// the unique index is restored
+	if _, err := db.Exec(`INSERT INTO users VALUES (4, 'a@example.com', 0)`); err == nil {
+		t.Errorf("expected a unique constraint error")
+	}
+}
+
+// readUsers reads the rows of table users.
+func readUsers(t *testing.T, db *sql.DB) [][]interface{} {
+	t.Helper()
+	rows, err := db.Query(`SELECT id, email, score FROM users ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var res [][]interface{}
+	for rows.Next() {
+		var id int64
+		var email string
+		var score sql.NullFloat64
+		if err := rows.Scan(&id, &email, &score); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, []interface{}{id, email, score})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
@@ -0,0 +1,284 @@
+package sqlfmt
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Kind is the kind of a token.
+type Kind int
+
+// Token kinds.
+const (
+	// Word is a keyword, an identifier or a placeholder.
+	Word Kind = iota
+	// Quoted is a quoted identifier.
+	Quoted
+	// String is a string literal.
+	String
+	// Number is a numeric literal.
+	Number
+	// Op is an operator.
+	Op
+	// Comma, Open, Close, Dot and Semicolon are punctuation.
+	Comma
+	Open
+	Close
+	Dot
+	Semicolon
+	// LineComment and BlockComment are comments.
+	LineComment
+	BlockComment
+)
+
+// Token is a token of a statement.
+type Token struct {
+	Kind Kind
+	Text string
+	// Pos is the offset of the token in the lexed string, in runes.
+	Pos int
+}
+
+// dialect are the lexical rules of a SQL dialect.
+type dialect struct {
+	// backticks and brackets quote identifiers.
+	backticks, brackets bool
+	// hashComments are comments starting with #.
+	hashComments bool
+	// dollarQuotes are strings quoted with $$ or $tag$.
+	dollarQuotes bool
+	// backslashEscapes are escapes in strings.
+	backslashEscapes bool
+	// keywords are the keywords of the dialect, in addition to the standard
+	// ones.
+	keywords []string
+}
+
+// dialects are the dialects, by driver name.
+var dialects = map[string]dialect{
+	"postgres": {
+		dollarQuotes: true,
+		keywords:     []string{"ANALYZE", "ILIKE", "SIMILAR", "VACUUM"},
+	},
+	"mysql": {
+		backticks:        true,
+		hashComments:     true,
+		backslashEscapes: true,
+		keywords:         []string{"DUPLICATE", "IGNORE", "REGEXP", "RLIKE", "STRAIGHT_JOIN"},
+	},
+	"sqlite3": {
+		backticks: true,
+		brackets:  true,
+		keywords:  []string{"AUTOINCREMENT", "GLOB", "PRAGMA"},
+	},
+	"sqlserver": {
+		brackets: true,
+		keywords: []string{"APPLY", "MATCHED", "MERGE", "OUTPUT", "PIVOT", "TOP"},
+	},
+	"oracle": {
+		keywords: []string{"CONNECT", "MATCHED", "MERGE", "MINUS", "PRIOR", "START"},
+	},
+	"clickhouse": {
+		backticks: true,
+		keywords:  []string{"ENGINE", "FINAL", "PREWHERE", "SAMPLE", "SETTINGS"},
+	},
+	"bigquery": {
+		backticks: true,
+		keywords:  []string{"QUALIFY", "UNNEST"},
+	},
+	"snowflake": {
+		keywords: []string{"ILIKE", "QUALIFY"},
+	},
+	"duckdb": {
+		keywords: []string{"ILIKE", "QUALIFY"},
+	},
+	"trino": {
+		keywords: []string{"UNNEST"},
+	},
+}
+
+// keywordSet returns the standard and dialect keywords.
+func (d dialect) keywordSet() map[string]bool {
+	keywords := make(map[string]bool, len(stdKeywords)+len(d.keywords))
+	for _, k := range stdKeywords {
+		keywords[k] = true
+	}
+	for _, k := range d.keywords {
+		keywords[k] = true
+	}
+	return keywords
+}
+
+// lex splits s into tokens.
+func lex(s string, d dialect) ([]Token, error) {
+	r := []rune(s)
+	var toks []Token
+	for i := 0; i < len(r); {
+		c, next := r[i], rune(0)
+		if i+1 < len(r) {
+			next = r[i+1]
+		}
+		start := i
+		var k Kind
+		switch {
+		case unicode.IsSpace(c):
+			i++
+			continue
+		case c == '-' && next == '-', c == '#' && d.hashComments:
+			for i < len(r) && r[i] != '\n' {
+				i++
+			}
+			k = LineComment
+		case c == '/' && next == '*':
+			end := index(r, i+2, "*/")
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated comment")
+			}
+			i, k = end+2, BlockComment
+		case c == '\'':
+			var err error
+			if i, err = quoted(r, i, '\'', d.backslashEscapes); err != nil {
+				return nil, err
+			}
+			k = String
+		case strings.ContainsRune("EeNnBbXx", c) && next == '\'':
+			var err error
+			if i, err = quoted(r, i+1, '\'', d.backslashEscapes || c == 'E' || c == 'e'); err != nil {
+				return nil, err
+			}
+			k = String
+		case c == '"', c == '`' && d.backticks:
+			var err error
+			if i, err = quoted(r, i, c, false); err != nil {
+				return nil, err
+			}
+			k = Quoted
+		case c == '[' && d.brackets:
+			var err error
+			if i, err = quoted(r, i, ']', false); err != nil {
+				return nil, err
+			}
+			k = Quoted
+		case c == '$' && d.dollarQuotes && (next == '$' || isIdentStart(next)):
+			tag := dollarTag(r, i)
+			if tag == "" {
+				i, k = ident(r, i+1), Word
+				break
+			}
+			n := len([]rune(tag))
+			end := index(r, i+n, tag)
+			if end == -1 {
+				return nil, fmt.Errorf("unterminated dollar-quoted string")
+			}
+			i, k = end+n, String
+		case unicode.IsDigit(c), c == '.' && unicode.IsDigit(next):
+			i = number(r, i)
+			k = Number
+		case isIdentStart(c):
+			i, k = ident(r, i), Word
+		case (c == '$' || c == '@' || c == ':') && (unicode.IsDigit(next) || isIdentStart(next)):
+			// placeholders and variables
+			i, k = ident(r, i+1), Word
+		case c == ',':
+			i, k = i+1, Comma
+		case c == '(':
+			i, k = i+1, Open
+		case c == ')':
+			i, k = i+1, Close
+		case c == '.':
+			i, k = i+1, Dot
+		case c == ';':
+			i, k = i+1, Semicolon
+		case strings.ContainsRune(opChars, c):
+			for i++; i < len(r) && strings.ContainsRune(opChars, r[i]) && !(r[i] == '-' && i+1 < len(r) && r[i+1] == '-'); i++ {
+			}
+			k = Op
+		default:
+			i, k = i+1, Op
+		}
+		toks = append(toks, Token{Kind: k, Text: string(r[start:i]), Pos: start})
+	}
+	return toks, nil
+}
+
+// opChars are the characters of operators.
+const opChars = "+-*/<>=~!@%^&|:?"
+
+// quoted returns the end of the quoted string starting at i, where a doubled
+// end quote is an escaped quote.
+func quoted(r []rune, i int, end rune, backslash bool) (int, error) {
+	for i++; i < len(r); i++ {
+		switch {
+		case backslash && r[i] == '\\':
+			i++
+		case r[i] == end && i+1 < len(r) && r[i+1] == end:
+			i++
+		case r[i] == end:
+			return i + 1, nil
+		}
+	}
+	return 0, fmt.Errorf("unterminated quoted string")
+}
+
+// dollarTag returns the tag of the dollar-quoted string starting at i ($$ or
+// $tag$), or empty when not a dollar-quoted string.
+func dollarTag(r []rune, i int) string {
+	for j := i + 1; j < len(r); j++ {
+		switch {
+		case r[j] == '$':
+			return string(r[i : j+1])
+		case !isIdentStart(r[j]) && !(j > i+1 && unicode.IsDigit(r[j])):
+			return ""
+		}
+	}
+	return ""
+}
+
+// isIdentStart returns true when c starts an identifier.
+func isIdentStart(c rune) bool {
+	return c == '_' || unicode.IsLetter(c)
+}
+
+// ident returns the end of the identifier starting at i.
+func ident(r []rune, i int) int {
+	for ; i < len(r) && (r[i] == '_' || r[i] == '$' || unicode.IsLetter(r[i]) || unicode.IsDigit(r[i])); i++ {
+	}
+	return i
+}
+
+// number returns the end of the number starting at i.
+func number(r []rune, i int) int {
+	if r[i] == '0' && i+1 < len(r) && (r[i+1] == 'x' || r[i+1] == 'X') {
+		for i += 2; i < len(r) && (isHex(r[i]) || r[i] == '_'); i++ {
+		}
+		return i
+	}
+	for ; i < len(r); i++ {
+		switch c := r[i]; {
+		case unicode.IsDigit(c), c == '.', c == '_':
+		case (c == 'e' || c == 'E') && i+1 < len(r) && unicode.IsDigit(r[i+1]):
+		case (c == 'e' || c == 'E') && i+2 < len(r) && (r[i+1] == '-' || r[i+1] == '+') && unicode.IsDigit(r[i+2]):
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// index returns the index of s in r from i, or -1.
+func index(r []rune, i int, s string) int {
+	p := []rune(s)
+	for ; i+len(p) <= len(r); i++ {
+		if string(r[i:i+len(p)]) == s {
+			return i
+		}
+	}
+	return -1
+}
+
+// isHex returns true when c is a hexadecimal digit.
+func isHex(c rune) bool {
+	return unicode.IsDigit(c) || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
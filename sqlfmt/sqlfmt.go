@@ -0,0 +1,465 @@
+// Package sqlfmt formats SQL statements, breaking their clauses and lists on
+// indented lines and normalizing the case of their keywords.
+package sqlfmt
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Case is the case of keywords.
+type Case int
+
+// Keyword cases.
+const (
+	// Upper formats keywords in upper case.
+	Upper Case = iota
+	// Lower formats keywords in lower case.
+	Lower
+	// Preserve keeps the case of keywords.
+	Preserve
+)
+
+// ParseCase parses a keyword case (upper, lower or preserve).
+func ParseCase(s string) (Case, error) {
+	switch strings.ToLower(s) {
+	case "", "upper":
+		return Upper, nil
+	case "lower":
+		return Lower, nil
+	case "preserve":
+		return Preserve, nil
+	}
+	return Upper, fmt.Errorf("invalid keyword case %q", s)
+}
+
+// DefaultIndent is the default number of spaces of an indentation level.
+const DefaultIndent = 2
+
+// Options are the formatting options.
+type Options struct {
+	// Dialect is the SQL dialect of the statements, as a driver name (ie,
+	// postgres, mysql, sqlserver), for their quotes, comments and keywords.
+	Dialect string
+	// Case is the case of keywords.
+	Case Case
+	// Indent is the number of spaces of an indentation level, or 0 for tabs.
+	Indent int
+}
+
+// Format formats the SQL statements of s, separated by blank lines.
+func Format(s string, opts Options) (string, error) {
+	d := dialects[opts.Dialect]
+	toks, err := lex(s, d)
+	if err != nil {
+		return "", err
+	}
+	keywords := d.keywordSet()
+	indent := "\t"
+	if opts.Indent > 0 {
+		indent = strings.Repeat(" ", opts.Indent)
+	}
+	var stmts []string
+	for _, stmt := range split(toks) {
+		f := &formatter{toks: stmt, keywords: keywords, kcase: opts.Case, indent: indent}
+		stmts = append(stmts, f.format())
+	}
+	if len(stmts) == 0 {
+		return "", nil
+	}
+	return strings.Join(stmts, "\n\n") + "\n", nil
+}
+
+// Statements splits the SQL statements of s into their tokens, ending with
+// their semicolon, using the lexical rules of dialect (a driver name).
+func Statements(s, dialect string) ([][]Token, error) {
+	toks, err := lex(s, dialects[dialect])
+	if err != nil {
+		return nil, err
+	}
+	return split(toks), nil
+}
+
+// Keywords returns the set of keywords of dialect (a driver name), in upper
+// case.
+func Keywords(dialect string) map[string]bool {
+	return dialects[dialect].keywordSet()
+}
+
+// split splits tokens into statements, ending with their semicolon.
+func split(toks []Token) [][]Token {
+	var stmts [][]Token
+	var depth, start int
+	for i, t := range toks {
+		switch t.Kind {
+		case Open:
+			depth++
+		case Close:
+			depth--
+		case Semicolon:
+			if depth <= 0 {
+				stmts, start, depth = append(stmts, toks[start:i+1]), i+1, 0
+			}
+		}
+	}
+	if start < len(toks) {
+		stmts = append(stmts, toks[start:])
+	}
+	return stmts
+}
+
+// clause types.
+const (
+	// clauseBlock clauses are followed by their items on indented lines.
+	clauseBlock = iota
+	// clauseLine clauses start a line, followed by their items.
+	clauseLine
+	// clauseJoin clauses start an indented line of the FROM clause.
+	clauseJoin
+	// clauseInline are phrases containing clause keywords, kept inline.
+	clauseInline
+)
+
+// clauses are the keyword phrases of clauses, by type, longest first.
+var clauses = []struct {
+	words []string
+	typ   int
+}{
+	{[]string{"IS", "NOT", "DISTINCT", "FROM"}, clauseInline},
+	{[]string{"IS", "DISTINCT", "FROM"}, clauseInline},
+	{[]string{"ON", "DUPLICATE", "KEY", "UPDATE"}, clauseBlock},
+	{[]string{"DO", "UPDATE", "SET"}, clauseBlock},
+	{[]string{"LEFT", "OUTER", "JOIN"}, clauseJoin},
+	{[]string{"RIGHT", "OUTER", "JOIN"}, clauseJoin},
+	{[]string{"FULL", "OUTER", "JOIN"}, clauseJoin},
+	{[]string{"WITH", "RECURSIVE"}, clauseBlock},
+	{[]string{"SELECT", "DISTINCT"}, clauseBlock},
+	{[]string{"GROUP", "BY"}, clauseBlock},
+	{[]string{"ORDER", "BY"}, clauseBlock},
+	{[]string{"INSERT", "INTO"}, clauseLine},
+	{[]string{"REPLACE", "INTO"}, clauseLine},
+	{[]string{"DELETE", "FROM"}, clauseLine},
+	{[]string{"UNION", "ALL"}, clauseLine},
+	{[]string{"UNION", "DISTINCT"}, clauseLine},
+	{[]string{"ON", "CONFLICT"}, clauseLine},
+	{[]string{"FOR", "UPDATE"}, clauseLine},
+	{[]string{"INNER", "JOIN"}, clauseJoin},
+	{[]string{"CROSS", "JOIN"}, clauseJoin},
+	{[]string{"NATURAL", "JOIN"}, clauseJoin},
+	{[]string{"LEFT", "JOIN"}, clauseJoin},
+	{[]string{"RIGHT", "JOIN"}, clauseJoin},
+	{[]string{"FULL", "JOIN"}, clauseJoin},
+	{[]string{"CROSS", "APPLY"}, clauseJoin},
+	{[]string{"OUTER", "APPLY"}, clauseJoin},
+	{[]string{"WITH"}, clauseBlock},
+	{[]string{"SELECT"}, clauseBlock},
+	{[]string{"FROM"}, clauseBlock},
+	{[]string{"WHERE"}, clauseBlock},
+	{[]string{"PREWHERE"}, clauseBlock},
+	{[]string{"HAVING"}, clauseBlock},
+	{[]string{"QUALIFY"}, clauseBlock},
+	{[]string{"WINDOW"}, clauseBlock},
+	{[]string{"SET"}, clauseBlock},
+	{[]string{"VALUES"}, clauseBlock},
+	{[]string{"RETURNING"}, clauseBlock},
+	{[]string{"INSERT"}, clauseLine},
+	{[]string{"UPDATE"}, clauseLine},
+	{[]string{"DELETE"}, clauseLine},
+	{[]string{"UNION"}, clauseLine},
+	{[]string{"INTERSECT"}, clauseLine},
+	{[]string{"EXCEPT"}, clauseLine},
+	{[]string{"MINUS"}, clauseLine},
+	{[]string{"LIMIT"}, clauseLine},
+	{[]string{"OFFSET"}, clauseLine},
+	{[]string{"FETCH"}, clauseLine},
+	{[]string{"JOIN"}, clauseJoin},
+}
+
+// breakItems are the clauses whose items separated by commas are on their
+// own lines, and breakConds the clauses whose AND and OR conditions are.
+var (
+	breakItems = map[string]bool{
+		"WITH": true, "WITH RECURSIVE": true, "SELECT": true, "SELECT DISTINCT": true,
+		"FROM": true, "GROUP BY": true, "ORDER BY": true, "WINDOW": true, "SET": true,
+		"VALUES": true, "RETURNING": true, "ON DUPLICATE KEY UPDATE": true, "DO UPDATE SET": true,
+	}
+	breakConds = map[string]bool{
+		"FROM": true, "WHERE": true, "PREWHERE": true, "HAVING": true, "QUALIFY": true,
+	}
+)
+
+// funcKeywords are the keywords called as functions.
+var funcKeywords = map[string]bool{
+	"CAST": true, "EXTRACT": true, "SUBSTRING": true, "TRIM": true,
+	"POSITION": true, "LEFT": true, "RIGHT": true, "REPLACE": true,
+}
+
+// stdKeywords are the standard keywords.
+var stdKeywords = []string{
+	"ADD", "ALL", "ALTER", "AND", "ANY", "AS", "ASC", "BETWEEN", "BY",
+	"CASCADE", "CASE", "CAST", "CHECK", "COLUMN", "CONFLICT", "CONSTRAINT",
+	"CREATE", "CROSS", "CURRENT_DATE", "CURRENT_TIME", "CURRENT_TIMESTAMP",
+	"DEFAULT", "DELETE", "DESC", "DISTINCT", "DO", "DROP", "ELSE", "END",
+	"ESCAPE", "EXCEPT", "EXISTS", "EXPLAIN", "EXTRACT", "FALSE", "FETCH",
+	"FILTER", "FIRST", "FOLLOWING", "FOR", "FOREIGN", "FROM", "FULL", "GROUP",
+	"HAVING", "IF", "IN", "INDEX", "INNER", "INSERT", "INTERSECT", "INTERVAL",
+	"INTO", "IS", "JOIN", "KEY", "LAST", "LATERAL", "LEFT", "LIKE", "LIMIT",
+	"NATURAL", "NEXT", "NOT", "NOTHING", "NULL", "NULLS", "OFFSET", "ON",
+	"ONLY", "OR", "ORDER", "OUTER", "OVER", "PARTITION", "POSITION",
+	"PRECEDING", "PRIMARY", "RANGE", "RECURSIVE", "REFERENCES", "REPLACE",
+	"RETURNING", "RIGHT", "ROW", "ROWS", "SCHEMA", "SELECT", "SET", "SOME",
+	"SUBSTRING", "TABLE", "THEN", "TO", "TRIM", "TRUE", "TRUNCATE",
+	"UNBOUNDED", "UNION", "UNIQUE", "UPDATE", "USING", "VALUES", "VIEW",
+	"WHEN", "WHERE", "WINDOW", "WITH",
+}
+
+// frame types.
+const (
+	// frameInline are parentheses kept on a line, such as function calls.
+	frameInline = iota
+	// frameQuery are the parentheses of a subquery.
+	frameQuery
+	// frameList are the parentheses of a list of items on their own lines,
+	// such as the columns of a created table.
+	frameList
+)
+
+// frame is an open parenthesis.
+type frame struct {
+	typ int
+	// level and clause are the level and clause of the enclosing query.
+	level  int
+	clause string
+}
+
+// formatter formats a statement.
+type formatter struct {
+	toks     []Token
+	keywords map[string]bool
+	kcase    Case
+	indent   string
+	b        []byte
+	// level is the indentation level of the clauses of the current query,
+	// and clause its current clause.
+	level  int
+	clause string
+	stack  []frame
+	// prev is the previously written token, and unary is set when it's a
+	// unary operator.
+	prev  Token
+	unary bool
+	// between is set in a BETWEEN condition, until its AND, and cases is
+	// the depth of CASE expressions.
+	between bool
+	cases   int
+	// table is set after the TABLE keyword of a CREATE statement.
+	table bool
+}
+
+// format formats the statement.
+func (f *formatter) format() string {
+	for i := 0; i < len(f.toks); {
+		i += f.token(i)
+	}
+	return strings.TrimRight(string(f.b), " \t\n")
+}
+
+// token formats the token(s) at i, returning the number of formatted tokens.
+func (f *formatter) token(i int) int {
+	t := f.toks[i]
+	query := len(f.stack) == 0 || f.stack[len(f.stack)-1].typ != frameInline
+	switch t.Kind {
+	case Word:
+		if !f.keyword(t) {
+			break
+		}
+		if n, typ := f.phrase(i); n != 0 && (query || typ == clauseInline) {
+			f.clauseWords(i, n, typ)
+			return n
+		}
+		switch w := strings.ToUpper(t.Text); {
+		case w == "CASE":
+			f.cases++
+		case w == "END" && f.cases > 0:
+			f.cases--
+		case w == "BETWEEN":
+			f.between = true
+		case w == "TABLE" && strings.EqualFold(f.toks[0].Text, "CREATE"):
+			f.table = true
+		case w == "AND" && f.between:
+			f.between = false
+		case (w == "AND" || w == "OR") && query && f.cases == 0 && breakConds[f.clause]:
+			f.newline(f.level + 1)
+		}
+	case Comma:
+		top := len(f.stack) != 0 && f.stack[len(f.stack)-1].typ == frameList
+		if (query && f.cases == 0 && breakItems[f.clause]) || top {
+			f.write(t)
+			f.newline(f.itemLevel())
+			return 1
+		}
+	case Open:
+		fr := frame{typ: frameInline, level: f.level, clause: f.clause}
+		switch {
+		case i+1 < len(f.toks) && f.keyword(f.toks[i+1]) && (strings.EqualFold(f.toks[i+1].Text, "SELECT") || strings.EqualFold(f.toks[i+1].Text, "WITH")):
+			fr.typ = frameQuery
+		case f.table && len(f.stack) == 0:
+			fr.typ = frameList
+		}
+		f.write(t)
+		f.table = false
+		f.stack = append(f.stack, fr)
+		switch fr.typ {
+		case frameQuery:
+			f.level, f.clause = f.itemLevel()+1, ""
+		case frameList:
+			f.level, f.clause = f.level+1, ""
+			f.newline(f.level)
+		}
+		return 1
+	case Close:
+		if len(f.stack) == 0 {
+			break
+		}
+		fr := f.stack[len(f.stack)-1]
+		f.stack = f.stack[:len(f.stack)-1]
+		f.level, f.clause = fr.level, fr.clause
+		switch fr.typ {
+		case frameQuery:
+			f.newline(f.itemLevel())
+		case frameList:
+			f.newline(f.level)
+		}
+	case LineComment:
+		f.write(t)
+		f.newline(f.itemLevel())
+		return 1
+	}
+	f.write(t)
+	return 1
+}
+
+// phrase returns the number of tokens and the type of the clause phrase at
+// i, if any.
+func (f *formatter) phrase(i int) (int, int) {
+loop:
+	for _, c := range clauses {
+		if i+len(c.words) > len(f.toks) {
+			continue
+		}
+		for j, w := range c.words {
+			if t := f.toks[i+j]; t.Kind != Word || !strings.EqualFold(t.Text, w) {
+				continue loop
+			}
+		}
+		if !f.keywords[c.words[0]] {
+			continue
+		}
+		return len(c.words), c.typ
+	}
+	return 0, 0
+}
+
+// clauseWords formats the n tokens of a clause phrase at i.
+func (f *formatter) clauseWords(i, n, typ int) {
+	var words []string
+	for _, t := range f.toks[i : i+n] {
+		words = append(words, strings.ToUpper(t.Text))
+	}
+	clause := strings.Join(words, " ")
+	// SET statements are not clauses
+	if clause == "SET" && i == 0 {
+		typ = clauseLine
+	}
+	switch typ {
+	case clauseBlock, clauseLine:
+		f.newline(f.level)
+		f.clause = clause
+	case clauseJoin:
+		f.newline(f.level + 1)
+	}
+	for _, t := range f.toks[i : i+n] {
+		f.write(t)
+	}
+	if typ == clauseBlock {
+		f.newline(f.level + 1)
+	}
+}
+
+// itemLevel returns the indentation level of the items of the current clause.
+func (f *formatter) itemLevel() int {
+	if f.clause != "" {
+		return f.level + 1
+	}
+	return f.level
+}
+
+// keyword returns true when t is a keyword.
+func (f *formatter) keyword(t Token) bool {
+	return t.Kind == Word && f.keywords[strings.ToUpper(t.Text)]
+}
+
+// newline starts a new line indented by level, unless at the start of the
+// statement.
+func (f *formatter) newline(level int) {
+	f.b = []byte(strings.TrimRight(string(f.b), " \t"))
+	if len(f.b) == 0 {
+		return
+	}
+	if f.b[len(f.b)-1] != '\n' {
+		f.b = append(f.b, '\n')
+	}
+	f.b = append(f.b, strings.Repeat(f.indent, level)...)
+}
+
+// write writes a token, preceded by a space when needed.
+func (f *formatter) write(t Token) {
+	if f.space(t) {
+		f.b = append(f.b, ' ')
+	}
+	s := t.Text
+	if f.keyword(t) {
+		switch f.kcase {
+		case Upper:
+			s = strings.ToUpper(s)
+		case Lower:
+			s = strings.ToLower(s)
+		}
+	}
+	f.b = append(f.b, s...)
+	prev := f.prev
+	f.prev = t
+	// unary operators follow operators, keywords, commas and opening
+	// parentheses
+	f.unary = t.Kind == Op && (t.Text == "-" || t.Text == "+") &&
+		(prev.Kind == Op || prev.Kind == Comma || prev.Kind == Open || f.keyword(prev) || prev.Text == "")
+}
+
+// space returns true when t is preceded by a space.
+func (f *formatter) space(t Token) bool {
+	switch {
+	case len(f.b) == 0, f.b[len(f.b)-1] == '\n', f.b[len(f.b)-1] == ' ', f.b[len(f.b)-1] == '\t':
+		return false
+	case t.Kind == Comma, t.Kind == Close, t.Kind == Dot, t.Kind == Semicolon:
+		return false
+	case f.prev.Kind == Open, f.prev.Kind == Dot, f.unary:
+		return false
+	case t.Text == "::", f.prev.Text == "::":
+		return false
+	case t.Text == "]", f.prev.Text == "[":
+		// array subscripts and constructors
+		return false
+	case t.Text == "[":
+		return f.prev.Kind == Op || f.prev.Kind == Comma
+	case t.Kind == Open:
+		switch {
+		case f.keyword(f.prev):
+			return !funcKeywords[strings.ToUpper(f.prev.Text)]
+		case f.prev.Kind == Word || f.prev.Kind == Quoted:
+			// the columns of created tables and inserted rows
+			return f.table || strings.HasPrefix(f.clause, "INSERT") || strings.HasPrefix(f.clause, "REPLACE")
+		}
+		return f.prev.Kind != Close
+	}
+	return true
+}
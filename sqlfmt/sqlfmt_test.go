@@ -0,0 +1,112 @@
+package sqlfmt
+
+import (
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		s    string
+		opts Options
+		exp  string
+	}{
+		{
+			`select a, b from t where a = 1 and b between 1 and 2 order by a`,
+			Options{Indent: 2},
+			"SELECT\n  a,\n  b\nFROM\n  t\nWHERE\n  a = 1\n  AND b BETWEEN 1 AND 2\nORDER BY\n  a\n",
+		},
+		{
+			`SELECT count(*) FROM t WHERE id IN (SELECT id FROM u)`,
+			Options{Indent: 4, Case: Lower},
+			"select\n    count(*)\nfrom\n    t\nwhere\n    id in (\n        select\n            id\n        from\n            u\n    )\n",
+		},
+		{
+			`select a from t left join u on u.id = t.id; delete from t where x = -1`,
+			Options{Case: Preserve},
+			"select\n\ta\nfrom\n\tt\n\tleft join u on u.id = t.id;\n\ndelete from t\nwhere\n\tx = -1\n",
+		},
+		{
+			`insert into t(a, b) values (1, 'a;b'), (2, $$c;d$$)`,
+			Options{Dialect: "postgres", Indent: 2},
+			"INSERT INTO t (a, b)\nVALUES\n  (1, 'a;b'),\n  (2, $$c;d$$)\n",
+		},
+		{
+			`create table t (id int primary key, price numeric(10,2)) -- prices`,
+			Options{Indent: 2},
+			"CREATE TABLE t (\n  id int PRIMARY KEY,\n  price numeric(10, 2)\n) -- prices\n",
+		},
+		{
+			"select `a` from t # comment\nwhere b = 'it\\'s'",
+			Options{Dialect: "mysql", Indent: 2},
+			"SELECT\n  `a`\nFROM\n  t # comment\nWHERE\n  b = 'it\\'s'\n",
+		},
+		{
+			`select a::text, arr[1], case when a is distinct from b then 1 end from t`,
+			Options{Dialect: "postgres", Indent: 2},
+			"SELECT\n  a::text,\n  arr[1],\n  CASE WHEN a IS DISTINCT FROM b THEN 1 END\nFROM\n  t\n",
+		},
+		{``, Options{}, ""},
+	}
+	for i, test := range tests {
+		s, err := Format(test.s, test.opts)
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if s != test.exp {
+			t.Errorf("test %d expected:\n%s\ngot:\n%s", i, test.exp, s)
+		}
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []string{
+		`select 'a`,
+		`select "a`,
+		`select /* a`,
+		`select $tag$ a`,
+	}
+	for i, s := range tests {
+		if _, err := Format(s, Options{Dialect: "postgres"}); err == nil {
+			t.Errorf("test %d expected an error", i)
+		}
+	}
+}
+
+func TestParseCase(t *testing.T) {
+	tests := []struct {
+		s    string
+		exp  Case
+		fail bool
+	}{
+		{"", Upper, false},
+		{"upper", Upper, false},
+		{"LOWER", Lower, false},
+		{"preserve", Preserve, false},
+		{"title", Upper, true},
+	}
+	for i, test := range tests {
+		c, err := ParseCase(test.s)
+		switch {
+		case test.fail && err == nil:
+			t.Errorf("test %d expected an error", i)
+		case !test.fail && c != test.exp:
+			t.Errorf("test %d expected %d, got: %d", i, test.exp, c)
+		}
+	}
+}
+
+func TestStatements(t *testing.T) {
+	stmts, err := Statements("select 'a;b' from t;\n  delete from u", "postgres")
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("expected 2 statements, got: %d", len(stmts))
+	}
+	if tok := stmts[0][1]; tok.Kind != String || tok.Text != "'a;b'" || tok.Pos != 7 {
+		t.Errorf("expected string 'a;b' at 7, got: %+v", tok)
+	}
+	if tok := stmts[1][0]; tok.Kind != Word || tok.Text != "delete" || tok.Pos != 23 {
+		t.Errorf("expected word delete at 23, got: %+v", tok)
+	}
+}
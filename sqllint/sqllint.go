@@ -0,0 +1,521 @@
+// Package sqllint statically checks SQL statements for common mistakes, such
+// as updates and deletes without a WHERE clause, and predicates preventing the
+// use of indexes.
+package sqllint
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/xo/usql/sqlfmt"
+)
+
+// Severity is the severity of a finding.
+type Severity string
+
+// Severities.
+const (
+	Error   Severity = "error"
+	Warning Severity = "warning"
+)
+
+// Rule names.
+const (
+	// MissingWhere are UPDATE and DELETE statements without a WHERE clause.
+	MissingWhere = "missing-where"
+	// AmbiguousColumn are unqualified columns of queries reading from several
+	// tables.
+	AmbiguousColumn = "ambiguous-column"
+	// NonSargable are conditions applying functions or operators to columns,
+	// or LIKE patterns starting with a wildcard.
+	NonSargable = "non-sargable"
+	// ImplicitCast are columns compared with numeric string literals.
+	ImplicitCast = "implicit-cast"
+)
+
+// Rules are the severities of the rules, by name.
+var Rules = map[string]Severity{
+	MissingWhere:    Error,
+	AmbiguousColumn: Warning,
+	NonSargable:     Warning,
+	ImplicitCast:    Warning,
+}
+
+// Finding is a problem found in a statement.
+type Finding struct {
+	// File is the linted file, set by the caller.
+	File string `json:"file,omitempty"`
+	// Line and Column are the position of the problem, starting at 1.
+	Line     int      `json:"line"`
+	Column   int      `json:"column"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	pos      int
+}
+
+// String satisfies the fmt.Stringer interface.
+func (f Finding) String() string {
+	return fmt.Sprintf("%s:%d:%d: %s: %s [%s]", f.File, f.Line, f.Column, f.Severity, f.Message, f.Rule)
+}
+
+// Options are the lint options.
+type Options struct {
+	// Dialect is the SQL dialect of the statements, as a driver name (ie,
+	// postgres, mysql, sqlserver).
+	Dialect string
+	// Disable are the names of the rules not to check.
+	Disable []string
+}
+
+// Lint checks the SQL statements of s, returning the findings ordered by
+// position.
+func Lint(s string, opts Options) ([]Finding, error) {
+	l := &linter{
+		dialect:  opts.Dialect,
+		keywords: sqlfmt.Keywords(opts.Dialect),
+		disabled: make(map[string]bool),
+	}
+	for _, rule := range opts.Disable {
+		if _, ok := Rules[rule]; !ok {
+			return nil, fmt.Errorf("unknown rule %q", rule)
+		}
+		l.disabled[rule] = true
+	}
+	stmts, err := sqlfmt.Statements(s, opts.Dialect)
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range stmts {
+		var toks []sqlfmt.Token
+		for _, t := range stmt {
+			switch t.Kind {
+			case sqlfmt.LineComment, sqlfmt.BlockComment, sqlfmt.Semicolon:
+			default:
+				toks = append(toks, t)
+			}
+		}
+		l.query(toks)
+	}
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		return l.findings[i].pos < l.findings[j].pos
+	})
+	// positions
+	line, col, pos := 1, 1, 0
+	for i, r := range []rune(s) {
+		for ; pos < len(l.findings) && l.findings[pos].pos == i; pos++ {
+			l.findings[pos].Line, l.findings[pos].Column = line, col
+		}
+		if col++; r == '\n' {
+			line, col = line+1, 1
+		}
+	}
+	return l.findings, nil
+}
+
+// linter checks statements.
+type linter struct {
+	dialect  string
+	keywords map[string]bool
+	disabled map[string]bool
+	findings []Finding
+}
+
+// add adds a finding for rule at t.
+func (l *linter) add(rule string, t sqlfmt.Token, format string, v ...interface{}) {
+	if l.disabled[rule] {
+		return
+	}
+	l.findings = append(l.findings, Finding{
+		Rule:     rule,
+		Severity: Rules[rule],
+		Message:  fmt.Sprintf(format, v...),
+		pos:      t.Pos,
+	})
+}
+
+// query checks the tokens of a statement or subquery, and of its subqueries.
+func (l *linter) query(toks []sqlfmt.Token) {
+	// subqueries are checked on their own, and left empty
+	var flat []sqlfmt.Token
+	for i := 0; i < len(toks); i++ {
+		flat = append(flat, toks[i])
+		if toks[i].Kind == sqlfmt.Open && i+1 < len(toks) && subqueryWords[l.word(toks[i+1])] {
+			end := closing(toks, i)
+			l.query(toks[i+1 : end])
+			i = end - 1
+		}
+	}
+	l.missingWhere(flat)
+	start := 0
+	for i, t := range flat {
+		if setOps[l.word(t)] {
+			l.clauses(flat[start:i])
+			start = i + 1
+		}
+	}
+	l.clauses(flat[start:])
+}
+
+// missingWhere checks that UPDATE and DELETE statements have a WHERE clause.
+func (l *linter) missingWhere(toks []sqlfmt.Token) {
+	verb := -1
+	for i, t := range toks {
+		if w := l.word(t); w != "" && !withWords[w] {
+			verb = i
+			break
+		}
+	}
+	if verb == -1 {
+		return
+	}
+	w := l.word(toks[verb])
+	if w != "UPDATE" && w != "DELETE" {
+		return
+	}
+	depth := 0
+	for _, t := range toks[verb:] {
+		switch {
+		case t.Kind == sqlfmt.Open:
+			depth++
+		case t.Kind == sqlfmt.Close:
+			depth--
+		case depth == 0 && l.word(t) == "WHERE":
+			return
+		}
+	}
+	l.add(MissingWhere, toks[verb], "%s without a WHERE clause changes all rows of the table", w)
+}
+
+// clauses checks the clauses of a query.
+func (l *linter) clauses(toks []sqlfmt.Token) {
+	var clause string
+	var depth, sources int
+	var refs []sqlfmt.Token
+	var conds [][]sqlfmt.Token
+	aliases, using := make(map[string]bool), make(map[string]bool)
+	for i, t := range toks {
+		switch t.Kind {
+		case sqlfmt.Open:
+			depth++
+		case sqlfmt.Close:
+			depth--
+		case sqlfmt.Comma:
+			if depth == 0 && clause == "FROM" {
+				sources++
+			}
+		}
+		prev, next := l.at(toks, i-1), l.at(toks, i+1)
+		if w := l.word(t); w != "" && depth == 0 {
+			c := clause
+			switch w {
+			case "SELECT", "WHERE", "HAVING", "QUALIFY", "SET", "VALUES", "RETURNING", "INTO", "LIMIT", "OFFSET", "WINDOW":
+				c = w
+			case "FROM":
+				if prev == "DISTINCT" {
+					// IS [NOT] DISTINCT FROM
+					break
+				}
+				c = "FROM"
+				sources++
+			case "UPDATE":
+				if prev == "DO" || prev == "KEY" || prev == "FOR" || prev == "ON" {
+					c = "CONFLICT"
+					break
+				}
+				c = "UPDATE"
+				sources++
+			case "JOIN", "APPLY":
+				c = "JOIN"
+				sources++
+			case "ON":
+				if clause == "JOIN" {
+					c = "ON"
+				} else if next == "CONFLICT" || next == "DUPLICATE" {
+					c = "CONFLICT"
+				}
+			case "USING":
+				if clause == "JOIN" {
+					c = "USING"
+				} else {
+					c = "FROM"
+					sources++
+				}
+			case "GROUP", "ORDER":
+				if next == "BY" {
+					c = w + " BY"
+				}
+			}
+			if c != clause {
+				clause = c
+				if clause == "WHERE" || clause == "ON" {
+					conds = append(conds, nil)
+				}
+				continue
+			}
+		}
+		if clause == "WHERE" || clause == "ON" {
+			conds[len(conds)-1] = append(conds[len(conds)-1], t)
+		}
+		if !l.isColumn(toks, i) {
+			continue
+		}
+		name := ident(t)
+		switch {
+		case clause == "USING":
+			using[name] = true
+		case l.isAlias(toks, i):
+			if clause == "SELECT" {
+				aliases[name] = true
+			}
+		case l.at(toks, i-1) == ".":
+		case checkedClauses[clause]:
+			refs = append(refs, t)
+		}
+	}
+	if sources > 1 {
+		seen := make(map[string]bool)
+		for _, t := range refs {
+			name := ident(t)
+			if aliases[name] || using[name] || seen[name] {
+				continue
+			}
+			seen[name] = true
+			l.add(AmbiguousColumn, t, "column %s is not qualified by a table, but the query reads from %d tables", t.Text, sources)
+		}
+	}
+	for _, cond := range conds {
+		l.conditions(cond)
+	}
+}
+
+// conditions checks the conditions of a WHERE or ON clause.
+func (l *linter) conditions(toks []sqlfmt.Token) {
+	for i := 0; i < len(toks); i++ {
+		t := toks[i]
+		switch w := l.word(t); {
+		case (w == "LIKE" || w == "ILIKE") && i+1 < len(toks) && toks[i+1].Kind == sqlfmt.String:
+			if p := toks[i+1].Text; strings.HasPrefix(p[strings.IndexByte(p, '\'')+1:], "%") {
+				l.add(NonSargable, toks[i+1], "LIKE pattern %s starts with a wildcard, which prevents the use of an index", p)
+			}
+		case t.Kind == sqlfmt.Word && i+1 < len(toks) && toks[i+1].Kind == sqlfmt.Open && (w == "" || funcKeywords[w]) && !placeholder(t):
+			end := closing(toks, i+1)
+			col := l.column(toks, i+2, end)
+			if col == nil || !(l.comparison(toks, i-1) || l.comparison(toks, end+1)) {
+				break
+			}
+			l.add(NonSargable, t, "function %s applied to column %s prevents the use of an index on it", t.Text, col.Text)
+			i = end
+		case l.isColumn(toks, i) && l.at(toks, i+1) == "::":
+			l.add(NonSargable, t, "cast of column %s prevents the use of an index on it", t.Text)
+		case l.isColumn(toks, i) && i+1 < len(toks) && toks[i+1].Kind == sqlfmt.Op && arithOps[toks[i+1].Text] && l.compared(toks, i+2):
+			l.add(NonSargable, t, "expression on column %s prevents the use of an index on it", t.Text)
+		}
+		if l.dialect != "postgres" {
+			l.implicitCast(toks, i)
+		}
+	}
+}
+
+// implicitCast checks that the column at i is not compared with a numeric
+// string literal. PostgreSQL gives string literals the type of the column
+// they are compared with, so doesn't cast the column.
+func (l *linter) implicitCast(toks []sqlfmt.Token, i int) {
+	if !l.isColumn(toks, i) {
+		return
+	}
+	// start of the qualified name
+	start := i
+	for start >= 2 && l.at(toks, start-1) == "." {
+		start -= 2
+	}
+	var lit *sqlfmt.Token
+	switch {
+	case l.at(toks, i+1) == "IN" && l.at(toks, i+2) == "(":
+		for j, end := i+3, closing(toks, i+2); j < end; j++ {
+			if numericString(toks[j]) {
+				lit = &toks[j]
+				break
+			}
+		}
+	case l.comparison(toks, i+1) && i+2 < len(toks) && numericString(toks[i+2]):
+		lit = &toks[i+2]
+	case l.comparison(toks, start-1) && start >= 2 && numericString(toks[start-2]):
+		lit = &toks[start-2]
+	}
+	if lit != nil {
+		l.add(ImplicitCast, toks[i], "column %s is compared with the string literal %s, causing an implicit cast", toks[i].Text, lit.Text)
+	}
+}
+
+// column returns the first column between i and end.
+func (l *linter) column(toks []sqlfmt.Token, i, end int) *sqlfmt.Token {
+	for ; i < end; i++ {
+		if l.isColumn(toks, i) {
+			return &toks[i]
+		}
+	}
+	return nil
+}
+
+// compared returns true when the expression at i is followed by a
+// comparison, before the end of the condition.
+func (l *linter) compared(toks []sqlfmt.Token, i int) bool {
+	depth := 0
+	for ; i < len(toks); i++ {
+		switch w := l.at(toks, i); {
+		case w == "(":
+			depth++
+		case w == ")":
+			if depth--; depth < 0 {
+				return false
+			}
+		case depth == 0 && (w == "AND" || w == "OR"):
+			return false
+		case depth == 0 && l.comparison(toks, i):
+			return true
+		}
+	}
+	return false
+}
+
+// comparison returns true when the token at i is a comparison.
+func (l *linter) comparison(toks []sqlfmt.Token, i int) bool {
+	if i < 0 || len(toks) <= i {
+		return false
+	}
+	t := toks[i]
+	return t.Kind == sqlfmt.Op && compOps[t.Text] || compWords[l.word(t)]
+}
+
+// isColumn returns true when the token at i is a column name, or the alias of
+// an expression.
+func (l *linter) isColumn(toks []sqlfmt.Token, i int) bool {
+	if i < 0 || len(toks) <= i {
+		return false
+	}
+	switch t := toks[i]; {
+	case t.Kind == sqlfmt.Quoted:
+	case t.Kind != sqlfmt.Word, l.keywords[strings.ToUpper(t.Text)], placeholder(t), nonColumns[strings.ToUpper(t.Text)]:
+		return false
+	}
+	next, prev := l.at(toks, i+1), l.at(toks, i-1)
+	return next != "(" && next != "." && prev != "::" && prev != "OVER"
+}
+
+// isAlias returns true when the column name at i is an alias, following an
+// expression.
+func (l *linter) isAlias(toks []sqlfmt.Token, i int) bool {
+	if i == 0 {
+		return false
+	}
+	switch prev := toks[i-1]; prev.Kind {
+	case sqlfmt.Quoted, sqlfmt.String, sqlfmt.Number, sqlfmt.Close:
+		return true
+	case sqlfmt.Word:
+		return !l.keywords[strings.ToUpper(prev.Text)] && !nonColumns[strings.ToUpper(prev.Text)] || strings.EqualFold(prev.Text, "AS")
+	}
+	return false
+}
+
+// word returns the upper case keyword of t, or empty.
+func (l *linter) word(t sqlfmt.Token) string {
+	if w := strings.ToUpper(t.Text); t.Kind == sqlfmt.Word && l.keywords[w] {
+		return w
+	}
+	return ""
+}
+
+// at returns the upper case keyword, or the text of the other tokens, at i.
+func (l *linter) at(toks []sqlfmt.Token, i int) string {
+	if i < 0 || len(toks) <= i {
+		return ""
+	}
+	if w := l.word(toks[i]); w != "" {
+		return w
+	}
+	return toks[i].Text
+}
+
+// closing returns the index of the parenthesis closing the one at i, or the
+// number of tokens.
+func closing(toks []sqlfmt.Token, i int) int {
+	depth := 0
+	for ; i < len(toks); i++ {
+		switch toks[i].Kind {
+		case sqlfmt.Open:
+			depth++
+		case sqlfmt.Close:
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return len(toks)
+}
+
+// ident returns the name of an identifier, without its quotes.
+func ident(t sqlfmt.Token) string {
+	if t.Kind == sqlfmt.Quoted {
+		return t.Text[1 : len(t.Text)-1]
+	}
+	return strings.ToLower(t.Text)
+}
+
+// placeholder returns true when t is a placeholder or variable.
+func placeholder(t sqlfmt.Token) bool {
+	return strings.ContainsAny(t.Text[:1], "$@:")
+}
+
+// numericRE matches numeric string literals.
+var numericRE = regexp.MustCompile(`^'[-+]?[0-9]+(\.[0-9]+)?'$`)
+
+// numericString returns true when t is a numeric string literal.
+func numericString(t sqlfmt.Token) bool {
+	return t.Kind == sqlfmt.String && numericRE.MatchString(t.Text)
+}
+
+var (
+	// subqueryWords start subqueries.
+	subqueryWords = map[string]bool{
+		"SELECT": true, "WITH": true, "VALUES": true, "INSERT": true, "UPDATE": true, "DELETE": true,
+	}
+	// setOps separate the queries of a compound query.
+	setOps = map[string]bool{
+		"UNION": true, "INTERSECT": true, "EXCEPT": true, "MINUS": true,
+	}
+	// withWords precede the statement of a WITH query.
+	withWords = map[string]bool{
+		"WITH": true, "RECURSIVE": true, "AS": true, "NOT": true,
+	}
+	// checkedClauses are the clauses whose columns are checked for
+	// ambiguity.
+	checkedClauses = map[string]bool{
+		"SELECT": true, "WHERE": true, "ON": true, "GROUP BY": true, "HAVING": true, "ORDER BY": true, "QUALIFY": true,
+	}
+	// funcKeywords are the keywords called as functions.
+	funcKeywords = map[string]bool{
+		"CAST": true, "EXTRACT": true, "SUBSTRING": true, "TRIM": true,
+		"POSITION": true, "LEFT": true, "RIGHT": true, "REPLACE": true,
+	}
+	// nonColumns are the words of expressions that are not columns.
+	nonColumns = map[string]bool{
+		"YEAR": true, "MONTH": true, "DAY": true, "HOUR": true, "MINUTE": true, "SECOND": true,
+		"WEEK": true, "QUARTER": true, "EPOCH": true, "DOW": true, "DOY": true,
+		"DATE": true, "TIME": true, "TIMESTAMP": true, "ZONE": true, "WITHOUT": true,
+		"BOTH": true, "LEADING": true, "TRAILING": true, "LOCALTIME": true, "LOCALTIMESTAMP": true,
+	}
+	// compOps and compWords are comparisons.
+	compOps = map[string]bool{
+		"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+	}
+	compWords = map[string]bool{
+		"LIKE": true, "ILIKE": true, "IN": true, "BETWEEN": true, "SIMILAR": true,
+	}
+	// arithOps are arithmetic and string operators.
+	arithOps = map[string]bool{
+		"+": true, "-": true, "*": true, "/": true, "%": true, "||": true,
+	}
+)
@@ -0,0 +1,71 @@
+package sqllint
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		s       string
+		dialect string
+		exp     []string
+	}{
+		{`update users set name = 'x'`, "postgres", []string{"1:1:missing-where"}},
+		{`delete from users where id = 1; DELETE FROM logs`, "", []string{"1:33:missing-where"}},
+		{
+			"with d as (\n  delete from t returning id\n)\nupdate u set x = 1 where id in (select id from d)",
+			"postgres",
+			[]string{"2:3:missing-where"},
+		},
+		{`insert into t (a) values (1) on conflict (a) do update set a = 2`, "postgres", nil},
+		{`select id, u.name from users u join orders o on o.user_id = u.id where total > 10`, "", []string{
+			"1:8:ambiguous-column",
+			"1:72:ambiguous-column",
+		}},
+		{`select count(*) n, a.x from a join b using (id) where id = 1 order by n`, "", nil},
+		{`select id from a union select id from b`, "", nil},
+		{`select * from users where lower(email) = $1 and name like '%son'`, "postgres", []string{
+			"1:27:non-sargable",
+			"1:59:non-sargable",
+		}},
+		{`select * from t where created_at::date = current_date or price * 2 > 10`, "postgres", []string{
+			"1:23:non-sargable",
+			"1:58:non-sargable",
+		}},
+		{`select * from t where a = lower($1) and b = c + 1 and exists (select 1 from u where u.id = t.id)`, "postgres", nil},
+		{`select * from t where t.id = '42' or code in ('1', '2')`, "mysql", []string{
+			"1:25:implicit-cast",
+			"1:38:implicit-cast",
+		}},
+		{`select * from t where id = '42'`, "postgres", nil},
+		{`select * from t where x is distinct from y`, "postgres", nil},
+	}
+	for i, test := range tests {
+		findings, err := Lint(test.s, Options{Dialect: test.dialect})
+		if err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		var res []string
+		for _, f := range findings {
+			res = append(res, fmt.Sprintf("%d:%d:%s", f.Line, f.Column, f.Rule))
+		}
+		if !reflect.DeepEqual(res, test.exp) {
+			t.Errorf("test %d expected %v, got: %v (%v)", i, test.exp, res, findings)
+		}
+	}
+}
+
+func TestLintDisable(t *testing.T) {
+	findings, err := Lint(`delete from t`, Options{Disable: []string{MissingWhere}})
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case len(findings) != 0:
+		t.Errorf("expected no findings, got: %v", findings)
+	}
+	if _, err := Lint(`delete from t`, Options{Disable: []string{"unknown"}}); err == nil {
+		t.Errorf("expected an error for an unknown rule")
+	}
+}
@@ -0,0 +1,186 @@
+package stmt
+
+import (
+	"strings"
+	"unicode"
+)
+
+// block kinds.
+const (
+	// blockNone are statements without blocks.
+	blockNone = iota
+	// blockRoutine are routines (ie, CREATE PROCEDURE ... BEGIN ... END) and
+	// anonymous blocks.
+	blockRoutine
+	// blockPackage are packages (ie, CREATE PACKAGE ... AS ... END), whose
+	// END closes their declarations.
+	blockPackage
+)
+
+// createWords is the maximum number of words of a CREATE statement before the
+// kind of the created object.
+const createWords = 8
+
+// blockState is the state of the BEGIN ... END blocks of a statement, whose
+// semicolons do not terminate the statement.
+type blockState struct {
+	// words is the number of read words.
+	words int
+	// create is set in a CREATE statement, until the kind of the created
+	// object, and typ after its TYPE.
+	create, typ bool
+	kind        int
+	// depth is the depth of BEGIN ... END and CASE ... END.
+	depth int
+	// declare is set in the declarations preceding a BEGIN, and declared
+	// once the declarations of a routine started.
+	declare, declared bool
+}
+
+// open returns true when in a block.
+func (s *blockState) open() bool {
+	return s.depth > 0 || s.declare
+}
+
+// scan returns true when the words of the statement can change its blocks.
+func (s *blockState) scan() bool {
+	return s.words == 0 || s.create || s.kind != blockNone
+}
+
+// word processes the upper case word w of a statement, followed by the rest
+// of its line. When anonymous is set, the statement can be an anonymous block
+// or routine with declarations (ie, PL/SQL).
+func (s *blockState) word(w string, rest []rune, balanced, anonymous bool) {
+	n := s.words
+	s.words++
+	switch {
+	case n == 0 && w == "CREATE":
+		s.create = true
+		return
+	case n == 0 && anonymous && w == "DECLARE":
+		s.kind, s.declare = blockRoutine, true
+		return
+	case n == 0 && anonymous && w == "BEGIN" && !transaction(rest):
+		s.kind, s.depth = blockRoutine, 1
+		return
+	case s.create:
+		switch w {
+		case "PROCEDURE", "FUNCTION", "TRIGGER", "EVENT":
+			s.kind, s.create = blockRoutine, false
+		case "PACKAGE":
+			s.kind, s.create = blockPackage, false
+		case "BODY":
+			if s.typ {
+				s.kind, s.create = blockPackage, false
+			}
+		case "TYPE":
+			s.typ = true
+		case "TABLE", "VIEW", "INDEX", "SEQUENCE", "SCHEMA", "DATABASE", "USER", "ROLE":
+			s.create = false
+		}
+		if n >= createWords {
+			s.create = false
+		}
+		return
+	case s.kind == blockNone:
+		return
+	}
+	switch w {
+	case "BEGIN":
+		s.declare, s.declared = false, true
+		s.depth++
+	case "CASE":
+		s.depth++
+	case "END":
+		if s.depth > 0 && !endsLoop(rest) {
+			s.depth--
+		}
+	case "DECLARE":
+		if anonymous && s.depth == 0 {
+			s.declare, s.declared = true, true
+		}
+	case "IS", "AS":
+		if !anonymous || !balanced || s.depth != 0 || s.declared {
+			break
+		}
+		s.declared = true
+		if s.kind == blockPackage {
+			s.depth = 1
+		} else {
+			s.declare = true
+		}
+	}
+}
+
+// transaction returns true when the rest of the line following BEGIN starts
+// a transaction.
+func transaction(rest []rune) bool {
+	s := strings.TrimSpace(string(rest))
+	if strings.HasPrefix(s, ";") {
+		return true
+	}
+	switch strings.ToUpper(firstWord(s)) {
+	case "TRAN", "TRANSACTION", "WORK":
+		return true
+	}
+	return false
+}
+
+// endsLoop returns true when the rest of the line following END ends a
+// control statement other than a block or CASE (ie, END IF).
+func endsLoop(rest []rune) bool {
+	switch strings.ToUpper(firstWord(strings.TrimSpace(string(rest)))) {
+	case "IF", "LOOP", "WHILE", "REPEAT", "FOR":
+		return true
+	}
+	return false
+}
+
+// firstWord returns the leading word of s.
+func firstWord(s string) string {
+	if i := strings.IndexFunc(s, func(c rune) bool { return !isWordRune(c) }); i != -1 {
+		return s[:i]
+	}
+	return s
+}
+
+// isWordRune returns true when c is part of a word.
+func isWordRune(c rune) bool {
+	return c == '_' || c == '$' || unicode.IsLetter(c) || unicode.IsDigit(c)
+}
+
+// readWord returns the end of the word starting at i.
+func readWord(r []rune, i, end int) int {
+	for ; i < end && isWordRune(r[i]); i++ {
+	}
+	return i
+}
+
+// readDelimiter reads a DELIMITER command (ie, DELIMITER //) changing the
+// statement delimiter, returning the delimiter, or empty for ;.
+func readDelimiter(r []rune, end int) (string, bool) {
+	i, _ := findNonSpace(r, 0, end)
+	j := readWord(r, i, end)
+	if !strings.EqualFold(string(r[i:j]), "DELIMITER") || j == end || !IsSpaceOrControl(r[j]) {
+		return "", false
+	}
+	fields := strings.Fields(string(r[j:end]))
+	if len(fields) == 0 {
+		return "", false
+	}
+	if fields[0] == ";" {
+		return "", true
+	}
+	return fields[0], true
+}
+
+// hasRunes returns true when r at i starts with s.
+func hasRunes(r []rune, i, end int, s string) bool {
+	for _, c := range s {
+		if i >= end || r[i] != c {
+			return false
+		}
+		i++
+	}
+	return true
+}
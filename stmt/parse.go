@@ -293,6 +293,28 @@ func FindPrefix(s string, allowCComments, allowHashComments, allowMultilineComme
 	return findPrefix([]rune(s), prefixCount, allowCComments, allowHashComments, allowMultilineComments)
 }
 
+// TrimComments trims the leading space and comments of s.
+func TrimComments(s string, allowCComments, allowHashComments, allowMultilineComments bool) string {
+	r := []rune(s)
+	for i, end := 0, len(r); ; i++ {
+		i, _ = findNonSpace(r, i, end)
+		c, next := grab(r, i, end), grab(r, i+1, end)
+		switch {
+		// single line comments '--' and '//'
+		case c == '-' && next == '-', c == '/' && next == '/' && allowCComments, c == '#' && allowHashComments:
+			i, _ = findRune(r, i, end, '\n')
+		// multiline comments '/*' '*/'
+		case c == '/' && next == '*' && allowMultilineComments:
+			i, _ = readMultilineComment(r, i+2, end)
+		default:
+			return string(r[i:])
+		}
+		if i >= end {
+			return ""
+		}
+	}
+}
+
 // substitute substitutes n runes in r starting at i with the runes in s.
 // Dynamically grows r if necessary.
 func substitute(r []rune, i, end, n int, s string) ([]rune, int) {
@@ -313,6 +313,28 @@ func TestFindPrefix(t *testing.T) {
 	}
 }
 
+func TestTrimComments(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp string
+	}{
+		{"", ""},
+		{"  select 1", "select 1"},
+		{"/* x */ DROP TABLE t", "DROP TABLE t"},
+		{"-- c\nDROP TABLE t", "DROP TABLE t"},
+		{"// c\n/**/\t# c\n drop table t -- c", "drop table t -- c"},
+		{"/* x */", ""},
+		{"/* x", ""},
+		{"-- c", ""},
+		{"select /* x */ 1", "select /* x */ 1"},
+	}
+	for i, test := range tests {
+		if s := TrimComments(test.s, true, true, true); s != test.exp {
+			t.Errorf("test %d %q expected %q, got: %q", i, test.s, test.exp, s)
+		}
+	}
+}
+
 func TestReadVar(t *testing.T) {
 	tests := []struct {
 		s   string
@@ -3,6 +3,8 @@ package stmt
 
 import (
 	"bytes"
+	"strings"
+	"unicode"
 )
 
 // MinCapIncrease is the minimum amount by which to grow a Stmt.Buf.
@@ -51,6 +53,17 @@ type Stmt struct {
 	allowCComments bool
 	// allowHashComments allows hash comments (ie, # ... )
 	allowHashComments bool
+	// allowBacktick allows backtick quoted identifiers (ie, `...`, MySQL).
+	allowBacktick bool
+	// allowBlocks allows anonymous blocks (ie, BEGIN ... END; and DECLARE ...
+	// BEGIN ... END;), declarations of routines before their BEGIN, and lines
+	// of a single / terminating blocks (ie, Oracle PL/SQL).
+	allowBlocks bool
+	// allowDelimiter allows changing the statement delimiter with DELIMITER
+	// (ie, MySQL).
+	allowDelimiter bool
+	// delimiter is the statement delimiter set with DELIMITER, replacing ;.
+	delimiter string
 	// Buf is the statement buffer
 	Buf []rune
 	// Len is the current len of any statement in Buf.
@@ -71,6 +84,8 @@ type Stmt struct {
 	multilineComment bool
 	// balanceCount is the balanced paren count
 	balanceCount int
+	// block is the state of the BEGIN ... END blocks
+	block blockState
 	// ready indicates that a complete statement has been parsed
 	ready bool
 }
@@ -142,6 +157,8 @@ func (b *Stmt) Reset(r []rune) {
 	b.multilineComment = false
 	// balance state
 	b.balanceCount = 0
+	// block state
+	b.block = blockState{}
 	// ready state
 	b.ready = false
 	if r != nil {
@@ -195,8 +212,26 @@ func (b *Stmt) Next(unquote func(string, bool) (bool, string, error)) (string, s
 		if err != nil {
 			return "", "", err
 		}
+		// strip the \r of the CRLF line endings of windows
+		if n := len(b.r); n != 0 && b.r[n-1] == '\r' {
+			b.r = b.r[:n-1]
+		}
 		b.rlen = len(b.r)
 	}
+	if b.quote == 0 && !b.multilineComment {
+		// change the delimiter
+		if b.allowDelimiter && b.Len == 0 {
+			if d, ok := readDelimiter(b.r, b.rlen); ok {
+				b.delimiter, b.r, b.rlen = d, nil, 0
+				return "", "", nil
+			}
+		}
+		// line of a single / terminating a block
+		if b.allowBlocks && strings.TrimSpace(string(b.r[:b.rlen])) == "/" {
+			b.ready, b.r, b.rlen = b.Len != 0, nil, 0
+			return "", "", nil
+		}
+	}
 	var cmd, params string
 	var ok bool
 parse:
@@ -215,8 +250,9 @@ parse:
 		case b.multilineComment:
 			i, ok = readMultilineComment(b.r, i, b.rlen)
 			b.multilineComment = !ok
-		// start of single or double quoted string
-		case c == '\'' || c == '"':
+		// start of single or double quoted string, or backtick quoted
+		// identifier (mysql)
+		case c == '\'' || c == '"' || b.allowBacktick && c == '`':
 			b.quote = c
 		// start of dollar quoted string literal (postgres)
 		case b.allowDollar && c == '$':
@@ -255,6 +291,11 @@ parse:
 					v.I += b.Len + 1
 				}
 			}
+		// words of BEGIN ... END blocks
+		case b.block.scan() && unicode.IsLetter(c) && (i == 0 || !isWordRune(b.r[i-1]) && b.r[i-1] != '.'):
+			end := readWord(b.r, i, b.rlen)
+			b.block.word(strings.ToUpper(string(b.r[i:end])), b.r[end:b.rlen], b.balanceCount == 0, b.allowBlocks)
+			i = end - 1
 		// unbalance
 		case c == '(':
 			b.balanceCount++
@@ -263,6 +304,12 @@ parse:
 			b.balanceCount = max(0, b.balanceCount-1)
 		// continue processing quoted string, multiline comment, or unbalanced statements
 		case b.quote != 0 || b.multilineComment || b.balanceCount != 0:
+		// terminated by the delimiter set with DELIMITER
+		case b.delimiter != "" && hasRunes(b.r, i, b.rlen, b.delimiter):
+			b.r = append(b.r[:i], b.r[i+len([]rune(b.delimiter)):]...)
+			b.rlen = len(b.r)
+			b.ready = true
+			break parse
 		// skip escaped backslash, semicolon, colon
 		case c == '\\' && (next == '\\' || next == ';' || next == ':'):
 			// FIXME: the below works, but it may not make sense to keep this enabled.
@@ -287,8 +334,8 @@ parse:
 			b.r = append(b.r[:i], b.r[pend:]...)
 			b.rlen = len(b.r)
 			break parse
-		// terminated
-		case c == ';':
+		// terminated, unless in a block
+		case c == ';' && b.delimiter == "" && !b.block.open():
 			b.ready = true
 			i++
 			break parse
@@ -415,3 +462,28 @@ func WithAllowHashComments(enable bool) Option {
 		b.allowHashComments = enable
 	}
 }
+
+// WithAllowBacktick is a statement buffer option to set allowing backtick
+// quoted identifiers (ie, MySQL's `name`).
+func WithAllowBacktick(enable bool) Option {
+	return func(b *Stmt) {
+		b.allowBacktick = enable
+	}
+}
+
+// WithAllowBlocks is a statement buffer option to set allowing anonymous
+// blocks, declarations of routines before their BEGIN, and lines of a single
+// / terminating blocks (ie, Oracle PL/SQL).
+func WithAllowBlocks(enable bool) Option {
+	return func(b *Stmt) {
+		b.allowBlocks = enable
+	}
+}
+
+// WithAllowDelimiter is a statement buffer option to set allowing changing
+// the statement delimiter with DELIMITER (ie, MySQL's DELIMITER //).
+func WithAllowDelimiter(enable bool) Option {
+	return func(b *Stmt) {
+		b.allowDelimiter = enable
+	}
+}
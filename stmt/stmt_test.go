@@ -184,6 +184,8 @@ func TestNextResetState(t *testing.T) {
 		{`select 1\g '\g `, []string{`select 1`}, []string{`\g| '\g `}, "=", nil},
 		{`select 1\g "\g `, []string{`select 1`}, []string{`\g| "\g `}, "=", nil},
 		{"select 1\\g `\\g ", []string{`select 1`}, []string{"\\g| `\\g "}, "=", nil},
+		{"select 1\r\n\\g\r\n", []string{`select 1`}, []string{"|", `\g|`, "|"}, "=", nil}, // 74
+		{"select 'a\r\nb';\r", []string{"select 'a\nb';"}, []string{"|", "|"}, "=", nil},
 	}
 	for i, test := range tests {
 		b := New(sp(test.s, "\n"), WithAllowDollar(true), WithAllowMultilineComments(true), WithAllowCComments(true))
@@ -254,6 +256,92 @@ func TestNextResetState(t *testing.T) {
 	}
 }
 
+func TestNextBlocks(t *testing.T) {
+	u, err := user.Current()
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	unquote := env.Unquote(u, false, env.Vars{})
+	mysql := []Option{WithAllowHashComments(true), WithAllowBacktick(true), WithAllowDelimiter(true)}
+	oracle := []Option{WithAllowMultilineComments(true), WithAllowBlocks(true)}
+	tests := []struct {
+		s     string
+		opts  []Option
+		stmts []string
+	}{
+		{
+			"create trigger t after insert on a for each row begin\n  update b set n = n + 1;\nend;\nselect 1;",
+			nil,
+			[]string{"create trigger t after insert on a for each row begin\n  update b set n = n + 1;\nend;", "select 1;"},
+		},
+		{
+			"CREATE PROCEDURE p() BEGIN\n  IF x THEN SELECT CASE WHEN y THEN 1 END; END IF;\n  lbl: BEGIN SELECT 2; END lbl;\nEND; select 3;",
+			mysql,
+			[]string{"CREATE PROCEDURE p() BEGIN\n  IF x THEN SELECT CASE WHEN y THEN 1 END; END IF;\n  lbl: BEGIN SELECT 2; END lbl;\nEND;", "select 3;"},
+		},
+		{
+			"DELIMITER //\ncreate procedure p() select `a;b`; select 2; //\ndelimiter ;\nselect 3;",
+			mysql,
+			[]string{"create procedure p() select `a;b`; select 2; ", "select 3;"},
+		},
+		{
+			"select `a;b`; select 2;",
+			mysql,
+			[]string{"select `a;b`;", "select 2;"},
+		},
+		{
+			"select `a;b`; select 2;",
+			nil,
+			[]string{"select `a;", "b`;", "select 2;"},
+		},
+		{
+			"begin;\nselect t.begin, end_date from t;\nbegin transaction;\ncommit;",
+			nil,
+			[]string{"begin;", "select t.begin, end_date from t;", "begin transaction;", "commit;"},
+		},
+		{
+			"create table t (begin int);\ncreate function f() returns int language sql begin atomic select 1; end;",
+			nil,
+			[]string{"create table t (begin int);", "create function f() returns int language sql begin atomic select 1; end;"},
+		},
+		{
+			"CREATE OR REPLACE PROCEDURE p IS\n  n NUMBER;\nBEGIN\n  n := 1;\nEND;\n/\nselect 1 from dual;",
+			oracle,
+			[]string{"CREATE OR REPLACE PROCEDURE p IS\n  n NUMBER;\nBEGIN\n  n := 1;\nEND;", "select 1 from dual;"},
+		},
+		{
+			"DECLARE\n  n NUMBER;\nBEGIN\n  NULL;\nEND;\nBEGIN\n  p;\nEND;\nselect 1 from dual\n/",
+			oracle,
+			[]string{"DECLARE\n  n NUMBER;\nBEGIN\n  NULL;\nEND;", "BEGIN\n  p;\nEND;", "select 1 from dual"},
+		},
+		{
+			"CREATE PACKAGE BODY k AS\n  PROCEDURE a IS BEGIN NULL; END;\nEND k;\nselect 1 from dual;",
+			oracle,
+			[]string{"CREATE PACKAGE BODY k AS\n  PROCEDURE a IS BEGIN NULL; END;\nEND k;", "select 1 from dual;"},
+		},
+	}
+	for i, test := range tests {
+		b := New(sp(test.s, "\n"), test.opts...)
+		var stmts []string
+		for {
+			_, _, err := b.Next(unquote)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("test %d expected no error, got: %v", i, err)
+			}
+			if b.Ready() {
+				stmts = append(stmts, b.String())
+				b.Reset(nil)
+			}
+		}
+		if !reflect.DeepEqual(stmts, test.stmts) {
+			t.Errorf("test %d expected statements %s, got: %s", i, jj(test.stmts), jj(stmts))
+		}
+	}
+}
+
 func TestEmptyVariablesRawString(t *testing.T) {
 	stmt := new(Stmt)
 	stmt.AppendString("select ", "\n")
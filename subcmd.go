@@ -0,0 +1,104 @@
+package main
+
+import (
+	"io"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/logging"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/policy"
+	"github.com/xo/usql/progress"
+	"github.com/xo/usql/text"
+)
+
+// subcmds are the usql subcommands (ie, usql import), run instead of usql's
+// regular command-line when the first argument is a subcommand name.
+var subcmds = kingpin.New(text.CommandLower(), text.Banner)
+
+// subcmdArgs are the config file arguments shared by all subcommands.
+var subcmdArgs = &Args{}
+
+func init() {
+	subcmds.Flag("config", "Databases config yaml file path").PlaceHolder("/path/to/config.yaml").StringVar(&subcmdArgs.ConfigFilePath)
+	subcmds.Flag("env-file", "set the environment variables of the .env file, for the ${VAR} references of the config file").PlaceHolder(".env").StringVar(&subcmdArgs.EnvFile)
+	subcmds.Flag("role", "user role to use for logging into given DB").PlaceHolder("reader").StringVar(&subcmdArgs.Role)
+	subcmds.Flag("verbose", "log the config file and the resolved database, role and DSN to stderr").BoolVar(&subcmdArgs.Verbose)
+	subcmds.Flag("debug", "log the config file discovery and the statements sent to the driver too, implies --verbose").BoolVar(&subcmdArgs.Debug)
+	subcmds.Flag("json", "write the output and the errors as JSON objects").BoolVar(&subcmdArgs.JSON)
+	subcmds.Flag("no-progress", "do not report the progress of imports, exports and dumps to stderr").BoolVar(&subcmdArgs.NoProgress)
+	subcmds.HelpFlag.Short('h')
+	subcmds.PreAction(func(*kingpin.ParseContext) error {
+		logging.SetLevel(subcmdArgs.logLevel())
+		if subcmdArgs.EnvFile != "" {
+			if err := config.LoadEnvFile(subcmdArgs.EnvFile); err != nil {
+				return err
+			}
+		}
+		subcmdArgs.configs = newConfigStore(subcmdArgs.ConfigFilePath)
+		return nil
+	})
+}
+
+// isSubcmd returns true when name is a subcommand.
+func isSubcmd(name string) bool {
+	return subcmds.GetCommand(name) != nil
+}
+
+// runSubcmd parses args and runs the selected subcommand.
+func runSubcmd(args []string) error {
+	_, err := subcmds.Parse(args)
+	return err
+}
+
+// newProgress starts reporting the progress of the operation with the name to
+// stderr, returning nil with --no-progress.
+func newProgress(name string) *progress.Progress {
+	if subcmdArgs.NoProgress {
+		return nil
+	}
+	return progress.New(os.Stderr, name)
+}
+
+// splitList splits the comma separated values of a repeatable flag.
+func splitList(values []string) []string {
+	var strs []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				strs = append(strs, s)
+			}
+		}
+	}
+	return strs
+}
+
+// readFile reads a file, or stdin when name is -.
+func readFile(name string) ([]byte, error) {
+	if name == "-" {
+		return io.ReadAll(os.Stdin)
+	}
+	return os.ReadFile(name)
+}
+
+// sqlDialect returns the SQL dialect of a driver or scheme name (ie, pg is
+// postgres).
+func sqlDialect(name string) string {
+	if u, err := dburl.Parse(name + ":"); name != "" && err == nil {
+		return drivers.Caps(u).Dialect
+	}
+	return name
+}
+
+// checkPolicy checks the statements of sqlstr, split for the driver of the
+// URL, against the policy.
+func checkPolicy(p *policy.Policy, u *dburl.URL, sqlstr string) error {
+	stmts, err := drivers.Statements(u, sqlstr)
+	if err != nil {
+		return err
+	}
+	return p.Check(stmts...)
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/activity"
+	"github.com/xo/usql/jsonout"
+)
+
+// runningQuery is the JSON object of a query running on a database.
+type runningQuery struct {
+	ID         string   `json:"id"`
+	User       string   `json:"user"`
+	State      string   `json:"state"`
+	DurationMS int64    `json:"duration_ms"`
+	BlockedBy  []string `json:"blocked_by,omitempty"`
+	Query      string   `json:"query"`
+}
+
+func init() {
+	var alias string
+	var watch, minDuration time.Duration
+	var kill []string
+	cmd := subcmds.Command("activity", "show the queries running on a database, with the sessions blocking them")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("watch", "refresh the queries at the interval, until interrupted").PlaceHolder("DURATION").DurationVar(&watch)
+	cmd.Flag("min-duration", "show the queries running for at least the duration").PlaceHolder("DURATION").DurationVar(&minDuration)
+	cmd.Flag("kill", "kill the sessions (or queries) with the ids, as permitted by the role").PlaceHolder("ID").StringsVar(&kill)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		if !activity.Supported(u) {
+			return fmt.Errorf("the activity of %s databases is not supported", u.Unaliased)
+		}
+		if len(kill) != 0 {
+			for _, id := range splitList(kill) {
+				if err := activity.Kill(ctx, u, db, id); err != nil {
+					return jsonout.WithCode(jsonout.CodeDatabase, fmt.Errorf("%s: %w", id, err))
+				}
+				fmt.Fprintf(os.Stderr, "killed %s\n", id)
+			}
+			return nil
+		}
+		clear := watch > 0 && !subcmdArgs.JSON && isatty.IsTerminal(os.Stdout.Fd())
+		for {
+			queries, err := activity.Read(ctx, u, db)
+			switch {
+			case ctx.Err() != nil:
+				return nil
+			case err != nil:
+				return jsonout.WithCode(jsonout.CodeDatabase, err)
+			}
+			var shown []activity.Query
+			for _, q := range queries {
+				if q.Duration >= minDuration {
+					shown = append(shown, q)
+				}
+			}
+			if clear {
+				fmt.Fprint(os.Stdout, "\033[H\033[2J")
+				fmt.Fprintf(os.Stdout, "%s, %s\n\n", alias, time.Now().Format(time.TimeOnly))
+			}
+			if err := writeActivity(shown); err != nil {
+				return err
+			}
+			if watch <= 0 {
+				return nil
+			}
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(watch):
+			}
+		}
+	})
+}
+
+// writeActivity writes the running queries, as JSON objects with --json.
+func writeActivity(queries []activity.Query) error {
+	if !subcmdArgs.JSON {
+		return activity.Write(os.Stdout, queries, 0)
+	}
+	for _, q := range queries {
+		if err := jsonout.Write(os.Stdout, runningQuery{q.ID, q.User, q.State, q.Duration.Milliseconds(), q.BlockedBy, q.Query}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/bench"
+)
+
+func init() {
+	var alias, sqlstr, workload string
+	var opts bench.Options
+	cmd := subcmds.Command("bench", "measure the latency and throughput of a statement or a workload")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("command", "statement to execute").Short('c').PlaceHolder("SQL").StringVar(&sqlstr)
+	cmd.Flag("workload", "workload file of weighted statements to execute").PlaceHolder("FILE").StringVar(&workload)
+	cmd.Flag("iterations", "number of statements to execute (default 100 without --duration)").IntVar(&opts.Iterations)
+	cmd.Flag("duration", "how long to execute statements").PlaceHolder("5m").DurationVar(&opts.Duration)
+	cmd.Flag("concurrency", "number of concurrent connections").Default("1").IntVar(&opts.Concurrency)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		var w *bench.Workload
+		switch {
+		case (sqlstr == "") == (workload == ""):
+			return errors.New("exactly one of --command or --workload is required")
+		case sqlstr != "":
+			w = bench.NewWorkload(sqlstr)
+		default:
+			var err error
+			if w, err = bench.LoadWorkload(workload); err != nil {
+				return err
+			}
+		}
+		if opts.Iterations == 0 && opts.Duration == 0 {
+			opts.Iterations = 100
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		res, err := bench.Run(ctx, u, db, w, opts)
+		if err != nil {
+			return err
+		}
+		res.Report(os.Stdout)
+		return nil
+	})
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/rline"
+	"gopkg.in/yaml.v2"
+)
+
+// configCmd is the config subcommand.
+var configCmd = subcmds.Command("config", "manage the config file")
+
+func init() {
+	var format, path string
+	cmd := configCmd.Command("import", "print the config file entries of the connections of a credential or connection file")
+	cmd.Flag("from", "format of the file: pgpass, mycnf or dbeaver").Required().EnumVar(&format, config.ImportFormats...)
+	cmd.Arg("file", "file to import (default ~/.pgpass or $PGPASSFILE for pgpass, ~/.my.cnf for mycnf)").StringVar(&path)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		if path == "" {
+			var err error
+			if path, err = defaultImportPath(format); err != nil {
+				return err
+			}
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		dbs, err := config.Import(format, f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		// skip the aliases of the config file in use
+		if subcmdArgs.ConfigFilePath != "" || config.Find() != "" {
+			cfg, err := loadConfig(subcmdArgs)
+			if err != nil {
+				return err
+			}
+			for alias := range dbs {
+				if _, ok := cfg.Databases[alias]; ok {
+					fmt.Fprintf(os.Stderr, "skipping %s: alias already in config file %s\n", alias, cfg.Path)
+					delete(dbs, alias)
+				}
+			}
+		}
+		if len(dbs) == 0 {
+			return fmt.Errorf("no connections to import from %s", path)
+		}
+		buf, err := yaml.Marshal(map[string]interface{}{"databases": dbs})
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(buf)
+		return err
+	})
+}
+
+func init() {
+	var redactSecrets bool
+	var secretRef, output string
+	cmd := configCmd.Command("export", "write a copy of the config file, without its secrets with --redact-secrets")
+	cmd.Flag("redact-secrets", "remove the passwords and notifier URLs").BoolVar(&redactSecrets)
+	cmd.Flag("secret-ref", "replace the passwords by references to the secret resolver PLUGIN (env for environment variables), implies --redact-secrets").PlaceHolder("PLUGIN").StringVar(&secretRef)
+	cmd.Flag("output", "file to write (default standard output)").Short('o').PlaceHolder("FILE").StringVar(&output)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		if redactSecrets || secretRef != "" {
+			if cfg, err = cfg.Redact(secretRef); err != nil {
+				return err
+			}
+		}
+		buf, err := yaml.Marshal(cfg)
+		if err != nil {
+			return err
+		}
+		if output == "" {
+			_, err = os.Stdout.Write(buf)
+			return err
+		}
+		return os.WriteFile(output, buf, 0o644)
+	})
+}
+
+func init() {
+	var output string
+	cmd := configCmd.Command("init", "create the config file of a database with an interactive setup wizard")
+	cmd.Flag("output", "file to write (asked when not set)").Short('o').PlaceHolder("FILE").StringVar(&output)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		l, err := rline.New(false, "", "")
+		if err != nil {
+			return err
+		}
+		defer l.Close()
+		if !l.Interactive() {
+			return errors.New("the setup wizard requires a terminal")
+		}
+		_, err = (&wizard{l: l}).run("", output)
+		return err
+	})
+}
+
+func init() {
+	var alias string
+	cmd := configCmd.Command("save-last", "add the last ad-hoc connection to the config file as a database alias")
+	cmd.Arg("alias", "alias of the database").Required().StringVar(&alias)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		if config.IsDSN(alias) {
+			return fmt.Errorf("invalid alias %s", alias)
+		}
+		dsn, err := config.LastDSN()
+		if err != nil {
+			return err
+		}
+		db, err := config.FromDSN(dsn)
+		if err != nil {
+			return err
+		}
+		if u, err := dburl.Parse(dsn); err == nil && u.RawQuery != "" {
+			fmt.Fprintf(os.Stderr, "warning: the parameters %s of the connection are not saved\n", u.RawQuery)
+		}
+		// the config file in use, or else ~/.dbconfig.yaml
+		path := subcmdArgs.ConfigFilePath
+		if path == "" {
+			path = config.Find()
+		}
+		if path == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return err
+			}
+			path = filepath.Join(home, config.DefaultFilename)
+		}
+		if err := config.AddDatabase(path, alias, db); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "Added %s to %s. Connect with: usql --db %s", alias, path, alias)
+		if len(db.Credentials) != 0 {
+			fmt.Fprintf(os.Stdout, " --role %s", db.Credentials[0].Name)
+		}
+		fmt.Fprintln(os.Stdout)
+		return nil
+	})
+}
+
+// validation is the JSON object of the validation of the config file.
+type validation struct {
+	Path      string            `json:"path"`
+	Valid     bool              `json:"valid"`
+	Databases int               `json:"databases"`
+	Errors    []validationError `json:"errors"`
+}
+
+// validationError is the JSON object of an error of the config file, of a
+// database alias when set.
+type validationError struct {
+	Alias string `json:"alias,omitempty"`
+	*jsonout.ErrorInfo
+}
+
+func init() {
+	cmd := configCmd.Command("validate", "check the config file and the connection settings of its database aliases")
+	cmd.Action(func(*kingpin.ParseContext) error {
+		v := validation{Path: subcmdArgs.ConfigFilePath, Errors: []validationError{}}
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			v.Errors = append(v.Errors, validationError{ErrorInfo: jsonout.Info(err)})
+		} else {
+			v.Path, v.Databases = cfg.Path, len(cfg.Databases)
+			// the passwords are not resolved, as the roles are not
+			for _, alias := range cfg.Aliases() {
+				if _, err := cfg.DSN(alias, ""); err != nil {
+					v.Errors = append(v.Errors, validationError{Alias: alias, ErrorInfo: jsonout.Info(jsonout.WithCode(jsonout.CodeConfig, err))})
+				}
+			}
+		}
+		v.Valid = len(v.Errors) == 0
+		switch {
+		case subcmdArgs.JSON:
+			if err := jsonout.Write(os.Stdout, v); err != nil {
+				return err
+			}
+		case v.Valid:
+			fmt.Fprintf(os.Stdout, "config file %s is valid (%d databases)\n", v.Path, v.Databases)
+		default:
+			for _, e := range v.Errors {
+				if e.Alias != "" {
+					fmt.Fprintf(os.Stdout, "%s: ", e.Alias)
+				}
+				fmt.Fprintln(os.Stdout, e.Message)
+			}
+		}
+		if !v.Valid {
+			return jsonout.WithCode(jsonout.CodeConfig, fmt.Errorf("config file %s is invalid", v.Path))
+		}
+		return nil
+	})
+}
+
+// defaultImportPath returns the default path of the files of the import
+// format.
+func defaultImportPath(format string) (string, error) {
+	if path := os.Getenv("PGPASSFILE"); format == config.ImportPgpass && path != "" {
+		return path, nil
+	}
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case config.ImportPgpass:
+		return filepath.Join(u.HomeDir, ".pgpass"), nil
+	case config.ImportMycnf:
+		return filepath.Join(u.HomeDir, ".my.cnf"), nil
+	}
+	return "", fmt.Errorf("the %s file to import is required", format)
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/datadiff"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/jsonout"
+)
+
+func init() {
+	var aliasA, aliasB string
+	var tables, key []string
+	var chunkSize, parallel int
+	cmd := subcmds.Command("datadiff", "compare the rows of tables in two databases")
+	cmd.Arg("aliasA", "database alias from the config file").Required().StringVar(&aliasA)
+	cmd.Arg("aliasB", "database alias to compare with").Required().StringVar(&aliasB)
+	cmd.Flag("table", "tables to compare, comma separated").Required().PlaceHolder("TABLE,...").StringsVar(&tables)
+	cmd.Flag("key", "key columns, comma separated (default primary key)").PlaceHolder("COLUMN,...").StringsVar(&key)
+	cmd.Flag("chunk-size", "keys per checksum chunk").Default(fmt.Sprint(datadiff.DefaultChunkSize)).IntVar(&chunkSize)
+	cmd.Flag("parallel", "tables compared at once, limited by the max_connections of the aliases").Default("1").IntVar(&parallel)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		var srcs [2]datadiff.Source
+		for i, alias := range []string{aliasA, aliasB} {
+			u, db, err := openAlias(ctx, subcmdArgs, alias)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			srcs[i] = datadiff.Source{URL: u, DB: db}
+		}
+		pool, err := newPool(ctx, subcmdArgs, parallel)
+		if err != nil {
+			return err
+		}
+		tables := splitList(tables)
+		opts := make([]datadiff.Options, len(tables))
+		results := make([]*datadiff.Result, len(tables))
+		for i, table := range tables {
+			i, table := i, table
+			pool.Go(func(ctx context.Context) error {
+				opts[i] = datadiff.Options{Table: table, Key: splitList(key), ChunkSize: chunkSize}
+				if len(opts[i].Key) == 0 {
+					var err error
+					if opts[i].Key, err = primaryKey(ctx, srcs[0], table); err != nil {
+						return err
+					}
+				}
+				res, err := datadiff.Compare(ctx, srcs[0], srcs[1], opts[i])
+				if err != nil {
+					return fmt.Errorf("%s: %w", table, err)
+				}
+				results[i] = res
+				return nil
+			}, aliasA, aliasB)
+		}
+		err = pool.Wait()
+		for i, res := range results {
+			if res != nil {
+				writeDataDiff(aliasA, aliasB, opts[i], res)
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return dataDiffErr(results)
+	})
+}
+
+// dataDiffErr returns an error with code jsonout.CodeDifferent when the rows
+// of some of the compared tables differ.
+func dataDiffErr(results []*datadiff.Result) error {
+	var n int
+	for _, res := range results {
+		if res != nil && len(res.Ranges) != 0 {
+			n++
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+	return jsonout.WithCode(jsonout.CodeDifferent, fmt.Errorf("%d of %d tables differ", n, len(results)))
+}
+
+// primaryKey returns the primary key columns of the table.
+func primaryKey(ctx context.Context, src datadiff.Source, table string) ([]string, error) {
+	tables, err := dump.ReadTables(ctx, src.URL, src.DB, []string{table})
+	if err != nil {
+		return nil, err
+	}
+	if len(tables[0].PrimaryKey) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key: use --key", table)
+	}
+	return tables[0].PrimaryKey, nil
+}
+
+// writeDataDiff writes the result of the comparison of a table.
+func writeDataDiff(aliasA, aliasB string, opts datadiff.Options, res *datadiff.Result) {
+	fmt.Fprintf(os.Stdout, "table %s: %s %d rows, %s %d rows", opts.Table, aliasA, res.RowsA, aliasB, res.RowsB)
+	if len(res.Ranges) == 0 {
+		fmt.Fprintf(os.Stdout, ", all %d chunks match\n", res.Chunks)
+		return
+	}
+	var n int
+	for _, r := range res.Ranges {
+		n += r.DivergentChunks
+	}
+	fmt.Fprintf(os.Stdout, ", %d of %d chunks differ\n", n, res.Chunks)
+	key := strings.Join(opts.Key, ", ")
+	if len(opts.Key) > 1 {
+		key = "(" + key + ")"
+	}
+	for _, r := range res.Ranges {
+		fmt.Fprintf(os.Stdout, "  %s %s - %s: %s %d rows, %s %d rows\n", key, datadiff.FormatKey(r.From), datadiff.FormatKey(r.To), aliasA, r.RowsA, aliasB, r.RowsB)
+	}
+}
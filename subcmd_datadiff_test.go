@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/xo/usql/datadiff"
+)
+
+func TestDataDiffErr(t *testing.T) {
+	same := &datadiff.Result{RowsA: 2, RowsB: 2, Chunks: 1}
+	differ := &datadiff.Result{RowsA: 2, RowsB: 1, Chunks: 1, Ranges: []datadiff.Range{{From: []string{"1"}, To: []string{"2"}, DivergentChunks: 1}}}
+	tests := []struct {
+		results []*datadiff.Result
+		exp     string
+		code    int
+	}{
+		{[]*datadiff.Result{same, same}, "", 0},
+		{[]*datadiff.Result{same, differ, nil}, "1 of 3 tables differ", exitDifferent},
+		{[]*datadiff.Result{differ, differ}, "2 of 2 tables differ", exitDifferent},
+	}
+	for i, test := range tests {
+		err := dataDiffErr(test.results)
+		switch {
+		case test.exp == "" && err != nil:
+			t.Errorf("test %d expected no error, got: %v", i, err)
+		case test.exp != "" && (err == nil || err.Error() != test.exp):
+			t.Errorf("test %d expected error %q, got: %v", i, test.exp, err)
+		case err != nil && exitCode(err) != test.code:
+			t.Errorf("test %d expected exit code %d, got: %d", i, test.code, exitCode(err))
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/deps"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/jsonout"
+)
+
+// tableDeps is the JSON object of the dependencies of a table.
+type tableDeps struct {
+	Table      string   `json:"table"`
+	References []string `json:"references"`
+}
+
+func init() {
+	var alias, table string
+	var dot, reverse bool
+	cmd := subcmds.Command("deps", "order the tables of a database by their foreign keys")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("table", "order the table, and the tables it references or referencing it").PlaceHolder("TABLE").StringVar(&table)
+	cmd.Flag("dot", "write the dependency graph in the Graphviz DOT language").BoolVar(&dot)
+	cmd.Flag("reverse", "order the tables referencing others first, as deleted or truncated").BoolVar(&reverse)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		tables, err := dump.ReadTables(ctx, u, db, nil)
+		if err != nil {
+			return jsonout.WithCode(jsonout.CodeDatabase, err)
+		}
+		g := deps.New(tables)
+		if table != "" {
+			if g, err = g.Related(table); err != nil {
+				return jsonout.WithCode(jsonout.CodeDatabase, err)
+			}
+		}
+		if dot {
+			return g.WriteDOT(os.Stdout)
+		}
+		order, err := g.Order()
+		if err != nil {
+			return jsonout.WithCode(jsonout.CodeDatabase, err)
+		}
+		if reverse {
+			for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+				order[i], order[j] = order[j], order[i]
+			}
+		}
+		for _, name := range order {
+			if !subcmdArgs.JSON {
+				fmt.Fprintln(os.Stdout, name)
+				continue
+			}
+			refs := g.References(name)
+			if refs == nil {
+				refs = []string{}
+			}
+			if err := jsonout.Write(os.Stdout, tableDeps{name, refs}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
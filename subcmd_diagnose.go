@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/pkg/conn"
+	"github.com/xo/usql/redact"
+)
+
+// testStep is the JSON object of a step of the diagnosis of a connection.
+type testStep struct {
+	Step       string             `json:"step"`
+	Status     string             `json:"status"`
+	DurationMS int64              `json:"duration_ms"`
+	Detail     string             `json:"detail,omitempty"`
+	Error      *jsonout.ErrorInfo `json:"error,omitempty"`
+	Hint       string             `json:"hint,omitempty"`
+}
+
+func init() {
+	var alias string
+	cmd := subcmds.Command("test", "diagnose the connection to a database alias: DNS resolution, TCP reachability, TLS handshake, authentication and a trivial query")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cfg, err := aliasConfig(subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		steps, err := newOpener(cfg).Diagnose(ctx, alias, subcmdArgs.Role)
+		if err != nil {
+			return err
+		}
+		var failed *conn.Step
+		for i, step := range steps {
+			s := testStep{Step: step.Name, Status: "ok", DurationMS: step.Duration.Milliseconds(), Detail: redact.String(step.Detail), Hint: step.Hint}
+			switch {
+			case step.Err != nil && step.Name == conn.StepQuery:
+				s.Status, s.Error, failed = "failed", jsonout.Info(step.Err), &steps[i]
+			case step.Err != nil:
+				s.Status, s.Error, failed = "failed", jsonout.Info(jsonout.WithCode(jsonout.CodeConnection, step.Err)), &steps[i]
+			case step.Skipped:
+				s.Status = "skipped"
+			}
+			if subcmdArgs.JSON {
+				if err := jsonout.Write(os.Stdout, s); err != nil {
+					return err
+				}
+				continue
+			}
+			detail := s.Detail
+			if s.Error != nil {
+				detail = s.Error.Message
+			}
+			fmt.Fprintf(os.Stdout, "%-6s %-8s %6dms  %s\n", s.Step, s.Status, s.DurationMS, detail)
+			if s.Hint != "" {
+				fmt.Fprintf(os.Stdout, "%-6s %-8s %8s  hint: %s\n", "", "", "", s.Hint)
+			}
+		}
+		if failed != nil {
+			return jsonout.WithCode(jsonout.CodeConnection, fmt.Errorf("the %s step of the connection to %s failed", failed.Name, alias))
+		}
+		return nil
+	})
+}
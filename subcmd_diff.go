@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/resultdiff"
+)
+
+func init() {
+	var aliases, key []string
+	var query string
+	cmd := subcmds.Command("diff", "compare the results of a query on two databases")
+	cmd.Flag("db", "database aliases from the config file, given twice").Required().PlaceHolder("ALIAS").StringsVar(&aliases)
+	cmd.Flag("command", "query to run on both databases").Short('c').Required().PlaceHolder("QUERY").StringVar(&query)
+	cmd.Flag("key", "key columns, comma separated (default the first column)").PlaceHolder("COLUMN,...").StringsVar(&key)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		if len(aliases) != 2 {
+			return jsonout.WithCode(jsonout.CodeMissingArgument, errors.New("--db must be given twice"))
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		var res [2]*resultdiff.Result
+		for i, alias := range aliases {
+			_, db, err := openAlias(ctx, subcmdArgs, alias)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+			if res[i], err = queryResult(ctx, db, query); err != nil {
+				return jsonout.WithCode(jsonout.CodeDatabase, fmt.Errorf("%s: %w", alias, err))
+			}
+		}
+		d, err := resultdiff.Compare(res[0], res[1], splitList(key))
+		if err != nil {
+			return err
+		}
+		return d.Write(os.Stdout, aliases[0], aliases[1])
+	})
+}
+
+// queryResult runs the query, reading all the rows of its result.
+func queryResult(ctx context.Context, db *sql.DB, query string) (*resultdiff.Result, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	res := &resultdiff.Result{Columns: cols}
+	for rows.Next() {
+		row := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range row {
+			ptrs[i] = &row[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		res.Rows = append(res.Rows, row)
+	}
+	return res, rows.Err()
+}
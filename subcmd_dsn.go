@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/pkg/config"
+)
+
+func init() {
+	var alias, tool string
+	var withPassword bool
+	cmd := subcmds.Command("dsn", "print the connection info of a database alias for an external tool")
+	cmd.Arg("alias", "alias of the database").Required().StringVar(&alias)
+	cmd.Flag("for", "tool: pgcli, datagrip, jdbc or sqlalchemy").Required().EnumVar(&tool, config.ToolFormats...)
+	cmd.Flag("with-password", "include the password (redacted by default)").BoolVar(&withPassword)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		s, err := cfg.ToolDSN(tool, alias, subcmdArgs.Role, withPassword)
+		if err != nil {
+			return err
+		}
+		fmt.Println(s)
+		return nil
+	})
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/usql/dump"
+)
+
+func init() {
+	var alias, out string
+	var tables []string
+	var portable bool
+	opts := dump.Options{}
+	cmd := subcmds.Command("dump", "dump a database as SQL")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("schema-only", "dump only the table definitions").BoolVar(&opts.SchemaOnly)
+	cmd.Flag("data-only", "dump only the table rows").BoolVar(&opts.DataOnly)
+	cmd.Flag("tables", "tables to dump, comma separated (default all)").PlaceHolder("TABLE,...").StringsVar(&tables)
+	cmd.Flag("out", "output file (default stdout)").PlaceHolder("FILE").Short('o').StringVar(&out)
+	cmd.Flag("portable", "always write portable SQL, instead of using pg_dump or mysqldump").BoolVar(&portable)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		if opts.SchemaOnly && opts.DataOnly {
+			return errors.New("--schema-only and --data-only cannot be used together")
+		}
+		opts.Tables = splitList(tables)
+		var w io.Writer = os.Stdout
+		if out != "" {
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		// the progress is not reported over the dump written to the terminal
+		if out != "" || !isatty.IsTerminal(os.Stdout.Fd()) {
+			opts.Progress = newProgress("dump " + alias)
+			defer opts.Progress.Done()
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		opener := newOpener(cfg)
+		if !portable {
+			u, err := opener.URL(alias, subcmdArgs.Role)
+			if err != nil {
+				return err
+			}
+			if cmd := dump.Native(ctx, u, opts); cmd != nil {
+				cmd.Stdout, cmd.Stderr = opts.Progress.Writer(w), os.Stderr
+				if err := cmd.Run(); err != nil {
+					return fmt.Errorf("%s: %w", cmd.Args[0], err)
+				}
+				return nil
+			}
+		}
+		u, db, err := opener.Open(ctx, alias, subcmdArgs.Role)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return dump.SQL(ctx, w, u, db, opts)
+	})
+}
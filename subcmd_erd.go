@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/erd"
+	"github.com/xo/usql/jsonout"
+)
+
+func init() {
+	var alias, schema, format string
+	var offline bool
+	cmd := subcmds.Command("erd", "write the entity-relationship diagram of the tables of a database")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("schema", "diagram the tables of the schema").PlaceHolder("SCHEMA").StringVar(&schema)
+	cmd.Flag("format", "diagram format: mermaid or plantuml").Default(erd.FormatMermaid).EnumVar(&format, erd.Formats...)
+	cmd.Flag("offline", "diagram the tables of the last metadata snapshot of the database (see usql offline sync), without connecting").BoolVar(&offline)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		tables, err := erdTables(alias, offline)
+		if err != nil {
+			return err
+		}
+		if schema != "" {
+			var res []*dump.Table
+			for _, t := range tables {
+				if strings.EqualFold(t.Schema, schema) {
+					res = append(res, t)
+				}
+			}
+			if len(res) == 0 {
+				return jsonout.WithCode(jsonout.CodeDatabase, fmt.Errorf("no tables in schema %s", schema))
+			}
+			tables = res
+		}
+		return erd.Write(os.Stdout, format, tables)
+	})
+}
+
+// erdTables reads the definitions of the tables of the database alias, or
+// loads them from its last metadata snapshot when offline.
+func erdTables(alias string, offline bool) ([]*dump.Table, error) {
+	if offline {
+		s, err := loadSnapshot(alias)
+		if err != nil {
+			return nil, err
+		}
+		return s.Definitions, nil
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	u, db, err := openAlias(ctx, subcmdArgs, alias)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	tables, err := dump.ReadTables(ctx, u, db, nil)
+	if err != nil {
+		return nil, jsonout.WithCode(jsonout.CodeDatabase, err)
+	}
+	return tables, nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/transfer"
+)
+
+func init() {
+	var alias, dir string
+	var key []string
+	opts := transfer.ExportOptions{}
+	cmd := subcmds.Command("export", "export a table as checksummed CSV chunks, resumable when interrupted")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("table", "table to export").Required().StringVar(&opts.Table)
+	cmd.Flag("dir", "directory of the chunks and their manifest").Required().PlaceHolder("DIR").StringVar(&dir)
+	cmd.Flag("key", "key columns the rows are ordered by, comma separated (default primary key)").PlaceHolder("COLUMN,...").StringsVar(&key)
+	cmd.Flag("chunk-rows", "rows per chunk").Default(fmt.Sprint(transfer.DefaultChunkRows)).IntVar(&opts.ChunkRows)
+	cmd.Flag("resume", "resume an interrupted export, after its last valid chunk").BoolVar(&opts.Resume)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		opts.Key = splitList(key)
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		opts.Progress = newProgress("export " + opts.Table)
+		m, err := transfer.Export(ctx, u, db, dir, opts)
+		opts.Progress.Done()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stdout, "EXPORT %d\n", m.Rows())
+		return nil
+	})
+}
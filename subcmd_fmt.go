@@ -0,0 +1,50 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/sqlfmt"
+)
+
+func init() {
+	var files []string
+	var dialect, keywordCase string
+	var write bool
+	opts := sqlfmt.Options{}
+	cmd := subcmds.Command("fmt", "format SQL files")
+	cmd.Arg("files", "SQL files to format (- for stdin)").Default("-").StringsVar(&files)
+	cmd.Flag("dialect", "SQL dialect of the files, as a driver or scheme name (ie, postgres, mysql, sqlserver)").StringVar(&dialect)
+	cmd.Flag("keyword-case", "case of keywords (upper, lower, preserve)").Default("upper").StringVar(&keywordCase)
+	cmd.Flag("indent", "spaces of an indentation level (0 for tabs)").Default(fmt.Sprint(sqlfmt.DefaultIndent)).IntVar(&opts.Indent)
+	cmd.Flag("write", "write the formatted SQL to the files, instead of stdout").Short('w').BoolVar(&write)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		var err error
+		if opts.Case, err = sqlfmt.ParseCase(keywordCase); err != nil {
+			return err
+		}
+		opts.Dialect = sqlDialect(dialect)
+		for _, file := range files {
+			if file == "-" && write {
+				return fmt.Errorf("stdin can't be written")
+			}
+			buf, err := readFile(file)
+			if err != nil {
+				return err
+			}
+			s, err := sqlfmt.Format(string(buf), opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			if write {
+				if err := os.WriteFile(file, []byte(s), 0o644); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Print(s)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/history"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/pkg/config"
+)
+
+func init() {
+	var pattern, alias, osUser string
+	var limit int
+	cmd := subcmds.Command("history", "search the query history shared by the team in the history_backend")
+	cmd.Arg("pattern", "text of the statements, case insensitive").StringVar(&pattern)
+	cmd.Flag("alias", "search the statements executed on the database alias").PlaceHolder("ALIAS").StringVar(&alias)
+	cmd.Flag("user", "search the statements executed by the OS user").PlaceHolder("USER").StringVar(&osUser)
+	cmd.Flag("limit", "maximum number of statements").Default("50").IntVar(&limit)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		if cfg.HistoryBackend == nil {
+			return jsonout.WithCode(jsonout.CodeConfig, fmt.Errorf("no history_backend in config file %s", cfg.Path))
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		s, err := openHistory(ctx, cfg.HistoryBackend)
+		if err != nil {
+			return jsonout.WithCode(jsonout.CodeConnection, err)
+		}
+		defer s.Close()
+		entries, err := s.Search(ctx, history.Filter{Pattern: pattern, Alias: alias, OSUser: osUser, Limit: limit})
+		if err != nil {
+			return jsonout.WithCode(jsonout.CodeDatabase, err)
+		}
+		if subcmdArgs.JSON {
+			for _, e := range entries {
+				if err := jsonout.Write(os.Stdout, e); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+		for _, e := range entries {
+			stmt := strings.Join(strings.Fields(e.Statement), " ")
+			if e.Error != "" {
+				stmt += " (error)"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", e.Time.Local().Format("2006-01-02 15:04:05"), e.OSUser, e.Alias, stmt)
+		}
+		return w.Flush()
+	})
+}
+
+// openHistory opens the shared query history of the history_backend.
+func openHistory(ctx context.Context, hc *config.HistoryBackendConfig) (*history.Store, error) {
+	if hc == nil || hc.URL == "" {
+		return nil, fmt.Errorf("no history_backend url is configured")
+	}
+	return history.Open(ctx, hc.URL, hc.Table)
+}
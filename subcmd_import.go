@@ -0,0 +1,252 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+
+	"github.com/alecthomas/kingpin/v2"
+	isatty "github.com/mattn/go-isatty"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/importer"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/transfer"
+)
+
+func init() {
+	var alias, table, format, delimiter, quote, header, dir string
+	var files []string
+	var parallel, sample int
+	var resume, infer, yes bool
+	opts := importer.Options{}
+	cmd := subcmds.Command("import", "import CSV/TSV and JSON files into a table")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("table", "target table, with an optional column list (ie, TABLE(A,B))").StringVar(&table)
+	cmd.Flag("file", "files to import, repeatable (default stdin)").StringsVar(&files)
+	cmd.Flag("format", "file format (csv, json; default from the file extension)").StringVar(&format)
+	cmd.Flag("delimiter", `field delimiter (default "," or tab for .tsv files)`).StringVar(&delimiter)
+	cmd.Flag("quote", "quote character").Default(`"`).StringVar(&quote)
+	cmd.Flag("header", "whether the first record is a header (auto, on, off)").Default("auto").StringVar(&header)
+	cmd.Flag("null", "string representing a NULL value").StringVar(&opts.Null)
+	cmd.Flag("batch-size", "records per insert statement, when not bulk loading").Default(fmt.Sprint(importer.DefaultBatchSize)).IntVar(&opts.BatchSize)
+	cmd.Flag("no-bulk", "disable the database's native bulk load (COPY, LOAD DATA)").BoolVar(&opts.NoBulk)
+	cmd.Flag("parallel", "files imported at once, limited by the max_connections of the alias").Default("1").IntVar(&parallel)
+	cmd.Flag("dir", "directory of the chunks of usql export to import, instead of files").PlaceHolder("DIR").StringVar(&dir)
+	cmd.Flag("resume", "resume an interrupted import of --dir, skipping its imported chunks").BoolVar(&resume)
+	cmd.Flag("infer-schema", "create the table, with the columns inferred from the records of the first file").BoolVar(&infer)
+	cmd.Flag("sample", "records sampled to infer the schema").Default(fmt.Sprint(importer.DefaultSample)).IntVar(&sample)
+	cmd.Flag("yes", "create the inferred table without asking to confirm it").Short('y').BoolVar(&yes)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		opts.Table, opts.Columns = importer.ParseTable(table)
+		switch {
+		case dir != "" && len(files) != 0:
+			return fmt.Errorf("--file and --dir cannot be used together")
+		case dir != "" && infer:
+			return fmt.Errorf("--infer-schema and --dir cannot be used together")
+		case dir != "":
+			return importChunks(alias, dir, resume, opts)
+		case resume:
+			return fmt.Errorf("--resume needs --dir")
+		case opts.Table == "":
+			return fmt.Errorf("missing --table")
+		case len(files) == 0:
+			files = []string{"-"}
+		}
+		var err error
+		if opts.Quote, err = importer.ParseDelimiter(quote); err != nil {
+			return err
+		}
+		if opts.Header, err = importer.ParseHeader(header); err != nil {
+			return err
+		}
+		var fmts []importer.Format
+		for _, file := range files {
+			f := importer.DefaultFormat(file)
+			if format != "" {
+				if f, err = importer.ParseFormat(format); err != nil {
+					return err
+				}
+			}
+			fmts = append(fmts, f)
+		}
+		var delim rune
+		if delimiter != "" {
+			if delim, err = importer.ParseDelimiter(delimiter); err != nil {
+				return err
+			}
+		}
+		for _, file := range files {
+			if file == "-" && len(files) != 1 {
+				return fmt.Errorf("stdin can't be imported with other files")
+			}
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		// the records read from stdin to infer the schema, imported first
+		var stdin bytes.Buffer
+		if infer {
+			opts.Format, opts.Delimiter = fmts[0], delim
+			if delim == 0 {
+				opts.Delimiter = importer.DefaultDelimiter(files[0])
+			}
+			if opts, err = createInferred(ctx, u, db, files[0], &stdin, sample, yes, opts); err != nil {
+				return err
+			}
+		}
+		pool, err := newPool(ctx, subcmdArgs, parallel)
+		if err != nil {
+			return err
+		}
+		// the progress of all the files, estimated from their size
+		opts.Progress = newProgress("import " + opts.Table)
+		var size int64
+		for _, file := range files {
+			fi, err := os.Stat(file)
+			if file == "-" || err != nil {
+				size = 0
+				break
+			}
+			size += fi.Size()
+		}
+		opts.Progress.SetSize(size)
+		var total int64
+		for i, file := range files {
+			file, opts := file, opts
+			if opts.Format, opts.Delimiter = fmts[i], delim; delim == 0 {
+				opts.Delimiter = importer.DefaultDelimiter(file)
+			}
+			pool.Go(func(ctx context.Context) error {
+				r := io.MultiReader(&stdin, os.Stdin)
+				if file != "-" {
+					f, err := os.Open(file)
+					if err != nil {
+						return err
+					}
+					defer f.Close()
+					r = f
+				}
+				n, err := importer.Import(ctx, u, db, r, opts)
+				if err != nil {
+					if len(files) != 1 {
+						return fmt.Errorf("%s: %w", file, err)
+					}
+					return err
+				}
+				atomic.AddInt64(&total, n)
+				return nil
+			}, alias)
+		}
+		err = pool.Wait()
+		opts.Progress.Done()
+		switch {
+		case err != nil && total == 0:
+			return err
+		case err != nil:
+			// some files were imported
+			fmt.Fprintf(os.Stdout, "IMPORT %d\n", total)
+			return jsonout.WithCode(jsonout.CodePartialFailure, err)
+		}
+		fmt.Fprintf(os.Stdout, "IMPORT %d\n", total)
+		return nil
+	})
+}
+
+// importChunks imports the chunks of the export in the directory, recording
+// the imported chunks in a journal of the directory named after the alias.
+func importChunks(alias, dir string, resume bool, opts importer.Options) error {
+	m, err := transfer.ReadManifest(dir)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer cancel()
+	u, db, err := openAlias(ctx, subcmdArgs, alias)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	name := opts.Table
+	if name == "" {
+		name = m.Table
+	}
+	opts.Progress = newProgress("import " + name)
+	n, err := transfer.Import(ctx, u, db, dir, transfer.ImportOptions{
+		Options: opts,
+		Journal: filepath.Join(dir, alias+".imported"),
+		Resume:  resume,
+	})
+	opts.Progress.Done()
+	switch {
+	case err != nil && n == 0:
+		return err
+	case err != nil:
+		// some chunks were imported
+		fmt.Fprintf(os.Stdout, "IMPORT %d\n", n)
+		return jsonout.WithCode(jsonout.CodePartialFailure, err)
+	}
+	fmt.Fprintf(os.Stdout, "IMPORT %d\n", n)
+	return nil
+}
+
+// createInferred infers the schema of the table from the records of the file,
+// and creates the table once confirmed, returning the options importing the
+// file into the columns of the table. The records read from stdin are kept in
+// buf.
+func createInferred(ctx context.Context, u *dburl.URL, db *sql.DB, file string, buf *bytes.Buffer, sample int, yes bool, opts importer.Options) (importer.Options, error) {
+	if err := importer.CheckTable(opts.Table); err != nil {
+		return opts, err
+	}
+	var r io.Reader = io.TeeReader(os.Stdin, buf)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return opts, err
+		}
+		defer f.Close()
+		r = f
+	}
+	s, err := importer.Infer(r, drivers.Caps(u).Dialect, opts, sample)
+	if err != nil {
+		return opts, fmt.Errorf("%s: %w", file, err)
+	}
+	stmt := s.CreateTable(opts.Table)
+	fmt.Fprintln(os.Stdout, stmt+";")
+	if !yes {
+		// stdin is read for the records
+		if file == "-" || !isatty.IsTerminal(os.Stdin.Fd()) || !isatty.IsTerminal(os.Stdout.Fd()) {
+			return opts, fmt.Errorf("the inferred table needs to be confirmed, pass --yes to create it without asking")
+		}
+		fmt.Fprintf(os.Stdout, "Create table %s? (y/N) ", opts.Table)
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return opts, err
+		}
+		switch strings.ToLower(strings.TrimSpace(line)) {
+		case "y", "yes":
+		default:
+			return opts, fmt.Errorf("import canceled")
+		}
+	}
+	if _, err := db.ExecContext(ctx, stmt); err != nil {
+		return opts, err
+	}
+	opts.Columns, opts.Keys = s.ColumnNames(), s.Keys
+	if opts.Header = importer.HeaderOff; s.Header {
+		opts.Header = importer.HeaderOn
+	}
+	return opts, nil
+}
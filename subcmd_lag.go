@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/replication"
+)
+
+// hostLag is the JSON object of the replication status of a host of a
+// database alias.
+type hostLag struct {
+	Alias string `json:"alias"`
+	// Endpoint is host or reader.
+	Endpoint string             `json:"endpoint"`
+	Host     string             `json:"host,omitempty"`
+	Role     string             `json:"role,omitempty"`
+	LagMS    *int64             `json:"lag_ms,omitempty"`
+	Error    *jsonout.ErrorInfo `json:"error,omitempty"`
+}
+
+func init() {
+	var aliases []string
+	var tag string
+	cmd := subcmds.Command("lag", "report the replication lag of the hosts and reader hosts of databases")
+	cmd.Arg("alias", "database aliases from the config file").StringsVar(&aliases)
+	cmd.Flag("tag", "report the databases with the tag").PlaceHolder("TAG").StringVar(&tag)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		if tag != "" {
+			tagged := cfg.Tagged(tag)
+			if len(tagged) == 0 {
+				return jsonout.WithCode(jsonout.CodeConfig, fmt.Errorf("no databases tagged %s", tag))
+			}
+			aliases = append(aliases, tagged...)
+		}
+		if len(aliases) == 0 {
+			return jsonout.WithCode(jsonout.CodeMissingArgument, errors.New("database aliases or --tag required"))
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		var res []hostLag
+		var failed int
+		for _, alias := range aliases {
+			dbConfig, err := cfg.Database(alias)
+			if err != nil {
+				return jsonout.WithCode(jsonout.CodeConfig, err)
+			}
+			endpoints := []string{"host"}
+			if dbConfig.ReaderHost != "" || strings.HasPrefix(dbConfig.DbType, "aurora-") {
+				endpoints = append(endpoints, "reader")
+			}
+			for _, endpoint := range endpoints {
+				r := readLag(ctx, cfg, alias, endpoint)
+				if r.Error != nil {
+					failed++
+				}
+				res = append(res, r)
+			}
+		}
+		if err := writeLag(res); err != nil {
+			return err
+		}
+		code := jsonout.CodeConnection
+		if failed < len(res) {
+			code = jsonout.CodePartialFailure
+		}
+		if failed != 0 {
+			return jsonout.WithCode(code, fmt.Errorf("%d of %d hosts failed", failed, len(res)))
+		}
+		return nil
+	})
+}
+
+// readLag reads the replication status of the host or reader host of the
+// database alias.
+func readLag(ctx context.Context, cfg *config.Config, alias, endpoint string) hostLag {
+	r := hostLag{Alias: alias, Endpoint: endpoint}
+	open := newOpener(cfg).Open
+	if endpoint == "reader" {
+		open = newOpener(cfg).OpenReader
+	}
+	err := func() error {
+		u, db, err := open(ctx, alias, subcmdArgs.Role)
+		if err != nil {
+			return jsonout.WithCode(jsonout.CodeConnection, err)
+		}
+		defer db.Close()
+		r.Host = u.Hostname()
+		return hostStatus(ctx, u, db, &r)
+	}()
+	if err != nil {
+		r.Error = jsonout.Info(err)
+	}
+	return r
+}
+
+// hostStatus reads the replication status of the host into r.
+func hostStatus(ctx context.Context, u *dburl.URL, db *sql.DB, r *hostLag) error {
+	if !replication.Supported(u) {
+		return fmt.Errorf("the replication status of %s databases is not supported", u.Unaliased)
+	}
+	st, err := replication.Read(ctx, u, db)
+	if err != nil {
+		return jsonout.WithCode(jsonout.CodeDatabase, err)
+	}
+	r.Role = "primary"
+	if st.Replica {
+		r.Role = "replica"
+		if st.Lag >= 0 {
+			ms := st.Lag.Milliseconds()
+			r.LagMS = &ms
+		}
+	}
+	return nil
+}
+
+// writeLag writes the replication status of the hosts, as JSON objects with
+// --json.
+func writeLag(res []hostLag) error {
+	if subcmdArgs.JSON {
+		for _, r := range res {
+			if err := jsonout.Write(os.Stdout, r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "alias\tendpoint\thost\trole\tlag")
+	for _, r := range res {
+		var lag string
+		switch {
+		case r.Error != nil:
+			lag = "error: " + r.Error.Message
+		case r.LagMS != nil:
+			lag = (time.Duration(*r.LagMS) * time.Millisecond).String()
+		case r.Role == "replica":
+			lag = "unknown (replication stopped?)"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", r.Alias, r.Endpoint, r.Host, r.Role, lag)
+	}
+	return w.Flush()
+}
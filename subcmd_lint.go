@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/sqllint"
+)
+
+func init() {
+	var files, disable []string
+	var dbType, format string
+	var strict bool
+	cmd := subcmds.Command("lint", "check SQL files for common mistakes")
+	cmd.Arg("files", "SQL files to check (- for stdin)").Default("-").StringsVar(&files)
+	cmd.Flag("db-type", "SQL dialect of the files, as a driver or scheme name (ie, postgres, mysql, sqlserver)").StringVar(&dbType)
+	cmd.Flag("format", "output format (text, json)").Default("text").EnumVar(&format, "text", "json")
+	cmd.Flag("disable", "rules not to check, comma separated").PlaceHolder("RULE,...").StringsVar(&disable)
+	cmd.Flag("strict", "fail on warnings, not only on errors").BoolVar(&strict)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		opts := sqllint.Options{
+			Dialect: sqlDialect(dbType),
+			Disable: splitList(disable),
+		}
+		for _, rule := range opts.Disable {
+			if _, ok := sqllint.Rules[rule]; !ok {
+				return fmt.Errorf("unknown rule %q", rule)
+			}
+		}
+		findings := []sqllint.Finding{}
+		for _, file := range files {
+			buf, err := readFile(file)
+			if err != nil {
+				return err
+			}
+			res, err := sqllint.Lint(string(buf), opts)
+			if err != nil {
+				return fmt.Errorf("%s: %w", file, err)
+			}
+			for _, f := range res {
+				f.File = file
+				findings = append(findings, f)
+			}
+		}
+		if format == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(findings); err != nil {
+				return err
+			}
+		} else {
+			for _, f := range findings {
+				fmt.Println(f)
+			}
+		}
+		return lintErr(findings, strict)
+	})
+}
+
+// lintErr returns an error counting the errors and warnings of the findings
+// when there are errors, or any findings when strict.
+func lintErr(findings []sqllint.Finding, strict bool) error {
+	var errs, warnings int
+	for _, f := range findings {
+		if f.Severity == sqllint.Error {
+			errs++
+		} else {
+			warnings++
+		}
+	}
+	if errs == 0 && (!strict || warnings == 0) {
+		return nil
+	}
+	return errors.New(plural(errs, "error") + ", " + plural(warnings, "warning"))
+}
+
+// plural returns the count n of noun, in the plural unless 1.
+func plural(n int, noun string) string {
+	if n != 1 {
+		noun += "s"
+	}
+	return fmt.Sprintf("%d %s", n, noun)
+}
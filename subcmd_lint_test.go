@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/xo/usql/sqllint"
+)
+
+func TestLintErr(t *testing.T) {
+	e, w := sqllint.Finding{Severity: sqllint.Error}, sqllint.Finding{Severity: sqllint.Warning}
+	tests := []struct {
+		findings []sqllint.Finding
+		strict   bool
+		exp      string
+	}{
+		{nil, true, ""},
+		{[]sqllint.Finding{w, w}, false, ""},
+		{[]sqllint.Finding{w, w}, true, "0 errors, 2 warnings"},
+		{[]sqllint.Finding{e}, false, "1 error, 0 warnings"},
+		{[]sqllint.Finding{e, w, e, w}, false, "2 errors, 2 warnings"},
+		{[]sqllint.Finding{e, w}, true, "1 error, 1 warning"},
+	}
+	for i, test := range tests {
+		err := lintErr(test.findings, test.strict)
+		switch {
+		case test.exp == "" && err != nil:
+			t.Errorf("test %d expected no error, got: %v", i, err)
+		case test.exp != "" && (err == nil || err.Error() != test.exp):
+			t.Errorf("test %d expected error %q, got: %v", i, test.exp, err)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kingpin/v2"
+)
+
+func init() {
+	var pattern string
+	var roles bool
+	cmd := subcmds.Command("list", "list the database aliases of the config file")
+	cmd.Arg("pattern", "database alias, or pattern of aliases with % or * wildcards (default all the aliases)").StringVar(&pattern)
+	cmd.Flag("roles", "list the roles of the aliases, with the role chosen for --role, or else the default or first role").BoolVar(&roles)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		aliases := cfg.Aliases()
+		if pattern != "" {
+			if aliases = cfg.Match(pattern); len(aliases) == 0 {
+				return fmt.Errorf("no database alias matches %s", pattern)
+			}
+		}
+		return printAliases(os.Stdout, cfg, aliases, roles, subcmdArgs.Role, subcmdArgs.JSON)
+	})
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/migrate"
+)
+
+// migratorRole is the role migrations run under when no role is passed and
+// the database has credentials for it.
+const migratorRole = "migrator"
+
+func init() {
+	var alias string
+	opts := migrate.Options{}
+	cmd := subcmds.Command("migrate", "apply versioned SQL migrations")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("dir", "migrations directory").Default("migrations").StringVar(&opts.Dir)
+	cmd.Flag("to", "target version (default latest)").Default("-1").Int64Var(&opts.To)
+	cmd.Flag("table", "migrations tracking table").Default(migrate.DefaultTable).StringVar(&opts.Table)
+	cmd.Flag("dry-run", "display the statements instead of executing them").BoolVar(&opts.DryRun)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		args := *subcmdArgs
+		if args.Role == "" {
+			if cfg, err := loadConfig(&args); err == nil {
+				if dbConfig, err := cfg.Database(alias); err == nil {
+					if _, err := dbConfig.Role(migratorRole); err == nil {
+						args.Role = migratorRole
+					}
+				}
+			}
+		}
+		u, db, err := openAlias(ctx, &args, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return migrate.Run(ctx, os.Stdout, u, db, opts)
+	})
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/offline"
+)
+
+// offlineCmd is the offline subcommand.
+var offlineCmd = subcmds.Command("offline", "manage the metadata snapshots of usql --offline")
+
+// syncResult is the JSON object of the metadata snapshot of a database alias.
+type syncResult struct {
+	Alias  string             `json:"alias"`
+	OK     bool               `json:"ok"`
+	Tables int                `json:"tables,omitempty"`
+	Error  *jsonout.ErrorInfo `json:"error,omitempty"`
+}
+
+func init() {
+	var aliases []string
+	cmd := offlineCmd.Command("sync", "take the metadata snapshots of database aliases, replacing their last snapshots")
+	cmd.Arg("alias", "database aliases from the config file (default all the aliases)").StringsVar(&aliases)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		if len(aliases) == 0 {
+			cfg, err := loadConfig(subcmdArgs)
+			if err != nil {
+				return err
+			}
+			aliases = cfg.Aliases()
+		}
+		st, err := offline.New()
+		if err != nil {
+			return err
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		var failed int
+		for _, alias := range aliases {
+			res := syncSnapshot(ctx, st, alias)
+			switch {
+			case subcmdArgs.JSON:
+				if err := jsonout.Write(os.Stdout, res); err != nil {
+					return err
+				}
+			case res.OK:
+				fmt.Fprintf(os.Stdout, "%s: ok (%d tables)\n", alias, res.Tables)
+			default:
+				fmt.Fprintf(os.Stdout, "%s: error: %s\n", alias, res.Error.Message)
+			}
+			if !res.OK {
+				failed++
+			}
+		}
+		code := jsonout.CodeError
+		if failed < len(aliases) {
+			code = jsonout.CodePartialFailure
+		}
+		if failed != 0 {
+			return jsonout.WithCode(code, fmt.Errorf("%d of %d metadata snapshots failed", failed, len(aliases)))
+		}
+		return nil
+	})
+}
+
+func init() {
+	cmd := offlineCmd.Command("list", "list the metadata snapshots, with the time they were taken")
+	cmd.Action(func(*kingpin.ParseContext) error {
+		st, err := offline.New()
+		if err != nil {
+			return err
+		}
+		snapshots, err := st.List()
+		if err != nil {
+			return err
+		}
+		for _, s := range snapshots {
+			if subcmdArgs.JSON {
+				if err := jsonout.Write(os.Stdout, s); err != nil {
+					return err
+				}
+				continue
+			}
+			fmt.Fprintf(os.Stdout, "%s: %s, taken %s\n", s.Alias, s.URL, s.Taken.Format("2006-01-02 15:04"))
+		}
+		return nil
+	})
+}
+
+// syncSnapshot connects to the database alias, and saves the snapshot of its
+// metadata.
+func syncSnapshot(ctx context.Context, st *offline.Store, alias string) syncResult {
+	u, db, err := openAlias(ctx, subcmdArgs, alias)
+	if err != nil {
+		return syncResult{Alias: alias, Error: jsonout.Info(err)}
+	}
+	defer db.Close()
+	s, err := offline.Take(ctx, alias, u, db)
+	if err == nil {
+		err = st.Save(s)
+	}
+	if err != nil {
+		return syncResult{Alias: alias, Error: jsonout.Info(jsonout.WithCode(jsonout.CodeDatabase, err))}
+	}
+	return syncResult{Alias: alias, OK: true, Tables: len(s.Tables)}
+}
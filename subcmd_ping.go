@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/jsonout"
+)
+
+// pingResult is the JSON object of the ping of a database alias.
+type pingResult struct {
+	Alias     string             `json:"alias"`
+	OK        bool               `json:"ok"`
+	LatencyMS int64              `json:"latency_ms,omitempty"`
+	Error     *jsonout.ErrorInfo `json:"error,omitempty"`
+}
+
+func init() {
+	var aliases []string
+	cmd := subcmds.Command("ping", "check the connections to database aliases, reporting their latency")
+	cmd.Arg("alias", "database aliases from the config file (default all the aliases)").StringsVar(&aliases)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		if len(aliases) == 0 {
+			cfg, err := loadConfig(subcmdArgs)
+			if err != nil {
+				return err
+			}
+			aliases = cfg.Aliases()
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		var failed int
+		for _, alias := range aliases {
+			res := ping(ctx, alias)
+			switch {
+			case subcmdArgs.JSON:
+				if err := jsonout.Write(os.Stdout, res); err != nil {
+					return err
+				}
+			case res.OK:
+				fmt.Fprintf(os.Stdout, "%s: ok (%dms)\n", alias, res.LatencyMS)
+			default:
+				fmt.Fprintf(os.Stdout, "%s: error: %s\n", alias, res.Error.Message)
+			}
+			if !res.OK {
+				failed++
+			}
+		}
+		code := jsonout.CodeConnection
+		if failed < len(aliases) {
+			code = jsonout.CodePartialFailure
+		}
+		if failed != 0 {
+			return jsonout.WithCode(code, fmt.Errorf("%d of %d databases are unreachable", failed, len(aliases)))
+		}
+		return nil
+	})
+}
+
+// ping connects to the database alias and pings it.
+func ping(ctx context.Context, alias string) pingResult {
+	start := time.Now()
+	_, db, err := openAlias(ctx, subcmdArgs, alias)
+	if err == nil {
+		err = db.PingContext(ctx)
+		db.Close()
+	}
+	if err != nil {
+		return pingResult{Alias: alias, Error: jsonout.Info(jsonout.WithCode(jsonout.CodeConnection, err))}
+	}
+	return pingResult{Alias: alias, OK: true, LatencyMS: time.Since(start).Milliseconds()}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/profile"
+)
+
+// columnProfile is the JSON object of the profile of a column.
+type columnProfile struct {
+	Table    string          `json:"table"`
+	Column   string          `json:"column"`
+	Type     string          `json:"type"`
+	Rows     int64           `json:"rows"`
+	Nulls    int64           `json:"nulls"`
+	NullRate float64         `json:"null_rate"`
+	Distinct int64           `json:"distinct"`
+	Min      string          `json:"min,omitempty"`
+	Max      string          `json:"max,omitempty"`
+	Top      []profile.Value `json:"top"`
+}
+
+func init() {
+	var alias string
+	var tables []string
+	var sample, top int
+	cmd := subcmds.Command("profile", "profile the data of tables from a sample of their rows")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("table", "tables to profile, comma separated").Required().PlaceHolder("TABLE,...").StringsVar(&tables)
+	cmd.Flag("sample", "number of sampled rows").Default(fmt.Sprint(profile.DefaultSample)).IntVar(&sample)
+	cmd.Flag("top", "number of most frequent values").Default("3").IntVar(&top)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		patterns, err := profileMask(alias)
+		if err != nil {
+			return err
+		}
+		for i, table := range splitList(tables) {
+			p, err := profile.Read(ctx, u, db, table, sample, top)
+			if err != nil {
+				return jsonout.WithCode(jsonout.CodeDatabase, fmt.Errorf("%s: %w", table, err))
+			}
+			p.Mask(patterns)
+			if !subcmdArgs.JSON {
+				if i != 0 {
+					fmt.Fprintln(os.Stdout)
+				}
+				if err := p.Write(os.Stdout); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, c := range p.Columns {
+				if err := jsonout.Write(os.Stdout, columnProfile{p.Table, c.Name, c.Type, p.Rows, c.Nulls, c.NullRate(p.Rows), c.Distinct, c.Min, c.Max, c.Top}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// profileMask returns the mask patterns of the columns of the database alias
+// whose values are masked in profiles.
+func profileMask(alias string) ([]string, error) {
+	cfg, err := aliasConfig(subcmdArgs, alias)
+	if err != nil || cfg.Databases[alias] == nil {
+		return nil, err
+	}
+	return cfg.Databases[alias].MaskPatterns(subcmdArgs.Role, false)
+}
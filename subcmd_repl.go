@@ -0,0 +1,41 @@
+package main
+
+import (
+	"io"
+	"os/user"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/env"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/rline"
+)
+
+func init() {
+	var pattern, name string
+	cmd := subcmds.Command("repl", "start an interactive session on a database alias, picked interactively when several match")
+	cmd.Arg("pattern", "database alias, or pattern of aliases with % or * wildcards (default all the aliases)").StringVar(&pattern)
+	cmd.Flag("session", "resume the named session saved with \\session save").PlaceHolder("NAME").StringVar(&name)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cur, err := user.Current()
+		if err != nil {
+			return err
+		}
+		args := *subcmdArgs
+		if name != "" {
+			s, err := env.LoadSession(env.SessionFile(cur, name))
+			if err != nil {
+				return jsonout.WithCode(jsonout.CodeConfig, err)
+			}
+			args.DB, args.DSN, args.session = s.Alias, s.URL, s
+			if args.Role == "" {
+				args.Role = s.Role
+			}
+		} else if args.DB, err = selectAlias(subcmdArgs, pattern); err != nil {
+			return err
+		}
+		if err := run(&args, cur); err != nil && err != io.EOF && err != rline.ErrInterrupt {
+			return err
+		}
+		return nil
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/session"
+)
+
+func init() {
+	var path, alias string
+	var opts session.Options
+	cmd := subcmds.Command("replay", "replay the statements of a session recorded with --record")
+	cmd.Arg("file", "session file").Required().StringVar(&path)
+	cmd.Flag("db", "database alias from the config file to replay the statements on").Required().StringVar(&alias)
+	cmd.Flag("continue-on-error", "continue with the next statements when a statement fails").BoolVar(&opts.ContinueOnError)
+	cmd.Flag("queries-only", "skip the statements that don't return rows").BoolVar(&opts.QueriesOnly)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		s, err := session.Load(path)
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		dbConfig, err := cfg.Database(alias)
+		if err != nil {
+			return err
+		}
+		p, err := rolePolicy(dbConfig, subcmdArgs.Role)
+		if err != nil {
+			return err
+		}
+		opts.Check = func(u *dburl.URL, sqlstr string) error {
+			return checkPolicy(p, u, sqlstr)
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := newOpener(cfg).Open(ctx, alias, subcmdArgs.Role)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return session.Replay(ctx, os.Stdout, u, db, s, opts)
+	})
+}
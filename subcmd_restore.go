@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/snapshot"
+)
+
+func init() {
+	var alias, file string
+	var tables []string
+	opts := snapshot.RestoreOptions{}
+	cmd := subcmds.Command("restore", "restore the tables of a snapshot")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Arg("file", "snapshot file of usql snapshot (- for stdin)").Required().StringVar(&file)
+	cmd.Flag("tables", "tables to restore, comma separated (default all)").PlaceHolder("TABLE,...").StringsVar(&tables)
+	cmd.Flag("replace", "drop the existing tables before restoring them").BoolVar(&opts.Replace)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		opts.Tables = splitList(tables)
+		var r io.Reader = os.Stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		opts.Progress = newProgress("restore " + alias)
+		res, err := snapshot.Restore(ctx, r, u, db, opts)
+		opts.Progress.Done()
+		for _, r := range res {
+			fmt.Fprintf(os.Stdout, "table %s: %d rows\n", r.Table, r.Rows)
+		}
+		return err
+	})
+}
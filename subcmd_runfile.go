@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/frontmatter"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/rline"
+)
+
+func init() {
+	var file, db, out string
+	var vars []string
+	cmd := subcmds.Command("runfile", "run a SQL file with the database alias, role, timeout, error handling and output of its front matter")
+	cmd.Arg("file", "SQL file, optionally starting with a front matter of directives between --- lines").Required().StringVar(&file)
+	cmd.Flag("db", "database alias from the config file, instead of the db or tag of the front matter").PlaceHolder("ALIAS").StringVar(&db)
+	cmd.Flag("out", "output file, instead of the output of the front matter").Short('o').PlaceHolder("FILE").StringVar(&out)
+	cmd.Flag("set", "set variable NAME to VALUE, after the variables of the front matter").Short('v').PlaceHolder("NAME=VALUE").StringsVar(&vars)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		cur, err := user.Current()
+		if err != nil {
+			return err
+		}
+		buf, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		d, _, err := frontmatter.Parse(buf)
+		switch {
+		case err != nil:
+			return fmt.Errorf("%s: %w", file, err)
+		case d == nil:
+			d = new(frontmatter.Directives)
+		}
+		args := *subcmdArgs
+		args.CommandOrFiles = []CommandOrFile{{Value: file}}
+		if args.DB, err = runfileAlias(&args, file, db, d); err != nil {
+			return err
+		}
+		if args.Role == "" {
+			args.Role = d.Role
+		}
+		args.timeout = d.Timeout
+		// stop at the first error, unless continuing
+		args.Variables = append(args.Variables, "ON_ERROR_STOP=on")
+		if d.OnError == frontmatter.OnErrorContinue {
+			args.Variables = append(args.Variables, "ON_ERROR_STOP=off")
+		}
+		args.Variables = append(append(args.Variables, d.Vars()...), vars...)
+		if d.Format != "" {
+			args.PVariables = append(args.PVariables, "format="+d.Format)
+		}
+		switch {
+		case out != "":
+			args.Out = out
+		case d.Output != "":
+			// relative to the file, so that the file runs the same anywhere
+			args.Out = d.Output
+			if !filepath.IsAbs(args.Out) {
+				args.Out = filepath.Join(filepath.Dir(file), args.Out)
+			}
+			if err := os.MkdirAll(filepath.Dir(args.Out), 0o755); err != nil {
+				return err
+			}
+		}
+		// only the results are written to the output file
+		if args.Out != "" {
+			args.Variables = append(args.Variables, "QUIET=on")
+		}
+		switch err := run(&args, cur); {
+		case err == nil, err == io.EOF, err == rline.ErrInterrupt:
+			return nil
+		case errors.Is(err, context.DeadlineExceeded):
+			return fmt.Errorf("%s: timeout of %s exceeded: %w", file, d.Timeout, err)
+		default:
+			return err
+		}
+	})
+}
+
+// runfileAlias returns the database alias a SQL file runs on: the alias of
+// --db, or of the db or tag of its front matter.
+func runfileAlias(args *Args, file, db string, d *frontmatter.Directives) (string, error) {
+	switch {
+	case db != "":
+		return db, nil
+	case d.DB != "":
+		return d.DB, nil
+	case d.Tag == "":
+		return "", jsonout.WithCode(jsonout.CodeMissingArgument, fmt.Errorf("%s: a database alias is required: set db or tag in its front matter, or use --db", file))
+	}
+	cfg, err := loadConfig(args)
+	if err != nil {
+		return "", err
+	}
+	switch tagged := cfg.Tagged(d.Tag); len(tagged) {
+	case 0:
+		return "", jsonout.WithCode(jsonout.CodeConfig, fmt.Errorf("no databases tagged %s", d.Tag))
+	case 1:
+		return tagged[0], nil
+	default:
+		return "", jsonout.WithCode(jsonout.CodeConfig, fmt.Errorf("%d databases are tagged %s: %s: use --db to pick one", len(tagged), d.Tag, strings.Join(tagged, ", ")))
+	}
+}
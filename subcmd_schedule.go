@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/schedule"
+)
+
+func init() {
+	var path, job, notifier string
+	cmd := subcmds.Command("schedule", "run the statements of a schedules file on their cron expressions")
+	cmd.Arg("file", "schedules file").Default("schedules.yaml").StringVar(&path)
+	cmd.Flag("run", "run the job once and exit").PlaceHolder("JOB").StringVar(&job)
+	cmd.Flag("notify", "notify notifier NAME from config when a job finishes").PlaceHolder("NAME").StringVar(&notifier)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		c, err := schedule.Load(path)
+		if err != nil {
+			return err
+		}
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		// the config file is reloaded when changed, so always use the latest
+		r := &schedule.Runner{
+			Config: c,
+			Open: func(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error) {
+				if role == "" {
+					role = subcmdArgs.Role
+				}
+				cfg, err := loadConfig(subcmdArgs)
+				if err != nil {
+					return nil, nil, err
+				}
+				return newOpener(cfg).Open(ctx, alias, role)
+			},
+			Query: func(name string) (*config.QueryConfig, error) {
+				cfg, err := loadConfig(subcmdArgs)
+				if err != nil {
+					return nil, err
+				}
+				return cfg.Query(name)
+			},
+			Stdout: os.Stdout,
+			Log:    redact.Writer(os.Stderr),
+		}
+		if notifier != "" {
+			if r.Notifier, err = newNotifier(cfg, notifier); err != nil {
+				return err
+			}
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		if job != "" {
+			return r.RunJob(ctx, job, time.Now())
+		}
+		if err := subcmdArgs.configs.Watch(ctx, func(_ *config.Config, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: config file: %v\n", redact.Error(err))
+			}
+		}); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "running %d scheduled jobs from %s\n", len(c.Jobs), c.Path)
+		return r.Run(ctx)
+	})
+}
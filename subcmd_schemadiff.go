@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/schemadiff"
+)
+
+func init() {
+	var aliasA, aliasB string
+	var tables []string
+	var sql bool
+	cmd := subcmds.Command("schemadiff", "compare the tables of two databases")
+	cmd.Arg("aliasA", "database alias from the config file").Required().StringVar(&aliasA)
+	cmd.Arg("aliasB", "database alias to compare with").Required().StringVar(&aliasB)
+	cmd.Flag("tables", "tables to compare, comma separated (default all)").PlaceHolder("TABLE,...").StringsVar(&tables)
+	cmd.Flag("sql", "write DDL statements changing aliasA to match aliasB").BoolVar(&sql)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		names := splitList(tables)
+		var dialect string
+		var schemas [2][]*dump.Table
+		for i, alias := range []string{aliasA, aliasB} {
+			u, db, err := openAlias(ctx, subcmdArgs, alias)
+			if err != nil {
+				return err
+			}
+			schemas[i], err = dump.ReadTables(ctx, u, db, names)
+			db.Close()
+			if err != nil {
+				return err
+			}
+			if i == 0 {
+				dialect = drivers.Caps(u).Dialect
+			}
+		}
+		diffs := schemadiff.Diff(schemas[0], schemas[1])
+		if sql {
+			return schemadiff.WriteSQL(os.Stdout, dialect, diffs)
+		}
+		return schemadiff.Write(os.Stdout, aliasA, aliasB, diffs)
+	})
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/seed"
+)
+
+func init() {
+	var alias, dir string
+	var truncate, upsert bool
+	cmd := subcmds.Command("seed", "load YAML, CSV and SQL fixtures into tables")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("dir", "fixtures directory, with a TABLE.yaml, TABLE.csv or TABLE.sql file per table").Default("seeds").StringVar(&dir)
+	cmd.Flag("truncate", "delete the rows of the tables before loading").BoolVar(&truncate)
+	cmd.Flag("upsert", "update the rows with the same primary key").BoolVar(&upsert)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		mode := seed.ModeInsert
+		switch {
+		case truncate && upsert:
+			return errors.New("--truncate and --upsert cannot be used together")
+		case truncate:
+			mode = seed.ModeTruncate
+		case upsert:
+			mode = seed.ModeUpsert
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		return seed.Run(ctx, os.Stdout, u, db, dir, mode)
+	})
+}
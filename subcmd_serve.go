@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/pkg/config"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/serve"
+)
+
+func init() {
+	var listen, grpcListen string
+	var aliases []string
+	s := &serve.Server{}
+	cmd := subcmds.Command("serve", "serve the database aliases over HTTP and gRPC")
+	cmd.Flag("listen", "address to listen on for HTTP requests (empty to disable)").Default(":8080").StringVar(&listen)
+	cmd.Flag("grpc-listen", "address to listen on for gRPC requests").PlaceHolder(":9090").StringVar(&grpcListen)
+	cmd.Flag("token", "bearer token required to authenticate requests").Envar("USQL_SERVE_TOKEN").StringVar(&s.Token)
+	cmd.Flag("alias", "database aliases to serve, comma separated (default all)").PlaceHolder("ALIAS,...").StringsVar(&aliases)
+	cmd.Flag("allow-write", "allow statements other than queries").BoolVar(&s.AllowWrite)
+	cmd.Flag("max-rows", "maximum number of rows returned by a query").Default(fmt.Sprint(serve.DefaultMaxRows)).IntVar(&s.MaxRows)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		switch {
+		case s.Token == "":
+			return errors.New("a token is required: use --token or USQL_SERVE_TOKEN")
+		case listen == "" && grpcListen == "":
+			return errors.New("nothing to serve: use --listen or --grpc-listen")
+		}
+		cfg, err := loadConfig(subcmdArgs)
+		if err != nil {
+			return err
+		}
+		s.Aliases = splitList(aliases)
+		all := len(s.Aliases) == 0
+		if all {
+			s.Aliases = cfg.Aliases()
+		}
+		for _, alias := range s.Aliases {
+			if _, err := cfg.Database(alias); err != nil {
+				return err
+			}
+		}
+		// the config file is reloaded when changed, so always use the latest
+		s.Open = func(ctx context.Context, alias, role string) (*dburl.URL, *sql.DB, error) {
+			if role == "" {
+				role = subcmdArgs.Role
+			}
+			cfg, err := loadConfig(subcmdArgs)
+			if err != nil {
+				return nil, nil, err
+			}
+			return newOpener(cfg).Open(ctx, alias, role)
+		}
+		s.Check = func(alias, role, sqlstr string) error {
+			if role == "" {
+				role = subcmdArgs.Role
+			}
+			cfg, err := loadConfig(subcmdArgs)
+			if err != nil {
+				return err
+			}
+			dbConfig, err := cfg.Database(alias)
+			if err != nil {
+				return err
+			}
+			p, err := rolePolicy(dbConfig, role)
+			if err != nil {
+				return err
+			}
+			// the URL is resolved without connecting
+			u, err := newOpener(cfg).URL(alias, role)
+			if err != nil {
+				return err
+			}
+			return checkPolicy(p, u, sqlstr)
+		}
+		defer s.Close()
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		if err := subcmdArgs.configs.Watch(ctx, func(cfg *config.Config, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: config file: %v\n", redact.Error(err))
+				return
+			}
+			if all {
+				s.SetAliases(cfg.Aliases())
+			}
+			fmt.Fprintln(os.Stderr, "config file reloaded")
+		}); err != nil {
+			return err
+		}
+		errc := make(chan error, 2)
+		if listen != "" {
+			srv := &http.Server{Addr: listen, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+			go func() {
+				if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+					errc <- err
+				}
+			}()
+			defer func() {
+				shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				_ = srv.Shutdown(shutdownCtx)
+			}()
+			fmt.Fprintf(os.Stderr, "serving %d database aliases over HTTP on %s\n", len(s.Aliases), listen)
+		}
+		if grpcListen != "" {
+			l, err := net.Listen("tcp", grpcListen)
+			if err != nil {
+				return err
+			}
+			g := s.NewGRPCServer()
+			go func() {
+				errc <- g.Serve(l)
+			}()
+			defer g.GracefulStop()
+			fmt.Fprintf(os.Stderr, "serving %d database aliases over gRPC on %s\n", len(s.Aliases), grpcListen)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case err := <-errc:
+			return err
+		}
+	})
+}
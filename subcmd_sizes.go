@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"text/tabwriter"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/jsonout"
+	"github.com/xo/usql/sizes"
+)
+
+// tableSize is the JSON object of the size of a table.
+type tableSize struct {
+	Alias     string `json:"alias"`
+	Schema    string `json:"schema"`
+	Table     string `json:"table"`
+	Rows      int64  `json:"rows"`
+	DataSize  int64  `json:"data_size"`
+	IndexSize int64  `json:"index_size"`
+}
+
+func init() {
+	var aliases []string
+	var tag, schema string
+	cmd := subcmds.Command("sizes", "report the row counts and sizes of the tables of databases")
+	cmd.Arg("alias", "database aliases from the config file").StringsVar(&aliases)
+	cmd.Flag("tag", "report the databases with the tag").PlaceHolder("TAG").StringVar(&tag)
+	cmd.Flag("schema", "report the tables of the schema").PlaceHolder("SCHEMA").StringVar(&schema)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		if tag != "" {
+			cfg, err := loadConfig(subcmdArgs)
+			if err != nil {
+				return err
+			}
+			tagged := cfg.Tagged(tag)
+			if len(tagged) == 0 {
+				return jsonout.WithCode(jsonout.CodeConfig, fmt.Errorf("no databases tagged %s", tag))
+			}
+			aliases = append(aliases, tagged...)
+		}
+		if len(aliases) == 0 {
+			return jsonout.WithCode(jsonout.CodeMissingArgument, errors.New("database aliases or --tag required"))
+		}
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		var failed int
+		for i, alias := range aliases {
+			tables, err := readSizes(ctx, alias, schema)
+			if err != nil {
+				if len(aliases) == 1 {
+					return err
+				}
+				printErr(fmt.Errorf("%s: %w", alias, err), subcmdArgs.JSON)
+				failed++
+				continue
+			}
+			if err := writeSizes(alias, tables, i != 0 && !subcmdArgs.JSON); err != nil {
+				return err
+			}
+		}
+		code := jsonout.CodeConnection
+		if failed < len(aliases) {
+			code = jsonout.CodePartialFailure
+		}
+		if failed != 0 {
+			return jsonout.WithCode(code, fmt.Errorf("%d of %d databases failed", failed, len(aliases)))
+		}
+		return nil
+	})
+}
+
+// readSizes reads the sizes of the tables of the database alias.
+func readSizes(ctx context.Context, alias, schema string) ([]sizes.Table, error) {
+	u, db, err := openAlias(ctx, subcmdArgs, alias)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+	tables, err := sizes.Read(ctx, u, db, schema)
+	if err != nil {
+		return nil, jsonout.WithCode(jsonout.CodeDatabase, err)
+	}
+	return tables, nil
+}
+
+// writeSizes writes the sizes of the tables of the database alias, as JSON
+// objects with --json.
+func writeSizes(alias string, tables []sizes.Table, sep bool) error {
+	if subcmdArgs.JSON {
+		for _, t := range tables {
+			if err := jsonout.Write(os.Stdout, tableSize{alias, t.Schema, t.Name, t.Rows, t.DataSize, t.IndexSize}); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if sep {
+		fmt.Fprintln(os.Stdout)
+	}
+	fmt.Fprintf(os.Stdout, "%s:\n", alias)
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "schema\ttable\trows\tdata\tindexes\ttotal")
+	for _, t := range tables {
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\t%s\n", t.Schema, t.Name, t.Rows, sizes.FormatSize(t.DataSize), sizes.FormatSize(t.IndexSize), sizes.FormatSize(t.Size()))
+	}
+	return w.Flush()
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+
+	"github.com/alecthomas/kingpin/v2"
+	"github.com/xo/usql/snapshot"
+)
+
+func init() {
+	var alias, out string
+	var tables []string
+	cmd := subcmds.Command("snapshot", "snapshot the definitions and rows of small tables")
+	cmd.Arg("alias", "database alias from the config file").Required().StringVar(&alias)
+	cmd.Flag("tables", "tables to snapshot, comma separated").Required().PlaceHolder("TABLE,...").StringsVar(&tables)
+	cmd.Flag("out", "snapshot file, a zstd compressed tar archive (- for stdout)").Required().PlaceHolder("FILE.tar.zst").Short('o').StringVar(&out)
+	cmd.Action(func(*kingpin.ParseContext) error {
+		ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer cancel()
+		u, db, err := openAlias(ctx, subcmdArgs, alias)
+		if err != nil {
+			return err
+		}
+		defer db.Close()
+		var w io.Writer = os.Stdout
+		if out != "-" {
+			f, err := os.Create(out)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			w = f
+		}
+		res, err := snapshot.Snapshot(ctx, w, u, db, splitList(tables))
+		if err != nil {
+			if out != "-" {
+				os.Remove(out)
+			}
+			return err
+		}
+		// the snapshot written to stdout is not mixed with its tables
+		msgs := os.Stdout
+		if out == "-" {
+			msgs = os.Stderr
+		}
+		for _, r := range res {
+			fmt.Fprintf(msgs, "table %s: %d rows\n", r.Table, r.Rows)
+		}
+		return nil
+	})
+}
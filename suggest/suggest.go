@@ -0,0 +1,204 @@
+// Package suggest suggests the completions of the statements being typed from
+// the history of the statements executed on a database alias, ranked by
+// recency and frequency, so that frequently run statements are recalled
+// without searching the history.
+package suggest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HalfLife is the time after which the weight of an execution of a statement
+// in its rank is halved.
+const HalfLife = 7 * 24 * time.Hour
+
+// MaxEntries is the number of executions kept in a history file, the oldest
+// being removed when the file holds twice as many.
+const MaxEntries = 10000
+
+// Entry is an execution of a statement, a line of JSON of a history file.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Statement string    `json:"statement"`
+}
+
+// rank is the rank of a statement: its score at the time of its last
+// execution, decaying with HalfLife.
+type rank struct {
+	score float64
+	last  time.Time
+}
+
+// at returns the score of the rank at time t.
+func (r rank) at(t time.Time) float64 {
+	return r.score * math.Exp2(-float64(t.Sub(r.last))/float64(HalfLife))
+}
+
+// add adds an execution at time t to the rank.
+func (r *rank) add(t time.Time) {
+	if t.Before(r.last) {
+		r.score += math.Exp2(-float64(r.last.Sub(t)) / float64(HalfLife))
+		return
+	}
+	r.score, r.last = r.at(t)+1, t
+}
+
+// History is the history of the statements executed on a database alias,
+// stored in a file of JSON lines. A History is safe for concurrent use.
+type History struct {
+	path  string
+	mu    sync.Mutex
+	ranks map[string]*rank
+}
+
+// Open opens the history file at path, creating it when it is first added to.
+func Open(path string) (*History, error) {
+	h := &History{path: path, ranks: make(map[string]*rank)}
+	buf, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return h, nil
+	case err != nil:
+		return nil, err
+	}
+	var entries []Entry
+	var lines [][]byte
+	s := bufio.NewScanner(bytes.NewReader(buf))
+	s.Buffer(nil, 1<<20)
+	for s.Scan() {
+		var e Entry
+		// skip the invalid lines, as a line partially written
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil || e.Statement == "" {
+			continue
+		}
+		entries, lines = append(entries, e), append(lines, append([]byte(nil), s.Bytes()...))
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if len(entries) > 2*MaxEntries {
+		entries, lines = entries[len(entries)-MaxEntries:], lines[len(lines)-MaxEntries:]
+		if err := write(path, lines); err != nil {
+			return nil, err
+		}
+	}
+	for _, e := range entries {
+		h.rank(e)
+	}
+	return h, nil
+}
+
+// rank adds the entry to the rank of its statement.
+func (h *History) rank(e Entry) {
+	r, ok := h.ranks[e.Statement]
+	if !ok {
+		r = new(rank)
+		h.ranks[e.Statement] = r
+	}
+	r.add(e.Time)
+}
+
+// Add adds an execution of the statement at time t to the history, appending
+// it to the history file. Statements over multiple lines are added as a
+// single line, and statements with comments, that would comment out the
+// lines following them, are not added.
+func (h *History) Add(stmt string, t time.Time) error {
+	stmt, ok := Line(stmt)
+	if !ok {
+		return nil
+	}
+	e := Entry{Time: t.UTC(), Statement: stmt}
+	buf, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(h.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(buf, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	h.rank(e)
+	return nil
+}
+
+// Suggest returns the completion of the prefix, the rest of the statement of
+// the history starting with it that ranks first at time t, or an empty
+// string when none. Statements ranking the same are ranked by their last
+// execution.
+func (h *History) Suggest(prefix string, t time.Time) string {
+	if strings.TrimSpace(prefix) == "" {
+		return ""
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var best string
+	var score float64
+	var last time.Time
+	for stmt, r := range h.ranks {
+		if len(stmt) <= len(prefix) || !strings.HasPrefix(stmt, prefix) {
+			continue
+		}
+		switch s := r.at(t); {
+		case best == "", s > score, s == score && r.last.After(last):
+			best, score, last = stmt, s, r.last
+		}
+	}
+	return strings.TrimPrefix(best, prefix)
+}
+
+// Line returns the statement as a single line, joining its trimmed lines with
+// a space, and false when it has comments.
+func Line(stmt string) (string, bool) {
+	var lines []string
+	for _, line := range strings.Split(stmt, "\n") {
+		if strings.Contains(line, "--") || strings.Contains(line, "#") {
+			return "", false
+		}
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return strings.Join(lines, " "), len(lines) != 0
+}
+
+// write writes the lines to the file at path, replacing it.
+func write(path string, lines [][]byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, line := range lines {
+		_, _ = w.Write(line)
+		_ = w.WriteByte('\n')
+	}
+	if err := w.Flush(); err != nil {
+		_ = f.Close()
+		_ = os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
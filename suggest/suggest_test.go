@@ -0,0 +1,126 @@
+package suggest
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSuggest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history", "app_db.jsonl")
+	h, err := Open(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	adds := []struct {
+		stmt string
+		ago  time.Duration
+	}{
+		// frequent, but a month ago
+		{"select * from orders where status = 'late';", 30 * 24 * time.Hour},
+		{"select * from orders where status = 'late';", 30 * 24 * time.Hour},
+		{"select * from orders where status = 'late';", 30 * 24 * time.Hour},
+		// frequent and recent
+		{"select count(*) from orders;", 2 * time.Hour},
+		{"select count(*) from orders;", time.Hour},
+		// recent
+		{"select * from orders\n  limit 10;", time.Minute},
+		// not added
+		{"select 1 -- comment\nfrom orders;", 0},
+		{"  \n", 0},
+	}
+	for _, a := range adds {
+		if err := h.Add(a.stmt, now.Add(-a.ago)); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	check := func(h *History) {
+		t.Helper()
+		tests := []struct {
+			prefix, exp string
+		}{
+			{"", ""},
+			{"  ", ""},
+			{"sel", "ect count(*) from orders;"},
+			{"select * ", "from orders limit 10;"},
+			{"select * from orders where", " status = 'late';"},
+			{"select count(*) from orders;", ""},
+			{"SELECT", ""},
+			{"delete", ""},
+			{"select 1", ""},
+		}
+		for i, test := range tests {
+			if s := h.Suggest(test.prefix, now); s != test.exp {
+				t.Errorf("test %d expected %q, got: %q", i, test.exp, s)
+			}
+		}
+	}
+	check(h)
+	// reopening keeps the history
+	if h, err = Open(path); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	check(h)
+	// the frequent statements rank first, until they age
+	if s := h.Suggest("select ", now.Add(time.Hour)); s != "count(*) from orders;" {
+		t.Errorf("expected count, got: %q", s)
+	}
+	for i := 0; i < 3; i++ {
+		if err := h.Add("select * from orders limit 10;", now); err != nil {
+			t.Fatalf("expected no error, got: %v", err)
+		}
+	}
+	if s := h.Suggest("select ", now.Add(time.Hour)); s != "* from orders limit 10;" {
+		t.Errorf("expected limit, got: %q", s)
+	}
+}
+
+func TestOpenInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app_db.jsonl")
+	data := `{"time":"2024-01-02T03:04:05Z","statement":"select 1;"}
+not json
+{"time":"2024-01-02T03:04:05Z","statement":""}
+{"time":"2024-01-02T03:04:05Z","stat`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h, err := Open(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s := h.Suggest("sel", time.Now()); s != "ect 1;" {
+		t.Errorf("expected %q, got: %q", "ect 1;", s)
+	}
+}
+
+func TestOpenTruncates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app_db.jsonl")
+	var sb strings.Builder
+	for i := 0; i < 2*MaxEntries+1; i++ {
+		fmt.Fprintf(&sb, `{"time":"2024-01-02T03:04:05Z","statement":"select %d;"}`+"\n", i)
+	}
+	if err := os.WriteFile(path, []byte(sb.String()), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	h, err := Open(path)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(string(buf), "\n"); n != MaxEntries {
+		t.Errorf("expected %d lines, got: %d", MaxEntries, n)
+	}
+	if s := h.Suggest("select 0", time.Now()); s != "" {
+		t.Errorf("expected the oldest entries removed, got: %q", s)
+	}
+	if s := h.Suggest(fmt.Sprintf("select %d", 2*MaxEntries), time.Now()); s != ";" {
+		t.Errorf("expected %q, got: %q", ";", s)
+	}
+}
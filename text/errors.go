@@ -21,6 +21,10 @@ var (
 	ErrPasswordAttemptsExhausted = errors.New("password attempts exhausted")
 	// ErrSingleTransactionCannotBeUsedWithInteractiveMode is the single transaction cannot be used with interactive mode error.
 	ErrSingleTransactionCannotBeUsedWithInteractiveMode = errors.New("--single-transaction cannot be used with interactive mode")
+	// ErrDryRunCannotBeUsedWithInteractiveMode is the dry run cannot be used with interactive mode error.
+	ErrDryRunCannotBeUsedWithInteractiveMode = errors.New("--dry-run cannot be used with interactive mode")
+	// ErrDryRunEndsTransaction is the dry run ends transaction error.
+	ErrDryRunEndsTransaction = errors.New("the transaction of a dry run cannot be started, committed or rolled back")
 	// ErrNoEditorDefined is the no editor defined error.
 	ErrNoEditorDefined = errors.New("no editor defined")
 	// ErrUnknownCommand is the unknown command error.
@@ -45,8 +49,12 @@ var (
 	ErrInvalidValue = errors.New("invalid value")
 	// ErrTooManyRows is the too many rows error.
 	ErrTooManyRows = errors.New("too many rows")
+	// ErrNoRows is the no rows returned error.
+	ErrNoRows = errors.New("no rows returned")
+	// ErrOutputFileRequired is the output file required error.
+	ErrOutputFileRequired = errors.New(`output format requires an output file (\o FILE or \g FILE)`)
 	// ErrInvalidFormatType is the invalid format type error.
-	ErrInvalidFormatType = errors.New(`\pset: allowed formats are unaligned, aligned, wrapped, html, asciidoc, latex, latex-longtable, troff-ms, json, csv`)
+	ErrInvalidFormatType = errors.New(`\pset: allowed formats are unaligned, aligned, wrapped, html, asciidoc, latex, latex-longtable, troff-ms, json, csv, parquet, xlsx, inserts`)
 	// ErrInvalidFormatPagerType is the invalid format pager error.
 	ErrInvalidFormatPagerType = errors.New(`\pset: allowed pager values are on, off, always`)
 	// ErrInvalidFormatExpandedType is the invalid format expanded error.
@@ -55,12 +63,18 @@ var (
 	ErrInvalidFormatLineStyle = errors.New(`\pset: allowed line styles are ascii, old-ascii, unicode`)
 	// ErrInvalidFormatBorderLineStyle is the invalid format border line style error.
 	ErrInvalidFormatBorderLineStyle = errors.New(`\pset: allowed Unicode border line styles are single, double`)
+	// ErrInvalidFormatBinary is the invalid format binary error.
+	ErrInvalidFormatBinary = errors.New(`\pset: allowed binary displays are raw, summary, files`)
+	// ErrInvalidFormatInsertBatch is the invalid format insert batch error.
+	ErrInvalidFormatInsertBatch = errors.New(`\pset: insert_batch must be a positive number of rows`)
 	// ErrInvalidQuotedString is the invalid quoted string error.
 	ErrInvalidQuotedString = errors.New(`invalid quoted string`)
 	// ErrInvalidFormatOption is the invalid format option error.
 	ErrInvalidFormatOption = errors.New("invalid format option")
 	// ErrInvalidWatchDuration is the invalid watch duration error.
 	ErrInvalidWatchDuration = errors.New("invalid watch duration")
+	// ErrWatchAlertRequiresNotifier is the watch alert requires notifier error.
+	ErrWatchAlertRequiresNotifier = errors.New("webhook alerts require a notifier: use --notify NAME")
 	// ErrUnableToNormalizeURL is the unable to normalize URL error.
 	ErrUnableToNormalizeURL = errors.New("unable to normalize URL")
 	// ErrInvalidIsolationLevel is the invalid isolation level error.
@@ -69,4 +83,37 @@ var (
 	ErrNotSupported = errors.New("not supported")
 	// ErrWrongNumberOfArguments is the wrong number of arguments error.
 	ErrWrongNumberOfArguments = errors.New("wrong number of arguments")
+	// ErrNoDatabaseAliases is the no database aliases error.
+	ErrNoDatabaseAliases = errors.New("no database aliases configured")
+	// ErrCacheNotConfigured is the cache not configured error.
+	ErrCacheNotConfigured = errors.New("no cache_ttl configured for the database")
+	// ErrNoPagedQuery is the no paged query error.
+	ErrNoPagedQuery = errors.New("no paged query: use \\page N to turn paging on")
+	// ErrLastPage is the last page error.
+	ErrLastPage = errors.New("already at the last page")
+	// ErrFirstPage is the first page error.
+	ErrFirstPage = errors.New("already at the first page")
+	// ErrNullValue is the null value error.
+	ErrNullValue = errors.New("value is null")
+	// ErrCopyInTransaction is the copy in transaction error.
+	ErrCopyInTransaction = errors.New(`\copy is not supported in a transaction`)
+	// ErrBackgroundInTransaction is the background in transaction error.
+	ErrBackgroundInTransaction = errors.New(`\bg is not supported in a transaction`)
+	// ErrNoConfigFile is the no config file error.
+	ErrNoConfigFile = errors.New("no config file in use")
+	// ErrChartResultMustHaveAtLeast2Columns is the chart result must have at
+	// least 2 columns error.
+	ErrChartResultMustHaveAtLeast2Columns = errors.New("chart result must have at least 2 columns")
+	// ErrNoResultsToDiff is the no results to diff error.
+	ErrNoResultsToDiff = errors.New(`\diff requires the results of two queries`)
+	// ErrResultsNotRecorded is the results not recorded error.
+	ErrResultsNotRecorded = errors.New("the results to compare were not kept: results larger than 10000 rows, or written with a cursor or in a binary format, are not")
+	// ErrMultipleResultSets is the multiple result sets error.
+	ErrMultipleResultSets = errors.New("parquet files hold a single result set: only the first result set was written")
+	// ErrInvalidPrepare is the invalid prepare error.
+	ErrInvalidPrepare = errors.New(`\prepare requires NAME AS QUERY`)
+	// ErrOffline is the offline error.
+	ErrOffline = errors.New("offline: statements are not executed, only the cached metadata of the database is available")
+	// ErrOfflineRequiresDB is the offline requires db error.
+	ErrOfflineRequiresDB = errors.New("--offline requires a database alias: use --db ALIAS")
 )
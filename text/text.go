@@ -9,31 +9,32 @@ import (
 
 // Various usql text bits.
 var (
-	CommandName           = `usql`
-	CommandVersion        = `0.0.0-dev`
-	PassfileName          = CommandName + `pass`
-	Banner                = `the universal command-line interface for SQL databases`
-	NotConnected          = `(not connected)`
-	HelpPrefix            = `help`
-	QuitPrefix            = `quit`
-	ExitPrefix            = `exit`
-	WelcomeDesc           = `Type "` + HelpPrefix + `" for help.`
-	QueryBufferEmpty      = `Query buffer is empty.`
-	QueryBufferReset      = `Query buffer reset (cleared).`
-	InvalidCommand        = `Invalid command \%s. Try \? for help.`
-	ExtraArgumentIgnored  = `\%s: extra argument %q ignored`
-	MissingRequiredArg    = `\%s: missing required argument`
-	Copyright             = CommandName + ", " + Banner + ".\n\n" + License
-	RowCount              = `(%d rows)`
-	AvailableDrivers      = `Available Drivers:`
-	ConnInfo              = `Connected with driver %s (%s)`
-	EnterPassword         = `Enter password: `
-	EnterPreviousPassword = `Enter previous password: `
-	PasswordsDoNotMatch   = `Passwords do not match, trying again ...`
-	NewPassword           = `Enter new password: `
-	ConfirmPassword       = `Confirm password: `
-	PasswordChangeFailed  = `\password for %q failed: %v`
-	CouldNotSetVariable   = `could not set variable %q`
+	CommandName            = `usql`
+	CommandVersion         = `0.0.0-dev`
+	PassfileName           = CommandName + `pass`
+	Banner                 = `the universal command-line interface for SQL databases`
+	NotConnected           = `(not connected)`
+	HelpPrefix             = `help`
+	QuitPrefix             = `quit`
+	ExitPrefix             = `exit`
+	WelcomeDesc            = `Type "` + HelpPrefix + `" for help.`
+	QueryBufferEmpty       = `Query buffer is empty.`
+	QueryBufferReset       = `Query buffer reset (cleared).`
+	InvalidCommand         = `Invalid command \%s. Try \? for help.`
+	ExtraArgumentIgnored   = `\%s: extra argument %q ignored`
+	MissingRequiredArg     = `\%s: missing required argument`
+	Copyright              = CommandName + ", " + Banner + ".\n\n" + License
+	RowCount               = `(%d rows)`
+	AvailableDrivers       = `Available Drivers:`
+	ConnInfo               = `Connected with driver %s (%s)`
+	EnterPassword          = `Enter password: `
+	EnterPreviousPassword  = `Enter previous password: `
+	PasswordsDoNotMatch    = `Passwords do not match, trying again ...`
+	NewPassword            = `Enter new password: `
+	ConfirmPassword        = `Confirm password: `
+	PasswordChangeFailed   = `\password for %q failed: %v`
+	CouldNotSetVariable    = `could not set variable %q`
+	ChartColumnNotInResult = `chart column %q not in result`
 	// PasswordChangeSucceeded = `\password succeeded for %q`
 	HelpDesc          string
 	HelpDescShort     = `Use \? for help or press control-C to clear the input buffer.`
@@ -51,6 +52,8 @@ var (
 	FormatFieldInvalid      = `unrecognized value %q for "%s"`
 	FormatFieldInvalidValue = `unrecognized value %q for "%s": %s expected`
 	FormatFieldNameSetMap   = map[string]string{
+		`binary`:                   `Binary display is %s.`,
+		`binary_dir`:               `Binary directory is %q.`,
 		`border`:                   `Border style is %d.`,
 		`columns`:                  `Target width is %d.`,
 		`expanded`:                 `Expanded display is %s.`,
@@ -59,8 +62,11 @@ var (
 		`fieldsep_zero`:            `Field separator is zero byte.`,
 		`footer`:                   `Default footer is %s.`,
 		`format`:                   `Output format is %s.`,
+		`insert_batch`:             `Insert batch is %d rows.`,
+		`insert_table`:             `Insert table is %q.`,
 		`linestyle`:                `Line style is %s.`,
 		`locale`:                   `Locale is %q.`,
+		`max_col_width`:            `Maximum column width is %d.`,
 		`null`:                     `Null display is %q.`,
 		`numericlocale`:            `Locale-adjusted numeric output is %s.`,
 		`pager`:                    `Pager usage is %s.`,
@@ -76,17 +82,47 @@ var (
 		`unicode_header_linestyle`: `Unicode header line style is %q.`,
 	}
 	FormatFieldNameUnsetMap = map[string]string{
-		`tableattr`: `Table attributes unset.`,
-		`title`:     `Title is unset.`,
+		`binary_dir`:   `Binary directory is unset.`,
+		`insert_table`: `Insert table is unset.`,
+		`tableattr`:    `Table attributes unset.`,
+		`title`:        `Title is unset.`,
 	}
 	TimingSet            = `Timing is %s.`
 	TimingDesc           = `Time: %0.3f ms`
+	TimingCached         = `(cached)`
+	CacheSet             = `Result caching is %s.`
+	CacheCleared         = `Result cache cleared.`
+	ConfigReloaded       = `Config file reloaded.`
+	SessionSaved         = `Session %s saved to %s.`
+	QueryParamPrompt     = `%s: `
 	InvalidValue         = `invalid -%s value %q: %s`
 	NotSupportedByDriver = `%s not supported by %s driver`
 	RelationNotFound     = `Did not find any relation named "%s".`
 	InvalidOption        = `invalid option %q`
 	NotificationReceived = `Asynchronous notification %q %sreceived from server process with PID %d.`
 	NotificationPayload  = `with payload %q `
+	JobStarted           = `[%d] started`
+	JobFinished          = `[%d] %s (%v)`
+	NoSuchJob            = `no such job %d`
+	JobStillRunning      = `job %d is still running`
+	FetchedRows          = `FETCH %s %d`
+	NoConnectionPools    = `No open connection pools.`
+	PageSet              = `Paging is %s.`
+	PageDesc             = `Page %d, rows %d to %d.`
+	PageNext             = `Use \next for the next page.`
+	PagePrev             = `Use \prev for the previous page.`
+	PageNextPrev         = `Use \next or \prev for the next or previous page.`
+	BinaryExported       = `lo_export %s %s`
+	ColumnNotFound       = `column %q not found`
+	ResultSetNumber      = `Result set %d:`
+	NoSuchPrepared       = `no such prepared statement %s`
+	DryRunRolledBack     = `Dry run rolled back: %d statements would have affected %d rows.`
+	DryRunImplicitCommit = `%s statements commit the transaction on %s, and cannot be executed in a dry run`
+	WatchAlert           = `alert: value %s crossed %s`
+	ConfirmStatement     = `Execute this %s statement on %s? [y/N] `
+	Offline              = `(offline)`
+	OfflineInfo          = `Offline, with the metadata of %s taken %s: statements are not executed.`
+	ResultBytesExceeded  = `warning: partial result: fetching stopped after %d rows, exceeding the max_result_bytes of %s`
 )
 
 func init() {
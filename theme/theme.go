@@ -0,0 +1,187 @@
+// Package theme provides the color themes of the interactive sessions,
+// coloring their prompt, the headers and NULL values of their results, and
+// their errors.
+package theme
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	runewidth "github.com/mattn/go-runewidth"
+	"github.com/xo/tblfmt"
+)
+
+// Color is a color, as the parameters of its SGR escape sequence, such as
+// 1;31 for bold red. The empty color leaves text uncolored.
+type Color string
+
+// attributes are the text attributes of colors.
+var attributes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+	"blink":     "5",
+	"reverse":   "7",
+}
+
+// colors are the names of the basic colors.
+var colors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+// ParseColor parses a color of space separated words: attributes (bold, dim,
+// italic, underline, blink, reverse), a basic color (black, red, green,
+// yellow, blue, magenta, cyan, white, or gray), optionally prefixed with
+// bright-, a 256 color palette index (0 to 255) or a #RRGGBB true color. A
+// color following on is the background color, such as in "white on red".
+func ParseColor(s string) (Color, error) {
+	var params []string
+	var bg bool
+	for _, word := range strings.Fields(strings.ToLower(s)) {
+		if p, ok := attributes[word]; ok && !bg {
+			params = append(params, p)
+			continue
+		}
+		if word == "on" && !bg {
+			bg = true
+			continue
+		}
+		p, err := parseColor(word, bg)
+		if err != nil {
+			return "", err
+		}
+		params, bg = append(params, p), false
+	}
+	if bg {
+		return "", fmt.Errorf("invalid color %q: missing background color", s)
+	}
+	return Color(strings.Join(params, ";")), nil
+}
+
+// parseColor parses the word of a foreground or background color.
+func parseColor(word string, bg bool) (string, error) {
+	base, ext := 30, "38"
+	if bg {
+		base, ext = 40, "48"
+	}
+	if word == "gray" || word == "grey" {
+		return strconv.Itoa(base + 60), nil
+	}
+	bright := strings.HasPrefix(word, "bright-")
+	if bright {
+		word, base = strings.TrimPrefix(word, "bright-"), base+60
+	}
+	for i, name := range colors {
+		if word == name {
+			return strconv.Itoa(base + i), nil
+		}
+	}
+	if !bright {
+		if n, err := strconv.ParseUint(word, 10, 8); err == nil {
+			return ext + ";5;" + strconv.FormatUint(n, 10), nil
+		}
+		if len(word) == 7 && word[0] == '#' {
+			if n, err := strconv.ParseUint(word[1:], 16, 32); err == nil {
+				return fmt.Sprintf("%s;2;%d;%d;%d", ext, n>>16, n>>8&0xff, n&0xff), nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid color %q", word)
+}
+
+// Wrap wraps the text with the escape sequences of the color.
+func (c Color) Wrap(s string) string {
+	if c == "" || s == "" {
+		return s
+	}
+	return "\x1b[" + string(c) + "m" + s + "\x1b[0m"
+}
+
+// Theme is a color theme.
+type Theme struct {
+	// Prompt is the color of the prompt.
+	Prompt Color
+	// Header is the color of the column names of the results.
+	Header Color
+	// Null is the color of the NULL values of the results, displayed as
+	// \pset null.
+	Null Color
+	// Error is the color of the errors.
+	Error Color
+}
+
+// themes are the builtin themes.
+var themes = map[string]Theme{
+	"none":  {},
+	"dark":  {Prompt: "1;32", Header: "1;36", Null: "90", Error: "1;31"},
+	"light": {Prompt: "1;34", Header: "1;34", Null: "2", Error: "31"},
+	"mono":  {Prompt: "1", Header: "1", Null: "2", Error: "1"},
+}
+
+// Builtin returns the builtin theme with the name.
+func Builtin(name string) (Theme, bool) {
+	t, ok := themes[name]
+	return t, ok
+}
+
+// Names returns the names of the builtin themes.
+func Names() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Formatter wraps a formatter, coloring the column names and the NULL values
+// of the results.
+type Formatter struct {
+	tblfmt.Formatter
+	header Color
+	null   *tblfmt.Value
+}
+
+// NewFormatter wraps the formatter, coloring the column names with the header
+// color and the NULL values, displayed as null, with the null color.
+func NewFormatter(f tblfmt.Formatter, header, null Color, nullstr string) *Formatter {
+	ff := &Formatter{Formatter: f, header: header}
+	if null != "" && nullstr != "" && !strings.Contains(nullstr, "\n") {
+		ff.null = &tblfmt.Value{
+			Buf:   []byte(null.Wrap(nullstr)),
+			Width: runewidth.StringWidth(nullstr),
+		}
+	}
+	return ff
+}
+
+// Header formats the column names, coloring them.
+func (f *Formatter) Header(headers []string) ([]*tblfmt.Value, error) {
+	vals, err := f.Formatter.Header(headers)
+	if err != nil || f.header == "" {
+		return vals, err
+	}
+	for _, v := range vals {
+		// values of several lines, or with tabs, are written by their offsets
+		if v != nil && len(v.Newlines) == 0 && (len(v.Tabs) == 0 || len(v.Tabs[0]) == 0) {
+			v.Buf = []byte(f.header.Wrap(string(v.Buf)))
+		}
+	}
+	return vals, nil
+}
+
+// Format formats the values of a row, coloring the NULL values.
+func (f *Formatter) Format(vals []interface{}) ([]*tblfmt.Value, error) {
+	res, err := f.Formatter.Format(vals)
+	if err != nil || f.null == nil {
+		return res, err
+	}
+	for i, v := range res {
+		// the formatted values of NULL values are nil
+		if v == nil {
+			res[i] = f.null
+		}
+	}
+	return res, nil
+}
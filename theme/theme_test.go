@@ -0,0 +1,101 @@
+package theme
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/xo/tblfmt"
+)
+
+func TestParseColor(t *testing.T) {
+	tests := []struct {
+		s   string
+		exp Color
+		err bool
+	}{
+		{"", "", false},
+		{"red", "31", false},
+		{"Bold Red", "1;31", false},
+		{"bright-yellow", "93", false},
+		{"dim gray", "2;90", false},
+		{"208", "38;5;208", false},
+		{"#ff8800", "38;2;255;136;0", false},
+		{"underline 33", "4;38;5;33", false},
+		{"bold white on red", "1;37;41", false},
+		{"on bright-blue", "104", false},
+		{"black on #00ff00", "30;48;2;0;255;0", false},
+		{"on gray", "100", false},
+		{"red on", "", true},
+		{"on bold", "", true},
+		{"256", "", true},
+		{"bright-208", "", true},
+		{"#ff88", "", true},
+		{"purple", "", true},
+	}
+	for i, test := range tests {
+		t.Run(strconv.Itoa(i), func(t *testing.T) {
+			c, err := ParseColor(test.s)
+			switch {
+			case test.err && err == nil:
+				t.Errorf("expected error, got: %q", c)
+			case !test.err && err != nil:
+				t.Errorf("expected no error, got: %v", err)
+			case c != test.exp:
+				t.Errorf("expected %q, got: %q", test.exp, c)
+			}
+		})
+	}
+}
+
+func TestWrap(t *testing.T) {
+	if s, exp := Color("1;31").Wrap("error"), "\x1b[1;31merror\x1b[0m"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	if s := Color("").Wrap("error"); s != "error" {
+		t.Errorf("expected uncolored text, got: %q", s)
+	}
+}
+
+func TestBuiltin(t *testing.T) {
+	for _, name := range Names() {
+		if _, ok := Builtin(name); !ok {
+			t.Errorf("expected builtin theme %s", name)
+		}
+	}
+	if th, ok := Builtin("none"); !ok || th != (Theme{}) {
+		t.Errorf("expected empty theme, got: %v", th)
+	}
+	if _, ok := Builtin("missing"); ok {
+		t.Errorf("expected no theme")
+	}
+}
+
+func TestFormatter(t *testing.T) {
+	f := NewFormatter(tblfmt.NewEscapeFormatter(), "1", "90", "(null)")
+	vals, err := f.Header([]string{"id", "name"})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s, exp := string(vals[0].Buf), "\x1b[1mid\x1b[0m"; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	if vals[0].Width != 2 {
+		t.Errorf("expected width 2, got: %d", vals[0].Width)
+	}
+	var id, null interface{} = int64(1), nil
+	vals, err = f.Format([]interface{}{&id, &null})
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if s := string(vals[0].Buf); s != "1" {
+		t.Errorf("expected 1, got: %q", s)
+	}
+	if s, exp := string(vals[1].Buf), "\x1b[90m(null)\x1b[0m"; s != exp || vals[1].Width != 6 {
+		t.Errorf("expected %q of width 6, got: %q of width %d", exp, s, vals[1].Width)
+	}
+	// NULL values are left to the encoder without null string
+	f = NewFormatter(tblfmt.NewEscapeFormatter(), "", "90", "")
+	if vals, _ = f.Format([]interface{}{&null}); vals[0] != nil {
+		t.Errorf("expected nil value, got: %v", vals[0])
+	}
+}
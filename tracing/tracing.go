@@ -0,0 +1,93 @@
+// Package tracing emits OpenTelemetry spans for connections and queries.
+package tracing
+
+import (
+	"context"
+	"os"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/redact"
+	"github.com/xo/usql/text"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.17.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attribute keys.
+const (
+	AliasKey  = attribute.Key("usql.alias")
+	DbTypeKey = attribute.Key("usql.db_type")
+	RowsKey   = attribute.Key("usql.rows")
+)
+
+// Enabled returns true when an OTLP endpoint is configured.
+func Enabled() bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT") != ""
+}
+
+// Init exports spans to the OTLP endpoint, when configured, returning a func
+// flushing the pending spans.
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if !Enabled() {
+		return func(context.Context) error { return nil }, nil
+	}
+	// the endpoint and headers are read from the OTEL_EXPORTER_OTLP_*
+	// environment variables
+	exp, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(text.CommandLower()),
+		semconv.ServiceVersion(text.CommandVersion),
+	))
+	if err != nil {
+		return nil, err
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	return tp.Shutdown, nil
+}
+
+// Attrs returns the attributes of spans on a database. The alias and db type
+// of the config file are included when not empty.
+func Attrs(u *dburl.URL, alias, dbType string) []attribute.KeyValue {
+	var attrs []attribute.KeyValue
+	if u != nil {
+		attrs = append(attrs, semconv.DBSystemKey.String(u.Driver))
+	}
+	if alias != "" {
+		attrs = append(attrs, AliasKey.String(alias))
+	}
+	if dbType != "" {
+		attrs = append(attrs, DbTypeKey.String(dbType))
+	}
+	return attrs
+}
+
+// Start starts a span.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer("github.com/xo/usql").Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End ends a span, recording err with its secrets redacted.
+func End(span trace.Span, err error) {
+	if err != nil {
+		err = redact.Error(err)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Operation returns the attribute of the operation of a statement, such as
+// SELECT.
+func Operation(typ string) attribute.KeyValue {
+	return semconv.DBOperationKey.String(typ)
+}
@@ -0,0 +1,310 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/drivers"
+	"github.com/xo/usql/dump"
+	"github.com/xo/usql/export"
+	"github.com/xo/usql/progress"
+)
+
+// DefaultChunkRows is the default number of rows per chunk.
+const DefaultChunkRows = 100000
+
+// ExportOptions are the export options.
+type ExportOptions struct {
+	// Table is the table to export, optionally schema qualified.
+	Table string
+	// Key are the key columns the rows are ordered by. Defaults to the
+	// primary key of the table.
+	Key []string
+	// ChunkRows is the number of rows per chunk.
+	ChunkRows int
+	// Resume resumes the export of the directory, after its last valid chunk.
+	Resume bool
+	// Progress is the progress the exported rows are added to, when not nil.
+	Progress *progress.Progress
+}
+
+// Export exports the rows of the table to the directory, as CSV files of
+// ChunkRows rows ordered by the key, listed in the manifest of the directory
+// as they are written. When resumed, the chunks of the manifest are verified,
+// and the rows after the last valid chunk are exported.
+func Export(ctx context.Context, u *dburl.URL, db *sql.DB, dir string, opts ExportOptions) (*Manifest, error) {
+	m, err := ReadManifest(dir)
+	switch {
+	case err != nil && !errors.Is(err, fs.ErrNotExist):
+		return nil, err
+	case err == nil && !opts.Resume:
+		return nil, fmt.Errorf("%s already has an export: resume it with --resume, or remove it", dir)
+	case err == nil && m.Table != opts.Table:
+		return nil, fmt.Errorf("%s has an export of table %s, not %s", dir, m.Table, opts.Table)
+	case err == nil:
+		// keep the chunks up to the first invalid one
+		for i, c := range m.Chunks {
+			if err := c.Verify(dir); err != nil {
+				m.Chunks, m.Complete = m.Chunks[:i], false
+				break
+			}
+		}
+		if m.Complete {
+			return m, nil
+		}
+	default:
+		if opts.ChunkRows <= 0 {
+			opts.ChunkRows = DefaultChunkRows
+		}
+		m = &Manifest{Table: opts.Table, Key: opts.Key, ChunkRows: opts.ChunkRows}
+		if len(m.Key) == 0 {
+			if m.Key, err = primaryKey(ctx, u, db, opts.Table); err != nil {
+				return nil, err
+			}
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	d := dump.DialectFor(drivers.Caps(u).Dialect)
+	schema, name := "", opts.Table
+	if i := strings.LastIndex(opts.Table, "."); i != -1 {
+		schema, name = opts.Table[:i], opts.Table[i+1:]
+	}
+	cols := "*"
+	if m.Columns != nil {
+		cols = d.QuoteIdents(m.Columns)
+	}
+	q := fmt.Sprintf("SELECT %s FROM %s", cols, d.Qualify(schema, name))
+	if n := len(m.Chunks); n != 0 {
+		q += " WHERE " + after(d, m.Key, m.Chunks[n-1].LastKey)
+	}
+	rows, err := db.QueryContext(ctx, q+" ORDER BY "+d.QuoteIdents(m.Key))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+	if m.Columns == nil {
+		for _, ct := range cts {
+			m.Columns = append(m.Columns, ct.Name())
+		}
+	}
+	kinds := make([]export.ColumnKind, len(cts))
+	for i, ct := range cts {
+		kinds[i] = export.Kind(ct)
+	}
+	keyPos, err := keyPositions(m.Columns, m.Key)
+	if err != nil {
+		return nil, fmt.Errorf("table %s: %w", opts.Table, err)
+	}
+	values := make([]interface{}, len(cts))
+	for i := range values {
+		values[i] = new(interface{})
+	}
+	var w *chunkWriter
+	defer func() {
+		if w != nil {
+			w.abort()
+		}
+	}()
+	for rows.Next() {
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		if w == nil {
+			file := fmt.Sprintf("%s-%06d.csv", strings.NewReplacer("/", "_", `\`, "_").Replace(opts.Table), len(m.Chunks)+1)
+			if w, err = newChunkWriter(dir, file, m.Columns); err != nil {
+				return nil, err
+			}
+		}
+		if err := w.write(kinds, values); err != nil {
+			return nil, err
+		}
+		opts.Progress.Add(1)
+		if w.rows < int64(m.ChunkRows) {
+			continue
+		}
+		if err := finish(dir, m, w, d, kinds, keyPos, values); err != nil {
+			return nil, err
+		}
+		w = nil
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if w != nil {
+		if err := finish(dir, m, w, d, kinds, keyPos, values); err != nil {
+			return nil, err
+		}
+	}
+	m.Complete = true
+	if err := m.Write(dir); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// finish finishes the chunk written by w, whose last row is values, and adds
+// it to the manifest.
+func finish(dir string, m *Manifest, w *chunkWriter, d dump.Dialect, kinds []export.ColumnKind, keyPos []int, values []interface{}) error {
+	last := make([]string, len(keyPos))
+	for i, pos := range keyPos {
+		v := *(values[pos].(*interface{}))
+		if v == nil {
+			return fmt.Errorf("column %s of the key is NULL", m.Columns[pos])
+		}
+		last[i] = d.Literal(kinds[pos], v)
+	}
+	c, err := w.close()
+	if err != nil {
+		return err
+	}
+	c.LastKey = last
+	m.Chunks = append(m.Chunks, c)
+	return m.Write(dir)
+}
+
+// primaryKey returns the primary key columns of the table.
+func primaryKey(ctx context.Context, u *dburl.URL, db *sql.DB, table string) ([]string, error) {
+	tables, err := dump.ReadTables(ctx, u, db, []string{table})
+	if err != nil {
+		return nil, err
+	}
+	if len(tables[0].PrimaryKey) == 0 {
+		return nil, fmt.Errorf("table %s has no primary key: use --key", table)
+	}
+	return tables[0].PrimaryKey, nil
+}
+
+// keyPositions returns the positions of the key columns in the columns.
+func keyPositions(columns, key []string) ([]int, error) {
+	pos := make([]int, len(key))
+	for i, k := range key {
+		pos[i] = -1
+		for j, col := range columns {
+			if strings.EqualFold(col, k) {
+				pos[i] = j
+			}
+		}
+		if pos[i] == -1 {
+			return nil, fmt.Errorf("no column %s", k)
+		}
+	}
+	return pos, nil
+}
+
+// after returns the predicate of the rows whose key is after the last key.
+func after(d dump.Dialect, key, last []string) string {
+	ors := make([]string, len(key))
+	for i := range key {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, d.QuoteIdent(key[j])+" = "+last[j])
+		}
+		ands = append(ands, d.QuoteIdent(key[i])+" > "+last[i])
+		ors[i] = strings.Join(ands, " AND ")
+	}
+	if len(ors) == 1 {
+		return ors[0]
+	}
+	return "(" + strings.Join(ors, ") OR (") + ")"
+}
+
+// chunkWriter writes the rows of a chunk to a temporary file, renamed once
+// the chunk is finished.
+type chunkWriter struct {
+	dir, file string
+	f         *os.File
+	w         *bufio.Writer
+	h         hash.Hash
+	size      int64
+	rows      int64
+}
+
+// newChunkWriter creates the temporary file of the chunk, writing the header
+// of the columns.
+func newChunkWriter(dir, file string, columns []string) (*chunkWriter, error) {
+	f, err := os.Create(filepath.Join(dir, file+".tmp"))
+	if err != nil {
+		return nil, err
+	}
+	cw := &chunkWriter{dir: dir, file: file, f: f, h: sha256.New()}
+	cw.w = bufio.NewWriter(io.MultiWriter(f, cw.h, (*counter)(&cw.size)))
+	for i, col := range columns {
+		if i != 0 {
+			cw.w.WriteByte(',')
+		}
+		cw.w.WriteString(export.CSVField(export.KindString, col))
+	}
+	cw.w.WriteByte('\n')
+	return cw, nil
+}
+
+// write writes a row of values.
+func (cw *chunkWriter) write(kinds []export.ColumnKind, values []interface{}) error {
+	for i, v := range values {
+		if i != 0 {
+			cw.w.WriteByte(',')
+		}
+		cw.w.WriteString(export.CSVField(kinds[i], *(v.(*interface{}))))
+	}
+	cw.rows++
+	return cw.w.WriteByte('\n')
+}
+
+// close syncs and renames the file of the chunk.
+func (cw *chunkWriter) close() (Chunk, error) {
+	if err := cw.w.Flush(); err != nil {
+		return Chunk{}, err
+	}
+	if err := cw.f.Sync(); err != nil {
+		return Chunk{}, err
+	}
+	if err := cw.f.Close(); err != nil {
+		return Chunk{}, err
+	}
+	cw.f = nil
+	name := filepath.Join(cw.dir, cw.file)
+	if err := os.Rename(name+".tmp", name); err != nil {
+		return Chunk{}, err
+	}
+	return Chunk{
+		File:   cw.file,
+		Rows:   cw.rows,
+		Size:   cw.size,
+		SHA256: hex.EncodeToString(cw.h.Sum(nil)),
+	}, nil
+}
+
+// abort removes the temporary file of the chunk, when not closed.
+func (cw *chunkWriter) abort() {
+	if cw.f != nil {
+		cw.f.Close()
+		os.Remove(cw.f.Name())
+	}
+}
+
+// counter counts the bytes written.
+type counter int64
+
+// Write satisfies the io.Writer interface.
+func (c *counter) Write(b []byte) (int, error) {
+	*c += counter(len(b))
+	return len(b), nil
+}
@@ -0,0 +1,123 @@
+package transfer
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xo/dburl"
+	"github.com/xo/usql/importer"
+)
+
+// ImportOptions are the import options.
+type ImportOptions struct {
+	// Options are the options of the import of the chunks. The table
+	// defaults to the exported table, and the columns to its columns.
+	importer.Options
+	// Journal is the file recording the imported chunks, from which the
+	// import is resumed.
+	Journal string
+	// Resume resumes the import, skipping the chunks of the journal.
+	Resume bool
+}
+
+// Import imports the chunks of the export in the directory into the table,
+// returning the number of imported records. The checksum of each chunk is
+// verified before it is imported, in its own transaction, and the chunk is
+// recorded in the journal once committed. When resumed, the chunks of the
+// journal are skipped.
+func Import(ctx context.Context, u *dburl.URL, db *sql.DB, dir string, opts ImportOptions) (int64, error) {
+	m, err := ReadManifest(dir)
+	if err != nil {
+		return 0, err
+	}
+	if !m.Complete {
+		return 0, fmt.Errorf("the export in %s is not complete: resume the export with --resume", dir)
+	}
+	imported, err := readJournal(opts.Journal)
+	switch {
+	case err != nil:
+		return 0, err
+	case len(imported) != 0 && !opts.Resume:
+		return 0, fmt.Errorf("%s was imported before: resume the import with --resume, or remove %s", dir, opts.Journal)
+	}
+	if opts.Table == "" {
+		opts.Table = m.Table
+	}
+	if len(opts.Columns) == 0 {
+		opts.Columns = m.Columns
+	}
+	opts.Delimiter, opts.Quote, opts.Header, opts.Null = ',', '"', importer.HeaderOn, ""
+	var chunks []Chunk
+	var size int64
+	for _, c := range m.Chunks {
+		if !imported[c.File+" "+c.SHA256] {
+			chunks, size = append(chunks, c), size+c.Size
+		}
+	}
+	opts.Progress.SetSize(size)
+	f, err := os.OpenFile(opts.Journal, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	var total int64
+	for _, c := range chunks {
+		n, err := importChunk(ctx, u, db, dir, c, opts.Options)
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if _, err := fmt.Fprintln(f, c.File+" "+c.SHA256); err != nil {
+			return total, err
+		}
+		if err := f.Sync(); err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// importChunk verifies and imports the chunk.
+func importChunk(ctx context.Context, u *dburl.URL, db *sql.DB, dir string, c Chunk, opts importer.Options) (int64, error) {
+	if err := c.Verify(dir); err != nil {
+		return 0, err
+	}
+	f, err := os.Open(filepath.Join(dir, c.File))
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n, err := importer.Import(ctx, u, db, f, opts)
+	if err != nil {
+		return 0, fmt.Errorf("chunk %s: %w", c.File, err)
+	}
+	return n, nil
+}
+
+// readJournal reads the chunks recorded in the journal, as their file and
+// checksum separated by a space.
+func readJournal(name string) (map[string]bool, error) {
+	f, err := os.Open(name)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+	defer f.Close()
+	imported := make(map[string]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		if line := strings.TrimSpace(s.Text()); line != "" {
+			imported[line] = true
+		}
+	}
+	return imported, s.Err()
+}
@@ -0,0 +1,126 @@
+// Package transfer exports tables as chunks of CSV files, listed with their
+// checksums in a manifest, and imports them, so that interrupted transfers of
+// large tables are resumed instead of restarted.
+package transfer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ManifestName is the name of the manifest file of an export directory.
+const ManifestName = "manifest.json"
+
+// Manifest is the manifest of an export, listing its chunks.
+type Manifest struct {
+	// Table is the exported table.
+	Table string `json:"table"`
+	// Columns are the columns of the table, written as the header of the
+	// chunks.
+	Columns []string `json:"columns"`
+	// Key are the key columns the rows are ordered by.
+	Key []string `json:"key"`
+	// ChunkRows is the number of rows per chunk.
+	ChunkRows int `json:"chunk_rows"`
+	// Chunks are the written chunks, in order.
+	Chunks []Chunk `json:"chunks"`
+	// Complete is set once all the rows are exported.
+	Complete bool `json:"complete"`
+}
+
+// Chunk is a chunk of an export.
+type Chunk struct {
+	// File is the name of the CSV file of the chunk, in the export directory.
+	File string `json:"file"`
+	// Rows is the number of rows of the chunk.
+	Rows int64 `json:"rows"`
+	// Size is the size of the file.
+	Size int64 `json:"size"`
+	// SHA256 is the hex encoded SHA-256 checksum of the file.
+	SHA256 string `json:"sha256"`
+	// LastKey are the key values of the last row of the chunk, as SQL
+	// literals, from which the export is resumed.
+	LastKey []string `json:"last_key"`
+}
+
+// ReadManifest reads the manifest of the export directory.
+func ReadManifest(dir string) (*Manifest, error) {
+	buf, err := os.ReadFile(filepath.Join(dir, ManifestName))
+	if err != nil {
+		return nil, err
+	}
+	m := new(Manifest)
+	if err := json.Unmarshal(buf, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %w", filepath.Join(dir, ManifestName), err)
+	}
+	return m, nil
+}
+
+// Write writes the manifest to the export directory, replacing the previous
+// manifest atomically.
+func (m *Manifest) Write(dir string) error {
+	buf, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(dir, ManifestName), append(buf, '\n'))
+}
+
+// Rows returns the number of rows of the chunks.
+func (m *Manifest) Rows() int64 {
+	var n int64
+	for _, c := range m.Chunks {
+		n += c.Rows
+	}
+	return n
+}
+
+// ErrChecksum is the error returned when the checksum of a chunk does not
+// match its manifest.
+var ErrChecksum = errors.New("checksum mismatch")
+
+// Verify verifies the size and checksum of the file of the chunk in the
+// export directory.
+func (c Chunk) Verify(dir string) error {
+	f, err := os.Open(filepath.Join(dir, c.File))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	switch {
+	case err != nil:
+		return err
+	case n != c.Size, hex.EncodeToString(h.Sum(nil)) != c.SHA256:
+		return fmt.Errorf("chunk %s: %w", c.File, ErrChecksum)
+	}
+	return nil
+}
+
+// writeFile writes the file through a temporary file renamed once synced, so
+// that the file is either fully written or not at all.
+func writeFile(name string, buf []byte) error {
+	f, err := os.Create(name + ".tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(name+".tmp", name)
+}
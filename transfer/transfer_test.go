@@ -0,0 +1,136 @@
+package transfer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/xo/dburl"
+	"github.com/xo/usql/dump"
+)
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	src, srcURL := openDB(t, filepath.Join(dir, "src.db"),
+		`CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, price REAL)`,
+		`INSERT INTO t VALUES (1, 'a', 1.5), (2, 'b"c', NULL), (3, '', 0), (4, NULL, 2), (5, 'e,f', -1)`,
+	)
+	out := filepath.Join(dir, "out")
+	opts := ExportOptions{Table: "t", Key: []string{"id"}, ChunkRows: 2}
+	m, err := Export(ctx, srcURL, src, out, opts)
+	if err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if !m.Complete || len(m.Chunks) != 3 || m.Rows() != 5 {
+		t.Fatalf("expected 3 chunks of 5 rows, got: %+v", m)
+	}
+	if exp := []string{"4"}; !reflect.DeepEqual(m.Chunks[1].LastKey, exp) {
+		t.Errorf("expected last key %v, got: %v", exp, m.Chunks[1].LastKey)
+	}
+	if _, err := Export(ctx, srcURL, src, out, opts); err == nil {
+		t.Errorf("expected an error exporting to an export without resuming")
+	}
+	// a corrupt chunk is exported again when resumed
+	sum := m.Chunks[1].SHA256
+	if err := os.WriteFile(filepath.Join(out, m.Chunks[1].File), []byte("corrupt"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Chunks[1].Verify(out); !errors.Is(err, ErrChecksum) {
+		t.Errorf("expected checksum mismatch, got: %v", err)
+	}
+	opts.Resume = true
+	if m, err = Export(ctx, srcURL, src, out, opts); err != nil {
+		t.Fatalf("expected no error, got: %v", err)
+	}
+	if len(m.Chunks) != 3 || m.Chunks[1].SHA256 != sum {
+		t.Errorf("expected chunk 2 exported again, got: %+v", m.Chunks)
+	}
+	// resumed imports skip the journaled chunks
+	dst, dstURL := openDB(t, filepath.Join(dir, "dst.db"), `CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT, price REAL)`)
+	journal := filepath.Join(out, "dst.imported")
+	if err := os.WriteFile(journal, []byte(m.Chunks[0].File+" "+m.Chunks[0].SHA256+"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dst.Exec(`INSERT INTO t VALUES (1, 'a', 1.5), (2, 'b"c', NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	iopts := ImportOptions{Journal: journal}
+	if _, err := Import(ctx, dstURL, dst, out, iopts); err == nil {
+		t.Errorf("expected an error importing a partial import without resuming")
+	}
+	iopts.Resume = true
+	n, err := Import(ctx, dstURL, dst, out, iopts)
+	switch {
+	case err != nil:
+		t.Fatalf("expected no error, got: %v", err)
+	case n != 3:
+		t.Errorf("expected 3 records, got: %d", n)
+	}
+	if exp, got := readRows(t, src), readRows(t, dst); !reflect.DeepEqual(got, exp) {
+		t.Errorf("expected %v, got: %v", exp, got)
+	}
+	if n, err = Import(ctx, dstURL, dst, out, iopts); err != nil || n != 0 {
+		t.Errorf("expected nothing imported, got: %d, %v", n, err)
+	}
+}
+
+func TestAfter(t *testing.T) {
+	d := dump.DialectFor("postgres")
+	if s, exp := after(d, []string{"id"}, []string{"10"}), `"id" > 10`; s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+	s, exp := after(d, []string{"a", "b", "c"}, []string{"1", "'x'", "3"}), `("a" > 1) OR ("a" = 1 AND "b" > 'x') OR ("a" = 1 AND "b" = 'x' AND "c" > 3)`
+	if s != exp {
+		t.Errorf("expected %q, got: %q", exp, s)
+	}
+}
+
+// openDB opens a sqlite database, executing the statements.
+func openDB(t *testing.T, name string, stmts ...string) (*sql.DB, *dburl.URL) {
+	t.Helper()
+	u, err := dburl.Parse("sqlite:" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := sql.Open("sqlite3", name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	for _, s := range stmts {
+		if _, err := db.Exec(s); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return db, u
+}
+
+// readRows reads the rows of table t.
+func readRows(t *testing.T, db *sql.DB) [][]interface{} {
+	t.Helper()
+	rows, err := db.Query(`SELECT id, name, price FROM t ORDER BY id`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var res [][]interface{}
+	for rows.Next() {
+		var id int64
+		var name sql.NullString
+		var price sql.NullFloat64
+		if err := rows.Scan(&id, &name, &price); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, []interface{}{id, name, price})
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
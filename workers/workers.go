@@ -0,0 +1,132 @@
+// Package workers runs the tasks of bulk operations on a pool of workers,
+// limiting the number of tasks running at once on each database alias, so
+// they can't exhaust the connection slots of a database.
+package workers
+
+import (
+	"context"
+	"sort"
+	"sync"
+)
+
+// Pool is a pool of workers. The first error of its tasks cancels the context
+// of the others.
+type Pool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	limit  func(string) int
+	// workers are the slots of the running tasks.
+	workers chan struct{}
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	// aliases are the slots of the tasks running on each alias.
+	aliases map[string]chan struct{}
+	err     error
+}
+
+// New creates a pool of n workers, running at most limit(alias) tasks at once
+// on a database alias, when not 0. limit may be nil.
+func New(ctx context.Context, n int, limit func(alias string) int) *Pool {
+	if n < 1 {
+		n = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pool{
+		ctx:     ctx,
+		cancel:  cancel,
+		limit:   limit,
+		workers: make(chan struct{}, n),
+		aliases: make(map[string]chan struct{}),
+	}
+}
+
+// Go runs f on a worker, waiting for a free worker, and for free slots of the
+// database aliases f connects to. Tasks are not run after the first error, or
+// once the context of the pool is done.
+func (p *Pool) Go(f func(context.Context) error, aliases ...string) {
+	select {
+	case p.workers <- struct{}{}:
+	case <-p.ctx.Done():
+		p.fail(p.ctx.Err())
+		return
+	}
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.workers }()
+		release, err := p.acquire(aliases)
+		if err != nil {
+			p.fail(err)
+			return
+		}
+		defer release()
+		if err := f(p.ctx); err != nil {
+			p.fail(err)
+		}
+	}()
+}
+
+// Wait waits for the tasks, returning the first error.
+func (p *Pool) Wait() error {
+	p.wg.Wait()
+	p.cancel()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// acquire acquires a slot of each alias, in order, to not deadlock with the
+// tasks acquiring the same aliases.
+func (p *Pool) acquire(aliases []string) (func(), error) {
+	aliases = append([]string{}, aliases...)
+	sort.Strings(aliases)
+	var held []chan struct{}
+	release := func() {
+		for _, ch := range held {
+			<-ch
+		}
+	}
+	for i, alias := range aliases {
+		if i != 0 && alias == aliases[i-1] {
+			continue
+		}
+		ch := p.slots(alias)
+		if ch == nil {
+			continue
+		}
+		select {
+		case ch <- struct{}{}:
+			held = append(held, ch)
+		case <-p.ctx.Done():
+			release()
+			return nil, p.ctx.Err()
+		}
+	}
+	return release, nil
+}
+
+// slots returns the slots of the tasks of the alias, or nil when unlimited.
+func (p *Pool) slots(alias string) chan struct{} {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	ch, ok := p.aliases[alias]
+	if !ok {
+		if p.limit != nil {
+			if n := p.limit(alias); n > 0 {
+				ch = make(chan struct{}, n)
+			}
+		}
+		p.aliases[alias] = ch
+	}
+	return ch
+}
+
+// fail records the first error, canceling the other tasks.
+func (p *Pool) fail(err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err == nil {
+		p.err = err
+		p.cancel()
+	}
+}
@@ -0,0 +1,70 @@
+package workers
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPool(t *testing.T) {
+	tests := []struct {
+		workers int
+		limit   int
+		aliases []string
+		exp     int32
+	}{
+		{4, 0, []string{"pg"}, 4},
+		{4, 2, []string{"pg"}, 2},
+		{4, 1, []string{"pg", "my"}, 1},
+		{2, 3, []string{"pg"}, 2},
+		{0, 0, nil, 1},
+	}
+	for i, test := range tests {
+		p := New(context.Background(), test.workers, func(alias string) int {
+			return test.limit
+		})
+		var running, max int32
+		var mu sync.Mutex
+		for j := 0; j < 12; j++ {
+			p.Go(func(context.Context) error {
+				n := atomic.AddInt32(&running, 1)
+				mu.Lock()
+				if n > max {
+					max = n
+				}
+				mu.Unlock()
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&running, -1)
+				return nil
+			}, test.aliases...)
+		}
+		if err := p.Wait(); err != nil {
+			t.Fatalf("test %d expected no error, got: %v", i, err)
+		}
+		if max != test.exp {
+			t.Errorf("test %d expected %d tasks at once, got: %d", i, test.exp, max)
+		}
+	}
+}
+
+func TestPoolError(t *testing.T) {
+	p := New(context.Background(), 2, nil)
+	errFailed := errors.New("failed")
+	p.Go(func(context.Context) error { return errFailed }, "pg")
+	for i := 0; i < 4; i++ {
+		p.Go(func(ctx context.Context) error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Second):
+				return errors.New("not canceled")
+			}
+		}, "pg")
+	}
+	if err := p.Wait(); !errors.Is(err, errFailed) {
+		t.Fatalf("expected %v, got: %v", errFailed, err)
+	}
+}